@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -39,6 +40,33 @@ func main() {
 	fabricService := services.NewFabricService(cfg)
 	aiService := services.NewAIService(cfg.AIModelEndpoint)
 	fileService := services.NewFileService()
+	webhookService := services.NewWebhookService(db, cfg)
+	apiKeyService := services.NewAPIKeyService(db, cfg)
+	retentionService := services.NewRetentionService(db, cfg)
+	sessionService := services.NewSessionService(db, cfg)
+	notificationClient := services.NewNotificationClient(cfg)
+	loginSecurityService := services.NewLoginSecurityService(db, cfg, notificationClient)
+	defer loginSecurityService.Close()
+	kybClient := services.NewKYBClient(cfg)
+	organizationService := services.NewOrganizationService(db, cfg, notificationClient)
+	approvalService := services.NewApprovalService(db, cfg)
+	if err := retentionService.SeedRules(); err != nil {
+		log.Printf("Failed to seed retention rules: %v", err)
+	}
+	// Accounts from before organizations existed get one of their own
+	if created, err := organizationService.BackfillOrganizations(); err != nil {
+		log.Printf("Failed to create organizations for existing users: %v", err)
+	} else if created > 0 {
+		log.Printf("Created organizations for %d existing users", created)
+	}
+
+	// Background jobs stop when the server exits
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go webhookService.StartWorker(ctx)
+	go invoiceService.StartOverdueMonitor(ctx, cfg.OverdueCheckInterval, webhookService)
+	go retentionService.StartScheduler(ctx)
 
 	// Initialize API server
 	server := api.NewServer(api.ServerConfig{
@@ -48,7 +76,21 @@ func main() {
 		FabricService:    fabricService,
 		AIService:        aiService,
 		FileService:      fileService,
+		WebhookService:   webhookService,
+		APIKeyService:    apiKeyService,
+		RetentionService: retentionService,
+		SessionService:   sessionService,
 		JWTSecret:        cfg.JWTSecret,
+
+		LoginSecurityService: loginSecurityService,
+		KYBClient:            kybClient,
+		OrganizationService:  organizationService,
+		ApprovalService:      approvalService,
+
+		AccessTokenTTL:        cfg.AccessTokenTTL,
+		RefreshTokenTTL:       cfg.RefreshTokenTTL,
+		SessionLocationHeader: cfg.SessionLocationHeader,
+		SessionRegistryToken:  cfg.SessionRegistryToken,
 	})
 
 	// Start server
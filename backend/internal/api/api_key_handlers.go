@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+	case errors.Is(err, services.ErrInvalidAPIKeyInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "API key operation failed"})
+	}
+}
+
+// API key handlers
+func (s *Server) createAPIKey(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	var request services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, plaintext, err := s.apiKeyService.CreateAPIKey(request, userID)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	// The plaintext key is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     plaintext,
+		"message": "Store this key now; it cannot be retrieved again",
+	})
+}
+
+func (s *Server) getAPIKeys(c *gin.Context) {
+	keys, err := s.apiKeyService.ListAPIKeys(c.Query("organization_id"), c.Query("include_revoked") == "true")
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys, "count": len(keys), "available_scopes": services.APIKeyScopes})
+}
+
+func (s *Server) revokeAPIKey(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	key, err := s.apiKeyService.RevokeAPIKey(keyID, userID)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
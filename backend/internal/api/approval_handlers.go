@@ -0,0 +1,219 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+	case errors.Is(err, services.ErrSelfApproval), errors.Is(err, services.ErrNotInitiator):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrApprovalPending), errors.Is(err, services.ErrApprovalNotPending),
+		errors.Is(err, services.ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrApprovalExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Approval operation failed"})
+	}
+}
+
+// approvalChecker loads the approval named in the URL and makes sure the user may decide
+// on it: they must act for the initiator's organization and hold the same permission
+func (s *Server) approvalChecker(c *gin.Context) (*models.PendingApproval, services.ApprovalChecker, bool) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return nil, services.ApprovalChecker{}, false
+	}
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return nil, services.ApprovalChecker{}, false
+	}
+
+	approval, err := s.approvalService.Get(approvalID)
+	if err != nil {
+		respondApprovalError(c, err)
+		return nil, services.ApprovalChecker{}, false
+	}
+	if !inOrganization(c, approval.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+		return nil, services.ApprovalChecker{}, false
+	}
+	if !access.Has(approval.Permission) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":                "Insufficient permissions",
+			"required_permissions": []string{approval.Permission},
+		})
+		return nil, services.ApprovalChecker{}, false
+	}
+
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	return approval, services.ApprovalChecker{UserID: userID, IPAddress: c.ClientIP(), Comment: body.Comment}, true
+}
+
+// Approval handlers
+func (s *Server) getApprovals(c *gin.Context) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+
+	approvals, err := s.approvalService.List(services.ApprovalFilter{
+		OrganizationID: &access.OrganizationID,
+		Status:         models.ApprovalStatus(c.Query("status")),
+		Action:         models.ApprovalAction(c.Query("action")),
+		Limit:          limit,
+	})
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+
+	// Members only see the actions they could confirm, and their own
+	visible := []models.PendingApproval{}
+	for _, approval := range approvals {
+		if access.Has(approval.Permission) || approval.InitiatedBy == userID {
+			visible = append(visible, approval)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"approvals": visible, "count": len(visible)})
+}
+
+func (s *Server) getApproval(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	approval, err := s.approvalService.Get(approvalID)
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+	if !inOrganization(c, approval.OrganizationID) || (!access.Has(approval.Permission) && approval.InitiatedBy != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+func (s *Server) confirmApproval(c *gin.Context) {
+	approval, checker, ok := s.approvalChecker(c)
+	if !ok {
+		return
+	}
+
+	approval, ready, err := s.approvalService.Approve(approval.UUID, checker)
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+	if !ready {
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Confirmation recorded; more approvers must confirm",
+			"approval": approval,
+		})
+		return
+	}
+
+	failure := s.carryOutApproval(c, approval)
+	if err := s.approvalService.Complete(approval, failure); err != nil {
+		log.Printf("Failed to record outcome of approval %s: %v", approval.UUID, err)
+	}
+	if failure != "" {
+		if !c.Writer.Written() {
+			c.JSON(http.StatusConflict, gin.H{"error": failure, "approval": approval})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Action confirmed and carried out", "approval": approval})
+}
+
+func (s *Server) rejectApproval(c *gin.Context) {
+	approval, checker, ok := s.approvalChecker(c)
+	if !ok {
+		return
+	}
+
+	approval, err := s.approvalService.Reject(approval.UUID, checker)
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Action rejected", "approval": approval})
+}
+
+func (s *Server) cancelApproval(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	approval, err := s.approvalService.Cancel(approvalID, userID)
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Approval cancelled", "approval": approval})
+}
+
+// carryOutApproval performs an action once enough approvers confirmed it. Its state is
+// checked again because it may have changed while the approval was pending. It returns
+// why the action failed, after responding if the reason needs more detail, or "".
+func (s *Server) carryOutApproval(c *gin.Context, approval *models.PendingApproval) string {
+	switch approval.Action {
+	case models.ApprovalFinancingApproval:
+		request, err := s.financingService.GetRequestByID(approval.ResourceID)
+		if err != nil {
+			return "Financing request not found"
+		}
+		if request.Status != models.FinancingStatusPending {
+			return "Financing request is no longer pending"
+		}
+		if !s.requireVerified(c, request.UserID, true) {
+			return "Customer or company verification is no longer current"
+		}
+		if err := s.financingService.UpdateRequestStatus(request.UUID, models.FinancingStatusApproved); err != nil {
+			return "Failed to approve request"
+		}
+		return ""
+	default:
+		return "Unsupported action"
+	}
+}
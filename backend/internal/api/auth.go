@@ -1,11 +1,14 @@
 package api
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
 	"invoice-financing-platform/pkg/auth"
 
 	"github.com/gin-gonic/gin"
@@ -72,8 +75,14 @@ func (s *Server) register(c *gin.Context) {
 		return
 	}
 
-	// Generate tokens (use UUID field)
-	token, refreshToken, err := s.generateTokens(user.UUID, user.Email, string(user.Role))
+	// New accounts own an organization they can invite staff to. If this fails it is
+	// created on the user's first request instead.
+	if _, err := s.organizationService.EnsurePersonalOrganization(user); err != nil {
+		log.Printf("Failed to create organization for user %s: %v", user.UUID, err)
+	}
+
+	// Every pair of tokens belongs to a session for this device
+	token, refreshToken, err := s.startSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -86,7 +95,7 @@ func (s *Server) register(c *gin.Context) {
 		Token:        token,
 		RefreshToken: refreshToken,
 		User:         *user,
-		ExpiresIn:    time.Now().Add(24 * time.Hour).Unix(),
+		ExpiresIn:    time.Now().Add(s.accessTokenTTL).Unix(),
 	})
 }
 
@@ -96,26 +105,65 @@ func (s *Server) login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	ipAddress, userAgent := c.ClientIP(), c.Request.UserAgent()
 
 	// Get user by email
 	user, err := s.userService.GetByEmail(req.Email)
 	if err != nil {
+		assessment := s.loginSecurityService.Assess(nil, ipAddress, userAgent)
+		s.loginSecurityService.RecordAttempt(nil, req.Email, ipAddress, userAgent, false, services.LoginFailUnknownAccount, assessment)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	assessment := s.loginSecurityService.Assess(user, ipAddress, userAgent)
+
+	// Locked accounts are refused even with the right password. The response is the one
+	// for wrong credentials, so it does not tell whether the email has an account; the
+	// user learns of the lockout from the emailed alert.
+	if until := services.LockedUntil(user, time.Now()); until != nil {
+		s.loginSecurityService.RecordAttempt(user, user.Email, ipAddress, userAgent, false, services.LoginFailAccountLocked, assessment)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.loginSecurityService.RecordAttempt(user, user.Email, ipAddress, userAgent, false, services.LoginFailInvalidPassword, assessment)
+		if _, err := s.loginSecurityService.RegisterFailure(user, ipAddress); err != nil {
+			log.Printf("Failed to count failed login: %v", err)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generate tokens (use UUID field)
-	token, refreshToken, err := s.generateTokens(user.UUID, user.Email, string(user.Role))
+	// Risky logins are completed with a code emailed to the user
+	if s.loginSecurityService.RequiresConfirmation(assessment) {
+		token, expiresAt, err := s.loginSecurityService.StartConfirmation(user, ipAddress, userAgent)
+		if err != nil {
+			respondLoginSecurityError(c, err)
+			return
+		}
+		s.loginSecurityService.RecordAttempt(user, user.Email, ipAddress, userAgent, false, services.LoginFailConfirmationRequired, assessment)
+		c.JSON(http.StatusOK, gin.H{
+			"confirmation_required": true,
+			"confirmation_token":    token,
+			"expires_at":            expiresAt,
+		})
+		return
+	}
+
+	s.completeLogin(c, user, assessment)
+}
+
+// completeLogin opens a session for a user whose login has been verified
+func (s *Server) completeLogin(c *gin.Context, user *models.User, assessment *services.LoginAssessment) {
+	// Every pair of tokens belongs to a session for this device
+	token, refreshToken, err := s.startSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
 	}
+	s.loginSecurityService.RecordSuccess(user, c.ClientIP(), c.Request.UserAgent(), assessment)
 
 	// Remove password hash from response
 	user.PasswordHash = ""
@@ -124,7 +172,7 @@ func (s *Server) login(c *gin.Context) {
 		Token:        token,
 		RefreshToken: refreshToken,
 		User:         *user,
-		ExpiresIn:    time.Now().Add(24 * time.Hour).Unix(),
+		ExpiresIn:    time.Now().Add(s.accessTokenTTL).Unix(),
 	})
 }
 
@@ -138,44 +186,90 @@ func (s *Server) refreshToken(c *gin.Context) {
 	refreshToken = strings.TrimPrefix(refreshToken, "Bearer ")
 	
 	claims, err := auth.ValidateToken(refreshToken, s.jwtSecret)
+	if err != nil || claims["type"] != auth.TokenTypeRefresh {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	sessionID, err := uuid.Parse(fmt.Sprint(claims["session_id"]))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
 
-	userID, _ := uuid.Parse(claims["user_id"].(string))
-	email := claims["email"].(string)
-	role := claims["role"].(string)
+	userID, err := uuid.Parse(fmt.Sprint(claims["user_id"]))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	// The new tokens carry the user's current email and role, not those the refresh
+	// token was issued with, and deleted or locked accounts cannot refresh at all
+	user, err := s.userService.GetByID(userID)
+	if err != nil || user.DeletedAt != nil || user.AnonymizedAt != nil || services.LockedUntil(user, time.Now()) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
 
-	// Generate new tokens
-	token, newRefreshToken, err := s.generateTokens(userID, email, role)
+	// Generate new tokens; the refresh token is rotated and the old one stops working
+	token, newRefreshToken, err := s.generateTokens(user.UUID, user.Email, string(user.Role), sessionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
 	}
+	if _, err := s.sessionService.RotateRefreshToken(sessionID, refreshToken, newRefreshToken); err != nil {
+		respondSessionError(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"token":         token,
 		"refresh_token": newRefreshToken,
-		"expires_in":    time.Now().Add(24 * time.Hour).Unix(),
+		"expires_in":    time.Now().Add(s.accessTokenTTL).Unix(),
 	})
 }
 
 func (s *Server) logout(c *gin.Context) {
-	// In a production system, you would invalidate the token
-	// by adding it to a blacklist or removing it from a whitelist
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	if err := s.sessionService.RevokeSession(userID, sessionID, models.SessionLogout); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
 }
 
-func (s *Server) generateTokens(userID uuid.UUID, email, role string) (string, string, error) {
-	// Generate access token (24 hours)
-	token, err := auth.GenerateToken(userID.String(), email, role, s.jwtSecret, 24*time.Hour)
+// startSession records the device signing in and issues tokens bound to it
+func (s *Server) startSession(c *gin.Context, user *models.User) (string, string, error) {
+	location := ""
+	if s.sessionLocationHeader != "" {
+		location = c.GetHeader(s.sessionLocationHeader)
+	}
+	session, err := s.sessionService.CreateSession(user, c.ClientIP(), c.Request.UserAgent(), location)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, refreshToken, err := s.generateTokens(user.UUID, user.Email, string(user.Role), session.UUID)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.sessionService.SetRefreshToken(session.UUID, refreshToken); err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+func (s *Server) generateTokens(userID uuid.UUID, email, role string, sessionID uuid.UUID) (string, string, error) {
+	token, err := auth.GenerateSessionToken(userID.String(), email, role, sessionID.String(), "", s.jwtSecret, s.accessTokenTTL)
 	if err != nil {
 		return "", "", err
 	}
 
-	// Generate refresh token (7 days)
-	refreshToken, err := auth.GenerateToken(userID.String(), email, role, s.jwtSecret, 7*24*time.Hour)
+	refreshToken, err := auth.GenerateSessionToken(userID.String(), email, role, sessionID.String(), auth.TokenTypeRefresh, s.jwtSecret, s.refreshTokenTTL)
 	if err != nil {
 		return "", "", err
 	}
@@ -183,7 +277,8 @@ func (s *Server) generateTokens(userID uuid.UUID, email, role string) (string, s
 	return token, refreshToken, nil
 }
 
-// AuthMiddleware validates JWT tokens
+// AuthMiddleware validates JWT tokens and the session they belong to, so revoked and
+// timed out sessions are cut off on their next request
 func (s *Server) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
@@ -196,13 +291,31 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 		
 		claims, err := auth.ValidateToken(tokenString, s.jwtSecret)
+		if err != nil || claims["type"] == auth.TokenTypeRefresh {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		// Tokens issued before sessions existed carry no session ID and are refused
+		sessionID, err := uuid.Parse(fmt.Sprint(claims["session_id"]))
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
+		session, err := s.sessionService.ValidateSession(sessionID)
+		if err == nil && session.UserID.String() != claims["user_id"] {
+			err = services.ErrSessionNotFound
+		}
+		if err != nil {
+			respondSessionError(c, err)
+			c.Abort()
+			return
+		}
 
 		// Set user info in context
+		c.Set("session_id", sessionID.String())
 		c.Set("user_id", claims["user_id"])
 		c.Set("user_email", claims["email"])
 		c.Set("user_role", claims["role"])
@@ -211,16 +324,80 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// AdminMiddleware checks if user has admin role
-func (s *Server) AdminMiddleware() gin.HandlerFunc {
+// organizationHeader selects the organization a request acts for. Without it, routes
+// with an :orgId parameter use that and all others the organization the user joined first.
+const organizationHeader = "X-Organization-ID"
+
+// organizationAccess resolves what the user may do in the organization the request acts
+// for, responding and returning false when it cannot be used
+func (s *Server) organizationAccess(c *gin.Context) (*services.OrganizationAccess, bool) {
+	if value, ok := c.Get("organization_access"); ok {
+		return value.(*services.OrganizationAccess), true
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(fmt.Sprint(userIDStr))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+
+	var organizationID *uuid.UUID
+	selected := c.Param("orgId")
+	if selected == "" {
+		selected = c.GetHeader(organizationHeader)
+	}
+	if selected != "" {
+		id, err := uuid.Parse(selected)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+			return nil, false
+		}
+		organizationID = &id
+	}
+
+	role, _ := c.Get("user_role")
+	access, err := s.organizationService.ResolveAccess(userID, fmt.Sprint(role), organizationID)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return nil, false
+	}
+	c.Set("organization_access", access)
+	c.Set("organization_id", access.OrganizationID.String())
+	return access, true
+}
+
+// RequirePermission allows the request when the user's role in the organization it acts
+// for grants every permission. Platform admins hold all permissions.
+func (s *Server) RequirePermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
-		if !exists || role != string(models.RoleAdmin) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		access, ok := s.organizationAccess(c)
+		if !ok {
 			c.Abort()
 			return
 		}
-		
+
+		for _, permission := range permissions {
+			if !access.Has(permission) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":                "Insufficient permissions",
+					"required_permissions": permissions,
+					"organization_id":      access.OrganizationID,
+				})
+				c.Abort()
+				return
+			}
+		}
 		c.Next()
 	}
 }
+
+// hasPermission reports whether a permission was granted for the request. It is only
+// meaningful after RequirePermission resolved the organization.
+func hasPermission(c *gin.Context, permission string) bool {
+	value, ok := c.Get("organization_access")
+	if !ok {
+		return false
+	}
+	return value.(*services.OrganizationAccess).Has(permission)
+}
@@ -1,11 +1,13 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -49,6 +51,22 @@ func (s *Server) updateUserProfile(c *gin.Context) {
 		return
 	}
 
+	// Once the company is in KYB its tax ID is the one compliance checked
+	if updateData.TaxID != user.TaxID {
+		eligibility, err := s.kybClient.CheckEligibility(userID)
+		if err != nil {
+			respondKYBError(c, err)
+			return
+		}
+		if eligibility.HasCase() {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "The tax ID cannot be changed once the company has been submitted for business verification (KYB)",
+				"kyb_status": eligibility.Status,
+			})
+			return
+		}
+	}
+
 	user.FirstName = updateData.FirstName
 	user.LastName = updateData.LastName
 	user.CompanyName = updateData.CompanyName
@@ -90,10 +108,12 @@ func (s *Server) getUserStats(c *gin.Context) {
 
 // Invoice handlers
 func (s *Server) getInvoices(c *gin.Context) {
-	userIDStr, _ := c.Get("user_id")
-	userID, _ := uuid.Parse(userIDStr.(string))
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
 
-	invoices, err := s.invoiceService.GetByUserID(userID)
+	invoices, err := s.invoiceService.GetByOrganizationID(access.OrganizationID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoices"})
 		return
@@ -112,11 +132,18 @@ func (s *Server) createInvoice(c *gin.Context) {
 		return
 	}
 
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+
 	invoice.UserID = userID
+	invoice.OrganizationID = &access.OrganizationID
 	if err := s.invoiceService.Create(&invoice); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
 		return
 	}
+	s.screenNewBuyer(&invoice)
 
 	c.JSON(http.StatusCreated, invoice)
 }
@@ -129,15 +156,29 @@ func (s *Server) getInvoice(c *gin.Context) {
 		return
 	}
 
-	invoice, err := s.invoiceService.GetByID(invoiceID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+	invoice, ok := s.loadOrgInvoice(c, invoiceID)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, invoice)
 }
 
+// loadOrgInvoice returns the invoice if it belongs to the organization the request acts
+// for. Otherwise it writes the error response and returns false.
+func (s *Server) loadOrgInvoice(c *gin.Context, invoiceID uuid.UUID) (*models.Invoice, bool) {
+	invoice, err := s.invoiceService.GetByID(invoiceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return nil, false
+	}
+	if !inOrganization(c, invoice.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return invoice, true
+}
+
 func (s *Server) updateInvoice(c *gin.Context) {
 	idParam := c.Param("id")
 	invoiceID, err := uuid.Parse(idParam)
@@ -146,18 +187,8 @@ func (s *Server) updateInvoice(c *gin.Context) {
 		return
 	}
 
-	userIDStr, _ := c.Get("user_id")
-	userID, _ := uuid.Parse(userIDStr.(string))
-
-	// Check if invoice exists and belongs to user
-	existingInvoice, err := s.invoiceService.GetByID(invoiceID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
-		return
-	}
-
-	if existingInvoice.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	existingInvoice, ok := s.loadOrgInvoice(c, invoiceID)
+	if !ok {
 		return
 	}
 
@@ -207,18 +238,7 @@ func (s *Server) deleteInvoice(c *gin.Context) {
 		return
 	}
 
-	userIDStr, _ := c.Get("user_id")
-	userID, _ := uuid.Parse(userIDStr.(string))
-
-	// Check if invoice exists and belongs to user
-	existingInvoice, err := s.invoiceService.GetByID(invoiceID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
-		return
-	}
-
-	if existingInvoice.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if _, ok := s.loadOrgInvoice(c, invoiceID); !ok {
 		return
 	}
 
@@ -244,11 +264,6 @@ func (s *Server) deleteInvoice(c *gin.Context) {
 }
 
 func (s *Server) verifyInvoice(c *gin.Context) {
-	// Implementation placeholder - would use AI service
-	c.JSON(http.StatusOK, gin.H{"message": "Invoice verification endpoint"})
-}
-
-func (s *Server) uploadInvoiceDocument(c *gin.Context) {
 	idParam := c.Param("id")
 	invoiceID, err := uuid.Parse(idParam)
 	if err != nil {
@@ -256,18 +271,46 @@ func (s *Server) uploadInvoiceDocument(c *gin.Context) {
 		return
 	}
 
-	userIDStr, _ := c.Get("user_id")
-	userID, _ := uuid.Parse(userIDStr.(string))
+	var verification struct {
+		Approved *bool  `json:"approved" binding:"required"`
+		Notes    string `json:"notes"`
+	}
+
+	if err := c.ShouldBindJSON(&verification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Check if invoice exists and belongs to user
-	existingInvoice, err := s.invoiceService.GetByID(invoiceID)
+	invoice, err := s.invoiceService.Verify(invoiceID, *verification.Approved)
+	if errors.Is(err, services.ErrInvoiceNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending invoices can be verified"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify invoice"})
 		return
 	}
 
-	if existingInvoice.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if *verification.Approved {
+		s.publishEvent(models.WebhookEventInvoiceVerified, invoice.UserID, gin.H{
+			"invoice": invoice,
+			"notes":   verification.Notes,
+		})
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+func (s *Server) uploadInvoiceDocument(c *gin.Context) {
+	idParam := c.Param("id")
+	invoiceID, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	existingInvoice, ok := s.loadOrgInvoice(c, invoiceID)
+	if !ok {
 		return
 	}
 
@@ -323,10 +366,12 @@ func (s *Server) uploadInvoiceDocument(c *gin.Context) {
 
 // Financing handlers
 func (s *Server) getFinancingRequests(c *gin.Context) {
-	userIDStr, _ := c.Get("user_id")
-	userID, _ := uuid.Parse(userIDStr.(string))
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
 
-	requests, err := s.financingService.GetRequestsByUserID(userID)
+	requests, err := s.financingService.GetRequestsByOrganizationID(access.OrganizationID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get financing requests"})
 		return
@@ -345,7 +390,25 @@ func (s *Server) createFinancingRequest(c *gin.Context) {
 		return
 	}
 
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+	invoice, err := s.invoiceService.GetByID(request.InvoiceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if !inOrganization(c, invoice.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
 	request.UserID = userID
+	request.OrganizationID = &access.OrganizationID
+	if !s.requireVerified(c, userID, true) || !s.requireBuyerScreened(c, invoice) {
+		return
+	}
 	if err := s.financingService.CreateRequest(&request); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create financing request"})
 		return
@@ -367,6 +430,10 @@ func (s *Server) getFinancingRequest(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Financing request not found"})
 		return
 	}
+	if !inOrganization(c, request.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
 
 	c.JSON(http.StatusOK, request)
 }
@@ -384,12 +451,6 @@ func (s *Server) approveFinancingRequest(c *gin.Context) {
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-	if userRole != string(models.RoleAdmin) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
-	}
-
 	request, err := s.financingService.GetRequestByID(requestID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Financing request not found"})
@@ -401,6 +462,40 @@ func (s *Server) approveFinancingRequest(c *gin.Context) {
 		return
 	}
 
+	// The company's verification may have lapsed since the request was made
+	if !s.requireVerified(c, request.UserID, true) {
+		return
+	}
+
+	// Above the configured amount another approver must confirm before it takes effect
+	if s.approvalService.RequiredApprovals(models.ApprovalFinancingApproval, request.RequestedAmount) > 0 {
+		access, ok := s.organizationAccess(c)
+		if !ok {
+			return
+		}
+		userIDStr, _ := c.Get("user_id")
+		userID, _ := uuid.Parse(userIDStr.(string))
+		approval, err := s.approvalService.Initiate(services.ApprovalInitiation{
+			OrganizationID: &access.OrganizationID,
+			Action:         models.ApprovalFinancingApproval,
+			ResourceType:   "financing_request",
+			ResourceID:     requestID,
+			Amount:         request.RequestedAmount,
+			Permission:     services.PermFinancingApprove,
+			InitiatedBy:    userID,
+		})
+		if err != nil {
+			respondApprovalError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "Financing approval is waiting for confirmation by another approver",
+			"approval": approval,
+		})
+		return
+	}
+
 	if err := s.financingService.UpdateRequestStatus(requestID, models.FinancingStatusApproved); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve request"})
 		return
@@ -417,12 +512,6 @@ func (s *Server) rejectFinancingRequest(c *gin.Context) {
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-	if userRole != string(models.RoleAdmin) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
-	}
-
 	var rejectionData struct {
 		Reason string `json:"reason"`
 	}
@@ -451,6 +540,66 @@ func (s *Server) rejectFinancingRequest(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Financing request rejected", "reason": rejectionData.Reason})
 }
 
+func (s *Server) repayFinancingRequest(c *gin.Context) {
+	idParam := c.Param("id")
+	requestID, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var repayment struct {
+		Amount    float64 `json:"amount" binding:"required,gt=0"`
+		Reference string  `json:"reference"`
+	}
+
+	if err := c.ShouldBindJSON(&repayment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := s.financingService.GetRequestByID(requestID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Financing request not found"})
+		return
+	}
+
+	if request.Status != models.FinancingStatusFunded {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only funded requests can be repaid"})
+		return
+	}
+
+	repaid, err := s.financingService.RecordRepayment(requestID, repayment.Amount)
+	switch {
+	case errors.Is(err, services.ErrRepaymentExceedsBalance):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrRequestNotRepayable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Financing request was already repaid"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record repayment"})
+		return
+	}
+
+	// The invoice is settled and investors are told only once the request is repaid in full
+	if !repaid.Completed {
+		c.JSON(http.StatusOK, gin.H{"message": "Partial repayment recorded", "repayment": repaid})
+		return
+	}
+
+	s.invoiceService.TransitionStatus(request.InvoiceID,
+		[]models.InvoiceStatus{models.InvoiceStatusFinanced, models.InvoiceStatusOverdue}, models.InvoiceStatusPaid)
+	request.Status = models.FinancingStatusCompleted
+	s.publishInvoiceEvent(models.WebhookEventInvoiceRepaid, request, gin.H{
+		"amount":     repayment.Amount,
+		"amount_due": repaid.AmountDue,
+		"reference":  repayment.Reference,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repayment recorded successfully", "repayment": repaid})
+}
+
 func (s *Server) getInvestmentOpportunities(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "20")
 	limit, _ := strconv.Atoi(limitStr)
@@ -489,6 +638,9 @@ func (s *Server) createInvestment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Financing request not approved for investment"})
 		return
 	}
+	if !s.requireVerified(c, userID, false) {
+		return
+	}
 
 	// Create investment
 	investment := &models.Investment{
@@ -506,6 +658,24 @@ func (s *Server) createInvestment(c *gin.Context) {
 		return
 	}
 
+	// The request is funded once investments cover the requested amount
+	totalInvested, err := s.financingService.GetTotalInvested(financingRequest.UUID)
+	if err == nil && totalInvested >= financingRequest.RequestedAmount {
+		funded, err := s.financingService.MarkFunded(financingRequest.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark financing request as funded"})
+			return
+		}
+		if funded {
+			s.invoiceService.TransitionStatus(financingRequest.InvoiceID,
+				[]models.InvoiceStatus{models.InvoiceStatusVerified}, models.InvoiceStatusFinanced)
+			financingRequest.Status = models.FinancingStatusFunded
+			s.publishInvoiceEvent(models.WebhookEventInvoiceFunded, financingRequest, gin.H{
+				"total_invested": totalInvested,
+			})
+		}
+	}
+
 	c.JSON(http.StatusCreated, investment)
 }
 
@@ -533,6 +703,16 @@ func (s *Server) tokenizeInvoice(c *gin.Context) {
 		return
 	}
 
+	invoice, err := s.invoiceService.GetByID(request.InvoiceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if !inOrganization(c, invoice.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
 	txHash, err := s.blockchainService.TokenizeInvoice(request.InvoiceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tokenize invoice"})
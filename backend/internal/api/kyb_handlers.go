@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requireVerified stops financing or investment by a user whose KYC is not current and,
+// with company set, whose company has not passed KYB. It responds and returns false when
+// the user may not proceed or their status cannot be checked.
+func (s *Server) requireVerified(c *gin.Context, userID uuid.UUID, company bool) bool {
+	kyc, err := s.kybClient.CheckKYC(userID)
+	if err != nil {
+		respondKYBError(c, err)
+		return false
+	}
+	if !kyc.Eligible {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Identity verification (KYC) must be current to request financing or invest",
+			"kyc_status": kyc.Status,
+		})
+		return false
+	}
+	if !company {
+		return true
+	}
+
+	eligibility, err := s.kybClient.CheckEligibility(userID)
+	if err != nil {
+		respondKYBError(c, err)
+		return false
+	}
+	if !eligibility.Eligible {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "The company must pass business verification (KYB) before it can be financed",
+			"kyb_status": eligibility.Status,
+			"reasons":    eligibility.Reasons,
+		})
+		return false
+	}
+	return true
+}
+
+// requireBuyerScreened screens the invoice's buyer before financing is requested against
+// it. It responds and returns false unless the buyer has cleared screening.
+func (s *Server) requireBuyerScreened(c *gin.Context, invoice *models.Invoice) bool {
+	outcome, err := s.kybClient.ScreenBuyer(invoice)
+	if err != nil {
+		respondKYBError(c, err)
+		return false
+	}
+	if outcome.ID != "" {
+		if err := s.invoiceService.SetBuyerScreening(invoice.UUID, outcome.Status); err != nil {
+			log.Printf("Failed to record buyer screening of invoice %s: %v", invoice.UUID, err)
+		}
+	}
+	if !outcome.Cleared() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            "The invoice buyer must clear sanctions screening before the invoice can be financed",
+			"screening_status": outcome.Status,
+			"screening_id":     outcome.ID,
+		})
+		return false
+	}
+	return true
+}
+
+// screenNewBuyer screens the buyer of a newly created invoice. The invoice is kept even
+// when screening fails; financing against it screens the buyer again.
+func (s *Server) screenNewBuyer(invoice *models.Invoice) {
+	outcome, err := s.kybClient.ScreenBuyer(invoice)
+	if err != nil {
+		log.Printf("Failed to screen buyer of invoice %s: %v", invoice.UUID, err)
+		return
+	}
+	if outcome.ID == "" {
+		return
+	}
+	invoice.BuyerScreening = outcome.Status
+	if err := s.invoiceService.SetBuyerScreening(invoice.UUID, outcome.Status); err != nil {
+		log.Printf("Failed to record buyer screening of invoice %s: %v", invoice.UUID, err)
+	}
+}
+
+func respondKYBError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrKYBUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verification status could not be checked, try again later"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check verification status"})
+	}
+}
+
+// getKYBStatus shows the user whether their company may be financed
+func (s *Server) getKYBStatus(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	eligibility, err := s.kybClient.CheckEligibility(userID)
+	if err != nil {
+		respondKYBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, eligibility)
+}
+
+// getKYCStatus shows the user whether their KYC allows financing and investment, and
+// when they need to verify again
+func (s *Server) getKYCStatus(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	eligibility, err := s.kybClient.CheckKYC(userID)
+	if err != nil {
+		respondKYBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, eligibility)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func respondLoginSecurityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrConfirmationInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login confirmation is invalid or expired; sign in again"})
+	case errors.Is(err, services.ErrConfirmationCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid confirmation code"})
+	case errors.Is(err, services.ErrConfirmationDelivery):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login security operation failed"})
+	}
+}
+
+// confirmLogin completes a risky login with the code emailed to the user
+func (s *Server) confirmLogin(c *gin.Context) {
+	var req struct {
+		ConfirmationToken string `json:"confirmation_token" binding:"required"`
+		Code              string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ipAddress, userAgent := c.ClientIP(), c.Request.UserAgent()
+
+	user, err := s.loginSecurityService.ConfirmLogin(req.ConfirmationToken, req.Code, ipAddress, userAgent)
+	if err != nil {
+		respondLoginSecurityError(c, err)
+		return
+	}
+
+	s.completeLogin(c, user, s.loginSecurityService.Assess(user, ipAddress, userAgent))
+}
+
+func (s *Server) getLoginHistory(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	attempts, err := s.loginSecurityService.LoginHistory(userID, limit)
+	if err != nil {
+		respondLoginSecurityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"login_history": attempts, "count": len(attempts)})
+}
+
+func (s *Server) getDevices(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	devices, err := s.loginSecurityService.Devices(userID)
+	if err != nil {
+		respondLoginSecurityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": devices, "count": len(devices)})
+}
+
+// forgetDevice removes a device from the known list, so the next login from it alerts
+// the user again
+func (s *Server) forgetDevice(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	if err := s.loginSecurityService.ForgetDevice(userID, deviceID); err != nil {
+		respondLoginSecurityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device removed"})
+}
+
+func (s *Server) adminGetLoginHistory(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	attempts, err := s.loginSecurityService.LoginHistory(userID, limit)
+	if err != nil {
+		respondLoginSecurityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"login_history": attempts, "count": len(attempts)})
+}
+
+// adminUnlockUser lifts a login lockout before it runs out
+func (s *Server) adminUnlockUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := s.loginSecurityService.UnlockAccount(userID); err != nil {
+		respondLoginSecurityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
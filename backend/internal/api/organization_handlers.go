@@ -0,0 +1,427 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func respondOrganizationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+	case errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	case errors.Is(err, services.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case errors.Is(err, services.ErrNotOrganizationMember), errors.Is(err, services.ErrPermissionEscalation),
+		errors.Is(err, services.ErrInvitationEmail):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrInvitationExists),
+		errors.Is(err, services.ErrRoleExists), errors.Is(err, services.ErrRoleInUse), errors.Is(err, services.ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvitationInvalid):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidOrganizationInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Organization operation failed"})
+	}
+}
+
+// organizationActor describes the user making a membership change. RequirePermission must
+// have run first.
+func (s *Server) organizationActor(c *gin.Context) (services.OrganizationActor, bool) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return services.OrganizationActor{}, false
+	}
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	return services.OrganizationActor{UserID: userID, IPAddress: c.ClientIP(), Access: access}, true
+}
+
+// inOrganization reports whether a record owned by organizationID belongs to the
+// organization the request acts for
+func inOrganization(c *gin.Context, organizationID *uuid.UUID) bool {
+	value, _ := c.Get("organization_id")
+	return organizationID != nil && organizationID.String() == value
+}
+
+// Organization handlers
+func (s *Server) getMyOrganizations(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	organizations, err := s.organizationService.ListUserOrganizations(userID)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": organizations, "count": len(organizations)})
+}
+
+func (s *Server) createOrganization(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	var request services.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := services.OrganizationActor{UserID: userID, IPAddress: c.ClientIP()}
+	organization, _, _, err := s.organizationService.CreateOrganization(request, actor, false)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+// getOrganization shows the organization with the caller's role and permissions in it
+func (s *Server) getOrganization(c *gin.Context) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+
+	organization, err := s.organizationService.GetOrganization(access.OrganizationID)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organization": organization, "access": access})
+}
+
+func (s *Server) acceptOrganizationInvitation(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := s.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	membership, err := s.organizationService.AcceptInvitation(user, request.Token, c.ClientIP())
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, membership)
+}
+
+// Member handlers
+func (s *Server) getOrganizationMembers(c *gin.Context) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+
+	members, err := s.organizationService.ListMembers(access.OrganizationID)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members, "count": len(members)})
+}
+
+func (s *Server) updateOrganizationMember(c *gin.Context) {
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	membership, err := s.organizationService.ChangeMemberRole(actor, memberID, request.Role)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, membership)
+}
+
+// removeOrganizationMember removes a member, or lets the caller leave when it is their own ID
+func (s *Server) removeOrganizationMember(c *gin.Context) {
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	if err := s.organizationService.RemoveMember(actor, memberID); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// Invitation handlers
+func (s *Server) getOrganizationInvitations(c *gin.Context) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := s.organizationService.ListInvitations(access.OrganizationID, c.Query("pending") == "true")
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations, "count": len(invitations)})
+}
+
+func (s *Server) inviteOrganizationMember(c *gin.Context) {
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	invitation, acceptURL, err := s.organizationService.InviteMember(actor, request.Email, request.Role)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	// The link only works for an account with the invited email address
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"accept_url": acceptURL,
+		"message":    "Invitation emailed; the link cannot be retrieved again",
+	})
+}
+
+func (s *Server) revokeOrganizationInvitation(c *gin.Context) {
+	invitationID, err := uuid.Parse(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	invitation, err := s.organizationService.RevokeInvitation(actor, invitationID)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+// Role handlers
+func (s *Server) getOrganizationRoles(c *gin.Context) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+
+	roles, err := s.organizationService.ListRoles(access.OrganizationID)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "available_permissions": services.OrganizationPermissions})
+}
+
+func (s *Server) createOrganizationRole(c *gin.Context) {
+	var input services.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	role, err := s.organizationService.CreateRole(actor, input)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+func (s *Server) updateOrganizationRole(c *gin.Context) {
+	var input services.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	role, err := s.organizationService.UpdateRole(actor, c.Param("key"), input)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+func (s *Server) deleteOrganizationRole(c *gin.Context) {
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	if err := s.organizationService.DeleteRole(actor, c.Param("key")); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+func (s *Server) getOrganizationAuditEvents(c *gin.Context) {
+	access, ok := s.organizationAccess(c)
+	if !ok {
+		return
+	}
+	s.respondAuditEvents(c, access.OrganizationID)
+}
+
+func (s *Server) respondAuditEvents(c *gin.Context, organizationID uuid.UUID) {
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+
+	events, err := s.organizationService.ListAuditEvents(organizationID, limit)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+}
+
+// Admin organization handlers
+func (s *Server) adminGetOrganizations(c *gin.Context) {
+	organizations, err := s.organizationService.ListOrganizations(c.Query("type"))
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": organizations, "count": len(organizations)})
+}
+
+// adminCreateOrganization creates an organization of any type, such as a bank, and
+// invites its owner
+func (s *Server) adminCreateOrganization(c *gin.Context) {
+	var request services.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.OwnerEmail == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner_email is required"})
+		return
+	}
+
+	actor, ok := s.organizationActor(c)
+	if !ok {
+		return
+	}
+	organization, invitation, acceptURL, err := s.organizationService.CreateOrganization(request, actor, true)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"organization": organization,
+		"invitation":   invitation,
+		"accept_url":   acceptURL,
+	})
+}
+
+func (s *Server) adminGetOrganizationAuditEvents(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	if _, err := s.organizationService.GetOrganization(organizationID); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+	s.respondAuditEvents(c, organizationID)
+}
+
+// provisionSSOMember adds a user who logged in through an organization's SSO connection in
+// the user management service to the organization
+func (s *Server) provisionSSOMember(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	var member services.ProvisionedMember
+	if err := c.ShouldBindJSON(&member); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	membership, err := s.organizationService.ProvisionMember(organizationID, member)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, membership)
+}
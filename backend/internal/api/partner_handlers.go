@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// partnerOrganization returns the organization the request's API key was issued for.
+// Keys issued before keys were bound to an organization ID are refused.
+func partnerOrganization(c *gin.Context) (uuid.UUID, bool) {
+	organizationID, err := uuid.Parse(c.GetString("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is not scoped to an organization"})
+		return uuid.Nil, false
+	}
+	return organizationID, true
+}
+
+// Partner handlers look records up within the key's organization, so records of other
+// organizations are reported as not found
+func (s *Server) getPartnerInvoice(c *gin.Context) {
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+	organizationID, ok := partnerOrganization(c)
+	if !ok {
+		return
+	}
+
+	invoice, err := s.invoiceService.GetByIDInOrganization(invoiceID, organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+func (s *Server) getPartnerFinancingRequest(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+	organizationID, ok := partnerOrganization(c)
+	if !ok {
+		return
+	}
+
+	request, err := s.financingService.GetRequestInOrganization(requestID, organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Financing request not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
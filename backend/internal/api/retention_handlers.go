@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func respondRetentionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRetentionRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention rule not found"})
+	case errors.Is(err, services.ErrLegalHoldNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Legal hold not found"})
+	case errors.Is(err, services.ErrPurgeRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purge run not found"})
+	case errors.Is(err, services.ErrInvalidRetentionRule), errors.Is(err, services.ErrInvalidLegalHold):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPurgeRunInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPurgeReportUnsigned):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention operation failed"})
+	}
+}
+
+// Retention rule handlers
+func (s *Server) getRetentionRules(c *gin.Context) {
+	rules, err := s.retentionService.ListRules()
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "count": len(rules)})
+}
+
+func (s *Server) createRetentionRule(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	var request services.CreateRetentionRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := s.retentionService.CreateRule(request, userID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (s *Server) updateRetentionRule(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	var request services.UpdateRetentionRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := s.retentionService.UpdateRule(c.Param("name"), request, userID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// Legal hold handlers
+func (s *Server) getLegalHolds(c *gin.Context) {
+	holds, err := s.retentionService.ListHolds(c.Query("include_released") != "true")
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holds": holds, "count": len(holds)})
+}
+
+func (s *Server) placeLegalHold(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	var request services.PlaceLegalHoldRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := s.retentionService.PlaceHold(request, userID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (s *Server) releaseLegalHold(c *gin.Context) {
+	holdID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid legal hold ID"})
+		return
+	}
+
+	var request struct {
+		Note string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	hold, err := s.retentionService.ReleaseHold(holdID, userID, request.Note)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// Purge run handlers
+func (s *Server) runPurge(c *gin.Context) {
+	var request struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	run, err := s.retentionService.RunPurge(c.Request.Context(), request.DryRun, "admin:"+userIDStr.(string))
+	if err != nil && run == nil {
+		respondRetentionError(c, err)
+		return
+	}
+	if err != nil {
+		// The run stopped part way; its signed report still records what was done
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention run failed", "run": run})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (s *Server) getPurgeRuns(c *gin.Context) {
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	runs, err := s.retentionService.ListRuns(limit)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "count": len(runs)})
+}
+
+func (s *Server) getPurgeRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge run ID"})
+		return
+	}
+
+	run, err := s.retentionService.GetRun(runID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// verifyPurgeRun checks the stored report against its signature
+func (s *Server) verifyPurgeRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge run ID"})
+		return
+	}
+
+	valid, reason, err := s.retentionService.VerifyRun(runID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": valid, "reason": reason})
+}
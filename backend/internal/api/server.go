@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"invoice-financing-platform/internal/services"
 	"invoice-financing-platform/middleware"
 	"invoice-financing-platform/models"
@@ -14,9 +16,24 @@ type Server struct {
 	invoiceService    *services.InvoiceService
 	financingService  *services.FinancingService
 	blockchainService *services.BlockchainService
+	fabricService     *services.FabricService
 	aiService         *services.AIService
 	fileService       *services.FileService
+	webhookService    *services.WebhookService
+	apiKeyService     *services.APIKeyService
+	retentionService  *services.RetentionService
+	sessionService    *services.SessionService
 	jwtSecret         string
+
+	loginSecurityService *services.LoginSecurityService
+	kybClient            *services.KYBClient
+	organizationService  *services.OrganizationService
+	approvalService      *services.ApprovalService
+
+	accessTokenTTL        time.Duration
+	refreshTokenTTL       time.Duration
+	sessionLocationHeader string
+	sessionRegistryToken  string
 }
 
 type ServerConfig struct {
@@ -24,9 +41,24 @@ type ServerConfig struct {
 	InvoiceService    *services.InvoiceService
 	FinancingService  *services.FinancingService
 	BlockchainService *services.BlockchainService
+	FabricService     *services.FabricService
 	AIService         *services.AIService
 	FileService       *services.FileService
+	WebhookService    *services.WebhookService
+	APIKeyService     *services.APIKeyService
+	RetentionService  *services.RetentionService
+	SessionService    *services.SessionService
 	JWTSecret         string
+
+	LoginSecurityService *services.LoginSecurityService
+	KYBClient            *services.KYBClient
+	OrganizationService  *services.OrganizationService
+	ApprovalService      *services.ApprovalService
+
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
+	SessionLocationHeader string
+	SessionRegistryToken  string
 }
 
 func NewServer(config ServerConfig) *Server {
@@ -36,9 +68,24 @@ func NewServer(config ServerConfig) *Server {
 		invoiceService:    config.InvoiceService,
 		financingService:  config.FinancingService,
 		blockchainService: config.BlockchainService,
+		fabricService:     config.FabricService,
 		aiService:         config.AIService,
 		fileService:       config.FileService,
+		webhookService:    config.WebhookService,
+		apiKeyService:     config.APIKeyService,
+		retentionService:  config.RetentionService,
+		sessionService:    config.SessionService,
 		jwtSecret:         config.JWTSecret,
+
+		loginSecurityService: config.LoginSecurityService,
+		kybClient:            config.KYBClient,
+		organizationService:  config.OrganizationService,
+		approvalService:      config.ApprovalService,
+
+		accessTokenTTL:        config.AccessTokenTTL,
+		refreshTokenTTL:       config.RefreshTokenTTL,
+		sessionLocationHeader: config.SessionLocationHeader,
+		sessionRegistryToken:  config.SessionRegistryToken,
 	}
 
 	server.setupMiddleware()
@@ -84,6 +131,7 @@ func (s *Server) setupRoutes() {
 	{
 		auth.POST("/register", middleware.ValidateJSON(&models.UserRegistrationRequest{}), s.register)
 		auth.POST("/login", middleware.ValidateJSON(&models.UserLoginRequest{}), s.login)
+		auth.POST("/login/confirm", s.confirmLogin)
 		auth.POST("/refresh", s.refreshToken)
 		auth.POST("/logout", s.AuthMiddleware(), s.logout)
 	}
@@ -96,43 +144,87 @@ func (s *Server) setupRoutes() {
 		users.PUT("/profile", s.updateUserProfile)
 		users.POST("/verify", s.verifyUser)
 		users.GET("/stats", s.getUserStats)
+		users.GET("/sessions", s.getSessions)
+		users.DELETE("/sessions/:id", s.revokeSession)
+		users.POST("/sessions/revoke-others", s.revokeOtherSessions)
+		users.GET("/login-history", s.getLoginHistory)
+		users.GET("/devices", s.getDevices)
+		users.DELETE("/devices/:id", s.forgetDevice)
+		users.GET("/kyb-status", s.getKYBStatus)
+		users.GET("/kyc-status", s.getKYCStatus)
+	}
+
+	// Organizations, their members, invitations and roles. Routes under :orgId act for
+	// that organization; every other route acts for the one named in X-Organization-ID.
+	organizations := api.Group("/organizations")
+	organizations.Use(s.AuthMiddleware())
+	{
+		organizations.GET("", s.getMyOrganizations)
+		organizations.POST("", s.createOrganization)
+		organizations.POST("/invitations/accept", s.acceptOrganizationInvitation)
+
+		organizations.GET("/:orgId", s.RequirePermission(), s.getOrganization)
+		organizations.GET("/:orgId/members", s.RequirePermission(services.PermMembersRead), s.getOrganizationMembers)
+		organizations.PUT("/:orgId/members/:userId", s.RequirePermission(services.PermMembersManage), s.updateOrganizationMember)
+		organizations.DELETE("/:orgId/members/:userId", s.RequirePermission(), s.removeOrganizationMember)
+		organizations.GET("/:orgId/invitations", s.RequirePermission(services.PermMembersRead), s.getOrganizationInvitations)
+		organizations.POST("/:orgId/invitations", s.RequirePermission(services.PermMembersManage), s.inviteOrganizationMember)
+		organizations.DELETE("/:orgId/invitations/:invitationId", s.RequirePermission(services.PermMembersManage), s.revokeOrganizationInvitation)
+		organizations.GET("/:orgId/roles", s.RequirePermission(services.PermMembersRead), s.getOrganizationRoles)
+		organizations.POST("/:orgId/roles", s.RequirePermission(services.PermRolesManage), s.createOrganizationRole)
+		organizations.PUT("/:orgId/roles/:key", s.RequirePermission(services.PermRolesManage), s.updateOrganizationRole)
+		organizations.DELETE("/:orgId/roles/:key", s.RequirePermission(services.PermRolesManage), s.deleteOrganizationRole)
+		organizations.GET("/:orgId/audit-events", s.RequirePermission(services.PermMembersRead), s.getOrganizationAuditEvents)
 	}
 
 	// Invoice routes
 	invoices := api.Group("/invoices")
 	invoices.Use(s.AuthMiddleware())
 	{
-		invoices.GET("", s.getInvoices)
-		invoices.POST("", s.createInvoice)
-		invoices.GET("/:id", s.getInvoice)
-		invoices.PUT("/:id", s.updateInvoice)
-		invoices.DELETE("/:id", s.deleteInvoice)
-		invoices.POST("/:id/verify", s.verifyInvoice)
-		invoices.POST("/:id/upload", s.uploadInvoiceDocument)
+		invoices.GET("", s.RequirePermission(services.PermInvoiceRead), s.getInvoices)
+		invoices.POST("", s.RequirePermission(services.PermInvoiceCreate), s.createInvoice)
+		invoices.GET("/:id", s.RequirePermission(services.PermInvoiceRead), s.getInvoice)
+		invoices.PUT("/:id", s.RequirePermission(services.PermInvoiceUpdate), s.updateInvoice)
+		invoices.DELETE("/:id", s.RequirePermission(services.PermInvoiceDelete), s.deleteInvoice)
+		invoices.POST("/:id/verify", s.RequirePermission(services.PermInvoiceVerify), s.verifyInvoice)
+		invoices.POST("/:id/upload", s.RequirePermission(services.PermInvoiceCreate), s.uploadInvoiceDocument)
 	}
 
 	// Financing routes
 	financing := api.Group("/financing")
 	financing.Use(s.AuthMiddleware())
 	{
-		financing.GET("/requests", s.getFinancingRequests)
-		financing.POST("/requests", s.createFinancingRequest)
-		financing.GET("/requests/:id", s.getFinancingRequest)
-		financing.PUT("/requests/:id", s.updateFinancingRequest)
-		financing.POST("/requests/:id/approve", s.approveFinancingRequest)
-		financing.POST("/requests/:id/reject", s.rejectFinancingRequest)
+		financing.GET("/requests", s.RequirePermission(services.PermFinancingRead), s.getFinancingRequests)
+		financing.POST("/requests", s.RequirePermission(services.PermFinancingRequest), s.createFinancingRequest)
+		financing.GET("/requests/:id", s.RequirePermission(services.PermFinancingRead), s.getFinancingRequest)
+		financing.PUT("/requests/:id", s.RequirePermission(services.PermFinancingRequest), s.updateFinancingRequest)
+		financing.POST("/requests/:id/approve", s.RequirePermission(services.PermFinancingApprove), s.approveFinancingRequest)
+		financing.POST("/requests/:id/reject", s.RequirePermission(services.PermFinancingApprove), s.rejectFinancingRequest)
+		financing.POST("/requests/:id/repay", s.RequirePermission(services.PermPaymentInitiate), s.repayFinancingRequest)
 		
 		// Investment endpoints
-		financing.GET("/opportunities", s.getInvestmentOpportunities)
-		financing.POST("/invest", s.createInvestment)
-		financing.GET("/investments", s.getUserInvestments)
+		financing.GET("/opportunities", s.RequirePermission(services.PermInvestmentRead), s.getInvestmentOpportunities)
+		financing.POST("/invest", s.RequirePermission(services.PermInvestmentCreate), s.createInvestment)
+		financing.GET("/investments", s.RequirePermission(services.PermInvestmentRead), s.getUserInvestments)
+	}
+
+	// Maker-checker approvals: actions waiting for confirmation by a second user of the
+	// same organization who holds the action's permission
+	approvals := api.Group("/approvals")
+	approvals.Use(s.AuthMiddleware(), s.RequirePermission())
+	{
+		approvals.GET("", s.getApprovals)
+		approvals.GET("/:approvalId", s.getApproval)
+		approvals.POST("/:approvalId/approve", s.confirmApproval)
+		approvals.POST("/:approvalId/reject", s.rejectApproval)
+		approvals.POST("/:approvalId/cancel", s.cancelApproval)
 	}
 
 	// Blockchain routes
 	blockchain := api.Group("/blockchain")
 	blockchain.Use(s.AuthMiddleware())
 	{
-		blockchain.POST("/tokenize-invoice", s.tokenizeInvoice)
+		blockchain.POST("/tokenize-invoice", s.RequirePermission(services.PermInvoiceUpdate), s.tokenizeInvoice)
 		blockchain.GET("/transactions/:hash", s.getBlockchainTransaction)
 		blockchain.POST("/verify-transaction", s.verifyTransaction)
 	}
@@ -147,20 +239,83 @@ func (s *Server) setupRoutes() {
 		ai.POST("/verify-document", s.verifyDocument)
 	}
 
-	// Admin routes
+	// Webhook subscription routes
+	webhooks := api.Group("/webhooks")
+	webhooks.Use(s.AuthMiddleware(), s.RequirePermission(services.PermWebhooksManage))
+	{
+		webhooks.GET("/subscriptions", s.getWebhookSubscriptions)
+		webhooks.POST("/subscriptions", s.createWebhookSubscription)
+		webhooks.GET("/subscriptions/:id", s.getWebhookSubscription)
+		webhooks.PUT("/subscriptions/:id", s.updateWebhookSubscription)
+		webhooks.DELETE("/subscriptions/:id", s.deleteWebhookSubscription)
+		webhooks.GET("/subscriptions/:id/deliveries", s.getWebhookSubscriptionDeliveries)
+
+		webhooks.GET("/deliveries", s.getWebhookDeliveries)
+		webhooks.GET("/deliveries/dead-letter", s.getDeadLetterDeliveries)
+		webhooks.GET("/deliveries/:id", s.getWebhookDelivery)
+		webhooks.POST("/deliveries/:id/redeliver", s.redeliverWebhook)
+	}
+
+	// Partner integration routes authenticate with scoped API keys, which are issued by
+	// platform staff for one organization and only read that organization's records
+	partner := api.Group("/partner")
+	partner.Use(middleware.APIKeyAuth(s.apiKeyService))
+	{
+		partner.GET("/invoices/:id", middleware.RequireScope("invoices:read"), s.getPartnerInvoice)
+		partner.GET("/financing/requests/:id", middleware.RequireScope("financing:read"), s.getPartnerFinancingRequest)
+	}
+
+	// Sessions opened by logins in the user management service live in the same store, so
+	// a revocation on either side ends access on both. Users it provisions through an
+	// organization's SSO connection are made members of the organization here.
+	internal := api.Group("/internal")
+	internal.Use(middleware.ServiceTokenAuth(s.sessionRegistryToken))
+	{
+		internal.POST("/sessions", s.registerSession)
+		internal.GET("/sessions/:id", s.checkSession)
+		internal.POST("/sessions/:id/refresh", s.rotateSessionRefreshToken)
+		internal.POST("/sessions/:id/revoke", s.revokeRegisteredSession)
+		internal.POST("/users/:id/sessions/revoke", s.revokeRegisteredUserSessions)
+		internal.POST("/organizations/:id/members", s.provisionSSOMember)
+	}
+
+	// Admin routes need platform permissions, held by platform admins and by members of the
+	// platform organization whose role grants them
 	admin := api.Group("/admin")
-	admin.Use(s.AuthMiddleware(), s.AdminMiddleware())
+	admin.Use(s.AuthMiddleware())
 	{
-		admin.GET("/dashboard", s.getAdminDashboard)
-		admin.GET("/users", s.getAllUsers)
-		admin.GET("/transactions", s.getAllTransactions)
-		admin.POST("/users/:id/verify", s.adminVerifyUser)
-		admin.POST("/users/:id/suspend", s.suspendUser)
+		admin.GET("/dashboard", s.RequirePermission(services.PermAdminReports), s.getAdminDashboard)
+		admin.GET("/users", s.RequirePermission(services.PermAdminUsers), s.getAllUsers)
+		admin.GET("/transactions", s.RequirePermission(services.PermAdminReports), s.getAllTransactions)
+		admin.POST("/users/:id/verify", s.RequirePermission(services.PermAdminUsers), s.adminVerifyUser)
+		admin.POST("/users/:id/suspend", s.RequirePermission(services.PermAdminUsers), s.suspendUser)
+		admin.DELETE("/users/:id/sessions", s.RequirePermission(services.PermAdminUsers), s.adminRevokeUserSessions)
+		admin.GET("/users/:id/login-history", s.RequirePermission(services.PermAdminUsers), s.adminGetLoginHistory)
+		admin.POST("/users/:id/unlock", s.RequirePermission(services.PermAdminUsers), s.adminUnlockUser)
+		admin.GET("/api-keys", s.RequirePermission(services.PermAdminAPIKeys), s.getAPIKeys)
+		admin.POST("/api-keys", s.RequirePermission(services.PermAdminAPIKeys), s.createAPIKey)
+		admin.DELETE("/api-keys/:id", s.RequirePermission(services.PermAdminAPIKeys), s.revokeAPIKey)
+
+		admin.GET("/organizations", s.RequirePermission(services.PermAdminOrganization), s.adminGetOrganizations)
+		admin.POST("/organizations", s.RequirePermission(services.PermAdminOrganization), s.adminCreateOrganization)
+		admin.GET("/organizations/:id/audit-events", s.RequirePermission(services.PermAdminOrganization), s.adminGetOrganizationAuditEvents)
+
+		retention := admin.Group("/retention", s.RequirePermission(services.PermAdminRetention))
+		retention.GET("/rules", s.getRetentionRules)
+		retention.POST("/rules", s.createRetentionRule)
+		retention.PUT("/rules/:name", s.updateRetentionRule)
+		retention.GET("/holds", s.getLegalHolds)
+		retention.POST("/holds", s.placeLegalHold)
+		retention.POST("/holds/:id/release", s.releaseLegalHold)
+		retention.GET("/runs", s.getPurgeRuns)
+		retention.POST("/runs", s.runPurge)
+		retention.GET("/runs/:id", s.getPurgeRun)
+		retention.GET("/runs/:id/verify", s.verifyPurgeRun)
 	}
 
 	// Analytics routes
 	analytics := api.Group("/analytics")
-	analytics.Use(s.AuthMiddleware())
+	analytics.Use(s.AuthMiddleware(), s.RequirePermission(services.PermAnalyticsRead))
 	{
 		analytics.GET("/dashboard", s.getDashboardAnalytics)
 		analytics.GET("/portfolio", s.getPortfolioAnalytics)
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func respondSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found"})
+	case errors.Is(err, services.ErrSessionRevoked):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked", "code": "session_revoked"})
+	case errors.Is(err, services.ErrSessionExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has expired", "code": "session_expired"})
+	case errors.Is(err, services.ErrRefreshReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; the session has been revoked", "code": "session_revoked"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session operation failed"})
+	}
+}
+
+// currentSession returns the user and session set by AuthMiddleware
+func currentSession(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userIDStr, _ := c.Get("user_id")
+	sessionIDStr, _ := c.Get("session_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	sessionID, err := uuid.Parse(sessionIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, sessionID, true
+}
+
+// Session handlers
+func (s *Server) getSessions(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.sessionService.ListSessions(userID)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].UUID == sessionID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "count": len(sessions)})
+}
+
+func (s *Server) revokeSession(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := s.sessionService.RevokeSession(userID, sessionID, models.SessionRevokedByUser); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// revokeOtherSessions logs out every device except the one making the request
+func (s *Server) revokeOtherSessions(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := s.sessionService.RevokeOtherSessions(userID, sessionID)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other devices logged out", "revoked": revoked})
+}
+
+func (s *Server) adminRevokeUserSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	revoked, err := s.sessionService.RevokeAllSessions(userID, models.SessionRevokedByAdmin)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User logged out of all devices", "revoked": revoked})
+}
+
+// Internal session handlers, called by the user management service for the sessions its
+// logins open
+
+// registeredRevokeReasons are the reasons the user management service may give
+var registeredRevokeReasons = map[models.SessionRevokeReason]bool{
+	models.SessionLogout:         true,
+	models.SessionRevokedByUser:  true,
+	models.SessionRevokedByAdmin: true,
+}
+
+func (s *Server) registerSession(c *gin.Context) {
+	var req services.SessionRegistration
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := s.sessionService.RegisterSession(req)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Session already exists"})
+			return
+		}
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// checkSession validates a session the way AuthMiddleware does, for requests made to the
+// user management service
+func (s *Server) checkSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	session, err := s.sessionService.ValidateSession(sessionID)
+	if err == nil && session.UserID.String() != c.Query("user_id") {
+		err = services.ErrSessionNotFound
+	}
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) rotateSessionRefreshToken(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	var req struct {
+		RefreshToken     string `json:"refresh_token" binding:"required"`
+		NextRefreshToken string `json:"next_refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := s.sessionService.RotateRefreshToken(sessionID, req.RefreshToken, req.NextRefreshToken)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) revokeRegisteredSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	var req struct {
+		UserID uuid.UUID                  `json:"user_id" binding:"required"`
+		Reason models.SessionRevokeReason `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !registeredRevokeReasons[req.Reason] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revocation reason"})
+		return
+	}
+
+	if err := s.sessionService.RevokeSession(req.UserID, sessionID, req.Reason); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+func (s *Server) revokeRegisteredUserSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var req struct {
+		Reason models.SessionRevokeReason `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !registeredRevokeReasons[req.Reason] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revocation reason"})
+		return
+	}
+
+	revoked, err := s.sessionService.RevokeAllSessions(userID, req.Reason)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User logged out of all devices", "revoked": revoked})
+}
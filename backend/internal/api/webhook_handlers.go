@@ -0,0 +1,219 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// publishEvent queues webhook deliveries for an event. Failures are logged rather than
+// returned so the state change that raised the event is not reported as failed.
+func (s *Server) publishEvent(event models.WebhookEvent, ownerID uuid.UUID, data gin.H) {
+	if s.webhookService == nil {
+		return
+	}
+	if err := s.webhookService.Publish(event, ownerID, data); err != nil {
+		log.Printf("Failed to publish %s event: %v", event, err)
+	}
+}
+
+// publishInvoiceEvent publishes an event about the invoice behind a financing request
+func (s *Server) publishInvoiceEvent(event models.WebhookEvent, request *models.FinancingRequest, extra gin.H) {
+	data := gin.H{"financing_request": request}
+	if invoice, err := s.invoiceService.GetByID(request.InvoiceID); err == nil {
+		data["invoice"] = invoice
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+	s.publishEvent(event, request.UserID, data)
+}
+
+// webhookActor returns the user and whether they may manage every subscription
+func webhookActor(c *gin.Context) (uuid.UUID, bool) {
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	return userID, hasPermission(c, services.PermAdminWebhooks)
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook subscription not found"})
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+	case errors.Is(err, services.ErrDeliveryInFlight):
+		c.JSON(http.StatusConflict, gin.H{"error": "Delivery is still pending or in flight"})
+	case errors.Is(err, services.ErrInvalidSubscription):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook operation failed"})
+	}
+}
+
+// Webhook subscription handlers
+func (s *Server) getWebhookSubscriptions(c *gin.Context) {
+	userID, _ := webhookActor(c)
+
+	subscriptions, err := s.webhookService.ListSubscriptions(userID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions, "supported_events": services.SupportedWebhookEvents})
+}
+
+func (s *Server) createWebhookSubscription(c *gin.Context) {
+	userID, isAdmin := webhookActor(c)
+
+	var input services.SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, secret, err := s.webhookService.CreateSubscription(userID, isAdmin, input)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	// The secret is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{"subscription": subscription, "secret": secret})
+}
+
+func (s *Server) getWebhookSubscription(c *gin.Context) {
+	subscriptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	userID, isAdmin := webhookActor(c)
+
+	subscription, err := s.webhookService.GetSubscription(subscriptionID, userID, isAdmin)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (s *Server) updateWebhookSubscription(c *gin.Context) {
+	subscriptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	userID, isAdmin := webhookActor(c)
+
+	var input services.SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, err := s.webhookService.UpdateSubscription(subscriptionID, userID, isAdmin, input)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (s *Server) deleteWebhookSubscription(c *gin.Context) {
+	subscriptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	userID, isAdmin := webhookActor(c)
+
+	if err := s.webhookService.DeleteSubscription(subscriptionID, userID, isAdmin); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook subscription deleted successfully"})
+}
+
+func (s *Server) getWebhookSubscriptionDeliveries(c *gin.Context) {
+	subscriptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	userID, isAdmin := webhookActor(c)
+
+	if _, err := s.webhookService.GetSubscription(subscriptionID, userID, isAdmin); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	s.listWebhookDeliveries(c, &subscriptionID, c.Query("status"))
+}
+
+// Webhook delivery handlers
+func (s *Server) getWebhookDeliveries(c *gin.Context) {
+	s.listWebhookDeliveries(c, nil, c.Query("status"))
+}
+
+func (s *Server) getDeadLetterDeliveries(c *gin.Context) {
+	s.listWebhookDeliveries(c, nil, string(models.WebhookDeliveryDeadLetter))
+}
+
+func (s *Server) listWebhookDeliveries(c *gin.Context, subscriptionID *uuid.UUID, status string) {
+	userID, isAdmin := webhookActor(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	deliveries, err := s.webhookService.ListDeliveries(userID, isAdmin, subscriptionID, status, limit)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "count": len(deliveries)})
+}
+
+func (s *Server) getWebhookDelivery(c *gin.Context) {
+	deliveryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	userID, isAdmin := webhookActor(c)
+
+	delivery, err := s.webhookService.GetDelivery(deliveryID, userID, isAdmin)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (s *Server) redeliverWebhook(c *gin.Context) {
+	deliveryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	userID, isAdmin := webhookActor(c)
+
+	delivery, err := s.webhookService.Redeliver(deliveryID, userID, isAdmin)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued for redelivery", "delivery": delivery})
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -18,6 +20,70 @@ type Config struct {
 	RedisURL                 string
 	Environment              string
 	Port                     int
+
+	// Outbound webhooks
+	WebhookMaxAttempts       int
+	WebhookBackoffBase       time.Duration
+	WebhookBackoffMax        time.Duration
+	WebhookTimeout           time.Duration
+	WebhookWorkerInterval    time.Duration
+	WebhookLeaseDuration     time.Duration
+	OverdueCheckInterval     time.Duration
+
+	// Partner API keys
+	APIKeyDefaultRateLimit   int
+	APIKeyLastUsedResolution time.Duration
+
+	// Data retention
+	RetentionEnabled         bool
+	RetentionRunInterval     time.Duration
+	RetentionBatchSize       int
+	RetentionCertificatePath string        // Signs purge reports
+	RetentionSigningKeyPath  string        // Empty when the key follows the certificate in its file
+	DataRetentionPeriod      time.Duration // Financial records
+	DeletedRecordRetention   time.Duration // Soft-deleted records
+
+	// Sessions
+	AccessTokenTTL           time.Duration
+	RefreshTokenTTL          time.Duration
+	SessionIdleTimeout       time.Duration
+	SessionAbsoluteTimeout   time.Duration
+	SessionCacheTTL          time.Duration // How long another instance may take to see a revocation
+	SessionMaxPerRole        map[string]int
+	SessionMaxDefault        int
+	SessionLocationHeader    string // Header set by the edge proxy with the client's location
+	SessionRegistryToken     string // Service token the user management service registers and revokes its sessions with
+
+	// Login lockout and risk scoring
+	LoginMaxAttempts         int           // Wrong passwords before the account is locked
+	LoginLockoutBase         time.Duration // Doubles with each consecutive lockout
+	LoginLockoutMax          time.Duration
+	LoginRiskThreshold       int           // Risk score that requires an emailed confirmation code
+	LoginMaxTravelSpeed      int           // km/h between logins above which travel is impossible
+	LoginStuffingWindow      time.Duration
+	LoginStuffingAccounts    int
+	LoginConfirmationTTL     time.Duration
+	GeoIPDatabasePath        string        // GeoLite2/GeoIP2 City .mmdb file
+
+	// Notification service, for security alerts
+	NotificationServiceURL   string
+	NotificationServiceToken string
+
+	// KYB (company verification) and customer KYC in the user management service, which
+	// gate financing and investment
+	KYBServiceURL            string
+	KYBServiceToken          string        // Service token with the bank or admin role
+	KYBRequired              bool          // Refuse financing of companies that are not verified
+	KYCRequired              bool          // Refuse financing and investment while a customer's KYC is not current
+	ScreeningRequired        bool          // Refuse financing against invoice buyers that have not cleared sanctions screening
+
+	// Organizations
+	OrganizationInvitationTTL time.Duration
+	OrganizationInvitationURL string // Link emailed with invitations; the token is appended
+
+	// Maker-checker approval of sensitive actions
+	DualControlWindow         time.Duration  // How long an action waits for confirmation
+	DualControlFinancingTiers map[string]int // Confirmations needed from each financing amount upwards
 }
 
 func Load() *Config {
@@ -36,6 +102,60 @@ func Load() *Config {
 		RedisURL:                 getEnv("REDIS_URL", "redis://localhost:6379"),
 		Environment:              getEnv("ENVIRONMENT", "development"),
 		Port:                     port,
+
+		WebhookMaxAttempts:       getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase:       getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookBackoffMax:        getEnvDuration("WEBHOOK_BACKOFF_MAX", 6*time.Hour),
+		WebhookTimeout:           getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookWorkerInterval:    getEnvDuration("WEBHOOK_WORKER_INTERVAL", 5*time.Second),
+		WebhookLeaseDuration:     getEnvDuration("WEBHOOK_LEASE_DURATION", time.Minute),
+		OverdueCheckInterval:     getEnvDuration("OVERDUE_CHECK_INTERVAL", time.Hour),
+
+		APIKeyDefaultRateLimit:   getEnvInt("API_KEY_DEFAULT_RATE_LIMIT", 60),
+		APIKeyLastUsedResolution: getEnvDuration("API_KEY_LAST_USED_RESOLUTION", time.Minute),
+
+		RetentionEnabled:         getEnvBool("RETENTION_ENABLED", true),
+		RetentionRunInterval:     getEnvDuration("RETENTION_RUN_INTERVAL", 24*time.Hour),
+		RetentionBatchSize:       getEnvInt("RETENTION_BATCH_SIZE", 500),
+		RetentionCertificatePath: getEnv("RETENTION_CERT_PATH", ""),
+		RetentionSigningKeyPath:  getEnv("RETENTION_SIGNING_KEY_PATH", ""),
+		DataRetentionPeriod:      getEnvDuration("DATA_RETENTION_PERIOD", 7*365*24*time.Hour), // 7 years
+		DeletedRecordRetention:   getEnvDuration("DELETED_RECORD_RETENTION", 90*24*time.Hour),
+
+		AccessTokenTTL:           getEnvDuration("JWT_EXPIRY", 24*time.Hour),
+		RefreshTokenTTL:          getEnvDuration("JWT_REFRESH_EXPIRY", 7*24*time.Hour),
+		SessionIdleTimeout:       getEnvDuration("SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		SessionAbsoluteTimeout:   getEnvDuration("SESSION_ABSOLUTE_TIMEOUT", 7*24*time.Hour),
+		SessionCacheTTL:          getEnvDuration("SESSION_CACHE_TTL", 30*time.Second),
+		SessionMaxPerRole:        getEnvIntMap("SESSION_MAX_PER_ROLE", map[string]int{"admin": 2, "sme": 5, "investor": 5}),
+		SessionMaxDefault:        getEnvInt("SESSION_MAX_DEFAULT", 5),
+		SessionLocationHeader:    getEnv("SESSION_LOCATION_HEADER", ""),
+		SessionRegistryToken:     getEnv("SESSION_REGISTRY_TOKEN", ""),
+
+		LoginMaxAttempts:         getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockoutBase:         getEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:          getEnvDuration("LOGIN_LOCKOUT_MAX", 24*time.Hour),
+		LoginRiskThreshold:       getEnvInt("LOGIN_RISK_THRESHOLD", 50),
+		LoginMaxTravelSpeed:      getEnvInt("LOGIN_MAX_TRAVEL_SPEED", 900),
+		LoginStuffingWindow:      getEnvDuration("LOGIN_STUFFING_WINDOW", 15*time.Minute),
+		LoginStuffingAccounts:    getEnvInt("LOGIN_STUFFING_ACCOUNTS", 5),
+		LoginConfirmationTTL:     getEnvDuration("LOGIN_CONFIRMATION_TTL", 10*time.Minute),
+		GeoIPDatabasePath:        getEnv("GEOIP_DATABASE_PATH", ""),
+
+		NotificationServiceURL:   getEnv("NOTIFICATION_SERVICE_URL", ""),
+		NotificationServiceToken: getEnv("NOTIFICATION_SERVICE_TOKEN", ""),
+
+		KYBServiceURL:            getEnv("KYB_SERVICE_URL", "http://localhost:8081"),
+		KYBServiceToken:          getEnv("KYB_SERVICE_TOKEN", ""),
+		KYBRequired:              getEnvBool("KYB_REQUIRED", true),
+		KYCRequired:              getEnvBool("KYC_REQUIRED", true),
+		ScreeningRequired:        getEnvBool("SCREENING_REQUIRED", true),
+
+		OrganizationInvitationTTL: getEnvDuration("ORGANIZATION_INVITATION_TTL", 7*24*time.Hour),
+		OrganizationInvitationURL: getEnv("ORGANIZATION_INVITATION_URL", "http://localhost:3000/invitations/accept?token="),
+
+		DualControlWindow:         getEnvDuration("DUAL_CONTROL_WINDOW", 24*time.Hour),
+		DualControlFinancingTiers: getEnvIntMap("DUAL_CONTROL_FINANCING_TIERS", map[string]int{"0": 1, "1000000": 2}),
 	}
 }
 
@@ -45,3 +165,46 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvIntMap parses a list such as "admin=2,sme=5"
+func getEnvIntMap(key string, defaultValue map[string]int) map[string]int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		name, limit, ok := strings.Cut(pair, "=")
+		if n, err := strconv.Atoi(strings.TrimSpace(limit)); ok && err == nil {
+			parsed[strings.TrimSpace(name)] = n
+		}
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -49,7 +50,7 @@ func RunMigrations(db *MongoDB) error {
 	// Create indexes for Users collection
 	userCollection := db.Database.Collection("users")
 	_, err := userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys: map[string]int{"email": 1},
+		Keys:    map[string]int{"email": 1},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
@@ -92,6 +93,189 @@ func RunMigrations(db *MongoDB) error {
 		log.Printf("Warning: Could not create transaction index: %v", err)
 	}
 
+	// Create indexes for webhook subscriptions and deliveries. Compound keys use bson.D
+	// because the field order matters.
+	subscriptionCollection := db.Database.Collection("webhook_subscriptions")
+	_, err = subscriptionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "events", Value: 1}, {Key: "active", Value: 1}}},
+		{Keys: map[string]int{"user_id": 1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create webhook subscription indexes: %v", err)
+	}
+
+	deliveryCollection := db.Database.Collection("webhook_deliveries")
+	_, err = deliveryCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "locked_until", Value: 1}}},
+		{Keys: bson.D{{Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create webhook delivery indexes: %v", err)
+	}
+
+	// Attempt logs no longer keep endpoint response bodies; drop the ones stored earlier
+	_, err = deliveryCollection.UpdateMany(ctx,
+		bson.M{"attempt_log.response_body": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"attempt_log.$[].response_body": ""}})
+	if err != nil {
+		log.Printf("Warning: Could not clear stored webhook responses: %v", err)
+	}
+
+	// Create indexes for API keys
+	apiKeyCollection := db.Database.Collection("api_keys")
+	_, err = apiKeyCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"key_hash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create API key indexes: %v", err)
+	}
+
+	// Create indexes for data retention. Soft-deleted records are found by deleted_at;
+	// the partial unique index on running purge runs allows one run at a time.
+	for _, name := range []string{"users", "invoices"} {
+		_, err = db.Database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    map[string]int{"deleted_at": 1},
+			Options: options.Index().SetSparse(true),
+		})
+		if err != nil {
+			log.Printf("Warning: Could not create %s deleted_at index: %v", name, err)
+		}
+	}
+
+	_, err = db.Database.Collection("retention_rules").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]int{"name": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create retention rule index: %v", err)
+	}
+
+	_, err = db.Database.Collection("legal_holds").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: map[string]int{"released_at": 1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create legal hold indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("purge_runs").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: map[string]int{"started_at": -1}},
+		{Keys: map[string]int{"status": 1}, Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": "running"})},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create purge run indexes: %v", err)
+	}
+
+	// Create indexes for sessions. Ended sessions are kept for the session history until
+	// retention removes them.
+	_, err = db.Database.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "is_active", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create session indexes: %v", err)
+	}
+
+	// Create indexes for login security. Attempts are looked up per account for history
+	// and lockouts and per IP address to spot credential stuffing.
+	_, err = db.Database.Collection("login_attempts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "attempted_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ip_address", Value: 1}, {Key: "attempted_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create login attempt indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("known_devices").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "fingerprint", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create known device indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("login_confirmations").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"token_hash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: map[string]int{"expires_at": 1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create login confirmation indexes: %v", err)
+	}
+
+	// Create indexes for organizations. A user belongs to an organization at most once and
+	// custom role keys are unique within an organization.
+	_, err = db.Database.Collection("organizations").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]int{"uuid": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create organization indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("organization_memberships").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "joined_at", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create membership indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("organization_roles").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create organization role indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("organization_invitations").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: map[string]int{"token_hash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "email", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create invitation indexes: %v", err)
+	}
+
+	_, err = db.Database.Collection("membership_audit_events").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create membership audit indexes: %v", err)
+	}
+
+	for _, name := range []string{"invoices", "financing_requests"} {
+		_, err = db.Database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: map[string]int{"organization_id": 1},
+		})
+		if err != nil {
+			log.Printf("Warning: Could not create %s organization index: %v", name, err)
+		}
+	}
+
+	// The partial unique index allows one pending approval per action and resource
+	_, err = db.Database.Collection("pending_approvals").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: map[string]int{"uuid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "resource_id", Value: 1}}, Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": "pending"})},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create approval indexes: %v", err)
+	}
+
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
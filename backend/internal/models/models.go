@@ -26,6 +26,12 @@ type User struct {
 	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`
 	DeletedAt         *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	// AnonymizedAt is set when retention scrubbed the user's personal data
+	AnonymizedAt      *time.Time         `json:"anonymized_at,omitempty" bson:"anonymized_at,omitempty"`
+	// Password login lockout; each consecutive lockout doubles the next one
+	FailedLogins      int                `json:"-" bson:"failed_logins"`
+	LockedUntil       *time.Time         `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	LockoutCount      int                `json:"-" bson:"lockout_count"`
 }
 
 type UserRole string
@@ -40,6 +46,7 @@ type Invoice struct {
 	ID                  primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UUID                uuid.UUID          `json:"uuid" bson:"uuid"`
 	UserID              uuid.UUID          `json:"user_id" bson:"user_id"`
+	OrganizationID      *uuid.UUID         `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
 	InvoiceNumber       string             `json:"invoice_number" bson:"invoice_number"`
 	CustomerName        string             `json:"customer_name" bson:"customer_name"`
 	CustomerEmail       string             `json:"customer_email" bson:"customer_email"`
@@ -51,6 +58,7 @@ type Invoice struct {
 	DocumentURL         string             `json:"document_url" bson:"document_url"`
 	VerificationStatus  VerificationStatus `json:"verification_status" bson:"verification_status"`
 	AIRiskScore         float64            `json:"ai_risk_score" bson:"ai_risk_score"`
+	BuyerScreening      string             `json:"buyer_screening_status,omitempty" bson:"buyer_screening_status,omitempty"` // Latest sanctions screening of the buyer
 	FabricTxID          string             `json:"fabric_tx_id" bson:"fabric_tx_id"`
 	AssetID             string             `json:"asset_id" bson:"asset_id"`
 	CreatedAt           time.Time          `json:"created_at" bson:"created_at"`
@@ -83,6 +91,7 @@ type FinancingRequest struct {
 	UUID              uuid.UUID          `json:"uuid" bson:"uuid"`
 	InvoiceID         uuid.UUID          `json:"invoice_id" bson:"invoice_id"`
 	UserID            uuid.UUID          `json:"user_id" bson:"user_id"`
+	OrganizationID    *uuid.UUID         `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
 	RequestedAmount   float64            `json:"requested_amount" bson:"requested_amount"`
 	InterestRate      float64            `json:"interest_rate" bson:"interest_rate"`
 	FinancingFee      float64            `json:"financing_fee" bson:"financing_fee"`
@@ -90,6 +99,7 @@ type FinancingRequest struct {
 	Status            FinancingStatus    `json:"status" bson:"status"`
 	Description       string             `json:"description" bson:"description"`
 	ExpectedReturn    float64            `json:"expected_return" bson:"expected_return"`
+	RepaidAmount      float64            `json:"repaid_amount" bson:"repaid_amount"`
 	RiskLevel         RiskLevel          `json:"risk_level" bson:"risk_level"`
 	ApprovedAt        *time.Time         `json:"approved_at" bson:"approved_at,omitempty"`
 	FundedAt          *time.Time         `json:"funded_at" bson:"funded_at,omitempty"`
@@ -181,3 +191,402 @@ const (
 	TransactionStatusConfirmed TransactionStatus = "confirmed"
 	TransactionStatusFailed    TransactionStatus = "failed"
 )
+
+// WebhookEvent names an event partners can subscribe to
+type WebhookEvent string
+
+const (
+	WebhookEventInvoiceVerified WebhookEvent = "invoice.verified"
+	WebhookEventInvoiceFunded   WebhookEvent = "invoice.funded"
+	WebhookEventInvoiceRepaid   WebhookEvent = "invoice.repaid"
+	WebhookEventInvoiceOverdue  WebhookEvent = "invoice.overdue"
+)
+
+type WebhookSubscription struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID        uuid.UUID          `json:"uuid" bson:"uuid"`
+	UserID      uuid.UUID          `json:"user_id" bson:"user_id"`
+	URL         string             `json:"url" bson:"url"`
+	Events      []WebhookEvent     `json:"events" bson:"events"`
+	Secret      string             `json:"-" bson:"secret"`
+	Description string             `json:"description" bson:"description"`
+	// AllInvoices subscriptions, created by admins, receive events for every invoice
+	AllInvoices bool               `json:"all_invoices" bson:"all_invoices"`
+	Active      bool               `json:"active" bson:"active"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID              primitive.ObjectID    `json:"id" bson:"_id,omitempty"`
+	UUID            uuid.UUID             `json:"uuid" bson:"uuid"`
+	SubscriptionID  uuid.UUID             `json:"subscription_id" bson:"subscription_id"`
+	UserID          uuid.UUID             `json:"user_id" bson:"user_id"`
+	EventID         uuid.UUID             `json:"event_id" bson:"event_id"`
+	Event           WebhookEvent          `json:"event" bson:"event"`
+	URL             string                `json:"url" bson:"url"`
+	Payload         string                `json:"payload" bson:"payload"`
+	Status          WebhookDeliveryStatus `json:"status" bson:"status"`
+	Attempts        int                   `json:"attempts" bson:"attempts"`
+	NextAttemptAt   time.Time             `json:"next_attempt_at" bson:"next_attempt_at"`
+	LockedBy        string                `json:"-" bson:"locked_by,omitempty"`
+	LockedUntil     *time.Time            `json:"-" bson:"locked_until,omitempty"`
+	LastStatusCode  int                   `json:"last_status_code" bson:"last_status_code"`
+	LastError       string                `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	AttemptLog      []WebhookAttempt      `json:"attempt_log" bson:"attempt_log"`
+	DeliveredAt     *time.Time            `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	DeadLetteredAt  *time.Time            `json:"dead_lettered_at,omitempty" bson:"dead_lettered_at,omitempty"`
+	CreatedAt       time.Time             `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at" bson:"updated_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending    WebhookDeliveryStatus = "pending"
+	WebhookDeliveryInFlight   WebhookDeliveryStatus = "delivering"
+	WebhookDeliverySucceeded  WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryDeadLetter WebhookDeliveryStatus = "dead_letter"
+)
+
+// WebhookAttempt is one entry of a delivery's log
+type WebhookAttempt struct {
+	Attempt     int       `json:"attempt" bson:"attempt"`
+	AttemptedAt time.Time `json:"attempted_at" bson:"attempted_at"`
+	StatusCode  int       `json:"status_code" bson:"status_code"`
+	DurationMs  int64     `json:"duration_ms" bson:"duration_ms"`
+	Error       string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// APIKey is a partner API key. Only a SHA-256 hash of the key is stored; the prefix
+// identifies the key in listings and logs.
+type APIKey struct {
+	ID                 primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID               uuid.UUID          `json:"uuid" bson:"uuid"`
+	OrganizationID     string             `json:"organization_id" bson:"organization_id"`
+	Name               string             `json:"name" bson:"name"`
+	Prefix             string             `json:"prefix" bson:"prefix"`
+	KeyHash            string             `json:"-" bson:"key_hash"`
+	Scopes             []string           `json:"scopes" bson:"scopes"`
+	RateLimitPerMinute int                `json:"rate_limit_per_minute" bson:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt         *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	LastUsedIP         string             `json:"last_used_ip,omitempty" bson:"last_used_ip,omitempty"`
+	CreatedBy          uuid.UUID          `json:"created_by" bson:"created_by"`
+	RevokedAt          *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedBy          *uuid.UUID         `json:"revoked_by,omitempty" bson:"revoked_by,omitempty"`
+	CreatedAt          time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// RetentionAction is what a retention rule does to expired records
+type RetentionAction string
+
+const (
+	RetentionActionPurge     RetentionAction = "purge"     // Delete the record
+	RetentionActionAnonymize RetentionAction = "anonymize" // Scrub personal data, keep the record
+)
+
+// RetentionRule expires the records of one collection. A record expires RetentionDays
+// after its Basis timestamp; Statuses, when set, limits the rule to records in those
+// statuses.
+type RetentionRule struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name          string             `json:"name" bson:"name"`
+	Entity        string             `json:"entity" bson:"entity"`
+	Basis         string             `json:"basis" bson:"basis"` // created_at, updated_at, deleted_at
+	Statuses      []string           `json:"statuses,omitempty" bson:"statuses,omitempty"`
+	RetentionDays int                `json:"retention_days" bson:"retention_days"`
+	Action        RetentionAction    `json:"action" bson:"action"`
+	Enabled       bool               `json:"enabled" bson:"enabled"`
+	Description   string             `json:"description" bson:"description"`
+	UpdatedBy     *uuid.UUID         `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// LegalHold blocks retention from touching a record, or everything belonging to a user,
+// until it is released. An empty Entity applies the hold to every collection.
+type LegalHold struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID          uuid.UUID          `json:"uuid" bson:"uuid"`
+	Entity        string             `json:"entity,omitempty" bson:"entity,omitempty"`
+	RecordID      *uuid.UUID         `json:"record_id,omitempty" bson:"record_id,omitempty"`
+	SubjectUserID *uuid.UUID         `json:"subject_user_id,omitempty" bson:"subject_user_id,omitempty"`
+	Reason        string             `json:"reason" bson:"reason"`
+	CaseReference string             `json:"case_reference,omitempty" bson:"case_reference,omitempty"`
+	PlacedBy      uuid.UUID          `json:"placed_by" bson:"placed_by"`
+	ReleasedAt    *time.Time         `json:"released_at,omitempty" bson:"released_at,omitempty"`
+	ReleasedBy    *uuid.UUID         `json:"released_by,omitempty" bson:"released_by,omitempty"`
+	ReleaseNote   string             `json:"release_note,omitempty" bson:"release_note,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type PurgeRunStatus string
+
+const (
+	PurgeRunRunning   PurgeRunStatus = "running"
+	PurgeRunCompleted PurgeRunStatus = "completed"
+	PurgeRunFailed    PurgeRunStatus = "failed"
+	PurgeRunAbandoned PurgeRunStatus = "abandoned" // The process died mid-run
+)
+
+// PurgeRun records one retention run. Report is the canonical JSON purge report and
+// Signature its signature by the retention certificate's key, named by
+// SignatureAlgorithm; CertificateFingerprint is the SHA-256 of that certificate.
+type PurgeRun struct {
+	ID                     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID                   uuid.UUID          `json:"uuid" bson:"uuid"`
+	Status                 PurgeRunStatus     `json:"status" bson:"status"`
+	DryRun                 bool               `json:"dry_run" bson:"dry_run"`
+	TriggeredBy            string             `json:"triggered_by" bson:"triggered_by"`
+	Purged                 int64              `json:"purged" bson:"purged"`
+	Anonymized             int64              `json:"anonymized" bson:"anonymized"`
+	Held                   int64              `json:"held" bson:"held"`
+	Error                  string             `json:"error,omitempty" bson:"error,omitempty"`
+	Report                 string             `json:"report,omitempty" bson:"report,omitempty"`
+	ReportSHA256           string             `json:"report_sha256,omitempty" bson:"report_sha256,omitempty"`
+	Signature              string             `json:"signature,omitempty" bson:"signature,omitempty"`
+	SignatureAlgorithm     string             `json:"signature_algorithm,omitempty" bson:"signature_algorithm,omitempty"`
+	CertificateFingerprint string             `json:"certificate_fingerprint,omitempty" bson:"certificate_fingerprint,omitempty"`
+	StartedAt              time.Time          `json:"started_at" bson:"started_at"`
+	CompletedAt            *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+
+// SessionRevokeReason records why a session stopped working
+type SessionRevokeReason string
+
+const (
+	SessionLogout          SessionRevokeReason = "logout"
+	SessionRevokedByUser   SessionRevokeReason = "revoked" // From the session list or "log out other devices"
+	SessionRevokedByAdmin  SessionRevokeReason = "admin"
+	SessionIdleTimeout     SessionRevokeReason = "idle_timeout"
+	SessionAbsoluteTimeout SessionRevokeReason = "absolute_timeout"
+	SessionLimitExceeded   SessionRevokeReason = "session_limit" // Evicted by a newer login
+	SessionRefreshReuse    SessionRevokeReason = "refresh_reuse" // A rotated refresh token was presented again
+)
+
+// Session is one signed-in device. Access and refresh tokens carry its UUID and stop
+// working as soon as it is revoked or times out.
+type Session struct {
+	ID               primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UUID             uuid.UUID           `json:"uuid" bson:"uuid"`
+	UserID           uuid.UUID           `json:"user_id" bson:"user_id"`
+	Role             string              `json:"role" bson:"role"`
+	RefreshTokenHash string              `json:"-" bson:"refresh_token_hash"` // Of the one refresh token currently valid
+	DeviceInfo       string              `json:"device_info" bson:"device_info"`
+	UserAgent        string              `json:"user_agent" bson:"user_agent"`
+	IPAddress        string              `json:"ip_address" bson:"ip_address"`
+	Location         string              `json:"location,omitempty" bson:"location,omitempty"`
+	IsActive         bool                `json:"is_active" bson:"is_active"`
+	CreatedAt        time.Time           `json:"created_at" bson:"created_at"`
+	LastSeenAt       time.Time           `json:"last_seen_at" bson:"last_seen_at"`
+	ExpiresAt        time.Time           `json:"expires_at" bson:"expires_at"` // Absolute timeout
+	RevokedAt        *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedReason    SessionRevokeReason `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty"`
+	Current          bool                `json:"current" bson:"-"` // The session making the request
+}
+
+
+// LoginAttempt records one password login, including failures and blocked attempts
+type LoginAttempt struct {
+	ID                 primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID               uuid.UUID          `json:"uuid" bson:"uuid"`
+	UserID             *uuid.UUID         `json:"user_id,omitempty" bson:"user_id,omitempty"` // Unset for unknown accounts
+	Email              string             `json:"email" bson:"email"`
+	IPAddress          string             `json:"ip_address" bson:"ip_address"`
+	UserAgent          string             `json:"user_agent" bson:"user_agent"`
+	Success            bool               `json:"success" bson:"success"`
+	FailReason         string             `json:"fail_reason,omitempty" bson:"fail_reason,omitempty"`
+	Country            string             `json:"country,omitempty" bson:"country,omitempty"` // From the local GeoIP database
+	City               string             `json:"city,omitempty" bson:"city,omitempty"`
+	Latitude           *float64           `json:"latitude,omitempty" bson:"latitude,omitempty"`
+	Longitude          *float64           `json:"longitude,omitempty" bson:"longitude,omitempty"`
+	DeviceID           *uuid.UUID         `json:"device_id,omitempty" bson:"device_id,omitempty"` // Known device, set on success
+	RiskScore          int                `json:"risk_score" bson:"risk_score"`                   // 0-100
+	RiskFactors        []string           `json:"risk_factors" bson:"risk_factors"`
+	SuspiciousActivity bool               `json:"suspicious_activity" bson:"suspicious_activity"`
+	AttemptedAt        time.Time          `json:"attempted_at" bson:"attempted_at"`
+}
+
+// KnownDevice is a browser or app a user has signed in from
+type KnownDevice struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID        uuid.UUID          `json:"uuid" bson:"uuid"`
+	UserID      uuid.UUID          `json:"user_id" bson:"user_id"`
+	Fingerprint string             `json:"-" bson:"fingerprint"` // SHA-256 of the user agent without version numbers
+	Label       string             `json:"label" bson:"label"`
+	LastIP      string             `json:"last_ip" bson:"last_ip"`
+	LastCountry string             `json:"last_country,omitempty" bson:"last_country,omitempty"`
+	FirstSeenAt time.Time          `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt  time.Time          `json:"last_seen_at" bson:"last_seen_at"`
+}
+
+// LoginConfirmation is a risky login waiting for the code emailed to the user
+type LoginConfirmation struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID        uuid.UUID          `json:"uuid" bson:"uuid"`
+	UserID      uuid.UUID          `json:"user_id" bson:"user_id"`
+	TokenHash   string             `json:"-" bson:"token_hash"` // Of the confirmation token given to the client
+	CodeHash    string             `json:"-" bson:"code_hash"`
+	Attempts    int                `json:"attempts" bson:"attempts"`
+	IPAddress   string             `json:"ip_address" bson:"ip_address"`
+	UserAgent   string             `json:"user_agent" bson:"user_agent"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"`
+	ConfirmedAt *time.Time         `json:"confirmed_at,omitempty" bson:"confirmed_at,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// OrganizationType limits which permissions an organization's roles may grant
+type OrganizationType string
+
+const (
+	OrganizationSME      OrganizationType = "sme"
+	OrganizationInvestor OrganizationType = "investor"
+	OrganizationBank     OrganizationType = "bank"     // Lenders deciding on and paying out financing
+	OrganizationPlatform OrganizationType = "platform" // The platform operator's own staff
+)
+
+// Organization is a company whose staff share invoices, financing and settings
+type Organization struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID      uuid.UUID          `json:"uuid" bson:"uuid"`
+	Name      string             `json:"name" bson:"name"`
+	Type      OrganizationType   `json:"type" bson:"type"`
+	TaxID     string             `json:"tax_id,omitempty" bson:"tax_id,omitempty"`
+	CreatedBy uuid.UUID          `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// OrganizationRole is a custom role defined by an organization. Built-in roles are not
+// stored; see services.BuiltinOrganizationRoles.
+type OrganizationRole struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID           uuid.UUID          `json:"uuid" bson:"uuid"`
+	OrganizationID uuid.UUID          `json:"organization_id" bson:"organization_id"`
+	Key            string             `json:"key" bson:"key"` // Referenced by memberships and invitations
+	Name           string             `json:"name" bson:"name"`
+	Description    string             `json:"description,omitempty" bson:"description,omitempty"`
+	Permissions    []string           `json:"permissions" bson:"permissions"`
+	Builtin        bool               `json:"builtin" bson:"-"`
+	CreatedBy      uuid.UUID          `json:"created_by" bson:"created_by"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// OrganizationMembership gives a user a role in an organization
+type OrganizationMembership struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID           uuid.UUID          `json:"uuid" bson:"uuid"`
+	OrganizationID uuid.UUID          `json:"organization_id" bson:"organization_id"`
+	UserID         uuid.UUID          `json:"user_id" bson:"user_id"`
+	Role           string             `json:"role" bson:"role"`
+	InvitedBy      *uuid.UUID         `json:"invited_by,omitempty" bson:"invited_by,omitempty"`
+	JoinedAt       time.Time          `json:"joined_at" bson:"joined_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// OrganizationInvitation asks someone to join an organization. Only a SHA-256 hash of the
+// emailed token is stored.
+type OrganizationInvitation struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID           uuid.UUID          `json:"uuid" bson:"uuid"`
+	OrganizationID uuid.UUID          `json:"organization_id" bson:"organization_id"`
+	Email          string             `json:"email" bson:"email"`
+	Role           string             `json:"role" bson:"role"`
+	TokenHash      string             `json:"-" bson:"token_hash"`
+	InvitedBy      uuid.UUID          `json:"invited_by" bson:"invited_by"`
+	ExpiresAt      time.Time          `json:"expires_at" bson:"expires_at"`
+	AcceptedAt     *time.Time         `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	AcceptedBy     *uuid.UUID         `json:"accepted_by,omitempty" bson:"accepted_by,omitempty"`
+	RevokedAt      *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedBy      *uuid.UUID         `json:"revoked_by,omitempty" bson:"revoked_by,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// MembershipAuditAction names a change to an organization's members or roles
+type MembershipAuditAction string
+
+const (
+	AuditOrganizationCreated MembershipAuditAction = "organization_created"
+	AuditMemberInvited       MembershipAuditAction = "member_invited"
+	AuditInvitationRevoked   MembershipAuditAction = "invitation_revoked"
+	AuditMemberJoined        MembershipAuditAction = "member_joined"
+	AuditMemberRoleChanged   MembershipAuditAction = "member_role_changed"
+	AuditMemberRemoved       MembershipAuditAction = "member_removed"
+	AuditRoleCreated         MembershipAuditAction = "role_created"
+	AuditRoleUpdated         MembershipAuditAction = "role_updated"
+	AuditRoleDeleted         MembershipAuditAction = "role_deleted"
+)
+
+// MembershipAuditEvent records who changed an organization's members or roles. Events are
+// never updated.
+type MembershipAuditEvent struct {
+	ID             primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	UUID           uuid.UUID              `json:"uuid" bson:"uuid"`
+	OrganizationID uuid.UUID              `json:"organization_id" bson:"organization_id"`
+	Action         MembershipAuditAction  `json:"action" bson:"action"`
+	ActorID        uuid.UUID              `json:"actor_id" bson:"actor_id"`
+	SubjectUserID  *uuid.UUID             `json:"subject_user_id,omitempty" bson:"subject_user_id,omitempty"`
+	SubjectEmail   string                 `json:"subject_email,omitempty" bson:"subject_email,omitempty"`
+	Role           string                 `json:"role,omitempty" bson:"role,omitempty"`
+	PreviousRole   string                 `json:"previous_role,omitempty" bson:"previous_role,omitempty"`
+	Details        map[string]interface{} `json:"details,omitempty" bson:"details,omitempty"`
+	IPAddress      string                 `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	CreatedAt      time.Time              `json:"created_at" bson:"created_at"`
+}
+
+// ApprovalAction names a sensitive action that needs a second person to confirm it
+type ApprovalAction string
+
+const (
+	ApprovalFinancingApproval ApprovalAction = "financing_approval"
+)
+
+// ApprovalStatus tracks a pending approval
+type ApprovalStatus string
+
+const (
+	ApprovalPending   ApprovalStatus = "pending"
+	ApprovalApproved  ApprovalStatus = "approved"  // Confirmed by enough checkers, being carried out
+	ApprovalExecuted  ApprovalStatus = "executed"  // Confirmed by enough checkers and carried out
+	ApprovalRejected  ApprovalStatus = "rejected"  // Refused by a checker
+	ApprovalCancelled ApprovalStatus = "cancelled" // Withdrawn by the initiator
+	ApprovalExpired   ApprovalStatus = "expired"   // Not confirmed within the approval window
+	ApprovalFailed    ApprovalStatus = "failed"    // Confirmed, but the action could no longer be carried out
+)
+
+// ApprovalDecision is one checker's confirmation or refusal
+type ApprovalDecision struct {
+	UserID    uuid.UUID `json:"user_id" bson:"user_id"`
+	Approved  bool      `json:"approved" bson:"approved"`
+	Comment   string    `json:"comment,omitempty" bson:"comment,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	DecidedAt time.Time `json:"decided_at" bson:"decided_at"`
+}
+
+// PendingApproval is a sensitive action held until other users with the same permission
+// confirm it (maker-checker). The initiator can never confirm their own action.
+type PendingApproval struct {
+	ID                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UUID              uuid.UUID          `json:"uuid" bson:"uuid"`
+	OrganizationID    *uuid.UUID         `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	Action            ApprovalAction     `json:"action" bson:"action"`
+	ResourceType      string             `json:"resource_type" bson:"resource_type"`
+	ResourceID        uuid.UUID          `json:"resource_id" bson:"resource_id"`
+	Amount            float64            `json:"amount" bson:"amount"`
+	Permission        string             `json:"permission" bson:"permission"` // Checkers must hold it too
+	RequiredApprovals int                `json:"required_approvals" bson:"required_approvals"`
+	InitiatedBy       uuid.UUID          `json:"initiated_by" bson:"initiated_by"`
+	Decisions         []ApprovalDecision `json:"decisions" bson:"decisions"`
+	Status            ApprovalStatus     `json:"status" bson:"status"`
+	FailureReason     string             `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	ExpiresAt         time.Time          `json:"expires_at" bson:"expires_at"`
+	CompletedAt       *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// apiKeyPrefix marks platform API keys so they are recognisable in logs and secret scanners
+const apiKeyPrefix = "ifk_"
+
+var (
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrAPIKeyInvalid      = errors.New("invalid api key")
+	ErrAPIKeyExpired      = errors.New("api key has expired")
+	ErrAPIKeyRevoked      = errors.New("api key has been revoked")
+	ErrInvalidAPIKeyInput = errors.New("invalid api key request")
+)
+
+// APIKeyScopes lists the scopes a key may be granted
+var APIKeyScopes = map[string]string{
+	"invoices:read":  "Read invoices and their status",
+	"financing:read": "Read financing requests and their status",
+}
+
+// CreateAPIKeyRequest is the input for issuing a key
+type CreateAPIKeyRequest struct {
+	OrganizationID     string     `json:"organization_id" binding:"required"`
+	Name               string     `json:"name" binding:"required"`
+	Scopes             []string   `json:"scopes" binding:"required"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+}
+
+// APIKeyService issues, revokes and validates partner API keys
+type APIKeyService struct {
+	db  *database.MongoDB
+	cfg *config.Config
+}
+
+func NewAPIKeyService(db *database.MongoDB, cfg *config.Config) *APIKeyService {
+	return &APIKeyService{db: db, cfg: cfg}
+}
+
+// CreateAPIKey issues a key and returns it with the plaintext value, which is not stored
+// and cannot be retrieved again
+func (s *APIKeyService) CreateAPIKey(req CreateAPIKeyRequest, createdBy uuid.UUID) (*models.APIKey, string, error) {
+	req.OrganizationID = strings.TrimSpace(req.OrganizationID)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.OrganizationID == "" || req.Name == "" {
+		return nil, "", fmt.Errorf("%w: organization_id and name are required", ErrInvalidAPIKeyInput)
+	}
+	// A key only reads its organization's records, so it must name an existing one
+	organizationID, err := uuid.Parse(req.OrganizationID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: organization_id must be an organization ID", ErrInvalidAPIKeyInput)
+	}
+	count, err := s.db.Database.Collection("organizations").CountDocuments(context.Background(), bson.M{"uuid": organizationID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up organization: %w", err)
+	}
+	if count == 0 {
+		return nil, "", fmt.Errorf("%w: organization %s does not exist", ErrInvalidAPIKeyInput, organizationID)
+	}
+	req.OrganizationID = organizationID.String()
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyInput)
+	}
+	if req.RateLimitPerMinute < 0 {
+		return nil, "", fmt.Errorf("%w: rate_limit_per_minute must not be negative", ErrInvalidAPIKeyInput)
+	}
+	if req.RateLimitPerMinute == 0 {
+		req.RateLimitPerMinute = s.cfg.APIKeyDefaultRateLimit
+	}
+
+	buf := make([]byte, 28)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	encoded := hex.EncodeToString(buf)
+	plaintext := apiKeyPrefix + encoded[:8] + "_" + encoded[8:]
+
+	now := time.Now()
+	key := &models.APIKey{
+		UUID:               uuid.New(),
+		OrganizationID:     req.OrganizationID,
+		Name:               req.Name,
+		Prefix:             apiKeyPrefix + encoded[:8],
+		KeyHash:            hashAPIKey(plaintext),
+		Scopes:             scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		ExpiresAt:          req.ExpiresAt,
+		CreatedBy:          createdBy,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	collection := s.db.Database.Collection("api_keys")
+	if _, err := collection.InsertOne(context.Background(), key); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, plaintext, nil
+}
+
+// ListAPIKeys returns an organization's keys, or every key when organizationID is empty
+func (s *APIKeyService) ListAPIKeys(organizationID string, includeRevoked bool) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	collection := s.db.Database.Collection("api_keys")
+
+	filter := bson.M{}
+	if organizationID != "" {
+		filter["organization_id"] = organizationID
+	}
+	if !includeRevoked {
+		filter["revoked_at"] = bson.M{"$exists": false}
+	}
+	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &keys)
+	return keys, err
+}
+
+// RevokeAPIKey revokes a key; revoking an already revoked key is a no-op
+func (s *APIKeyService) RevokeAPIKey(id uuid.UUID, revokedBy uuid.UUID) (*models.APIKey, error) {
+	var key models.APIKey
+	collection := s.db.Database.Collection("api_keys")
+
+	now := time.Now()
+	filter := bson.M{"uuid": id, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": now, "revoked_by": revokedBy, "updated_at": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Either unknown or already revoked
+		err = collection.FindOne(context.Background(), bson.M{"uuid": id}).Decode(&key)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAPIKeyNotFound
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return &key, nil
+}
+
+// ValidateAPIKey resolves a presented key and records its use. Rate limits are applied
+// by the middleware using the key's configured limit.
+func (s *APIKeyService) ValidateAPIKey(rawKey, clientIP string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var key models.APIKey
+	collection := s.db.Database.Collection("api_keys")
+	err := collection.FindOne(context.Background(), bson.M{"key_hash": hashAPIKey(rawKey)}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api key: %w", err)
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	// Last-used is only written once per resolution window to keep writes off the hot path
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.cfg.APIKeyLastUsedResolution || key.LastUsedIP != clientIP {
+		collection.UpdateOne(context.Background(), bson.M{"uuid": key.UUID},
+			bson.M{"$set": bson.M{"last_used_at": now, "last_used_ip": clientIP}})
+		key.LastUsedAt = &now
+		key.LastUsedIP = clientIP
+	}
+	return &key, nil
+}
+
+// HasScope reports whether the key was granted scope
+func HasScope(key *models.APIKey, scope string) bool {
+	for _, granted := range key.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyInput)
+	}
+
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if _, ok := APIKeyScopes[scope]; !ok {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyInput, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrApprovalNotFound   = errors.New("approval not found")
+	ErrApprovalPending    = errors.New("an approval for this action is already pending")
+	ErrApprovalNotPending = errors.New("approval is no longer pending")
+	ErrApprovalExpired    = errors.New("approval window has passed")
+	ErrSelfApproval       = errors.New("the initiator cannot confirm their own action")
+	ErrAlreadyDecided     = errors.New("you have already confirmed this action")
+	ErrNotInitiator       = errors.New("only the initiator can cancel an approval")
+)
+
+// ApprovalTier is the number of confirmations an action needs from MinAmount upwards
+type ApprovalTier struct {
+	MinAmount float64 `json:"min_amount"`
+	Approvals int     `json:"approvals"` // 0 lets the initiator act alone
+}
+
+// parseApprovalTiers turns "amount=approvals" pairs from the configuration into tiers
+// sorted by amount
+func parseApprovalTiers(values map[string]int) []ApprovalTier {
+	tiers := make([]ApprovalTier, 0, len(values))
+	for amount, approvals := range values {
+		minAmount, err := strconv.ParseFloat(amount, 64)
+		if err != nil || minAmount < 0 || approvals < 0 {
+			continue
+		}
+		tiers = append(tiers, ApprovalTier{MinAmount: minAmount, Approvals: approvals})
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinAmount < tiers[j].MinAmount })
+	return tiers
+}
+
+// ApprovalInitiation describes a sensitive action to hold for confirmation
+type ApprovalInitiation struct {
+	OrganizationID *uuid.UUID
+	Action         models.ApprovalAction
+	ResourceType   string
+	ResourceID     uuid.UUID
+	Amount         float64
+	Permission     string
+	InitiatedBy    uuid.UUID
+}
+
+// ApprovalChecker is the user confirming or refusing an action
+type ApprovalChecker struct {
+	UserID    uuid.UUID
+	IPAddress string
+	Comment   string
+}
+
+// ApprovalFilter narrows the approvals listed
+type ApprovalFilter struct {
+	OrganizationID *uuid.UUID
+	Status         models.ApprovalStatus
+	Action         models.ApprovalAction
+	Limit          int64
+}
+
+// ApprovalService holds sensitive actions until a second user confirms them (maker-checker).
+// How many confirmations an action needs depends on its amount; the caller carries the
+// action out once Approve reports that enough were given.
+type ApprovalService struct {
+	db     *database.MongoDB
+	window time.Duration
+	tiers  map[models.ApprovalAction][]ApprovalTier
+}
+
+func NewApprovalService(db *database.MongoDB, cfg *config.Config) *ApprovalService {
+	return &ApprovalService{
+		db:     db,
+		window: cfg.DualControlWindow,
+		tiers: map[models.ApprovalAction][]ApprovalTier{
+			models.ApprovalFinancingApproval: parseApprovalTiers(cfg.DualControlFinancingTiers),
+		},
+	}
+}
+
+func (s *ApprovalService) approvals() *mongo.Collection {
+	return s.db.Database.Collection("pending_approvals")
+}
+
+// Tiers returns the confirmation thresholds of an action
+func (s *ApprovalService) Tiers(action models.ApprovalAction) []ApprovalTier {
+	return s.tiers[action]
+}
+
+// RequiredApprovals returns how many users other than the initiator must confirm an
+// action of this amount. Actions below the lowest tier need none.
+func (s *ApprovalService) RequiredApprovals(action models.ApprovalAction, amount float64) int {
+	required := 0
+	for _, tier := range s.tiers[action] {
+		if amount >= tier.MinAmount {
+			required = tier.Approvals
+		}
+	}
+	return required
+}
+
+// Initiate records a pending approval. Only one may be pending per action and resource.
+func (s *ApprovalService) Initiate(req ApprovalInitiation) (*models.PendingApproval, error) {
+	if err := s.ExpireStale(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	approval := &models.PendingApproval{
+		UUID:              uuid.New(),
+		OrganizationID:    req.OrganizationID,
+		Action:            req.Action,
+		ResourceType:      req.ResourceType,
+		ResourceID:        req.ResourceID,
+		Amount:            req.Amount,
+		Permission:        req.Permission,
+		RequiredApprovals: s.RequiredApprovals(req.Action, req.Amount),
+		InitiatedBy:       req.InitiatedBy,
+		Decisions:         []models.ApprovalDecision{},
+		Status:            models.ApprovalPending,
+		ExpiresAt:         now.Add(s.window),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if approval.RequiredApprovals < 1 {
+		approval.RequiredApprovals = 1
+	}
+
+	// The partial unique index on action and resource makes this safe against a race
+	if _, err := s.approvals().InsertOne(context.Background(), approval); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrApprovalPending
+		}
+		return nil, fmt.Errorf("failed to create approval: %w", err)
+	}
+	return approval, nil
+}
+
+// Get returns an approval, marking it expired if its window has passed
+func (s *ApprovalService) Get(id uuid.UUID) (*models.PendingApproval, error) {
+	var approval models.PendingApproval
+	err := s.approvals().FindOne(context.Background(), bson.M{"uuid": id}).Decode(&approval)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval: %w", err)
+	}
+	if approval.Status == models.ApprovalPending && time.Now().After(approval.ExpiresAt) {
+		if err := s.ExpireStale(); err != nil {
+			return nil, err
+		}
+		approval.Status = models.ApprovalExpired
+	}
+	return &approval, nil
+}
+
+// List returns approvals, newest first
+func (s *ApprovalService) List(filter ApprovalFilter) ([]models.PendingApproval, error) {
+	if err := s.ExpireStale(); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+
+	query := bson.M{}
+	if filter.OrganizationID != nil {
+		query["organization_id"] = *filter.OrganizationID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+
+	approvals := []models.PendingApproval{}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(filter.Limit)
+	cursor, err := s.approvals().Find(context.Background(), query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &approvals)
+	return approvals, err
+}
+
+// PendingFor returns the pending approval of an action on a resource, if there is one
+func (s *ApprovalService) PendingFor(action models.ApprovalAction, resourceID uuid.UUID) (*models.PendingApproval, error) {
+	if err := s.ExpireStale(); err != nil {
+		return nil, err
+	}
+	var approval models.PendingApproval
+	err := s.approvals().FindOne(context.Background(), bson.M{
+		"action":      action,
+		"resource_id": resourceID,
+		"status":      models.ApprovalPending,
+	}).Decode(&approval)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval: %w", err)
+	}
+	return &approval, nil
+}
+
+// Approve records a checker's confirmation. It returns true when this was the last
+// confirmation needed; the approval then moves to approved and the caller must carry
+// the action out and report the outcome with Complete.
+func (s *ApprovalService) Approve(id uuid.UUID, checker ApprovalChecker) (*models.PendingApproval, bool, error) {
+	return s.decide(id, checker, true)
+}
+
+// Reject records a checker's refusal, which ends the approval
+func (s *ApprovalService) Reject(id uuid.UUID, checker ApprovalChecker) (*models.PendingApproval, error) {
+	approval, _, err := s.decide(id, checker, false)
+	return approval, err
+}
+
+func (s *ApprovalService) decide(id uuid.UUID, checker ApprovalChecker, approved bool) (*models.PendingApproval, bool, error) {
+	approval, err := s.Get(id)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case approval.Status == models.ApprovalExpired:
+		return nil, false, ErrApprovalExpired
+	case approval.Status != models.ApprovalPending:
+		return nil, false, ErrApprovalNotPending
+	case approval.InitiatedBy == checker.UserID:
+		return nil, false, ErrSelfApproval
+	}
+
+	now := time.Now()
+	decision := models.ApprovalDecision{
+		UserID:    checker.UserID,
+		Approved:  approved,
+		Comment:   checker.Comment,
+		IPAddress: checker.IPAddress,
+		DecidedAt: now,
+	}
+	update := bson.M{
+		"$push": bson.M{"decisions": decision},
+		"$set":  bson.M{"updated_at": now},
+	}
+	if !approved {
+		update["$set"] = bson.M{"status": models.ApprovalRejected, "completed_at": now, "updated_at": now}
+	}
+
+	// Matching on status and the checker's absence guards against concurrent decisions
+	result, err := s.approvals().UpdateOne(context.Background(), bson.M{
+		"uuid":              id,
+		"status":            models.ApprovalPending,
+		"expires_at":        bson.M{"$gt": now},
+		"decisions.user_id": bson.M{"$ne": checker.UserID},
+	}, update)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record decision: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		for _, previous := range approval.Decisions {
+			if previous.UserID == checker.UserID {
+				return nil, false, ErrAlreadyDecided
+			}
+		}
+		return nil, false, ErrApprovalNotPending
+	}
+
+	approval.Decisions = append(approval.Decisions, decision)
+	approval.UpdatedAt = now
+	if !approved {
+		approval.Status = models.ApprovalRejected
+		approval.CompletedAt = &now
+		return approval, false, nil
+	}
+	if countApprovals(approval) < approval.RequiredApprovals {
+		return approval, false, nil
+	}
+
+	// Only the checker whose confirmation moves it to approved carries the action out
+	result, err = s.approvals().UpdateOne(context.Background(),
+		bson.M{"uuid": id, "status": models.ApprovalPending},
+		bson.M{"$set": bson.M{"status": models.ApprovalApproved, "updated_at": now}})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update approval: %w", err)
+	}
+	if result.ModifiedCount == 0 {
+		return approval, false, nil
+	}
+	approval.Status = models.ApprovalApproved
+	return approval, true, nil
+}
+
+func countApprovals(approval *models.PendingApproval) int {
+	count := 0
+	for _, decision := range approval.Decisions {
+		if decision.Approved {
+			count++
+		}
+	}
+	return count
+}
+
+// Complete records whether an approved action was carried out
+func (s *ApprovalService) Complete(approval *models.PendingApproval, failureReason string) error {
+	now := time.Now()
+	set := bson.M{"status": models.ApprovalExecuted, "completed_at": now, "updated_at": now}
+	if failureReason != "" {
+		set["status"] = models.ApprovalFailed
+		set["failure_reason"] = failureReason
+	}
+	_, err := s.approvals().UpdateOne(context.Background(),
+		bson.M{"uuid": approval.UUID, "status": models.ApprovalApproved}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to complete approval: %w", err)
+	}
+	approval.Status = set["status"].(models.ApprovalStatus)
+	approval.FailureReason = failureReason
+	approval.CompletedAt = &now
+	return nil
+}
+
+// Cancel withdraws a pending approval. Only its initiator may do so.
+func (s *ApprovalService) Cancel(id uuid.UUID, userID uuid.UUID) (*models.PendingApproval, error) {
+	approval, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.InitiatedBy != userID {
+		return nil, ErrNotInitiator
+	}
+
+	now := time.Now()
+	result, err := s.approvals().UpdateOne(context.Background(),
+		bson.M{"uuid": id, "status": models.ApprovalPending},
+		bson.M{"$set": bson.M{"status": models.ApprovalCancelled, "completed_at": now, "updated_at": now}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel approval: %w", err)
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrApprovalNotPending
+	}
+	approval.Status = models.ApprovalCancelled
+	approval.CompletedAt = &now
+	return approval, nil
+}
+
+// ExpireStale marks pending approvals whose window has passed as expired
+func (s *ApprovalService) ExpireStale() error {
+	now := time.Now()
+	_, err := s.approvals().UpdateMany(context.Background(),
+		bson.M{"status": models.ApprovalPending, "expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.ApprovalExpired, "completed_at": now, "updated_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	return nil
+}
@@ -10,8 +10,6 @@ import (
 
 	"invoice-financing-platform/internal/config"
 	"invoice-financing-platform/internal/models"
-
-	"github.com/google/uuid"
 )
 
 // FabricService handles interactions with Hyperledger Fabric
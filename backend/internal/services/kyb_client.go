@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrKYBUnavailable means KYB or KYC status could not be checked. Financing and
+// investment are refused rather than allowed unchecked.
+var ErrKYBUnavailable = errors.New("verification status could not be checked")
+
+// KYBEligibility is whether a company may be financed, as decided by the user
+// management service
+type KYBEligibility struct {
+	Eligible bool     `json:"eligible"`
+	Status   string   `json:"kyb_status"` // not_started, pending, verified, rejected, unknown
+	Reasons  []string `json:"reasons"`
+}
+
+// KYCEligibility is whether a customer's KYC allows new financing or investment
+type KYCEligibility struct {
+	Eligible     bool       `json:"eligible"`
+	Status       string     `json:"kyc_status"` // not_started, pending, approved, rejected, expired, unknown
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RefreshDueAt *time.Time `json:"refresh_due_at,omitempty"` // Re-verification requested by this date
+}
+
+// ScreeningOutcome is the sanctions and PEP screening of a party, as recorded by the
+// user management service
+type ScreeningOutcome struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // clear, potential_match, confirmed_match, dismissed, not_required, no_buyer_name
+}
+
+// Cleared reports whether the party may be financed against: it had no hits, or an
+// analyst dismissed them
+func (o *ScreeningOutcome) Cleared() bool {
+	return o.Status == "clear" || o.Status == "dismissed" || o.Status == "not_required"
+}
+
+// KYBClient asks the user management service whether a company has passed KYB, meaning
+// it is verified and its directors and beneficial owners hold current KYC approvals,
+// whether a customer's own KYC is current, and has invoice buyers screened against the
+// sanctions and PEP lists
+type KYBClient struct {
+	url               string
+	token             string
+	required          bool
+	kycRequired       bool
+	screeningRequired bool
+	client            *http.Client
+}
+
+func NewKYBClient(cfg *config.Config) *KYBClient {
+	return &KYBClient{
+		url:               strings.TrimSuffix(cfg.KYBServiceURL, "/"),
+		token:             cfg.KYBServiceToken,
+		required:          cfg.KYBRequired,
+		kycRequired:       cfg.KYCRequired,
+		screeningRequired: cfg.ScreeningRequired,
+		client:            &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HasCase reports whether the user management service holds a KYB case for the company
+func (e *KYBEligibility) HasCase() bool {
+	return e.Status != "unknown" && e.Status != "not_required"
+}
+
+// CheckEligibility looks up the company whose KYB case userID submitted. The tax ID on
+// the account is not used since users set it themselves. Companies unknown to the user
+// management service are not eligible.
+func (k *KYBClient) CheckEligibility(userID uuid.UUID) (*KYBEligibility, error) {
+	if !k.required {
+		return &KYBEligibility{Eligible: true, Status: "not_required", Reasons: []string{}}, nil
+	}
+
+	var eligibility KYBEligibility
+	found, err := k.get("/api/v1/kyb/eligibility?user_id="+url.QueryEscape(userID.String()), &eligibility)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &KYBEligibility{Status: "unknown", Reasons: []string{"The company has not been registered for verification"}}, nil
+	}
+	return &eligibility, nil
+}
+
+// CheckKYC looks the customer up by the user ID their token carries, since an email can
+// be registered again by someone else. Customers unknown to the user management service
+// are not eligible.
+func (k *KYBClient) CheckKYC(userID uuid.UUID) (*KYCEligibility, error) {
+	if !k.kycRequired {
+		return &KYCEligibility{Eligible: true, Status: "not_required"}, nil
+	}
+
+	var eligibility KYCEligibility
+	found, err := k.get("/api/v1/kyc/customers/eligibility?user_id="+url.QueryEscape(userID.String()), &eligibility)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &KYCEligibility{Status: "unknown"}, nil
+	}
+	return &eligibility, nil
+}
+
+// ScreenBuyer screens the buyer of an invoice, who is also the counterparty of any
+// financing against it, and records the result in the user management service. The
+// buyer is identified by the invoice, so an analyst's dismissal of a false positive
+// carries over to later screenings of the same invoice.
+func (k *KYBClient) ScreenBuyer(invoice *models.Invoice) (*ScreeningOutcome, error) {
+	if !k.screeningRequired {
+		return &ScreeningOutcome{Status: "not_required"}, nil
+	}
+	if strings.TrimSpace(invoice.CustomerName) == "" {
+		return &ScreeningOutcome{Status: "no_buyer_name"}, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"subject_type": "buyer",
+		"subject_id":   invoice.UUID.String(),
+		"name":         invoice.CustomerName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKYBUnavailable, err)
+	}
+
+	var outcome ScreeningOutcome
+	found, err := k.do(http.MethodPost, "/api/v1/screening/screen", body, &outcome)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: screening endpoint not found", ErrKYBUnavailable)
+	}
+	return &outcome, nil
+}
+
+// get decodes a response into out, reporting false for 404
+func (k *KYBClient) get(path string, out interface{}) (bool, error) {
+	return k.do(http.MethodGet, path, nil, out)
+}
+
+func (k *KYBClient) do(method, path string, body []byte, out interface{}) (bool, error) {
+	req, err := http.NewRequest(method, k.url+path, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrKYBUnavailable, err)
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrKYBUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: user management service returned %s", ErrKYBUnavailable, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrKYBUnavailable, err)
+	}
+	return true, nil
+}
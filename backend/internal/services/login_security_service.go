@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+	"shared/loginrisk"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrConfirmationInvalid  = errors.New("login confirmation is invalid or expired")
+	ErrConfirmationCode     = errors.New("invalid confirmation code")
+	ErrConfirmationDelivery = errors.New("the confirmation code could not be sent")
+	ErrDeviceNotFound       = errors.New("device not found")
+)
+
+// Reasons recorded on failed and blocked login attempts
+const (
+	LoginFailInvalidPassword      = loginrisk.FailInvalidPassword
+	LoginFailUnknownAccount       = loginrisk.FailUnknownAccount
+	LoginFailAccountLocked        = loginrisk.FailAccountLocked
+	LoginFailConfirmationRequired = loginrisk.FailConfirmationRequired // Password accepted, emailed code required
+	LoginFailConfirmationFailed   = loginrisk.FailConfirmationFailed
+)
+
+// Wrong confirmation codes allowed before the login has to be started again
+const loginConfirmationAttempts = 5
+
+// LoginAssessment is the risk of one login attempt, scored by the engine the user
+// management service uses as well
+type LoginAssessment = loginrisk.Assessment
+
+// LoginSecurityService records every password login, locks accounts after repeated wrong
+// passwords, scores logins for risk and tracks the devices users sign in from
+type LoginSecurityService struct {
+	db       *database.MongoDB
+	cfg      *config.Config
+	engine   *loginrisk.Engine
+	notifier *NotificationClient
+}
+
+func NewLoginSecurityService(db *database.MongoDB, cfg *config.Config, notifier *NotificationClient) *LoginSecurityService {
+	s := &LoginSecurityService{db: db, cfg: cfg, notifier: notifier}
+	var locator *loginrisk.Locator
+	if cfg.GeoIPDatabasePath == "" {
+		log.Println("Login geolocation is off: GEOIP_DATABASE_PATH is not set")
+	} else if l, err := loginrisk.OpenLocator(cfg.GeoIPDatabasePath); err != nil {
+		log.Printf("Login geolocation is off: %v", err)
+	} else {
+		locator = l
+	}
+	s.engine = loginrisk.NewEngine(&loginStore{s}, locator, loginrisk.Options{
+		MaxAttempts:        cfg.LoginMaxAttempts,
+		LockoutBase:        cfg.LoginLockoutBase,
+		LockoutMax:         cfg.LoginLockoutMax,
+		ChallengeThreshold: cfg.LoginRiskThreshold,
+		MaxTravelSpeed:     float64(cfg.LoginMaxTravelSpeed),
+		StuffingWindow:     cfg.LoginStuffingWindow,
+		StuffingAccounts:   cfg.LoginStuffingAccounts,
+	})
+	return s
+}
+
+func (s *LoginSecurityService) Close() error {
+	return s.engine.Close()
+}
+
+func (s *LoginSecurityService) attempts() *mongo.Collection {
+	return s.db.Database.Collection("login_attempts")
+}
+
+func (s *LoginSecurityService) devices() *mongo.Collection {
+	return s.db.Database.Collection("known_devices")
+}
+
+func (s *LoginSecurityService) confirmations() *mongo.Collection {
+	return s.db.Database.Collection("login_confirmations")
+}
+
+// RequiresConfirmation reports whether a login scored high enough to need the emailed code
+func (s *LoginSecurityService) RequiresConfirmation(a *LoginAssessment) bool {
+	return s.engine.RequiresConfirmation(a)
+}
+
+// Assess scores a login attempt by user, which is nil for unknown accounts
+func (s *LoginSecurityService) Assess(user *models.User, ipAddress, userAgent string) *LoginAssessment {
+	var account *loginrisk.Account
+	if user != nil {
+		account = &loginrisk.Account{ID: user.UUID, FailedLogins: user.FailedLogins}
+	}
+	return s.engine.Assess(context.Background(), account, ipAddress, userAgent)
+}
+
+// loginStore reads the login history the engine scores against
+type loginStore struct {
+	s *LoginSecurityService
+}
+
+func credentialFailuresSince(since time.Time) bson.M {
+	return bson.M{
+		"success":      false,
+		"fail_reason":  bson.M{"$in": loginrisk.CredentialFailures},
+		"attempted_at": bson.M{"$gt": since},
+	}
+}
+
+func (l *loginStore) FailedAccounts(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	filter := credentialFailuresSince(since)
+	filter["ip_address"] = ipAddress
+	emails, err := l.s.attempts().Distinct(ctx, "email", filter)
+	return len(emails), err
+}
+
+func (l *loginStore) FailedAddresses(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error) {
+	filter := credentialFailuresSince(since)
+	filter["user_id"] = accountID
+	addresses, err := l.s.attempts().Distinct(ctx, "ip_address", filter)
+	return len(addresses), err
+}
+
+func (l *loginStore) KnownDevices(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	values, err := l.s.devices().Distinct(ctx, "fingerprint", bson.M{"user_id": accountID})
+	return distinctStrings(values), err
+}
+
+func (l *loginStore) LoginCountries(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	values, err := l.s.attempts().Distinct(ctx, "country", bson.M{"user_id": accountID, "success": true, "country": bson.M{"$nin": []interface{}{"", nil}}})
+	return distinctStrings(values), err
+}
+
+func (l *loginStore) LastLocatedLogin(ctx context.Context, accountID uuid.UUID) (*loginrisk.LocatedLogin, error) {
+	var last models.LoginAttempt
+	err := l.s.attempts().FindOne(ctx,
+		bson.M{"user_id": accountID, "success": true, "latitude": bson.M{"$ne": nil}, "longitude": bson.M{"$ne": nil}},
+		options.FindOne().SetSort(bson.M{"attempted_at": -1})).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loginrisk.LocatedLogin{Latitude: *last.Latitude, Longitude: *last.Longitude, AttemptedAt: last.AttemptedAt}, nil
+}
+
+func distinctStrings(values []interface{}) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
+
+// RecordAttempt stores one login attempt. user is nil when the email matched no account.
+func (s *LoginSecurityService) RecordAttempt(user *models.User, email, ipAddress, userAgent string, success bool, failReason string, a *LoginAssessment) *models.LoginAttempt {
+	attempt := &models.LoginAttempt{
+		UUID:               uuid.New(),
+		Email:              strings.ToLower(strings.TrimSpace(email)),
+		IPAddress:          ipAddress,
+		UserAgent:          userAgent,
+		Success:            success,
+		FailReason:         failReason,
+		RiskScore:          a.Score,
+		RiskFactors:        a.Factors,
+		SuspiciousActivity: s.RequiresConfirmation(a),
+		AttemptedAt:        time.Now(),
+	}
+	if user != nil {
+		attempt.UserID = &user.UUID
+	}
+	if location := a.Location; location != nil {
+		attempt.Country = location.Country
+		attempt.City = location.City
+		if location.HasCoordinates {
+			attempt.Latitude = &location.Latitude
+			attempt.Longitude = &location.Longitude
+		}
+	}
+	if success {
+		attempt.DeviceID = s.rememberDevice(user, ipAddress, a)
+	}
+	if _, err := s.attempts().InsertOne(context.Background(), attempt); err != nil {
+		log.Printf("Failed to record login attempt for %s: %v", attempt.Email, err)
+	}
+	return attempt
+}
+
+// RecordSuccess clears the user's failure count, records the login and alerts the user
+// when the device is new
+func (s *LoginSecurityService) RecordSuccess(user *models.User, ipAddress, userAgent string, a *LoginAssessment) {
+	_, err := s.db.Database.Collection("users").UpdateOne(context.Background(),
+		bson.M{"uuid": user.UUID},
+		bson.M{"$set": bson.M{"failed_logins": 0, "lockout_count": 0}, "$unset": bson.M{"locked_until": ""}})
+	if err != nil {
+		log.Printf("Failed to reset failed logins for user %s: %v", user.UUID, err)
+	}
+	attempt := s.RecordAttempt(user, user.Email, ipAddress, userAgent, true, "", a)
+
+	if !a.Has(loginrisk.FactorNewDevice) {
+		return
+	}
+	data := loginrisk.NewDeviceAlert(a, ipAddress, attempt.AttemptedAt)
+	go func() {
+		if err := s.notifier.SendSecurityAlert(user.UUID, AlertNewDevice, data); err != nil {
+			log.Printf("Failed to alert user %s of a new device: %v", user.UUID, err)
+		}
+	}()
+}
+
+// LockedUntil returns when the user's lockout ends, or nil when they may sign in
+func LockedUntil(user *models.User, now time.Time) *time.Time {
+	return loginrisk.LockedUntil(user.LockedUntil, now)
+}
+
+// registerFailureRetries bounds how often RegisterFailure retries after losing a race
+// with a concurrent failure on the same account
+const registerFailureRetries = 5
+
+// RegisterFailure counts a wrong password and returns when the account became locked, if
+// this attempt locked it. The user is alerted of the lockout.
+func (s *LoginSecurityService) RegisterFailure(user *models.User, ipAddress string) (*time.Time, error) {
+	ctx := context.Background()
+	users := s.db.Database.Collection("users")
+
+	for i := 0; i < registerFailureRetries; i++ {
+		var current models.User
+		if err := users.FindOne(ctx, bson.M{"uuid": user.UUID}).Decode(&current); err != nil {
+			return nil, fmt.Errorf("failed to record failed login: %w", err)
+		}
+		now := time.Now()
+		if LockedUntil(&current, now) != nil {
+			// Locked by a concurrent attempt
+			return nil, nil
+		}
+		next, lockedUntil := s.engine.RegisterFailure(loginrisk.Lockout{
+			FailedLogins: current.FailedLogins,
+			LockedUntil:  current.LockedUntil,
+			Lockouts:     current.LockoutCount,
+		}, now)
+
+		// The update only applies to the state it was worked out from, so concurrent
+		// failures are counted one after the other and only one of them locks the account
+		set := bson.M{"failed_logins": next.FailedLogins, "lockout_count": next.Lockouts}
+		if next.LockedUntil != nil {
+			set["locked_until"] = *next.LockedUntil
+		}
+		result, err := users.UpdateOne(ctx,
+			bson.M{"uuid": user.UUID, "failed_logins": storedCount(current.FailedLogins), "lockout_count": storedCount(current.LockoutCount)},
+			bson.M{"$set": set})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record failed login: %w", err)
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		if lockedUntil != nil {
+			s.alertLocked(user, ipAddress, *lockedUntil)
+		}
+		return lockedUntil, nil
+	}
+	return nil, fmt.Errorf("failed to record failed login for user %s: too many concurrent attempts", user.UUID)
+}
+
+// storedCount matches a counter holding n, treating a missing counter as zero
+func storedCount(n int) interface{} {
+	if n == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return n
+}
+
+// alertLocked tells the user their account was locked, since someone may be guessing
+// their password
+func (s *LoginSecurityService) alertLocked(user *models.User, ipAddress string, until time.Time) {
+	data := loginrisk.LockoutAlert(ipAddress, until)
+	go func() {
+		if err := s.notifier.SendSecurityAlert(user.UUID, AlertAccountLocked, data); err != nil {
+			log.Printf("Failed to alert user %s of a lockout: %v", user.UUID, err)
+		}
+	}()
+}
+
+// UnlockAccount lifts a lockout before it runs out
+func (s *LoginSecurityService) UnlockAccount(userID uuid.UUID) error {
+	result, err := s.db.Database.Collection("users").UpdateOne(context.Background(),
+		bson.M{"uuid": userID},
+		bson.M{"$set": bson.M{"failed_logins": 0, "lockout_count": 0}, "$unset": bson.M{"locked_until": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// rememberDevice adds the device to the user's known devices or updates when it was last
+// used
+func (s *LoginSecurityService) rememberDevice(user *models.User, ipAddress string, a *LoginAssessment) *uuid.UUID {
+	now := time.Now()
+	seen := a.Device(ipAddress)
+	var device models.KnownDevice
+	err := s.devices().FindOneAndUpdate(context.Background(),
+		bson.M{"user_id": user.UUID, "fingerprint": seen.Fingerprint},
+		bson.M{
+			"$set":         bson.M{"label": seen.Label, "last_ip": seen.IPAddress, "last_country": seen.Country, "last_seen_at": now},
+			"$setOnInsert": bson.M{"uuid": uuid.New(), "first_seen_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&device)
+	if err != nil {
+		log.Printf("Failed to remember device for user %s: %v", user.UUID, err)
+		return nil
+	}
+	return &device.UUID
+}
+
+// StartConfirmation emails a code for a risky login and returns the token the client
+// completes the login with
+func (s *LoginSecurityService) StartConfirmation(user *models.User, ipAddress, userAgent string) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate confirmation code: %w", err)
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	now := time.Now()
+	confirmation := &models.LoginConfirmation{
+		UUID:      uuid.New(),
+		UserID:    user.UUID,
+		TokenHash: hashSessionToken(token),
+		CodeHash:  hashConfirmationCode(token, code),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(s.cfg.LoginConfirmationTTL),
+		CreatedAt: now,
+	}
+	if _, err := s.confirmations().InsertOne(context.Background(), confirmation); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store login confirmation: %w", err)
+	}
+
+	err = s.notifier.SendSecurityAlert(user.UUID, AlertLoginConfirmation, map[string]interface{}{
+		"code":       code,
+		"device":     loginrisk.DescribeDevice(userAgent),
+		"ip_address": ipAddress,
+		"expires_at": confirmation.ExpiresAt,
+	})
+	if err != nil {
+		log.Printf("Failed to send login confirmation to user %s: %v", user.UUID, err)
+		return "", time.Time{}, ErrConfirmationDelivery
+	}
+	return token, confirmation.ExpiresAt, nil
+}
+
+// ConfirmLogin checks the emailed code for a confirmation token and returns the user to
+// sign in. A confirmation can be used once; wrong codes are recorded as failed logins.
+func (s *LoginSecurityService) ConfirmLogin(token, code, ipAddress, userAgent string) (*models.User, error) {
+	ctx := context.Background()
+	now := time.Now()
+	tokenHash := hashSessionToken(token)
+
+	// The attempt is counted before the code is compared so parallel guesses cannot
+	// exceed the limit
+	var confirmation models.LoginConfirmation
+	err := s.confirmations().FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash":   tokenHash,
+			"confirmed_at": bson.M{"$exists": false},
+			"expires_at":   bson.M{"$gt": now},
+			"attempts":     bson.M{"$lt": loginConfirmationAttempts},
+		},
+		bson.M{"$inc": bson.M{"attempts": 1}}).Decode(&confirmation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConfirmationInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load login confirmation: %w", err)
+	}
+	var user models.User
+	if err := s.db.Database.Collection("users").FindOne(ctx, bson.M{"uuid": confirmation.UserID}).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if !hmac.Equal([]byte(hashConfirmationCode(token, strings.TrimSpace(code))), []byte(confirmation.CodeHash)) {
+		s.RecordAttempt(&user, user.Email, ipAddress, userAgent, false, LoginFailConfirmationFailed, s.Assess(&user, ipAddress, userAgent))
+		return nil, ErrConfirmationCode
+	}
+
+	result, err := s.confirmations().UpdateOne(ctx,
+		bson.M{"uuid": confirmation.UUID, "confirmed_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"confirmed_at": now}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete login confirmation: %w", err)
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrConfirmationInvalid
+	}
+	return &user, nil
+}
+
+// LoginHistory returns the user's most recent login attempts
+func (s *LoginSecurityService) LoginHistory(userID uuid.UUID, limit int) ([]models.LoginAttempt, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	attempts := []models.LoginAttempt{}
+	opts := options.Find().SetSort(bson.M{"attempted_at": -1}).SetLimit(int64(limit))
+	cursor, err := s.attempts().Find(context.Background(), bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login history: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &attempts)
+	return attempts, err
+}
+
+// Devices returns the devices the user has signed in from, most recent first
+func (s *LoginSecurityService) Devices(userID uuid.UUID) ([]models.KnownDevice, error) {
+	devices := []models.KnownDevice{}
+	cursor, err := s.devices().Find(context.Background(), bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"last_seen_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devices: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &devices)
+	return devices, err
+}
+
+// ForgetDevice removes a known device, so the next login from it counts as new
+func (s *LoginSecurityService) ForgetDevice(userID, deviceID uuid.UUID) error {
+	result, err := s.devices().DeleteOne(context.Background(), bson.M{"uuid": deviceID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to forget device: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// hashConfirmationCode binds a code to its token so equal codes do not hash alike
+func hashConfirmationCode(token, code string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
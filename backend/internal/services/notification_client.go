@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// Security alerts sent to users
+const (
+	AlertNewDevice         = "new_device_login"
+	AlertLoginConfirmation = "login_confirmation"
+	AlertAccountLocked     = "account_locked"
+)
+
+// NotificationClient emails security alerts and organization invitations through the
+// notification service. Without NOTIFICATION_SERVICE_URL they are logged instead, so
+// development setups can read confirmation codes and invitation links from the log.
+type NotificationClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewNotificationClient(cfg *config.Config) *NotificationClient {
+	return &NotificationClient{
+		url:    strings.TrimSuffix(cfg.NotificationServiceURL, "/"),
+		token:  cfg.NotificationServiceToken,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSecurityAlert delivers alert to the user by email
+func (n *NotificationClient) SendSecurityAlert(userID uuid.UUID, alert string, data map[string]interface{}) error {
+	payload := map[string]interface{}{"alert": alert}
+	for key, value := range data {
+		payload[key] = value
+	}
+	if n.url == "" {
+		log.Printf("Security alert %s for user %s: %v", alert, userID, payload)
+		return nil
+	}
+
+	return n.send(alert, map[string]interface{}{
+		"recipient_id":      userID.String(),
+		"notification_type": "security_alert",
+		"channels":          []string{"email"},
+		"priority":          "high",
+		"data":              payload,
+	})
+}
+
+// SendOrganizationInvitation emails an invitation to join an organization. Invitees
+// without an account yet have no userID and are addressed by email alone.
+func (n *NotificationClient) SendOrganizationInvitation(userID *uuid.UUID, email string, data map[string]interface{}) error {
+	if n.url == "" {
+		log.Printf("Organization invitation for %s: %v", email, data)
+		return nil
+	}
+
+	notification := map[string]interface{}{
+		"recipient_email":   email,
+		"notification_type": "organization_invitation",
+		"channels":          []string{"email"},
+		"priority":          "normal",
+		"data":              data,
+	}
+	if userID != nil {
+		notification["recipient_id"] = userID.String()
+	}
+	return n.send("organization_invitation", notification)
+}
+
+func (n *NotificationClient) send(kind string, notification map[string]interface{}) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url+"/api/v1/notifications/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", kind, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send %s: notification service returned %s", kind, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,1248 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrOrganizationNotFound     = errors.New("organization not found")
+	ErrNotOrganizationMember    = errors.New("not a member of this organization")
+	ErrMemberNotFound           = errors.New("member not found")
+	ErrAlreadyMember            = errors.New("already a member of this organization")
+	ErrInvitationNotFound       = errors.New("invitation not found")
+	ErrInvitationExists         = errors.New("a pending invitation already exists for this email")
+	ErrInvitationInvalid        = errors.New("invitation is expired, revoked or already used")
+	ErrInvitationEmail          = errors.New("invitation was sent to a different email address")
+	ErrRoleNotFound             = errors.New("role not found")
+	ErrRoleExists               = errors.New("a role with this key already exists")
+	ErrRoleInUse                = errors.New("role is assigned to members or pending invitations")
+	ErrLastOwner                = errors.New("an organization must keep at least one owner")
+	ErrPermissionEscalation     = errors.New("cannot grant permissions you do not hold")
+	ErrInvalidOrganizationInput = errors.New("invalid organization request")
+)
+
+// Permissions granted by organization roles
+const (
+	PermInvoiceRead       = "invoice:read"
+	PermInvoiceCreate     = "invoice:create"
+	PermInvoiceUpdate     = "invoice:update"
+	PermInvoiceDelete     = "invoice:delete"
+	PermInvoiceVerify     = "invoice:verify"
+	PermFinancingRead     = "financing:read"
+	PermFinancingRequest  = "financing:request"
+	PermFinancingApprove  = "financing:approve"
+	PermInvestmentRead    = "investment:read"
+	PermInvestmentCreate  = "investment:create"
+	PermPaymentInitiate   = "payment:initiate"
+	PermAnalyticsRead     = "analytics:read"
+	PermWebhooksManage    = "webhooks:manage"
+	PermMembersRead       = "members:read"
+	PermMembersManage     = "members:manage"
+	PermRolesManage       = "roles:manage"
+	PermAdminUsers        = "admin:users"
+	PermAdminReports      = "admin:reports"
+	PermAdminAPIKeys      = "admin:api_keys"
+	PermAdminRetention    = "admin:retention"
+	PermAdminOrganization = "admin:organizations"
+	PermAdminWebhooks     = "admin:webhooks"
+)
+
+// OrganizationPermissions describes every permission a role may grant
+var OrganizationPermissions = map[string]string{
+	PermInvoiceRead:       "View the organization's invoices",
+	PermInvoiceCreate:     "Create invoices and upload invoice documents",
+	PermInvoiceUpdate:     "Edit and tokenize invoices",
+	PermInvoiceDelete:     "Delete invoices without active financing",
+	PermInvoiceVerify:     "Verify or reject submitted invoices",
+	PermFinancingRead:     "View financing requests",
+	PermFinancingRequest:  "Request financing against the organization's invoices",
+	PermFinancingApprove:  "Approve or reject financing requests",
+	PermInvestmentRead:    "View investment opportunities and investments",
+	PermInvestmentCreate:  "Invest in financing requests",
+	PermPaymentInitiate:   "Initiate and record payments such as repayments",
+	PermAnalyticsRead:     "View dashboards and analytics",
+	PermWebhooksManage:    "Manage webhook subscriptions",
+	PermMembersRead:       "View members, invitations, roles and the membership audit log",
+	PermMembersManage:     "Invite, remove and change the role of members",
+	PermRolesManage:       "Create, edit and delete custom roles",
+	PermAdminUsers:        "Platform: verify, suspend and unlock user accounts",
+	PermAdminReports:      "Platform: view the admin dashboard and all transactions",
+	PermAdminAPIKeys:      "Platform: issue and revoke partner API keys",
+	PermAdminRetention:    "Platform: manage data retention and legal holds",
+	PermAdminOrganization: "Platform: create organizations and read any membership audit log",
+	PermAdminWebhooks:     "Platform: manage every webhook subscription and delivery",
+}
+
+var memberPermissions = []string{PermAnalyticsRead, PermWebhooksManage, PermMembersRead, PermMembersManage, PermRolesManage}
+
+// organizationTypePermissions limits the permissions an organization's roles may grant,
+// so that for example an SME cannot approve its own financing
+var organizationTypePermissions = map[models.OrganizationType][]string{
+	models.OrganizationSME: append([]string{
+		PermInvoiceRead, PermInvoiceCreate, PermInvoiceUpdate, PermInvoiceDelete,
+		PermFinancingRead, PermFinancingRequest,
+	}, memberPermissions...),
+	models.OrganizationInvestor: append([]string{
+		PermFinancingRead, PermInvestmentRead, PermInvestmentCreate,
+	}, memberPermissions...),
+	models.OrganizationBank: append([]string{
+		PermInvoiceRead, PermInvoiceVerify, PermFinancingRead, PermFinancingApprove,
+		PermInvestmentRead, PermInvestmentCreate, PermPaymentInitiate,
+	}, memberPermissions...),
+}
+
+// OwnerRole is the built-in role holding every permission the organization may grant
+const OwnerRole = "owner"
+
+// BuiltinOrganizationRoles are available in every organization. Their permissions are
+// limited to those the organization's type allows.
+var BuiltinOrganizationRoles = []models.OrganizationRole{
+	{Key: OwnerRole, Name: "Owner", Description: "Full control, including members and roles"},
+	{Key: "cfo", Name: "CFO", Description: "Commits the organization: requests financing for an SME, decides on and pays out financing for a bank",
+		Permissions: []string{PermInvoiceRead, PermFinancingRead, PermFinancingRequest, PermFinancingApprove,
+			PermInvestmentRead, PermInvestmentCreate, PermPaymentInitiate, PermAnalyticsRead, PermMembersRead}},
+	{Key: "ap_clerk", Name: "Accounts payable clerk", Description: "Uploads and maintains invoices",
+		Permissions: []string{PermInvoiceRead, PermInvoiceCreate, PermInvoiceUpdate, PermFinancingRead}},
+	{Key: "viewer", Name: "Viewer", Description: "Read-only access",
+		Permissions: []string{PermInvoiceRead, PermFinancingRead, PermInvestmentRead, PermAnalyticsRead, PermMembersRead}},
+}
+
+var roleKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,39}$`)
+
+// allowedPermissions returns what an organization of type t may grant
+func allowedPermissions(t models.OrganizationType) []string {
+	if t == models.OrganizationPlatform {
+		all := make([]string, 0, len(OrganizationPermissions))
+		for permission := range OrganizationPermissions {
+			all = append(all, permission)
+		}
+		sort.Strings(all)
+		return all
+	}
+	return organizationTypePermissions[t]
+}
+
+func builtinRole(key string, t models.OrganizationType) (models.OrganizationRole, bool) {
+	for _, role := range BuiltinOrganizationRoles {
+		if role.Key != key {
+			continue
+		}
+		role.Builtin = true
+		if key == OwnerRole {
+			role.Permissions = allowedPermissions(t)
+		} else {
+			role.Permissions = intersectPermissions(role.Permissions, allowedPermissions(t))
+		}
+		return role, true
+	}
+	return models.OrganizationRole{}, false
+}
+
+func intersectPermissions(permissions, allowed []string) []string {
+	result := []string{}
+	for _, permission := range permissions {
+		if containsString(allowed, permission) {
+			result = append(result, permission)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// OrganizationAccess is what a user may do in the organization a request acts for
+type OrganizationAccess struct {
+	OrganizationID   uuid.UUID               `json:"organization_id"`
+	OrganizationName string                  `json:"organization_name"`
+	OrganizationType models.OrganizationType `json:"organization_type"`
+	Role             string                  `json:"role"` // Empty for platform admins who are not members
+	Permissions      []string                `json:"permissions"`
+	PlatformAdmin    bool                    `json:"platform_admin"`
+}
+
+// Has reports whether the access grants permission. Platform admins hold every permission.
+func (a *OrganizationAccess) Has(permission string) bool {
+	return a.PlatformAdmin || containsString(a.Permissions, permission)
+}
+
+// OrganizationActor is the user making a membership change, for checks and the audit log
+type OrganizationActor struct {
+	UserID    uuid.UUID
+	IPAddress string
+	Access    *OrganizationAccess
+}
+
+// OrganizationService manages organizations, their members, invitations and roles, and
+// resolves the permissions a user holds in one
+type OrganizationService struct {
+	db       *database.MongoDB
+	cfg      *config.Config
+	notifier *NotificationClient
+}
+
+func NewOrganizationService(db *database.MongoDB, cfg *config.Config, notifier *NotificationClient) *OrganizationService {
+	return &OrganizationService{db: db, cfg: cfg, notifier: notifier}
+}
+
+func (s *OrganizationService) organizations() *mongo.Collection {
+	return s.db.Database.Collection("organizations")
+}
+
+func (s *OrganizationService) memberships() *mongo.Collection {
+	return s.db.Database.Collection("organization_memberships")
+}
+
+func (s *OrganizationService) invitations() *mongo.Collection {
+	return s.db.Database.Collection("organization_invitations")
+}
+
+func (s *OrganizationService) roles() *mongo.Collection {
+	return s.db.Database.Collection("organization_roles")
+}
+
+func (s *OrganizationService) auditEvents() *mongo.Collection {
+	return s.db.Database.Collection("membership_audit_events")
+}
+
+// ResolveAccess returns the user's access in organizationID, or in the organization they
+// joined first when it is nil. Users who belong to no organization get a personal one.
+func (s *OrganizationService) ResolveAccess(userID uuid.UUID, platformRole string, organizationID *uuid.UUID) (*OrganizationAccess, error) {
+	ctx := context.Background()
+	platformAdmin := platformRole == string(models.RoleAdmin)
+
+	var membership models.OrganizationMembership
+	var err error
+	if organizationID != nil {
+		err = s.memberships().FindOne(ctx, bson.M{"organization_id": *organizationID, "user_id": userID}).Decode(&membership)
+	} else {
+		opts := options.FindOne().SetSort(bson.M{"joined_at": 1})
+		err = s.memberships().FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&membership)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		switch {
+		case organizationID != nil && platformAdmin:
+			organization, err := s.GetOrganization(*organizationID)
+			if err != nil {
+				return nil, err
+			}
+			return &OrganizationAccess{
+				OrganizationID:   organization.UUID,
+				OrganizationName: organization.Name,
+				OrganizationType: organization.Type,
+				Permissions:      allowedPermissions(models.OrganizationPlatform),
+				PlatformAdmin:    true,
+			}, nil
+		case organizationID != nil:
+			return nil, ErrNotOrganizationMember
+		}
+		if _, err := s.ensurePersonalOrganization(userID); err != nil {
+			return nil, err
+		}
+		return s.ResolveAccess(userID, platformRole, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load membership: %w", err)
+	}
+
+	organization, err := s.GetOrganization(membership.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.findRole(organization, membership.Role)
+	if err != nil {
+		return nil, err
+	}
+	access := &OrganizationAccess{
+		OrganizationID:   organization.UUID,
+		OrganizationName: organization.Name,
+		OrganizationType: organization.Type,
+		Role:             role.Key,
+		Permissions:      role.Permissions,
+		PlatformAdmin:    platformAdmin,
+	}
+	if platformAdmin {
+		access.Permissions = allowedPermissions(models.OrganizationPlatform)
+	}
+	return access, nil
+}
+
+// findRole looks up a built-in or custom role. Custom roles are limited to the
+// organization type's permissions in case the type changed after they were defined.
+func (s *OrganizationService) findRole(organization *models.Organization, key string) (*models.OrganizationRole, error) {
+	if role, ok := builtinRole(key, organization.Type); ok {
+		return &role, nil
+	}
+
+	var role models.OrganizationRole
+	err := s.roles().FindOne(context.Background(), bson.M{"organization_id": organization.UUID, "key": key}).Decode(&role)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role: %w", err)
+	}
+	role.Permissions = intersectPermissions(role.Permissions, allowedPermissions(organization.Type))
+	return &role, nil
+}
+
+// personalOrganizationType is the organization type created for a user registering alone
+func personalOrganizationType(role models.UserRole) models.OrganizationType {
+	switch role {
+	case models.RoleInvestor:
+		return models.OrganizationInvestor
+	case models.RoleAdmin:
+		return models.OrganizationPlatform
+	default:
+		return models.OrganizationSME
+	}
+}
+
+// EnsurePersonalOrganization makes sure the user belongs to at least one organization,
+// creating one they own from their profile if not. Invoices and financing requests the
+// user made before organizations existed are moved into it.
+func (s *OrganizationService) EnsurePersonalOrganization(user *models.User) (*models.Organization, error) {
+	ctx := context.Background()
+
+	var membership models.OrganizationMembership
+	err := s.memberships().FindOne(ctx, bson.M{"user_id": user.UUID}).Decode(&membership)
+	if err == nil {
+		return s.GetOrganization(membership.OrganizationID)
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to load membership: %w", err)
+	}
+
+	name := strings.TrimSpace(user.CompanyName)
+	if name == "" {
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	now := time.Now()
+	organization := &models.Organization{
+		UUID:      uuid.New(),
+		Name:      name,
+		Type:      personalOrganizationType(user.Role),
+		TaxID:     user.TaxID,
+		CreatedBy: user.UUID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := s.organizations().InsertOne(ctx, organization); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	if err := s.addMember(organization.UUID, user.UUID, OwnerRole, nil); err != nil {
+		return nil, err
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditOrganizationCreated,
+		ActorID:        user.UUID,
+		SubjectUserID:  &user.UUID,
+		Role:           OwnerRole,
+		Details:        map[string]interface{}{"personal": true},
+	})
+
+	unowned := bson.M{"user_id": user.UUID, "organization_id": bson.M{"$exists": false}}
+	claim := bson.M{"$set": bson.M{"organization_id": organization.UUID}}
+	for _, name := range []string{"invoices", "financing_requests"} {
+		if _, err := s.db.Database.Collection(name).UpdateMany(ctx, unowned, claim); err != nil {
+			return nil, fmt.Errorf("failed to move %s into organization: %w", name, err)
+		}
+	}
+	return organization, nil
+}
+
+func (s *OrganizationService) ensurePersonalOrganization(userID uuid.UUID) (*models.Organization, error) {
+	var user models.User
+	err := s.db.Database.Collection("users").FindOne(context.Background(), bson.M{"uuid": userID}).Decode(&user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return s.EnsurePersonalOrganization(&user)
+}
+
+// BackfillOrganizations gives every user without an organization a personal one, so that
+// records created before organizations existed stay reachable
+func (s *OrganizationService) BackfillOrganizations() (int, error) {
+	ctx := context.Background()
+	members, err := s.memberships().Distinct(ctx, "user_id", bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	filter := bson.M{"uuid": bson.M{"$nin": members}, "deleted_at": bson.M{"$exists": false}}
+	cursor, err := s.db.Database.Collection("users").Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	created := 0
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return created, err
+		}
+		if _, err := s.EnsurePersonalOrganization(&user); err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, cursor.Err()
+}
+
+// CreateOrganizationRequest is the input for creating an organization
+type CreateOrganizationRequest struct {
+	Name  string                  `json:"name" binding:"required"`
+	Type  models.OrganizationType `json:"type" binding:"required"`
+	TaxID string                  `json:"tax_id"`
+	// OwnerEmail, used by platform admins, invites the owner instead of making the creator
+	// the owner
+	OwnerEmail string `json:"owner_email"`
+}
+
+// CreateOrganization creates an organization. Users may create SME and investor
+// organizations they own; platform admins may create any type and invite its owner.
+// The owner's invitation is returned with its accept link.
+func (s *OrganizationService) CreateOrganization(req CreateOrganizationRequest, actor OrganizationActor, platformAdmin bool) (*models.Organization, *models.OrganizationInvitation, string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.OwnerEmail = strings.ToLower(strings.TrimSpace(req.OwnerEmail))
+	if req.Name == "" {
+		return nil, nil, "", fmt.Errorf("%w: name is required", ErrInvalidOrganizationInput)
+	}
+	switch req.Type {
+	case models.OrganizationSME, models.OrganizationInvestor:
+	case models.OrganizationBank, models.OrganizationPlatform:
+		if !platformAdmin {
+			return nil, nil, "", fmt.Errorf("%w: only platform admins can create %s organizations", ErrInvalidOrganizationInput, req.Type)
+		}
+	default:
+		return nil, nil, "", fmt.Errorf("%w: unknown organization type %q", ErrInvalidOrganizationInput, req.Type)
+	}
+	if req.OwnerEmail != "" && !platformAdmin {
+		return nil, nil, "", fmt.Errorf("%w: owner_email is only accepted from platform admins", ErrInvalidOrganizationInput)
+	}
+
+	now := time.Now()
+	organization := &models.Organization{
+		UUID:      uuid.New(),
+		Name:      req.Name,
+		Type:      req.Type,
+		TaxID:     strings.TrimSpace(req.TaxID),
+		CreatedBy: actor.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := s.organizations().InsertOne(context.Background(), organization); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to create organization: %w", err)
+	}
+	event := models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditOrganizationCreated,
+		ActorID:        actor.UserID,
+		IPAddress:      actor.IPAddress,
+		Details:        map[string]interface{}{"name": organization.Name, "type": organization.Type},
+	}
+
+	if req.OwnerEmail == "" {
+		if err := s.addMember(organization.UUID, actor.UserID, OwnerRole, nil); err != nil {
+			return nil, nil, "", err
+		}
+		event.SubjectUserID = &actor.UserID
+		event.Role = OwnerRole
+		s.audit(event)
+		return organization, nil, "", nil
+	}
+
+	s.audit(event)
+	invitation, acceptURL, err := s.invite(organization, req.OwnerEmail, OwnerRole, actor)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return organization, invitation, acceptURL, nil
+}
+
+// GetOrganization looks an organization up by UUID
+func (s *OrganizationService) GetOrganization(id uuid.UUID) (*models.Organization, error) {
+	var organization models.Organization
+	err := s.organizations().FindOne(context.Background(), bson.M{"uuid": id}).Decode(&organization)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
+	return &organization, nil
+}
+
+// ListOrganizations returns every organization, optionally of one type
+func (s *OrganizationService) ListOrganizations(orgType string) ([]models.Organization, error) {
+	organizations := []models.Organization{}
+	filter := bson.M{}
+	if orgType != "" {
+		filter["type"] = orgType
+	}
+	cursor, err := s.organizations().Find(context.Background(), filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &organizations)
+	return organizations, err
+}
+
+// UserOrganization is an organization the user belongs to and their role in it
+type UserOrganization struct {
+	Organization models.Organization `json:"organization"`
+	Role         string              `json:"role"`
+	JoinedAt     time.Time           `json:"joined_at"`
+}
+
+// ListUserOrganizations returns the user's organizations, the first joined first. That one
+// is used when a request does not select an organization.
+func (s *OrganizationService) ListUserOrganizations(userID uuid.UUID) ([]UserOrganization, error) {
+	ctx := context.Background()
+	memberships := []models.OrganizationMembership{}
+	cursor, err := s.memberships().Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"joined_at": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+
+	result := []UserOrganization{}
+	for _, membership := range memberships {
+		organization, err := s.GetOrganization(membership.OrganizationID)
+		if errors.Is(err, ErrOrganizationNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, UserOrganization{Organization: *organization, Role: membership.Role, JoinedAt: membership.JoinedAt})
+	}
+	return result, nil
+}
+
+func (s *OrganizationService) addMember(organizationID, userID uuid.UUID, role string, invitedBy *uuid.UUID) error {
+	now := time.Now()
+	membership := &models.OrganizationMembership{
+		UUID:           uuid.New(),
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+		InvitedBy:      invitedBy,
+		JoinedAt:       now,
+		UpdatedAt:      now,
+	}
+	_, err := s.memberships().InsertOne(context.Background(), membership)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadyMember
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	return nil
+}
+
+// MemberView is a membership with the member's name and email
+type MemberView struct {
+	models.OrganizationMembership `bson:",inline"`
+	Email                         string `json:"email"`
+	FirstName                     string `json:"first_name"`
+	LastName                      string `json:"last_name"`
+}
+
+// ListMembers returns an organization's members
+func (s *OrganizationService) ListMembers(organizationID uuid.UUID) ([]MemberView, error) {
+	ctx := context.Background()
+	memberships := []models.OrganizationMembership{}
+	cursor, err := s.memberships().Find(ctx, bson.M{"organization_id": organizationID}, options.Find().SetSort(bson.M{"joined_at": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+
+	members := make([]MemberView, 0, len(memberships))
+	for _, membership := range memberships {
+		member := MemberView{OrganizationMembership: membership}
+		var user models.User
+		if err := s.db.Database.Collection("users").FindOne(ctx, bson.M{"uuid": membership.UserID}).Decode(&user); err == nil {
+			member.Email, member.FirstName, member.LastName = user.Email, user.FirstName, user.LastName
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (s *OrganizationService) getMembership(organizationID, userID uuid.UUID) (*models.OrganizationMembership, error) {
+	var membership models.OrganizationMembership
+	err := s.memberships().FindOne(context.Background(), bson.M{"organization_id": organizationID, "user_id": userID}).Decode(&membership)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load member: %w", err)
+	}
+	return &membership, nil
+}
+
+// checkGrant refuses changes that would give someone permissions the actor does not hold
+func checkGrant(actor OrganizationActor, permissions []string) error {
+	for _, permission := range permissions {
+		if !actor.Access.Has(permission) {
+			return fmt.Errorf("%w: %s", ErrPermissionEscalation, permission)
+		}
+	}
+	return nil
+}
+
+// checkLastOwner refuses to take the owner role from the organization's only owner
+func (s *OrganizationService) checkLastOwner(membership *models.OrganizationMembership) error {
+	if membership.Role != OwnerRole {
+		return nil
+	}
+	owners, err := s.memberships().CountDocuments(context.Background(), bson.M{"organization_id": membership.OrganizationID, "role": OwnerRole})
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// ChangeMemberRole gives a member another role. The actor must hold every permission of
+// both the member's current role and the new one.
+func (s *OrganizationService) ChangeMemberRole(actor OrganizationActor, userID uuid.UUID, roleKey string) (*models.OrganizationMembership, error) {
+	organization, err := s.GetOrganization(actor.Access.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	membership, err := s.getMembership(organization.UUID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.Role == roleKey {
+		return membership, nil
+	}
+	if userID == actor.UserID && !actor.Access.PlatformAdmin {
+		return nil, fmt.Errorf("%w: you cannot change your own role", ErrInvalidOrganizationInput)
+	}
+
+	current, err := s.findRole(organization, membership.Role)
+	if err != nil && !errors.Is(err, ErrRoleNotFound) {
+		return nil, err
+	}
+	if current != nil {
+		if err := checkGrant(actor, current.Permissions); err != nil {
+			return nil, err
+		}
+	}
+	role, err := s.findRole(organization, roleKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrant(actor, role.Permissions); err != nil {
+		return nil, err
+	}
+	if err := s.checkLastOwner(membership); err != nil {
+		return nil, err
+	}
+
+	previous := membership.Role
+	membership.Role = role.Key
+	membership.UpdatedAt = time.Now()
+	_, err = s.memberships().UpdateOne(context.Background(), bson.M{"uuid": membership.UUID},
+		bson.M{"$set": bson.M{"role": membership.Role, "updated_at": membership.UpdatedAt}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to change role: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditMemberRoleChanged,
+		ActorID:        actor.UserID,
+		SubjectUserID:  &userID,
+		Role:           membership.Role,
+		PreviousRole:   previous,
+		IPAddress:      actor.IPAddress,
+	})
+	return membership, nil
+}
+
+// RemoveMember takes a user out of the organization. Members may always leave unless they
+// are the last owner.
+func (s *OrganizationService) RemoveMember(actor OrganizationActor, userID uuid.UUID) error {
+	organization, err := s.GetOrganization(actor.Access.OrganizationID)
+	if err != nil {
+		return err
+	}
+	membership, err := s.getMembership(organization.UUID, userID)
+	if err != nil {
+		return err
+	}
+	if userID != actor.UserID {
+		if !actor.Access.Has(PermMembersManage) {
+			return fmt.Errorf("%w: %s", ErrPermissionEscalation, PermMembersManage)
+		}
+		if role, err := s.findRole(organization, membership.Role); err == nil {
+			if err := checkGrant(actor, role.Permissions); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.checkLastOwner(membership); err != nil {
+		return err
+	}
+
+	if _, err := s.memberships().DeleteOne(context.Background(), bson.M{"uuid": membership.UUID}); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditMemberRemoved,
+		ActorID:        actor.UserID,
+		SubjectUserID:  &userID,
+		PreviousRole:   membership.Role,
+		IPAddress:      actor.IPAddress,
+		Details:        map[string]interface{}{"left": userID == actor.UserID},
+	})
+	return nil
+}
+
+// InviteMember emails an invitation to join the actor's organization with a role. It is
+// returned with the link to accept it, which cannot be retrieved again.
+func (s *OrganizationService) InviteMember(actor OrganizationActor, email, roleKey string) (*models.OrganizationInvitation, string, error) {
+	organization, err := s.GetOrganization(actor.Access.OrganizationID)
+	if err != nil {
+		return nil, "", err
+	}
+	role, err := s.findRole(organization, roleKey)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkGrant(actor, role.Permissions); err != nil {
+		return nil, "", err
+	}
+	return s.invite(organization, strings.ToLower(strings.TrimSpace(email)), role.Key, actor)
+}
+
+func (s *OrganizationService) invite(organization *models.Organization, email, roleKey string, actor OrganizationActor) (*models.OrganizationInvitation, string, error) {
+	ctx := context.Background()
+	if !strings.Contains(email, "@") {
+		return nil, "", fmt.Errorf("%w: a valid email is required", ErrInvalidOrganizationInput)
+	}
+
+	var inviteeID *uuid.UUID
+	var user models.User
+	if err := s.db.Database.Collection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err == nil {
+		if _, err := s.getMembership(organization.UUID, user.UUID); err == nil {
+			return nil, "", ErrAlreadyMember
+		}
+		inviteeID = &user.UUID
+	}
+	now := time.Now()
+	pending := bson.M{
+		"organization_id": organization.UUID,
+		"email":           email,
+		"accepted_at":     bson.M{"$exists": false},
+		"revoked_at":      bson.M{"$exists": false},
+		"expires_at":      bson.M{"$gt": now},
+	}
+	if count, err := s.invitations().CountDocuments(ctx, pending); err != nil {
+		return nil, "", fmt.Errorf("failed to check invitations: %w", err)
+	} else if count > 0 {
+		return nil, "", ErrInvitationExists
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	invitation := &models.OrganizationInvitation{
+		UUID:           uuid.New(),
+		OrganizationID: organization.UUID,
+		Email:          email,
+		Role:           roleKey,
+		TokenHash:      hashInvitationToken(token),
+		InvitedBy:      actor.UserID,
+		ExpiresAt:      now.Add(s.cfg.OrganizationInvitationTTL),
+		CreatedAt:      now,
+	}
+	if _, err := s.invitations().InsertOne(ctx, invitation); err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditMemberInvited,
+		ActorID:        actor.UserID,
+		SubjectEmail:   email,
+		Role:           roleKey,
+		IPAddress:      actor.IPAddress,
+	})
+
+	acceptURL := s.cfg.OrganizationInvitationURL + token
+	err := s.notifier.SendOrganizationInvitation(inviteeID, email, map[string]interface{}{
+		"organization_name": organization.Name,
+		"role":              roleKey,
+		"accept_url":        acceptURL,
+		"expires_at":        invitation.ExpiresAt,
+	})
+	if err != nil {
+		log.Printf("Failed to email invitation %s: %v", invitation.UUID, err)
+	}
+	return invitation, acceptURL, nil
+}
+
+// ListInvitations returns an organization's invitations, newest first
+func (s *OrganizationService) ListInvitations(organizationID uuid.UUID, pendingOnly bool) ([]models.OrganizationInvitation, error) {
+	invitations := []models.OrganizationInvitation{}
+	filter := bson.M{"organization_id": organizationID}
+	if pendingOnly {
+		filter["accepted_at"] = bson.M{"$exists": false}
+		filter["revoked_at"] = bson.M{"$exists": false}
+		filter["expires_at"] = bson.M{"$gt": time.Now()}
+	}
+	cursor, err := s.invitations().Find(context.Background(), filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &invitations)
+	return invitations, err
+}
+
+// RevokeInvitation cancels a pending invitation
+func (s *OrganizationService) RevokeInvitation(actor OrganizationActor, invitationID uuid.UUID) (*models.OrganizationInvitation, error) {
+	var invitation models.OrganizationInvitation
+	now := time.Now()
+	filter := bson.M{
+		"uuid":            invitationID,
+		"organization_id": actor.Access.OrganizationID,
+		"accepted_at":     bson.M{"$exists": false},
+		"revoked_at":      bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"revoked_at": now, "revoked_by": actor.UserID}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.invitations().FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: invitation.OrganizationID,
+		Action:         models.AuditInvitationRevoked,
+		ActorID:        actor.UserID,
+		SubjectEmail:   invitation.Email,
+		Role:           invitation.Role,
+		IPAddress:      actor.IPAddress,
+	})
+	return &invitation, nil
+}
+
+// AcceptInvitation adds the user to the organization they were invited to. The invitation
+// must have been sent to the user's email address.
+func (s *OrganizationService) AcceptInvitation(user *models.User, token, ipAddress string) (*UserOrganization, error) {
+	ctx := context.Background()
+	var invitation models.OrganizationInvitation
+	err := s.invitations().FindOne(ctx, bson.M{"token_hash": hashInvitationToken(strings.TrimSpace(token))}).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invitation: %w", err)
+	}
+	now := time.Now()
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || !now.Before(invitation.ExpiresAt) {
+		return nil, ErrInvitationInvalid
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationEmail
+	}
+
+	// Records the user made before organizations existed stay in their personal one
+	if _, err := s.EnsurePersonalOrganization(user); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"uuid": invitation.UUID, "accepted_at": bson.M{"$exists": false}, "revoked_at": bson.M{"$exists": false}}
+	result, err := s.invitations().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"accepted_at": now, "accepted_by": user.UUID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrInvitationInvalid
+	}
+	if err := s.addMember(invitation.OrganizationID, user.UUID, invitation.Role, &invitation.InvitedBy); err != nil {
+		return nil, err
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: invitation.OrganizationID,
+		Action:         models.AuditMemberJoined,
+		ActorID:        user.UUID,
+		SubjectUserID:  &user.UUID,
+		SubjectEmail:   invitation.Email,
+		Role:           invitation.Role,
+		IPAddress:      ipAddress,
+		Details:        map[string]interface{}{"invitation_id": invitation.UUID, "invited_by": invitation.InvitedBy},
+	})
+
+	organization, err := s.GetOrganization(invitation.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	return &UserOrganization{Organization: *organization, Role: invitation.Role, JoinedAt: now}, nil
+}
+
+// SSOMemberRole is the role given to users who join an organization by logging in
+// through its SSO connection, unless the connection names another
+const SSOMemberRole = "viewer"
+
+// ProvisionedMember is a user the user management service provisioned through an
+// organization's SSO connection
+type ProvisionedMember struct {
+	UserID     uuid.UUID `json:"user_id" binding:"required"`
+	Email      string    `json:"email" binding:"required"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Role       string    `json:"role" binding:"required"` // Platform role
+	MemberRole string    `json:"member_role"`             // Role in the organization when joining
+}
+
+// ProvisionMember makes a user who logged in through the organization's SSO connection a
+// member of it, creating their account here on first login. Existing members keep the
+// role the organization gave them.
+func (s *OrganizationService) ProvisionMember(organizationID uuid.UUID, member ProvisionedMember) (*models.OrganizationMembership, error) {
+	ctx := context.Background()
+	organization, err := s.GetOrganization(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	roleKey := member.MemberRole
+	if roleKey == "" {
+		roleKey = SSOMemberRole
+	}
+	if _, err := s.findRole(organization, roleKey); err != nil {
+		return nil, err
+	}
+
+	users := s.db.Database.Collection("users")
+	err = users.FindOne(ctx, bson.M{"uuid": member.UserID}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		now := time.Now()
+		_, err = users.InsertOne(ctx, &models.User{
+			UUID:       member.UserID,
+			Email:      strings.ToLower(strings.TrimSpace(member.Email)),
+			FirstName:  member.FirstName,
+			LastName:   member.LastName,
+			Role:       models.UserRole(member.Role),
+			IsVerified: true,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+		// A password account registered here with the same email stays separate; the
+		// membership below is what grants the SSO user access
+		if mongo.IsDuplicateKeyError(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	var membership models.OrganizationMembership
+	filter := bson.M{"organization_id": organizationID, "user_id": member.UserID}
+	err = s.memberships().FindOne(ctx, filter).Decode(&membership)
+	if err == nil {
+		return &membership, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to load membership: %w", err)
+	}
+	if err := s.addMember(organizationID, member.UserID, roleKey, nil); err != nil && !errors.Is(err, ErrAlreadyMember) {
+		return nil, err
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditMemberJoined,
+		ActorID:        member.UserID,
+		SubjectUserID:  &member.UserID,
+		SubjectEmail:   member.Email,
+		Role:           roleKey,
+		Details:        map[string]interface{}{"sso": true},
+	})
+
+	if err := s.memberships().FindOne(ctx, filter).Decode(&membership); err != nil {
+		return nil, fmt.Errorf("failed to load membership: %w", err)
+	}
+	return &membership, nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListRoles returns the built-in roles followed by the organization's custom roles
+func (s *OrganizationService) ListRoles(organizationID uuid.UUID) ([]models.OrganizationRole, error) {
+	organization, err := s.GetOrganization(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []models.OrganizationRole{}
+	for _, builtin := range BuiltinOrganizationRoles {
+		role, _ := builtinRole(builtin.Key, organization.Type)
+		roles = append(roles, role)
+	}
+
+	custom := []models.OrganizationRole{}
+	cursor, err := s.roles().Find(context.Background(), bson.M{"organization_id": organizationID}, options.Find().SetSort(bson.M{"key": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer cursor.Close(context.Background())
+	if err := cursor.All(context.Background(), &custom); err != nil {
+		return nil, err
+	}
+	return append(roles, custom...), nil
+}
+
+// RoleInput is the input for creating or editing a custom role
+type RoleInput struct {
+	Key         string   `json:"key"` // Only used when creating
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+func (s *OrganizationService) validateRole(organization *models.Organization, actor OrganizationActor, input *RoleInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidOrganizationInput)
+	}
+	if len(input.Permissions) == 0 {
+		return fmt.Errorf("%w: at least one permission is required", ErrInvalidOrganizationInput)
+	}
+
+	allowed := allowedPermissions(organization.Type)
+	normalized := []string{}
+	for _, permission := range input.Permissions {
+		permission = strings.TrimSpace(permission)
+		if _, ok := OrganizationPermissions[permission]; !ok {
+			return fmt.Errorf("%w: unknown permission %q", ErrInvalidOrganizationInput, permission)
+		}
+		if !containsString(allowed, permission) {
+			return fmt.Errorf("%w: %s organizations cannot grant %s", ErrInvalidOrganizationInput, organization.Type, permission)
+		}
+		if !containsString(normalized, permission) {
+			normalized = append(normalized, permission)
+		}
+	}
+	sort.Strings(normalized)
+	input.Permissions = normalized
+	return checkGrant(actor, normalized)
+}
+
+// CreateRole defines a custom role in the actor's organization
+func (s *OrganizationService) CreateRole(actor OrganizationActor, input RoleInput) (*models.OrganizationRole, error) {
+	organization, err := s.GetOrganization(actor.Access.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	input.Key = strings.TrimSpace(input.Key)
+	if !roleKeyPattern.MatchString(input.Key) {
+		return nil, fmt.Errorf("%w: key must be 2-40 lowercase letters, digits or underscores", ErrInvalidOrganizationInput)
+	}
+	if _, ok := builtinRole(input.Key, organization.Type); ok {
+		return nil, ErrRoleExists
+	}
+	if err := s.validateRole(organization, actor, &input); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	role := &models.OrganizationRole{
+		UUID:           uuid.New(),
+		OrganizationID: organization.UUID,
+		Key:            input.Key,
+		Name:           input.Name,
+		Description:    strings.TrimSpace(input.Description),
+		Permissions:    input.Permissions,
+		CreatedBy:      actor.UserID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	_, err = s.roles().InsertOne(context.Background(), role)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditRoleCreated,
+		ActorID:        actor.UserID,
+		Role:           role.Key,
+		IPAddress:      actor.IPAddress,
+		Details:        map[string]interface{}{"permissions": role.Permissions},
+	})
+	return role, nil
+}
+
+// UpdateRole changes a custom role; members holding it are affected on their next request
+func (s *OrganizationService) UpdateRole(actor OrganizationActor, key string, input RoleInput) (*models.OrganizationRole, error) {
+	organization, err := s.GetOrganization(actor.Access.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	var role models.OrganizationRole
+	err = s.roles().FindOne(context.Background(), bson.M{"organization_id": organization.UUID, "key": key}).Decode(&role)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role: %w", err)
+	}
+	if err := checkGrant(actor, role.Permissions); err != nil {
+		return nil, err
+	}
+	if err := s.validateRole(organization, actor, &input); err != nil {
+		return nil, err
+	}
+
+	previous := role.Permissions
+	role.Name = input.Name
+	role.Description = strings.TrimSpace(input.Description)
+	role.Permissions = input.Permissions
+	role.UpdatedAt = time.Now()
+	_, err = s.roles().UpdateOne(context.Background(), bson.M{"uuid": role.UUID}, bson.M{"$set": bson.M{
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": role.Permissions,
+		"updated_at":  role.UpdatedAt,
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organization.UUID,
+		Action:         models.AuditRoleUpdated,
+		ActorID:        actor.UserID,
+		Role:           role.Key,
+		IPAddress:      actor.IPAddress,
+		Details:        map[string]interface{}{"permissions": role.Permissions, "previous_permissions": previous},
+	})
+	return &role, nil
+}
+
+// DeleteRole removes a custom role that no member or pending invitation uses
+func (s *OrganizationService) DeleteRole(actor OrganizationActor, key string) error {
+	ctx := context.Background()
+	organizationID := actor.Access.OrganizationID
+	var role models.OrganizationRole
+	err := s.roles().FindOne(ctx, bson.M{"organization_id": organizationID, "key": key}).Decode(&role)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load role: %w", err)
+	}
+	if err := checkGrant(actor, role.Permissions); err != nil {
+		return err
+	}
+
+	members, err := s.memberships().CountDocuments(ctx, bson.M{"organization_id": organizationID, "role": key})
+	if err != nil {
+		return fmt.Errorf("failed to check role use: %w", err)
+	}
+	invitations, err := s.invitations().CountDocuments(ctx, bson.M{
+		"organization_id": organizationID,
+		"role":            key,
+		"accepted_at":     bson.M{"$exists": false},
+		"revoked_at":      bson.M{"$exists": false},
+		"expires_at":      bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check role use: %w", err)
+	}
+	if members+invitations > 0 {
+		return ErrRoleInUse
+	}
+
+	if _, err := s.roles().DeleteOne(ctx, bson.M{"uuid": role.UUID}); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	s.audit(models.MembershipAuditEvent{
+		OrganizationID: organizationID,
+		Action:         models.AuditRoleDeleted,
+		ActorID:        actor.UserID,
+		Role:           role.Key,
+		IPAddress:      actor.IPAddress,
+		Details:        map[string]interface{}{"permissions": role.Permissions},
+	})
+	return nil
+}
+
+// audit records a membership change. Failures are logged rather than returned because the
+// change itself has already been made.
+func (s *OrganizationService) audit(event models.MembershipAuditEvent) {
+	event.UUID = uuid.New()
+	event.CreatedAt = time.Now()
+	if _, err := s.auditEvents().InsertOne(context.Background(), event); err != nil {
+		log.Printf("Failed to record %s audit event for organization %s: %v", event.Action, event.OrganizationID, err)
+	}
+}
+
+// ListAuditEvents returns an organization's membership changes, newest first
+func (s *OrganizationService) ListAuditEvents(organizationID uuid.UUID, limit int64) ([]models.MembershipAuditEvent, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	events := []models.MembershipAuditEvent{}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+	cursor, err := s.auditEvents().Find(context.Background(), bson.M{"organization_id": organizationID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &events)
+	return events, err
+}
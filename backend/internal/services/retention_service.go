@@ -0,0 +1,640 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+	"shared/retention"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrRetentionRuleNotFound = retention.ErrRuleNotFound
+	ErrInvalidRetentionRule  = retention.ErrInvalidRule
+	ErrLegalHoldNotFound     = retention.ErrHoldNotFound
+	ErrInvalidLegalHold      = retention.ErrInvalidHold
+	ErrPurgeRunNotFound      = retention.ErrRunNotFound
+	ErrPurgeRunInProgress    = retention.ErrRunInProgress
+	ErrPurgeReportUnsigned   = retention.ErrReportUnsigned
+)
+
+// retentionCollection describes how retention reaches one collection. Financial
+// collections are regulated: they must be kept for DataRetentionPeriod unless a rule is
+// limited to transient statuses, in which no money moved.
+type retentionCollection struct {
+	retention.Entity
+	ownerField string // The user a record belongs to, matched against legal holds
+	// anonymize lists the fields scrubbed by the anonymize action; collections without it
+	// can only be purged
+	anonymize bson.M
+}
+
+var retentionCollections = map[string]retentionCollection{
+	"users": {
+		Entity:     retention.Entity{Bases: []string{"deleted_at"}},
+		ownerField: "uuid",
+		anonymize: bson.M{
+			// Emails are unique, so each gets a placeholder derived from the document id
+			"email":          bson.M{"$concat": bson.A{"anonymized-", bson.M{"$toString": "$_id"}, "@redacted.invalid"}},
+			"password_hash":  "",
+			"first_name":     "",
+			"last_name":      "",
+			"company_name":   "",
+			"tax_id":         "",
+			"wallet_address": "",
+		},
+	},
+	"invoices": {
+		Entity: retention.Entity{Bases: []string{"deleted_at"}, Regulated: true, TransientStatuses: []string{
+			string(models.InvoiceStatusPending), string(models.InvoiceStatusVerified), string(models.InvoiceStatusRejected),
+		}},
+		ownerField: "user_id",
+		anonymize: bson.M{
+			"customer_name":  "",
+			"customer_email": "",
+			"description":    "",
+			"document_url":   "",
+		},
+	},
+	"financing_requests": {
+		Entity: retention.Entity{Bases: []string{"deleted_at"}, Regulated: true, TransientStatuses: []string{
+			string(models.FinancingStatusPending), string(models.FinancingStatusRejected), string(models.FinancingStatusExpired),
+		}},
+		ownerField: "user_id",
+		anonymize:  bson.M{"description": ""},
+	},
+	"investments":              {Entity: retention.Entity{Bases: []string{"deleted_at"}, Regulated: true}, ownerField: "investor_id"},
+	"transactions":             {Entity: retention.Entity{Bases: []string{"deleted_at"}, Regulated: true}, ownerField: "user_id", anonymize: bson.M{"description": ""}},
+	"webhook_deliveries":       {Entity: retention.Entity{Bases: []string{"deleted_at"}}, ownerField: "user_id"},
+	"sessions":                 {Entity: retention.Entity{Bases: []string{"deleted_at"}}, ownerField: "user_id"},
+	"login_attempts":           {Entity: retention.Entity{Bases: []string{"deleted_at", "attempted_at"}}, ownerField: "user_id"},
+	"known_devices":            {Entity: retention.Entity{Bases: []string{"deleted_at", "last_seen_at"}}, ownerField: "user_id"},
+	"login_confirmations":      {Entity: retention.Entity{Bases: []string{"deleted_at", "expires_at"}}, ownerField: "user_id"},
+	"organization_invitations": {Entity: retention.Entity{Bases: []string{"deleted_at", "expires_at"}}, ownerField: "accepted_by"},
+	"membership_audit_events":  {Entity: retention.Entity{Bases: []string{"deleted_at"}}, ownerField: "subject_user_id"},
+}
+
+// defaultRetentionRules are created on startup when missing. Existing rules are never
+// overwritten, so changes made through the admin API survive restarts.
+func defaultRetentionRules(cfg *config.Config) []models.RetentionRule {
+	financialDays := int(cfg.DataRetentionPeriod.Hours() / 24)
+	deletedDays := int(cfg.DeletedRecordRetention.Hours() / 24)
+	return []models.RetentionRule{
+		{Name: "deleted_users", Entity: "users", Basis: "deleted_at", RetentionDays: deletedDays, Action: models.RetentionActionAnonymize,
+			Description: "Scrub personal data of deleted accounts"},
+		{Name: "deleted_unfunded_invoices", Entity: "invoices", Basis: "deleted_at", RetentionDays: deletedDays, Action: models.RetentionActionPurge,
+			Statuses:    []string{string(models.InvoiceStatusPending), string(models.InvoiceStatusVerified), string(models.InvoiceStatusRejected)},
+			Description: "Delete invoices removed before they were financed"},
+		{Name: "closed_invoices", Entity: "invoices", Basis: "updated_at", RetentionDays: financialDays, Action: models.RetentionActionPurge,
+			Statuses:    []string{string(models.InvoiceStatusPaid), string(models.InvoiceStatusRejected)},
+			Description: "Delete settled invoices after the financial record retention period"},
+		{Name: "closed_financing_requests", Entity: "financing_requests", Basis: "updated_at", RetentionDays: financialDays, Action: models.RetentionActionPurge,
+			Statuses:    []string{string(models.FinancingStatusCompleted), string(models.FinancingStatusRejected), string(models.FinancingStatusExpired)},
+			Description: "Delete closed financing requests after the financial record retention period"},
+		{Name: "closed_investments", Entity: "investments", Basis: "updated_at", RetentionDays: financialDays, Action: models.RetentionActionPurge,
+			Statuses:    []string{string(models.InvestmentStatusCompleted), string(models.InvestmentStatusDefaulted)},
+			Description: "Delete closed investments after the financial record retention period"},
+		{Name: "transactions", Entity: "transactions", Basis: "created_at", RetentionDays: financialDays, Action: models.RetentionActionPurge,
+			Description: "Delete ledger transactions after the financial record retention period"},
+		{Name: "finished_webhook_deliveries", Entity: "webhook_deliveries", Basis: "created_at", RetentionDays: deletedDays, Action: models.RetentionActionPurge,
+			Statuses:    []string{string(models.WebhookDeliverySucceeded), string(models.WebhookDeliveryDeadLetter)},
+			Description: "Delete delivered and dead-lettered webhook deliveries"},
+		{Name: "old_sessions", Entity: "sessions", Basis: "created_at", RetentionDays: deletedDays, Action: models.RetentionActionPurge,
+			Description: "Delete session history; sessions end long before this through their absolute timeout"},
+		{Name: "old_login_attempts", Entity: "login_attempts", Basis: "attempted_at", RetentionDays: deletedDays, Action: models.RetentionActionPurge,
+			Description: "Delete login history, including attempts against unknown accounts"},
+		{Name: "inactive_devices", Entity: "known_devices", Basis: "last_seen_at", RetentionDays: 365, Action: models.RetentionActionPurge,
+			Description: "Forget devices not used for a year; signing in from one again alerts the user"},
+		{Name: "expired_login_confirmations", Entity: "login_confirmations", Basis: "expires_at", RetentionDays: 30, Action: models.RetentionActionPurge,
+			Description: "Delete used and expired login confirmation codes"},
+		{Name: "expired_organization_invitations", Entity: "organization_invitations", Basis: "expires_at", RetentionDays: 90, Action: models.RetentionActionPurge,
+			Description: "Delete invitations 90 days after they expired; accepting one is kept in the membership audit log"},
+	}
+}
+
+// CreateRetentionRuleRequest is the input for adding a rule
+type CreateRetentionRuleRequest struct {
+	Name          string                 `json:"name" binding:"required"`
+	Entity        string                 `json:"entity" binding:"required"`
+	Basis         string                 `json:"basis" binding:"required"`
+	Statuses      []string               `json:"statuses"`
+	RetentionDays int                    `json:"retention_days" binding:"required"`
+	Action        models.RetentionAction `json:"action" binding:"required"`
+	Description   string                 `json:"description"`
+}
+
+// UpdateRetentionRuleRequest changes the given fields of a rule
+type UpdateRetentionRuleRequest struct {
+	RetentionDays *int                    `json:"retention_days"`
+	Action        *models.RetentionAction `json:"action"`
+	Statuses      *[]string               `json:"statuses"`
+	Enabled       *bool                   `json:"enabled"`
+	Description   *string                 `json:"description"`
+}
+
+// PlaceLegalHoldRequest is the input for placing a hold
+type PlaceLegalHoldRequest struct {
+	Entity        string     `json:"entity"`
+	RecordID      *uuid.UUID `json:"record_id"`
+	SubjectUserID *uuid.UUID `json:"subject_user_id"`
+	Reason        string     `json:"reason" binding:"required"`
+	CaseReference string     `json:"case_reference"`
+}
+
+// RetentionService applies retention rules to the platform's collections and keeps
+// reports of every run, signed with the retention certificate
+type RetentionService struct {
+	db     *database.MongoDB
+	cfg    *config.Config
+	engine *retention.Engine
+}
+
+func NewRetentionService(db *database.MongoDB, cfg *config.Config) *RetentionService {
+	s := &RetentionService{db: db, cfg: cfg}
+	s.engine = retention.NewEngine(&retentionStore{service: s}, retention.Config{
+		Service:         "backend",
+		CertificatePath: cfg.RetentionCertificatePath,
+		SigningKeyPath:  cfg.RetentionSigningKeyPath,
+		MinimumDays:     int(cfg.DataRetentionPeriod.Hours() / 24),
+	})
+	return s
+}
+
+// SeedRules creates the default rules that do not exist yet
+func (s *RetentionService) SeedRules() error {
+	collection := s.db.Database.Collection("retention_rules")
+	now := time.Now()
+	for _, rule := range defaultRetentionRules(s.cfg) {
+		rule.Enabled = true
+		rule.CreatedAt = now
+		rule.UpdatedAt = now
+		_, err := collection.UpdateOne(context.Background(), bson.M{"name": rule.Name},
+			bson.M{"$setOnInsert": rule}, options.Update().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("failed to seed retention rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *RetentionService) ListRules() ([]models.RetentionRule, error) {
+	rules := []models.RetentionRule{}
+	collection := s.db.Database.Collection("retention_rules")
+
+	cursor, err := collection.Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{Key: "entity", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention rules: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &rules)
+	return rules, err
+}
+
+func (s *RetentionService) CreateRule(req CreateRetentionRuleRequest, createdBy uuid.UUID) (*models.RetentionRule, error) {
+	now := time.Now()
+	rule := &models.RetentionRule{
+		Name:          strings.TrimSpace(req.Name),
+		Entity:        req.Entity,
+		Basis:         req.Basis,
+		Statuses:      req.Statuses,
+		RetentionDays: req.RetentionDays,
+		Action:        req.Action,
+		Enabled:       true,
+		Description:   req.Description,
+		UpdatedBy:     &createdBy,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if rule.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRetentionRule)
+	}
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	collection := s.db.Database.Collection("retention_rules")
+	if _, err := collection.InsertOne(context.Background(), rule); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: a rule named %q already exists", ErrInvalidRetentionRule, rule.Name)
+		}
+		return nil, fmt.Errorf("failed to create retention rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *RetentionService) UpdateRule(name string, req UpdateRetentionRuleRequest, updatedBy uuid.UUID) (*models.RetentionRule, error) {
+	var rule models.RetentionRule
+	collection := s.db.Database.Collection("retention_rules")
+	err := collection.FindOne(context.Background(), bson.M{"name": name}).Decode(&rule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRetentionRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retention rule: %w", err)
+	}
+
+	if req.RetentionDays != nil {
+		rule.RetentionDays = *req.RetentionDays
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.Statuses != nil {
+		rule.Statuses = *req.Statuses
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.UpdatedBy = &updatedBy
+	rule.UpdatedAt = time.Now()
+
+	_, err = collection.UpdateOne(context.Background(), bson.M{"name": name}, bson.M{"$set": bson.M{
+		"retention_days": rule.RetentionDays,
+		"action":         rule.Action,
+		"statuses":       rule.Statuses,
+		"enabled":        rule.Enabled,
+		"description":    rule.Description,
+		"updated_by":     rule.UpdatedBy,
+		"updated_at":     rule.UpdatedAt,
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update retention rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *RetentionService) validateRule(rule *models.RetentionRule) error {
+	return s.engine.ValidateRule(engineRule(*rule))
+}
+
+func engineRule(rule models.RetentionRule) retention.Rule {
+	return retention.Rule{
+		Name:          rule.Name,
+		Entity:        rule.Entity,
+		Basis:         rule.Basis,
+		Statuses:      rule.Statuses,
+		RetentionDays: rule.RetentionDays,
+		Action:        string(rule.Action),
+		Enabled:       rule.Enabled,
+	}
+}
+
+// PlaceHold places a legal hold on a record or on everything belonging to a user
+func (s *RetentionService) PlaceHold(req PlaceLegalHoldRequest, placedBy uuid.UUID) (*models.LegalHold, error) {
+	if err := s.engine.ValidateHold(req.Entity, req.RecordID, req.SubjectUserID, req.Reason, "subject_user_id"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	hold := &models.LegalHold{
+		UUID:          uuid.New(),
+		Entity:        req.Entity,
+		RecordID:      req.RecordID,
+		SubjectUserID: req.SubjectUserID,
+		Reason:        strings.TrimSpace(req.Reason),
+		CaseReference: req.CaseReference,
+		PlacedBy:      placedBy,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	collection := s.db.Database.Collection("legal_holds")
+	if _, err := collection.InsertOne(context.Background(), hold); err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+	return hold, nil
+}
+
+// ReleaseHold releases an active hold; releasing a released hold is a no-op
+func (s *RetentionService) ReleaseHold(id uuid.UUID, releasedBy uuid.UUID, note string) (*models.LegalHold, error) {
+	var hold models.LegalHold
+	collection := s.db.Database.Collection("legal_holds")
+
+	now := time.Now()
+	filter := bson.M{"uuid": id, "released_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"released_at": now, "released_by": releasedBy, "release_note": note, "updated_at": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&hold)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = collection.FindOne(context.Background(), bson.M{"uuid": id}).Decode(&hold)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrLegalHoldNotFound
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (s *RetentionService) ListHolds(activeOnly bool) ([]models.LegalHold, error) {
+	holds := []models.LegalHold{}
+	collection := s.db.Database.Collection("legal_holds")
+
+	filter := bson.M{}
+	if activeOnly {
+		filter["released_at"] = bson.M{"$exists": false}
+	}
+	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &holds)
+	return holds, err
+}
+
+// RunPurge applies every enabled rule and stores a signed report of the run. Dry runs
+// only count what would be purged.
+func (s *RetentionService) RunPurge(ctx context.Context, dryRun bool, triggeredBy string) (*models.PurgeRun, error) {
+	run, err := s.engine.RunPurge(ctx, dryRun, triggeredBy)
+	if run == nil {
+		return nil, err
+	}
+	return purgeRunModel(run), err
+}
+
+// VerifyRun checks a stored report against its digest and the certificate's signature
+func (s *RetentionService) VerifyRun(id uuid.UUID) (bool, string, error) {
+	run, err := s.GetRun(id)
+	if err != nil {
+		return false, "", err
+	}
+	return s.engine.Verify(&retention.Run{
+		Report:                 run.Report,
+		ReportSHA256:           run.ReportSHA256,
+		Signature:              run.Signature,
+		CertificateFingerprint: run.CertificateFingerprint,
+	})
+}
+
+func (s *RetentionService) GetRun(id uuid.UUID) (*models.PurgeRun, error) {
+	var run models.PurgeRun
+	collection := s.db.Database.Collection("purge_runs")
+	err := collection.FindOne(context.Background(), bson.M{"uuid": id}).Decode(&run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPurgeRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load purge run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListRuns returns the most recent runs without their report bodies
+func (s *RetentionService) ListRuns(limit int64) ([]models.PurgeRun, error) {
+	runs := []models.PurgeRun{}
+	collection := s.db.Database.Collection("purge_runs")
+
+	opts := options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(limit).SetProjection(bson.M{"report": 0})
+	cursor, err := collection.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge runs: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &runs)
+	return runs, err
+}
+
+// StartScheduler runs the retention rules every RetentionRunInterval until ctx is
+// cancelled
+func (s *RetentionService) StartScheduler(ctx context.Context) {
+	if !s.cfg.RetentionEnabled {
+		return
+	}
+	s.engine.Schedule(ctx, s.cfg.RetentionRunInterval)
+}
+
+// retentionStore keeps rules, holds and runs in their collections and applies rules to
+// the collections in retentionCollections
+type retentionStore struct {
+	service *RetentionService
+}
+
+func (r *retentionStore) Entity(name string) (retention.Entity, bool) {
+	collection, ok := retentionCollections[name]
+	if !ok {
+		return retention.Entity{}, false
+	}
+	entity := collection.Entity
+	entity.Anonymizable = collection.anonymize != nil
+	return entity, true
+}
+
+func (r *retentionStore) Rules(ctx context.Context) ([]retention.Rule, error) {
+	rules, err := r.service.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]retention.Rule, len(rules))
+	for i, rule := range rules {
+		out[i] = engineRule(rule)
+	}
+	return out, nil
+}
+
+func (r *retentionStore) ActiveHolds(ctx context.Context) ([]retention.Hold, error) {
+	holds, err := r.service.ListHolds(true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]retention.Hold, len(holds))
+	for i, hold := range holds {
+		out[i] = retention.Hold{Entity: hold.Entity, RecordID: hold.RecordID, SubjectID: hold.SubjectUserID}
+	}
+	return out, nil
+}
+
+func (r *retentionStore) StartRun(ctx context.Context, run *retention.Run, abandonBefore time.Time) error {
+	runs := r.service.db.Database.Collection("purge_runs")
+	runs.UpdateMany(ctx,
+		bson.M{"status": models.PurgeRunRunning, "started_at": bson.M{"$lt": abandonBefore}},
+		bson.M{"$set": bson.M{"status": models.PurgeRunAbandoned}})
+
+	// The partial unique index on running runs keeps replicas from purging concurrently
+	if _, err := runs.InsertOne(ctx, purgeRunModel(run)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrPurgeRunInProgress
+		}
+		return fmt.Errorf("failed to start purge run: %w", err)
+	}
+	return nil
+}
+
+func (r *retentionStore) FinishRun(ctx context.Context, run *retention.Run) error {
+	runs := r.service.db.Database.Collection("purge_runs")
+	_, err := runs.UpdateOne(ctx, bson.M{"uuid": run.ID}, bson.M{"$set": bson.M{
+		"status":                  run.Status,
+		"purged":                  run.Purged,
+		"anonymized":              run.Anonymized,
+		"held":                    run.Held,
+		"error":                   run.Error,
+		"report":                  run.Report,
+		"report_sha256":           run.ReportSHA256,
+		"signature":               run.Signature,
+		"signature_algorithm":     run.SignatureAlgorithm,
+		"certificate_fingerprint": run.CertificateFingerprint,
+		"completed_at":            run.CompletedAt,
+	}})
+	return err
+}
+
+// holdConditions returns the filters matching records of name under an active hold
+func holdConditions(name string, holds []retention.Hold) bson.A {
+	collection := retentionCollections[name]
+	var records, subjects []uuid.UUID
+	for _, hold := range holds {
+		if hold.Entity != "" && hold.Entity != name {
+			continue
+		}
+		if hold.RecordID != nil {
+			records = append(records, *hold.RecordID)
+		}
+		if hold.SubjectID != nil {
+			subjects = append(subjects, *hold.SubjectID)
+		}
+	}
+
+	conditions := bson.A{}
+	if len(records) > 0 {
+		conditions = append(conditions, bson.M{"uuid": bson.M{"$in": records}})
+	}
+	if len(subjects) > 0 {
+		conditions = append(conditions, bson.M{collection.ownerField: bson.M{"$in": subjects}})
+	}
+	return conditions
+}
+
+func (r *retentionStore) Apply(ctx context.Context, rule retention.Rule, holds []retention.Hold, result *retention.RuleResult, now time.Time, dryRun bool) error {
+	expired := bson.M{rule.Basis: bson.M{"$lt": result.Cutoff}}
+	if len(rule.Statuses) > 0 {
+		expired["status"] = bson.M{"$in": rule.Statuses}
+	}
+	if rule.Action == retention.ActionAnonymize {
+		expired["anonymized_at"] = bson.M{"$exists": false}
+	}
+	eligible := bson.M{}
+	for key, value := range expired {
+		eligible[key] = value
+	}
+
+	collection := r.service.db.Database.Collection(rule.Entity)
+	if held := holdConditions(rule.Entity, holds); len(held) > 0 {
+		eligible["$nor"] = held
+		count, err := collection.CountDocuments(ctx, bson.M{"$and": bson.A{expired, bson.M{"$or": held}}})
+		if err != nil {
+			return err
+		}
+		result.Held = count
+	}
+
+	count, err := collection.CountDocuments(ctx, eligible)
+	if err != nil {
+		return err
+	}
+	result.Eligible = count
+	if dryRun || count == 0 {
+		return nil
+	}
+
+	batchSize := r.service.cfg.RetentionBatchSize
+	for ctx.Err() == nil {
+		ids, err := nextRetentionBatch(ctx, collection, eligible, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		// Re-applying the eligibility filter skips records put on hold since the batch
+		// was read
+		batch := bson.M{"$and": bson.A{eligible, bson.M{"uuid": bson.M{"$in": ids}}}}
+		var processed int64
+		if rule.Action == retention.ActionAnonymize {
+			set := bson.M{"anonymized_at": now, "updated_at": now}
+			for field, value := range retentionCollections[rule.Entity].anonymize {
+				set[field] = value
+			}
+			res, err := collection.UpdateMany(ctx, batch, bson.A{bson.M{"$set": set}})
+			if err != nil {
+				return err
+			}
+			processed = res.ModifiedCount
+		} else {
+			res, err := collection.DeleteMany(ctx, batch)
+			if err != nil {
+				return err
+			}
+			processed = res.DeletedCount
+		}
+
+		result.Processed += processed
+		for _, id := range ids {
+			result.RecordIDs = append(result.RecordIDs, id.String())
+		}
+		if len(ids) < batchSize {
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+func nextRetentionBatch(ctx context.Context, collection *mongo.Collection, filter bson.M, limit int) ([]uuid.UUID, error) {
+	opts := options.Find().SetProjection(bson.M{"uuid": 1}).SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		UUID uuid.UUID `bson:"uuid"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.UUID
+	}
+	return ids, nil
+}
+
+func purgeRunModel(run *retention.Run) *models.PurgeRun {
+	return &models.PurgeRun{
+		UUID:                   run.ID,
+		Status:                 models.PurgeRunStatus(run.Status),
+		DryRun:                 run.DryRun,
+		TriggeredBy:            run.TriggeredBy,
+		Purged:                 run.Purged,
+		Anonymized:             run.Anonymized,
+		Held:                   run.Held,
+		Error:                  run.Error,
+		Report:                 run.Report,
+		ReportSHA256:           run.ReportSHA256,
+		Signature:              run.Signature,
+		SignatureAlgorithm:     run.SignatureAlgorithm,
+		CertificateFingerprint: run.CertificateFingerprint,
+		StartedAt:              run.StartedAt,
+		CompletedAt:            run.CompletedAt,
+	}
+}
@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"time"
 
 	"invoice-financing-platform/internal/database"
@@ -10,6 +13,14 @@ import (
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvoiceNotPending       = errors.New("invoice is not pending verification")
+	ErrRequestNotRepayable     = errors.New("only funded requests can be repaid")
+	ErrRepaymentExceedsBalance = errors.New("repayment exceeds the outstanding balance")
 )
 
 // InvoiceService handles invoice-related operations
@@ -31,11 +42,12 @@ func (s *InvoiceService) Create(invoice *models.Invoice) error {
 	return err
 }
 
-func (s *InvoiceService) GetByUserID(userID uuid.UUID) ([]models.Invoice, error) {
+// GetByOrganizationID returns the invoices of an organization
+func (s *InvoiceService) GetByOrganizationID(organizationID uuid.UUID) ([]models.Invoice, error) {
 	var invoices []models.Invoice
 	collection := s.db.Database.Collection("invoices")
 	
-	filter := bson.M{"user_id": userID}
+	filter := bson.M{"organization_id": organizationID}
 	cursor, err := collection.Find(context.Background(), filter)
 	if err != nil {
 		return nil, err
@@ -58,6 +70,18 @@ func (s *InvoiceService) GetByID(id uuid.UUID) (*models.Invoice, error) {
 	return &invoice, nil
 }
 
+// GetByIDInOrganization returns an invoice only if it belongs to the organization
+func (s *InvoiceService) GetByIDInOrganization(id, organizationID uuid.UUID) (*models.Invoice, error) {
+	var invoice models.Invoice
+	collection := s.db.Database.Collection("invoices")
+
+	filter := bson.M{"uuid": id, "organization_id": organizationID}
+	if err := collection.FindOne(context.Background(), filter).Decode(&invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
 func (s *InvoiceService) Update(invoice *models.Invoice) error {
 	invoice.UpdatedAt = time.Now()
 	collection := s.db.Database.Collection("invoices")
@@ -79,6 +103,118 @@ func (s *InvoiceService) Delete(id uuid.UUID) error {
 	return err
 }
 
+// Verify records the verification outcome of a pending invoice
+func (s *InvoiceService) Verify(id uuid.UUID, approved bool) (*models.Invoice, error) {
+	status, verification := models.InvoiceStatusVerified, models.VerificationApproved
+	if !approved {
+		status, verification = models.InvoiceStatusRejected, models.VerificationRejected
+	}
+
+	var invoice models.Invoice
+	collection := s.db.Database.Collection("invoices")
+
+	filter := bson.M{"uuid": id, "status": models.InvoiceStatusPending}
+	update := bson.M{"$set": bson.M{
+		"status":              status,
+		"verification_status": verification,
+		"updated_at":          time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvoiceNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// TransitionStatus moves an invoice to status if it is currently in one of from. It
+// reports whether this call made the change, so concurrent callers publish events once.
+// SetBuyerScreening records the latest sanctions screening status of an invoice's buyer
+func (s *InvoiceService) SetBuyerScreening(id uuid.UUID, status string) error {
+	collection := s.db.Database.Collection("invoices")
+
+	update := bson.M{"$set": bson.M{"buyer_screening_status": status, "updated_at": time.Now()}}
+	_, err := collection.UpdateOne(context.Background(), bson.M{"uuid": id}, update)
+	return err
+}
+
+func (s *InvoiceService) TransitionStatus(id uuid.UUID, from []models.InvoiceStatus, status models.InvoiceStatus) (bool, error) {
+	collection := s.db.Database.Collection("invoices")
+
+	filter := bson.M{"uuid": id, "status": bson.M{"$in": from}}
+	update := bson.M{"$set": bson.M{
+		"status":     status,
+		"updated_at": time.Now(),
+	}}
+	result, err := collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// MarkOverdue flags verified and financed invoices past their due date as overdue and
+// returns the invoices this call changed
+func (s *InvoiceService) MarkOverdue(now time.Time) ([]models.Invoice, error) {
+	var candidates []models.Invoice
+	collection := s.db.Database.Collection("invoices")
+
+	open := []models.InvoiceStatus{models.InvoiceStatusVerified, models.InvoiceStatusFinanced}
+	filter := bson.M{
+		"status":     bson.M{"$in": open},
+		"due_date":   bson.M{"$lt": now},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &candidates); err != nil {
+		return nil, err
+	}
+
+	overdue := []models.Invoice{}
+	for _, invoice := range candidates {
+		changed, err := s.TransitionStatus(invoice.UUID, open, models.InvoiceStatusOverdue)
+		if err != nil {
+			return overdue, err
+		}
+		if changed {
+			invoice.Status = models.InvoiceStatusOverdue
+			overdue = append(overdue, invoice)
+		}
+	}
+	return overdue, nil
+}
+
+// StartOverdueMonitor marks overdue invoices every interval and publishes an event for
+// each until ctx is cancelled
+func (s *InvoiceService) StartOverdueMonitor(ctx context.Context, interval time.Duration, publisher EventPublisher) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		overdue, err := s.MarkOverdue(time.Now())
+		if err != nil {
+			log.Printf("Overdue invoice check failed: %v", err)
+		}
+		for _, invoice := range overdue {
+			if err := publisher.Publish(models.WebhookEventInvoiceOverdue, invoice.UserID, map[string]interface{}{"invoice": invoice}); err != nil {
+				log.Printf("Failed to publish overdue event for invoice %s: %v", invoice.UUID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // FinancingService handles financing request operations
 type FinancingService struct {
 	db *database.MongoDB
@@ -98,11 +234,12 @@ func (s *FinancingService) CreateRequest(request *models.FinancingRequest) error
 	return err
 }
 
-func (s *FinancingService) GetRequestsByUserID(userID uuid.UUID) ([]models.FinancingRequest, error) {
+// GetRequestsByOrganizationID returns the financing requests of an organization
+func (s *FinancingService) GetRequestsByOrganizationID(organizationID uuid.UUID) ([]models.FinancingRequest, error) {
 	var requests []models.FinancingRequest
 	collection := s.db.Database.Collection("financing_requests")
 	
-	filter := bson.M{"user_id": userID}
+	filter := bson.M{"organization_id": organizationID}
 	cursor, err := collection.Find(context.Background(), filter)
 	if err != nil {
 		return nil, err
@@ -140,6 +277,19 @@ func (s *FinancingService) GetRequestByID(id uuid.UUID) (*models.FinancingReques
 	return &request, nil
 }
 
+// GetRequestInOrganization returns a financing request only if it belongs to the
+// organization
+func (s *FinancingService) GetRequestInOrganization(id, organizationID uuid.UUID) (*models.FinancingRequest, error) {
+	var request models.FinancingRequest
+	collection := s.db.Database.Collection("financing_requests")
+
+	filter := bson.M{"uuid": id, "organization_id": organizationID}
+	if err := collection.FindOne(context.Background(), filter).Decode(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
 func (s *FinancingService) GetRequestsByInvoiceID(invoiceID uuid.UUID) ([]models.FinancingRequest, error) {
 	var requests []models.FinancingRequest
 	collection := s.db.Database.Collection("financing_requests")
@@ -192,6 +342,155 @@ func (s *FinancingService) UpdateRequestStatus(requestID uuid.UUID, status model
 	return err
 }
 
+// GetTotalInvested sums the investments made into a financing request
+func (s *FinancingService) GetTotalInvested(requestID uuid.UUID) (float64, error) {
+	collection := s.db.Database.Collection("investments")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"financing_request_id": requestID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$amount"}}}},
+	}
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	var result []struct {
+		Total float64 `bson:"total"`
+	}
+	if err := cursor.All(context.Background(), &result); err != nil || len(result) == 0 {
+		return 0, err
+	}
+	return result[0].Total, nil
+}
+
+// MarkFunded moves an approved request to funded. It reports whether this call made the
+// change.
+func (s *FinancingService) MarkFunded(requestID uuid.UUID) (bool, error) {
+	collection := s.db.Database.Collection("financing_requests")
+
+	now := time.Now()
+	filter := bson.M{"uuid": requestID, "status": models.FinancingStatusApproved}
+	update := bson.M{"$set": bson.M{
+		"status":     models.FinancingStatusFunded,
+		"funded_at":  now,
+		"updated_at": now,
+	}}
+	result, err := collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Repayment is the state of a financing request after a repayment was recorded
+type Repayment struct {
+	AmountDue    float64 `json:"amount_due"`
+	RepaidAmount float64 `json:"repaid_amount"`
+	Outstanding  float64 `json:"outstanding"`
+	Completed    bool    `json:"completed"`
+}
+
+// AmountDue is what the borrower owes on a request: the financed amount plus interest,
+// which is also what its investors are expected to receive
+func AmountDue(request *models.FinancingRequest) float64 {
+	return roundCents(request.RequestedAmount * (1 + request.InterestRate/100))
+}
+
+// RecordRepayment adds amount to a funded request's repaid total. The amount may not
+// exceed the outstanding balance; the check and the increment are one update, so
+// concurrent repayments cannot overpay. The request is completed once it is repaid in
+// full.
+func (s *FinancingService) RecordRepayment(requestID uuid.UUID, amount float64) (*Repayment, error) {
+	request, err := s.GetRequestByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != models.FinancingStatusFunded {
+		return nil, ErrRequestNotRepayable
+	}
+
+	amount = roundCents(amount)
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be at least 0.01", ErrRepaymentExceedsBalance)
+	}
+	due := AmountDue(request)
+
+	collection := s.db.Database.Collection("financing_requests")
+	repaid := bson.M{"$ifNull": bson.A{"$repaid_amount", 0}}
+	filter := bson.M{
+		"uuid":   requestID,
+		"status": models.FinancingStatusFunded,
+		"$expr":  bson.M{"$lte": bson.A{bson.M{"$add": bson.A{repaid, amount}}, due + 0.005}},
+	}
+	update := bson.M{
+		"$inc": bson.M{"repaid_amount": amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.FinancingRequest
+	err = collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, err := s.GetRequestByID(requestID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status != models.FinancingStatusFunded {
+			return nil, ErrRequestNotRepayable
+		}
+		return nil, fmt.Errorf("%w: %.2f outstanding", ErrRepaymentExceedsBalance, math.Max(due-current.RepaidAmount, 0))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record repayment: %w", err)
+	}
+
+	result := &Repayment{
+		AmountDue:    due,
+		RepaidAmount: roundCents(updated.RepaidAmount),
+		Outstanding:  math.Max(roundCents(due-updated.RepaidAmount), 0),
+	}
+	if result.Outstanding == 0 {
+		if result.Completed, err = s.CompleteRequest(requestID); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// CompleteRequest records repayment of a funded request and completes its investments.
+// It reports whether this call made the change.
+func (s *FinancingService) CompleteRequest(requestID uuid.UUID) (bool, error) {
+	collection := s.db.Database.Collection("financing_requests")
+
+	now := time.Now()
+	filter := bson.M{"uuid": requestID, "status": models.FinancingStatusFunded}
+	update := bson.M{"$set": bson.M{
+		"status":       models.FinancingStatusCompleted,
+		"completed_at": now,
+		"updated_at":   now,
+	}}
+	result, err := collection.UpdateOne(context.Background(), filter, update)
+	if err != nil || result.ModifiedCount == 0 {
+		return false, err
+	}
+
+	investments := s.db.Database.Collection("investments")
+	_, err = investments.UpdateMany(context.Background(),
+		bson.M{"financing_request_id": requestID, "status": models.InvestmentStatusActive},
+		bson.A{bson.M{"$set": bson.M{
+			"status":        models.InvestmentStatusCompleted,
+			"actual_return": "$expected_return",
+			"return_date":   now,
+			"updated_at":    now,
+		}}})
+	return true, err
+}
+
 // BlockchainService handles blockchain operations
 type BlockchainService struct {
 	rpcURL          string
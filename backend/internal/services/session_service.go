@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+	"shared/loginrisk"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+	ErrSessionExpired  = errors.New("session has expired")
+	ErrRefreshReused   = errors.New("refresh token has already been used")
+	ErrSessionExists   = errors.New("session already exists")
+)
+
+// sessionLastSeenResolution limits how often activity is written back to the database
+const sessionLastSeenResolution = time.Minute
+
+type cachedSession struct {
+	session   models.Session
+	fetchedAt time.Time
+}
+
+// SessionService tracks signed-in devices. Every request is checked against the session
+// through a short-lived in-process cache, so a revocation takes effect immediately on the
+// instance that made it and within SessionCacheTTL everywhere else.
+type SessionService struct {
+	db  *database.MongoDB
+	cfg *config.Config
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedSession
+}
+
+func NewSessionService(db *database.MongoDB, cfg *config.Config) *SessionService {
+	return &SessionService{db: db, cfg: cfg, cache: make(map[uuid.UUID]cachedSession)}
+}
+
+func (s *SessionService) collection() *mongo.Collection {
+	return s.db.Database.Collection("sessions")
+}
+
+// CreateSession starts a session for a login. When the role's session limit is reached
+// the least recently used sessions are signed out to make room.
+func (s *SessionService) CreateSession(user *models.User, ipAddress, userAgent, location string) (*models.Session, error) {
+	now := time.Now()
+	session := &models.Session{
+		UUID:       uuid.New(),
+		UserID:     user.UUID,
+		Role:       string(user.Role),
+		DeviceInfo: loginrisk.DescribeDevice(userAgent),
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		Location:   location,
+		IsActive:   true,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.cfg.SessionAbsoluteTimeout),
+	}
+	if err := s.insert(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SessionRegistration is a session opened by a login in the user management service
+// (TOTP, passkey and SSO). Its tokens are signed with the shared secret and carry the
+// session ID chosen there.
+type SessionRegistration struct {
+	SessionID        uuid.UUID `json:"session_id" binding:"required"`
+	UserID           uuid.UUID `json:"user_id" binding:"required"`
+	Role             string    `json:"role" binding:"required"`
+	IPAddress        string    `json:"ip_address"`
+	UserAgent        string    `json:"user_agent"`
+	Location         string    `json:"location"`
+	RefreshTokenHash string    `json:"refresh_token_hash" binding:"required"` // Hex SHA-256 of the refresh token
+}
+
+// RegisterSession records a session opened by the user management service, so its tokens
+// are validated, rotated and revoked here like those of a password login
+func (s *SessionService) RegisterSession(registration SessionRegistration) (*models.Session, error) {
+	existing, err := s.collection().CountDocuments(context.Background(), bson.M{"uuid": registration.SessionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrSessionExists
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UUID:             registration.SessionID,
+		UserID:           registration.UserID,
+		Role:             registration.Role,
+		RefreshTokenHash: registration.RefreshTokenHash,
+		DeviceInfo:       loginrisk.DescribeDevice(registration.UserAgent),
+		UserAgent:        registration.UserAgent,
+		IPAddress:        registration.IPAddress,
+		Location:         registration.Location,
+		IsActive:         true,
+		CreatedAt:        now,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(s.cfg.SessionAbsoluteTimeout),
+	}
+	if err := s.insert(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// insert stores a new session and signs out the least recently used ones beyond the
+// role's limit
+func (s *SessionService) insert(session *models.Session) error {
+	if _, err := s.collection().InsertOne(context.Background(), session); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return s.enforceLimit(session.UserID, session.Role)
+}
+
+func (s *SessionService) enforceLimit(userID uuid.UUID, role string) error {
+	limit, ok := s.cfg.SessionMaxPerRole[role]
+	if !ok {
+		limit = s.cfg.SessionMaxDefault
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	sessions, err := s.ListSessions(userID)
+	if err != nil {
+		return err
+	}
+	if len(sessions) <= limit {
+		return nil
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	for _, session := range sessions[limit:] {
+		if err := s.revoke(bson.M{"uuid": session.UUID}, models.SessionLimitExceeded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRefreshToken records the refresh token issued for a new session
+func (s *SessionService) SetRefreshToken(sessionID uuid.UUID, refreshToken string) error {
+	_, err := s.collection().UpdateOne(context.Background(),
+		bson.M{"uuid": sessionID},
+		bson.M{"$set": bson.M{"refresh_token_hash": hashSessionToken(refreshToken)}})
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// RotateRefreshToken swaps the session's refresh token for a new one. Refresh tokens are
+// single-use: presenting one that has already been rotated means it was copied, so the
+// session is revoked.
+func (s *SessionService) RotateRefreshToken(sessionID uuid.UUID, presented, next string) (*models.Session, error) {
+	session, err := s.ValidateSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{"uuid": sessionID, "is_active": true, "refresh_token_hash": hashSessionToken(presented)}
+	update := bson.M{"$set": bson.M{"refresh_token_hash": hashSessionToken(next), "last_seen_at": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = s.collection().FindOneAndUpdate(context.Background(), filter, update, opts).Decode(session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if err := s.revoke(bson.M{"uuid": sessionID}, models.SessionRefreshReuse); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshReused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	s.store(*session, now)
+	return session, nil
+}
+
+// ValidateSession returns the session if it may still be used and records activity on
+// it. Sessions past their idle or absolute timeout are revoked here.
+func (s *SessionService) ValidateSession(sessionID uuid.UUID) (*models.Session, error) {
+	now := time.Now()
+	session, err := s.lookup(sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive {
+		return nil, ErrSessionRevoked
+	}
+	if !now.Before(session.ExpiresAt) {
+		s.revoke(bson.M{"uuid": sessionID}, models.SessionAbsoluteTimeout)
+		return nil, ErrSessionExpired
+	}
+	if now.Sub(session.LastSeenAt) > s.cfg.SessionIdleTimeout {
+		s.revoke(bson.M{"uuid": sessionID}, models.SessionIdleTimeout)
+		return nil, ErrSessionExpired
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionLastSeenResolution {
+		_, err := s.collection().UpdateOne(context.Background(),
+			bson.M{"uuid": sessionID, "is_active": true},
+			bson.M{"$set": bson.M{"last_seen_at": now}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update session: %w", err)
+		}
+		session.LastSeenAt = now
+		s.mu.Lock()
+		if cached, ok := s.cache[sessionID]; ok {
+			cached.session.LastSeenAt = now
+			s.cache[sessionID] = cached
+		}
+		s.mu.Unlock()
+	}
+	return session, nil
+}
+
+func (s *SessionService) lookup(sessionID uuid.UUID, now time.Time) (*models.Session, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[sessionID]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < s.cfg.SessionCacheTTL {
+		session := cached.session
+		return &session, nil
+	}
+
+	var session models.Session
+	err := s.collection().FindOne(context.Background(), bson.M{"uuid": sessionID}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	s.store(session, now)
+	return &session, nil
+}
+
+func (s *SessionService) store(session models.Session, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[session.UUID] = cachedSession{session: session, fetchedAt: now}
+	if len(s.cache) > 10000 {
+		for id, cached := range s.cache {
+			if now.Sub(cached.fetchedAt) >= s.cfg.SessionCacheTTL {
+				delete(s.cache, id)
+			}
+		}
+	}
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *SessionService) ListSessions(userID uuid.UUID) ([]models.Session, error) {
+	sessions := []models.Session{}
+	filter := bson.M{"user_id": userID, "is_active": true, "expires_at": bson.M{"$gt": time.Now()}}
+	cursor, err := s.collection().Find(context.Background(), filter, options.Find().SetSort(bson.M{"last_seen_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &sessions)
+	return sessions, err
+}
+
+// RevokeSession signs out one of the user's sessions
+func (s *SessionService) RevokeSession(userID, sessionID uuid.UUID, reason models.SessionRevokeReason) error {
+	var session models.Session
+	err := s.collection().FindOne(context.Background(), bson.M{"uuid": sessionID, "user_id": userID}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+	return s.revoke(bson.M{"uuid": sessionID}, reason)
+}
+
+// RevokeOtherSessions signs out every session of the user except the current one and
+// returns how many were ended
+func (s *SessionService) RevokeOtherSessions(userID, currentID uuid.UUID) (int64, error) {
+	return s.revokeMany(bson.M{"user_id": userID, "uuid": bson.M{"$ne": currentID}}, models.SessionRevokedByUser)
+}
+
+// RevokeAllSessions signs the user out everywhere
+func (s *SessionService) RevokeAllSessions(userID uuid.UUID, reason models.SessionRevokeReason) (int64, error) {
+	return s.revokeMany(bson.M{"user_id": userID}, reason)
+}
+
+func (s *SessionService) revoke(filter bson.M, reason models.SessionRevokeReason) error {
+	_, err := s.revokeMany(filter, reason)
+	return err
+}
+
+func (s *SessionService) revokeMany(filter bson.M, reason models.SessionRevokeReason) (int64, error) {
+	filter["is_active"] = true
+
+	// Collect the IDs first so the cache entries can be dropped
+	var ids []uuid.UUID
+	cursor, err := s.collection().Find(context.Background(), filter, options.Find().SetProjection(bson.M{"uuid": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find sessions: %w", err)
+	}
+	var found []models.Session
+	if err := cursor.All(context.Background(), &found); err != nil {
+		return 0, fmt.Errorf("failed to find sessions: %w", err)
+	}
+	for _, session := range found {
+		ids = append(ids, session.UUID)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	result, err := s.collection().UpdateMany(context.Background(),
+		bson.M{"uuid": bson.M{"$in": ids}, "is_active": true},
+		bson.M{"$set": bson.M{"is_active": false, "revoked_at": now, "revoked_reason": reason, "refresh_token_hash": ""}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.cache, id)
+	}
+	s.mu.Unlock()
+	return result.ModifiedCount, nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
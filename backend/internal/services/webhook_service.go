@@ -0,0 +1,782 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"invoice-financing-platform/internal/config"
+	"invoice-financing-platform/internal/database"
+	"invoice-financing-platform/internal/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Outbound deliveries are signed the same way inbound webhooks are verified by the
+// platform services: HMAC-SHA256 over "<timestamp>.<delivery id>.<raw body>".
+const (
+	webhookSenderName      = "invoice-financing-platform"
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookResponseLimit   = 64 << 10
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDeliveryInFlight     = errors.New("webhook delivery is pending or in flight")
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+	ErrWebhookAddress       = errors.New("webhook endpoint resolves to a non-public address")
+)
+
+// webhookBlockedNetworks are ranges outside the ones net.IP classifies that must not be
+// reachable from subscriber-supplied URLs
+var webhookBlockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64, which maps onto IPv4 addresses
+)
+
+// SupportedWebhookEvents lists the events a subscription may filter on
+var SupportedWebhookEvents = []models.WebhookEvent{
+	models.WebhookEventInvoiceVerified,
+	models.WebhookEventInvoiceFunded,
+	models.WebhookEventInvoiceRepaid,
+	models.WebhookEventInvoiceOverdue,
+}
+
+// EventPublisher fans a platform event out to subscribers
+type EventPublisher interface {
+	Publish(event models.WebhookEvent, ownerID uuid.UUID, data interface{}) error
+}
+
+// WebhookService manages subscriptions and delivers events to them
+type WebhookService struct {
+	db       *database.MongoDB
+	cfg      *config.Config
+	client   *http.Client
+	workerID string
+}
+
+func NewWebhookService(db *database.MongoDB, cfg *config.Config) *WebhookService {
+	host, _ := os.Hostname()
+	return &WebhookService{
+		db:       db,
+		cfg:      cfg,
+		client:   newWebhookClient(cfg.WebhookTimeout),
+		workerID: fmt.Sprintf("%s-%s", host, uuid.New().String()[:8]),
+	}
+}
+
+// SubscriptionInput holds the writable fields of a subscription
+type SubscriptionInput struct {
+	URL         string                `json:"url"`
+	Events      []models.WebhookEvent `json:"events"`
+	Secret      string                `json:"secret"`
+	Description string                `json:"description"`
+	Active      *bool                 `json:"active"`
+}
+
+// CreateSubscription registers an endpoint. When no secret is supplied one is generated;
+// the secret is returned only here.
+func (s *WebhookService) CreateSubscription(userID uuid.UUID, isAdmin bool, input SubscriptionInput) (*models.WebhookSubscription, string, error) {
+	if err := s.validateURL(input.URL); err != nil {
+		return nil, "", err
+	}
+	events, err := normalizeEvents(input.Events)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, "", err
+		}
+	} else if len(secret) < 16 {
+		return nil, "", fmt.Errorf("%w: secret must be at least 16 characters", ErrInvalidSubscription)
+	}
+
+	now := time.Now()
+	subscription := &models.WebhookSubscription{
+		UUID:        uuid.New(),
+		UserID:      userID,
+		URL:         input.URL,
+		Events:      events,
+		Secret:      secret,
+		Description: input.Description,
+		AllInvoices: isAdmin,
+		Active:      input.Active == nil || *input.Active,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	collection := s.db.Database.Collection("webhook_subscriptions")
+	if _, err := collection.InsertOne(context.Background(), subscription); err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return subscription, secret, nil
+}
+
+func (s *WebhookService) ListSubscriptions(userID uuid.UUID) ([]models.WebhookSubscription, error) {
+	subscriptions := []models.WebhookSubscription{}
+	collection := s.db.Database.Collection("webhook_subscriptions")
+
+	filter := bson.M{"user_id": userID, "deleted_at": bson.M{"$exists": false}}
+	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &subscriptions)
+	return subscriptions, err
+}
+
+// GetSubscription returns a subscription owned by userID, or any subscription for admins
+func (s *WebhookService) GetSubscription(id, userID uuid.UUID, isAdmin bool) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	collection := s.db.Database.Collection("webhook_subscriptions")
+
+	filter := bson.M{"uuid": id, "deleted_at": bson.M{"$exists": false}}
+	if !isAdmin {
+		filter["user_id"] = userID
+	}
+	err := collection.FindOne(context.Background(), filter).Decode(&subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+func (s *WebhookService) UpdateSubscription(id, userID uuid.UUID, isAdmin bool, input SubscriptionInput) (*models.WebhookSubscription, error) {
+	subscription, err := s.GetSubscription(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if input.URL != "" {
+		if err := s.validateURL(input.URL); err != nil {
+			return nil, err
+		}
+		set["url"] = input.URL
+	}
+	if input.Events != nil {
+		events, err := normalizeEvents(input.Events)
+		if err != nil {
+			return nil, err
+		}
+		set["events"] = events
+	}
+	if input.Secret != "" {
+		if len(input.Secret) < 16 {
+			return nil, fmt.Errorf("%w: secret must be at least 16 characters", ErrInvalidSubscription)
+		}
+		set["secret"] = input.Secret
+	}
+	if input.Description != "" {
+		set["description"] = input.Description
+	}
+	if input.Active != nil {
+		set["active"] = *input.Active
+	}
+
+	collection := s.db.Database.Collection("webhook_subscriptions")
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := collection.FindOneAndUpdate(context.Background(), bson.M{"uuid": subscription.UUID}, bson.M{"$set": set}, opts).
+		Decode(subscription); err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription soft deletes the subscription. Queued deliveries are dead-lettered
+// so they stay visible in the log but are not sent.
+func (s *WebhookService) DeleteSubscription(id, userID uuid.UUID, isAdmin bool) error {
+	subscription, err := s.GetSubscription(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	collection := s.db.Database.Collection("webhook_subscriptions")
+	update := bson.M{"$set": bson.M{"deleted_at": now, "active": false, "updated_at": now}}
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"uuid": subscription.UUID}, update); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	deliveries := s.db.Database.Collection("webhook_deliveries")
+	_, err = deliveries.UpdateMany(context.Background(),
+		bson.M{"subscription_id": subscription.UUID, "status": models.WebhookDeliveryPending},
+		bson.M{"$set": bson.M{
+			"status":           models.WebhookDeliveryDeadLetter,
+			"last_error":       "subscription deleted",
+			"dead_lettered_at": now,
+			"updated_at":       now,
+		}})
+	if err != nil {
+		return fmt.Errorf("failed to cancel queued deliveries: %w", err)
+	}
+	return nil
+}
+
+// Publish queues a delivery for every active subscription that filters on the event and
+// can see invoices of ownerID. Each delivery gets its own ID, which receivers use for
+// deduplication; the event ID in the payload is shared by all deliveries of one event.
+func (s *WebhookService) Publish(event models.WebhookEvent, ownerID uuid.UUID, data interface{}) error {
+	ctx := context.Background()
+	collection := s.db.Database.Collection("webhook_subscriptions")
+
+	filter := bson.M{
+		"active":     true,
+		"events":     event,
+		"deleted_at": bson.M{"$exists": false},
+		"$or":        bson.A{bson.M{"user_id": ownerID}, bson.M{"all_invoices": true}},
+	}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to find webhook subscriptions: %w", err)
+	}
+	var subscriptions []models.WebhookSubscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return fmt.Errorf("failed to read webhook subscriptions: %w", err)
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	eventID := uuid.New()
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         eventID,
+		"event":      event,
+		"created_at": now,
+		"data":       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	deliveries := make([]interface{}, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			UUID:           uuid.New(),
+			SubscriptionID: subscription.UUID,
+			UserID:         subscription.UserID,
+			EventID:        eventID,
+			Event:          event,
+			URL:            subscription.URL,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			AttemptLog:     []models.WebhookAttempt{},
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	if _, err := s.db.Database.Collection("webhook_deliveries").InsertMany(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log, newest first. A nil subscriptionID lists
+// deliveries across all of the user's subscriptions.
+func (s *WebhookService) ListDeliveries(userID uuid.UUID, isAdmin bool, subscriptionID *uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	filter := bson.M{}
+	if !isAdmin {
+		filter["user_id"] = userID
+	}
+	if subscriptionID != nil {
+		filter["subscription_id"] = *subscriptionID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	collection := s.db.Database.Collection("webhook_deliveries")
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(limit))
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	err = cursor.All(context.Background(), &deliveries)
+	return deliveries, err
+}
+
+func (s *WebhookService) GetDelivery(id, userID uuid.UUID, isAdmin bool) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	collection := s.db.Database.Collection("webhook_deliveries")
+
+	filter := bson.M{"uuid": id}
+	if !isAdmin {
+		filter["user_id"] = userID
+	}
+	err := collection.FindOne(context.Background(), filter).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// Redeliver queues a dead-lettered or succeeded delivery again with a fresh attempt
+// budget. The delivery keeps its ID, so receivers still deduplicate it; the attempt log
+// is kept.
+func (s *WebhookService) Redeliver(id, userID uuid.UUID, isAdmin bool) (*models.WebhookDelivery, error) {
+	delivery, err := s.GetDelivery(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription models.WebhookSubscription
+	err = s.db.Database.Collection("webhook_subscriptions").FindOne(context.Background(),
+		bson.M{"uuid": delivery.SubscriptionID, "deleted_at": bson.M{"$exists": false}}).Decode(&subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook subscription: %w", err)
+	}
+
+	now := time.Now()
+	collection := s.db.Database.Collection("webhook_deliveries")
+	filter := bson.M{
+		"uuid":   delivery.UUID,
+		"status": bson.M{"$in": bson.A{models.WebhookDeliveryDeadLetter, models.WebhookDeliverySucceeded}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          models.WebhookDeliveryPending,
+			"attempts":        0,
+			"url":             subscription.URL,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		},
+		"$unset": bson.M{"dead_lettered_at": "", "last_error": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDeliveryInFlight
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// claimNext leases the next due delivery to this worker. The lease is taken with a single
+// findAndModify, so replicas never send the same attempt twice; a delivery whose worker
+// died becomes claimable again once its lease expires.
+func (s *WebhookService) claimNext(ctx context.Context) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": models.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"status": models.WebhookDeliveryInFlight, "locked_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"status":       models.WebhookDeliveryInFlight,
+		"locked_by":    s.workerID,
+		"locked_until": now.Add(s.cfg.WebhookLeaseDuration),
+		"updated_at":   now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"next_attempt_at": 1}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := s.db.Database.Collection("webhook_deliveries").FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ProcessNext sends one due delivery. It reports false when nothing was due.
+func (s *WebhookService) ProcessNext(ctx context.Context) (bool, error) {
+	delivery, err := s.claimNext(ctx)
+	if err != nil || delivery == nil {
+		return false, err
+	}
+
+	var subscription models.WebhookSubscription
+	err = s.db.Database.Collection("webhook_subscriptions").FindOne(ctx,
+		bson.M{"uuid": delivery.SubscriptionID, "deleted_at": bson.M{"$exists": false}}).Decode(&subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return true, s.deadLetter(ctx, delivery, "subscription deleted")
+	}
+	if err != nil {
+		return true, s.release(ctx, delivery, fmt.Errorf("failed to load webhook subscription: %w", err))
+	}
+	if !subscription.Active {
+		return true, s.deadLetter(ctx, delivery, "subscription disabled")
+	}
+
+	attempt := s.send(ctx, &subscription, delivery)
+	return true, s.recordAttempt(ctx, delivery, attempt)
+}
+
+func (s *WebhookService) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) models.WebhookAttempt {
+	started := time.Now()
+	attempt := models.WebhookAttempt{Attempt: delivery.Attempts + 1, AttemptedAt: started}
+
+	body := []byte(delivery.Payload)
+	timestamp := started.Unix()
+
+	// The dialer refuses non-public addresses as well; checking here first records a clear
+	// reason in the attempt log instead of a dial error
+	if err := checkWebhookHost(ctx, subscription.URL); err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	deliveryID := delivery.UUID.String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookSenderName+"/1.0")
+	req.Header.Set("X-Webhook-Sender", webhookSenderName)
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Webhook-Id", deliveryID)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhookSignatureHeader, SignWebhook(subscription.Secret, timestamp, deliveryID, body))
+
+	resp, err := s.client.Do(req)
+	attempt.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	// Only the status code is recorded. The body is drained so the connection can be
+	// reused, but never stored: the endpoint controls it.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("endpoint responded with %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// recordAttempt stores the outcome and schedules the retry. Updates are fenced on the
+// lease so a worker whose lease expired cannot overwrite a newer attempt.
+func (s *WebhookService) recordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	now := time.Now()
+	set := bson.M{
+		"attempts":         attempt.Attempt,
+		"last_status_code": attempt.StatusCode,
+		"updated_at":       now,
+	}
+	unset := bson.M{"locked_by": "", "locked_until": ""}
+
+	switch {
+	case attempt.Error == "":
+		set["status"] = models.WebhookDeliverySucceeded
+		set["delivered_at"] = now
+		unset["last_error"] = ""
+	case attempt.Attempt >= s.cfg.WebhookMaxAttempts:
+		set["status"] = models.WebhookDeliveryDeadLetter
+		set["dead_lettered_at"] = now
+		set["last_error"] = attempt.Error
+	default:
+		set["status"] = models.WebhookDeliveryPending
+		set["next_attempt_at"] = now.Add(s.backoff(attempt.Attempt))
+		set["last_error"] = attempt.Error
+	}
+
+	update := bson.M{"$set": set, "$unset": unset, "$push": bson.M{"attempt_log": attempt}}
+	filter := bson.M{"uuid": delivery.UUID, "locked_by": s.workerID}
+	if _, err := s.db.Database.Collection("webhook_deliveries").UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// backoff doubles the delay after every failed attempt up to the configured maximum,
+// with up to 20% jitter so retries from one outage do not arrive together
+func (s *WebhookService) backoff(attempt int) time.Duration {
+	delay := s.cfg.WebhookBackoffBase
+	for i := 1; i < attempt && delay < s.cfg.WebhookBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.WebhookBackoffMax {
+		delay = s.cfg.WebhookBackoffMax
+	}
+
+	var buf [2]byte
+	if _, err := rand.Read(buf[:]); err == nil {
+		jitter := time.Duration(int64(delay) / 5 * int64(uint16(buf[0])<<8|uint16(buf[1])) / 65535)
+		delay += jitter
+	}
+	return delay
+}
+
+func (s *WebhookService) deadLetter(ctx context.Context, delivery *models.WebhookDelivery, reason string) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":           models.WebhookDeliveryDeadLetter,
+			"last_error":       reason,
+			"dead_lettered_at": now,
+			"updated_at":       now,
+		},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+	filter := bson.M{"uuid": delivery.UUID, "locked_by": s.workerID}
+	if _, err := s.db.Database.Collection("webhook_deliveries").UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to dead-letter webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// release hands a delivery back without counting an attempt, for failures on our side
+func (s *WebhookService) release(ctx context.Context, delivery *models.WebhookDelivery, cause error) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.WebhookDeliveryPending, "next_attempt_at": time.Now().Add(s.cfg.WebhookBackoffBase)},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+	filter := bson.M{"uuid": delivery.UUID, "locked_by": s.workerID}
+	if _, err := s.db.Database.Collection("webhook_deliveries").UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to release webhook delivery: %w", err)
+	}
+	return cause
+}
+
+// StartWorker delivers due webhooks until ctx is cancelled. Any number of replicas may
+// run it; deliveries are leased one at a time.
+func (s *WebhookService) StartWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.WebhookWorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := s.ProcessNext(ctx)
+			if err != nil {
+				log.Printf("Webhook delivery failed: %v", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SignWebhook returns the signature header value for a delivery
+func SignWebhook(secret string, timestamp int64, deliveryID string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s.", timestamp, deliveryID)
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be absolute", ErrInvalidSubscription)
+	}
+	switch parsed.Scheme {
+	case "https":
+	case "http":
+		if s.cfg.Environment == "production" {
+			return fmt.Errorf("%w: url must use https", ErrInvalidSubscription)
+		}
+	default:
+		return fmt.Errorf("%w: url must use http or https", ErrInvalidSubscription)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: url must not contain credentials", ErrInvalidSubscription)
+	}
+
+	// Hosts that are addresses are checked now; names are resolved again on every delivery
+	// because their records can change after the subscription is saved
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("%w: url must point to a public host", ErrInvalidSubscription)
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicWebhookIP(ip) {
+		return fmt.Errorf("%w: url must point to a public host", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// newWebhookClient returns a client that can only connect to public addresses. The check
+// runs in the dialer against the address actually being connected to, so a host name
+// that resolves differently between the check and the connection (DNS rebinding) and
+// redirects to internal hosts are refused too. Proxies are not used, since the dialer
+// would then only see the proxy's address.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicWebhookIP(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// A redirect is reported as the endpoint's answer rather than followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkWebhookHost resolves the URL's host and fails if any of its addresses is not public
+func checkWebhookHost(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicWebhookIP(ip) {
+			return fmt.Errorf("%w: %s", ErrWebhookAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !publicWebhookIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrWebhookAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// publicWebhookIP reports whether deliveries may be sent to ip. Loopback, private,
+// link-local (which includes cloud metadata endpoints), multicast and reserved ranges
+// are refused.
+func publicWebhookIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range webhookBlockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func normalizeEvents(events []models.WebhookEvent) ([]models.WebhookEvent, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidSubscription)
+	}
+
+	seen := make(map[models.WebhookEvent]bool)
+	normalized := make([]models.WebhookEvent, 0, len(events))
+	for _, event := range events {
+		supported := false
+		for _, candidate := range SupportedWebhookEvents {
+			if event == candidate {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			names := make([]string, len(SupportedWebhookEvents))
+			for i, candidate := range SupportedWebhookEvents {
+				names[i] = string(candidate)
+			}
+			return nil, fmt.Errorf("%w: unsupported event %q, expected one of %s", ErrInvalidSubscription, event, strings.Join(names, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -12,6 +12,9 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// apiKeyLimiterTTL is how long an unused API key's limiter is kept
+const apiKeyLimiterTTL = 10 * time.Minute
+
 // RateLimiter represents a rate limiter for a specific client
 type RateLimiter struct {
 	limiter  *rate.Limiter
@@ -137,6 +140,15 @@ func IPBasedRateLimit() gin.HandlerFunc {
 	manager := NewRateLimitManager(DefaultRateLimitConfig())
 	suspiciousIPs := make(map[string]time.Time)
 	var suspiciousMu sync.RWMutex
+	sweepEvery(10*time.Minute, func() {
+		suspiciousMu.Lock()
+		defer suspiciousMu.Unlock()
+		for ip, since := range suspiciousIPs {
+			if time.Since(since) >= time.Hour {
+				delete(suspiciousIPs, ip)
+			}
+		}
+	})
 
 	return gin.HandlerFunc(func(c *gin.Context) {
 		clientIP := getClientIP(c)
@@ -235,15 +247,29 @@ func (m *RateLimitManager) cleanup() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	cutoff := time.Now().Add(-m.config.CleanupInterval * 2)
-	
-	for clientID, limiter := range m.limiters {
+	evictIdle(m.limiters, time.Now().Add(-m.config.CleanupInterval*2))
+}
+
+// evictIdle removes limiters last used before cutoff. The caller holds the map's lock.
+func evictIdle(limiters map[string]*RateLimiter, cutoff time.Time) {
+	for clientID, limiter := range limiters {
 		if limiter.lastSeen.Before(cutoff) {
-			delete(m.limiters, clientID)
+			delete(limiters, clientID)
 		}
 	}
 }
 
+// sweepEvery runs sweep periodically for the life of the process
+func sweepEvery(interval time.Duration, sweep func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sweep()
+		}
+	}()
+}
+
 // getClientIP extracts the real client IP address
 func getClientIP(c *gin.Context) string {
 	// Check X-Forwarded-For header first (for reverse proxies)
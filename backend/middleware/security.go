@@ -1,13 +1,22 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	internalmodels "invoice-financing-platform/internal/models"
+	"invoice-financing-platform/internal/services"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
 )
 
 // SecurityConfig holds security configuration
@@ -202,39 +211,111 @@ func SecureJSON() gin.HandlerFunc {
 	})
 }
 
-// APIKeyAuth middleware for API key authentication (optional)
-func APIKeyAuth() gin.HandlerFunc {
-	validAPIKeys := make(map[string]bool)
-	
-	// Load API keys from environment
-	if keys := os.Getenv("API_KEYS"); keys != "" {
-		for _, key := range strings.Split(keys, ",") {
-			if key != "" {
-				validAPIKeys[strings.TrimSpace(key)] = true
+// APIKeyAuth authenticates partner integrations with API keys from the key store.
+// Each key is rate limited to its own per-minute limit. Authenticated requests get the
+// key as "api_key" and its organization as "organization_id". Limiters of keys that have
+// not been used for apiKeyLimiterTTL are dropped.
+func APIKeyAuth(apiKeys *services.APIKeyService) gin.HandlerFunc {
+	limiters := make(map[string]*RateLimiter)
+	var mu sync.Mutex
+	sweepEvery(apiKeyLimiterTTL/2, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		evictIdle(limiters, time.Now().Add(-apiKeyLimiterTTL))
+	})
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or missing API key",
+			})
+			c.Abort()
+			return
+		}
+
+		key, err := apiKeys.ValidateAPIKey(apiKey, getClientIP(c))
+		if err != nil {
+			status, message := http.StatusUnauthorized, "Invalid or missing API key"
+			switch {
+			case errors.Is(err, services.ErrAPIKeyExpired), errors.Is(err, services.ErrAPIKeyRevoked):
+				message = err.Error()
+			case !errors.Is(err, services.ErrAPIKeyInvalid):
+				status, message = http.StatusServiceUnavailable, "API key validation unavailable"
 			}
+			c.JSON(status, gin.H{"error": message})
+			c.Abort()
+			return
 		}
-	}
 
+		if key.RateLimitPerMinute > 0 {
+			mu.Lock()
+			limiter, exists := limiters[key.UUID.String()]
+			// A changed limit takes effect with a fresh limiter
+			if !exists || limiter.limiter.Burst() != key.RateLimitPerMinute {
+				limiter = &RateLimiter{
+					limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(key.RateLimitPerMinute)), key.RateLimitPerMinute),
+				}
+				limiters[key.UUID.String()] = limiter
+			}
+			limiter.lastSeen = time.Now()
+			allowed := limiter.limiter.Allow()
+			mu.Unlock()
+
+			c.Header("X-Rate-Limit-Limit", strconv.Itoa(key.RateLimitPerMinute))
+			if !allowed {
+				c.Header("X-Rate-Limit-Remaining", "0")
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"error":       "Rate limit exceeded",
+					"message":     fmt.Sprintf("Maximum %d requests per minute allowed for this API key", key.RateLimitPerMinute),
+					"retry_after": 60,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("api_key", key)
+		c.Set("organization_id", key.OrganizationID)
+		c.Next()
+	})
+}
+
+// RequireScope checks that the authenticated API key was granted every listed scope
+func RequireScope(requiredScopes ...string) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		// Skip if no API keys configured
-		if len(validAPIKeys) == 0 {
-			c.Next()
+		value, _ := c.Get("api_key")
+		key, ok := value.(*internalmodels.APIKey)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key required"})
+			c.Abort()
 			return
 		}
 
-		apiKey := c.GetHeader("X-API-Key")
-		if apiKey == "" {
-			apiKey = c.Query("api_key")
+		for _, scope := range requiredScopes {
+			if !services.HasScope(key, scope) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":           "Insufficient scope",
+					"required_scopes": requiredScopes,
+				})
+				c.Abort()
+				return
+			}
 		}
+		c.Next()
+	})
+}
 
-		if apiKey == "" || !validAPIKeys[apiKey] {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or missing API key",
-			})
+// ServiceTokenAuth admits other platform services presenting the shared service token as
+// a bearer token. Every request is refused while no token is configured.
+func ServiceTokenAuth(token string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid service token"})
 			c.Abort()
 			return
 		}
-
 		c.Next()
 	})
 }
@@ -5,24 +5,39 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
+// Token types; access tokens leave Type empty
+const TokenTypeRefresh = "refresh"
+
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"session_id,omitempty"`
+	Type      string `json:"type,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(userID, email, role, secret string, duration time.Duration) (string, error) {
+	return GenerateSessionToken(userID, email, role, "", "", secret, duration)
+}
+
+// GenerateSessionToken issues a token bound to a session, which the auth middleware checks
+// on every request
+func GenerateSessionToken(userID, email, role, sessionID, tokenType, secret string, duration time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		Type:      tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   userID,
+			ID:        uuid.NewString(), // Keeps tokens issued in the same second distinct
 		},
 	}
 
@@ -8,9 +8,12 @@ require (
 	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.5.7
 	gorm.io/driver/sqlite v1.5.5
 	gorm.io/gorm v1.25.7
+	shared/retention v0.0.0-00010101000000-000000000000
+	shared/webhooks v0.0.0-00010101000000-000000000000
 )
 
 require (
@@ -46,5 +49,9 @@ require (
 	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	google.golang.org/protobuf v1.34.1 // indirect
 )
+
+replace shared/webhooks => ../shared/webhooks
+
+replace shared/retention => ../shared/retention
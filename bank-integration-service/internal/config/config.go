@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +25,7 @@ type Config struct {
 	// JWT and Security
 	JWTSecret         string
 	JWTExpiration     time.Duration
+	StepUpMaxAge      time.Duration // How recent the token's MFA assertion must be for sensitive operations
 	APIKeyRequired    bool
 	RateLimitRequests int
 	RateLimitWindow   time.Duration
@@ -52,16 +54,75 @@ type Config struct {
 	MaxCreditAmount    float64
 	MinCreditAmount    float64
 	DefaultCreditTerms int
+	CreditRulesPath    string
+	AuctionOfferWindow    time.Duration
+	AuctionMaxOfferWindow time.Duration
 	FundingMatchingEnabled bool
+	FundingReservationTTL           time.Duration
+	FundingMaxCustomerConcentration float64
+	FundingMaxIndustryConcentration float64
+	PortfolioLossGivenDefault       float64
 	RealTimeProcessing     bool
 	
+	// Payment reconciliation
+	ReconciliationEnabled         bool
+	ReconciliationDateWindow      time.Duration
+	ReconciliationAmountTolerance float64
+	ReconciliationAutoMatchScore  float64
+	ReconciliationReviewScore     float64
+	
+	// Report generation
+	ReportStorageDir     string
+	ReportRetention      time.Duration
+	ReportWorkerInterval time.Duration
+	ReportMaxAttempts    int
+	
+	// Inbound webhook verification
+	WebhookTimestampTolerance time.Duration
+	WebhookDedupeRetention    time.Duration
+	WebhookRequireAPIKey      bool
+	
+	// Partner API keys
+	APIKeyDefaultRateLimit   int
+	APIKeyLastUsedResolution time.Duration
+	
 	// Compliance thresholds
 	MaxDailyTransactionAmount  float64
 	MaxMonthlyTransactionAmount float64
 	SuspiciousActivityThreshold float64
+	ConnectionDailyTransactionLimit   float64
+	ConnectionMonthlyTransactionLimit float64
 	RequiredKYCDocuments       []string
 	AMLCheckRequired           bool
 	
+	// Customer KYC standing, looked up in the user management service for credit decisions
+	KYCServiceURL   string
+	KYCServiceToken string // Service token with the bank or admin role
+	
+	// AML transaction monitoring
+	AMLMonitoringInterval    time.Duration
+	AMLMonitoringBatchSize   int
+	AMLHighRiskJurisdictions []string
+	
+	// Audit trail anchoring on the Fabric ledger
+	FabricLedgerServiceURL string
+	FabricChannelName      string
+	FabricChaincodeName    string
+	AuditAnchorInterval    time.Duration
+	
+	// Data retention; financial records are kept for Epic4Config.DataRetentionPeriod
+	RetentionEnabled     bool
+	RetentionRunInterval time.Duration
+	RetentionBatchSize   int
+	
+	// Maker-checker approval of payments, transfers, disbursements, credit limits and
+	// bank account changes
+	DualControlWindow       time.Duration                // To confirm, and then to carry out the confirmed request
+	DualControlCheckerRoles []string                     // Roles that may confirm another user's request
+	DualControlTiers        map[string][]DualControlTier // Per action, sorted by amount
+	DualControlCurrency     string                       // Tier amounts are in this currency
+	DualControlRates        map[string]float64           // Units of DualControlCurrency per unit of another currency
+	
 	// Feature flags
 	EnableBulkProcessing      bool
 	EnableRealTimeTransfers   bool
@@ -88,6 +149,13 @@ type BankConfig struct {
 	RateLimit       int
 	MaxAmount       float64
 	SupportedCurrencies []string
+	WebhookSecret       string
+}
+
+// DualControlTier is the number of confirmations an action needs from MinAmount upwards
+type DualControlTier struct {
+	MinAmount float64 `json:"min_amount"`
+	Approvals int     `json:"approvals"` // 0 lets the initiator act alone
 }
 
 type Epic4Config struct {
@@ -102,6 +170,10 @@ type Epic4Config struct {
 	ReportingFrequency     string
 	DataRetentionPeriod    time.Duration
 	EncryptionRequired     bool
+	SigningKeyPath         string // Private key for CertificatePath; may be bundled in the certificate file
+	ReportingEntityID      string
+	MaxSubmissionAttempts  int
+	ResubmissionDelay      time.Duration
 }
 
 func Load() *Config {
@@ -122,6 +194,7 @@ func Load() *Config {
 		// JWT and Security
 		JWTSecret:         getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
 		JWTExpiration:     getEnvDuration("JWT_EXPIRATION", 24*time.Hour),
+		StepUpMaxAge:      getEnvDuration("STEP_UP_MAX_AGE", 5*time.Minute),
 		APIKeyRequired:    getEnvBool("API_KEY_REQUIRED", true),
 		RateLimitRequests: getEnvInt("RATE_LIMIT_REQUESTS", 100),
 		RateLimitWindow:   getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
@@ -150,16 +223,74 @@ func Load() *Config {
 		MaxCreditAmount:        getEnvFloat("MAX_CREDIT_AMOUNT", 10000000.0),
 		MinCreditAmount:        getEnvFloat("MIN_CREDIT_AMOUNT", 1000.0),
 		DefaultCreditTerms:     getEnvInt("DEFAULT_CREDIT_TERMS", 30),
+		CreditRulesPath:        getEnv("CREDIT_RULES_PATH", "config/credit_rules.yaml"),
+		AuctionOfferWindow:     getEnvDuration("AUCTION_OFFER_WINDOW", 48*time.Hour),
+		AuctionMaxOfferWindow:  getEnvDuration("AUCTION_MAX_OFFER_WINDOW", 7*24*time.Hour),
 		FundingMatchingEnabled: getEnvBool("FUNDING_MATCHING_ENABLED", true),
+		FundingReservationTTL:           getEnvDuration("FUNDING_RESERVATION_TTL", 24*time.Hour),
+		FundingMaxCustomerConcentration: getEnvFloat("FUNDING_MAX_CUSTOMER_CONCENTRATION", 0.25),
+		FundingMaxIndustryConcentration: getEnvFloat("FUNDING_MAX_INDUSTRY_CONCENTRATION", 0.40),
+		PortfolioLossGivenDefault:       getEnvFloat("PORTFOLIO_LOSS_GIVEN_DEFAULT", 0.45),
 		RealTimeProcessing:     getEnvBool("REAL_TIME_PROCESSING", true),
 		
+		// Payment reconciliation
+		ReconciliationEnabled:         getEnvBool("RECONCILIATION_ENABLED", true),
+		ReconciliationDateWindow:      getEnvDuration("RECONCILIATION_DATE_WINDOW", 72*time.Hour),
+		ReconciliationAmountTolerance: getEnvFloat("RECONCILIATION_AMOUNT_TOLERANCE", 0.01),
+		ReconciliationAutoMatchScore:  getEnvFloat("RECONCILIATION_AUTO_MATCH_SCORE", 0.90),
+		ReconciliationReviewScore:     getEnvFloat("RECONCILIATION_REVIEW_SCORE", 0.60),
+		
+		// Report generation
+		ReportStorageDir:     getEnv("REPORT_STORAGE_DIR", "data/reports"),
+		ReportRetention:      getEnvDuration("REPORT_RETENTION", 30*24*time.Hour),
+		ReportWorkerInterval: getEnvDuration("REPORT_WORKER_INTERVAL", 10*time.Second),
+		ReportMaxAttempts:    getEnvInt("REPORT_MAX_ATTEMPTS", 3),
+		
+		// Inbound webhook verification
+		WebhookTimestampTolerance: getEnvDuration("WEBHOOK_TIMESTAMP_TOLERANCE", 5*time.Minute),
+		WebhookDedupeRetention:    getEnvDuration("WEBHOOK_DEDUPE_RETENTION", 72*time.Hour),
+		WebhookRequireAPIKey:      getEnvBool("WEBHOOK_REQUIRE_API_KEY", false),
+		
+		// Partner API keys
+		APIKeyDefaultRateLimit:   getEnvInt("API_KEY_DEFAULT_RATE_LIMIT", 60),
+		APIKeyLastUsedResolution: getEnvDuration("API_KEY_LAST_USED_RESOLUTION", time.Minute),
+		
 		// Compliance thresholds
 		MaxDailyTransactionAmount:   getEnvFloat("MAX_DAILY_TRANSACTION_AMOUNT", 1000000.0),
 		MaxMonthlyTransactionAmount: getEnvFloat("MAX_MONTHLY_TRANSACTION_AMOUNT", 10000000.0),
 		SuspiciousActivityThreshold: getEnvFloat("SUSPICIOUS_ACTIVITY_THRESHOLD", 50000.0),
+		ConnectionDailyTransactionLimit:   getEnvFloat("CONNECTION_DAILY_TRANSACTION_LIMIT", 5000000.0),
+		ConnectionMonthlyTransactionLimit: getEnvFloat("CONNECTION_MONTHLY_TRANSACTION_LIMIT", 50000000.0),
 		RequiredKYCDocuments:        strings.Split(getEnv("REQUIRED_KYC_DOCUMENTS", "id_document,proof_of_address,financial_statements"), ","),
 		AMLCheckRequired:            getEnvBool("AML_CHECK_REQUIRED", true),
 		
+		// Customer KYC standing
+		KYCServiceURL:   getEnv("KYC_SERVICE_URL", "http://localhost:8081"),
+		KYCServiceToken: getEnv("KYC_SERVICE_TOKEN", ""),
+		
+		// AML transaction monitoring
+		AMLMonitoringInterval:    getEnvDuration("AML_MONITORING_INTERVAL", 5*time.Minute),
+		AMLMonitoringBatchSize:   getEnvInt("AML_MONITORING_BATCH_SIZE", 500),
+		AMLHighRiskJurisdictions: strings.Split(getEnv("AML_HIGH_RISK_JURISDICTIONS", "KP,IR,MM"), ","),
+		
+		// Audit trail anchoring on the Fabric ledger
+		FabricLedgerServiceURL: getEnv("FABRIC_LEDGER_SERVICE_URL", "http://localhost:8086"),
+		FabricChannelName:      getEnv("FABRIC_CHANNEL_NAME", "invoice-financing-channel"),
+		FabricChaincodeName:    getEnv("FABRIC_CHAINCODE_NAME", "invoice-financing"),
+		AuditAnchorInterval:    getEnvDuration("AUDIT_ANCHOR_INTERVAL", time.Hour),
+		
+		// Data retention
+		RetentionEnabled:     getEnvBool("RETENTION_ENABLED", true),
+		RetentionRunInterval: getEnvDuration("RETENTION_RUN_INTERVAL", 24*time.Hour),
+		RetentionBatchSize:   getEnvInt("RETENTION_BATCH_SIZE", 500),
+		
+		// Maker-checker approval
+		DualControlWindow:       getEnvDuration("DUAL_CONTROL_WINDOW", 24*time.Hour),
+		DualControlCheckerRoles: strings.Split(getEnv("DUAL_CONTROL_CHECKER_ROLES", "admin,bank_admin"), ","),
+		DualControlTiers:        loadDualControlTiers(),
+		DualControlCurrency:     strings.ToUpper(getEnv("DUAL_CONTROL_BASE_CURRENCY", "USD")),
+		DualControlRates:        loadDualControlRates(),
+		
 		// Feature flags
 		EnableBulkProcessing:      getEnvBool("ENABLE_BULK_PROCESSING", true),
 		EnableRealTimeTransfers:   getEnvBool("ENABLE_REAL_TIME_TRANSFERS", true),
@@ -191,6 +322,7 @@ func loadBankConfigs() map[string]BankConfig {
 		RateLimit:         getEnvInt("CHASE_RATE_LIMIT", 1000),
 		MaxAmount:         getEnvFloat("CHASE_MAX_AMOUNT", 5000000.0),
 		SupportedCurrencies: strings.Split(getEnv("CHASE_CURRENCIES", "USD,EUR,GBP"), ","),
+		WebhookSecret:       getEnv("CHASE_WEBHOOK_SECRET", ""),
 	}
 	
 	// Wells Fargo
@@ -212,6 +344,7 @@ func loadBankConfigs() map[string]BankConfig {
 		RateLimit:         getEnvInt("WELLS_FARGO_RATE_LIMIT", 800),
 		MaxAmount:         getEnvFloat("WELLS_FARGO_MAX_AMOUNT", 3000000.0),
 		SupportedCurrencies: strings.Split(getEnv("WELLS_FARGO_CURRENCIES", "USD,CAD"), ","),
+		WebhookSecret:       getEnv("WELLS_FARGO_WEBHOOK_SECRET", ""),
 	}
 	
 	// Bank of America
@@ -233,6 +366,7 @@ func loadBankConfigs() map[string]BankConfig {
 		RateLimit:         getEnvInt("BOA_RATE_LIMIT", 600),
 		MaxAmount:         getEnvFloat("BOA_MAX_AMOUNT", 2000000.0),
 		SupportedCurrencies: strings.Split(getEnv("BOA_CURRENCIES", "USD"), ","),
+		WebhookSecret:       getEnv("BOA_WEBHOOK_SECRET", ""),
 	}
 	
 	// Citibank
@@ -254,6 +388,7 @@ func loadBankConfigs() map[string]BankConfig {
 		RateLimit:         getEnvInt("CITI_RATE_LIMIT", 1200),
 		MaxAmount:         getEnvFloat("CITI_MAX_AMOUNT", 10000000.0),
 		SupportedCurrencies: strings.Split(getEnv("CITI_CURRENCIES", "USD,EUR,GBP,JPY,AUD,CAD"), ","),
+		WebhookSecret:       getEnv("CITI_WEBHOOK_SECRET", ""),
 	}
 	
 	return banks
@@ -272,7 +407,54 @@ func loadEpic4Config() Epic4Config {
 		ReportingFrequency:    getEnv("EPIC4_REPORTING_FREQUENCY", "daily"),
 		DataRetentionPeriod:   getEnvDuration("EPIC4_DATA_RETENTION", 7*365*24*time.Hour), // 7 years
 		EncryptionRequired:    getEnvBool("EPIC4_ENCRYPTION_REQUIRED", true),
+		SigningKeyPath:        getEnv("EPIC4_SIGNING_KEY_PATH", ""),
+		ReportingEntityID:     getEnv("EPIC4_REPORTING_ENTITY_ID", ""),
+		MaxSubmissionAttempts: getEnvInt("EPIC4_MAX_SUBMISSION_ATTEMPTS", 3),
+		ResubmissionDelay:     getEnvDuration("EPIC4_RESUBMISSION_DELAY", time.Hour),
+	}
+}
+
+// loadDualControlTiers reads the thresholds of each action from a list of
+// amount=confirmations pairs such as "10000=1,1000000=2"
+func loadDualControlTiers() map[string][]DualControlTier {
+	defaults := map[string]string{
+		"payment":      "10000=1,1000000=2",
+		"transfer":     "10000=1,1000000=2",
+		"disbursement": "0=1,1000000=2",
+		"credit_limit": "0=1,5000000=2",
+		"bank_account": "0=1",
+	}
+
+	tiers := make(map[string][]DualControlTier)
+	for action, value := range defaults {
+		value = getEnv("DUAL_CONTROL_"+strings.ToUpper(action)+"_TIERS", value)
+		for _, pair := range strings.Split(value, ",") {
+			amount, approvals, ok := strings.Cut(pair, "=")
+			minAmount, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+			count, countErr := strconv.Atoi(strings.TrimSpace(approvals))
+			if !ok || err != nil || countErr != nil || minAmount < 0 || count < 0 {
+				continue
+			}
+			tiers[action] = append(tiers[action], DualControlTier{MinAmount: minAmount, Approvals: count})
+		}
+		sort.Slice(tiers[action], func(i, j int) bool { return tiers[action][i].MinAmount < tiers[action][j].MinAmount })
+	}
+	return tiers
+}
+
+// loadDualControlRates reads conversion rates into the base currency from a list of
+// currency=rate pairs such as "EUR=1.08,GBP=1.27"
+func loadDualControlRates() map[string]float64 {
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(getEnv("DUAL_CONTROL_RATES", ""), ",") {
+		currency, value, ok := strings.Cut(pair, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || rate <= 0 {
+			continue
+		}
+		rates[strings.ToUpper(strings.TrimSpace(currency))] = rate
 	}
+	return rates
 }
 
 func getEnv(key, defaultValue string) string {
@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"bank-integration-service/internal/models"
+	"shared/webhooks"
 )
 
 // Initialize creates a new database connection
@@ -53,6 +54,10 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 func RunMigrations(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 
+	// Audit rows written before hash chaining existed are chained once, on the
+	// migration that adds the columns
+	chainLegacyAudit := db.Migrator().HasTable(&models.AuditTrail{}) && !db.Migrator().HasColumn(&models.AuditTrail{}, "Hash")
+
 	err := db.AutoMigrate(
 		&models.BankConnection{},
 		&models.CreditDecision{},
@@ -66,17 +71,47 @@ func RunMigrations(db *gorm.DB) error {
 		&models.FundingMatching{},
 		&models.RiskAssessment{},
 		&models.ReconciliationJob{},
+		&models.BankStatementLine{},
+		&models.FinancingOffer{},
+		&models.PortfolioSnapshot{},
+		&models.PortfolioReport{},
+		&models.APIKey{},
+		&models.TransactionLimitOverride{},
+		&models.TransactionLimitReservation{},
+		&models.AMLScenario{},
+		&models.AMLAlert{},
+		&models.AMLAlertNote{},
+		&models.RegulatoryFiling{},
+		&models.RegulatoryFilingSubmission{},
+		&models.AuditAnchor{},
+		&models.RetentionRule{},
+		&models.LegalHold{},
+		&models.PurgeRun{},
+		&models.DualControlApproval{},
+		&models.DualControlDecision{},
+		&webhooks.SenderSecret{},
+		&webhooks.ReceivedEvent{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if chainLegacyAudit {
+		if err := chainLegacyAuditTrails(db); err != nil {
+			return fmt.Errorf("failed to chain existing audit trails: %w", err)
+		}
+	}
+
 	// Create indexes for better performance
 	if err := createIndexes(db); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	if err := createAuditTrailGuards(db); err != nil {
+		return fmt.Errorf("failed to make audit trails append-only: %w", err)
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
@@ -101,12 +136,16 @@ func createIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_status ON payment_transactions(status)",
 		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_created_at ON payment_transactions(created_at)",
 		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_processed_at ON payment_transactions(processed_at)",
+		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_customer_created ON payment_transactions(customer_id, created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_connection_created ON payment_transactions(bank_connection_id, created_at)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_limit_overrides_customer_id ON transaction_limit_overrides(customer_id)",
 
 		// FinancingRequest indexes
 		"CREATE INDEX IF NOT EXISTS idx_financing_requests_customer_id ON financing_requests(customer_id)",
 		"CREATE INDEX IF NOT EXISTS idx_financing_requests_status ON financing_requests(status)",
 		"CREATE INDEX IF NOT EXISTS idx_financing_requests_request_type ON financing_requests(request_type)",
 		"CREATE INDEX IF NOT EXISTS idx_financing_requests_created_at ON financing_requests(created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_financing_requests_offer_deadline ON financing_requests(offer_deadline)",
 
 		// PortfolioItem indexes
 		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_bank_connection_id ON portfolio_items(bank_connection_id)",
@@ -114,6 +153,21 @@ func createIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_status ON portfolio_items(status)",
 		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_maturity_date ON portfolio_items(maturity_date)",
 		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_risk_rating ON portfolio_items(risk_rating)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_currency ON portfolio_items(currency)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_industry ON portfolio_items(industry)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_next_payment_due ON portfolio_items(next_payment_due)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_items_created_at ON portfolio_items(created_at)",
+		// One snapshot per day and segment
+		"DROP INDEX IF EXISTS idx_portfolio_snapshots_snapshot_date",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_portfolio_snapshots_segment ON portfolio_snapshots(snapshot_date, COALESCE(bank_connection_id::text, ''), currency, industry)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_reports_status ON portfolio_reports(status)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_reports_expires_at ON portfolio_reports(expires_at)",
+		"CREATE INDEX IF NOT EXISTS idx_portfolio_reports_requested_by ON portfolio_reports(requested_by)",
+
+		// APIKey indexes
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(prefix)",
+		"CREATE INDEX IF NOT EXISTS idx_api_keys_organization_id ON api_keys(organization_id)",
 
 		// FundingSource indexes
 		"CREATE INDEX IF NOT EXISTS idx_funding_sources_bank_connection_id ON funding_sources(bank_connection_id)",
@@ -127,11 +181,43 @@ func createIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_compliance_records_status ON compliance_records(status)",
 		"CREATE INDEX IF NOT EXISTS idx_compliance_records_checked_at ON compliance_records(checked_at)",
 
+		// AML monitoring indexes
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_aml_scenarios_code ON aml_scenarios(code)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_aml_alerts_scenario_entity ON aml_alerts(scenario_code, entity_type, entity_id)",
+		"CREATE INDEX IF NOT EXISTS idx_aml_alerts_status ON aml_alerts(status)",
+		"CREATE INDEX IF NOT EXISTS idx_aml_alerts_customer_id ON aml_alerts(customer_id)",
+		"CREATE INDEX IF NOT EXISTS idx_aml_alerts_assigned_to ON aml_alerts(assigned_to)",
+		"CREATE INDEX IF NOT EXISTS idx_aml_alert_notes_alert_id ON aml_alert_notes(alert_id)",
+		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_aml_pending ON payment_transactions(created_at) WHERE aml_screened_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_financing_requests_aml_pending ON financing_requests(disbursed_at) WHERE aml_screened_at IS NULL AND disbursed_at IS NOT NULL",
+
+		// DualControlApproval indexes; a checker decides on an approval only once
+		"CREATE INDEX IF NOT EXISTS idx_dual_control_approvals_status_expires ON dual_control_approvals(status, expires_at)",
+		"CREATE INDEX IF NOT EXISTS idx_dual_control_approvals_initiated_by ON dual_control_approvals(initiated_by)",
+		"CREATE INDEX IF NOT EXISTS idx_dual_control_approvals_created_at ON dual_control_approvals(created_at)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_dual_control_decisions_approval_user ON dual_control_decisions(approval_id, user_id)",
+
+		// RegulatoryFiling indexes
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_regulatory_filings_reference ON regulatory_filings(reference)",
+		"CREATE INDEX IF NOT EXISTS idx_regulatory_filings_filing_type ON regulatory_filings(filing_type)",
+		"CREATE INDEX IF NOT EXISTS idx_regulatory_filings_status ON regulatory_filings(status)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_regulatory_filings_period ON regulatory_filings(filing_type, period_start, period_end) WHERE period_start IS NOT NULL",
+		"CREATE INDEX IF NOT EXISTS idx_regulatory_filings_next_attempt ON regulatory_filings(next_attempt_at) WHERE next_attempt_at IS NOT NULL",
+		"CREATE INDEX IF NOT EXISTS idx_regulatory_filing_submissions_filing_id ON regulatory_filing_submissions(filing_id)",
+
 		// AuditTrail indexes
 		"CREATE INDEX IF NOT EXISTS idx_audit_trails_entity_type ON audit_trails(entity_type)",
 		"CREATE INDEX IF NOT EXISTS idx_audit_trails_entity_id ON audit_trails(entity_id)",
 		"CREATE INDEX IF NOT EXISTS idx_audit_trails_user_id ON audit_trails(user_id)",
 		"CREATE INDEX IF NOT EXISTS idx_audit_trails_timestamp ON audit_trails(timestamp)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_trails_sequence ON audit_trails(sequence)",
+		"CREATE INDEX IF NOT EXISTS idx_audit_anchors_sequence ON audit_anchors(sequence)",
+
+		// Data retention indexes; only one purge run may be running at a time
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_retention_rules_name ON retention_rules(name)",
+		"CREATE INDEX IF NOT EXISTS idx_legal_holds_active ON legal_holds(entity) WHERE released_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_purge_runs_started_at ON purge_runs(started_at)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_purge_runs_running ON purge_runs(status) WHERE status = 'running'",
 
 		// BankAccount indexes
 		"CREATE INDEX IF NOT EXISTS idx_bank_accounts_bank_connection_id ON bank_accounts(bank_connection_id)",
@@ -143,6 +229,8 @@ func createIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_funding_matching_funding_source_id ON funding_matchings(funding_source_id)",
 		"CREATE INDEX IF NOT EXISTS idx_funding_matching_status ON funding_matchings(status)",
 		"CREATE INDEX IF NOT EXISTS idx_funding_matching_matched_at ON funding_matchings(matched_at)",
+		"CREATE INDEX IF NOT EXISTS idx_funding_matching_expires_at ON funding_matchings(expires_at)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_funding_matching_active_request ON funding_matchings(financing_request_id) WHERE status IN ('pending', 'confirmed')",
 
 		// RiskAssessment indexes
 		"CREATE INDEX IF NOT EXISTS idx_risk_assessments_entity_type ON risk_assessments(entity_type)",
@@ -155,6 +243,20 @@ func createIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_reconciliation_jobs_job_type ON reconciliation_jobs(job_type)",
 		"CREATE INDEX IF NOT EXISTS idx_reconciliation_jobs_status ON reconciliation_jobs(status)",
 		"CREATE INDEX IF NOT EXISTS idx_reconciliation_jobs_created_at ON reconciliation_jobs(created_at)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_jobs_scheduled_period ON reconciliation_jobs(job_type, start_date) WHERE job_type <> 'on_demand'",
+
+		// BankStatementLine indexes
+		"CREATE INDEX IF NOT EXISTS idx_bank_statement_lines_bank_connection_id ON bank_statement_lines(bank_connection_id)",
+		"CREATE INDEX IF NOT EXISTS idx_bank_statement_lines_status ON bank_statement_lines(status)",
+		"CREATE INDEX IF NOT EXISTS idx_bank_statement_lines_booking_date ON bank_statement_lines(booking_date)",
+		"CREATE INDEX IF NOT EXISTS idx_bank_statement_lines_reference ON bank_statement_lines(reference)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_statement_lines_external ON bank_statement_lines(bank_connection_id, external_line_id) WHERE external_line_id <> ''",
+		"CREATE INDEX IF NOT EXISTS idx_payment_transactions_reconciled_at ON payment_transactions(reconciled_at)",
+
+		// FinancingOffer indexes
+		"CREATE INDEX IF NOT EXISTS idx_financing_offers_financing_request_id ON financing_offers(financing_request_id)",
+		"CREATE INDEX IF NOT EXISTS idx_financing_offers_status ON financing_offers(status)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_financing_offers_request_bank ON financing_offers(financing_request_id, bank_connection_id)",
 	}
 
 	for _, indexSQL := range indexes {
@@ -167,6 +269,75 @@ func createIndexes(db *gorm.DB) error {
 	return nil
 }
 
+// chainLegacyAuditTrails links audit rows that predate hash chaining, oldest first
+func chainLegacyAuditTrails(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var trails []models.AuditTrail
+		if err := tx.Order("timestamp ASC, created_at ASC, id ASC").Find(&trails).Error; err != nil {
+			return err
+		}
+		prevHash := models.AuditGenesisHash
+		for i := range trails {
+			trail := &trails[i]
+			trail.Sequence = int64(i + 1)
+			trail.PrevHash = prevHash
+			trail.Hash = trail.ComputeHash()
+			if err := tx.Model(trail).Updates(map[string]interface{}{
+				"sequence":  trail.Sequence,
+				"prev_hash": trail.PrevHash,
+				"hash":      trail.Hash,
+			}).Error; err != nil {
+				return err
+			}
+			prevHash = trail.Hash
+		}
+		log.Printf("Chained %d existing audit trail entries", len(trails))
+		return nil
+	})
+}
+
+// createAuditTrailGuards installs triggers that reject any UPDATE, DELETE or TRUNCATE on
+// audit_trails and audit_anchors, so entries and anchors can only ever be appended
+func createAuditTrailGuards(db *gorm.DB) error {
+	tables := []string{"audit_trails", "audit_anchors"}
+
+	var statements []string
+	switch db.Dialector.Name() {
+	case "postgres":
+		statements = []string{
+			`CREATE OR REPLACE FUNCTION audit_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION '% is append-only: % rejected', TG_TABLE_NAME, TG_OP;
+END;
+$$ LANGUAGE plpgsql`,
+		}
+		for _, table := range tables {
+			statements = append(statements,
+				fmt.Sprintf("DROP TRIGGER IF EXISTS %s_no_modify ON %s", table, table),
+				fmt.Sprintf("CREATE TRIGGER %s_no_modify BEFORE UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION audit_append_only()", table, table),
+				fmt.Sprintf("DROP TRIGGER IF EXISTS %s_no_truncate ON %s", table, table),
+				fmt.Sprintf("CREATE TRIGGER %s_no_truncate BEFORE TRUNCATE ON %s FOR EACH STATEMENT EXECUTE FUNCTION audit_append_only()", table, table),
+			)
+		}
+		// Superseded by audit_append_only, which names the table it guards
+		statements = append(statements, "DROP FUNCTION IF EXISTS audit_trails_append_only()")
+	case "sqlite":
+		for _, table := range tables {
+			statements = append(statements,
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_no_update BEFORE UPDATE ON %s BEGIN SELECT RAISE(ABORT, '%s is append-only: UPDATE rejected'); END", table, table, table),
+				fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_no_delete BEFORE DELETE ON %s BEGIN SELECT RAISE(ABORT, '%s is append-only: DELETE rejected'); END", table, table, table),
+			)
+		}
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SeedData inserts initial data for development/testing
 func SeedData(db *gorm.DB) error {
 	log.Println("Seeding database with initial data...")
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"bank-integration-service/internal/models"
 	"bank-integration-service/internal/services"
 )
 
@@ -15,13 +23,15 @@ type BankHandler struct {
 	bankAPIService    *services.BankAPIService
 	complianceService *services.ComplianceService
 	auditService      *services.AuditService
+	paymentService    *services.PaymentProcessingService
 }
 
-func NewBankHandler(bankAPIService *services.BankAPIService, complianceService *services.ComplianceService, auditService *services.AuditService) *BankHandler {
+func NewBankHandler(bankAPIService *services.BankAPIService, complianceService *services.ComplianceService, auditService *services.AuditService, paymentService *services.PaymentProcessingService) *BankHandler {
 	return &BankHandler{
 		bankAPIService:    bankAPIService,
 		complianceService: complianceService,
 		auditService:      auditService,
+		paymentService:    paymentService,
 	}
 }
 
@@ -57,8 +67,95 @@ func (h *BankHandler) GetAllBankConnections(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Get all bank connections - implementation needed"})
 }
 
+// webhookEvent is the body of an inbound webhook; authenticity has already been checked
+// by the webhook verifier middleware
+type webhookEvent struct {
+	Event string          `json:"event" binding:"required"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// paymentEvent reports whether the event carries the status of a payment or transfer
+func (e *webhookEvent) paymentEvent() bool {
+	return strings.HasPrefix(e.Event, "payment.") || strings.HasPrefix(e.Event, "transfer.")
+}
+
+// applyPaymentWebhook moves the payment or transfer named in the event to its reported
+// status. The status may be given in the data or, for events such as payment.completed,
+// by the event name.
+func applyPaymentWebhook(c *gin.Context, payments *services.PaymentProcessingService, audit *services.AuditService, event *webhookEvent) {
+	var update services.PaymentStatusUpdate
+	if len(event.Data) > 0 {
+		if err := json.Unmarshal(event.Data, &update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event data"})
+			return
+		}
+	}
+	if update.Status == "" {
+		_, update.Status, _ = strings.Cut(event.Event, ".")
+	}
+
+	sender := c.GetString("webhookSender")
+	eventID := c.GetString("webhookEventID")
+	payment, previous, err := payments.ApplyStatusUpdate(sender, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidPaymentStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPaymentStatusTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Payment webhook %s from %s failed: %v", eventID, sender, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply payment status"})
+		}
+		return
+	}
+
+	if previous != "" {
+		if _, err := audit.Record(services.AuditEntry{
+			EntityType:  "payment_transaction",
+			EntityID:    payment.ID,
+			Action:      "status_webhook",
+			IPAddress:   c.ClientIP(),
+			Details:     map[string]interface{}{"sender": sender, "event_id": eventID, "event": event.Event},
+			BeforeState: map[string]interface{}{"status": previous},
+			AfterState:  map[string]interface{}{"status": payment.Status},
+		}); err != nil {
+			log.Printf("Failed to audit payment webhook %s: %v", eventID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "applied",
+		"sender":     sender,
+		"event_id":   eventID,
+		"event":      event.Event,
+		"payment_id": payment.PaymentID,
+		"changed":    previous != "",
+	})
+}
+
+// BankWebhookHandler applies payment and transfer status events and acknowledges other
+// bank notifications
 func (h *BankHandler) BankWebhookHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Bank webhook handler - implementation needed"})
+	var event webhookEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if event.paymentEvent() {
+		applyPaymentWebhook(c, h.paymentService, h.auditService, &event)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "received",
+		"sender":   c.GetString("webhookSender"),
+		"event_id": c.GetString("webhookEventID"),
+		"event":    event.Event,
+	})
 }
 
 func (h *BankHandler) GetExternalBankRates(c *gin.Context) {
@@ -121,11 +218,52 @@ func NewCreditHandler(creditDecisionService *services.CreditDecisionService, ris
 }
 
 func (h *CreditHandler) RequestCreditDecision(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Request credit decision - implementation needed"})
+	var request services.CreditDecisionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	request.RequestedBy = c.GetString("userID")
+
+	// Credit is always requested for the authenticated customer
+	customerID, err := uuid.Parse(request.RequestedBy)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Credit decisions can only be requested by a customer"})
+		return
+	}
+	request.CustomerID = customerID
+
+	decision, err := h.creditDecisionService.RequestDecision(request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrKYCUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "KYC status could not be checked, try again later"})
+		case errors.Is(err, services.ErrConnectionNotOwned):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Credit decision request failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request credit decision"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, decision)
 }
 
 func (h *CreditHandler) GetCreditDecision(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get credit decision - implementation needed"})
+	decisionID, err := uuid.Parse(c.Param("decisionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid decision ID"})
+		return
+	}
+
+	decision, err := h.creditDecisionService.GetDecision(decisionID, c.GetString("userID"), isCreditReviewer(c))
+	if err != nil {
+		respondCreditDecisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
 }
 
 func (h *CreditHandler) UpdateCreditDecision(c *gin.Context) {
@@ -133,15 +271,105 @@ func (h *CreditHandler) UpdateCreditDecision(c *gin.Context) {
 }
 
 func (h *CreditHandler) ApproveCreditDecision(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Approve credit decision - implementation needed"})
+	decisionID, err := uuid.Parse(c.Param("decisionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid decision ID"})
+		return
+	}
+
+	var request struct {
+		Notes string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	decision, err := h.creditDecisionService.ApproveDecision(decisionID, fmt.Sprint(c.MustGet("userID")), request.Notes)
+	if err != nil {
+		respondCreditDecisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
 }
 
 func (h *CreditHandler) RejectCreditDecision(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Reject credit decision - implementation needed"})
+	decisionID, err := uuid.Parse(c.Param("decisionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid decision ID"})
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A rejection reason is required"})
+		return
+	}
+
+	decision, err := h.creditDecisionService.RejectDecision(decisionID, fmt.Sprint(c.MustGet("userID")), request.Reason)
+	if err != nil {
+		respondCreditDecisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
 }
 
 func (h *CreditHandler) GetCreditDecisions(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get credit decisions - implementation needed"})
+	var customerID *uuid.UUID
+	if value := c.Query("customer_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+			return
+		}
+		customerID = &id
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	decisions, total, err := h.creditDecisionService.ListDecisions(customerID, c.Query("status"), limit, offset, c.GetString("userID"), isCreditReviewer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load credit decisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decisions": decisions,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// isCreditReviewer reports whether the caller is bank staff who can see every customer's
+// credit decisions
+func isCreditReviewer(c *gin.Context) bool {
+	role, _ := c.Get("userRole")
+	return role == "bank" || role == "admin" || role == "bank_admin"
+}
+
+func respondCreditDecisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCreditDecisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCreditDecisionExpired), errors.Is(err, services.ErrCreditDecisionFinal):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCreditDecisionOwn):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
 }
 
 func (h *CreditHandler) AssessRisk(c *gin.Context) {
@@ -159,13 +387,15 @@ func (h *CreditHandler) UpdateCreditLimits(c *gin.Context) {
 // PaymentHandler handles payment operations
 type PaymentHandler struct {
 	paymentProcessingService *services.PaymentProcessingService
+	reconciliationService   *services.ReconciliationService
 	bankAPIService          *services.BankAPIService
 	auditService            *services.AuditService
 }
 
-func NewPaymentHandler(paymentProcessingService *services.PaymentProcessingService, bankAPIService *services.BankAPIService, auditService *services.AuditService) *PaymentHandler {
+func NewPaymentHandler(paymentProcessingService *services.PaymentProcessingService, reconciliationService *services.ReconciliationService, bankAPIService *services.BankAPIService, auditService *services.AuditService) *PaymentHandler {
 	return &PaymentHandler{
 		paymentProcessingService: paymentProcessingService,
+		reconciliationService:   reconciliationService,
 		bankAPIService:          bankAPIService,
 		auditService:            auditService,
 	}
@@ -196,11 +426,176 @@ func (h *PaymentHandler) GetTransactions(c *gin.Context) {
 }
 
 func (h *PaymentHandler) ReconcilePayments(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Reconcile payments - implementation needed"})
+	var request struct {
+		JobType   string    `json:"job_type"`
+		StartDate time.Time `json:"start_date"`
+		EndDate   time.Time `json:"end_date"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if request.JobType == "" {
+		request.JobType = services.ReconciliationJobOnDemand
+	}
+	if request.JobType != services.ReconciliationJobOnDemand && request.StartDate.IsZero() {
+		request.StartDate, request.EndDate = services.ReconciliationPeriod(request.JobType, time.Now())
+	}
+	if request.StartDate.IsZero() || request.EndDate.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required for on-demand reconciliation"})
+		return
+	}
+
+	job, err := h.reconciliationService.CreateJob(request.JobType, request.StartDate, request.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Matching can take a while for large statements, so run it in the background
+	go func() {
+		if err := h.reconciliationService.RunJob(job.ID); err != nil {
+			log.Printf("Reconciliation job %s failed: %v", job.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, job)
 }
 
 func (h *PaymentHandler) GetReconciliationStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get reconciliation status - implementation needed"})
+	jobID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.reconciliationService.GetJob(jobID)
+	if err != nil {
+		if errors.Is(err, services.ErrReconciliationJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reconciliation job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *PaymentHandler) ImportStatementLines(c *gin.Context) {
+	var request struct {
+		BankConnectionID uuid.UUID                     `json:"bank_connection_id" binding:"required"`
+		Lines            []services.StatementLineInput `json:"lines" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	lines, err := h.reconciliationService.ImportStatementLines(request.BankConnectionID, request.Lines, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondReconciliationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"imported": len(lines),
+		"skipped":  len(request.Lines) - len(lines),
+		"lines":    lines,
+	})
+}
+
+func (h *PaymentHandler) GetManualMatchQueue(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	lines, total, err := h.reconciliationService.GetManualQueue(limit, offset, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load manual match queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lines":  lines,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *PaymentHandler) ConfirmManualMatch(c *gin.Context) {
+	lineID, err := uuid.Parse(c.Param("lineId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid statement line ID"})
+		return
+	}
+
+	var request struct {
+		TransactionID uuid.UUID `json:"transaction_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	line, err := h.reconciliationService.ConfirmManualMatch(lineID, request.TransactionID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondReconciliationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, line)
+}
+
+func (h *PaymentHandler) IgnoreStatementLine(c *gin.Context) {
+	lineID, err := uuid.Parse(c.Param("lineId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid statement line ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	line, err := h.reconciliationService.IgnoreStatementLine(lineID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondReconciliationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, line)
+}
+
+func respondReconciliationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrStatementLineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrConnectionNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTransactionAlreadyMatched), errors.Is(err, services.ErrStatementLineMatched):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Reconciliation request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Reconciliation request failed"})
+	}
 }
 
 func (h *PaymentHandler) InitiateTransfer(c *gin.Context) {
@@ -227,8 +622,15 @@ func (h *PaymentHandler) BulkTransfer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Bulk transfer - implementation needed"})
 }
 
+// PaymentStatusWebhook applies a bank's payment or transfer status update
 func (h *PaymentHandler) PaymentStatusWebhook(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Payment status webhook - implementation needed"})
+	var event webhookEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	applyPaymentWebhook(c, h.paymentProcessingService, h.auditService, &event)
 }
 
 func (h *PaymentHandler) GetPaymentSummary(c *gin.Context) {
@@ -238,24 +640,190 @@ func (h *PaymentHandler) GetPaymentSummary(c *gin.Context) {
 // FinancingHandler handles financing operations
 type FinancingHandler struct {
 	financingService       *services.FinancingService
+	offerAuctionService    *services.OfferAuctionService
 	fundingMatchingService *services.FundingMatchingService
 	complianceService      *services.ComplianceService
 }
 
-func NewFinancingHandler(financingService *services.FinancingService, fundingMatchingService *services.FundingMatchingService, complianceService *services.ComplianceService) *FinancingHandler {
+func NewFinancingHandler(financingService *services.FinancingService, offerAuctionService *services.OfferAuctionService, fundingMatchingService *services.FundingMatchingService, complianceService *services.ComplianceService) *FinancingHandler {
 	return &FinancingHandler{
 		financingService:       financingService,
+		offerAuctionService:    offerAuctionService,
 		fundingMatchingService: fundingMatchingService,
 		complianceService:      complianceService,
 	}
 }
 
 func (h *FinancingHandler) CreateFinancingRequest(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Create financing request - implementation needed"})
+	var request struct {
+		RequestType     string    `json:"request_type" binding:"required"`
+		RequestedAmount float64   `json:"requested_amount" binding:"required"`
+		Currency        string    `json:"currency"`
+		Term            int       `json:"term"`
+		Purpose         string    `json:"purpose"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Requests are always made for the authenticated customer
+	customerID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Financing requests can only be made by a customer"})
+		return
+	}
+
+	financingRequest := &models.FinancingRequest{
+		CustomerID:      customerID,
+		RequestType:     request.RequestType,
+		RequestedAmount: request.RequestedAmount,
+		Currency:        strings.ToUpper(request.Currency),
+		Term:            request.Term,
+		Purpose:         request.Purpose,
+	}
+	if err := h.financingService.CreateRequest(financingRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, financingRequest)
 }
 
 func (h *FinancingHandler) GetFinancingRequest(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get financing request - implementation needed"})
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	financingRequest, err := h.financingService.GetRequest(requestID)
+	if err != nil {
+		respondAuctionError(c, err)
+		return
+	}
+
+	offers, err := h.offerAuctionService.ListOffers(requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load offers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"request": financingRequest,
+		"offers":  offers,
+	})
+}
+
+func (h *FinancingHandler) StartOfferAuction(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var request struct {
+		OfferDeadline *time.Time `json:"offer_deadline"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	financingRequest, offers, err := h.offerAuctionService.StartAuction(requestID, request.OfferDeadline, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondAuctionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"request": financingRequest,
+		"offers":  offers,
+	})
+}
+
+func (h *FinancingHandler) GetFinancingOffers(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	offers, err := h.offerAuctionService.ListOffers(requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load offers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"offers": offers})
+}
+
+func (h *FinancingHandler) SubmitFinancingOffer(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var request services.OfferInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Bank users may only respond on behalf of their own bank
+	bankCode, _ := c.Get("bankID")
+	bankCodeValue, _ := bankCode.(string)
+
+	offer, err := h.offerAuctionService.SubmitOffer(requestID, request, bankCodeValue)
+	if err != nil {
+		respondAuctionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, offer)
+}
+
+func (h *FinancingHandler) AcceptFinancingOffer(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+	offerID, err := uuid.Parse(c.Param("offerId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	offer, err := h.offerAuctionService.AcceptOffer(requestID, offerID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondAuctionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, offer)
+}
+
+func respondAuctionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFinancingRequestNotFound), errors.Is(err, services.ErrFinancingOfferNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRequestOwner), errors.Is(err, services.ErrBankNotIdentified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAuctionNotOpen), errors.Is(err, services.ErrAuctionStillOpen),
+		errors.Is(err, services.ErrOfferNotAcceptable), errors.Is(err, services.ErrNoEligibleBanks):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
 }
 
 func (h *FinancingHandler) UpdateFinancingRequest(c *gin.Context) {
@@ -315,11 +883,91 @@ func (h *FinancingHandler) GetFundingAllocations(c *gin.Context) {
 }
 
 func (h *FinancingHandler) RunFundingMatching(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Run funding matching - implementation needed"})
+	result, err := h.fundingMatchingService.RunMatching()
+	if err != nil {
+		if errors.Is(err, services.ErrFundingMatchingDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
 func (h *FinancingHandler) GetMatchingResults(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get matching results - implementation needed"})
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	matches, total, err := h.fundingMatchingService.ListMatches(c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load matching results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matches": matches,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func (h *FinancingHandler) ConfirmFundingMatch(c *gin.Context) {
+	matchID, err := uuid.Parse(c.Param("matchId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+	match, err := h.fundingMatchingService.ConfirmMatch(matchID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondFundingMatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, match)
+}
+
+func (h *FinancingHandler) RejectFundingMatch(c *gin.Context) {
+	matchID, err := uuid.Parse(c.Param("matchId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+	match, err := h.fundingMatchingService.RejectMatch(matchID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondFundingMatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, match)
+}
+
+func respondFundingMatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFundingMatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFundingMatchNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFundingMatchNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Funding match request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Funding match request failed"})
+	}
 }
 
 func (h *FinancingHandler) GetFinancingSummary(c *gin.Context) {
@@ -329,42 +977,229 @@ func (h *FinancingHandler) GetFinancingSummary(c *gin.Context) {
 // PortfolioHandler handles portfolio operations
 type PortfolioHandler struct {
 	portfolioService      *services.PortfolioService
+	reportService         *services.ReportService
 	riskAssessmentService *services.RiskAssessmentService
 }
 
-func NewPortfolioHandler(portfolioService *services.PortfolioService, riskAssessmentService *services.RiskAssessmentService) *PortfolioHandler {
+func NewPortfolioHandler(portfolioService *services.PortfolioService, reportService *services.ReportService, riskAssessmentService *services.RiskAssessmentService) *PortfolioHandler {
 	return &PortfolioHandler{
 		portfolioService:      portfolioService,
+		reportService:         reportService,
 		riskAssessmentService: riskAssessmentService,
 	}
 }
 
 func (h *PortfolioHandler) GetPortfolioOverview(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get portfolio overview - implementation needed"})
+	filter, ok := portfolioFilter(c)
+	if !ok {
+		return
+	}
+
+	overview, err := h.portfolioService.Overview(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
 }
 
 func (h *PortfolioHandler) GetPortfolioPerformance(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get portfolio performance - implementation needed"})
+	filter, ok := portfolioFilter(c)
+	if !ok {
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -90)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		to = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	performance, err := h.portfolioService.Performance(filter, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, performance)
 }
 
 func (h *PortfolioHandler) GetRiskAnalysis(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get risk analysis - implementation needed"})
+	filter, ok := portfolioFilter(c)
+	if !ok {
+		return
+	}
+
+	analysis, err := h.portfolioService.RiskAnalysis(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
 }
 
 func (h *PortfolioHandler) GetExposures(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get exposures - implementation needed"})
+	filter, ok := portfolioFilter(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 20
+	}
+
+	exposures := gin.H{}
+	for _, dimension := range []string{"customer", "industry", "currency"} {
+		entries, err := h.portfolioService.Exposures(filter, dimension, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		exposures[dimension] = entries
+	}
+
+	c.JSON(http.StatusOK, exposures)
 }
 
 func (h *PortfolioHandler) GetConcentrations(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get concentrations - implementation needed"})
+	filter, ok := portfolioFilter(c)
+	if !ok {
+		return
+	}
+
+	concentrations, err := h.portfolioService.Concentrations(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"concentrations": concentrations})
+}
+
+// portfolioFilter reads the optional bank_connection_id, currency and industry query parameters
+func portfolioFilter(c *gin.Context) (services.PortfolioFilter, bool) {
+	filter := services.PortfolioFilter{Currency: c.Query("currency"), Industry: c.Query("industry")}
+	if value := c.Query("bank_connection_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank connection ID"})
+			return filter, false
+		}
+		filter.BankConnectionID = &id
+	}
+	return filter, true
 }
 
 func (h *PortfolioHandler) GenerateReport(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Generate report - implementation needed"})
+	var request services.ReportRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.reportService.EnqueueReport(request, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"report":     report,
+		"status_url": fmt.Sprintf("/api/v1/portfolio/reports/%s", report.ID),
+	})
 }
 
 func (h *PortfolioHandler) GetReport(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get report - implementation needed"})
+	reportID, err := uuid.Parse(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	report, err := h.reportService.GetReport(reportID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	response := gin.H{"report": report}
+	if report.Status == services.ReportStatusCompleted {
+		response["download_url"] = fmt.Sprintf("/api/v1/portfolio/reports/%s/download", report.ID)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *PortfolioHandler) ListReports(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	reports, err := h.reportService.ListReports(fmt.Sprint(c.MustGet("userID")), isAdmin, c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports, "count": len(reports)})
+}
+
+func (h *PortfolioHandler) DownloadReport(c *gin.Context) {
+	reportID, err := uuid.Parse(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	isAdmin := role == "admin" || role == "bank_admin"
+
+	report, path, err := h.reportService.OpenArtifact(reportID, fmt.Sprint(c.MustGet("userID")), isAdmin)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", report.ContentType)
+	c.Header("X-Content-SHA256", report.Checksum)
+	c.FileAttachment(path, report.FileName)
+}
+
+func respondReportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReportNotReady), errors.Is(err, services.ErrReportFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReportExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReportRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 func (h *PortfolioHandler) GetAnalytics(c *gin.Context) {
@@ -379,12 +1214,18 @@ func (h *PortfolioHandler) GetPortfolioSummary(c *gin.Context) {
 type ComplianceHandler struct {
 	complianceService *services.ComplianceService
 	auditService      *services.AuditService
+	limitService      *services.TransactionLimitService
+	amlService        *services.AMLService
+	filingService     *services.RegulatoryFilingService
 }
 
-func NewComplianceHandler(complianceService *services.ComplianceService, auditService *services.AuditService) *ComplianceHandler {
+func NewComplianceHandler(complianceService *services.ComplianceService, auditService *services.AuditService, limitService *services.TransactionLimitService, amlService *services.AMLService, filingService *services.RegulatoryFilingService) *ComplianceHandler {
 	return &ComplianceHandler{
 		complianceService: complianceService,
 		auditService:      auditService,
+		limitService:      limitService,
+		amlService:        amlService,
+		filingService:     filingService,
 	}
 }
 
@@ -411,22 +1252,1042 @@ func (h *ComplianceHandler) GetEpic4Reports(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Get Epic 4 reports - implementation needed"})
 }
 
+func respondAuditError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAuditEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAuditChainEmpty):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAuditChainBroken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAuditAnchorFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Audit operation failed"})
+	}
+}
+
+// CreateAuditTrail appends an application event, such as a state change made by another
+// service, to the hash-chained audit trail
 func (h *ComplianceHandler) CreateAuditTrail(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Create audit trail - implementation needed"})
+	var req struct {
+		EntityType  string          `json:"entity_type" binding:"required"`
+		EntityID    uuid.UUID       `json:"entity_id" binding:"required"`
+		Action      string          `json:"action" binding:"required"`
+		Details     json.RawMessage `json:"details"`
+		BeforeState json.RawMessage `json:"before_state"`
+		AfterState  json.RawMessage `json:"after_state"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	trail, err := h.auditService.Record(services.AuditEntry{
+		EntityType:  req.EntityType,
+		EntityID:    req.EntityID,
+		Action:      req.Action,
+		UserID:      userID,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Details:     req.Details,
+		BeforeState: req.BeforeState,
+		AfterState:  req.AfterState,
+	})
+	if err != nil {
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, trail)
 }
 
 func (h *ComplianceHandler) GetAuditTrails(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get audit trails - implementation needed"})
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter := services.AuditTrailFilter{
+		EntityType: c.Query("entity_type"),
+		Action:     c.Query("action"),
+		Page:       page,
+		Limit:      limit,
+	}
+	if value := c.Query("entity_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity_id"})
+			return
+		}
+		filter.EntityID = &id
+	}
+	if value := c.Query("user_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		filter.UserID = &id
+	}
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ", expected RFC 3339"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	trails, total, err := h.auditService.ListTrails(filter)
+	if err != nil {
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_trails": trails,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// VerifyAuditChain walks the audit chain and reports the first broken link
+func (h *ComplianceHandler) VerifyAuditChain(c *gin.Context) {
+	report, err := h.auditService.VerifyChain()
+	if err != nil {
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ComplianceHandler) GetAuditAnchors(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	anchors, err := h.auditService.ListAnchors(limit)
+	if err != nil {
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"anchors": anchors})
 }
 
+// AnchorAuditChain publishes the current chain head to the Fabric ledger immediately
+func (h *ComplianceHandler) AnchorAuditChain(c *gin.Context) {
+	anchor, err := h.auditService.AnchorHead()
+	if err != nil {
+		if anchor != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "anchor": anchor})
+			return
+		}
+		respondAuditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, anchor)
+}
+
+// CreateRegulatoryFiling prepares a filing. Suspicious activity reports are assembled
+// from AML alerts on one customer.
 func (h *ComplianceHandler) CreateRegulatoryFiling(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Create regulatory filing - implementation needed"})
+	var req struct {
+		FilingType  string     `json:"filing_type" binding:"required"`
+		PeriodStart *time.Time `json:"period_start"` // Periodic reports only
+		PeriodEnd   *time.Time `json:"period_end"`
+		services.SARRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch req.FilingType {
+	case services.FilingTypeSAR:
+		filing, export, err := h.amlService.CreateSAR(req.SARRequest, fmt.Sprint(c.MustGet("userID")))
+		if err != nil {
+			respondAMLError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"filing": filing, "export": export})
+	case services.FilingTypeTransactionReport, services.FilingTypeExposureReport:
+		if req.PeriodStart == nil || req.PeriodEnd == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "period_start and period_end are required"})
+			return
+		}
+		filing, err := h.filingService.GeneratePeriodicReport(req.FilingType, *req.PeriodStart, *req.PeriodEnd, fmt.Sprint(c.MustGet("userID")))
+		if err != nil {
+			respondFilingError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"filing": filing})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported filing type"})
+	}
 }
 
 func (h *ComplianceHandler) GetRegulatoryFilings(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get regulatory filings - implementation needed"})
+	filings, err := h.amlService.ListFilings(c.Query("filing_type"), c.Query("status"))
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filings": filings, "count": len(filings)})
+}
+
+func (h *ComplianceHandler) GetRegulatoryFiling(c *gin.Context) {
+	filingID, err := uuid.Parse(c.Param("filingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filing ID"})
+		return
+	}
+
+	filing, err := h.amlService.GetFiling(filingID)
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, filing)
+}
+
+func respondFilingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRegulatoryFilingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regulatory filing not found"})
+	case errors.Is(err, services.ErrFilingExists), errors.Is(err, services.ErrFilingNotSubmittable), errors.Is(err, services.ErrFilingNotResubmitable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidFilingRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReportingDisabled), errors.Is(err, services.ErrFilingSigningDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Regulatory filing operation failed"})
+	}
+}
+
+// SubmitRegulatoryFiling signs a draft filing and sends it to the Epic 4 reporting endpoint
+func (h *ComplianceHandler) SubmitRegulatoryFiling(c *gin.Context) {
+	filingID, err := uuid.Parse(c.Param("filingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filing ID"})
+		return
+	}
+
+	filing, err := h.filingService.Submit(filingID, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondFilingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, filing)
+}
+
+// ResubmitRegulatoryFiling sends a rejected or failed filing again
+func (h *ComplianceHandler) ResubmitRegulatoryFiling(c *gin.Context) {
+	filingID, err := uuid.Parse(c.Param("filingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filing ID"})
+		return
+	}
+
+	filing, err := h.filingService.Resubmit(filingID, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondFilingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, filing)
+}
+
+// RefreshRegulatoryFilingStatus polls the reporting endpoint for an acknowledgment
+func (h *ComplianceHandler) RefreshRegulatoryFilingStatus(c *gin.Context) {
+	filingID, err := uuid.Parse(c.Param("filingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filing ID"})
+		return
+	}
+
+	filing, err := h.filingService.RefreshStatus(filingID)
+	if err != nil {
+		respondFilingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, filing)
+}
+
+func (h *ComplianceHandler) GetRegulatoryFilingSubmissions(c *gin.Context) {
+	filingID, err := uuid.Parse(c.Param("filingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filing ID"})
+		return
+	}
+
+	submissions, err := h.filingService.ListSubmissions(filingID)
+	if err != nil {
+		respondFilingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submissions": submissions, "count": len(submissions)})
 }
 
 func (h *ComplianceHandler) GetSystemAuditLog(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Get system audit log - implementation needed"})
 }
+
+func respondLimitError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrLimitOverrideNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Limit override not found"})
+	case errors.Is(err, services.ErrInvalidLimitOverride):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction limit operation failed"})
+	}
+}
+
+// GetCustomerLimits returns a customer's effective limits and rolling usage
+func (h *ComplianceHandler) GetCustomerLimits(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customerId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	limits, err := h.limitService.GetCustomerLimits(customerID)
+	if err != nil {
+		respondLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// SetLimitOverride raises or lowers a customer's limits
+func (h *ComplianceHandler) SetLimitOverride(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customerId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	var req services.LimitOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override, err := h.limitService.SetOverride(customerID, req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+// DeleteLimitOverride restores a customer's default limits
+func (h *ComplianceHandler) DeleteLimitOverride(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customerId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	if err := h.limitService.RemoveOverride(customerID); err != nil {
+		respondLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Limit override removed"})
+}
+
+func respondAMLError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAMLAlertNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "AML alert not found"})
+	case errors.Is(err, services.ErrAMLScenarioNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "AML scenario not found"})
+	case errors.Is(err, services.ErrRegulatoryFilingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regulatory filing not found"})
+	case errors.Is(err, services.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	case errors.Is(err, services.ErrFinancingRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Financing request not found"})
+	case errors.Is(err, services.ErrAMLAlertClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAMLRequest), errors.Is(err, services.ErrInvalidFilingRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "AML operation failed"})
+	}
+}
+
+// GetAMLScenarios lists the transaction monitoring scenarios
+func (h *ComplianceHandler) GetAMLScenarios(c *gin.Context) {
+	scenarios, err := h.amlService.ListScenarios()
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scenarios": scenarios})
+}
+
+// UpdateAMLScenario retunes, enables or disables a scenario
+func (h *ComplianceHandler) UpdateAMLScenario(c *gin.Context) {
+	var update services.ScenarioUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scenario, err := h.amlService.UpdateScenario(c.Param("code"), update, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scenario)
+}
+
+// ScreenPayment runs the scenarios against a payment immediately
+func (h *ComplianceHandler) ScreenPayment(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("paymentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	alerts, err := h.amlService.EvaluatePayment(paymentID)
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts, "count": len(alerts)})
+}
+
+// ScreenFinancing runs the scenarios against a disbursed financing request immediately
+func (h *ComplianceHandler) ScreenFinancing(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid financing request ID"})
+		return
+	}
+
+	alerts, err := h.amlService.EvaluateFinancing(requestID)
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts, "count": len(alerts)})
+}
+
+func (h *ComplianceHandler) GetAMLAlerts(c *gin.Context) {
+	filter := services.AMLAlertFilter{
+		Status:       c.Query("status"),
+		ScenarioCode: c.Query("scenario"),
+		AssignedTo:   c.Query("assigned_to"),
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		id, err := uuid.Parse(customerID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+			return
+		}
+		filter.CustomerID = &id
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	alerts, err := h.amlService.ListAlerts(filter)
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts, "count": len(alerts)})
+}
+
+func (h *ComplianceHandler) GetAMLAlert(c *gin.Context) {
+	alertID, err := uuid.Parse(c.Param("alertId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	alert, err := h.amlService.GetAlert(alertID)
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+func (h *ComplianceHandler) AssignAMLAlert(c *gin.Context) {
+	alertID, err := uuid.Parse(c.Param("alertId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	var req struct {
+		Assignee string `json:"assignee" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert, err := h.amlService.AssignAlert(alertID, req.Assignee, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+func (h *ComplianceHandler) AddAMLAlertNote(c *gin.Context) {
+	alertID, err := uuid.Parse(c.Param("alertId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert, err := h.amlService.AddAlertNote(alertID, req.Note, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+func (h *ComplianceHandler) EscalateAMLAlert(c *gin.Context) {
+	alertID, err := uuid.Parse(c.Param("alertId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert, err := h.amlService.EscalateAlert(alertID, req.Reason, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+func (h *ComplianceHandler) CloseAMLAlert(c *gin.Context) {
+	alertID, err := uuid.Parse(c.Param("alertId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	var req struct {
+		Resolution string `json:"resolution" binding:"required"`
+		Reason     string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert, err := h.amlService.CloseAlert(alertID, req.Resolution, req.Reason, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAMLError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// APIKeyHandler manages partner API keys
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// keyOrganization returns the organization a caller may manage keys for. Platform admins
+// may manage any organization; bank admins only their own bank's.
+func keyOrganization(c *gin.Context, requested string) (string, bool) {
+	role, _ := c.Get("userRole")
+	if role == "admin" {
+		return requested, true
+	}
+	bankID, exists := c.Get("bankID")
+	if !exists || bankID == nil || fmt.Sprint(bankID) == "" {
+		return "", false
+	}
+	if requested != "" && requested != fmt.Sprint(bankID) {
+		return "", false
+	}
+	return fmt.Sprint(bankID), true
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var request services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organizationID, ok := keyOrganization(c, request.OrganizationID)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to organization"})
+		return
+	}
+	request.OrganizationID = organizationID
+
+	key, plaintext, err := h.apiKeyService.CreateAPIKey(request, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     plaintext,
+		"message": "Store this key now; it cannot be retrieved again",
+	})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	organizationID, ok := keyOrganization(c, c.Query("organization_id"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to organization"})
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(organizationID, c.Query("include_revoked") == "true")
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys, "count": len(keys), "available_scopes": services.APIKeyScopes})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	organizationID, ok := keyOrganization(c, "")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to organization"})
+		return
+	}
+
+	key, err := h.apiKeyService.RevokeAPIKey(keyID, organizationID, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAPIKeyInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// RetentionHandler manages retention rules, legal holds and purge runs
+type RetentionHandler struct {
+	retentionService *services.RetentionService
+}
+
+func NewRetentionHandler(retentionService *services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+	}
+}
+
+func respondRetentionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRetentionRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention rule not found"})
+	case errors.Is(err, services.ErrLegalHoldNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Legal hold not found"})
+	case errors.Is(err, services.ErrPurgeRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purge run not found"})
+	case errors.Is(err, services.ErrInvalidRetentionRule), errors.Is(err, services.ErrInvalidLegalHold):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPurgeRunInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPurgeReportUnsigned):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention operation failed"})
+	}
+}
+
+func (h *RetentionHandler) GetRules(c *gin.Context) {
+	rules, err := h.retentionService.ListRules()
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "count": len(rules)})
+}
+
+func (h *RetentionHandler) CreateRule(c *gin.Context) {
+	var req services.RetentionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.retentionService.CreateRule(req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *RetentionHandler) UpdateRule(c *gin.Context) {
+	var req services.RetentionRuleUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.retentionService.UpdateRule(c.Param("name"), req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *RetentionHandler) GetLegalHolds(c *gin.Context) {
+	holds, err := h.retentionService.ListHolds(c.Query("include_released") != "true")
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holds": holds, "count": len(holds)})
+}
+
+func (h *RetentionHandler) PlaceLegalHold(c *gin.Context) {
+	var req services.LegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := h.retentionService.PlaceHold(req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (h *RetentionHandler) ReleaseLegalHold(c *gin.Context) {
+	holdID, err := uuid.Parse(c.Param("holdId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid legal hold ID"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := h.retentionService.ReleaseHold(holdID, fmt.Sprint(c.MustGet("userID")), req.Note)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// RunPurge applies the retention rules now; dry runs only report what would be purged
+func (h *RetentionHandler) RunPurge(c *gin.Context) {
+	var req struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := h.retentionService.RunPurge(c.Request.Context(), req.DryRun, fmt.Sprint(c.MustGet("userID")))
+	if err != nil && run == nil {
+		respondRetentionError(c, err)
+		return
+	}
+	if err != nil {
+		// The run stopped part way; its signed report still records what was done
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention run failed", "run": run})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *RetentionHandler) GetPurgeRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	runs, err := h.retentionService.ListRuns(limit)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "count": len(runs)})
+}
+
+func (h *RetentionHandler) GetPurgeRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge run ID"})
+		return
+	}
+
+	run, err := h.retentionService.GetRun(runID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// VerifyPurgeRun checks a stored purge report against its signature
+func (h *RetentionHandler) VerifyPurgeRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge run ID"})
+		return
+	}
+
+	valid, reason, err := h.retentionService.VerifyRun(runID)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": valid, "reason": reason})
+}
+
+// DualControlHandler lists requests held for maker-checker approval and records
+// checkers' decisions
+type DualControlHandler struct {
+	dualControlService *services.DualControlService
+}
+
+func NewDualControlHandler(dualControlService *services.DualControlService) *DualControlHandler {
+	return &DualControlHandler{
+		dualControlService: dualControlService,
+	}
+}
+
+func respondDualControlError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+	case errors.Is(err, services.ErrSelfApproval), errors.Is(err, services.ErrNotChecker), errors.Is(err, services.ErrNotInitiator):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrApprovalNotPending), errors.Is(err, services.ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrApprovalExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Approval operation failed"})
+	}
+}
+
+// isChecker reports whether the user may confirm other users' requests
+func (h *DualControlHandler) isChecker(c *gin.Context) bool {
+	role := c.GetString("userRole")
+	for _, checkerRole := range h.dualControlService.CheckerRoles() {
+		if role == checkerRole {
+			return true
+		}
+	}
+	return false
+}
+
+// canView lets checkers see the requests of their bank, and everyone their own
+func (h *DualControlHandler) canView(c *gin.Context, approval *models.DualControlApproval) bool {
+	if approval.InitiatedBy == c.GetString("userID") {
+		return true
+	}
+	if !h.isChecker(c) {
+		return false
+	}
+	return c.GetString("userRole") == "admin" || approval.BankID == "" || approval.BankID == c.GetString("bankID")
+}
+
+func (h *DualControlHandler) checker(c *gin.Context) services.DualControlChecker {
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	return services.DualControlChecker{
+		UserID:    c.GetString("userID"),
+		Role:      c.GetString("userRole"),
+		BankID:    c.GetString("bankID"),
+		IPAddress: c.ClientIP(),
+		Comment:   body.Comment,
+	}
+}
+
+// GetApprovals lists held requests. Checkers see their bank's, others only their own.
+func (h *DualControlHandler) GetApprovals(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	filter := services.DualControlFilter{
+		Status: c.Query("status"),
+		Action: c.Query("action"),
+		Page:   page,
+		Limit:  limit,
+	}
+	switch {
+	case !h.isChecker(c):
+		filter.InitiatedBy = c.GetString("userID")
+	case c.GetString("userRole") != "admin":
+		filter.BankID = c.GetString("bankID")
+	}
+
+	approvals, total, err := h.dualControlService.ListApprovals(filter)
+	if err != nil {
+		respondDualControlError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": approvals,
+		"total":     total,
+		"page":      filter.Page,
+		"limit":     filter.Limit,
+	})
+}
+
+func (h *DualControlHandler) GetApproval(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+
+	approval, err := h.dualControlService.GetApproval(approvalID)
+	if err != nil {
+		respondDualControlError(c, err)
+		return
+	}
+	if !h.canView(c, approval) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// ApproveRequest confirms another user's request. Once enough checkers confirmed it,
+// the initiator can carry it out.
+func (h *DualControlHandler) ApproveRequest(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+
+	approval, err := h.dualControlService.Approve(approvalID, h.checker(c))
+	if err != nil {
+		respondDualControlError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+func (h *DualControlHandler) RejectRequest(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+
+	approval, err := h.dualControlService.Reject(approvalID, h.checker(c))
+	if err != nil {
+		respondDualControlError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// CancelRequest withdraws the caller's own held request
+func (h *DualControlHandler) CancelRequest(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("approvalId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+
+	approval, err := h.dualControlService.Cancel(approvalID, c.GetString("userID"))
+	if err != nil {
+		respondDualControlError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
@@ -1,9 +1,13 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +16,8 @@ import (
 	"github.com/google/uuid"
 
 	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+	"bank-integration-service/internal/services"
 )
 
 // SecurityHeaders adds security headers to responses
@@ -133,6 +139,7 @@ func JWTAuth(jwtSecret string) gin.HandlerFunc {
 			c.Set("userID", claims["user_id"])
 			c.Set("userRole", claims["role"])
 			c.Set("bankID", claims["bank_id"])
+			c.Set("mfaAt", claims["mfa_at"])
 		}
 
 		c.Next()
@@ -176,6 +183,25 @@ func RequireRole(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequireStepUp requires the token to carry an MFA assertion, the mfa_at claim set by the
+// user management service at login or step-up, made within maxAge
+func RequireStepUp(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mfaAt, ok := c.Value("mfaAt").(float64)
+		age := time.Since(time.Unix(int64(mfaAt), 0))
+		if !ok || age > maxAge || age < -time.Minute {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":            "Step-up authentication required",
+				"step_up_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // BankAccess ensures user can only access their bank's data
 func BankAccess() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -208,8 +234,10 @@ func BankAccess() gin.HandlerFunc {
 	}
 }
 
-// APIKeyAuth validates API keys for external integrations
-func APIKeyAuth() gin.HandlerFunc {
+// APIKeyAuth validates API keys for external integrations against the key store.
+// Authenticated requests get the key's organization as "organizationID" and the key
+// itself as "apiKey"; "userID" is set to the key ID for audit logging.
+func APIKeyAuth(apiKeys *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey == "" {
@@ -220,28 +248,86 @@ func APIKeyAuth() gin.HandlerFunc {
 			return
 		}
 
-		// In production, validate against database
-		// For now, simple validation
-		if !isValidAPIKey(apiKey) {
+		key, err := apiKeys.Authenticate(apiKey, c.ClientIP())
+		switch {
+		case err == nil:
+		case errors.Is(err, services.ErrAPIKeyRateLimited):
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Rate limit exceeded",
+				"message": fmt.Sprintf("Maximum %d requests per minute allowed for this API key", key.RateLimitPerMinute),
+			})
+			c.Abort()
+			return
+		case errors.Is(err, services.ErrAPIKeyExpired), errors.Is(err, services.ErrAPIKeyRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			c.Abort()
+			return
+		case errors.Is(err, services.ErrAPIKeyInvalid):
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid API key",
 			})
 			c.Abort()
 			return
+		default:
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "API key validation unavailable",
+			})
+			c.Abort()
+			return
 		}
 
 		c.Set("apiKeyAuth", true)
+		c.Set("apiKey", key)
+		c.Set("organizationID", key.OrganizationID)
+		c.Set("userID", "api_key:"+key.ID.String())
+		c.Next()
+	}
+}
+
+// RequireScope checks that the authenticated API key was granted every listed scope
+func RequireScope(requiredScopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("apiKey")
+		key, ok := value.(*models.APIKey)
+		if !exists || !ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API key information not found",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, scope := range requiredScopes {
+			if !services.HasScope(key, scope) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":           "Insufficient scope",
+					"required_scopes": requiredScopes,
+					"granted_scopes":  key.Scopes,
+				})
+				c.Abort()
+				return
+			}
+		}
 		c.Next()
 	}
 }
 
-// Epic4Compliance adds Epic 4 compliance headers and validation
-func Epic4Compliance() gin.HandlerFunc {
+// Epic4Compliance adds Epic 4 compliance headers and validation. The advertised data
+// retention is the configured period enforced by the retention service.
+func Epic4Compliance(retention time.Duration) gin.HandlerFunc {
+	retentionHeader := fmt.Sprintf("%d-days", int(retention.Hours()/24))
+	if days := int(retention.Hours() / 24); days%365 == 0 {
+		retentionHeader = fmt.Sprintf("%d-years", days/365)
+	}
+
 	return func(c *gin.Context) {
 		// Add Epic 4 compliance headers
 		c.Header("X-Epic4-Compliant", "true")
 		c.Header("X-Audit-Trail", "enabled")
-		c.Header("X-Data-Retention", "7-years")
+		c.Header("X-Data-Retention", retentionHeader)
 
 		// Set compliance context
 		c.Set("epic4Compliant", true)
@@ -252,41 +338,184 @@ func Epic4Compliance() gin.HandlerFunc {
 	}
 }
 
-// TransactionLimits enforces transaction limits for compliance
-func TransactionLimits(cfg *config.Config) gin.HandlerFunc {
+// TransactionLimits enforces rolling per-customer and per-connection velocity limits on
+// transaction endpoints. The customer is the owner of the bank connection the payment is
+// made from, or else the authenticated user; a customer_id in the body is not trusted.
+// The request body is restored so handlers can still bind it.
+func TransactionLimits(limits *services.TransactionLimitService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if this is a transaction endpoint
-		if !isTransactionEndpoint(c.Request.URL.Path) {
+		if !isTransactionEndpoint(c.FullPath()) || c.Request.Body == nil {
 			c.Next()
 			return
 		}
 
-		// Extract amount from request if present
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Bulk requests are checked for the sum of their items
+		amount, currency := services.RequestAmount(body)
+		if amount <= 0 {
+			c.Next()
+			return
+		}
 		var requestData struct {
-			Amount   float64 `json:"amount"`
-			Currency string  `json:"currency"`
+			BankConnectionID *uuid.UUID `json:"bank_connection_id"`
 		}
+		json.Unmarshal(body, &requestData)
 
-		if err := c.ShouldBindJSON(&requestData); err == nil {
-			// Validate transaction limits
-			if requestData.Amount > cfg.MaxDailyTransactionAmount {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Transaction amount exceeds daily limit",
-					"limit": cfg.MaxDailyTransactionAmount,
-					"amount": requestData.Amount,
-				})
+		var customerID *uuid.UUID
+		if requestData.BankConnectionID != nil {
+			if customerID, err = limits.ResolveCustomer(*requestData.BankConnectionID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check transaction limits"})
 				c.Abort()
 				return
 			}
-
-			// Check for suspicious activity threshold
-			if requestData.Amount > cfg.SuspiciousActivityThreshold {
-				c.Set("suspiciousActivity", true)
-				c.Set("requiresManualReview", true)
+		}
+		if customerID == nil {
+			// API keys authenticate as "api_key:<id>" and have no customer of their own
+			if userID, err := uuid.Parse(c.GetString("userID")); err == nil {
+				customerID = &userID
 			}
 		}
 
+		result, err := limits.Check(services.TransactionCheck{
+			CustomerID:       customerID,
+			BankConnectionID: requestData.BankConnectionID,
+			Amount:           amount,
+			Currency:         currency,
+			Path:             c.Request.URL.Path,
+			RequestID:        c.GetString("RequestID"),
+			RequestedBy:      c.GetString("userID"),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check transaction limits"})
+			c.Abort()
+			return
+		}
+
+		// Suspicious payments go ahead; the opened compliance record is their manual review
+		if result.ComplianceRecordID != nil {
+			c.Header("X-Compliance-Record-ID", result.ComplianceRecordID.String())
+		}
+
+		if !result.Allowed {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Transaction exceeds velocity limits",
+				"violations": result.Violations,
+				"amount":     amount,
+			})
+			c.Abort()
+			return
+		}
+
 		c.Next()
+
+		if result.ReservationID != nil {
+			if err := limits.Release(*result.ReservationID); err != nil {
+				fmt.Printf("Failed to release transaction limit reservation %s: %v\n", result.ReservationID, err)
+			}
+		}
+	}
+}
+
+// ApprovalHeader carries the ID of a confirmed maker-checker approval
+const ApprovalHeader = "X-Approval-ID"
+
+// DualControl holds a request for confirmation by a second user once its amount reaches
+// the action's threshold, answering 202 with the pending approval. After it was
+// confirmed, the initiator repeats the identical request with the ApprovalHeader set
+// to carry it out. The request body is restored so handlers can still bind it.
+func DualControl(dualControl *services.DualControlService, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		userID := c.GetString("userID")
+
+		if header := c.GetHeader(ApprovalHeader); header != "" {
+			approvalID, err := uuid.Parse(header)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+				c.Abort()
+				return
+			}
+			if _, err := dualControl.Consume(approvalID, userID, action, c.Request.Method, c.Request.URL.Path, body); err != nil {
+				respondApprovalError(c, err)
+				c.Abort()
+				return
+			}
+			c.Set("approvalID", approvalID.String())
+
+			c.Next()
+
+			// The response is already written, so a failure here can only be logged; the
+			// approval is left executed without its response status
+			if err := dualControl.RecordOutcome(approvalID, c.Writer.Status()); err != nil {
+				log.Printf("Failed to record outcome %d of %s approval %s for %s %s: %v",
+					c.Writer.Status(), action, approvalID, c.Request.Method, c.Request.URL.Path, err)
+			}
+			return
+		}
+
+		amount, currency := services.RequestAmount(body)
+		if dualControl.RequiredApprovals(action, amount, currency) == 0 {
+			c.Next()
+			return
+		}
+
+		approval, err := dualControl.Initiate(services.DualControlRequest{
+			Action:      action,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			Body:        body,
+			Amount:      amount,
+			Currency:    currency,
+			InitiatedBy: userID,
+			Role:        c.GetString("userRole"),
+			BankID:      c.GetString("bankID"),
+		})
+		if err != nil {
+			respondApprovalError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "The request is waiting for confirmation by a second user",
+			"approval": approval,
+		})
+		c.Abort()
+	}
+}
+
+// respondApprovalError answers a request that could not be held or carried out
+func respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+	case errors.Is(err, services.ErrNotInitiator):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrApprovalNotPending), errors.Is(err, services.ErrApprovalNotReady),
+		errors.Is(err, services.ErrApprovalMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrApprovalExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Approval operation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Approval operation failed"})
 	}
 }
 
@@ -309,70 +538,80 @@ func ErrorHandler() gin.HandlerFunc {
 
 // Helper functions
 
-func isValidAPIKey(apiKey string) bool {
-	// In production, validate against database or external service
-	validKeys := []string{
-		"test-api-key-1",
-		"test-api-key-2",
-		"bank-integration-key",
-	}
-
-	for _, validKey := range validKeys {
-		if apiKey == validKey {
-			return true
-		}
-	}
-	return false
-}
-
-func isTransactionEndpoint(path string) bool {
-	transactionPaths := []string{
+// isTransactionEndpoint matches the route pattern of a request, so path parameters do
+// not need to be parsed out
+func isTransactionEndpoint(route string) bool {
+	transactionRoutes := []string{
 		"/payments/process",
 		"/payments/bulk-process",
 		"/transfers/initiate",
 		"/transfers/bulk-transfer",
-		"/financing/disburse",
+		"/financing/requests/:requestId/disburse",
 	}
 
-	for _, transactionPath := range transactionPaths {
-		if strings.Contains(path, transactionPath) {
+	for _, transactionRoute := range transactionRoutes {
+		if strings.HasSuffix(route, transactionRoute) {
 			return true
 		}
 	}
 	return false
 }
 
-// Audit middleware for Epic 4 compliance
-func AuditLogging() gin.HandlerFunc {
+// AuditLogging appends every state-changing request to the hash-chained audit trail
+// for Epic 4 compliance. Reads are not recorded.
+func AuditLogging(audit *services.AuditService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		start := time.Now()
-
-		// Capture request details
-		auditData := map[string]interface{}{
-			"method":     c.Request.Method,
-			"path":       c.Request.URL.Path,
-			"ip":         c.ClientIP(),
-			"user_agent": c.Request.UserAgent(),
-			"timestamp":  start,
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
 		}
 
-		if userID, exists := c.Get("userID"); exists {
-			auditData["user_id"] = userID
+		start := time.Now()
+		c.Next()
+
+		details := map[string]interface{}{
+			"method":        c.Request.Method,
+			"path":          c.Request.URL.Path,
+			"status_code":   c.Writer.Status(),
+			"response_time": time.Since(start).Milliseconds(),
 		}
 
+		// The request ID doubles as the entity ID so the entry can be correlated with logs
+		entityID := uuid.New()
 		if requestID, exists := c.Get("RequestID"); exists {
-			auditData["request_id"] = requestID
+			details["request_id"] = requestID
+			if parsed, err := uuid.Parse(fmt.Sprint(requestID)); err == nil {
+				entityID = parsed
+			}
 		}
 
-		c.Next()
+		var userID uuid.UUID
+		if value, exists := c.Get("userID"); exists {
+			userID, _ = uuid.Parse(fmt.Sprint(value))
+		}
+		if value, exists := c.Get("apiKey"); exists {
+			if key, ok := value.(*models.APIKey); ok {
+				details["api_key_id"] = key.ID
+			}
+		}
 
-		// Log after request completion
-		auditData["status_code"] = c.Writer.Status()
-		auditData["response_time"] = time.Since(start).Milliseconds()
-		auditData["response_size"] = c.Writer.Size()
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
 
-		// In production, send to audit service or log to database
-		fmt.Printf("Audit Log: %+v\n", auditData)
+		_, err := audit.Record(services.AuditEntry{
+			EntityType: "api_request",
+			EntityID:   entityID,
+			Action:     c.Request.Method + " " + route,
+			UserID:     userID,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Details:    details,
+		})
+		if err != nil {
+			fmt.Printf("Failed to record audit trail: %v (%+v)\n", err, details)
+		}
 	}
 }
 
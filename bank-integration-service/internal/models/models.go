@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -33,11 +37,15 @@ type CreditDecision struct {
 	ApprovedAmount   float64   `gorm:"type:decimal(15,2)" json:"approved_amount"`
 	InterestRate     float64   `gorm:"type:decimal(5,2)" json:"interest_rate"`
 	Term             int       `json:"term"` // Term in days
-	Status           string    `gorm:"type:varchar(50);default:'pending'" json:"status"` // pending, approved, rejected, expired
+	Status           string    `gorm:"type:varchar(50);default:'pending'" json:"status"` // pending, approved, referred, rejected, expired
 	Decision         string    `gorm:"type:text" json:"decision"`
 	Conditions       string    `gorm:"type:text" json:"conditions"`
 	RiskScore        float64   `gorm:"type:decimal(5,2)" json:"risk_score"`
 	RiskFactors      string    `gorm:"type:json" json:"risk_factors"`
+	ReasonTrace      string    `gorm:"type:json" json:"reason_trace"`
+	RuleSetVersion   string    `gorm:"type:varchar(50)" json:"rule_set_version"`
+	RequestedBy      string    `gorm:"type:varchar(100)" json:"requested_by"`
+	DecidedBy        string    `gorm:"type:varchar(100)" json:"decided_by"` // rules_engine or reviewer user ID
 	DecisionDate     *time.Time `json:"decision_date"`
 	ExpiryDate       *time.Time `json:"expiry_date"`
 	CreatedAt        time.Time  `json:"created_at"`
@@ -50,11 +58,13 @@ type CreditDecision struct {
 type PaymentTransaction struct {
 	ID                 uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	BankConnectionID   uuid.UUID `gorm:"type:uuid;not null" json:"bank_connection_id"`
+	CustomerID         *uuid.UUID `gorm:"type:uuid" json:"customer_id,omitempty"`
 	PaymentID          string    `gorm:"type:varchar(255);unique" json:"payment_id"`
 	ExternalPaymentID  string    `gorm:"type:varchar(255)" json:"external_payment_id"`
 	Type               string    `gorm:"type:varchar(50);not null" json:"type"` // transfer, payment, settlement
 	FromAccountID      string    `gorm:"type:varchar(255)" json:"from_account_id"`
 	ToAccountID        string    `gorm:"type:varchar(255)" json:"to_account_id"`
+	CounterpartyCountry string   `gorm:"type:varchar(2)" json:"counterparty_country,omitempty"` // ISO 3166-1 alpha-2
 	Amount             float64   `gorm:"type:decimal(15,2);not null" json:"amount"`
 	Currency           string    `gorm:"type:varchar(3);default:'USD'" json:"currency"`
 	Status             string    `gorm:"type:varchar(50);default:'pending'" json:"status"` // pending, processing, completed, failed, cancelled
@@ -65,6 +75,9 @@ type PaymentTransaction struct {
 	FailureReason      string     `gorm:"type:text" json:"failure_reason,omitempty"`
 	RetryCount         int        `gorm:"default:0" json:"retry_count"`
 	Epic4ComplianceData string    `gorm:"type:json" json:"epic4_compliance_data"`
+	ReconciledAt       *time.Time `json:"reconciled_at,omitempty"`
+	AMLScreenedAt      *time.Time `json:"aml_screened_at,omitempty"`
+	AnonymizedAt       *time.Time `json:"anonymized_at,omitempty"` // Set when retention scrubbed account details
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 
@@ -81,17 +94,21 @@ type FinancingRequest struct {
 	Term                 int       `json:"term"` // Term in days
 	Purpose              string    `gorm:"type:text" json:"purpose"`
 	Status               string    `gorm:"type:varchar(50);default:'draft'" json:"status"` // draft, submitted, under_review, approved, rejected, disbursed
-	SubmittedToBanks     []string  `gorm:"type:json" json:"submitted_to_banks"`
-	ApprovedByBanks      []string  `gorm:"type:json" json:"approved_by_banks"`
+	SubmittedToBanks     []string  `gorm:"type:json;serializer:json" json:"submitted_to_banks"`
+	ApprovedByBanks      []string  `gorm:"type:json;serializer:json" json:"approved_by_banks"`
 	BestOffer            string    `gorm:"type:json" json:"best_offer"`
-	Documents            []string  `gorm:"type:json" json:"documents"`
+	Documents            []string  `gorm:"type:json;serializer:json" json:"documents"`
 	CollateralDetails    string    `gorm:"type:json" json:"collateral_details"`
 	BusinessDetails      string    `gorm:"type:json" json:"business_details"`
 	FinancialInformation string    `gorm:"type:json" json:"financial_information"`
 	RequestedAt          time.Time `json:"requested_at"`
+	OfferDeadline        *time.Time `json:"offer_deadline"`
+	AuctionClosedAt      *time.Time `json:"auction_closed_at"`
+	AcceptedOfferID      *uuid.UUID `gorm:"type:uuid" json:"accepted_offer_id"`
 	ReviewedAt           *time.Time `json:"reviewed_at"`
 	ApprovedAt           *time.Time `json:"approved_at"`
 	DisbursedAt          *time.Time `json:"disbursed_at"`
+	AMLScreenedAt        *time.Time `json:"aml_screened_at,omitempty"`
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
 }
@@ -105,6 +122,8 @@ type PortfolioItem struct {
 	Type                string    `gorm:"type:varchar(50);not null" json:"type"` // invoice, purchase_order, credit_line
 	Principal           float64   `gorm:"type:decimal(15,2);not null" json:"principal"`
 	Outstanding         float64   `gorm:"type:decimal(15,2);not null" json:"outstanding"`
+	Currency            string    `gorm:"type:varchar(3);default:'USD'" json:"currency"`
+	Industry            string    `gorm:"type:varchar(100)" json:"industry"`
 	InterestRate        float64   `gorm:"type:decimal(5,2)" json:"interest_rate"`
 	MaturityDate        time.Time `json:"maturity_date"`
 	Status              string    `gorm:"type:varchar(50);default:'active'" json:"status"` // active, matured, defaulted, written_off
@@ -174,8 +193,17 @@ type AuditTrail struct {
 	AfterState  string    `gorm:"type:json" json:"after_state"`
 	Timestamp   time.Time `json:"timestamp"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Hash chain: each entry commits to the previous one, so an edited, removed or
+	// reordered row breaks every link after it
+	Sequence int64  `gorm:"not null;default:0" json:"sequence"`
+	PrevHash string `gorm:"type:varchar(64);not null;default:''" json:"prev_hash"`
+	Hash     string `gorm:"type:varchar(64);not null;default:''" json:"hash"`
 }
 
+// AuditGenesisHash is the previous hash of the first entry in the audit chain
+var AuditGenesisHash = strings.Repeat("0", 64)
+
 // BankAccount represents bank account information
 type BankAccount struct {
 	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -257,6 +285,380 @@ type ReconciliationJob struct {
 	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
+// BankStatementLine represents a single line imported from a bank statement
+type BankStatementLine struct {
+	ID                   uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BankConnectionID     uuid.UUID  `gorm:"type:uuid;not null" json:"bank_connection_id"`
+	ExternalLineID       string     `gorm:"type:varchar(255)" json:"external_line_id"`
+	BookingDate          time.Time  `json:"booking_date"`
+	ValueDate            *time.Time `json:"value_date"`
+	Amount               float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Currency             string     `gorm:"type:varchar(3);default:'USD'" json:"currency"`
+	Reference            string     `gorm:"type:varchar(255)" json:"reference"`
+	CounterpartyName     string     `gorm:"type:varchar(255)" json:"counterparty_name"`
+	CounterpartyAccount  string     `gorm:"type:varchar(255)" json:"counterparty_account"`
+	Description          string     `gorm:"type:text" json:"description"`
+	Status               string     `gorm:"type:varchar(50);default:'unmatched'" json:"status"` // unmatched, matched, manual_review, manually_matched, ignored
+	MatchedTransactionID *uuid.UUID `gorm:"type:uuid" json:"matched_transaction_id,omitempty"`
+	MatchConfidence      float64    `gorm:"type:decimal(5,4)" json:"match_confidence"`
+	MatchCandidates      string     `gorm:"type:json" json:"match_candidates"`
+	ReconciliationJobID  *uuid.UUID `gorm:"type:uuid" json:"reconciliation_job_id,omitempty"`
+	MatchedBy            string     `gorm:"type:varchar(100)" json:"matched_by,omitempty"` // system or user ID
+	MatchedAt            *time.Time `json:"matched_at"`
+	AnonymizedAt         *time.Time `json:"anonymized_at,omitempty"` // Set when retention scrubbed counterparty details
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	BankConnection BankConnection `gorm:"foreignKey:BankConnectionID" json:"bank_connection,omitempty"`
+}
+
+// FinancingOffer represents a bank's offer in a financing request auction
+type FinancingOffer struct {
+	ID                 uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	FinancingRequestID uuid.UUID `gorm:"type:uuid;not null" json:"financing_request_id"`
+	BankConnectionID   uuid.UUID `gorm:"type:uuid;not null" json:"bank_connection_id"`
+	BankCode           string    `gorm:"type:varchar(50);not null" json:"bank_code"`
+	ExternalOfferID    string    `gorm:"type:varchar(255)" json:"external_offer_id"`
+	Status             string    `gorm:"type:varchar(50);default:'requested'" json:"status"` // requested, submitted, accepted, declined, expired
+	OfferedAmount      float64   `gorm:"type:decimal(15,2)" json:"offered_amount"`
+	InterestRate       float64   `gorm:"type:decimal(5,2)" json:"interest_rate"` // Nominal annual rate in percent
+	Term               int       `json:"term"`                                   // Term in days
+	OriginationFee     float64   `gorm:"type:decimal(15,2);default:0" json:"origination_fee"`
+	OtherFees          float64   `gorm:"type:decimal(15,2);default:0" json:"other_fees"`
+	FeeDetails         string    `gorm:"type:json" json:"fee_details"`
+	EffectiveAPR       float64   `gorm:"type:decimal(7,4)" json:"effective_apr"` // Annualized cost including fees, in percent
+	Rank               int       `json:"rank"`
+	Conditions         string    `gorm:"type:json" json:"conditions"`
+	DeclineReason      string    `gorm:"type:text" json:"decline_reason,omitempty"`
+	ValidUntil         *time.Time `json:"valid_until"`
+	RequestedAt        time.Time  `json:"requested_at"`
+	SubmittedAt        *time.Time `json:"submitted_at"`
+	RespondedAt        *time.Time `json:"responded_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	FinancingRequest FinancingRequest `gorm:"foreignKey:FinancingRequestID" json:"-"`
+	BankConnection   BankConnection   `gorm:"foreignKey:BankConnectionID" json:"bank_connection,omitempty"`
+}
+
+// PortfolioSnapshot represents a daily snapshot of portfolio metrics
+type PortfolioSnapshot struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SnapshotDate      time.Time `gorm:"not null" json:"snapshot_date"`
+	// Segment the snapshot covers; empty values mean all banks, currencies or industries
+	BankConnectionID  *uuid.UUID `gorm:"type:uuid" json:"bank_connection_id,omitempty"`
+	Currency          string     `gorm:"type:varchar(3);not null;default:''" json:"currency,omitempty"`
+	Industry          string     `gorm:"type:varchar(100);not null;default:''" json:"industry,omitempty"`
+	ItemCount         int64     `json:"item_count"`
+	ActiveCount       int64     `json:"active_count"`
+	TotalPrincipal    float64   `gorm:"type:decimal(18,2)" json:"total_principal"`
+	TotalOutstanding  float64   `gorm:"type:decimal(18,2)" json:"total_outstanding"`
+	WeightedRate      float64   `gorm:"type:decimal(7,4)" json:"weighted_rate"`
+	LossAdjustedYield float64   `gorm:"type:decimal(7,4)" json:"loss_adjusted_yield"`
+	ExpectedLoss      float64   `gorm:"type:decimal(18,2)" json:"expected_loss"`
+	DPD30Plus         float64   `gorm:"column:dpd30_plus;type:decimal(18,2)" json:"dpd30_plus"`
+	DPD90Plus         float64   `gorm:"column:dpd90_plus;type:decimal(18,2)" json:"dpd90_plus"`
+	DefaultedAmount   float64   `gorm:"type:decimal(18,2)" json:"defaulted_amount"`
+	CustomerHHI       float64   `gorm:"column:customer_hhi;type:decimal(9,2)" json:"customer_hhi"`
+	Metrics           string    `gorm:"type:json" json:"metrics"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// PortfolioReport represents an asynchronously generated portfolio report artifact
+type PortfolioReport struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ReportType       string     `gorm:"type:varchar(50);not null" json:"report_type"` // monthly_pack, holdings, risk, exposures, performance
+	Format           string     `gorm:"type:varchar(10);not null" json:"format"`      // csv, xlsx, pdf
+	Status           string     `gorm:"type:varchar(20);default:'queued'" json:"status"` // queued, running, completed, failed, expired
+	PeriodStart      time.Time  `gorm:"not null" json:"period_start"`
+	PeriodEnd        time.Time  `gorm:"not null" json:"period_end"`
+	BankConnectionID *uuid.UUID `gorm:"type:uuid" json:"bank_connection_id,omitempty"`
+	Filters          string     `gorm:"type:json" json:"filters"`
+	RequestedBy      string     `gorm:"type:varchar(255)" json:"requested_by"`
+	Attempts         int        `gorm:"default:0" json:"attempts"`
+	FileName         string     `gorm:"type:varchar(255)" json:"file_name,omitempty"`
+	FilePath         string     `gorm:"type:varchar(500)" json:"-"`
+	ContentType      string     `gorm:"type:varchar(100)" json:"content_type,omitempty"`
+	FileSize         int64      `json:"file_size,omitempty"`
+	Checksum         string     `gorm:"type:varchar(64)" json:"checksum,omitempty"` // SHA-256 of the artifact
+	RowCount         int        `json:"row_count,omitempty"`
+	ErrorMessage     string     `gorm:"type:text" json:"error_message,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// APIKey is a partner API key. Only a SHA-256 hash of the key is stored; the prefix
+// identifies the key in listings and logs.
+type APIKey struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID     string     `gorm:"type:varchar(100);not null" json:"organization_id"`
+	Name               string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix             string     `gorm:"type:varchar(20);not null" json:"prefix"`
+	KeyHash            string     `gorm:"type:varchar(64);not null" json:"-"`
+	Scopes             []string   `gorm:"type:json;serializer:json" json:"scopes"`
+	RateLimitPerMinute int        `gorm:"default:60" json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt         *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP         string     `gorm:"type:varchar(45)" json:"last_used_ip,omitempty"`
+	CreatedBy          string     `gorm:"type:varchar(255)" json:"created_by"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy          string     `gorm:"type:varchar(255)" json:"revoked_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// TransactionLimitOverride replaces the default velocity limits for one customer.
+// A nil limit keeps the default for that window.
+type TransactionLimitOverride struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CustomerID   uuid.UUID  `gorm:"type:uuid;not null" json:"customer_id"`
+	DailyLimit   *float64   `gorm:"type:decimal(15,2)" json:"daily_limit,omitempty"`
+	MonthlyLimit *float64   `gorm:"type:decimal(15,2)" json:"monthly_limit,omitempty"`
+	Reason       string     `gorm:"type:text;not null" json:"reason"`
+	ApprovedBy   string     `gorm:"type:varchar(255)" json:"approved_by"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TransactionLimitReservation holds velocity headroom for a payment between the limit
+// check and the creation of its payment transaction, so concurrent payments cannot
+// both pass the check. It is released once the request finishes and lapses at ExpiresAt
+// if it never is.
+type TransactionLimitReservation struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CustomerID       *uuid.UUID `gorm:"type:uuid;index" json:"customer_id,omitempty"`
+	BankConnectionID *uuid.UUID `gorm:"type:uuid;index" json:"bank_connection_id,omitempty"`
+	Amount           float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
+	RequestID        string     `gorm:"type:varchar(100)" json:"request_id"`
+	ExpiresAt        time.Time  `gorm:"not null;index" json:"expires_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// AMLScenarioParameters tunes an AML monitoring scenario. Each scenario reads only the
+// fields it needs.
+type AMLScenarioParameters struct {
+	Threshold        float64  `json:"threshold,omitempty"`          // amount the scenario keys off
+	BandPercent      float64  `json:"band_percent,omitempty"`       // structuring: how far below the threshold counts
+	MinCount         int      `json:"min_count,omitempty"`          // occurrences needed within the window
+	WindowHours      int      `json:"window_hours,omitempty"`       // lookback window
+	RoundingUnit     float64  `json:"rounding_unit,omitempty"`      // round amounts: multiple that counts as round
+	PassThroughRatio float64  `json:"pass_through_ratio,omitempty"` // rapid movement: share of inflows moved out
+	Countries        []string `json:"countries,omitempty"`          // high-risk jurisdictions
+}
+
+// AMLScenario is a configurable transaction monitoring rule
+type AMLScenario struct {
+	ID          uuid.UUID             `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Code        string                `gorm:"type:varchar(50);not null" json:"code"` // structuring, rapid_movement, round_amounts, high_risk_jurisdiction, new_counterparty_large
+	Name        string                `gorm:"type:varchar(255);not null" json:"name"`
+	Description string                `gorm:"type:text" json:"description"`
+	Enabled     bool                  `gorm:"default:true" json:"enabled"`
+	Severity    string                `gorm:"type:varchar(20);default:'medium'" json:"severity"` // low, medium, high
+	Parameters  AMLScenarioParameters `gorm:"type:json;serializer:json" json:"parameters"`
+	UpdatedBy   string                `gorm:"type:varchar(255)" json:"updated_by,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// AMLAlert is a scenario hit on a payment or financing event, worked as a case
+type AMLAlert struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ScenarioCode       string     `gorm:"type:varchar(50);not null" json:"scenario_code"`
+	Severity           string     `gorm:"type:varchar(20);not null" json:"severity"`
+	Status             string     `gorm:"type:varchar(20);default:'open'" json:"status"` // open, investigating, escalated, closed
+	EntityType         string     `gorm:"type:varchar(50);not null" json:"entity_type"`  // payment, financing
+	EntityID           uuid.UUID  `gorm:"type:uuid;not null" json:"entity_id"`
+	CustomerID         *uuid.UUID `gorm:"type:uuid" json:"customer_id,omitempty"`
+	Amount             float64    `gorm:"type:decimal(15,2)" json:"amount"`
+	Currency           string     `gorm:"type:varchar(3)" json:"currency"`
+	Details            string     `gorm:"type:json" json:"details"`
+	AssignedTo         string     `gorm:"type:varchar(255)" json:"assigned_to,omitempty"`
+	EscalatedAt        *time.Time `json:"escalated_at,omitempty"`
+	EscalatedBy        string     `gorm:"type:varchar(255)" json:"escalated_by,omitempty"`
+	Resolution         string     `gorm:"type:varchar(50)" json:"resolution,omitempty"` // false_positive, no_action, sar_filed
+	ClosedAt           *time.Time `json:"closed_at,omitempty"`
+	ClosedBy           string     `gorm:"type:varchar(255)" json:"closed_by,omitempty"`
+	ComplianceRecordID *uuid.UUID `gorm:"type:uuid" json:"compliance_record_id,omitempty"`
+	FilingID           *uuid.UUID `gorm:"type:uuid" json:"filing_id,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	Notes []AMLAlertNote `gorm:"foreignKey:AlertID" json:"notes,omitempty"`
+}
+
+// AMLAlertNote is one entry in an alert's case history
+type AMLAlertNote struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AlertID   uuid.UUID `gorm:"type:uuid;not null" json:"alert_id"`
+	Action    string    `gorm:"type:varchar(50);not null" json:"action"` // note, assigned, escalated, closed
+	Author    string    `gorm:"type:varchar(255);not null" json:"author"`
+	Body      string    `gorm:"type:text" json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RegulatoryFiling is a report prepared for a regulator, such as a suspicious activity
+// report assembled from AML alerts or a periodic transaction or exposure report
+type RegulatoryFiling struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	FilingType    string     `gorm:"type:varchar(50);not null" json:"filing_type"` // sar, transaction_report, exposure_report
+	Reference     string     `gorm:"type:varchar(100);not null" json:"reference"`
+	Status        string     `gorm:"type:varchar(50);default:'draft'" json:"status"` // draft, submitting, submitted, acknowledged, rejected, failed
+	SchemaVersion string     `gorm:"type:varchar(20)" json:"schema_version,omitempty"`
+	SubjectID     *uuid.UUID `gorm:"type:uuid" json:"subject_id,omitempty"`
+	AlertIDs      []string   `gorm:"type:json;serializer:json" json:"alert_ids,omitempty"`
+	PeriodStart   *time.Time `json:"period_start,omitempty"`
+	PeriodEnd     *time.Time `json:"period_end,omitempty"` // Exclusive
+	Payload       string     `gorm:"type:json" json:"payload"`
+	CreatedBy     string     `gorm:"type:varchar(255)" json:"created_by"`
+
+	// Signature over the exact payload bytes with the Epic 4 certificate's key
+	Signature              string     `gorm:"type:text" json:"signature,omitempty"` // Base64
+	SignatureAlgorithm     string     `gorm:"type:varchar(20)" json:"signature_algorithm,omitempty"`
+	CertificateFingerprint string     `gorm:"type:varchar(64)" json:"certificate_fingerprint,omitempty"` // SHA-256 of the DER certificate
+	SignedAt               *time.Time `json:"signed_at,omitempty"`
+
+	// Submission and acknowledgment tracking
+	Attempts         int        `gorm:"default:0" json:"attempts"`
+	SubmissionID     string     `gorm:"type:varchar(255)" json:"submission_id,omitempty"` // Assigned by the regulator
+	SubmittedAt      *time.Time `json:"submitted_at,omitempty"`
+	AcknowledgmentID string     `gorm:"type:varchar(255)" json:"acknowledgment_id,omitempty"`
+	AcknowledgedAt   *time.Time `json:"acknowledged_at,omitempty"`
+	RejectionReason  string     `gorm:"type:text" json:"rejection_reason,omitempty"`
+	LastError        string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt    *time.Time `json:"next_attempt_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RegulatoryFilingSubmission is one attempt to deliver a filing to the reporting endpoint
+type RegulatoryFilingSubmission struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	FilingID         uuid.UUID  `gorm:"type:uuid;not null" json:"filing_id"`
+	Attempt          int        `gorm:"not null" json:"attempt"`
+	PayloadHash      string     `gorm:"type:varchar(64);not null" json:"payload_hash"` // SHA-256 of the payload sent
+	Status           string     `gorm:"type:varchar(50);not null" json:"status"`       // pending, accepted, rejected, error
+	HTTPStatus       int        `json:"http_status,omitempty"`
+	SubmissionID     string     `gorm:"type:varchar(255)" json:"submission_id,omitempty"`
+	AcknowledgmentID string     `gorm:"type:varchar(255)" json:"acknowledgment_id,omitempty"`
+	Response         string     `gorm:"type:text" json:"response,omitempty"`
+	Error            string     `gorm:"type:text" json:"error,omitempty"`
+	SubmittedBy      string     `gorm:"type:varchar(255)" json:"submitted_by"`
+	RespondedAt      *time.Time `json:"responded_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// AuditAnchor records an audit chain head published to the Fabric ledger
+type AuditAnchor struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Sequence   int64      `gorm:"not null" json:"sequence"` // Sequence of the chain head
+	Hash       string     `gorm:"type:varchar(64);not null" json:"hash"`
+	Status     string     `gorm:"type:varchar(50);not null" json:"status"` // anchored, failed
+	FabricTxID string     `gorm:"type:varchar(255)" json:"fabric_tx_id,omitempty"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	AnchoredAt *time.Time `json:"anchored_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RetentionRule expires the rows of one table RetentionDays after their Basis
+// timestamp. Statuses, when set, limits the rule to rows in those statuses.
+type RetentionRule struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name          string     `gorm:"type:varchar(100);not null" json:"name"`
+	Entity        string     `gorm:"type:varchar(100);not null" json:"entity"` // Table name
+	Basis         string     `gorm:"type:varchar(50);not null" json:"basis"`   // created_at, updated_at, or an entity-specific timestamp
+	Statuses      []string   `gorm:"type:json;serializer:json" json:"statuses,omitempty"`
+	RetentionDays int        `gorm:"not null" json:"retention_days"`
+	Action        string     `gorm:"type:varchar(20);not null" json:"action"` // purge, anonymize
+	Enabled       bool       `gorm:"default:true" json:"enabled"`
+	Description   string     `gorm:"type:text" json:"description"`
+	UpdatedBy     string     `gorm:"type:varchar(255)" json:"updated_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// LegalHold keeps retention away from a row, or from every row of a customer, until it
+// is released. An empty Entity applies the hold to every table.
+type LegalHold struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Entity        string     `gorm:"type:varchar(100)" json:"entity,omitempty"`
+	RecordID      *uuid.UUID `gorm:"type:uuid" json:"record_id,omitempty"`
+	CustomerID    *uuid.UUID `gorm:"type:uuid" json:"customer_id,omitempty"`
+	Reason        string     `gorm:"type:text;not null" json:"reason"`
+	CaseReference string     `gorm:"type:varchar(255)" json:"case_reference,omitempty"`
+	PlacedBy      string     `gorm:"type:varchar(255);not null" json:"placed_by"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleasedBy    string     `gorm:"type:varchar(255)" json:"released_by,omitempty"`
+	ReleaseNote   string     `gorm:"type:text" json:"release_note,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// PurgeRun records one retention run. Report is the canonical JSON purge report, signed
+// with the Epic 4 certificate's key.
+type PurgeRun struct {
+	ID                     uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Status                 string     `gorm:"type:varchar(20);not null" json:"status"` // running, completed, failed, abandoned
+	DryRun                 bool       `gorm:"default:false" json:"dry_run"`
+	TriggeredBy            string     `gorm:"type:varchar(255);not null" json:"triggered_by"`
+	Purged                 int64      `gorm:"default:0" json:"purged"`
+	Anonymized             int64      `gorm:"default:0" json:"anonymized"`
+	Held                   int64      `gorm:"default:0" json:"held"`
+	Error                  string     `gorm:"type:text" json:"error,omitempty"`
+	Report                 string     `gorm:"type:text" json:"report,omitempty"`
+	ReportSHA256           string     `gorm:"type:varchar(64)" json:"report_sha256,omitempty"`
+	Signature              string     `gorm:"type:text" json:"signature,omitempty"`
+	SignatureAlgorithm     string     `gorm:"type:varchar(20)" json:"signature_algorithm,omitempty"`
+	CertificateFingerprint string     `gorm:"type:varchar(64)" json:"certificate_fingerprint,omitempty"`
+	StartedAt              time.Time  `json:"started_at"`
+	CompletedAt            *time.Time `json:"completed_at,omitempty"`
+}
+
+// DualControlApproval holds a sensitive request until other users with a checker role
+// confirm it (maker-checker). Once approved, the initiator repeats the identical request
+// with the approval ID, which lets it through once.
+type DualControlApproval struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Action            string     `gorm:"type:varchar(50);not null" json:"action"` // payment, transfer, disbursement, credit_limit, bank_account
+	Method            string     `gorm:"type:varchar(10);not null" json:"method"`
+	Path              string     `gorm:"type:varchar(500);not null" json:"path"`
+	RequestBody       string     `gorm:"type:text" json:"request_body"`
+	PayloadHash       string     `gorm:"type:varchar(64);not null" json:"-"` // SHA-256 of method, path and body
+	Amount            float64    `gorm:"type:decimal(15,2)" json:"amount"`
+	Currency          string     `gorm:"type:varchar(3)" json:"currency,omitempty"`
+	RequiredApprovals int        `gorm:"not null" json:"required_approvals"`
+	Approvals         int        `gorm:"default:0" json:"approvals"`
+	InitiatedBy       string     `gorm:"type:varchar(255);not null" json:"initiated_by"`
+	InitiatorRole     string     `gorm:"type:varchar(50)" json:"initiator_role"`
+	BankID            string     `gorm:"type:varchar(255)" json:"bank_id,omitempty"` // Checkers must belong to the same bank
+	Status            string     `gorm:"type:varchar(20);not null" json:"status"`    // pending, approved, executed, failed, rejected, cancelled, expired
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`                 // Deadline to confirm, then to carry out once approved
+	ApprovedAt        *time.Time `json:"approved_at,omitempty"`
+	ExecutedAt        *time.Time `json:"executed_at,omitempty"`
+	ResponseStatus    int        `json:"response_status,omitempty"` // Of the request carried out
+	ClosedAt          *time.Time `json:"closed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Decisions []DualControlDecision `gorm:"foreignKey:ApprovalID" json:"decisions,omitempty"`
+}
+
+// DualControlDecision is one checker's confirmation or refusal
+type DualControlDecision struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ApprovalID uuid.UUID `gorm:"type:uuid;not null" json:"approval_id"`
+	UserID     string    `gorm:"type:varchar(255);not null" json:"user_id"`
+	Role       string    `gorm:"type:varchar(50)" json:"role"`
+	Decision   string    `gorm:"type:varchar(20);not null" json:"decision"` // approve, reject
+	Comment    string    `gorm:"type:text" json:"comment,omitempty"`
+	IPAddress  string    `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // BeforeCreate hooks for UUID generation
 func (bc *BankConnection) BeforeCreate(tx *gorm.DB) error {
 	if bc.ID == uuid.Nil {
@@ -341,3 +743,151 @@ func (rj *ReconciliationJob) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+func (bsl *BankStatementLine) BeforeCreate(tx *gorm.DB) error {
+	if bsl.ID == uuid.Nil {
+		bsl.ID = uuid.New()
+	}
+	return nil
+}
+
+func (fo *FinancingOffer) BeforeCreate(tx *gorm.DB) error {
+	if fo.ID == uuid.Nil {
+		fo.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ps *PortfolioSnapshot) BeforeCreate(tx *gorm.DB) error {
+	if ps.ID == uuid.Nil {
+		ps.ID = uuid.New()
+	}
+	return nil
+}
+
+func (pr *PortfolioReport) BeforeCreate(tx *gorm.DB) error {
+	if pr.ID == uuid.Nil {
+		pr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ak *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if ak.ID == uuid.Nil {
+		ak.ID = uuid.New()
+	}
+	return nil
+}
+
+func (tlo *TransactionLimitOverride) BeforeCreate(tx *gorm.DB) error {
+	if tlo.ID == uuid.Nil {
+		tlo.ID = uuid.New()
+	}
+	return nil
+}
+
+func (tlr *TransactionLimitReservation) BeforeCreate(tx *gorm.DB) error {
+	if tlr.ID == uuid.Nil {
+		tlr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (as *AMLScenario) BeforeCreate(tx *gorm.DB) error {
+	if as.ID == uuid.Nil {
+		as.ID = uuid.New()
+	}
+	return nil
+}
+
+func (aa *AMLAlert) BeforeCreate(tx *gorm.DB) error {
+	if aa.ID == uuid.Nil {
+		aa.ID = uuid.New()
+	}
+	return nil
+}
+
+func (an *AMLAlertNote) BeforeCreate(tx *gorm.DB) error {
+	if an.ID == uuid.Nil {
+		an.ID = uuid.New()
+	}
+	return nil
+}
+
+func (rf *RegulatoryFiling) BeforeCreate(tx *gorm.DB) error {
+	if rf.ID == uuid.Nil {
+		rf.ID = uuid.New()
+	}
+	return nil
+}
+
+func (rs *RegulatoryFilingSubmission) BeforeCreate(tx *gorm.DB) error {
+	if rs.ID == uuid.Nil {
+		rs.ID = uuid.New()
+	}
+	return nil
+}
+
+func (aa *AuditAnchor) BeforeCreate(tx *gorm.DB) error {
+	if aa.ID == uuid.Nil {
+		aa.ID = uuid.New()
+	}
+	return nil
+}
+
+func (rr *RetentionRule) BeforeCreate(tx *gorm.DB) error {
+	if rr.ID == uuid.Nil {
+		rr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (lh *LegalHold) BeforeCreate(tx *gorm.DB) error {
+	if lh.ID == uuid.Nil {
+		lh.ID = uuid.New()
+	}
+	return nil
+}
+
+func (pr *PurgeRun) BeforeCreate(tx *gorm.DB) error {
+	if pr.ID == uuid.Nil {
+		pr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (da *DualControlApproval) BeforeCreate(tx *gorm.DB) error {
+	if da.ID == uuid.Nil {
+		da.ID = uuid.New()
+	}
+	return nil
+}
+
+func (dd *DualControlDecision) BeforeCreate(tx *gorm.DB) error {
+	if dd.ID == uuid.Nil {
+		dd.ID = uuid.New()
+	}
+	return nil
+}
+
+// ComputeHash returns the SHA-256 of the entry's content and chain position. Timestamps
+// are hashed in UTC at microsecond precision, which is what PostgreSQL stores.
+func (at *AuditTrail) ComputeHash() string {
+	content, _ := json.Marshal([]interface{}{
+		at.Sequence,
+		at.PrevHash,
+		at.ID.String(),
+		at.EntityType,
+		at.EntityID.String(),
+		at.Action,
+		at.UserID.String(),
+		at.IPAddress,
+		at.UserAgent,
+		at.Details,
+		at.BeforeState,
+		at.AfterState,
+		at.Timestamp.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,1027 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// AML scenario codes
+const (
+	AMLScenarioStructuring          = "structuring"
+	AMLScenarioRapidMovement        = "rapid_movement"
+	AMLScenarioRoundAmounts         = "round_amounts"
+	AMLScenarioHighRiskJurisdiction = "high_risk_jurisdiction"
+	AMLScenarioNewCounterpartyLarge = "new_counterparty_large"
+)
+
+// AML alert statuses
+const (
+	AMLAlertOpen          = "open"
+	AMLAlertInvestigating = "investigating"
+	AMLAlertEscalated     = "escalated"
+	AMLAlertClosed        = "closed"
+)
+
+// FilingTypeSAR is a suspicious activity report
+const FilingTypeSAR = "sar"
+
+// sarSchemaVersion versions the SAR export payload
+const sarSchemaVersion = "1.0"
+
+// amlResolutions lists how an analyst may close an alert; sar_filed is set by CreateSAR
+var amlResolutions = map[string]string{
+	"false_positive": "Activity explained; no suspicion remains",
+	"no_action":      "Suspicion not strong enough to report",
+}
+
+// excludedPaymentStatuses never count towards scenario aggregates
+var excludedPaymentStatuses = []string{"failed", "cancelled"}
+
+var (
+	ErrPaymentNotFound          = errors.New("payment transaction not found")
+	ErrAMLAlertNotFound         = errors.New("aml alert not found")
+	ErrAMLAlertClosed           = errors.New("aml alert is closed")
+	ErrAMLScenarioNotFound      = errors.New("aml scenario not found")
+	ErrInvalidAMLRequest        = errors.New("invalid aml request")
+	ErrInvalidFilingRequest     = errors.New("invalid regulatory filing request")
+	ErrRegulatoryFilingNotFound = errors.New("regulatory filing not found")
+)
+
+// AMLAlertFilter narrows an alert listing
+type AMLAlertFilter struct {
+	Status       string
+	ScenarioCode string
+	AssignedTo   string
+	CustomerID   *uuid.UUID
+	Limit        int
+}
+
+// ScenarioUpdate changes a scenario's configuration; nil fields are left unchanged
+type ScenarioUpdate struct {
+	Enabled    *bool                         `json:"enabled"`
+	Severity   string                        `json:"severity"`
+	Parameters *models.AMLScenarioParameters `json:"parameters"`
+}
+
+// SARRequest is the input for assembling a suspicious activity report
+type SARRequest struct {
+	AlertIDs  []uuid.UUID `json:"alert_ids"`
+	Narrative string      `json:"narrative"`
+}
+
+// SARExport is the suspicious activity report payload stored with the filing
+type SARExport struct {
+	SchemaVersion string           `json:"schema_version"`
+	FilingType    string           `json:"filing_type"`
+	Reference     string           `json:"reference"`
+	PreparedBy    string           `json:"prepared_by"`
+	PreparedAt    time.Time        `json:"prepared_at"`
+	Subject       SARSubject       `json:"subject"`
+	Activity      SARActivity      `json:"activity"`
+	Scenarios     []string         `json:"scenarios"`
+	Alerts        []SARAlert       `json:"alerts"`
+	Transactions  []SARTransaction `json:"transactions"`
+	Narrative     string           `json:"narrative"`
+}
+
+// SARSubject identifies who the report is about
+type SARSubject struct {
+	CustomerID        *uuid.UUID `json:"customer_id,omitempty"`
+	BankConnectionIDs []string   `json:"bank_connection_ids,omitempty"`
+}
+
+// SARActivity summarises the reported activity
+type SARActivity struct {
+	Start            time.Time          `json:"start"`
+	End              time.Time          `json:"end"`
+	TotalAmounts     map[string]float64 `json:"total_amounts"` // by currency
+	TransactionCount int                `json:"transaction_count"`
+}
+
+// SARAlert is an alert included in the report
+type SARAlert struct {
+	ID           uuid.UUID       `json:"id"`
+	ScenarioCode string          `json:"scenario_code"`
+	Severity     string          `json:"severity"`
+	RaisedAt     time.Time       `json:"raised_at"`
+	Details      json.RawMessage `json:"details"`
+}
+
+// SARTransaction is a payment or financing event included in the report
+type SARTransaction struct {
+	EntityType          string    `json:"entity_type"`
+	ID                  uuid.UUID `json:"id"`
+	Reference           string    `json:"reference,omitempty"`
+	Type                string    `json:"type"`
+	Amount              float64   `json:"amount"`
+	Currency            string    `json:"currency"`
+	FromAccount         string    `json:"from_account,omitempty"`
+	ToAccount           string    `json:"to_account,omitempty"`
+	CounterpartyCountry string    `json:"counterparty_country,omitempty"`
+	Status              string    `json:"status"`
+	Date                time.Time `json:"date"`
+}
+
+// amlHit is a scenario match and the evidence behind it
+type amlHit struct {
+	scenario *models.AMLScenario
+	details  map[string]interface{}
+}
+
+// AMLService screens payments and financing events against configurable monitoring
+// scenarios and manages the resulting alerts as cases
+type AMLService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func NewAMLService(db *gorm.DB, cfg *config.Config) *AMLService {
+	return &AMLService{db: db, config: cfg}
+}
+
+// DefaultAMLScenarios returns the built-in scenarios with their default tuning
+func DefaultAMLScenarios(cfg *config.Config) []models.AMLScenario {
+	return []models.AMLScenario{
+		{
+			Code:        AMLScenarioStructuring,
+			Name:        "Structuring below reporting threshold",
+			Description: "Several payments by one customer just under the threshold within the window",
+			Severity:    "high",
+			Parameters: models.AMLScenarioParameters{
+				Threshold:   cfg.SuspiciousActivityThreshold,
+				BandPercent: 10,
+				MinCount:    3,
+				WindowHours: 72,
+			},
+		},
+		{
+			Code:        AMLScenarioRapidMovement,
+			Name:        "Rapid movement of funds",
+			Description: "Funds received through settlements or financing disbursements are paid out again within the window",
+			Severity:    "high",
+			Parameters: models.AMLScenarioParameters{
+				Threshold:        10000,
+				PassThroughRatio: 0.8,
+				WindowHours:      48,
+			},
+		},
+		{
+			Code:        AMLScenarioRoundAmounts,
+			Name:        "Repeated round amounts",
+			Description: "Several large round-amount payments or disbursements for one customer within the window",
+			Severity:    "low",
+			Parameters: models.AMLScenarioParameters{
+				Threshold:    10000,
+				RoundingUnit: 1000,
+				MinCount:     3,
+				WindowHours:  168,
+			},
+		},
+		{
+			Code:        AMLScenarioHighRiskJurisdiction,
+			Name:        "High-risk jurisdiction",
+			Description: "Payment to a counterparty in a high-risk jurisdiction",
+			Severity:    "high",
+			Parameters: models.AMLScenarioParameters{
+				Countries: cfg.AMLHighRiskJurisdictions,
+			},
+		},
+		{
+			Code:        AMLScenarioNewCounterpartyLarge,
+			Name:        "Large payment to new counterparty",
+			Description: "Large payment to an account the customer has not paid before",
+			Severity:    "medium",
+			Parameters: models.AMLScenarioParameters{
+				Threshold: 25000,
+			},
+		},
+	}
+}
+
+// SeedScenarios creates any built-in scenario that does not exist yet. Existing scenarios
+// keep their tuning.
+func (s *AMLService) SeedScenarios() error {
+	for _, scenario := range DefaultAMLScenarios(s.config) {
+		scenario := scenario
+		if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&scenario).Error; err != nil {
+			return fmt.Errorf("failed to seed aml scenario %s: %w", scenario.Code, err)
+		}
+	}
+	return nil
+}
+
+// ListScenarios returns all monitoring scenarios
+func (s *AMLService) ListScenarios() ([]models.AMLScenario, error) {
+	scenarios := []models.AMLScenario{}
+	if err := s.db.Order("code").Find(&scenarios).Error; err != nil {
+		return nil, fmt.Errorf("failed to list aml scenarios: %w", err)
+	}
+	return scenarios, nil
+}
+
+// UpdateScenario retunes, enables or disables a scenario
+func (s *AMLService) UpdateScenario(code string, update ScenarioUpdate, updatedBy string) (*models.AMLScenario, error) {
+	var scenario models.AMLScenario
+	if err := s.db.Where("code = ?", code).First(&scenario).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAMLScenarioNotFound
+		}
+		return nil, fmt.Errorf("failed to load aml scenario: %w", err)
+	}
+
+	if update.Severity != "" {
+		if !validSeverity(update.Severity) {
+			return nil, fmt.Errorf("%w: severity must be low, medium or high", ErrInvalidAMLRequest)
+		}
+		scenario.Severity = update.Severity
+	}
+	if update.Enabled != nil {
+		scenario.Enabled = *update.Enabled
+	}
+	if update.Parameters != nil {
+		params := *update.Parameters
+		if params.Threshold < 0 || params.BandPercent < 0 || params.BandPercent >= 100 || params.MinCount < 0 ||
+			params.WindowHours < 0 || params.RoundingUnit < 0 || params.PassThroughRatio < 0 {
+			return nil, fmt.Errorf("%w: parameters out of range", ErrInvalidAMLRequest)
+		}
+		for i, country := range params.Countries {
+			params.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+		}
+		scenario.Parameters = params
+	}
+	scenario.UpdatedBy = updatedBy
+
+	if err := s.db.Save(&scenario).Error; err != nil {
+		return nil, fmt.Errorf("failed to update aml scenario: %w", err)
+	}
+	return &scenario, nil
+}
+
+// EvaluatePayment screens a payment against the enabled scenarios and raises an alert
+// for each hit. The payment is marked as screened either way.
+func (s *AMLService) EvaluatePayment(paymentID uuid.UUID) ([]models.AMLAlert, error) {
+	var payment models.PaymentTransaction
+	if err := s.db.Where("id = ?", paymentID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("failed to load payment: %w", err)
+	}
+
+	hits := []amlHit{}
+	if !containsFold(excludedPaymentStatuses, payment.Status) {
+		scenarios, err := s.enabledScenarios()
+		if err != nil {
+			return nil, err
+		}
+		if hits, err = s.screenPayment(&payment, scenarios); err != nil {
+			return nil, err
+		}
+	}
+
+	alerts := []models.AMLAlert{}
+	for _, hit := range hits {
+		alert, err := s.raiseAlert(hit, "payment", payment.ID, payment.CustomerID, payment.Amount, payment.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if alert != nil {
+			alerts = append(alerts, *alert)
+		}
+	}
+
+	if err := s.db.Model(&models.PaymentTransaction{}).Where("id = ?", payment.ID).
+		Update("aml_screened_at", time.Now()).Error; err != nil {
+		return nil, fmt.Errorf("failed to mark payment screened: %w", err)
+	}
+	return alerts, nil
+}
+
+// EvaluateFinancing screens a disbursed financing request. Disbursements also count as
+// inflows when later payments are checked for rapid movement of funds.
+func (s *AMLService) EvaluateFinancing(requestID uuid.UUID) ([]models.AMLAlert, error) {
+	var request models.FinancingRequest
+	if err := s.db.Where("id = ?", requestID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFinancingRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to load financing request: %w", err)
+	}
+	if request.DisbursedAt == nil {
+		return nil, fmt.Errorf("%w: financing request has not been disbursed", ErrInvalidAMLRequest)
+	}
+
+	scenarios, err := s.enabledScenarios()
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []models.AMLAlert{}
+	if scenario, ok := scenarios[AMLScenarioRoundAmounts]; ok {
+		details, err := s.roundAmounts(scenario, &request.CustomerID, request.RequestedAmount, *request.DisbursedAt)
+		if err != nil {
+			return nil, err
+		}
+		if details != nil {
+			customerID := request.CustomerID
+			alert, err := s.raiseAlert(amlHit{scenario: scenario, details: details}, "financing", request.ID, &customerID, request.RequestedAmount, request.Currency)
+			if err != nil {
+				return nil, err
+			}
+			if alert != nil {
+				alerts = append(alerts, *alert)
+			}
+		}
+	}
+
+	if err := s.db.Model(&models.FinancingRequest{}).Where("id = ?", request.ID).
+		Update("aml_screened_at", time.Now()).Error; err != nil {
+		return nil, fmt.Errorf("failed to mark financing request screened: %w", err)
+	}
+	return alerts, nil
+}
+
+func (s *AMLService) enabledScenarios() (map[string]*models.AMLScenario, error) {
+	var scenarios []models.AMLScenario
+	if err := s.db.Where("enabled = ?", true).Find(&scenarios).Error; err != nil {
+		return nil, fmt.Errorf("failed to load aml scenarios: %w", err)
+	}
+	byCode := make(map[string]*models.AMLScenario, len(scenarios))
+	for i := range scenarios {
+		byCode[scenarios[i].Code] = &scenarios[i]
+	}
+	return byCode, nil
+}
+
+func (s *AMLService) screenPayment(payment *models.PaymentTransaction, scenarios map[string]*models.AMLScenario) ([]amlHit, error) {
+	checks := []struct {
+		code  string
+		check func(*models.AMLScenario, *models.PaymentTransaction) (map[string]interface{}, error)
+	}{
+		{AMLScenarioStructuring, s.structuring},
+		{AMLScenarioRapidMovement, s.rapidMovement},
+		{AMLScenarioRoundAmounts, func(scenario *models.AMLScenario, p *models.PaymentTransaction) (map[string]interface{}, error) {
+			return s.roundAmounts(scenario, p.CustomerID, p.Amount, p.CreatedAt)
+		}},
+		{AMLScenarioHighRiskJurisdiction, s.highRiskJurisdiction},
+		{AMLScenarioNewCounterpartyLarge, s.newCounterpartyLarge},
+	}
+
+	hits := []amlHit{}
+	for _, check := range checks {
+		scenario, ok := scenarios[check.code]
+		if !ok {
+			continue
+		}
+		details, err := check.check(scenario, payment)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", check.code, err)
+		}
+		if details != nil {
+			hits = append(hits, amlHit{scenario: scenario, details: details})
+		}
+	}
+	return hits, nil
+}
+
+// structuring flags a customer's payments clustering just under the threshold
+func (s *AMLService) structuring(scenario *models.AMLScenario, payment *models.PaymentTransaction) (map[string]interface{}, error) {
+	params := scenario.Parameters
+	threshold := params.Threshold
+	if threshold <= 0 {
+		threshold = s.config.SuspiciousActivityThreshold
+	}
+	lower := threshold * (1 - params.BandPercent/100)
+	if payment.CustomerID == nil || payment.Amount < lower || payment.Amount >= threshold {
+		return nil, nil
+	}
+
+	var result struct {
+		Count int64
+		Total float64
+	}
+	err := s.customerPayments(*payment.CustomerID, payment.CreatedAt, params.WindowHours).
+		Where("amount >= ? AND amount < ?", lower, threshold).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	if result.Count < int64(params.MinCount) {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"threshold":     threshold,
+		"band_lower":    roundTo(lower, 2),
+		"payment_count": result.Count,
+		"total_amount":  roundTo(result.Total, 2),
+		"window_hours":  params.WindowHours,
+	}, nil
+}
+
+// rapidMovement flags outgoing payments that pass on most of what the customer received
+// in the window. Settlements and financing disbursements count as inflows.
+func (s *AMLService) rapidMovement(scenario *models.AMLScenario, payment *models.PaymentTransaction) (map[string]interface{}, error) {
+	params := scenario.Parameters
+	if payment.CustomerID == nil || (payment.Type != "payment" && payment.Type != "transfer") {
+		return nil, nil
+	}
+	from := payment.CreatedAt.Add(-time.Duration(params.WindowHours) * time.Hour)
+
+	var settled, disbursed, outflows float64
+	if err := s.customerPayments(*payment.CustomerID, payment.CreatedAt, params.WindowHours).
+		Where("type = ?", "settlement").
+		Select("COALESCE(SUM(amount), 0)").Scan(&settled).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.FinancingRequest{}).
+		Where("customer_id = ? AND disbursed_at >= ? AND disbursed_at <= ?", *payment.CustomerID, from, payment.CreatedAt).
+		Select("COALESCE(SUM(requested_amount), 0)").Scan(&disbursed).Error; err != nil {
+		return nil, err
+	}
+	if err := s.customerPayments(*payment.CustomerID, payment.CreatedAt, params.WindowHours).
+		Where("type IN ?", []string{"payment", "transfer"}).
+		Select("COALESCE(SUM(amount), 0)").Scan(&outflows).Error; err != nil {
+		return nil, err
+	}
+
+	inflows := settled + disbursed
+	if inflows < params.Threshold || inflows <= 0 || outflows < inflows*params.PassThroughRatio {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"inflows":            roundTo(inflows, 2),
+		"settlements":        roundTo(settled, 2),
+		"disbursements":      roundTo(disbursed, 2),
+		"outflows":           roundTo(outflows, 2),
+		"pass_through_ratio": roundTo(outflows/inflows, 4),
+		"window_hours":       params.WindowHours,
+	}, nil
+}
+
+// roundAmounts flags repeated large round amounts across a customer's payments and
+// financing disbursements
+func (s *AMLService) roundAmounts(scenario *models.AMLScenario, customerID *uuid.UUID, amount float64, at time.Time) (map[string]interface{}, error) {
+	params := scenario.Parameters
+	if customerID == nil || params.RoundingUnit <= 0 || amount < params.Threshold || !isRoundAmount(amount, params.RoundingUnit) {
+		return nil, nil
+	}
+
+	var amounts []float64
+	if err := s.customerPayments(*customerID, at, params.WindowHours).
+		Where("amount >= ?", params.Threshold).Pluck("amount", &amounts).Error; err != nil {
+		return nil, err
+	}
+	var disbursed []float64
+	from := at.Add(-time.Duration(params.WindowHours) * time.Hour)
+	if err := s.db.Model(&models.FinancingRequest{}).
+		Where("customer_id = ? AND disbursed_at >= ? AND disbursed_at <= ? AND requested_amount >= ?", *customerID, from, at, params.Threshold).
+		Pluck("requested_amount", &disbursed).Error; err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for _, value := range append(amounts, disbursed...) {
+		if isRoundAmount(value, params.RoundingUnit) {
+			count++
+		}
+	}
+	if count < params.MinCount {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"rounding_unit": params.RoundingUnit,
+		"round_count":   count,
+		"window_hours":  params.WindowHours,
+	}, nil
+}
+
+func (s *AMLService) highRiskJurisdiction(scenario *models.AMLScenario, payment *models.PaymentTransaction) (map[string]interface{}, error) {
+	country := strings.ToUpper(strings.TrimSpace(payment.CounterpartyCountry))
+	if country == "" || !containsFold(scenario.Parameters.Countries, country) {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"counterparty_country": country,
+		"to_account_id":        payment.ToAccountID,
+	}, nil
+}
+
+// newCounterpartyLarge flags a large first payment to an account
+func (s *AMLService) newCounterpartyLarge(scenario *models.AMLScenario, payment *models.PaymentTransaction) (map[string]interface{}, error) {
+	if payment.CustomerID == nil || payment.ToAccountID == "" || payment.Amount < scenario.Parameters.Threshold {
+		return nil, nil
+	}
+
+	var previous int64
+	if err := s.db.Model(&models.PaymentTransaction{}).
+		Where("customer_id = ? AND to_account_id = ? AND created_at < ? AND id <> ? AND status NOT IN ?",
+			*payment.CustomerID, payment.ToAccountID, payment.CreatedAt, payment.ID, excludedPaymentStatuses).
+		Count(&previous).Error; err != nil {
+		return nil, err
+	}
+	if previous > 0 {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"to_account_id": payment.ToAccountID,
+		"threshold":     scenario.Parameters.Threshold,
+	}, nil
+}
+
+// customerPayments scopes a query to a customer's live payments in the window ending at
+func (s *AMLService) customerPayments(customerID uuid.UUID, at time.Time, windowHours int) *gorm.DB {
+	from := at.Add(-time.Duration(windowHours) * time.Hour)
+	return s.db.Model(&models.PaymentTransaction{}).
+		Where("customer_id = ? AND created_at >= ? AND created_at <= ? AND status NOT IN ?", customerID, from, at, excludedPaymentStatuses)
+}
+
+// raiseAlert records a scenario hit with its compliance record. An entity raises at most
+// one alert per scenario, so re-screening returns nil for hits already alerted.
+func (s *AMLService) raiseAlert(hit amlHit, entityType string, entityID uuid.UUID, customerID *uuid.UUID, amount float64, currency string) (*models.AMLAlert, error) {
+	alert := &models.AMLAlert{
+		ScenarioCode: hit.scenario.Code,
+		Severity:     hit.scenario.Severity,
+		Status:       AMLAlertOpen,
+		EntityType:   entityType,
+		EntityID:     entityID,
+		CustomerID:   customerID,
+		Amount:       amount,
+		Currency:     currency,
+		Details:      toJSON(hit.details),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			alert = nil
+			return nil
+		}
+
+		record := &models.ComplianceRecord{
+			EntityType:     entityType,
+			EntityID:       entityID,
+			ComplianceType: "aml",
+			Status:         "under_review",
+			CheckedAt:      time.Now(),
+			ComplianceData: toJSON(map[string]interface{}{
+				"alert_id": alert.ID,
+				"scenario": hit.scenario.Code,
+				"details":  hit.details,
+			}),
+			Issues:      toJSON([]string{hit.scenario.Code}),
+			Remediation: "AML alert under investigation",
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		alert.ComplianceRecordID = &record.ID
+		return tx.Model(alert).Update("compliance_record_id", record.ID).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to raise aml alert: %w", err)
+	}
+	return alert, nil
+}
+
+// ListAlerts returns alerts, newest first
+func (s *AMLService) ListAlerts(filter AMLAlertFilter) ([]models.AMLAlert, error) {
+	query := s.db.Order("created_at DESC")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ScenarioCode != "" {
+		query = query.Where("scenario_code = ?", filter.ScenarioCode)
+	}
+	if filter.AssignedTo != "" {
+		query = query.Where("assigned_to = ?", filter.AssignedTo)
+	}
+	if filter.CustomerID != nil {
+		query = query.Where("customer_id = ?", *filter.CustomerID)
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+
+	alerts := []models.AMLAlert{}
+	if err := query.Limit(filter.Limit).Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list aml alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// GetAlert returns an alert with its case history
+func (s *AMLService) GetAlert(id uuid.UUID) (*models.AMLAlert, error) {
+	var alert models.AMLAlert
+	err := s.db.Preload("Notes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("id = ?", id).First(&alert).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAMLAlertNotFound
+		}
+		return nil, fmt.Errorf("failed to load aml alert: %w", err)
+	}
+	return &alert, nil
+}
+
+// AssignAlert hands an alert to an analyst and starts the investigation
+func (s *AMLService) AssignAlert(id uuid.UUID, assignee, actor string) (*models.AMLAlert, error) {
+	assignee = strings.TrimSpace(assignee)
+	if assignee == "" {
+		return nil, fmt.Errorf("%w: assignee is required", ErrInvalidAMLRequest)
+	}
+	return s.updateCase(id, actor, "assigned", "Assigned to "+assignee, func(alert *models.AMLAlert, updates map[string]interface{}) {
+		updates["assigned_to"] = assignee
+		if alert.Status == AMLAlertOpen {
+			updates["status"] = AMLAlertInvestigating
+		}
+	})
+}
+
+// AddAlertNote appends an investigation note
+func (s *AMLService) AddAlertNote(id uuid.UUID, body, actor string) (*models.AMLAlert, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: note is required", ErrInvalidAMLRequest)
+	}
+	return s.updateCase(id, actor, "note", body, nil)
+}
+
+// EscalateAlert raises an alert to senior compliance review
+func (s *AMLService) EscalateAlert(id uuid.UUID, reason, actor string) (*models.AMLAlert, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidAMLRequest)
+	}
+	return s.updateCase(id, actor, "escalated", reason, func(alert *models.AMLAlert, updates map[string]interface{}) {
+		updates["status"] = AMLAlertEscalated
+		updates["severity"] = "high"
+		updates["escalated_at"] = time.Now()
+		updates["escalated_by"] = actor
+	})
+}
+
+// CloseAlert closes an alert without a report
+func (s *AMLService) CloseAlert(id uuid.UUID, resolution, reason, actor string) (*models.AMLAlert, error) {
+	if _, ok := amlResolutions[resolution]; !ok {
+		return nil, fmt.Errorf("%w: resolution must be false_positive or no_action", ErrInvalidAMLRequest)
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidAMLRequest)
+	}
+	return s.updateCase(id, actor, "closed", resolution+": "+reason, func(alert *models.AMLAlert, updates map[string]interface{}) {
+		updates["status"] = AMLAlertClosed
+		updates["resolution"] = resolution
+		updates["closed_at"] = time.Now()
+		updates["closed_by"] = actor
+	})
+}
+
+// updateCase applies a case action to an open alert and records it in the case history
+func (s *AMLService) updateCase(id uuid.UUID, actor, action, body string, apply func(*models.AMLAlert, map[string]interface{})) (*models.AMLAlert, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var alert models.AMLAlert
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&alert).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAMLAlertNotFound
+			}
+			return err
+		}
+		if alert.Status == AMLAlertClosed {
+			return ErrAMLAlertClosed
+		}
+
+		if apply != nil {
+			updates := map[string]interface{}{}
+			apply(&alert, updates)
+			if err := tx.Model(&alert).Updates(updates).Error; err != nil {
+				return err
+			}
+			if updates["status"] == AMLAlertClosed && alert.ComplianceRecordID != nil {
+				if err := tx.Model(&models.ComplianceRecord{}).Where("id = ?", *alert.ComplianceRecordID).
+					Updates(map[string]interface{}{"status": "compliant", "remediation": body}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Create(&models.AMLAlertNote{AlertID: alert.ID, Action: action, Author: actor, Body: body}).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrAMLAlertNotFound) || errors.Is(err, ErrAMLAlertClosed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update aml alert: %w", err)
+	}
+	return s.GetAlert(id)
+}
+
+// CreateSAR assembles a suspicious activity report from open alerts on one subject and
+// closes the alerts as reported
+func (s *AMLService) CreateSAR(req SARRequest, preparedBy string) (*models.RegulatoryFiling, *SARExport, error) {
+	if len(req.AlertIDs) == 0 {
+		return nil, nil, fmt.Errorf("%w: alert_ids is required", ErrInvalidFilingRequest)
+	}
+	req.Narrative = strings.TrimSpace(req.Narrative)
+	if req.Narrative == "" {
+		return nil, nil, fmt.Errorf("%w: narrative is required", ErrInvalidFilingRequest)
+	}
+
+	var alerts []models.AMLAlert
+	if err := s.db.Where("id IN ?", req.AlertIDs).Order("created_at").Find(&alerts).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load aml alerts: %w", err)
+	}
+	if len(alerts) != len(uniqueUUIDs(req.AlertIDs)) {
+		return nil, nil, ErrAMLAlertNotFound
+	}
+	subject := alerts[0].CustomerID
+	for _, alert := range alerts {
+		if alert.Status == AMLAlertClosed {
+			return nil, nil, fmt.Errorf("%w: alert %s", ErrAMLAlertClosed, alert.ID)
+		}
+		if !sameCustomer(subject, alert.CustomerID) {
+			return nil, nil, fmt.Errorf("%w: alerts must concern the same customer", ErrInvalidFilingRequest)
+		}
+	}
+
+	now := time.Now()
+	export := &SARExport{
+		SchemaVersion: sarSchemaVersion,
+		FilingType:    "SAR",
+		Reference:     fmt.Sprintf("SAR-%s-%s", now.Format("20060102"), strings.ToUpper(uuid.NewString()[:8])),
+		PreparedBy:    preparedBy,
+		PreparedAt:    now,
+		Subject:       SARSubject{CustomerID: subject},
+		Narrative:     req.Narrative,
+	}
+	if err := s.collectSARActivity(export, alerts); err != nil {
+		return nil, nil, err
+	}
+
+	alertIDs := make([]string, len(alerts))
+	for i, alert := range alerts {
+		alertIDs[i] = alert.ID.String()
+	}
+	filing := &models.RegulatoryFiling{
+		FilingType:    FilingTypeSAR,
+		Reference:     export.Reference,
+		Status:        FilingStatusDraft,
+		SchemaVersion: sarSchemaVersion,
+		SubjectID:     subject,
+		AlertIDs:      alertIDs,
+		Payload:       toJSON(export),
+		CreatedBy:     preparedBy,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(filing).Error; err != nil {
+			return err
+		}
+		for _, alert := range alerts {
+			result := tx.Model(&models.AMLAlert{}).Where("id = ? AND status <> ?", alert.ID, AMLAlertClosed).
+				Updates(map[string]interface{}{
+					"status":     AMLAlertClosed,
+					"resolution": "sar_filed",
+					"filing_id":  filing.ID,
+					"closed_at":  now,
+					"closed_by":  preparedBy,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: alert %s", ErrAMLAlertClosed, alert.ID)
+			}
+			note := &models.AMLAlertNote{AlertID: alert.ID, Action: "closed", Author: preparedBy, Body: "sar_filed: " + filing.Reference}
+			if err := tx.Create(note).Error; err != nil {
+				return err
+			}
+			if alert.ComplianceRecordID != nil {
+				if err := tx.Model(&models.ComplianceRecord{}).Where("id = ?", *alert.ComplianceRecordID).
+					Updates(map[string]interface{}{"status": "non_compliant", "remediation": "Reported in " + filing.Reference}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrAMLAlertClosed) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to create suspicious activity report: %w", err)
+	}
+	return filing, export, nil
+}
+
+// collectSARActivity adds the alerts and the payments and disbursements behind them
+func (s *AMLService) collectSARActivity(export *SARExport, alerts []models.AMLAlert) error {
+	var paymentIDs, financingIDs []uuid.UUID
+	scenarios := map[string]bool{}
+	for _, alert := range alerts {
+		export.Alerts = append(export.Alerts, SARAlert{
+			ID:           alert.ID,
+			ScenarioCode: alert.ScenarioCode,
+			Severity:     alert.Severity,
+			RaisedAt:     alert.CreatedAt,
+			Details:      json.RawMessage(alert.Details),
+		})
+		scenarios[alert.ScenarioCode] = true
+		if alert.EntityType == "financing" {
+			financingIDs = append(financingIDs, alert.EntityID)
+		} else {
+			paymentIDs = append(paymentIDs, alert.EntityID)
+		}
+	}
+	for code := range scenarios {
+		export.Scenarios = append(export.Scenarios, code)
+	}
+	sort.Strings(export.Scenarios)
+
+	connections := map[string]bool{}
+	if len(paymentIDs) > 0 {
+		var payments []models.PaymentTransaction
+		if err := s.db.Where("id IN ?", uniqueUUIDs(paymentIDs)).Find(&payments).Error; err != nil {
+			return fmt.Errorf("failed to load payments: %w", err)
+		}
+		for _, p := range payments {
+			export.Transactions = append(export.Transactions, SARTransaction{
+				EntityType:          "payment",
+				ID:                  p.ID,
+				Reference:           p.PaymentID,
+				Type:                p.Type,
+				Amount:              p.Amount,
+				Currency:            p.Currency,
+				FromAccount:         p.FromAccountID,
+				ToAccount:           p.ToAccountID,
+				CounterpartyCountry: p.CounterpartyCountry,
+				Status:              p.Status,
+				Date:                p.CreatedAt,
+			})
+			connections[p.BankConnectionID.String()] = true
+		}
+	}
+	if len(financingIDs) > 0 {
+		var requests []models.FinancingRequest
+		if err := s.db.Where("id IN ?", uniqueUUIDs(financingIDs)).Find(&requests).Error; err != nil {
+			return fmt.Errorf("failed to load financing requests: %w", err)
+		}
+		for _, r := range requests {
+			date := r.CreatedAt
+			if r.DisbursedAt != nil {
+				date = *r.DisbursedAt
+			}
+			export.Transactions = append(export.Transactions, SARTransaction{
+				EntityType: "financing",
+				ID:         r.ID,
+				Type:       r.RequestType,
+				Amount:     r.RequestedAmount,
+				Currency:   r.Currency,
+				Status:     r.Status,
+				Date:       date,
+			})
+		}
+	}
+	for id := range connections {
+		export.Subject.BankConnectionIDs = append(export.Subject.BankConnectionIDs, id)
+	}
+	sort.Strings(export.Subject.BankConnectionIDs)
+
+	sort.Slice(export.Transactions, func(i, j int) bool {
+		return export.Transactions[i].Date.Before(export.Transactions[j].Date)
+	})
+	export.Activity.TotalAmounts = map[string]float64{}
+	for i, t := range export.Transactions {
+		if i == 0 || t.Date.Before(export.Activity.Start) {
+			export.Activity.Start = t.Date
+		}
+		if t.Date.After(export.Activity.End) {
+			export.Activity.End = t.Date
+		}
+		export.Activity.TotalAmounts[t.Currency] = roundTo(export.Activity.TotalAmounts[t.Currency]+t.Amount, 2)
+	}
+	export.Activity.TransactionCount = len(export.Transactions)
+	return nil
+}
+
+// ListFilings returns regulatory filings, newest first
+func (s *AMLService) ListFilings(filingType, status string) ([]models.RegulatoryFiling, error) {
+	query := s.db.Order("created_at DESC")
+	if filingType != "" {
+		query = query.Where("filing_type = ?", filingType)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	filings := []models.RegulatoryFiling{}
+	if err := query.Find(&filings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list regulatory filings: %w", err)
+	}
+	return filings, nil
+}
+
+// GetFiling returns a regulatory filing
+func (s *AMLService) GetFiling(id uuid.UUID) (*models.RegulatoryFiling, error) {
+	var filing models.RegulatoryFiling
+	if err := s.db.Where("id = ?", id).First(&filing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRegulatoryFilingNotFound
+		}
+		return nil, fmt.Errorf("failed to load regulatory filing: %w", err)
+	}
+	return &filing, nil
+}
+
+// StartMonitor screens new payments and disbursed financing requests on an interval
+func (s *AMLService) StartMonitor(ctx context.Context) {
+	if !s.config.AMLCheckRequired {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.AMLMonitoringInterval)
+	defer ticker.Stop()
+
+	for {
+		s.screenPending()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *AMLService) screenPending() {
+	var paymentIDs []uuid.UUID
+	if err := s.db.Model(&models.PaymentTransaction{}).Where("aml_screened_at IS NULL").
+		Order("created_at").Limit(s.config.AMLMonitoringBatchSize).Pluck("id", &paymentIDs).Error; err != nil {
+		log.Printf("AML monitor: failed to load payments: %v", err)
+		return
+	}
+	for _, id := range paymentIDs {
+		if alerts, err := s.EvaluatePayment(id); err != nil {
+			log.Printf("AML monitor: failed to screen payment %s: %v", id, err)
+		} else if len(alerts) > 0 {
+			log.Printf("AML monitor: payment %s raised %d alerts", id, len(alerts))
+		}
+	}
+
+	var requestIDs []uuid.UUID
+	if err := s.db.Model(&models.FinancingRequest{}).Where("aml_screened_at IS NULL AND disbursed_at IS NOT NULL").
+		Order("disbursed_at").Limit(s.config.AMLMonitoringBatchSize).Pluck("id", &requestIDs).Error; err != nil {
+		log.Printf("AML monitor: failed to load financing requests: %v", err)
+		return
+	}
+	for _, id := range requestIDs {
+		if alerts, err := s.EvaluateFinancing(id); err != nil {
+			log.Printf("AML monitor: failed to screen financing request %s: %v", id, err)
+		} else if len(alerts) > 0 {
+			log.Printf("AML monitor: financing request %s raised %d alerts", id, len(alerts))
+		}
+	}
+}
+
+func isRoundAmount(amount, unit float64) bool {
+	return math.Abs(math.Mod(amount, unit)) < 0.005
+}
+
+func validSeverity(severity string) bool {
+	return severity == "low" || severity == "medium" || severity == "high"
+}
+
+func sameCustomer(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// apiKeyPrefix marks platform API keys so they are recognisable in logs and secret scanners
+const apiKeyPrefix = "ifk_"
+
+var (
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrAPIKeyInvalid      = errors.New("invalid api key")
+	ErrAPIKeyExpired      = errors.New("api key has expired")
+	ErrAPIKeyRevoked      = errors.New("api key has been revoked")
+	ErrAPIKeyRateLimited  = errors.New("api key rate limit exceeded")
+	ErrInvalidAPIKeyInput = errors.New("invalid api key request")
+)
+
+// APIKeyScopes lists the scopes a key may be granted
+var APIKeyScopes = map[string]string{
+	"payments:read":    "Read payments and their status",
+	"payments:write":   "Submit and cancel payments",
+	"transfers:read":   "Read transfers and their status",
+	"transfers:write":  "Initiate and cancel transfers",
+	"accounts:sync":    "Push account balances and transactions",
+	"webhooks:receive": "Deliver webhooks to the platform",
+}
+
+// CreateAPIKeyRequest is the input for issuing a key
+type CreateAPIKeyRequest struct {
+	OrganizationID     string     `json:"organization_id"`
+	Name               string     `json:"name" binding:"required"`
+	Scopes             []string   `json:"scopes" binding:"required"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+}
+
+// APIKeyService issues, revokes and authenticates partner API keys
+type APIKeyService struct {
+	db     *gorm.DB
+	config *config.Config
+
+	mu      sync.Mutex
+	windows map[uuid.UUID]*apiKeyWindow
+}
+
+// apiKeyWindow counts requests of one key in the current one-minute window
+type apiKeyWindow struct {
+	start time.Time
+	count int
+}
+
+func NewAPIKeyService(db *gorm.DB, cfg *config.Config) *APIKeyService {
+	return &APIKeyService{
+		db:      db,
+		config:  cfg,
+		windows: make(map[uuid.UUID]*apiKeyWindow),
+	}
+}
+
+// CreateAPIKey issues a key and returns it with the plaintext value, which is not stored
+// and cannot be retrieved again
+func (s *APIKeyService) CreateAPIKey(req CreateAPIKeyRequest, createdBy string) (*models.APIKey, string, error) {
+	req.OrganizationID = strings.TrimSpace(req.OrganizationID)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.OrganizationID == "" || req.Name == "" {
+		return nil, "", fmt.Errorf("%w: organization_id and name are required", ErrInvalidAPIKeyInput)
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyInput)
+	}
+	if req.RateLimitPerMinute < 0 {
+		return nil, "", fmt.Errorf("%w: rate_limit_per_minute must not be negative", ErrInvalidAPIKeyInput)
+	}
+	if req.RateLimitPerMinute == 0 {
+		req.RateLimitPerMinute = s.config.APIKeyDefaultRateLimit
+	}
+
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := apiKeyPrefix + prefix + "_" + secret
+
+	key := &models.APIKey{
+		OrganizationID:     req.OrganizationID,
+		Name:               req.Name,
+		Prefix:             apiKeyPrefix + prefix,
+		KeyHash:            hashAPIKey(plaintext),
+		Scopes:             scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		ExpiresAt:          req.ExpiresAt,
+		CreatedBy:          createdBy,
+	}
+	if err := s.db.Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, plaintext, nil
+}
+
+// ListAPIKeys returns an organization's keys, or every key when organizationID is empty
+func (s *APIKeyService) ListAPIKeys(organizationID string, includeRevoked bool) ([]models.APIKey, error) {
+	query := s.db.Order("created_at DESC")
+	if organizationID != "" {
+		query = query.Where("organization_id = ?", organizationID)
+	}
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	keys := []models.APIKey{}
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key. A non-empty organizationID restricts the lookup to that
+// organization's keys.
+func (s *APIKeyService) RevokeAPIKey(id uuid.UUID, organizationID, revokedBy string) (*models.APIKey, error) {
+	var key models.APIKey
+	query := s.db.Where("id = ?", id)
+	if organizationID != "" {
+		query = query.Where("organization_id = ?", organizationID)
+	}
+	if err := query.First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to load api key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return &key, nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&key).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": revokedBy,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	key.RevokedAt = &now
+	key.RevokedBy = revokedBy
+	return &key, nil
+}
+
+// Authenticate resolves a presented key, enforces its rate limit and records its use
+func (s *APIKeyService) Authenticate(rawKey, clientIP string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var key models.APIKey
+	if err := s.db.Where("key_hash = ?", hashAPIKey(rawKey)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, fmt.Errorf("failed to load api key: %w", err)
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, ErrAPIKeyExpired
+	}
+	if !s.allow(&key, now) {
+		return &key, ErrAPIKeyRateLimited
+	}
+
+	// Last-used is only written once per resolution window to keep writes off the hot path
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.config.APIKeyLastUsedResolution || key.LastUsedIP != clientIP {
+		s.db.Model(&models.APIKey{}).Where("id = ?", key.ID).
+			Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": clientIP})
+		key.LastUsedAt = &now
+		key.LastUsedIP = clientIP
+	}
+	return &key, nil
+}
+
+// allow counts the request against the key's per-minute limit. Counters live in process
+// memory, so with several replicas each enforces the limit separately.
+func (s *APIKeyService) allow(key *models.APIKey, now time.Time) bool {
+	if key.RateLimitPerMinute <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window, ok := s.windows[key.ID]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &apiKeyWindow{start: now}
+		s.windows[key.ID] = window
+	}
+	if window.count >= key.RateLimitPerMinute {
+		return false
+	}
+	window.count++
+	return true
+}
+
+// HasScope reports whether the key was granted scope
+func HasScope(key *models.APIKey, scope string) bool {
+	for _, granted := range key.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyInput)
+	}
+
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if _, ok := APIKeyScopes[scope]; !ok {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyInput, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func generateAPIKey() (string, string, error) {
+	buf := make([]byte, 28)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	encoded := hex.EncodeToString(buf)
+	return encoded[:8], encoded[8:], nil
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,500 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/models"
+)
+
+var (
+	ErrInvalidAuditEntry = errors.New("invalid audit entry")
+	ErrAuditChainEmpty   = errors.New("audit chain is empty")
+	ErrAuditChainBroken  = errors.New("audit chain is broken")
+	ErrAuditAnchorFailed = errors.New("failed to anchor audit chain")
+)
+
+// auditChainLockKey is the PostgreSQL advisory lock that serialises appends to the
+// audit chain across service replicas
+const auditChainLockKey = 0x61756474 // "audt"
+
+// auditVerifyBatchSize is how many entries are loaded at a time while walking the chain
+const auditVerifyBatchSize = 1000
+
+// auditAnchorSource identifies this service's chain among the anchors on the ledger
+const auditAnchorSource = "bank-integration-service"
+
+// AuditEntry is an event to append to the audit trail. Details and states may be JSON
+// strings or any value that marshals to JSON.
+type AuditEntry struct {
+	EntityType  string
+	EntityID    uuid.UUID
+	Action      string
+	UserID      uuid.UUID
+	IPAddress   string
+	UserAgent   string
+	Details     interface{}
+	BeforeState interface{}
+	AfterState  interface{}
+}
+
+// AuditTrailFilter narrows ListTrails
+type AuditTrailFilter struct {
+	EntityType string
+	EntityID   *uuid.UUID
+	UserID     *uuid.UUID
+	Action     string
+	From       *time.Time
+	To         *time.Time
+	Page       int
+	Limit      int
+}
+
+// AuditChainBreak describes the first entry where the chain no longer verifies
+type AuditChainBreak struct {
+	Sequence int64      `json:"sequence"`
+	EntryID  *uuid.UUID `json:"entry_id,omitempty"`
+	Reason   string     `json:"reason"` // sequence_gap, prev_hash_mismatch, hash_mismatch, anchor_mismatch, truncated, unchained_entry, ledger_anchor_mismatch, ledger_unverified
+	Expected string     `json:"expected,omitempty"`
+	Actual   string     `json:"actual,omitempty"`
+}
+
+// AuditChainReport is the outcome of walking the audit chain
+type AuditChainReport struct {
+	Valid          bool                `json:"valid"`
+	EntriesChecked int64               `json:"entries_checked"`
+	HeadSequence   int64               `json:"head_sequence"`
+	HeadHash       string              `json:"head_hash"`
+	LastAnchor     *models.AuditAnchor `json:"last_anchor,omitempty"`
+	FirstBreak     *AuditChainBreak    `json:"first_break,omitempty"`
+	VerifiedAt     time.Time           `json:"verified_at"`
+}
+
+// Record appends an entry to the hash-chained audit trail
+func (s *AuditService) Record(entry AuditEntry) (*models.AuditTrail, error) {
+	if entry.EntityType == "" || entry.Action == "" {
+		return nil, fmt.Errorf("%w: entity_type and action are required", ErrInvalidAuditEntry)
+	}
+
+	trail := &models.AuditTrail{
+		ID:          uuid.New(),
+		EntityType:  entry.EntityType,
+		EntityID:    entry.EntityID,
+		Action:      entry.Action,
+		UserID:      entry.UserID,
+		IPAddress:   entry.IPAddress,
+		UserAgent:   entry.UserAgent,
+		Details:     auditJSON(entry.Details),
+		BeforeState: auditJSON(entry.BeforeState),
+		AfterState:  auditJSON(entry.AfterState),
+		Timestamp:   time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+				return err
+			}
+		}
+
+		var head []models.AuditTrail
+		if err := tx.Select("sequence", "hash").Order("sequence DESC").Limit(1).Find(&head).Error; err != nil {
+			return err
+		}
+		trail.Sequence = 1
+		trail.PrevHash = models.AuditGenesisHash
+		if len(head) > 0 {
+			trail.Sequence = head[0].Sequence + 1
+			trail.PrevHash = head[0].Hash
+		}
+		trail.Hash = trail.ComputeHash()
+
+		return tx.Create(trail).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record audit trail: %w", err)
+	}
+	return trail, nil
+}
+
+// auditJSON converts a value to the JSON text stored in a json column. Strings that are
+// already JSON are kept as they are.
+func auditJSON(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if v == "" {
+			return "null"
+		}
+		if json.Valid([]byte(v)) {
+			return v
+		}
+	case []byte:
+		if json.Valid(v) {
+			return string(v)
+		}
+		return toJSON(string(v))
+	case json.RawMessage:
+		if len(v) == 0 {
+			return "null"
+		}
+		return string(v)
+	}
+	return toJSON(value)
+}
+
+// ListTrails returns audit entries, newest first
+func (s *AuditService) ListTrails(filter AuditTrailFilter) ([]models.AuditTrail, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 500 {
+		filter.Limit = 50
+	}
+
+	query := s.db.Model(&models.AuditTrail{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("timestamp >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("timestamp < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit trails: %w", err)
+	}
+	var trails []models.AuditTrail
+	if err := query.Order("sequence DESC").Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&trails).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit trails: %w", err)
+	}
+	return trails, total, nil
+}
+
+// VerifyChain walks the whole audit chain from the first entry, recomputing every hash,
+// and reports the first broken link. Anchored chain heads must also still match, which
+// catches a chain that was rewritten and re-hashed end to end. The anchors themselves
+// are checked against the Fabric ledger, so rewriting audit_anchors along with the chain
+// is caught too; a chain whose anchors cannot be confirmed is not reported valid.
+func (s *AuditService) VerifyChain() (*AuditChainReport, error) {
+	report, err := s.verifyFrom(0, models.AuditGenesisHash)
+	if err != nil || !report.Valid {
+		return report, err
+	}
+
+	var anchors []models.AuditAnchor
+	if err := s.db.Where("status = ?", "anchored").Order("sequence ASC").Find(&anchors).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audit anchors: %w", err)
+	}
+	for i := range anchors {
+		if brk := s.checkLedgerAnchor(&anchors[i]); brk != nil {
+			report.Valid = false
+			report.FirstBreak = brk
+			break
+		}
+	}
+	return report, nil
+}
+
+// checkLedgerAnchor compares a local anchor with the one recorded on the ledger
+func (s *AuditService) checkLedgerAnchor(anchor *models.AuditAnchor) *AuditChainBreak {
+	var onLedger struct {
+		Sequence int64  `json:"sequence"`
+		Hash     string `json:"hash"`
+		TxID     string `json:"tx_id"`
+	}
+	if err := s.queryLedger("GetAuditAnchor", []interface{}{auditAnchorSource, fmt.Sprint(anchor.Sequence)}, &onLedger); err != nil {
+		return &AuditChainBreak{Sequence: anchor.Sequence, Reason: "ledger_unverified", Expected: anchor.Hash, Actual: err.Error()}
+	}
+	if onLedger.Sequence != anchor.Sequence || onLedger.Hash != anchor.Hash {
+		return &AuditChainBreak{Sequence: anchor.Sequence, Reason: "ledger_anchor_mismatch", Expected: onLedger.Hash, Actual: anchor.Hash}
+	}
+	if onLedger.TxID != "" && anchor.FabricTxID != "" && onLedger.TxID != anchor.FabricTxID {
+		return &AuditChainBreak{Sequence: anchor.Sequence, Reason: "ledger_anchor_mismatch", Expected: onLedger.TxID, Actual: anchor.FabricTxID}
+	}
+	return nil
+}
+
+func (s *AuditService) verifyFrom(sequence int64, prevHash string) (*AuditChainReport, error) {
+	var anchors []models.AuditAnchor
+	if err := s.db.Where("status = ?", "anchored").Order("sequence ASC").Find(&anchors).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audit anchors: %w", err)
+	}
+	anchored := make(map[int64]string, len(anchors))
+	for _, anchor := range anchors {
+		anchored[anchor.Sequence] = anchor.Hash
+	}
+
+	report := &AuditChainReport{Valid: true, HeadSequence: sequence, HeadHash: prevHash, VerifiedAt: time.Now()}
+	if len(anchors) > 0 {
+		report.LastAnchor = &anchors[len(anchors)-1]
+	}
+
+	// Rows outside the sequence would otherwise never be walked
+	var unchained []models.AuditTrail
+	if err := s.db.Where("sequence < 1 OR hash = ''").Order("timestamp ASC").Limit(1).Find(&unchained).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audit trails: %w", err)
+	}
+	if len(unchained) > 0 {
+		report.Valid = false
+		report.FirstBreak = &AuditChainBreak{Sequence: unchained[0].Sequence, EntryID: &unchained[0].ID, Reason: "unchained_entry", Actual: unchained[0].Hash}
+		return report, nil
+	}
+
+	for report.Valid {
+		var batch []models.AuditTrail
+		if err := s.db.Where("sequence > ?", report.HeadSequence).Order("sequence ASC").Limit(auditVerifyBatchSize).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to load audit trails: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			entry := &batch[i]
+			if brk := checkAuditLink(entry, report.HeadSequence, report.HeadHash, anchored); brk != nil {
+				report.Valid = false
+				report.FirstBreak = brk
+				break
+			}
+			report.EntriesChecked++
+			report.HeadSequence = entry.Sequence
+			report.HeadHash = entry.Hash
+		}
+	}
+
+	if report.Valid && report.LastAnchor != nil && report.LastAnchor.Sequence > report.HeadSequence {
+		report.Valid = false
+		report.FirstBreak = &AuditChainBreak{
+			Sequence: report.HeadSequence + 1,
+			Reason:   "truncated",
+			Expected: fmt.Sprintf("entries up to anchored sequence %d", report.LastAnchor.Sequence),
+			Actual:   fmt.Sprintf("chain ends at sequence %d", report.HeadSequence),
+		}
+	}
+	return report, nil
+}
+
+func checkAuditLink(entry *models.AuditTrail, prevSequence int64, prevHash string, anchored map[int64]string) *AuditChainBreak {
+	id := entry.ID
+	switch {
+	case entry.Sequence != prevSequence+1:
+		return &AuditChainBreak{
+			Sequence: prevSequence + 1,
+			Reason:   "sequence_gap",
+			Expected: fmt.Sprint(prevSequence + 1),
+			Actual:   fmt.Sprint(entry.Sequence),
+		}
+	case entry.PrevHash != prevHash:
+		return &AuditChainBreak{Sequence: entry.Sequence, EntryID: &id, Reason: "prev_hash_mismatch", Expected: prevHash, Actual: entry.PrevHash}
+	}
+	if computed := entry.ComputeHash(); computed != entry.Hash {
+		return &AuditChainBreak{Sequence: entry.Sequence, EntryID: &id, Reason: "hash_mismatch", Expected: computed, Actual: entry.Hash}
+	}
+	if hash, ok := anchored[entry.Sequence]; ok && hash != entry.Hash {
+		return &AuditChainBreak{Sequence: entry.Sequence, EntryID: &id, Reason: "anchor_mismatch", Expected: hash, Actual: entry.Hash}
+	}
+	return nil
+}
+
+// AnchorHead publishes the current chain head to the Fabric ledger. The entries added
+// since the previous anchor are verified first so a broken chain is never anchored.
+func (s *AuditService) AnchorHead() (*models.AuditAnchor, error) {
+	var last models.AuditAnchor
+	from, fromHash := int64(0), models.AuditGenesisHash
+	err := s.db.Where("status = ?", "anchored").Order("sequence DESC").First(&last).Error
+	switch {
+	case err == nil:
+		// The next anchor chains from this one, so it must be the one on the ledger
+		if brk := s.checkLedgerAnchor(&last); brk != nil {
+			return nil, fmt.Errorf("%w at sequence %d: %s", ErrAuditChainBroken, brk.Sequence, brk.Reason)
+		}
+		from, fromHash = last.Sequence, last.Hash
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to get last audit anchor: %w", err)
+	}
+
+	report, err := s.verifyFrom(from, fromHash)
+	if err != nil {
+		return nil, err
+	}
+	if !report.Valid {
+		return nil, fmt.Errorf("%w at sequence %d: %s", ErrAuditChainBroken, report.FirstBreak.Sequence, report.FirstBreak.Reason)
+	}
+	if report.HeadSequence == 0 {
+		return nil, ErrAuditChainEmpty
+	}
+	if report.HeadSequence == from {
+		return &last, nil // nothing appended since the last anchor
+	}
+
+	anchor := &models.AuditAnchor{
+		Sequence: report.HeadSequence,
+		Hash:     report.HeadHash,
+		Status:   "anchored",
+	}
+	txID, anchorErr := s.invokeLedger("AnchorAuditChain", map[string]interface{}{
+		"source":     auditAnchorSource,
+		"sequence":   anchor.Sequence,
+		"hash":       anchor.Hash,
+		"prev_hash":  fromHash,
+		"entries":    report.EntriesChecked,
+		"created_at": time.Now().UTC(),
+	})
+	if anchorErr != nil {
+		anchor.Status = "failed"
+		anchor.Error = anchorErr.Error()
+	} else {
+		now := time.Now()
+		anchor.FabricTxID = txID
+		anchor.AnchoredAt = &now
+	}
+
+	if err := s.db.Create(anchor).Error; err != nil {
+		return nil, fmt.Errorf("failed to save audit anchor: %w", err)
+	}
+	if anchorErr != nil {
+		return anchor, fmt.Errorf("%w: %v", ErrAuditAnchorFailed, anchorErr)
+	}
+	return anchor, nil
+}
+
+// invokeLedger submits a chaincode transaction through the blockchain ledger service
+// and returns its transaction ID
+func (s *AuditService) invokeLedger(function string, arg interface{}) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"function":       function,
+		"args":           []interface{}{arg},
+		"chaincode_name": s.config.FabricChaincodeName,
+		"channel_name":   s.config.FabricChannelName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := s.client.Post(s.config.FabricLedgerServiceURL+"/api/v1/blockchain/chaincode/invoke", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to reach ledger service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ledger response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("chaincode invocation failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to unmarshal ledger response: %w", err)
+	}
+	if result.TransactionID == "" {
+		return "", fmt.Errorf("ledger response has no transaction ID")
+	}
+	return result.TransactionID, nil
+}
+
+// queryLedger evaluates a chaincode function through the blockchain ledger service
+// without submitting a transaction, decoding its result into out
+func (s *AuditService) queryLedger(function string, args []interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"function":       function,
+		"args":           args,
+		"chaincode_name": s.config.FabricChaincodeName,
+		"channel_name":   s.config.FabricChannelName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := s.client.Post(s.config.FabricLedgerServiceURL+"/api/v1/blockchain/chaincode/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach ledger service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read ledger response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("chaincode query failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	// The ledger service answers with the chaincode result, either bare or under "result"
+	var wrapped struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &wrapped); err == nil && len(wrapped.Result) > 0 && string(wrapped.Result) != "null" {
+		respBody = wrapped.Result
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal ledger response: %w", err)
+	}
+	return nil
+}
+
+// ListAnchors returns the most recent anchoring attempts
+func (s *AuditService) ListAnchors(limit int) ([]models.AuditAnchor, error) {
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	var anchors []models.AuditAnchor
+	if err := s.db.Order("created_at DESC").Limit(limit).Find(&anchors).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit anchors: %w", err)
+	}
+	return anchors, nil
+}
+
+// StartAnchorScheduler anchors the chain head every AuditAnchorInterval until ctx is
+// cancelled
+func (s *AuditService) StartAnchorScheduler(ctx context.Context) {
+	ticker := time.NewTicker(s.config.AuditAnchorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			anchor, err := s.AnchorHead()
+			switch {
+			case errors.Is(err, ErrAuditChainEmpty):
+			case err != nil:
+				log.Printf("Audit chain anchoring failed: %v", err)
+			default:
+				log.Printf("Anchored audit chain at sequence %d (tx %s)", anchor.Sequence, anchor.FabricTxID)
+			}
+		}
+	}
+}
@@ -0,0 +1,753 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// Credit decision outcomes produced by the rules engine
+const (
+	CreditOutcomeApprove = "approve"
+	CreditOutcomeDecline = "decline"
+	CreditOutcomeRefer   = "refer"
+)
+
+var (
+	ErrCreditDecisionNotFound = errors.New("credit decision not found")
+	ErrCreditDecisionExpired  = errors.New("credit decision has expired")
+	ErrCreditDecisionFinal    = errors.New("credit decision is no longer open for review")
+	ErrCreditDecisionOwn      = errors.New("a credit decision cannot be approved by the user who requested it")
+)
+
+// CreditRuleSet is the YAML document that drives credit decisions
+type CreditRuleSet struct {
+	Version      string        `yaml:"version"`
+	ValidityDays int           `yaml:"validity_days"`
+	BaseRate     float64       `yaml:"base_rate"`
+	DefaultTerm  int           `yaml:"default_term"`
+	Pricing      []PricingTier `yaml:"pricing"`
+	Rules        []CreditRule  `yaml:"rules"`
+}
+
+// PricingTier sets the base rate for risk scores up to MaxRiskScore
+type PricingTier struct {
+	MaxRiskScore float64 `yaml:"max_risk_score"`
+	Rate         float64 `yaml:"rate"`
+}
+
+// CreditRule applies its effects when all of its conditions hold
+type CreditRule struct {
+	Name           string          `yaml:"name"`
+	Description    string          `yaml:"description"`
+	When           []RuleCondition `yaml:"when"`
+	Outcome        string          `yaml:"outcome"`
+	Reason         string          `yaml:"reason"`
+	CapField       string          `yaml:"cap_field"`
+	CapFactor      float64         `yaml:"cap_factor"`
+	RateAdjustment float64         `yaml:"rate_adjustment"`
+	MaxTerm        int             `yaml:"max_term"`
+	Condition      string          `yaml:"condition"`
+}
+
+// RuleCondition compares a fact against a value
+type RuleCondition struct {
+	Field string      `yaml:"field"`
+	Op    string      `yaml:"op"` // eq, ne, gt, gte, lt, lte, in, not_in
+	Value interface{} `yaml:"value"`
+}
+
+// CreditDecisionRequest holds the inputs to a credit decision. The customer is the caller;
+// KYC standing, annual revenue, risk score, repayment history and existing exposure are
+// always loaded server side.
+type CreditDecisionRequest struct {
+	CustomerID       uuid.UUID `json:"-"`
+	BankConnectionID uuid.UUID `json:"bank_connection_id" binding:"required"`
+	RequestType      string    `json:"request_type" binding:"required"`
+	RequestedAmount  float64   `json:"requested_amount" binding:"required"`
+	Term             int       `json:"term"`
+	RequestedBy      string    `json:"-"`
+}
+
+// PaymentHistorySummary summarises a customer's repayment record
+type PaymentHistorySummary struct {
+	Settled        int     `json:"settled"` // Financings repaid, defaulted or overdue
+	OnTimeRate     float64 `json:"on_time_rate"`
+	LatePayments   int     `json:"late_payments"`
+	Defaults       int     `json:"defaults"`
+	MaxDaysPastDue int     `json:"max_days_past_due"`
+}
+
+// DecisionTraceEntry records why the engine reached its outcome
+type DecisionTraceEntry struct {
+	Rule    string `json:"rule"`
+	Matched bool   `json:"matched"`
+	Effect  string `json:"effect,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// CreditDecisionService evaluates credit requests against a YAML rule set
+type CreditDecisionService struct {
+	db     *gorm.DB
+	config *config.Config
+	kyc    *KYCClient
+
+	mu    sync.RWMutex
+	rules *CreditRuleSet
+}
+
+func NewCreditDecisionService(db *gorm.DB, cfg *config.Config) *CreditDecisionService {
+	s := &CreditDecisionService{db: db, config: cfg, kyc: NewKYCClient(cfg)}
+	if err := s.ReloadRules(); err != nil {
+		// Without rules every request is referred to an underwriter
+		log.Printf("Credit decision rules not loaded: %v", err)
+	}
+	return s
+}
+
+// ReloadRules reads the rule set from CreditRulesPath and swaps it in
+func (s *CreditDecisionService) ReloadRules() error {
+	rules, err := LoadCreditRuleSet(s.config.CreditRulesPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.mu.Unlock()
+	return nil
+}
+
+// LoadCreditRuleSet parses and validates a rule set file
+func LoadCreditRuleSet(path string) (*CreditRuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credit rules: %w", err)
+	}
+
+	var rules CreditRuleSet
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse credit rules: %w", err)
+	}
+
+	for i, rule := range rules.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("credit rule %d has no name", i)
+		}
+		if rule.Outcome != "" && rule.Outcome != CreditOutcomeDecline && rule.Outcome != CreditOutcomeRefer {
+			return nil, fmt.Errorf("credit rule %s has invalid outcome %q", rule.Name, rule.Outcome)
+		}
+		if rule.CapField != "" && rule.CapFactor <= 0 {
+			return nil, fmt.Errorf("credit rule %s has cap_field without a positive cap_factor", rule.Name)
+		}
+		for _, cond := range rule.When {
+			switch cond.Op {
+			case "eq", "ne", "gt", "gte", "lt", "lte", "in", "not_in":
+			default:
+				return nil, fmt.Errorf("credit rule %s has invalid operator %q", rule.Name, cond.Op)
+			}
+		}
+	}
+
+	sort.Slice(rules.Pricing, func(i, j int) bool {
+		return rules.Pricing[i].MaxRiskScore < rules.Pricing[j].MaxRiskScore
+	})
+	if rules.ValidityDays <= 0 {
+		rules.ValidityDays = 30
+	}
+
+	return &rules, nil
+}
+
+// RequestDecision gathers facts for the request, runs the rules and stores the decision.
+// Credit is requested through one of the customer's own bank connections.
+func (s *CreditDecisionService) RequestDecision(req CreditDecisionRequest) (*models.CreditDecision, error) {
+	var connections int64
+	if err := s.db.Model(&models.BankConnection{}).
+		Where("id = ? AND user_id = ?", req.BankConnectionID, req.CustomerID).
+		Count(&connections).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bank connection: %w", err)
+	}
+	if connections == 0 {
+		return nil, ErrConnectionNotOwned
+	}
+
+	facts, trace, err := s.gatherFacts(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	rules := s.rules
+	s.mu.RUnlock()
+
+	outcome := CreditOutcomeApprove
+	approvedAmount := req.RequestedAmount
+	term := req.Term
+	rate := 0.0
+	var conditions []string
+	ruleSetVersion := ""
+
+	if rules == nil {
+		outcome = CreditOutcomeRefer
+		trace = append(trace, DecisionTraceEntry{Rule: "rule_set", Matched: true, Effect: "refer", Reason: "No credit rule set is loaded"})
+	} else {
+		ruleSetVersion = rules.Version
+		rate = rules.priceFor(facts["risk_score"])
+		if term <= 0 {
+			term = rules.DefaultTerm
+		}
+
+		for _, rule := range rules.Rules {
+			if !rule.matches(facts) {
+				trace = append(trace, DecisionTraceEntry{Rule: rule.Name, Matched: false})
+				continue
+			}
+
+			var effects []string
+			if rule.Outcome == CreditOutcomeDecline || rule.Outcome == CreditOutcomeRefer && outcome == CreditOutcomeApprove {
+				outcome = rule.Outcome
+			}
+			if rule.Outcome != "" {
+				effects = append(effects, rule.Outcome)
+			}
+			if rule.CapField != "" {
+				if base, ok := toFloat(facts[rule.CapField]); ok {
+					limit := roundTo(base*rule.CapFactor, 2)
+					if limit < approvedAmount {
+						approvedAmount = limit
+						effects = append(effects, fmt.Sprintf("cap_amount:%.2f", limit))
+					}
+				}
+			}
+			if rule.RateAdjustment != 0 {
+				rate += rule.RateAdjustment
+				effects = append(effects, fmt.Sprintf("rate%+.2f", rule.RateAdjustment))
+			}
+			if rule.MaxTerm > 0 && (term <= 0 || term > rule.MaxTerm) {
+				term = rule.MaxTerm
+				effects = append(effects, fmt.Sprintf("max_term:%d", rule.MaxTerm))
+			}
+			if rule.Condition != "" {
+				conditions = append(conditions, rule.Condition)
+				effects = append(effects, "condition")
+			}
+
+			trace = append(trace, DecisionTraceEntry{
+				Rule:    rule.Name,
+				Matched: true,
+				Effect:  strings.Join(effects, ","),
+				Reason:  rule.Reason,
+			})
+		}
+	}
+
+	if term <= 0 {
+		term = s.config.DefaultCreditTerms
+	}
+
+	// No score means the risk-based rules cannot be trusted, so force a human review
+	if available, _ := facts["risk_score_available"].(bool); !available && outcome == CreditOutcomeApprove {
+		outcome = CreditOutcomeRefer
+		trace = append(trace, DecisionTraceEntry{Rule: "risk_score", Matched: true, Effect: "refer", Reason: "No current credit risk assessment"})
+	}
+	// Likewise a customer without a repayment record is not treated as a clean one
+	if available, _ := facts["payment_history_available"].(bool); !available && outcome == CreditOutcomeApprove {
+		outcome = CreditOutcomeRefer
+		trace = append(trace, DecisionTraceEntry{Rule: "payment_history", Matched: true, Effect: "refer", Reason: "No repayment history with the platform"})
+	}
+	// and without verified revenue the revenue cap and exposure rules have nothing to
+	// measure against
+	if available, _ := facts["annual_revenue_available"].(bool); !available && outcome == CreditOutcomeApprove {
+		outcome = CreditOutcomeRefer
+		trace = append(trace, DecisionTraceEntry{Rule: "annual_revenue", Matched: true, Effect: "refer", Reason: "No verified annual revenue on file"})
+	}
+
+	// Platform limits always apply, whatever the rule set says
+	if req.RequestedAmount < s.config.MinCreditAmount {
+		outcome = CreditOutcomeDecline
+		trace = append(trace, DecisionTraceEntry{Rule: "min_credit_amount", Matched: true, Effect: "decline",
+			Reason: fmt.Sprintf("Requested amount is below the minimum of %.2f", s.config.MinCreditAmount)})
+	}
+	if approvedAmount > s.config.MaxCreditAmount {
+		approvedAmount = s.config.MaxCreditAmount
+		trace = append(trace, DecisionTraceEntry{Rule: "max_credit_amount", Matched: true,
+			Effect: fmt.Sprintf("cap_amount:%.2f", s.config.MaxCreditAmount), Reason: "Amount capped at the platform maximum"})
+	}
+	if outcome != CreditOutcomeDecline && approvedAmount < s.config.MinCreditAmount {
+		outcome = CreditOutcomeDecline
+		trace = append(trace, DecisionTraceEntry{Rule: "min_credit_amount", Matched: true, Effect: "decline",
+			Reason: fmt.Sprintf("Capped amount %.2f is below the minimum of %.2f", approvedAmount, s.config.MinCreditAmount)})
+	}
+
+	now := time.Now()
+	decision := &models.CreditDecision{
+		CustomerID:       req.CustomerID,
+		BankConnectionID: req.BankConnectionID,
+		RequestType:      req.RequestType,
+		RequestedAmount:  req.RequestedAmount,
+		Term:             term,
+		Decision:         outcome,
+		RiskScore:        facts["risk_score"].(float64),
+		RuleSetVersion:   ruleSetVersion,
+		RequestedBy:      req.RequestedBy,
+		DecidedBy:        "rules_engine",
+		DecisionDate:     &now,
+	}
+
+	switch outcome {
+	case CreditOutcomeApprove:
+		decision.Status = "approved"
+	case CreditOutcomeRefer:
+		decision.Status = "referred"
+	default:
+		decision.Status = "rejected"
+		approvedAmount = 0
+		rate = 0
+		conditions = nil
+	}
+	decision.ApprovedAmount = approvedAmount
+	decision.InterestRate = roundTo(rate, 2)
+
+	if outcome != CreditOutcomeDecline {
+		validityDays := 30
+		if rules != nil {
+			validityDays = rules.ValidityDays
+		}
+		expiry := now.AddDate(0, 0, validityDays)
+		decision.ExpiryDate = &expiry
+	}
+
+	if conditions == nil {
+		conditions = []string{}
+	}
+	conditionsJSON, _ := json.Marshal(conditions)
+	factsJSON, _ := json.Marshal(facts)
+	traceJSON, _ := json.Marshal(trace)
+	decision.Conditions = string(conditionsJSON)
+	decision.RiskFactors = string(factsJSON)
+	decision.ReasonTrace = string(traceJSON)
+
+	if err := s.db.Create(decision).Error; err != nil {
+		return nil, fmt.Errorf("failed to save credit decision: %w", err)
+	}
+	return decision, nil
+}
+
+// gatherFacts builds the fact map the rules are evaluated against from the customer's KYC
+// and KYB, latest risk assessment and portfolio record
+func (s *CreditDecisionService) gatherFacts(req CreditDecisionRequest) (map[string]interface{}, []DecisionTraceEntry, error) {
+	var trace []DecisionTraceEntry
+
+	standing, err := s.kyc.Standing(req.CustomerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	annualRevenue, err := s.kyc.VerifiedRevenue(req.CustomerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exposure := 0.0
+	if err := s.db.Model(&models.PortfolioItem{}).
+		Where("customer_id = ? AND status = ?", req.CustomerID, "active").
+		Select("COALESCE(SUM(outstanding), 0)").Scan(&exposure).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load existing exposure: %w", err)
+	}
+
+	history, err := s.paymentHistory(req.CustomerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if history.Settled == 0 {
+		trace = append(trace, DecisionTraceEntry{Rule: "payment_history", Matched: true, Reason: "No settled or overdue financing on record"})
+	}
+
+	riskScore := -1.0
+	var assessment models.RiskAssessment
+	err = s.db.Where("entity_type = ? AND entity_id = ? AND assessment_type = ? AND (expiry_date IS NULL OR expiry_date > ?)",
+		"customer", req.CustomerID, "credit", time.Now()).
+		Order("assessed_at DESC").First(&assessment).Error
+	if err == nil {
+		riskScore = assessment.RiskScore
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, fmt.Errorf("failed to load risk assessment: %w", err)
+	}
+
+	totalExposure := exposure + req.RequestedAmount
+	exposureToRevenue := 0.0
+	if annualRevenue > 0 {
+		exposureToRevenue = roundTo(totalExposure/annualRevenue, 4)
+	}
+
+	facts := map[string]interface{}{
+		"requested_amount":          req.RequestedAmount,
+		"term":                      float64(req.Term),
+		"request_type":              req.RequestType,
+		"annual_revenue":            annualRevenue,
+		"annual_revenue_available":  annualRevenue > 0,
+		"existing_exposure":         exposure,
+		"total_exposure":            totalExposure,
+		"exposure_to_revenue":       exposureToRevenue,
+		"risk_score":                riskScore,
+		"kyc_status":                standing.Fact(),
+		"on_time_payment_rate":      history.OnTimeRate,
+		"late_payments":             float64(history.LatePayments),
+		"defaults":                  float64(history.Defaults),
+		"max_days_past_due":         float64(history.MaxDaysPastDue),
+		"payment_history_available": history.Settled > 0,
+	}
+
+	if riskScore < 0 {
+		facts["risk_score"] = 0.0
+		facts["risk_score_available"] = false
+	} else {
+		facts["risk_score_available"] = true
+	}
+
+	return facts, trace, nil
+}
+
+// paymentHistory summarises how the customer repaid earlier financing. Matured items
+// count as on time when the last payment came by the maturity date; active items past
+// their due date count as late.
+func (s *CreditDecisionService) paymentHistory(customerID uuid.UUID) (*PaymentHistorySummary, error) {
+	var items []models.PortfolioItem
+	if err := s.db.Where("customer_id = ? AND status IN ?", customerID, []string{"active", "matured", "defaulted", "written_off"}).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to load payment history: %w", err)
+	}
+
+	now := time.Now()
+	history := &PaymentHistorySummary{}
+	onTime := 0
+	for _, item := range items {
+		daysPastDue := 0
+		switch item.Status {
+		case "defaulted", "written_off":
+			history.Defaults++
+			if item.MaturityDate.Before(now) {
+				daysPastDue = int(now.Sub(item.MaturityDate).Hours() / 24)
+			}
+		case "matured":
+			if item.LastPaymentDate == nil || !item.LastPaymentDate.After(item.MaturityDate) {
+				onTime++
+			} else {
+				history.LatePayments++
+				daysPastDue = int(item.LastPaymentDate.Sub(item.MaturityDate).Hours() / 24)
+			}
+		default:
+			due := item.MaturityDate
+			if item.NextPaymentDue != nil {
+				due = *item.NextPaymentDue
+			}
+			if !due.Before(now) {
+				continue
+			}
+			history.LatePayments++
+			daysPastDue = int(now.Sub(due).Hours() / 24)
+		}
+		history.Settled++
+		if daysPastDue > history.MaxDaysPastDue {
+			history.MaxDaysPastDue = daysPastDue
+		}
+	}
+
+	if history.Settled > 0 {
+		history.OnTimeRate = roundTo(float64(onTime)/float64(history.Settled), 4)
+	}
+	return history, nil
+}
+
+// GetDecision returns a decision. Customers can only see their own; reviewers see all.
+func (s *CreditDecisionService) GetDecision(id uuid.UUID, actorID string, isReviewer bool) (*models.CreditDecision, error) {
+	decision, err := s.loadDecision(id)
+	if err != nil {
+		return nil, err
+	}
+	if !isReviewer && decision.CustomerID.String() != actorID {
+		return nil, ErrCreditDecisionNotFound
+	}
+	return decision, nil
+}
+
+// loadDecision returns a decision, expiring it first if it is past its expiry date
+func (s *CreditDecisionService) loadDecision(id uuid.UUID) (*models.CreditDecision, error) {
+	var decision models.CreditDecision
+	if err := s.db.First(&decision, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCreditDecisionNotFound
+		}
+		return nil, err
+	}
+
+	if isExpirable(decision.Status) && decision.ExpiryDate != nil && decision.ExpiryDate.Before(time.Now()) {
+		decision.Status = "expired"
+		if err := s.db.Model(&decision).Update("status", "expired").Error; err != nil {
+			return nil, err
+		}
+	}
+	return &decision, nil
+}
+
+// ListDecisions returns decisions, optionally filtered by customer and status. Customers
+// only see their own, whatever customer they ask for.
+func (s *CreditDecisionService) ListDecisions(customerID *uuid.UUID, status string, limit, offset int, actorID string, isReviewer bool) ([]models.CreditDecision, int64, error) {
+	var decisions []models.CreditDecision
+	var total int64
+
+	query := s.db.Model(&models.CreditDecision{})
+	if !isReviewer {
+		query = query.Where("customer_id = ?", actorID)
+	} else if customerID != nil {
+		query = query.Where("customer_id = ?", *customerID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&decisions).Error; err != nil {
+		return nil, 0, err
+	}
+	return decisions, total, nil
+}
+
+// ApproveDecision lets an underwriter approve a decision the engine referred, on the
+// amount and rate the engine priced. A decision cannot be approved by its requester.
+func (s *CreditDecisionService) ApproveDecision(id uuid.UUID, reviewerID, notes string) (*models.CreditDecision, error) {
+	decision, err := s.reviewableDecision(id)
+	if err != nil {
+		return nil, err
+	}
+	if decision.RequestedBy != "" && decision.RequestedBy == reviewerID {
+		return nil, ErrCreditDecisionOwn
+	}
+	if decision.ApprovedAmount < s.config.MinCreditAmount || decision.ApprovedAmount > s.config.MaxCreditAmount {
+		return nil, fmt.Errorf("approved amount must be between %.2f and %.2f", s.config.MinCreditAmount, s.config.MaxCreditAmount)
+	}
+
+	now := time.Now()
+	trace := appendTrace(decision.ReasonTrace, DecisionTraceEntry{Rule: "manual_review", Matched: true, Effect: "approve", Reason: notes})
+
+	// The status condition stops two reviewers deciding the same decision
+	result := s.db.Model(&models.CreditDecision{}).
+		Where("id = ? AND status IN ?", decision.ID, reviewableStatuses).
+		Updates(map[string]interface{}{
+			"status":        "approved",
+			"decision":      CreditOutcomeApprove,
+			"decided_by":    reviewerID,
+			"decision_date": now,
+			"reason_trace":  trace,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to approve credit decision: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCreditDecisionFinal
+	}
+
+	decision.Status = "approved"
+	decision.Decision = CreditOutcomeApprove
+	decision.DecidedBy = reviewerID
+	decision.DecisionDate = &now
+	decision.ReasonTrace = trace
+	return decision, nil
+}
+
+// RejectDecision lets an underwriter decline a decision that is still open. Approved
+// decisions are final.
+func (s *CreditDecisionService) RejectDecision(id uuid.UUID, reviewerID, reason string) (*models.CreditDecision, error) {
+	decision, err := s.reviewableDecision(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	trace := appendTrace(decision.ReasonTrace, DecisionTraceEntry{Rule: "manual_review", Matched: true, Effect: "decline", Reason: reason})
+
+	// As with approvals, a decision another reviewer decided meanwhile is left alone
+	result := s.db.Model(&models.CreditDecision{}).
+		Where("id = ? AND status IN ?", decision.ID, reviewableStatuses).
+		Updates(map[string]interface{}{
+			"status":          "rejected",
+			"decision":        CreditOutcomeDecline,
+			"approved_amount": 0,
+			"decided_by":      reviewerID,
+			"decision_date":   now,
+			"reason_trace":    trace,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to reject credit decision: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCreditDecisionFinal
+	}
+
+	decision.Status = "rejected"
+	decision.Decision = CreditOutcomeDecline
+	decision.ApprovedAmount = 0
+	decision.DecidedBy = reviewerID
+	decision.DecisionDate = &now
+	decision.ReasonTrace = trace
+	return decision, nil
+}
+
+// reviewableStatuses are those of decisions waiting for an underwriter
+var reviewableStatuses = []string{"pending", "referred"}
+
+func (s *CreditDecisionService) reviewableDecision(id uuid.UUID) (*models.CreditDecision, error) {
+	decision, err := s.loadDecision(id)
+	if err != nil {
+		return nil, err
+	}
+	switch decision.Status {
+	case "expired":
+		return nil, ErrCreditDecisionExpired
+	case "pending", "referred":
+		return decision, nil
+	default:
+		return nil, ErrCreditDecisionFinal
+	}
+}
+
+// ExpireDecisions marks open decisions past their expiry date as expired
+func (s *CreditDecisionService) ExpireDecisions() (int64, error) {
+	result := s.db.Model(&models.CreditDecision{}).
+		Where("status IN ? AND expiry_date IS NOT NULL AND expiry_date < ?", []string{"pending", "referred", "approved"}, time.Now()).
+		Update("status", "expired")
+	return result.RowsAffected, result.Error
+}
+
+// StartExpiryScheduler expires stale decisions every hour until ctx is cancelled
+func (s *CreditDecisionService) StartExpiryScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if count, err := s.ExpireDecisions(); err != nil {
+			log.Printf("Credit decision expiry failed: %v", err)
+		} else if count > 0 {
+			log.Printf("Expired %d credit decisions", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// priceFor returns the base rate for a risk score from the pricing tiers
+func (r *CreditRuleSet) priceFor(score interface{}) float64 {
+	value, _ := toFloat(score)
+	for _, tier := range r.Pricing {
+		if value <= tier.MaxRiskScore {
+			return tier.Rate
+		}
+	}
+	return r.BaseRate
+}
+
+func (r CreditRule) matches(facts map[string]interface{}) bool {
+	for _, cond := range r.When {
+		if !cond.holds(facts[cond.Field]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c RuleCondition) holds(fact interface{}) bool {
+	switch c.Op {
+	case "in", "not_in":
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return false
+		}
+		found := false
+		for _, v := range values {
+			if strings.EqualFold(fmt.Sprint(v), fmt.Sprint(fact)) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == "in")
+	}
+
+	left, leftOK := toFloat(fact)
+	right, rightOK := toFloat(c.Value)
+	if !leftOK || !rightOK {
+		// Non-numeric facts only support equality
+		switch c.Op {
+		case "eq":
+			return strings.EqualFold(fmt.Sprint(fact), fmt.Sprint(c.Value))
+		case "ne":
+			return !strings.EqualFold(fmt.Sprint(fact), fmt.Sprint(c.Value))
+		}
+		return false
+	}
+
+	switch c.Op {
+	case "eq":
+		return left == right
+	case "ne":
+		return left != right
+	case "gt":
+		return left > right
+	case "gte":
+		return left >= right
+	case "lt":
+		return left < right
+	case "lte":
+		return left <= right
+	}
+	return false
+}
+
+func isExpirable(status string) bool {
+	return status == "pending" || status == "referred" || status == "approved"
+}
+
+func appendTrace(traceJSON string, entry DecisionTraceEntry) string {
+	var trace []DecisionTraceEntry
+	if traceJSON != "" {
+		json.Unmarshal([]byte(traceJSON), &trace)
+	}
+	trace = append(trace, entry)
+	updated, _ := json.Marshal(trace)
+	return string(updated)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return math.NaN(), false
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// Actions held for maker-checker approval
+const (
+	DualControlPayment      = "payment"
+	DualControlTransfer     = "transfer"
+	DualControlDisbursement = "disbursement"
+	DualControlCreditLimit  = "credit_limit"
+	DualControlBankAccount  = "bank_account"
+)
+
+// Approval statuses
+const (
+	DualControlPending   = "pending"
+	DualControlApproved  = "approved" // Confirmed; the initiator may now carry the request out
+	DualControlExecuted  = "executed"
+	DualControlFailed    = "failed" // Carried out, but the request was refused
+	DualControlRejected  = "rejected"
+	DualControlCancelled = "cancelled"
+	DualControlExpired   = "expired"
+)
+
+var (
+	ErrApprovalNotFound   = errors.New("approval not found")
+	ErrApprovalNotPending = errors.New("approval is no longer pending")
+	ErrApprovalExpired    = errors.New("approval window has passed")
+	ErrSelfApproval       = errors.New("the initiator cannot confirm their own request")
+	ErrAlreadyDecided     = errors.New("you have already decided on this request")
+	ErrNotChecker         = errors.New("your role may not confirm this request")
+	ErrNotInitiator       = errors.New("only the initiator can use or cancel this approval")
+	ErrApprovalMismatch   = errors.New("the request differs from the one that was approved")
+	ErrApprovalNotReady   = errors.New("approval has not been confirmed")
+)
+
+// DualControlRequest is a request to hold for confirmation
+type DualControlRequest struct {
+	Action      string
+	Method      string
+	Path        string
+	Body        []byte
+	Amount      float64
+	Currency    string
+	InitiatedBy string
+	Role        string
+	BankID      string
+}
+
+// DualControlChecker is the user confirming or refusing a request
+type DualControlChecker struct {
+	UserID    string
+	Role      string
+	BankID    string
+	IPAddress string
+	Comment   string
+}
+
+// DualControlFilter narrows ListApprovals
+type DualControlFilter struct {
+	Status      string
+	Action      string
+	InitiatedBy string
+	BankID      string
+	Page        int
+	Limit       int
+}
+
+// DualControlService holds payments, transfers, disbursements, credit limit and bank
+// account changes until a second authorized user confirms them. How many confirmations a
+// request needs depends on its action and amount.
+type DualControlService struct {
+	db           *gorm.DB
+	window       time.Duration
+	checkerRoles []string
+	tiers        map[string][]config.DualControlTier
+	currency     string
+	rates        map[string]float64
+}
+
+func NewDualControlService(db *gorm.DB, cfg *config.Config) *DualControlService {
+	return &DualControlService{
+		db:           db,
+		window:       cfg.DualControlWindow,
+		checkerRoles: cfg.DualControlCheckerRoles,
+		tiers:        cfg.DualControlTiers,
+		currency:     cfg.DualControlCurrency,
+		rates:        cfg.DualControlRates,
+	}
+}
+
+// CheckerRoles returns the roles that may confirm another user's request
+func (s *DualControlService) CheckerRoles() []string {
+	return s.checkerRoles
+}
+
+// RequiredApprovals returns how many users other than the initiator must confirm a
+// request of this amount. The amount is converted to the base currency the tiers are in;
+// one in a currency without a rate needs the highest tier. Requests below the lowest
+// tier need none.
+func (s *DualControlService) RequiredApprovals(action string, amount float64, currency string) int {
+	tiers := s.tiers[action]
+	base, ok := s.toBase(amount, currency)
+	if !ok {
+		if len(tiers) == 0 {
+			return 0
+		}
+		return tiers[len(tiers)-1].Approvals
+	}
+
+	required := 0
+	for _, tier := range tiers {
+		if base >= tier.MinAmount {
+			required = tier.Approvals
+		}
+	}
+	return required
+}
+
+// toBase converts an amount to the base currency. Requests without a currency are in the
+// base currency, as the models default to it.
+func (s *DualControlService) toBase(amount float64, currency string) (float64, bool) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == s.currency {
+		return amount, true
+	}
+	rate, ok := s.rates[currency]
+	if !ok {
+		return 0, false
+	}
+	return amount * rate, true
+}
+
+// MixedCurrency is reported by RequestAmount for bulk requests whose items are in
+// different currencies. It is the ISO 4217 code for no currency, so no rate converts it.
+const MixedCurrency = "XXX"
+
+// RequestAmount reads the amount of a request body: its amount, or for credit limit
+// changes its credit limit, plus the amounts of the items of bulk requests. Items
+// without a currency are in the request's.
+func RequestAmount(body []byte) (float64, string) {
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return 0, ""
+	}
+
+	currency, _ := request["currency"].(string)
+	total := 0.0
+	if amount, ok := request["amount"].(float64); ok {
+		total = amount
+	} else if limit, ok := request["credit_limit"].(float64); ok {
+		total = limit
+	}
+	for _, value := range request {
+		items, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			if fields, ok := item.(map[string]interface{}); ok {
+				if amount, ok := fields["amount"].(float64); ok {
+					total += amount
+				}
+				itemCurrency, _ := fields["currency"].(string)
+				switch {
+				case itemCurrency == "" || strings.EqualFold(itemCurrency, currency):
+				case currency == "":
+					currency = itemCurrency
+				default:
+					currency = MixedCurrency
+				}
+			}
+		}
+	}
+	return total, currency
+}
+
+func payloadHash(method, path string, body []byte) string {
+	sum := sha256.Sum256([]byte(method + " " + path + "\n" + string(body)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Initiate holds a request for confirmation
+func (s *DualControlService) Initiate(req DualControlRequest) (*models.DualControlApproval, error) {
+	required := s.RequiredApprovals(req.Action, req.Amount, req.Currency)
+	if required < 1 {
+		required = 1
+	}
+
+	approval := &models.DualControlApproval{
+		Action:            req.Action,
+		Method:            req.Method,
+		Path:              req.Path,
+		RequestBody:       string(req.Body),
+		PayloadHash:       payloadHash(req.Method, req.Path, req.Body),
+		Amount:            req.Amount,
+		Currency:          req.Currency,
+		RequiredApprovals: required,
+		InitiatedBy:       req.InitiatedBy,
+		InitiatorRole:     req.Role,
+		BankID:            req.BankID,
+		Status:            DualControlPending,
+		ExpiresAt:         time.Now().Add(s.window),
+	}
+	if err := s.db.Create(approval).Error; err != nil {
+		return nil, fmt.Errorf("failed to create approval: %w", err)
+	}
+	return approval, nil
+}
+
+// GetApproval returns an approval with its decisions
+func (s *DualControlService) GetApproval(id uuid.UUID) (*models.DualControlApproval, error) {
+	if err := s.ExpireStale(); err != nil {
+		return nil, err
+	}
+	var approval models.DualControlApproval
+	err := s.db.Preload("Decisions").Where("id = ?", id).First(&approval).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrApprovalNotFound
+	}
+	return &approval, err
+}
+
+// ListApprovals returns approvals, newest first
+func (s *DualControlService) ListApprovals(filter DualControlFilter) ([]models.DualControlApproval, int64, error) {
+	if err := s.ExpireStale(); err != nil {
+		return nil, 0, err
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	query := s.db.Model(&models.DualControlApproval{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.InitiatedBy != "" {
+		query = query.Where("initiated_by = ?", filter.InitiatedBy)
+	}
+	if filter.BankID != "" {
+		query = query.Where("bank_id = ?", filter.BankID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var approvals []models.DualControlApproval
+	err := query.Preload("Decisions").Order("created_at DESC").
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&approvals).Error
+	return approvals, total, err
+}
+
+// Approve records a checker's confirmation. The approval becomes approved once enough
+// checkers confirmed it, and the initiator then has one more window to carry it out.
+func (s *DualControlService) Approve(id uuid.UUID, checker DualControlChecker) (*models.DualControlApproval, error) {
+	return s.decide(id, checker, "approve")
+}
+
+// Reject records a checker's refusal, which ends the approval
+func (s *DualControlService) Reject(id uuid.UUID, checker DualControlChecker) (*models.DualControlApproval, error) {
+	return s.decide(id, checker, "reject")
+}
+
+func (s *DualControlService) decide(id uuid.UUID, checker DualControlChecker, decision string) (*models.DualControlApproval, error) {
+	if !containsString(s.checkerRoles, checker.Role) {
+		return nil, ErrNotChecker
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		approval, err := lockApproval(tx, id)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		switch {
+		case approval.Status == DualControlPending && now.After(approval.ExpiresAt):
+			return ErrApprovalExpired // Marked expired by ExpireStale
+		case approval.Status != DualControlPending:
+			return ErrApprovalNotPending
+		case approval.InitiatedBy == checker.UserID:
+			return ErrSelfApproval
+		case approval.BankID != "" && checker.Role != "admin" && checker.BankID != approval.BankID:
+			return ErrNotChecker
+		}
+
+		var decided int64
+		if err := tx.Model(&models.DualControlDecision{}).
+			Where("approval_id = ? AND user_id = ?", id, checker.UserID).Count(&decided).Error; err != nil {
+			return err
+		}
+		if decided > 0 {
+			return ErrAlreadyDecided
+		}
+		if err := tx.Create(&models.DualControlDecision{
+			ApprovalID: id,
+			UserID:     checker.UserID,
+			Role:       checker.Role,
+			Decision:   decision,
+			Comment:    checker.Comment,
+			IPAddress:  checker.IPAddress,
+		}).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if decision == "reject" {
+			updates["status"] = DualControlRejected
+			updates["closed_at"] = now
+		} else {
+			updates["approvals"] = approval.Approvals + 1
+			if approval.Approvals+1 >= approval.RequiredApprovals {
+				updates["status"] = DualControlApproved
+				updates["approved_at"] = now
+				updates["expires_at"] = now.Add(s.window)
+			}
+		}
+		return tx.Model(approval).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetApproval(id)
+}
+
+// Cancel withdraws a pending or approved request. Only its initiator may do so.
+func (s *DualControlService) Cancel(id uuid.UUID, userID string) (*models.DualControlApproval, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		approval, err := lockApproval(tx, id)
+		if err != nil {
+			return err
+		}
+		if approval.InitiatedBy != userID {
+			return ErrNotInitiator
+		}
+		if approval.Status != DualControlPending && approval.Status != DualControlApproved {
+			return ErrApprovalNotPending
+		}
+		return tx.Model(approval).Updates(map[string]interface{}{"status": DualControlCancelled, "closed_at": time.Now()}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetApproval(id)
+}
+
+// Consume lets an approved request through once. The request must be made by the
+// initiator and match the approved one exactly.
+func (s *DualControlService) Consume(id uuid.UUID, userID, action, method, path string, body []byte) (*models.DualControlApproval, error) {
+	var approval *models.DualControlApproval
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		approval, err = lockApproval(tx, id)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		switch {
+		case approval.InitiatedBy != userID:
+			return ErrNotInitiator
+		case approval.Status == DualControlPending:
+			return ErrApprovalNotReady
+		case approval.Status != DualControlApproved:
+			return ErrApprovalNotPending
+		case now.After(approval.ExpiresAt):
+			return ErrApprovalExpired
+		case approval.Action != action || approval.PayloadHash != payloadHash(method, path, body):
+			return ErrApprovalMismatch
+		}
+		return tx.Model(approval).Updates(map[string]interface{}{"status": DualControlExecuted, "executed_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// RecordOutcome stores the response status of a request carried out with an approval
+func (s *DualControlService) RecordOutcome(id uuid.UUID, statusCode int) error {
+	updates := map[string]interface{}{"response_status": statusCode, "closed_at": time.Now()}
+	if statusCode >= 400 {
+		updates["status"] = DualControlFailed
+	}
+	if err := s.db.Model(&models.DualControlApproval{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record approval outcome: %w", err)
+	}
+	return nil
+}
+
+// ExpireStale closes approvals that were not confirmed, or not carried out, in time
+func (s *DualControlService) ExpireStale() error {
+	now := time.Now()
+	err := s.db.Model(&models.DualControlApproval{}).
+		Where("status IN ? AND expires_at <= ?", []string{DualControlPending, DualControlApproved}, now).
+		Updates(map[string]interface{}{"status": DualControlExpired, "closed_at": now}).Error
+	if err != nil {
+		return fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	return nil
+}
+
+func lockApproval(tx *gorm.DB, id uuid.UUID) (*models.DualControlApproval, error) {
+	var approval models.DualControlApproval
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&approval).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
@@ -0,0 +1,595 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+var (
+	ErrFundingMatchingDisabled = errors.New("funding matching is disabled")
+	ErrFundingMatchNotFound    = errors.New("funding match not found")
+	ErrFundingMatchNotPending  = errors.New("funding match is no longer pending")
+	ErrFundingMatchNotOwned    = errors.New("funding match allocates another funder's source")
+)
+
+// FundingRestrictions is the JSON stored in FundingSource.Restrictions
+type FundingRestrictions struct {
+	Industries               []string `json:"industries"`          // allowed industries, empty means any
+	ExcludedIndustries       []string `json:"excluded_industries"`
+	RequestTypes             []string `json:"request_types"`       // allowed request types, empty means any
+	MinTicket                float64  `json:"min_ticket"`
+	MaxTicket                float64  `json:"max_ticket"`
+	MinTenorDays             int      `json:"min_tenor_days"`
+	MaxTenorDays             int      `json:"max_tenor_days"`
+	MaxCustomerConcentration float64  `json:"max_customer_concentration"` // share of total capacity, overrides the default
+	MaxIndustryConcentration float64  `json:"max_industry_concentration"` // share of total capacity, overrides the default
+}
+
+// FundingMatchingResult summarises a matching run
+type FundingMatchingResult struct {
+	RunID               uuid.UUID                `json:"run_id"`
+	Matches             []models.FundingMatching `json:"matches"`
+	Unmatched           []UnmatchedRequest       `json:"unmatched"`
+	TotalMatched        float64                  `json:"total_matched"`
+	WeightedAverageRate float64                  `json:"weighted_average_rate"`
+	ReleasedExpired     int                      `json:"released_expired"`
+}
+
+// UnmatchedRequest explains why a request could not be funded in a run
+type UnmatchedRequest struct {
+	FinancingRequestID uuid.UUID `json:"financing_request_id"`
+	Amount             float64   `json:"amount"`
+	Reason             string    `json:"reason"`
+}
+
+// FundingMatchingService allocates approved financing requests to bank funding sources
+type FundingMatchingService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func NewFundingMatchingService(db *gorm.DB, cfg *config.Config) *FundingMatchingService {
+	return &FundingMatchingService{db: db, config: cfg}
+}
+
+// matchingRequest is a financing request prepared for allocation
+type matchingRequest struct {
+	request  models.FinancingRequest
+	amount   float64
+	industry string
+	bankOnly *uuid.UUID // set when an accepted auction offer ties the request to one bank
+}
+
+// matchingSource tracks a funding source's remaining room during a run
+type matchingSource struct {
+	source           models.FundingSource
+	restrictions     FundingRestrictions
+	remaining        float64
+	customerExposure map[uuid.UUID]float64
+	industryExposure map[string]float64
+}
+
+// RunMatching releases expired reservations, then allocates every unfunded approved request
+// to the cheapest source that satisfies capacity, currency, restriction and concentration limits.
+//
+// Requests are placed in order of regret (the extra cost of falling back to their second-best
+// source), so requests with the most to lose from a poor placement are assigned first. Each
+// allocation reserves capacity in its own transaction with a guarded update, so capacity can
+// never go negative even when runs overlap.
+func (s *FundingMatchingService) RunMatching() (*FundingMatchingResult, error) {
+	if !s.config.FundingMatchingEnabled {
+		return nil, ErrFundingMatchingDisabled
+	}
+
+	released, err := s.ReleaseExpiredMatches()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FundingMatchingResult{
+		RunID:           uuid.New(),
+		Matches:         []models.FundingMatching{},
+		Unmatched:       []UnmatchedRequest{},
+		ReleasedExpired: released,
+	}
+
+	requests, err := s.loadPendingRequests()
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return result, nil
+	}
+
+	sources, err := s.loadSources()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := requests
+	var weightedRate float64
+	for len(pending) > 0 {
+		bestIdx := -1
+		var bestSource *matchingSource
+		bestRegret := -1.0
+		var bestCandidates int
+
+		var next []*matchingRequest
+		for i, req := range pending {
+			first, second, candidates := s.cheapestSources(req, sources)
+			if first == nil {
+				result.Unmatched = append(result.Unmatched, UnmatchedRequest{
+					FinancingRequestID: req.request.ID,
+					Amount:             req.amount,
+					Reason:             s.unmatchedReason(req, sources),
+				})
+				continue
+			}
+
+			regret := math.Inf(1)
+			if second != nil {
+				regret = second.source.InterestRate - first.source.InterestRate
+			}
+			if bestIdx == -1 || regret > bestRegret || (regret == bestRegret && req.amount > pending[bestIdx].amount) {
+				bestIdx, bestSource, bestRegret, bestCandidates = i, first, regret, candidates
+			}
+			next = append(next, req)
+		}
+		if bestIdx == -1 {
+			break
+		}
+
+		chosen := pending[bestIdx]
+		match, err := s.reserve(result.RunID, chosen, bestSource, sources, bestCandidates)
+		if err != nil {
+			result.Unmatched = append(result.Unmatched, UnmatchedRequest{
+				FinancingRequestID: chosen.request.ID,
+				Amount:             chosen.amount,
+				Reason:             err.Error(),
+			})
+		} else {
+			bestSource.remaining -= chosen.amount
+			bestSource.customerExposure[chosen.request.CustomerID] += chosen.amount
+			if chosen.industry != "" {
+				bestSource.industryExposure[chosen.industry] += chosen.amount
+			}
+			result.Matches = append(result.Matches, *match)
+			result.TotalMatched += chosen.amount
+			weightedRate += chosen.amount * bestSource.source.InterestRate
+		}
+
+		pending = pending[:0]
+		for _, req := range next {
+			if req != chosen {
+				pending = append(pending, req)
+			}
+		}
+	}
+
+	result.TotalMatched = roundTo(result.TotalMatched, 2)
+	if result.TotalMatched > 0 {
+		result.WeightedAverageRate = roundTo(weightedRate/result.TotalMatched, 4)
+	}
+	return result, nil
+}
+
+// loadPendingRequests returns approved requests that have no pending or confirmed match
+func (s *FundingMatchingService) loadPendingRequests() ([]*matchingRequest, error) {
+	var requests []models.FinancingRequest
+	if err := s.db.Where("status = ?", "approved").
+		Where("NOT EXISTS (SELECT 1 FROM funding_matchings fm WHERE fm.financing_request_id = financing_requests.id AND fm.status IN ?)",
+			[]string{"pending", "confirmed"}).
+		Order("requested_at").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load financing requests: %w", err)
+	}
+
+	prepared := make([]*matchingRequest, 0, len(requests))
+	for _, request := range requests {
+		req := &matchingRequest{
+			request:  request,
+			amount:   request.RequestedAmount,
+			industry: industryOf(&request),
+		}
+
+		// An accepted auction offer fixes the amount and the bank that funds it
+		if request.AcceptedOfferID != nil {
+			var offer models.FinancingOffer
+			if err := s.db.First(&offer, "id = ?", *request.AcceptedOfferID).Error; err == nil {
+				req.amount = offer.OfferedAmount
+				req.bankOnly = &offer.BankConnectionID
+			}
+		}
+
+		prepared = append(prepared, req)
+	}
+	return prepared, nil
+}
+
+// loadSources returns active sources with their current customer and industry exposure
+func (s *FundingMatchingService) loadSources() ([]*matchingSource, error) {
+	var sources []models.FundingSource
+	if err := s.db.Where("status = ? AND available_capacity > 0 AND (expiry_date IS NULL OR expiry_date > ?)", "active", time.Now()).
+		Order("interest_rate").Find(&sources).Error; err != nil {
+		return nil, fmt.Errorf("failed to load funding sources: %w", err)
+	}
+
+	prepared := make([]*matchingSource, 0, len(sources))
+	byID := make(map[uuid.UUID]*matchingSource)
+	for _, source := range sources {
+		ms := &matchingSource{
+			source:           source,
+			remaining:        source.AvailableCapacity,
+			customerExposure: make(map[uuid.UUID]float64),
+			industryExposure: make(map[string]float64),
+		}
+		if source.Restrictions != "" {
+			if err := json.Unmarshal([]byte(source.Restrictions), &ms.restrictions); err != nil {
+				log.Printf("Funding source %s has invalid restrictions, skipping: %v", source.ID, err)
+				continue
+			}
+		}
+		prepared = append(prepared, ms)
+		byID[source.ID] = ms
+	}
+
+	var active []models.FundingMatching
+	if err := s.db.Preload("FinancingRequest").
+		Where("status IN ?", []string{"pending", "confirmed"}).Find(&active).Error; err != nil {
+		return nil, fmt.Errorf("failed to load existing allocations: %w", err)
+	}
+	for _, match := range active {
+		ms, ok := byID[match.FundingSourceID]
+		if !ok {
+			continue
+		}
+		ms.customerExposure[match.FinancingRequest.CustomerID] += match.MatchedAmount
+		if industry := industryOf(&match.FinancingRequest); industry != "" {
+			ms.industryExposure[industry] += match.MatchedAmount
+		}
+	}
+
+	return prepared, nil
+}
+
+// cheapestSources returns the two lowest-rate eligible sources for a request and the number of eligible sources
+func (s *FundingMatchingService) cheapestSources(req *matchingRequest, sources []*matchingSource) (*matchingSource, *matchingSource, int) {
+	var first, second *matchingSource
+	count := 0
+	for _, ms := range sources {
+		if s.ineligibleReason(req, ms) != "" {
+			continue
+		}
+		count++
+		switch {
+		case first == nil || ms.source.InterestRate < first.source.InterestRate:
+			first, second = ms, first
+		case second == nil || ms.source.InterestRate < second.source.InterestRate:
+			second = ms
+		}
+	}
+	return first, second, count
+}
+
+// ineligibleReason returns why a source cannot fund a request, or "" if it can
+func (s *FundingMatchingService) ineligibleReason(req *matchingRequest, ms *matchingSource) string {
+	r := ms.restrictions
+	request := req.request
+
+	if req.bankOnly != nil && ms.source.BankConnectionID != *req.bankOnly {
+		return "source belongs to a different bank than the accepted offer"
+	}
+	if !strings.EqualFold(ms.source.Currency, request.Currency) {
+		return "currency mismatch"
+	}
+	if ms.remaining < req.amount {
+		return "insufficient capacity"
+	}
+	if r.MinTicket > 0 && req.amount < r.MinTicket {
+		return "below minimum ticket"
+	}
+	if r.MaxTicket > 0 && req.amount > r.MaxTicket {
+		return "above maximum ticket"
+	}
+	if r.MinTenorDays > 0 && request.Term < r.MinTenorDays {
+		return "tenor too short"
+	}
+	if r.MaxTenorDays > 0 && request.Term > r.MaxTenorDays {
+		return "tenor too long"
+	}
+	if len(r.RequestTypes) > 0 && !containsFold(r.RequestTypes, request.RequestType) {
+		return "request type not permitted"
+	}
+	if len(r.Industries) > 0 && (req.industry == "" || !containsFold(r.Industries, req.industry)) {
+		return "industry not permitted"
+	}
+	if req.industry != "" && containsFold(r.ExcludedIndustries, req.industry) {
+		return "industry excluded"
+	}
+
+	customerLimit := s.config.FundingMaxCustomerConcentration
+	if r.MaxCustomerConcentration > 0 {
+		customerLimit = r.MaxCustomerConcentration
+	}
+	if customerLimit > 0 && ms.customerExposure[request.CustomerID]+req.amount > customerLimit*ms.source.TotalCapacity {
+		return "customer concentration limit"
+	}
+
+	industryLimit := s.config.FundingMaxIndustryConcentration
+	if r.MaxIndustryConcentration > 0 {
+		industryLimit = r.MaxIndustryConcentration
+	}
+	if req.industry != "" && industryLimit > 0 && ms.industryExposure[req.industry]+req.amount > industryLimit*ms.source.TotalCapacity {
+		return "industry concentration limit"
+	}
+
+	return ""
+}
+
+// unmatchedReason reports the most common reason sources rejected a request
+func (s *FundingMatchingService) unmatchedReason(req *matchingRequest, sources []*matchingSource) string {
+	if len(sources) == 0 {
+		return "no active funding sources"
+	}
+	counts := make(map[string]int)
+	top := ""
+	for _, ms := range sources {
+		reason := s.ineligibleReason(req, ms)
+		counts[reason]++
+		if top == "" || counts[reason] > counts[top] {
+			top = reason
+		}
+	}
+	return "no eligible funding source: " + top
+}
+
+// reserve atomically moves capacity from available to utilized and records a pending match
+func (s *FundingMatchingService) reserve(runID uuid.UUID, req *matchingRequest, chosen *matchingSource, sources []*matchingSource, candidates int) (*models.FundingMatching, error) {
+	cheapest := chosen.source.InterestRate
+	for _, ms := range sources {
+		if ms.source.InterestRate < cheapest {
+			cheapest = ms.source.InterestRate
+		}
+	}
+	score := 100.0
+	if chosen.source.InterestRate > 0 {
+		score = roundTo(100*cheapest/chosen.source.InterestRate, 2)
+	}
+
+	now := time.Now()
+	match := &models.FundingMatching{
+		FinancingRequestID: req.request.ID,
+		FundingSourceID:    chosen.source.ID,
+		MatchedAmount:      req.amount,
+		MatchScore:         score,
+		Status:             "pending",
+		MatchingReason: fmt.Sprintf("Lowest-cost eligible source at %.2f%% among %d candidate(s)",
+			chosen.source.InterestRate, candidates),
+		MatchingCriteria: toJSON(map[string]interface{}{
+			"run_id":             runID,
+			"interest_rate":      chosen.source.InterestRate,
+			"currency":           req.request.Currency,
+			"term":               req.request.Term,
+			"industry":           req.industry,
+			"eligible_sources":   candidates,
+			"remaining_capacity": roundTo(chosen.remaining-req.amount, 2),
+		}),
+		MatchedAt: now,
+		ExpiresAt: now.Add(s.config.FundingReservationTTL),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		update := tx.Model(&models.FundingSource{}).
+			Where("id = ? AND status = ? AND available_capacity >= ?", chosen.source.ID, "active", req.amount).
+			Updates(map[string]interface{}{
+				"available_capacity": gorm.Expr("available_capacity - ?", req.amount),
+				"utilized_capacity":  gorm.Expr("utilized_capacity + ?", req.amount),
+			})
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return fmt.Errorf("funding source capacity changed during matching")
+		}
+		return tx.Create(match).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// checkFunder returns ErrFundingMatchNotOwned unless actorID owns the bank connection of the
+// funding source the match allocates
+func (s *FundingMatchingService) checkFunder(tx *gorm.DB, match *models.FundingMatching, actorID string, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.FundingSource{}).
+		Joins("JOIN bank_connections ON bank_connections.id = funding_sources.bank_connection_id").
+		Where("funding_sources.id = ? AND bank_connections.user_id = ?", match.FundingSourceID, actorID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrFundingMatchNotOwned
+	}
+	return nil
+}
+
+// ConfirmMatch turns a pending reservation into a confirmed allocation. Only the funder
+// whose source it allocates, or an administrator, can confirm it.
+func (s *FundingMatchingService) ConfirmMatch(matchID uuid.UUID, actorID string, isAdmin bool) (*models.FundingMatching, error) {
+	var match models.FundingMatching
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&match, "id = ?", matchID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFundingMatchNotFound
+			}
+			return err
+		}
+		if err := s.checkFunder(tx, &match, actorID, isAdmin); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if match.Status != "pending" || match.ExpiresAt.Before(now) {
+			return ErrFundingMatchNotPending
+		}
+
+		result := tx.Model(&models.FundingMatching{}).
+			Where("id = ? AND status = ?", matchID, "pending").
+			Updates(map[string]interface{}{"status": "confirmed", "confirmed_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrFundingMatchNotPending
+		}
+
+		match.Status = "confirmed"
+		match.ConfirmedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// RejectMatch cancels a pending reservation and returns its capacity to the source. As with
+// confirmation, only the source's funder or an administrator can reject it.
+func (s *FundingMatchingService) RejectMatch(matchID uuid.UUID, actorID string, isAdmin bool) (*models.FundingMatching, error) {
+	var match models.FundingMatching
+	if err := s.db.First(&match, "id = ?", matchID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFundingMatchNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkFunder(s.db, &match, actorID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	released, err := s.release(&match, "rejected")
+	if err != nil {
+		return nil, err
+	}
+	if !released {
+		return nil, ErrFundingMatchNotPending
+	}
+	match.Status = "rejected"
+	return &match, nil
+}
+
+// ReleaseExpiredMatches expires pending reservations past ExpiresAt and returns their capacity
+func (s *FundingMatchingService) ReleaseExpiredMatches() (int, error) {
+	var expired []models.FundingMatching
+	if err := s.db.Where("status = ? AND expires_at < ?", "pending", time.Now()).Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired matches: %w", err)
+	}
+
+	count := 0
+	for i := range expired {
+		released, err := s.release(&expired[i], "expired")
+		if err != nil {
+			log.Printf("Failed to release funding match %s: %v", expired[i].ID, err)
+			continue
+		}
+		if released {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// release moves a pending match to status and gives its capacity back, exactly once
+func (s *FundingMatchingService) release(match *models.FundingMatching, status string) (bool, error) {
+	released := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.FundingMatching{}).
+			Where("id = ? AND status = ?", match.ID, "pending").
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.FundingSource{}).
+			Where("id = ?", match.FundingSourceID).
+			Updates(map[string]interface{}{
+				"available_capacity": gorm.Expr("available_capacity + ?", match.MatchedAmount),
+				"utilized_capacity":  gorm.Expr("utilized_capacity - ?", match.MatchedAmount),
+			}).Error; err != nil {
+			return err
+		}
+		released = true
+		return nil
+	})
+	return released, err
+}
+
+// ListMatches returns matches, optionally filtered by status
+func (s *FundingMatchingService) ListMatches(status string, limit, offset int) ([]models.FundingMatching, int64, error) {
+	var matches []models.FundingMatching
+	var total int64
+
+	query := s.db.Model(&models.FundingMatching{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("matched_at DESC").Limit(limit).Offset(offset).Find(&matches).Error; err != nil {
+		return nil, 0, err
+	}
+	return matches, total, nil
+}
+
+// StartScheduler releases expired reservations every minute until ctx is cancelled
+func (s *FundingMatchingService) StartScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if count, err := s.ReleaseExpiredMatches(); err != nil {
+			log.Printf("Funding reservation release failed: %v", err)
+		} else if count > 0 {
+			log.Printf("Released %d expired funding reservations", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func industryOf(request *models.FinancingRequest) string {
+	if request.BusinessDetails == "" {
+		return ""
+	}
+	var details struct {
+		Industry string `json:"industry"`
+	}
+	if json.Unmarshal([]byte(request.BusinessDetails), &details) != nil {
+		return ""
+	}
+	return strings.ToLower(details.Industry)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bank-integration-service/internal/config"
+)
+
+// ErrKYCUnavailable means a customer's KYC standing could not be looked up. Credit
+// decisions are refused rather than made without it.
+var ErrKYCUnavailable = errors.New("KYC status could not be checked")
+
+// KYCStanding is a customer's KYC as recorded by the user management service
+type KYCStanding struct {
+	Status   string `json:"kyc_status"` // not_started, pending, approved, rejected, expired
+	Eligible bool   `json:"eligible"`
+}
+
+// KYCClient looks customers' KYC and KYB up in the user management service by user ID
+type KYCClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewKYCClient(cfg *config.Config) *KYCClient {
+	return &KYCClient{
+		url:    strings.TrimSuffix(cfg.KYCServiceURL, "/"),
+		token:  cfg.KYCServiceToken,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Standing returns the customer's KYC. Customers unknown to the user management service
+// are reported as not started.
+func (k *KYCClient) Standing(customerID uuid.UUID) (*KYCStanding, error) {
+	standing := KYCStanding{Status: "not_started"}
+	if err := k.get("/api/v1/kyc/customers/eligibility?user_id="+url.QueryEscape(customerID.String()), &standing); err != nil {
+		return nil, err
+	}
+	return &standing, nil
+}
+
+// VerifiedRevenue returns the annual revenue of the company whose KYB case the customer
+// submitted, as the user management service holds it once KYB verified the company. It
+// is zero for customers without a verified company.
+func (k *KYCClient) VerifiedRevenue(customerID uuid.UUID) (float64, error) {
+	var company struct {
+		AnnualRevenue float64 `json:"annual_revenue"`
+	}
+	if err := k.get("/api/v1/kyb/eligibility?user_id="+url.QueryEscape(customerID.String()), &company); err != nil {
+		return 0, err
+	}
+	return company.AnnualRevenue, nil
+}
+
+// get decodes a user management service response into v, leaving v as it is when the
+// customer is not found
+func (k *KYCClient) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, k.url+path, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrKYCUnavailable, err)
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrKYCUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("%w: user management service returned %s", ErrKYCUnavailable, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrKYCUnavailable, err)
+	}
+	return nil
+}
+
+// Fact is the kyc_status the credit rules see. An approval that is past its expiry or
+// restricted pending re-verification does not count as verified.
+func (s *KYCStanding) Fact() string {
+	if s.Status == "approved" && !s.Eligible {
+		return "restricted"
+	}
+	return strings.ToLower(s.Status)
+}
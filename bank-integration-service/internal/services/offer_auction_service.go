@@ -0,0 +1,529 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+var (
+	ErrFinancingRequestNotFound = errors.New("financing request not found")
+	ErrFinancingOfferNotFound   = errors.New("financing offer not found")
+	ErrNoEligibleBanks          = errors.New("no eligible banks for this financing request")
+	ErrAuctionNotOpen           = errors.New("financing request is not accepting offers")
+	ErrAuctionStillOpen         = errors.New("offers can be accepted once the offer deadline has passed")
+	ErrOfferNotAcceptable       = errors.New("financing offer can no longer be accepted")
+	ErrNotRequestOwner          = errors.New("financing request belongs to another customer")
+	ErrBankNotIdentified        = errors.New("the caller is not linked to a bank")
+)
+
+// creditCapabilities are the bank capabilities that qualify a bank for financing auctions
+var creditCapabilities = []string{"credit_decisions", "credit_facilities"}
+
+// OfferInput is a bank's response to a financing request
+type OfferInput struct {
+	BankConnectionID uuid.UUID          `json:"bank_connection_id" binding:"required"`
+	ExternalOfferID  string             `json:"external_offer_id"`
+	OfferedAmount    float64            `json:"offered_amount" binding:"required"`
+	InterestRate     float64            `json:"interest_rate"`
+	Term             int                `json:"term" binding:"required"`
+	OriginationFee   float64            `json:"origination_fee"`
+	OtherFees        float64            `json:"other_fees"`
+	FeeDetails       map[string]float64 `json:"fee_details"`
+	Conditions       []string           `json:"conditions"`
+	ValidUntil       *time.Time         `json:"valid_until"`
+}
+
+// OfferAuctionService runs multi-bank offer auctions for financing requests
+type OfferAuctionService struct {
+	db             *gorm.DB
+	config         *config.Config
+	bankAPIService *BankAPIService
+}
+
+func NewOfferAuctionService(db *gorm.DB, cfg *config.Config, bankAPIService *BankAPIService) *OfferAuctionService {
+	return &OfferAuctionService{db: db, config: cfg, bankAPIService: bankAPIService}
+}
+
+// StartAuction sends a draft financing request to every eligible bank and opens it for offers until the deadline.
+// Only the requesting customer, or an admin, can start it.
+func (s *OfferAuctionService) StartAuction(requestID uuid.UUID, deadline *time.Time, actorID string, isAdmin bool) (*models.FinancingRequest, []models.FinancingOffer, error) {
+	now := time.Now()
+	offerDeadline := now.Add(s.config.AuctionOfferWindow)
+	if deadline != nil {
+		if !deadline.After(now) || deadline.Sub(now) > s.config.AuctionMaxOfferWindow {
+			return nil, nil, fmt.Errorf("offer deadline must be within %s from now", s.config.AuctionMaxOfferWindow)
+		}
+		offerDeadline = *deadline
+	}
+
+	var request models.FinancingRequest
+	var offers []models.FinancingOffer
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&request, "id = ?", requestID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFinancingRequestNotFound
+			}
+			return err
+		}
+		if !isAdmin && request.CustomerID.String() != actorID {
+			return ErrNotRequestOwner
+		}
+		if request.Status != "draft" {
+			return fmt.Errorf("financing request is %s; only draft requests can be auctioned", request.Status)
+		}
+
+		connections, err := s.eligibleConnections(tx, &request)
+		if err != nil {
+			return err
+		}
+		if len(connections) == 0 {
+			return ErrNoEligibleBanks
+		}
+
+		bankCodes := make([]string, 0, len(connections))
+		for _, connection := range connections {
+			offer := models.FinancingOffer{
+				FinancingRequestID: request.ID,
+				BankConnectionID:   connection.ID,
+				BankCode:           connection.BankCode,
+				Status:             "requested",
+				RequestedAt:        now,
+			}
+			if err := tx.Create(&offer).Error; err != nil {
+				return err
+			}
+			offers = append(offers, offer)
+			bankCodes = append(bankCodes, connection.BankCode)
+		}
+
+		// Guard against a concurrent start of the same request
+		result := tx.Model(&models.FinancingRequest{}).
+			Where("id = ? AND status = ?", request.ID, "draft").
+			Updates(map[string]interface{}{
+				"status":             "submitted",
+				"submitted_to_banks": toJSON(bankCodes),
+				"offer_deadline":     offerDeadline,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("financing request was submitted concurrently")
+		}
+
+		request.Status = "submitted"
+		request.SubmittedToBanks = bankCodes
+		request.OfferDeadline = &offerDeadline
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.dispatchToBanks(&request, offers)
+	return &request, offers, nil
+}
+
+// eligibleConnections returns one active connection per bank whose configuration can fund the request
+func (s *OfferAuctionService) eligibleConnections(tx *gorm.DB, request *models.FinancingRequest) ([]models.BankConnection, error) {
+	var connections []models.BankConnection
+	if err := tx.Where("status = ?", "active").Order("created_at").Find(&connections).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bank connections: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var eligible []models.BankConnection
+	for _, connection := range connections {
+		if seen[connection.BankCode] {
+			continue
+		}
+		bankConfig, ok := s.config.BankConfigs[connection.BankCode]
+		if !ok {
+			continue
+		}
+		if bankConfig.MaxAmount > 0 && request.RequestedAmount > bankConfig.MaxAmount {
+			continue
+		}
+		if !containsFold(bankConfig.SupportedCurrencies, request.Currency) {
+			continue
+		}
+		if !hasAnyCapability(bankConfig.Capabilities, creditCapabilities) {
+			continue
+		}
+		seen[connection.BankCode] = true
+		eligible = append(eligible, connection)
+	}
+	return eligible, nil
+}
+
+// dispatchToBanks submits the request to all invited banks concurrently; a failed submission declines that bank's offer
+func (s *OfferAuctionService) dispatchToBanks(request *models.FinancingRequest, offers []models.FinancingOffer) {
+	var wg sync.WaitGroup
+	for i := range offers {
+		wg.Add(1)
+		go func(offer *models.FinancingOffer) {
+			defer wg.Done()
+
+			reference, err := s.bankAPIService.SubmitFinancingRequest(offer.BankCode, request)
+			updates := map[string]interface{}{"external_offer_id": reference}
+			if err != nil {
+				now := time.Now()
+				updates = map[string]interface{}{
+					"status":         "declined",
+					"decline_reason": fmt.Sprintf("submission failed: %v", err),
+					"responded_at":   now,
+				}
+				offer.Status = "declined"
+			} else {
+				offer.ExternalOfferID = reference
+			}
+
+			if err := s.db.Model(&models.FinancingOffer{}).Where("id = ?", offer.ID).Updates(updates).Error; err != nil {
+				log.Printf("Failed to record submission to %s for financing request %s: %v", offer.BankCode, request.ID, err)
+			}
+		}(&offers[i])
+	}
+	wg.Wait()
+}
+
+// SubmitOffer records or revises a bank's offer while the auction is open. Callers answer only for the bank in
+// their token.
+func (s *OfferAuctionService) SubmitOffer(requestID uuid.UUID, input OfferInput, bankCode string) (*models.FinancingOffer, error) {
+	if bankCode == "" {
+		return nil, ErrBankNotIdentified
+	}
+	if input.OfferedAmount <= 0 || input.Term <= 0 || input.InterestRate < 0 {
+		return nil, fmt.Errorf("offered amount and term must be positive and interest rate non-negative")
+	}
+	fees := input.OriginationFee + input.OtherFees
+	if fees < 0 || fees >= input.OfferedAmount {
+		return nil, fmt.Errorf("fees must be non-negative and less than the offered amount")
+	}
+
+	var offer models.FinancingOffer
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var request models.FinancingRequest
+		if err := tx.First(&request, "id = ?", requestID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFinancingRequestNotFound
+			}
+			return err
+		}
+		now := time.Now()
+		if request.Status != "submitted" || request.AuctionClosedAt != nil ||
+			request.OfferDeadline == nil || !now.Before(*request.OfferDeadline) {
+			return ErrAuctionNotOpen
+		}
+
+		if err := tx.First(&offer, "financing_request_id = ? AND bank_connection_id = ?", requestID, input.BankConnectionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFinancingOfferNotFound
+			}
+			return err
+		}
+		if !strings.EqualFold(bankCode, offer.BankCode) {
+			return ErrFinancingOfferNotFound
+		}
+		if offer.Status != "requested" && offer.Status != "submitted" {
+			return ErrAuctionNotOpen
+		}
+
+		offer.OfferedAmount = input.OfferedAmount
+		offer.InterestRate = input.InterestRate
+		offer.Term = input.Term
+		offer.OriginationFee = input.OriginationFee
+		offer.OtherFees = input.OtherFees
+		offer.FeeDetails = toJSON(input.FeeDetails)
+		offer.Conditions = toJSON(input.Conditions)
+		offer.ValidUntil = input.ValidUntil
+		offer.EffectiveAPR = EffectiveAPR(input.OfferedAmount, input.InterestRate, input.Term, fees)
+		offer.Status = "submitted"
+		offer.SubmittedAt = &now
+		if input.ExternalOfferID != "" {
+			offer.ExternalOfferID = input.ExternalOfferID
+		}
+
+		return tx.Save(&offer).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// ListOffers returns a request's offers, best effective APR first
+func (s *OfferAuctionService) ListOffers(requestID uuid.UUID) ([]models.FinancingOffer, error) {
+	var offers []models.FinancingOffer
+	err := s.db.Where("financing_request_id = ?", requestID).
+		Order("CASE WHEN status IN ('submitted', 'accepted') THEN 0 ELSE 1 END, effective_apr, offered_amount DESC").
+		Find(&offers).Error
+	return offers, err
+}
+
+// CloseAuction expires unanswered invitations, ranks the submitted offers and records the best one.
+// It is idempotent, so the scheduler on every replica can safely call it.
+func (s *OfferAuctionService) CloseAuction(requestID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.FinancingRequest{}).
+			Where("id = ? AND status = ? AND auction_closed_at IS NULL", requestID, "submitted").
+			Update("auction_closed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.FinancingOffer{}).
+			Where("financing_request_id = ? AND (status = ? OR (status = ? AND valid_until IS NOT NULL AND valid_until < ?))",
+				requestID, "requested", "submitted", now).
+			Updates(map[string]interface{}{"status": "expired", "responded_at": now}).Error; err != nil {
+			return err
+		}
+
+		var offers []models.FinancingOffer
+		if err := tx.Where("financing_request_id = ? AND status = ?", requestID, "submitted").Find(&offers).Error; err != nil {
+			return err
+		}
+		rankOffers(offers)
+
+		bankCodes := make([]string, 0, len(offers))
+		for i := range offers {
+			if err := tx.Model(&offers[i]).Update("rank", offers[i].Rank).Error; err != nil {
+				return err
+			}
+			bankCodes = append(bankCodes, offers[i].BankCode)
+		}
+
+		updates := map[string]interface{}{
+			"approved_by_banks": toJSON(bankCodes),
+		}
+		if len(offers) == 0 {
+			updates["status"] = "rejected"
+		} else {
+			best := offers[0]
+			updates["status"] = "under_review"
+			updates["best_offer"] = toJSON(map[string]interface{}{
+				"offer_id":       best.ID,
+				"bank_code":      best.BankCode,
+				"offered_amount": best.OfferedAmount,
+				"interest_rate":  best.InterestRate,
+				"term":           best.Term,
+				"total_fees":     best.OriginationFee + best.OtherFees,
+				"effective_apr":  best.EffectiveAPR,
+			})
+		}
+
+		return tx.Model(&models.FinancingRequest{}).Where("id = ?", requestID).Updates(updates).Error
+	})
+}
+
+// AcceptOffer accepts one ranked offer on behalf of the customer and declines all others
+func (s *OfferAuctionService) AcceptOffer(requestID, offerID uuid.UUID, actorID string, isAdmin bool) (*models.FinancingOffer, error) {
+	request, err := s.loadRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && request.CustomerID.String() != actorID {
+		return nil, ErrNotRequestOwner
+	}
+
+	// The scheduler may not have closed an auction whose deadline just passed
+	if request.Status == "submitted" && request.OfferDeadline != nil && !time.Now().Before(*request.OfferDeadline) {
+		if err := s.CloseAuction(requestID); err != nil {
+			return nil, err
+		}
+		if request, err = s.loadRequest(requestID); err != nil {
+			return nil, err
+		}
+	}
+	if request.Status == "submitted" {
+		return nil, ErrAuctionStillOpen
+	}
+	if request.Status != "under_review" {
+		return nil, ErrOfferNotAcceptable
+	}
+
+	var accepted models.FinancingOffer
+	var declined []models.FinancingOffer
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.First(&accepted, "id = ? AND financing_request_id = ?", offerID, requestID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFinancingOfferNotFound
+			}
+			return err
+		}
+		if accepted.Status != "submitted" || (accepted.ValidUntil != nil && accepted.ValidUntil.Before(now)) {
+			return ErrOfferNotAcceptable
+		}
+
+		result := tx.Model(&models.FinancingRequest{}).
+			Where("id = ? AND status = ?", requestID, "under_review").
+			Updates(map[string]interface{}{
+				"status":            "approved",
+				"accepted_offer_id": accepted.ID,
+				"approved_at":       now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOfferNotAcceptable
+		}
+
+		accepted.Status = "accepted"
+		accepted.RespondedAt = &now
+		if err := tx.Save(&accepted).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("financing_request_id = ? AND id <> ? AND status = ?", requestID, accepted.ID, "submitted").
+			Find(&declined).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.FinancingOffer{}).
+			Where("financing_request_id = ? AND id <> ? AND status = ?", requestID, accepted.ID, "submitted").
+			Updates(map[string]interface{}{
+				"status":         "declined",
+				"decline_reason": "Customer accepted another offer",
+				"responded_at":   now,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Bank notifications are best effort; the decision is already committed
+	if err := s.bankAPIService.RespondToOffer(accepted.BankCode, accepted.ExternalOfferID, true); err != nil {
+		log.Printf("Failed to notify %s of accepted offer %s: %v", accepted.BankCode, accepted.ID, err)
+	}
+	for _, offer := range declined {
+		if err := s.bankAPIService.RespondToOffer(offer.BankCode, offer.ExternalOfferID, false); err != nil {
+			log.Printf("Failed to notify %s of declined offer %s: %v", offer.BankCode, offer.ID, err)
+		}
+	}
+
+	return &accepted, nil
+}
+
+// CloseExpiredAuctions closes every open auction whose offer deadline has passed
+func (s *OfferAuctionService) CloseExpiredAuctions() error {
+	var requestIDs []uuid.UUID
+	if err := s.db.Model(&models.FinancingRequest{}).
+		Where("status = ? AND auction_closed_at IS NULL AND offer_deadline <= ?", "submitted", time.Now()).
+		Pluck("id", &requestIDs).Error; err != nil {
+		return fmt.Errorf("failed to load expired auctions: %w", err)
+	}
+
+	for _, id := range requestIDs {
+		if err := s.CloseAuction(id); err != nil {
+			log.Printf("Failed to close auction for financing request %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// StartScheduler closes auctions as their deadlines pass. Deadlines live in the database,
+// so auctions that expired while the service was down are closed on the first tick.
+func (s *OfferAuctionService) StartScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if err := s.CloseExpiredAuctions(); err != nil {
+			log.Printf("Auction scheduler: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OfferAuctionService) loadRequest(requestID uuid.UUID) (*models.FinancingRequest, error) {
+	var request models.FinancingRequest
+	if err := s.db.First(&request, "id = ?", requestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFinancingRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// EffectiveAPR annualizes the total cost of an offer, in percent. Interest is simple interest
+// over the term and fees are assumed to be deducted from the amount disbursed.
+func EffectiveAPR(amount, ratePercent float64, termDays int, fees float64) float64 {
+	if amount <= 0 || termDays <= 0 || fees >= amount {
+		return 0
+	}
+	interest := amount * ratePercent / 100 * float64(termDays) / 365
+	netDisbursed := amount - fees
+	apr := ((amount+interest)/netDisbursed - 1) * 365 / float64(termDays) * 100
+	return roundTo(apr, 4)
+}
+
+// rankOffers orders offers by effective APR, then larger amount, then earliest submission, and numbers them from 1
+func rankOffers(offers []models.FinancingOffer) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, b := offers[i], offers[j]
+		if a.EffectiveAPR != b.EffectiveAPR {
+			return a.EffectiveAPR < b.EffectiveAPR
+		}
+		if a.OfferedAmount != b.OfferedAmount {
+			return a.OfferedAmount > b.OfferedAmount
+		}
+		if a.SubmittedAt != nil && b.SubmittedAt != nil {
+			return a.SubmittedAt.Before(*b.SubmittedAt)
+		}
+		return false
+	})
+	for i := range offers {
+		offers[i].Rank = i + 1
+	}
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), target) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyCapability(capabilities, wanted []string) bool {
+	for _, capability := range wanted {
+		if containsFold(capabilities, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+func toJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bank-integration-service/internal/models"
+)
+
+var (
+	ErrInvalidPaymentStatus    = errors.New("invalid payment status")
+	ErrPaymentStatusTransition = errors.New("payment status cannot change from its current state")
+)
+
+// paymentStatusOrder ranks payment states; a payment only moves forward, and the final
+// states never change
+var paymentStatusOrder = map[string]int{
+	"pending":    0,
+	"processing": 1,
+	"completed":  2,
+	"failed":     2,
+	"cancelled":  2,
+}
+
+// PaymentStatusUpdate is a bank's report of where a payment or transfer stands
+type PaymentStatusUpdate struct {
+	PaymentID         string     `json:"payment_id"`
+	ExternalPaymentID string     `json:"external_payment_id"`
+	Status            string     `json:"status"`
+	FailureReason     string     `json:"failure_reason"`
+	ProcessedAt       *time.Time `json:"processed_at"`
+	Fees              *float64   `json:"fees"`
+}
+
+// ApplyStatusUpdate moves a payment or transfer made through bankCode to the reported
+// status. Payments of other banks are reported as not found. Repeated reports of the
+// current status are accepted without change. It returns the payment and its status
+// before the update, which is empty when nothing changed.
+func (s *PaymentProcessingService) ApplyStatusUpdate(bankCode string, update PaymentStatusUpdate) (*models.PaymentTransaction, string, error) {
+	status := strings.ToLower(strings.TrimSpace(update.Status))
+	rank, ok := paymentStatusOrder[status]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %q", ErrInvalidPaymentStatus, update.Status)
+	}
+	if update.PaymentID == "" && update.ExternalPaymentID == "" {
+		return nil, "", fmt.Errorf("%w: payment_id or external_payment_id is required", ErrPaymentNotFound)
+	}
+
+	var payment models.PaymentTransaction
+	previous := ""
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		connections := tx.Model(&models.BankConnection{}).Select("id").Where("bank_code = ?", bankCode)
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("bank_connection_id IN (?)", connections)
+		if update.PaymentID != "" {
+			query = query.Where("payment_id = ?", update.PaymentID)
+		} else {
+			query = query.Where("external_payment_id = ?", update.ExternalPaymentID)
+		}
+		if err := query.First(&payment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+
+		if payment.Status == status {
+			return nil
+		}
+		current := paymentStatusOrder[payment.Status]
+		if current >= 2 || rank < current {
+			return fmt.Errorf("%w: %s to %s", ErrPaymentStatusTransition, payment.Status, status)
+		}
+
+		updates := map[string]interface{}{"status": status}
+		if update.ExternalPaymentID != "" && payment.ExternalPaymentID == "" {
+			updates["external_payment_id"] = update.ExternalPaymentID
+		}
+		if update.Fees != nil && *update.Fees >= 0 {
+			updates["fees"] = *update.Fees
+		}
+		switch status {
+		case "completed":
+			processedAt := time.Now()
+			if update.ProcessedAt != nil {
+				processedAt = *update.ProcessedAt
+			}
+			updates["processed_at"] = processedAt
+		case "failed", "cancelled":
+			updates["failure_reason"] = update.FailureReason
+		}
+
+		before := payment.Status
+		if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+		previous = before
+		payment.Status = status
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &payment, previous, nil
+}
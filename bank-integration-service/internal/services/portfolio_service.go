@@ -0,0 +1,672 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// ratingPD maps risk ratings to annual probabilities of default. Both letter grades and the
+// low/medium/high/critical scale used by risk assessments are supported.
+var ratingPD = map[string]float64{
+	"AAA": 0.0001, "AA": 0.0002, "A": 0.0006, "BBB": 0.002, "BB": 0.009,
+	"B": 0.035, "CCC": 0.12, "CC": 0.20, "C": 0.30, "D": 1.0,
+	"LOW": 0.005, "MEDIUM": 0.03, "HIGH": 0.10, "CRITICAL": 0.25,
+}
+
+const unratedPD = 0.05
+
+// exposureDimensions are the columns portfolio exposure can be grouped by
+var exposureDimensions = map[string]string{
+	"customer": "customer_id",
+	"industry": "industry",
+	"currency": "currency",
+	"bank":     "bank_connection_id",
+}
+
+// PortfolioFilter narrows analytics to part of the portfolio
+type PortfolioFilter struct {
+	BankConnectionID *uuid.UUID
+	Currency         string
+	Industry         string
+}
+
+// StatusBreakdown is the portfolio total for one status
+type StatusBreakdown struct {
+	Status      string  `json:"status"`
+	Count       int64   `json:"count"`
+	Principal   float64 `json:"principal"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// DPDBucket groups active items by days past due
+type DPDBucket struct {
+	Bucket      string  `json:"bucket"`
+	Count       int64   `json:"count"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// PortfolioOverview summarises the portfolio by status and delinquency
+type PortfolioOverview struct {
+	ItemCount        int64             `json:"item_count"`
+	TotalPrincipal   float64           `json:"total_principal"`
+	TotalOutstanding float64           `json:"total_outstanding"`
+	WeightedRate     float64           `json:"weighted_rate"`
+	ByStatus         []StatusBreakdown `json:"by_status"`
+	DPDBuckets       []DPDBucket       `json:"dpd_buckets"`
+}
+
+// RatingExposure is the exposure and expected loss for one risk rating
+type RatingExposure struct {
+	RiskRating   string  `json:"risk_rating"`
+	Count        int64   `json:"count"`
+	Outstanding  float64 `json:"outstanding"`
+	PD           float64 `json:"pd"`
+	ExpectedLoss float64 `json:"expected_loss"`
+}
+
+// RiskAnalysis reports expected loss and delinquency
+type RiskAnalysis struct {
+	TotalExposure     float64          `json:"total_exposure"`
+	ExpectedLoss      float64          `json:"expected_loss"`
+	ExpectedLossRatio float64          `json:"expected_loss_ratio"`
+	LossGivenDefault  float64          `json:"loss_given_default"`
+	DefaultedAmount   float64          `json:"defaulted_amount"`
+	WrittenOffAmount  float64          `json:"written_off_amount"`
+	ByRating          []RatingExposure `json:"by_rating"`
+	DPDBuckets        []DPDBucket      `json:"dpd_buckets"`
+}
+
+// ExposureEntry is one group in an exposure breakdown
+type ExposureEntry struct {
+	Key         string  `json:"key"`
+	Count       int64   `json:"count"`
+	Outstanding float64 `json:"outstanding"`
+	Share       float64 `json:"share"`
+}
+
+// ConcentrationMetrics reports HHI and largest-exposure shares per dimension
+type ConcentrationMetrics struct {
+	Dimension      string  `json:"dimension"`
+	HHI            float64 `json:"hhi"` // 0-10000; above 2500 is highly concentrated
+	Groups         int64   `json:"groups"`
+	LargestShare   float64 `json:"largest_share"`
+	TopTenShare    float64 `json:"top_ten_share"`
+	Classification string  `json:"classification"`
+}
+
+// YieldMetrics compares contractual yield with expected and realized loss-adjusted yield
+type YieldMetrics struct {
+	ContractualYield float64 `json:"contractual_yield"`
+	ExpectedYield    float64 `json:"expected_yield"` // contractual less expected loss
+	RealizedYield    float64 `json:"realized_yield"` // contractual less realized write-offs
+	YieldGap         float64 `json:"yield_gap"`      // realized minus expected
+}
+
+// VintageCohort is the performance of items originated in the same month
+type VintageCohort struct {
+	Cohort             string  `json:"cohort"`
+	MonthsOnBook       int     `json:"months_on_book"`
+	Count              int64   `json:"count"`
+	Principal          float64 `json:"principal"`
+	Outstanding        float64 `json:"outstanding"`
+	DefaultedPrincipal float64 `json:"defaulted_principal"`
+	DPD30Outstanding   float64 `json:"dpd30_outstanding"`
+	RepaidPct          float64 `json:"repaid_pct"`
+	DefaultPct         float64 `json:"default_pct"`
+	DPD30Pct           float64 `json:"dpd30_pct"`
+}
+
+// PortfolioPerformance combines yield, vintage and snapshot history
+type PortfolioPerformance struct {
+	Yield     YieldMetrics               `json:"yield"`
+	Vintages  []VintageCohort            `json:"vintages"`
+	Snapshots []models.PortfolioSnapshot `json:"snapshots"`
+}
+
+// PortfolioService computes portfolio analytics with SQL-side aggregation
+type PortfolioService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func NewPortfolioService(db *gorm.DB, cfg *config.Config) *PortfolioService {
+	return &PortfolioService{db: db, config: cfg}
+}
+
+// segment is the filter as stored on snapshots
+func (f PortfolioFilter) segment() PortfolioFilter {
+	f.Currency = strings.ToUpper(strings.TrimSpace(f.Currency))
+	f.Industry = strings.TrimSpace(f.Industry)
+	return f
+}
+
+func (s *PortfolioService) items(filter PortfolioFilter) *gorm.DB {
+	query := s.db.Model(&models.PortfolioItem{})
+	if filter.BankConnectionID != nil {
+		query = query.Where("bank_connection_id = ?", *filter.BankConnectionID)
+	}
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", strings.ToUpper(filter.Currency))
+	}
+	if filter.Industry != "" {
+		query = query.Where("industry = ?", filter.Industry)
+	}
+	return query
+}
+
+// Overview returns totals by status and DPD buckets
+func (s *PortfolioService) Overview(filter PortfolioFilter) (*PortfolioOverview, error) {
+	overview := &PortfolioOverview{ByStatus: []StatusBreakdown{}}
+
+	if err := s.items(filter).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(principal), 0) AS principal, COALESCE(SUM(outstanding), 0) AS outstanding").
+		Group("status").Order("status").
+		Scan(&overview.ByStatus).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate portfolio by status: %w", err)
+	}
+	for _, row := range overview.ByStatus {
+		overview.ItemCount += row.Count
+		overview.TotalPrincipal += row.Principal
+		overview.TotalOutstanding += row.Outstanding
+	}
+
+	rate, err := s.weightedRate(filter)
+	if err != nil {
+		return nil, err
+	}
+	overview.WeightedRate = rate
+
+	buckets, err := s.dpdBuckets(filter)
+	if err != nil {
+		return nil, err
+	}
+	overview.DPDBuckets = buckets
+
+	overview.TotalPrincipal = roundTo(overview.TotalPrincipal, 2)
+	overview.TotalOutstanding = roundTo(overview.TotalOutstanding, 2)
+	return overview, nil
+}
+
+// weightedRate is the outstanding-weighted contractual rate of active items
+func (s *PortfolioService) weightedRate(filter PortfolioFilter) (float64, error) {
+	var rate float64
+	if err := s.items(filter).Where("status = ?", "active").
+		Select("COALESCE(SUM(outstanding * interest_rate) / NULLIF(SUM(outstanding), 0), 0)").
+		Scan(&rate).Error; err != nil {
+		return 0, fmt.Errorf("failed to compute weighted rate: %w", err)
+	}
+	return roundTo(rate, 4), nil
+}
+
+// dpdBuckets groups active items by days past their next payment date
+func (s *PortfolioService) dpdBuckets(filter PortfolioFilter) ([]DPDBucket, error) {
+	now := time.Now()
+	bucketExpr := "CASE WHEN next_payment_due IS NULL OR next_payment_due >= ? THEN 'current' " +
+		"WHEN next_payment_due >= ? THEN '1-30' " +
+		"WHEN next_payment_due >= ? THEN '31-60' " +
+		"WHEN next_payment_due >= ? THEN '61-90' " +
+		"ELSE '90+' END"
+
+	var rows []DPDBucket
+	if err := s.items(filter).Where("status = ?", "active").
+		Select(bucketExpr+" AS bucket, COUNT(*) AS count, COALESCE(SUM(outstanding), 0) AS outstanding",
+			now, now.AddDate(0, 0, -30), now.AddDate(0, 0, -60), now.AddDate(0, 0, -90)).
+		Group("bucket").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute DPD buckets: %w", err)
+	}
+
+	// Always return every bucket, in order
+	byName := make(map[string]DPDBucket)
+	for _, row := range rows {
+		byName[row.Bucket] = row
+	}
+	buckets := make([]DPDBucket, 0, 5)
+	for _, name := range []string{"current", "1-30", "31-60", "61-90", "90+"} {
+		bucket := byName[name]
+		bucket.Bucket = name
+		bucket.Outstanding = roundTo(bucket.Outstanding, 2)
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// pdExpr builds a SQL CASE that maps each item to its probability of default
+func pdExpr() (string, []interface{}) {
+	ratings := make([]string, 0, len(ratingPD))
+	for rating := range ratingPD {
+		ratings = append(ratings, rating)
+	}
+	sort.Strings(ratings)
+
+	var b strings.Builder
+	args := make([]interface{}, 0, len(ratings)*2+1)
+	b.WriteString("CASE WHEN status IN ('defaulted', 'written_off') THEN 1.0")
+	for _, rating := range ratings {
+		b.WriteString(" WHEN UPPER(risk_rating) = ? THEN ?")
+		args = append(args, rating, ratingPD[rating])
+	}
+	b.WriteString(" ELSE ? END")
+	args = append(args, unratedPD)
+	return b.String(), args
+}
+
+// RiskAnalysis returns expected loss (PD x LGD x outstanding) by rating and DPD buckets
+func (s *PortfolioService) RiskAnalysis(filter PortfolioFilter) (*RiskAnalysis, error) {
+	lgd := s.config.PortfolioLossGivenDefault
+	analysis := &RiskAnalysis{LossGivenDefault: lgd, ByRating: []RatingExposure{}}
+
+	pd, pdArgs := pdExpr()
+	ratingExpr := "COALESCE(NULLIF(UPPER(risk_rating), ''), 'UNRATED')"
+
+	var rows []struct {
+		RiskRating  string
+		Count       int64
+		Outstanding float64
+		Weighted    float64
+	}
+	if err := s.items(filter).Where("status IN ?", []string{"active", "defaulted"}).
+		Select(ratingExpr+" AS risk_rating, COUNT(*) AS count, COALESCE(SUM(outstanding), 0) AS outstanding, "+
+			"COALESCE(SUM(outstanding * "+pd+"), 0) AS weighted", pdArgs...).
+		Group(ratingExpr).Order("outstanding DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute expected loss: %w", err)
+	}
+
+	for _, row := range rows {
+		entry := RatingExposure{
+			RiskRating:   row.RiskRating,
+			Count:        row.Count,
+			Outstanding:  roundTo(row.Outstanding, 2),
+			ExpectedLoss: roundTo(row.Weighted*lgd, 2),
+		}
+		if row.Outstanding > 0 {
+			entry.PD = roundTo(row.Weighted/row.Outstanding, 6)
+		}
+		analysis.ByRating = append(analysis.ByRating, entry)
+		analysis.TotalExposure += row.Outstanding
+		analysis.ExpectedLoss += row.Weighted * lgd
+	}
+
+	var losses struct {
+		Defaulted  float64
+		WrittenOff float64
+	}
+	if err := s.items(filter).
+		Select("COALESCE(SUM(CASE WHEN status = 'defaulted' THEN outstanding ELSE 0 END), 0) AS defaulted, " +
+			"COALESCE(SUM(CASE WHEN status = 'written_off' THEN outstanding ELSE 0 END), 0) AS written_off").
+		Scan(&losses).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute losses: %w", err)
+	}
+	analysis.DefaultedAmount = roundTo(losses.Defaulted, 2)
+	analysis.WrittenOffAmount = roundTo(losses.WrittenOff, 2)
+
+	if analysis.TotalExposure > 0 {
+		analysis.ExpectedLossRatio = roundTo(analysis.ExpectedLoss/analysis.TotalExposure, 6)
+	}
+	analysis.TotalExposure = roundTo(analysis.TotalExposure, 2)
+	analysis.ExpectedLoss = roundTo(analysis.ExpectedLoss, 2)
+
+	buckets, err := s.dpdBuckets(filter)
+	if err != nil {
+		return nil, err
+	}
+	analysis.DPDBuckets = buckets
+	return analysis, nil
+}
+
+// Exposures returns the largest active exposures grouped by customer, industry, currency or bank
+func (s *PortfolioService) Exposures(filter PortfolioFilter, dimension string, limit int) ([]ExposureEntry, error) {
+	column, ok := exposureDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unsupported exposure dimension: %s", dimension)
+	}
+
+	var total float64
+	if err := s.items(filter).Where("status = ?", "active").
+		Select("COALESCE(SUM(outstanding), 0)").Scan(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute total exposure: %w", err)
+	}
+
+	keyExpr := fmt.Sprintf("COALESCE(NULLIF(CAST(%s AS VARCHAR(255)), ''), 'unknown')", column)
+	entries := []ExposureEntry{}
+	if err := s.items(filter).Where("status = ?", "active").
+		Select(keyExpr + " AS key, COUNT(*) AS count, COALESCE(SUM(outstanding), 0) AS outstanding").
+		Group(keyExpr).Order("outstanding DESC").Limit(limit).
+		Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute %s exposure: %w", dimension, err)
+	}
+
+	for i := range entries {
+		entries[i].Outstanding = roundTo(entries[i].Outstanding, 2)
+		if total > 0 {
+			entries[i].Share = roundTo(entries[i].Outstanding/total, 6)
+		}
+	}
+	return entries, nil
+}
+
+// Concentrations returns the Herfindahl-Hirschman index for every exposure dimension
+func (s *PortfolioService) Concentrations(filter PortfolioFilter) ([]ConcentrationMetrics, error) {
+	dimensions := make([]string, 0, len(exposureDimensions))
+	for dimension := range exposureDimensions {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Strings(dimensions)
+
+	metrics := make([]ConcentrationMetrics, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		m, err := s.concentration(filter, dimension)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, *m)
+	}
+	return metrics, nil
+}
+
+func (s *PortfolioService) concentration(filter PortfolioFilter, dimension string) (*ConcentrationMetrics, error) {
+	column := exposureDimensions[dimension]
+	grouped := s.items(filter).Where("status = ?", "active").
+		Select("SUM(outstanding) AS exposure").Group(column)
+
+	var totals struct {
+		SumSquares float64
+		Total      float64
+		Largest    float64
+		Groups     int64
+	}
+	if err := s.db.Table("(?) AS grouped", grouped).
+		Select("COALESCE(SUM(exposure * exposure), 0) AS sum_squares, COALESCE(SUM(exposure), 0) AS total, " +
+			"COALESCE(MAX(exposure), 0) AS largest, COUNT(*) AS groups").
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute %s concentration: %w", dimension, err)
+	}
+
+	var topTen float64
+	top := s.items(filter).Where("status = ?", "active").
+		Select("SUM(outstanding) AS exposure").Group(column).Order("exposure DESC").Limit(10)
+	if err := s.db.Table("(?) AS top_groups", top).
+		Select("COALESCE(SUM(exposure), 0)").Scan(&topTen).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute %s top exposures: %w", dimension, err)
+	}
+
+	m := &ConcentrationMetrics{Dimension: dimension, Groups: totals.Groups}
+	if totals.Total > 0 {
+		m.HHI = roundTo(totals.SumSquares/(totals.Total*totals.Total)*10000, 2)
+		m.LargestShare = roundTo(totals.Largest/totals.Total, 6)
+		m.TopTenShare = roundTo(topTen/totals.Total, 6)
+	}
+	switch {
+	case m.HHI > 2500:
+		m.Classification = "highly_concentrated"
+	case m.HHI >= 1500:
+		m.Classification = "moderately_concentrated"
+	default:
+		m.Classification = "unconcentrated"
+	}
+	return m, nil
+}
+
+// Yield compares contractual yield with expected-loss and realized-loss adjusted yield
+func (s *PortfolioService) Yield(filter PortfolioFilter) (*YieldMetrics, error) {
+	contractual, err := s.weightedRate(filter)
+	if err != nil {
+		return nil, err
+	}
+	risk, err := s.RiskAnalysis(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalPrincipal float64
+	if err := s.items(filter).Select("COALESCE(SUM(principal), 0)").Scan(&totalPrincipal).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute total principal: %w", err)
+	}
+
+	metrics := &YieldMetrics{
+		ContractualYield: contractual,
+		ExpectedYield:    roundTo(contractual-risk.ExpectedLossRatio*100, 4),
+		RealizedYield:    contractual,
+	}
+	if totalPrincipal > 0 {
+		metrics.RealizedYield = roundTo(contractual-risk.WrittenOffAmount/totalPrincipal*100, 4)
+	}
+	metrics.YieldGap = roundTo(metrics.RealizedYield-metrics.ExpectedYield, 4)
+	return metrics, nil
+}
+
+// Vintages returns performance by origination month
+func (s *PortfolioService) Vintages(filter PortfolioFilter) ([]VintageCohort, error) {
+	monthExpr := "to_char(created_at, 'YYYY-MM')"
+	if s.db.Dialector.Name() == "sqlite" {
+		monthExpr = "strftime('%Y-%m', created_at)"
+	}
+
+	cohorts := []VintageCohort{}
+	if err := s.items(filter).
+		Select(monthExpr+" AS cohort, COUNT(*) AS count, COALESCE(SUM(principal), 0) AS principal, "+
+			"COALESCE(SUM(outstanding), 0) AS outstanding, "+
+			"COALESCE(SUM(CASE WHEN status IN ('defaulted', 'written_off') THEN principal ELSE 0 END), 0) AS defaulted_principal, "+
+			"COALESCE(SUM(CASE WHEN status = 'active' AND next_payment_due < ? THEN outstanding ELSE 0 END), 0) AS dpd30_outstanding",
+			time.Now().AddDate(0, 0, -30)).
+		Group(monthExpr).Order("cohort").
+		Scan(&cohorts).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute vintages: %w", err)
+	}
+
+	now := time.Now()
+	for i := range cohorts {
+		c := &cohorts[i]
+		if start, err := time.Parse("2006-01", c.Cohort); err == nil {
+			c.MonthsOnBook = (now.Year()-start.Year())*12 + int(now.Month()-start.Month())
+		}
+		if c.Principal > 0 {
+			c.RepaidPct = roundTo((c.Principal-c.Outstanding)/c.Principal*100, 4)
+			c.DefaultPct = roundTo(c.DefaultedPrincipal/c.Principal*100, 4)
+		}
+		if c.Outstanding > 0 {
+			c.DPD30Pct = roundTo(c.DPD30Outstanding/c.Outstanding*100, 4)
+		}
+	}
+	return cohorts, nil
+}
+
+// Performance returns yield, vintages and the snapshot series between from and to
+func (s *PortfolioService) Performance(filter PortfolioFilter, from, to time.Time) (*PortfolioPerformance, error) {
+	yield, err := s.Yield(filter)
+	if err != nil {
+		return nil, err
+	}
+	vintages, err := s.Vintages(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are kept per segment, so the series matches the filter exactly
+	segment := filter.segment()
+	query := s.db.Where("snapshot_date >= ? AND snapshot_date <= ? AND currency = ? AND industry = ?",
+		from, to, segment.Currency, segment.Industry)
+	if segment.BankConnectionID != nil {
+		query = query.Where("bank_connection_id = ?", *segment.BankConnectionID)
+	} else {
+		query = query.Where("bank_connection_id IS NULL")
+	}
+	snapshots := []models.PortfolioSnapshot{}
+	if err := query.Order("snapshot_date").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to load portfolio snapshots: %w", err)
+	}
+
+	return &PortfolioPerformance{Yield: *yield, Vintages: vintages, Snapshots: snapshots}, nil
+}
+
+// TakeSnapshot records the metrics for the given day, once per day, for the whole portfolio
+// and for every combination of bank connection, currency and industry held in it
+func (s *PortfolioService) TakeSnapshot(day time.Time) (*models.PortfolioSnapshot, error) {
+	day = day.UTC().Truncate(24 * time.Hour)
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var whole *models.PortfolioSnapshot
+	for _, segment := range segments {
+		snapshot, err := s.snapshotSegment(day, segment)
+		if err != nil {
+			return nil, err
+		}
+		if segment.BankConnectionID == nil && segment.Currency == "" && segment.Industry == "" {
+			whole = snapshot
+		}
+	}
+	return whole, nil
+}
+
+// segments returns the whole portfolio first, then each distinct combination of its
+// bank connections, currencies and industries, including the partial ones
+func (s *PortfolioService) segments() ([]PortfolioFilter, error) {
+	var rows []struct {
+		BankConnectionID uuid.UUID
+		Currency         string
+		Industry         string
+	}
+	if err := s.db.Model(&models.PortfolioItem{}).
+		Distinct("bank_connection_id", "currency", "industry").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load portfolio segments: %w", err)
+	}
+
+	segments := []PortfolioFilter{{}}
+	seen := map[string]bool{"||": true}
+	for _, row := range rows {
+		bankID := row.BankConnectionID
+		// Every subset of the three dimensions, as a bit mask
+		for mask := 1; mask < 8; mask++ {
+			var segment PortfolioFilter
+			if mask&1 != 0 {
+				segment.BankConnectionID = &bankID
+			}
+			if mask&2 != 0 {
+				segment.Currency = row.Currency
+			}
+			if mask&4 != 0 {
+				segment.Industry = row.Industry
+			}
+			segment = segment.segment()
+			// Items without a currency or industry only belong to the wider segments
+			if mask&2 != 0 && segment.Currency == "" || mask&4 != 0 && segment.Industry == "" {
+				continue
+			}
+
+			key := segment.Currency + "|" + segment.Industry + "|"
+			if segment.BankConnectionID != nil {
+				key += segment.BankConnectionID.String()
+			}
+			if !seen[key] {
+				seen[key] = true
+				segments = append(segments, segment)
+			}
+		}
+	}
+	return segments, nil
+}
+
+// snapshotSegment records one segment's snapshot for the day unless it already exists
+func (s *PortfolioService) snapshotSegment(day time.Time, filter PortfolioFilter) (*models.PortfolioSnapshot, error) {
+	query := s.db.Where("snapshot_date = ? AND currency = ? AND industry = ?", day, filter.Currency, filter.Industry)
+	if filter.BankConnectionID != nil {
+		query = query.Where("bank_connection_id = ?", *filter.BankConnectionID)
+	} else {
+		query = query.Where("bank_connection_id IS NULL")
+	}
+	var existing models.PortfolioSnapshot
+	if err := query.First(&existing).Error; err == nil {
+		return &existing, nil
+	}
+
+	overview, err := s.Overview(filter)
+	if err != nil {
+		return nil, err
+	}
+	risk, err := s.RiskAnalysis(filter)
+	if err != nil {
+		return nil, err
+	}
+	yield, err := s.Yield(filter)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := s.concentration(filter, "customer")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.PortfolioSnapshot{
+		SnapshotDate:      day,
+		BankConnectionID:  filter.BankConnectionID,
+		Currency:          filter.Currency,
+		Industry:          filter.Industry,
+		ItemCount:         overview.ItemCount,
+		TotalPrincipal:    overview.TotalPrincipal,
+		TotalOutstanding:  overview.TotalOutstanding,
+		WeightedRate:      overview.WeightedRate,
+		LossAdjustedYield: yield.ExpectedYield,
+		ExpectedLoss:      risk.ExpectedLoss,
+		DefaultedAmount:   risk.DefaultedAmount,
+		CustomerHHI:       customer.HHI,
+	}
+	for _, status := range overview.ByStatus {
+		if status.Status == "active" {
+			snapshot.ActiveCount = status.Count
+		}
+	}
+	for _, bucket := range overview.DPDBuckets {
+		switch bucket.Bucket {
+		case "31-60", "61-90":
+			snapshot.DPD30Plus += bucket.Outstanding
+		case "90+":
+			snapshot.DPD30Plus += bucket.Outstanding
+			snapshot.DPD90Plus += bucket.Outstanding
+		}
+	}
+	snapshot.Metrics = toJSON(map[string]interface{}{
+		"by_status":   overview.ByStatus,
+		"dpd_buckets": overview.DPDBuckets,
+		"by_rating":   risk.ByRating,
+		"yield":       yield,
+	})
+
+	if err := s.db.Create(snapshot).Error; err != nil {
+		return nil, fmt.Errorf("failed to save portfolio snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// StartSnapshotScheduler takes the daily snapshot, checking hourly until ctx is cancelled
+func (s *PortfolioService) StartSnapshotScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.TakeSnapshot(time.Now()); err != nil {
+			log.Printf("Portfolio snapshot failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,722 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// Reconciliation job types
+const (
+	ReconciliationJobDaily    = "daily"
+	ReconciliationJobWeekly   = "weekly"
+	ReconciliationJobMonthly  = "monthly"
+	ReconciliationJobOnDemand = "on_demand"
+)
+
+// Bank statement line statuses
+const (
+	StatementLineUnmatched       = "unmatched"
+	StatementLineMatched         = "matched"
+	StatementLineManualReview    = "manual_review"
+	StatementLineManuallyMatched = "manually_matched"
+	StatementLineIgnored         = "ignored"
+)
+
+// Weights used to combine the individual match signals into a confidence score
+const (
+	referenceWeight    = 0.40
+	amountWeight       = 0.30
+	dateWeight         = 0.15
+	counterpartyWeight = 0.15
+
+	// ambiguityMargin is the minimum lead the best candidate needs over the
+	// runner-up before it is matched automatically
+	ambiguityMargin = 0.05
+	maxCandidates   = 3
+)
+
+var (
+	ErrReconciliationJobNotFound = errors.New("reconciliation job not found")
+	ErrStatementLineNotFound     = errors.New("statement line not found")
+	ErrTransactionAlreadyMatched = errors.New("payment transaction is already reconciled")
+	ErrConnectionNotOwned        = errors.New("bank connection belongs to another user")
+	ErrStatementLineMatched      = errors.New("statement line is already matched")
+)
+
+// ReconciliationService matches imported bank statement lines to payment transactions
+type ReconciliationService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func NewReconciliationService(db *gorm.DB, cfg *config.Config) *ReconciliationService {
+	return &ReconciliationService{db: db, config: cfg}
+}
+
+// StatementLineInput is a single bank statement line to import
+type StatementLineInput struct {
+	ExternalLineID      string     `json:"external_line_id"`
+	BookingDate         time.Time  `json:"booking_date" binding:"required"`
+	ValueDate           *time.Time `json:"value_date"`
+	Amount              float64    `json:"amount" binding:"required"`
+	Currency            string     `json:"currency"`
+	Reference           string     `json:"reference"`
+	CounterpartyName    string     `json:"counterparty_name"`
+	CounterpartyAccount string     `json:"counterparty_account"`
+	Description         string     `json:"description"`
+}
+
+// MatchCandidate is a scored payment transaction suggested for a statement line
+type MatchCandidate struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	PaymentID     string    `json:"payment_id"`
+	Amount        float64   `json:"amount"`
+	Confidence    float64   `json:"confidence"`
+	Reasons       []string  `json:"reasons"`
+}
+
+// ReconciliationSummary is stored as JSON on each reconciliation job
+type ReconciliationSummary struct {
+	AutoMatched              int     `json:"auto_matched"`
+	QueuedForReview          int     `json:"queued_for_review"`
+	Unmatched                int     `json:"unmatched"`
+	MatchedAmount            float64 `json:"matched_amount"`
+	UnmatchedAmount          float64 `json:"unmatched_amount"`
+	AverageConfidence        float64 `json:"average_confidence"`
+	UnreconciledTransactions int64   `json:"unreconciled_transactions"`
+}
+
+// ReconciliationError describes a statement line that could not be processed
+type ReconciliationError struct {
+	StatementLineID uuid.UUID `json:"statement_line_id"`
+	Error           string    `json:"error"`
+}
+
+// checkConnection allows actorID to work on the bank connection's statements when they
+// own the connection, or when isAdmin
+func (s *ReconciliationService) checkConnection(tx *gorm.DB, bankConnectionID uuid.UUID, actorID string, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.BankConnection{}).Where("id = ? AND user_id = ?", bankConnectionID, actorID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrConnectionNotOwned
+	}
+	return nil
+}
+
+// ImportStatementLines stores statement lines for a bank connection of actorID, skipping
+// lines whose external ID has already been imported
+func (s *ReconciliationService) ImportStatementLines(bankConnectionID uuid.UUID, inputs []StatementLineInput, actorID string, isAdmin bool) ([]models.BankStatementLine, error) {
+	if err := s.checkConnection(s.db, bankConnectionID, actorID, isAdmin); err != nil {
+		return nil, err
+	}
+	lines := make([]models.BankStatementLine, 0, len(inputs))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, input := range inputs {
+			if input.ExternalLineID != "" {
+				var count int64
+				if err := tx.Model(&models.BankStatementLine{}).
+					Where("bank_connection_id = ? AND external_line_id = ?", bankConnectionID, input.ExternalLineID).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+			}
+
+			currency := strings.ToUpper(input.Currency)
+			if currency == "" {
+				currency = "USD"
+			}
+
+			line := models.BankStatementLine{
+				BankConnectionID:    bankConnectionID,
+				ExternalLineID:      input.ExternalLineID,
+				BookingDate:         input.BookingDate,
+				ValueDate:           input.ValueDate,
+				Amount:              input.Amount,
+				Currency:            currency,
+				Reference:           input.Reference,
+				CounterpartyName:    input.CounterpartyName,
+				CounterpartyAccount: input.CounterpartyAccount,
+				Description:         input.Description,
+				Status:              StatementLineUnmatched,
+			}
+			if err := tx.Create(&line).Error; err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import statement lines: %w", err)
+	}
+
+	return lines, nil
+}
+
+// CreateJob registers a reconciliation job for the given period
+func (s *ReconciliationService) CreateJob(jobType string, startDate, endDate time.Time) (*models.ReconciliationJob, error) {
+	switch jobType {
+	case ReconciliationJobDaily, ReconciliationJobWeekly, ReconciliationJobMonthly, ReconciliationJobOnDemand:
+	default:
+		return nil, fmt.Errorf("unsupported job type: %s", jobType)
+	}
+	if !endDate.After(startDate) {
+		return nil, fmt.Errorf("end date must be after start date")
+	}
+
+	job := &models.ReconciliationJob{
+		JobType:   jobType,
+		Status:    "pending",
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create reconciliation job: %w", err)
+	}
+	return job, nil
+}
+
+// GetJob returns a reconciliation job by ID
+func (s *ReconciliationService) GetJob(jobID uuid.UUID) (*models.ReconciliationJob, error) {
+	var job models.ReconciliationJob
+	if err := s.db.First(&job, "id = ?", jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RunJob matches every open statement line in the job period and records the outcome on the job
+func (s *ReconciliationService) RunJob(jobID uuid.UUID) error {
+	job, err := s.GetJob(jobID)
+	if err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+	job.Status = "running"
+	job.StartedAt = &startedAt
+	if err := s.db.Save(job).Error; err != nil {
+		return fmt.Errorf("failed to start reconciliation job: %w", err)
+	}
+
+	var lines []models.BankStatementLine
+	if err := s.db.Where("status IN ? AND booking_date >= ? AND booking_date < ?",
+		[]string{StatementLineUnmatched, StatementLineManualReview}, job.StartDate, job.EndDate).
+		Order("booking_date").Find(&lines).Error; err != nil {
+		return s.failJob(job, err)
+	}
+
+	job.TotalRecords = len(lines)
+	summary := ReconciliationSummary{}
+	var errorDetails []ReconciliationError
+	var confidenceTotal float64
+
+	for i := range lines {
+		line := &lines[i]
+		outcome, confidence, err := s.reconcileLine(line, job.ID)
+		job.ProcessedRecords++
+
+		if err != nil {
+			job.ErrorRecords++
+			errorDetails = append(errorDetails, ReconciliationError{StatementLineID: line.ID, Error: err.Error()})
+			continue
+		}
+
+		switch outcome {
+		case StatementLineMatched:
+			job.MatchedRecords++
+			summary.AutoMatched++
+			summary.MatchedAmount += math.Abs(line.Amount)
+			confidenceTotal += confidence
+		case StatementLineManualReview:
+			job.UnmatchedRecords++
+			summary.QueuedForReview++
+			summary.UnmatchedAmount += math.Abs(line.Amount)
+		default:
+			job.UnmatchedRecords++
+			summary.Unmatched++
+			summary.UnmatchedAmount += math.Abs(line.Amount)
+		}
+
+		if job.ProcessedRecords%100 == 0 {
+			s.db.Model(job).Updates(map[string]interface{}{
+				"processed_records": job.ProcessedRecords,
+				"matched_records":   job.MatchedRecords,
+				"unmatched_records": job.UnmatchedRecords,
+				"error_records":     job.ErrorRecords,
+			})
+		}
+	}
+
+	if summary.AutoMatched > 0 {
+		summary.AverageConfidence = roundTo(confidenceTotal/float64(summary.AutoMatched), 4)
+	}
+	summary.MatchedAmount = roundTo(summary.MatchedAmount, 2)
+	summary.UnmatchedAmount = roundTo(summary.UnmatchedAmount, 2)
+
+	s.db.Model(&models.PaymentTransaction{}).
+		Where("status = ? AND reconciled_at IS NULL AND COALESCE(processed_at, created_at) >= ? AND COALESCE(processed_at, created_at) < ?",
+			"completed", job.StartDate, job.EndDate).
+		Count(&summary.UnreconciledTransactions)
+
+	summaryJSON, _ := json.Marshal(summary)
+	if errorDetails == nil {
+		errorDetails = []ReconciliationError{}
+	}
+	errorsJSON, _ := json.Marshal(errorDetails)
+
+	completedAt := time.Now()
+	job.Summary = string(summaryJSON)
+	job.ErrorDetails = string(errorsJSON)
+	job.CompletedAt = &completedAt
+	job.Status = "completed"
+
+	if err := s.db.Save(job).Error; err != nil {
+		return fmt.Errorf("failed to complete reconciliation job: %w", err)
+	}
+	return nil
+}
+
+// reconcileLine scores the candidates for a single statement line and applies the result
+func (s *ReconciliationService) reconcileLine(line *models.BankStatementLine, jobID uuid.UUID) (string, float64, error) {
+	candidates, err := s.findCandidates(line)
+	if err != nil {
+		return "", 0, err
+	}
+
+	outcome := StatementLineUnmatched
+	var best MatchCandidate
+	if len(candidates) > 0 {
+		best = candidates[0]
+		runnerUp := 0.0
+		if len(candidates) > 1 {
+			runnerUp = candidates[1].Confidence
+		}
+
+		switch {
+		case best.Confidence >= s.config.ReconciliationAutoMatchScore && best.Confidence-runnerUp >= ambiguityMargin:
+			outcome = StatementLineMatched
+		case best.Confidence >= s.config.ReconciliationReviewScore:
+			outcome = StatementLineManualReview
+		}
+	}
+
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	candidatesJSON, _ := json.Marshal(candidates)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":                outcome,
+			"match_candidates":      string(candidatesJSON),
+			"reconciliation_job_id": jobID,
+			"match_confidence":      best.Confidence,
+		}
+
+		if outcome == StatementLineMatched {
+			now := time.Now()
+			result := tx.Model(&models.PaymentTransaction{}).
+				Where("id = ? AND reconciled_at IS NULL", best.TransactionID).
+				Update("reconciled_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				// Another line claimed the transaction first; leave this one for review
+				outcome = StatementLineManualReview
+				updates["status"] = outcome
+			} else {
+				updates["matched_transaction_id"] = best.TransactionID
+				updates["matched_by"] = "system"
+				updates["matched_at"] = now
+			}
+		}
+
+		return tx.Model(line).Updates(updates).Error
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to record match: %w", err)
+	}
+
+	return outcome, best.Confidence, nil
+}
+
+// findCandidates loads open transactions near the statement line and ranks them by confidence
+func (s *ReconciliationService) findCandidates(line *models.BankStatementLine) ([]MatchCandidate, error) {
+	window := s.config.ReconciliationDateWindow
+	amount := math.Abs(line.Amount)
+
+	query := s.db.Model(&models.PaymentTransaction{}).
+		Where("bank_connection_id = ? AND currency = ? AND reconciled_at IS NULL", line.BankConnectionID, line.Currency).
+		Where("status IN ?", []string{"processing", "completed"}).
+		Where("COALESCE(processed_at, created_at) BETWEEN ? AND ?", line.BookingDate.Add(-window), line.BookingDate.Add(window))
+
+	if line.Reference != "" {
+		query = query.Where("(reference = ? OR payment_id = ? OR amount BETWEEN ? AND ?)",
+			line.Reference, line.Reference, amount*0.9, amount*1.1)
+	} else {
+		query = query.Where("amount BETWEEN ? AND ?", amount*0.9, amount*1.1)
+	}
+
+	var transactions []models.PaymentTransaction
+	if err := query.Limit(200).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	candidates := make([]MatchCandidate, 0, len(transactions))
+	for _, transaction := range transactions {
+		confidence, reasons := s.scoreMatch(line, &transaction)
+		if confidence <= 0 {
+			continue
+		}
+		candidates = append(candidates, MatchCandidate{
+			TransactionID: transaction.ID,
+			PaymentID:     transaction.PaymentID,
+			Amount:        transaction.Amount,
+			Confidence:    confidence,
+			Reasons:       reasons,
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	return candidates, nil
+}
+
+// scoreMatch combines reference, amount, date and counterparty similarity into a 0-1 confidence
+func (s *ReconciliationService) scoreMatch(line *models.BankStatementLine, transaction *models.PaymentTransaction) (float64, []string) {
+	var reasons []string
+
+	// Reference: the bank may put our reference in either the reference or the free-text description
+	referenceScore := 0.0
+	for _, lineRef := range []string{line.Reference, line.Description} {
+		for _, txRef := range []string{transaction.Reference, transaction.PaymentID, transaction.ExternalPaymentID} {
+			referenceScore = math.Max(referenceScore, referenceSimilarity(lineRef, txRef))
+		}
+	}
+	if referenceScore == 1 {
+		reasons = append(reasons, "reference_exact")
+	} else if referenceScore > 0.5 {
+		reasons = append(reasons, "reference_fuzzy")
+	}
+
+	// Amount: statements may show the gross amount or the amount including fees
+	amount := math.Abs(line.Amount)
+	diff := math.Min(math.Abs(amount-transaction.Amount), math.Abs(amount-(transaction.Amount+transaction.Fees)))
+	amountScore := 0.0
+	if diff <= s.config.ReconciliationAmountTolerance {
+		amountScore = 1
+		reasons = append(reasons, "amount_exact")
+	} else if transaction.Amount > 0 {
+		// Linear fall-off that reaches zero at a 5% difference
+		amountScore = math.Max(0, 1-diff/(transaction.Amount*0.05))
+		if amountScore > 0 {
+			reasons = append(reasons, "amount_close")
+		}
+	}
+
+	// Date: closeness within the configured window
+	transactionDate := transaction.CreatedAt
+	if transaction.ProcessedAt != nil {
+		transactionDate = *transaction.ProcessedAt
+	}
+	dateScore := 0.0
+	if window := s.config.ReconciliationDateWindow; window > 0 {
+		delta := math.Abs(line.BookingDate.Sub(transactionDate).Hours())
+		dateScore = math.Max(0, 1-delta/window.Hours())
+	}
+	if dateScore > 0.5 {
+		reasons = append(reasons, "date_close")
+	}
+
+	// Counterparty: account number first, then name against the payment description
+	counterpartyScore := 0.0
+	if account := normalizeReference(line.CounterpartyAccount); account != "" &&
+		(account == normalizeReference(transaction.ToAccountID) || account == normalizeReference(transaction.FromAccountID)) {
+		counterpartyScore = 1
+		reasons = append(reasons, "counterparty_account")
+	} else if line.CounterpartyName != "" && transaction.Description != "" {
+		counterpartyScore = nameSimilarity(line.CounterpartyName, transaction.Description)
+		if counterpartyScore > 0.7 {
+			reasons = append(reasons, "counterparty_name")
+		}
+	}
+
+	confidence := referenceScore*referenceWeight +
+		amountScore*amountWeight +
+		dateScore*dateWeight +
+		counterpartyScore*counterpartyWeight
+
+	return roundTo(confidence, 4), reasons
+}
+
+// GetManualQueue returns statement lines that need a human decision, limited to the
+// actor's bank connections unless isAdmin
+func (s *ReconciliationService) GetManualQueue(limit, offset int, actorID string, isAdmin bool) ([]models.BankStatementLine, int64, error) {
+	var lines []models.BankStatementLine
+	var total int64
+
+	query := s.db.Model(&models.BankStatementLine{}).Where("status = ?", StatementLineManualReview)
+	if !isAdmin {
+		connections := s.db.Model(&models.BankConnection{}).Select("id").Where("user_id = ?", actorID)
+		query = query.Where("bank_connection_id IN (?)", connections)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("booking_date").Limit(limit).Offset(offset).Find(&lines).Error; err != nil {
+		return nil, 0, err
+	}
+	return lines, total, nil
+}
+
+// ConfirmManualMatch links a statement line to a transaction chosen by a reviewer who
+// owns the line's bank connection, or is an administrator
+func (s *ReconciliationService) ConfirmManualMatch(lineID, transactionID uuid.UUID, userID string, isAdmin bool) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&line, "id = ?", lineID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrStatementLineNotFound
+			}
+			return err
+		}
+		if err := s.checkConnection(tx, line.BankConnectionID, userID, isAdmin); err != nil {
+			return err
+		}
+		if line.Status == StatementLineMatched || line.Status == StatementLineManuallyMatched {
+			return ErrStatementLineMatched
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.PaymentTransaction{}).
+			Where("id = ? AND bank_connection_id = ? AND reconciled_at IS NULL", transactionID, line.BankConnectionID).
+			Update("reconciled_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransactionAlreadyMatched
+		}
+
+		line.Status = StatementLineManuallyMatched
+		line.MatchedTransactionID = &transactionID
+		line.MatchedBy = userID
+		line.MatchedAt = &now
+		return tx.Save(&line).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// IgnoreStatementLine removes a line from the review queue, e.g. bank fees or interest
+func (s *ReconciliationService) IgnoreStatementLine(lineID uuid.UUID, userID string, isAdmin bool) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	if err := s.db.First(&line, "id = ?", lineID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStatementLineNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkConnection(s.db, line.BankConnectionID, userID, isAdmin); err != nil {
+		return nil, err
+	}
+	if line.Status == StatementLineMatched || line.Status == StatementLineManuallyMatched {
+		return nil, ErrStatementLineMatched
+	}
+
+	now := time.Now()
+	line.Status = StatementLineIgnored
+	line.MatchedBy = userID
+	line.MatchedAt = &now
+	if err := s.db.Save(&line).Error; err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// StartScheduler runs the daily, weekly and monthly reconciliation jobs until ctx is cancelled
+func (s *ReconciliationService) StartScheduler(ctx context.Context) {
+	if !s.config.ReconciliationEnabled {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	s.runDueJobs(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDueJobs(now)
+		}
+	}
+}
+
+// runDueJobs creates and runs any scheduled job whose period has closed but has not been run yet
+func (s *ReconciliationService) runDueJobs(now time.Time) {
+	for _, jobType := range []string{ReconciliationJobDaily, ReconciliationJobWeekly, ReconciliationJobMonthly} {
+		start, end := ReconciliationPeriod(jobType, now)
+
+		var count int64
+		if err := s.db.Model(&models.ReconciliationJob{}).
+			Where("job_type = ? AND start_date = ?", jobType, start).
+			Count(&count).Error; err != nil {
+			log.Printf("Reconciliation scheduler: failed to check %s job: %v", jobType, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+
+		// The unique (job_type, start_date) index makes this a no-op on other replicas
+		job, err := s.CreateJob(jobType, start, end)
+		if err != nil {
+			log.Printf("Reconciliation scheduler: skipped %s job: %v", jobType, err)
+			continue
+		}
+		if err := s.RunJob(job.ID); err != nil {
+			log.Printf("Reconciliation scheduler: %s job %s failed: %v", jobType, job.ID, err)
+		}
+	}
+}
+
+// ReconciliationPeriod returns the most recently closed period for a scheduled job type, in UTC
+func ReconciliationPeriod(jobType string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch jobType {
+	case ReconciliationJobWeekly:
+		// Weeks run Monday to Monday
+		offset := (int(today.Weekday()) + 6) % 7
+		end := today.AddDate(0, 0, -offset)
+		return end.AddDate(0, 0, -7), end
+	case ReconciliationJobMonthly:
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return end.AddDate(0, -1, 0), end
+	default:
+		return today.AddDate(0, 0, -1), today
+	}
+}
+
+func (s *ReconciliationService) failJob(job *models.ReconciliationJob, cause error) error {
+	completedAt := time.Now()
+	errorsJSON, _ := json.Marshal([]ReconciliationError{{Error: cause.Error()}})
+	job.Status = "failed"
+	job.ErrorDetails = string(errorsJSON)
+	job.CompletedAt = &completedAt
+	s.db.Save(job)
+	return fmt.Errorf("reconciliation job %s failed: %w", job.ID, cause)
+}
+
+// normalizeReference upper-cases a reference and strips everything but letters and digits
+func normalizeReference(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// referenceSimilarity scores two references: exact, containment, then edit distance
+func referenceSimilarity(a, b string) float64 {
+	a, b = normalizeReference(a), normalizeReference(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	if len(b) >= 6 && strings.Contains(a, b) || len(a) >= 6 && strings.Contains(b, a) {
+		return 0.9
+	}
+	return levenshteinRatio(a, b)
+}
+
+// nameSimilarity compares counterparty names ignoring case, punctuation and word order
+func nameSimilarity(a, b string) float64 {
+	tokensA := strings.Fields(strings.ToUpper(a))
+	tokensB := strings.Fields(strings.ToUpper(b))
+	sort.Strings(tokensA)
+	sort.Strings(tokensB)
+	return levenshteinRatio(normalizeReference(strings.Join(tokensA, "")), normalizeReference(strings.Join(tokensB, "")))
+}
+
+// levenshteinRatio returns 1 - distance/maxLen, so identical strings score 1
+func levenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(maxLen)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
@@ -0,0 +1,862 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+	"shared/retention"
+)
+
+// Periodic filing types
+const (
+	FilingTypeTransactionReport = "transaction_report"
+	FilingTypeExposureReport    = "exposure_report"
+)
+
+// Regulatory filing statuses
+const (
+	FilingStatusDraft        = "draft"
+	FilingStatusSubmitting   = "submitting"   // Claimed by a submitter, delivery in progress
+	FilingStatusSubmitted    = "submitted"    // Delivered, awaiting acknowledgment
+	FilingStatusAcknowledged = "acknowledged" // Accepted by the regulator
+	FilingStatusRejected     = "rejected"     // Rejected; resubmitted automatically until attempts run out
+	FilingStatusFailed       = "failed"       // Out of attempts; needs manual resubmission
+)
+
+// regulatoryReportSchemaVersion versions the periodic report envelope and data layout
+const regulatoryReportSchemaVersion = "1.0"
+
+// filingSchedulerInterval is how often the scheduler looks for due reports,
+// acknowledgments and resubmissions
+const filingSchedulerInterval = 15 * time.Minute
+
+// filingSubmitTimeout is how long a filing may stay claimed for submission before the
+// scheduler treats the delivery as interrupted
+const filingSubmitTimeout = 10 * time.Minute
+
+// periodicFilingTypes are generated automatically every reporting period
+var periodicFilingTypes = []string{FilingTypeTransactionReport, FilingTypeExposureReport}
+
+// largeExposureCount is how many of the largest customer exposures are itemised
+const largeExposureCount = 20
+
+var (
+	ErrFilingExists          = errors.New("a filing already exists for this period")
+	ErrFilingNotSubmittable  = errors.New("filing cannot be submitted in its current status")
+	ErrFilingNotResubmitable = errors.New("only rejected or failed filings can be resubmitted")
+	ErrFilingSigningDisabled = errors.New("filing signing is not configured")
+	ErrReportingDisabled     = errors.New("epic 4 regulatory reporting is disabled")
+)
+
+// RegulatoryFilingService assembles periodic regulatory reports, signs filings and
+// submits them to the Epic 4 reporting endpoint
+type RegulatoryFilingService struct {
+	db     *gorm.DB
+	config *config.Config
+	client *http.Client
+}
+
+func NewRegulatoryFilingService(db *gorm.DB, cfg *config.Config) *RegulatoryFilingService {
+	return &RegulatoryFilingService{db: db, config: cfg, client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// FilingEnvelope wraps every periodic report. Regulators validate Data against the
+// schema named by Schema and SchemaVersion.
+type FilingEnvelope struct {
+	Schema          string       `json:"schema"`
+	SchemaVersion   string       `json:"schema_version"`
+	FilingType      string       `json:"filing_type"`
+	Reference       string       `json:"reference"`
+	ReportingEntity string       `json:"reporting_entity"`
+	Period          FilingPeriod `json:"period"`
+	PreparedBy      string       `json:"prepared_by"`
+	GeneratedAt     time.Time    `json:"generated_at"`
+	Data            interface{}  `json:"data"`
+}
+
+// FilingPeriod is the reporting window, end exclusive
+type FilingPeriod struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Frequency string    `json:"frequency,omitempty"`
+}
+
+// TransactionReport lists the payments made in the period
+type TransactionReport struct {
+	Summary      TransactionReportSummary `json:"summary"`
+	Transactions []ReportedTransaction    `json:"transactions"`
+	Compliance   ComplianceSummary        `json:"compliance"`
+}
+
+// TransactionReportSummary aggregates the period's payments
+type TransactionReportSummary struct {
+	TransactionCount  int                          `json:"transaction_count"`
+	ByCurrency        map[string]CurrencyAggregate `json:"by_currency"`
+	ByStatus          map[string]int               `json:"by_status"`
+	ByType            map[string]int               `json:"by_type"`
+	CrossBorderCount  int                          `json:"cross_border_count"`
+	LargeTransactions int                          `json:"large_transactions"` // At or above the suspicious activity threshold
+}
+
+// CurrencyAggregate totals amounts in one currency
+type CurrencyAggregate struct {
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+	Fees   float64 `json:"fees,omitempty"`
+}
+
+// ReportedTransaction is one payment in a transaction report
+type ReportedTransaction struct {
+	ID                  uuid.UUID  `json:"id"`
+	PaymentID           string     `json:"payment_id"`
+	BankConnectionID    uuid.UUID  `json:"bank_connection_id"`
+	CustomerID          *uuid.UUID `json:"customer_id,omitempty"`
+	Type                string     `json:"type"`
+	Amount              float64    `json:"amount"`
+	Currency            string     `json:"currency"`
+	Fees                float64    `json:"fees"`
+	Status              string     `json:"status"`
+	CounterpartyCountry string     `json:"counterparty_country,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	ProcessedAt         *time.Time `json:"processed_at,omitempty"`
+}
+
+// ExposureReport describes the financing book at the end of the period
+type ExposureReport struct {
+	AsOf            time.Time                    `json:"as_of"`
+	ItemCount       int                          `json:"item_count"`
+	ByCurrency      map[string]ExposureAggregate `json:"by_currency"`
+	ByRiskRating    map[string]ExposureAggregate `json:"by_risk_rating"`
+	ByIndustry      map[string]ExposureAggregate `json:"by_industry"`
+	ByStatus        map[string]ExposureAggregate `json:"by_status"`
+	LargeExposures  []CustomerExposure           `json:"large_exposures"`
+	NewInPeriod     int                          `json:"new_in_period"`
+	DefaultedAmount map[string]float64           `json:"defaulted_amount"` // Outstanding on defaulted items, by currency
+	Compliance      ComplianceSummary            `json:"compliance"`
+}
+
+// ExposureAggregate totals portfolio items
+type ExposureAggregate struct {
+	Count       int     `json:"count"`
+	Principal   float64 `json:"principal"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// CustomerExposure is one customer's outstanding exposure in one currency
+type CustomerExposure struct {
+	CustomerID  uuid.UUID `json:"customer_id"`
+	Currency    string    `json:"currency"`
+	Outstanding float64   `json:"outstanding"`
+	Items       int       `json:"items"`
+	Share       float64   `json:"share"` // Of total outstanding in the currency, 0-1
+}
+
+// ComplianceSummary counts the compliance records checked in the period
+type ComplianceSummary struct {
+	ByType              map[string]map[string]int `json:"by_type"` // compliance_type -> status -> count
+	ReportedToAuthority int                       `json:"reported_to_authority"`
+}
+
+// GeneratePeriodicReport assembles a transaction or exposure report for [start, end)
+// and stores it as a draft filing
+func (s *RegulatoryFilingService) GeneratePeriodicReport(filingType string, start, end time.Time, preparedBy string) (*models.RegulatoryFiling, error) {
+	if filingType != FilingTypeTransactionReport && filingType != FilingTypeExposureReport {
+		return nil, fmt.Errorf("%w: unsupported filing type %q", ErrInvalidFilingRequest, filingType)
+	}
+	start, end = start.UTC(), end.UTC()
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: period_end must be after period_start", ErrInvalidFilingRequest)
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.RegulatoryFiling{}).
+		Where("filing_type = ? AND period_start = ? AND period_end = ?", filingType, start, end).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check existing filings: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrFilingExists
+	}
+
+	filing := &models.RegulatoryFiling{
+		FilingType:    filingType,
+		Reference:     fmt.Sprintf("%s-%s-%s", filingReferencePrefix(filingType), start.Format("20060102"), strings.ToUpper(uuid.NewString()[:8])),
+		Status:        FilingStatusDraft,
+		SchemaVersion: regulatoryReportSchemaVersion,
+		PeriodStart:   &start,
+		PeriodEnd:     &end,
+		CreatedBy:     preparedBy,
+	}
+	payload, err := s.renderPeriodicReport(filing)
+	if err != nil {
+		return nil, err
+	}
+	filing.Payload = payload
+
+	if err := s.db.Create(filing).Error; err != nil {
+		return nil, fmt.Errorf("failed to create regulatory filing: %w", err)
+	}
+	return filing, nil
+}
+
+func filingReferencePrefix(filingType string) string {
+	switch filingType {
+	case FilingTypeTransactionReport:
+		return "TXR"
+	case FilingTypeExposureReport:
+		return "EXR"
+	default:
+		return strings.ToUpper(filingType)
+	}
+}
+
+// renderPeriodicReport builds the envelope for a periodic filing from current data
+func (s *RegulatoryFilingService) renderPeriodicReport(filing *models.RegulatoryFiling) (string, error) {
+	start, end := *filing.PeriodStart, *filing.PeriodEnd
+
+	var data interface{}
+	var err error
+	switch filing.FilingType {
+	case FilingTypeTransactionReport:
+		data, err = s.buildTransactionReport(start, end)
+	case FilingTypeExposureReport:
+		data, err = s.buildExposureReport(start, end)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	envelope := FilingEnvelope{
+		Schema:          "epic4." + filing.FilingType,
+		SchemaVersion:   filing.SchemaVersion,
+		FilingType:      filing.FilingType,
+		Reference:       filing.Reference,
+		ReportingEntity: s.config.Epic4Config.ReportingEntityID,
+		Period:          FilingPeriod{Start: start, End: end, Frequency: s.config.Epic4Config.ReportingFrequency},
+		PreparedBy:      filing.CreatedBy,
+		GeneratedAt:     time.Now().UTC(),
+		Data:            data,
+	}
+	return toJSON(envelope), nil
+}
+
+func (s *RegulatoryFilingService) buildTransactionReport(start, end time.Time) (*TransactionReport, error) {
+	var payments []models.PaymentTransaction
+	if err := s.db.Where("created_at >= ? AND created_at < ?", start, end).Order("created_at ASC").Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load payments: %w", err)
+	}
+
+	report := &TransactionReport{
+		Summary: TransactionReportSummary{
+			ByCurrency: map[string]CurrencyAggregate{},
+			ByStatus:   map[string]int{},
+			ByType:     map[string]int{},
+		},
+		Transactions: make([]ReportedTransaction, 0, len(payments)),
+	}
+	for _, p := range payments {
+		report.Transactions = append(report.Transactions, ReportedTransaction{
+			ID:                  p.ID,
+			PaymentID:           p.PaymentID,
+			BankConnectionID:    p.BankConnectionID,
+			CustomerID:          p.CustomerID,
+			Type:                p.Type,
+			Amount:              p.Amount,
+			Currency:            p.Currency,
+			Fees:                p.Fees,
+			Status:              p.Status,
+			CounterpartyCountry: p.CounterpartyCountry,
+			CreatedAt:           p.CreatedAt,
+			ProcessedAt:         p.ProcessedAt,
+		})
+
+		summary := &report.Summary
+		summary.TransactionCount++
+		summary.ByStatus[p.Status]++
+		summary.ByType[p.Type]++
+		if p.CounterpartyCountry != "" {
+			summary.CrossBorderCount++
+		}
+		if s.config.SuspiciousActivityThreshold > 0 && p.Amount >= s.config.SuspiciousActivityThreshold {
+			summary.LargeTransactions++
+		}
+		aggregate := summary.ByCurrency[p.Currency]
+		aggregate.Count++
+		aggregate.Amount = roundTo(aggregate.Amount+p.Amount, 2)
+		aggregate.Fees = roundTo(aggregate.Fees+p.Fees, 2)
+		summary.ByCurrency[p.Currency] = aggregate
+	}
+
+	compliance, err := s.summariseCompliance(start, end)
+	if err != nil {
+		return nil, err
+	}
+	report.Compliance = *compliance
+	return report, nil
+}
+
+func (s *RegulatoryFilingService) buildExposureReport(start, end time.Time) (*ExposureReport, error) {
+	var items []models.PortfolioItem
+	if err := s.db.Where("created_at < ? AND status IN ?", end, []string{"active", "defaulted"}).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to load portfolio items: %w", err)
+	}
+
+	report := &ExposureReport{
+		AsOf:            end,
+		ItemCount:       len(items),
+		ByCurrency:      map[string]ExposureAggregate{},
+		ByRiskRating:    map[string]ExposureAggregate{},
+		ByIndustry:      map[string]ExposureAggregate{},
+		ByStatus:        map[string]ExposureAggregate{},
+		DefaultedAmount: map[string]float64{},
+		LargeExposures:  []CustomerExposure{},
+	}
+	add := func(groups map[string]ExposureAggregate, key string, item models.PortfolioItem) {
+		if key == "" {
+			key = "unspecified"
+		}
+		aggregate := groups[key]
+		aggregate.Count++
+		aggregate.Principal = roundTo(aggregate.Principal+item.Principal, 2)
+		aggregate.Outstanding = roundTo(aggregate.Outstanding+item.Outstanding, 2)
+		groups[key] = aggregate
+	}
+
+	type customerKey struct {
+		customer uuid.UUID
+		currency string
+	}
+	byCustomer := map[customerKey]*CustomerExposure{}
+	for _, item := range items {
+		add(report.ByCurrency, item.Currency, item)
+		add(report.ByRiskRating, item.RiskRating, item)
+		add(report.ByIndustry, item.Industry, item)
+		add(report.ByStatus, item.Status, item)
+		if !item.CreatedAt.Before(start) {
+			report.NewInPeriod++
+		}
+		if item.Status == "defaulted" {
+			report.DefaultedAmount[item.Currency] = roundTo(report.DefaultedAmount[item.Currency]+item.Outstanding, 2)
+		}
+
+		key := customerKey{item.CustomerID, item.Currency}
+		exposure, ok := byCustomer[key]
+		if !ok {
+			exposure = &CustomerExposure{CustomerID: item.CustomerID, Currency: item.Currency}
+			byCustomer[key] = exposure
+		}
+		exposure.Outstanding = roundTo(exposure.Outstanding+item.Outstanding, 2)
+		exposure.Items++
+	}
+
+	for _, exposure := range byCustomer {
+		if total := report.ByCurrency[exposure.Currency].Outstanding; total > 0 {
+			exposure.Share = roundTo(exposure.Outstanding/total, 4)
+		}
+		report.LargeExposures = append(report.LargeExposures, *exposure)
+	}
+	sort.Slice(report.LargeExposures, func(i, j int) bool {
+		if report.LargeExposures[i].Share != report.LargeExposures[j].Share {
+			return report.LargeExposures[i].Share > report.LargeExposures[j].Share
+		}
+		return report.LargeExposures[i].CustomerID.String() < report.LargeExposures[j].CustomerID.String()
+	})
+	if len(report.LargeExposures) > largeExposureCount {
+		report.LargeExposures = report.LargeExposures[:largeExposureCount]
+	}
+
+	compliance, err := s.summariseCompliance(start, end)
+	if err != nil {
+		return nil, err
+	}
+	report.Compliance = *compliance
+	return report, nil
+}
+
+func (s *RegulatoryFilingService) summariseCompliance(start, end time.Time) (*ComplianceSummary, error) {
+	var rows []struct {
+		ComplianceType      string
+		Status              string
+		ReportedToAuthority bool
+		Count               int
+	}
+	err := s.db.Model(&models.ComplianceRecord{}).
+		Select("compliance_type, status, reported_to_authority, COUNT(*) AS count").
+		Where("checked_at >= ? AND checked_at < ?", start, end).
+		Group("compliance_type, status, reported_to_authority").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise compliance records: %w", err)
+	}
+
+	summary := &ComplianceSummary{ByType: map[string]map[string]int{}}
+	for _, row := range rows {
+		if summary.ByType[row.ComplianceType] == nil {
+			summary.ByType[row.ComplianceType] = map[string]int{}
+		}
+		summary.ByType[row.ComplianceType][row.Status] += row.Count
+		if row.ReportedToAuthority {
+			summary.ReportedToAuthority += row.Count
+		}
+	}
+	return summary, nil
+}
+
+// loadFilingSigner reads the Epic 4 certificate and its private key. Filings are signed
+// the same way as retention reports.
+func loadFilingSigner(certPath, keyPath string) (*retention.Signer, error) {
+	if certPath == "" {
+		return nil, ErrFilingSigningDisabled
+	}
+	return retention.LoadSigner(certPath, keyPath)
+}
+
+// Sign signs the filing's payload with the configured certificate's key
+func (s *RegulatoryFilingService) Sign(filing *models.RegulatoryFiling) error {
+	signer, err := loadFilingSigner(s.config.Epic4Config.CertificatePath, s.config.Epic4Config.SigningKeyPath)
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Sign([]byte(filing.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to sign filing: %w", err)
+	}
+
+	now := time.Now()
+	filing.Signature = signature
+	filing.SignatureAlgorithm = signer.Algorithm()
+	filing.CertificateFingerprint = signer.Fingerprint()
+	filing.SignedAt = &now
+	return s.db.Model(filing).Updates(map[string]interface{}{
+		"signature":               filing.Signature,
+		"signature_algorithm":     filing.SignatureAlgorithm,
+		"certificate_fingerprint": filing.CertificateFingerprint,
+		"signed_at":               filing.SignedAt,
+	}).Error
+}
+
+// filingResponse is the reporting endpoint's answer to a submission or status query
+type filingResponse struct {
+	SubmissionID     string   `json:"submission_id"`
+	Status           string   `json:"status"` // received, pending, accepted, rejected
+	AcknowledgmentID string   `json:"acknowledgment_id"`
+	Message          string   `json:"message"`
+	Errors           []string `json:"errors"`
+}
+
+func (r *filingResponse) reason() string {
+	if len(r.Errors) > 0 {
+		return strings.Join(r.Errors, "; ")
+	}
+	return r.Message
+}
+
+// Submit signs a draft filing and delivers it to the reporting endpoint
+func (s *RegulatoryFilingService) Submit(id uuid.UUID, submittedBy string) (*models.RegulatoryFiling, error) {
+	filing, err := s.getFiling(id)
+	if err != nil {
+		return nil, err
+	}
+	if filing.Status != FilingStatusDraft || !s.claim(filing, FilingStatusDraft) {
+		return nil, fmt.Errorf("%w: %s", ErrFilingNotSubmittable, filing.Status)
+	}
+	return filing, s.submit(filing, submittedBy, FilingStatusDraft)
+}
+
+// Resubmit delivers a rejected or failed filing again. Periodic reports are rebuilt
+// first so corrections made to the underlying records since the rejection are included.
+func (s *RegulatoryFilingService) Resubmit(id uuid.UUID, submittedBy string) (*models.RegulatoryFiling, error) {
+	filing, err := s.getFiling(id)
+	if err != nil {
+		return nil, err
+	}
+	previous := filing.Status
+	if (previous != FilingStatusRejected && previous != FilingStatusFailed) || !s.claim(filing, previous) {
+		return nil, fmt.Errorf("%w: %s", ErrFilingNotResubmitable, filing.Status)
+	}
+	if filing.PeriodStart != nil && filing.PeriodEnd != nil {
+		payload, err := s.renderPeriodicReport(filing)
+		if err != nil {
+			s.release(filing, previous)
+			return nil, err
+		}
+		filing.Payload = payload
+		if err := s.db.Model(filing).Update("payload", payload).Error; err != nil {
+			s.release(filing, previous)
+			return nil, fmt.Errorf("failed to update filing payload: %w", err)
+		}
+	}
+	return filing, s.submit(filing, submittedBy, previous)
+}
+
+// claim moves a filing that is still in status from to submitting. Only the caller whose
+// update took effect may deliver it, so concurrent submitters and scheduler replicas never
+// send the same filing twice.
+func (s *RegulatoryFilingService) claim(filing *models.RegulatoryFiling, from string) bool {
+	result := s.db.Model(&models.RegulatoryFiling{}).
+		Where("id = ? AND status = ?", filing.ID, from).
+		Updates(map[string]interface{}{"status": FilingStatusSubmitting, "updated_at": time.Now()})
+	if result.Error != nil {
+		log.Printf("Failed to claim filing %s for submission: %v", filing.Reference, result.Error)
+		return false
+	}
+	if result.RowsAffected != 1 {
+		return false
+	}
+	filing.Status = FilingStatusSubmitting
+	return true
+}
+
+// release returns a claimed filing that was not delivered to its previous status
+func (s *RegulatoryFilingService) release(filing *models.RegulatoryFiling, previous string) {
+	filing.Status = previous
+	if err := s.db.Model(filing).Update("status", previous).Error; err != nil {
+		log.Printf("Failed to release filing %s: %v", filing.Reference, err)
+	}
+}
+
+// submit delivers a filing claimed from status previous. Filings that are not delivered
+// go back to that status.
+func (s *RegulatoryFilingService) submit(filing *models.RegulatoryFiling, submittedBy, previous string) error {
+	if !s.config.Epic4Config.Enabled {
+		s.release(filing, previous)
+		return ErrReportingDisabled
+	}
+	if err := s.Sign(filing); err != nil {
+		s.release(filing, previous)
+		return err
+	}
+
+	payloadHash := sha256.Sum256([]byte(filing.Payload))
+	submission := &models.RegulatoryFilingSubmission{
+		FilingID:    filing.ID,
+		Attempt:     filing.Attempts + 1,
+		PayloadHash: hex.EncodeToString(payloadHash[:]),
+		SubmittedBy: submittedBy,
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(s.config.Epic4Config.ReportingEndpoint, "/")+"/filings", strings.NewReader(filing.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to build submission request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", s.config.Epic4Config.APIKey)
+	req.Header.Set("X-Filing-Reference", filing.Reference)
+	req.Header.Set("X-Filing-Type", filing.FilingType)
+	req.Header.Set("X-Signature", filing.Signature)
+	req.Header.Set("X-Signature-Algorithm", filing.SignatureAlgorithm)
+	req.Header.Set("X-Certificate-Fingerprint", filing.CertificateFingerprint)
+
+	response, httpStatus, sendErr := s.send(req)
+	now := time.Now()
+	submission.HTTPStatus = httpStatus
+	submission.RespondedAt = &now
+	filing.Attempts++
+
+	filing.Status = previous
+	updates := map[string]interface{}{"attempts": filing.Attempts, "status": previous}
+	switch {
+	case sendErr != nil:
+		// Transport errors and server faults are retried without counting as a rejection
+		submission.Status = "error"
+		submission.Error = sendErr.Error()
+		filing.LastError = sendErr.Error()
+		updates["last_error"] = filing.LastError
+		s.scheduleRetry(filing, updates, now)
+	default:
+		submission.Response = toJSON(response)
+		submission.SubmissionID = response.SubmissionID
+		filing.SubmissionID = response.SubmissionID
+		filing.SubmittedAt = &now
+		updates["submission_id"] = filing.SubmissionID
+		updates["submitted_at"] = filing.SubmittedAt
+		updates["last_error"] = ""
+		submission.Status = s.applyResponse(filing, response, updates, now)
+		submission.AcknowledgmentID = response.AcknowledgmentID
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(submission).Error; err != nil {
+			return err
+		}
+		return tx.Model(filing).Updates(updates).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record filing submission: %w", err)
+	}
+	return nil
+}
+
+// applyResponse moves the filing to the status reported by the endpoint and returns the
+// submission status to record
+func (s *RegulatoryFilingService) applyResponse(filing *models.RegulatoryFiling, response *filingResponse, updates map[string]interface{}, now time.Time) string {
+	switch strings.ToLower(response.Status) {
+	case "accepted", "acknowledged":
+		filing.Status = FilingStatusAcknowledged
+		filing.AcknowledgmentID = response.AcknowledgmentID
+		filing.AcknowledgedAt = &now
+		filing.NextAttemptAt = nil
+		updates["status"] = filing.Status
+		updates["acknowledgment_id"] = filing.AcknowledgmentID
+		updates["acknowledged_at"] = filing.AcknowledgedAt
+		updates["rejection_reason"] = ""
+		updates["next_attempt_at"] = nil
+		return "accepted"
+	case "rejected":
+		filing.RejectionReason = response.reason()
+		updates["rejection_reason"] = filing.RejectionReason
+		filing.Status = FilingStatusRejected
+		updates["status"] = filing.Status
+		s.scheduleRetry(filing, updates, now)
+		return "rejected"
+	default:
+		filing.Status = FilingStatusSubmitted
+		filing.NextAttemptAt = nil
+		updates["status"] = filing.Status
+		updates["next_attempt_at"] = nil
+		return "pending"
+	}
+}
+
+// scheduleRetry sets the next automatic attempt, or fails the filing once attempts are
+// exhausted
+func (s *RegulatoryFilingService) scheduleRetry(filing *models.RegulatoryFiling, updates map[string]interface{}, now time.Time) {
+	if filing.Attempts >= s.config.Epic4Config.MaxSubmissionAttempts {
+		filing.Status = FilingStatusFailed
+		filing.NextAttemptAt = nil
+		updates["status"] = filing.Status
+		updates["next_attempt_at"] = nil
+		return
+	}
+	next := now.Add(s.config.Epic4Config.ResubmissionDelay)
+	filing.NextAttemptAt = &next
+	updates["next_attempt_at"] = next
+}
+
+// send performs a request against the reporting endpoint. Rejections come back as
+// responses; only transport failures and server errors are returned as errors.
+func (s *RegulatoryFilingService) send(req *http.Request) (*filingResponse, int, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to reach reporting endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read reporting endpoint response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, resp.StatusCode, fmt.Errorf("reporting endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	response := &filingResponse{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, response); err != nil {
+			return nil, resp.StatusCode, fmt.Errorf("failed to parse reporting endpoint response: %w", err)
+		}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		// Validation and authorisation failures are rejections of this submission
+		response.Status = "rejected"
+		if response.reason() == "" {
+			response.Message = fmt.Sprintf("reporting endpoint returned status %d", resp.StatusCode)
+		}
+	}
+	return response, resp.StatusCode, nil
+}
+
+// RefreshStatus asks the reporting endpoint for the acknowledgment of a submitted filing
+func (s *RegulatoryFilingService) RefreshStatus(id uuid.UUID) (*models.RegulatoryFiling, error) {
+	filing, err := s.getFiling(id)
+	if err != nil {
+		return nil, err
+	}
+	if filing.Status != FilingStatusSubmitted || filing.SubmissionID == "" {
+		return filing, nil
+	}
+	return filing, s.refreshStatus(filing)
+}
+
+func (s *RegulatoryFilingService) refreshStatus(filing *models.RegulatoryFiling) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(s.config.Epic4Config.ReportingEndpoint, "/")+"/filings/"+filing.SubmissionID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build status request: %w", err)
+	}
+	req.Header.Set("X-API-Key", s.config.Epic4Config.APIKey)
+
+	response, _, err := s.send(req)
+	if err != nil {
+		return err
+	}
+	if status := strings.ToLower(response.Status); status != "accepted" && status != "acknowledged" && status != "rejected" {
+		return nil // still pending
+	}
+
+	updates := map[string]interface{}{}
+	submissionStatus := s.applyResponse(filing, response, updates, time.Now())
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RegulatoryFilingSubmission{}).
+			Where("filing_id = ? AND submission_id = ?", filing.ID, filing.SubmissionID).
+			Updates(map[string]interface{}{
+				"status":            submissionStatus,
+				"acknowledgment_id": response.AcknowledgmentID,
+				"response":          toJSON(response),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(filing).Updates(updates).Error
+	})
+}
+
+// ListSubmissions returns a filing's submission attempts, oldest first
+func (s *RegulatoryFilingService) ListSubmissions(filingID uuid.UUID) ([]models.RegulatoryFilingSubmission, error) {
+	if _, err := s.getFiling(filingID); err != nil {
+		return nil, err
+	}
+	submissions := []models.RegulatoryFilingSubmission{}
+	if err := s.db.Where("filing_id = ?", filingID).Order("attempt ASC").Find(&submissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list filing submissions: %w", err)
+	}
+	return submissions, nil
+}
+
+func (s *RegulatoryFilingService) getFiling(id uuid.UUID) (*models.RegulatoryFiling, error) {
+	var filing models.RegulatoryFiling
+	if err := s.db.Where("id = ?", id).First(&filing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRegulatoryFilingNotFound
+		}
+		return nil, fmt.Errorf("failed to load regulatory filing: %w", err)
+	}
+	return &filing, nil
+}
+
+// reportingPeriod returns the most recent complete reporting period before now
+func reportingPeriod(frequency string, now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch strings.ToLower(frequency) {
+	case "daily":
+		return today.AddDate(0, 0, -1), today, nil
+	case "weekly":
+		weekday := (int(today.Weekday()) + 6) % 7 // Monday = 0
+		end := today.AddDate(0, 0, -weekday)
+		return end.AddDate(0, 0, -7), end, nil
+	case "monthly":
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return end.AddDate(0, -1, 0), end, nil
+	case "quarterly":
+		end := time.Date(now.Year(), time.Month((int(now.Month())-1)/3*3+1), 1, 0, 0, 0, 0, time.UTC)
+		return end.AddDate(0, -3, 0), end, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unsupported reporting frequency %q", frequency)
+	}
+}
+
+// StartScheduler generates the periodic reports for each completed reporting period,
+// submits them when automatic filing is enabled, collects acknowledgments and retries
+// rejected submissions until ctx is cancelled
+func (s *RegulatoryFilingService) StartScheduler(ctx context.Context) {
+	if !s.config.Epic4Config.Enabled || !s.config.EnableRegulatoryReporting {
+		return
+	}
+
+	ticker := time.NewTicker(filingSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runSchedule()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RegulatoryFilingService) runSchedule() {
+	if s.config.Epic4Config.TransactionReporting {
+		start, end, err := reportingPeriod(s.config.Epic4Config.ReportingFrequency, time.Now())
+		if err != nil {
+			log.Printf("Regulatory reporting: %v", err)
+		} else {
+			for _, filingType := range periodicFilingTypes {
+				filing, err := s.GeneratePeriodicReport(filingType, start, end, "system")
+				if errors.Is(err, ErrFilingExists) {
+					continue
+				}
+				if err != nil {
+					log.Printf("Failed to generate %s for %s: %v", filingType, start.Format("2006-01-02"), err)
+					continue
+				}
+				log.Printf("Generated %s %s", filingType, filing.Reference)
+			}
+		}
+	}
+
+	if !s.config.Epic4Config.RegulatoryFilingAuto {
+		return
+	}
+
+	// Submit generated periodic drafts; SARs are submitted by an analyst
+	var drafts []models.RegulatoryFiling
+	if err := s.db.Where("status = ? AND filing_type IN ?", FilingStatusDraft, periodicFilingTypes).Find(&drafts).Error; err != nil {
+		log.Printf("Failed to load draft filings: %v", err)
+	}
+	for i := range drafts {
+		if drafts[i].NextAttemptAt != nil && drafts[i].NextAttemptAt.After(time.Now()) {
+			continue
+		}
+		if !s.claim(&drafts[i], FilingStatusDraft) {
+			continue // submitted by someone else since it was loaded
+		}
+		if err := s.submit(&drafts[i], "system", FilingStatusDraft); err != nil {
+			log.Printf("Failed to submit filing %s: %v", drafts[i].Reference, err)
+		}
+	}
+
+	// A claim older than the submit timeout belongs to a submitter that stopped mid-delivery.
+	// Whether the regulator received it is unknown, so it is left for manual resubmission.
+	interrupted := s.db.Model(&models.RegulatoryFiling{}).
+		Where("status = ? AND updated_at < ?", FilingStatusSubmitting, time.Now().Add(-filingSubmitTimeout)).
+		Updates(map[string]interface{}{"status": FilingStatusFailed, "last_error": "submission was interrupted", "next_attempt_at": nil})
+	if interrupted.Error != nil {
+		log.Printf("Failed to recover interrupted filing submissions: %v", interrupted.Error)
+	}
+
+	var submitted []models.RegulatoryFiling
+	if err := s.db.Where("status = ? AND submission_id <> ''", FilingStatusSubmitted).Find(&submitted).Error; err != nil {
+		log.Printf("Failed to load submitted filings: %v", err)
+	}
+	for i := range submitted {
+		if err := s.refreshStatus(&submitted[i]); err != nil {
+			log.Printf("Failed to refresh status of filing %s: %v", submitted[i].Reference, err)
+		}
+	}
+
+	var due []models.RegulatoryFiling
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", FilingStatusRejected, time.Now()).Find(&due).Error; err != nil {
+		log.Printf("Failed to load rejected filings: %v", err)
+	}
+	for _, filing := range due {
+		if _, err := s.Resubmit(filing.ID, "system"); err != nil && !errors.Is(err, ErrFilingNotResubmitable) {
+			log.Printf("Failed to resubmit filing %s: %v", filing.Reference, err)
+		}
+	}
+}
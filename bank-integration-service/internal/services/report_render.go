@@ -0,0 +1,318 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// renderCSV writes each table as a title line, a header row and its rows, separated by
+// blank lines
+func renderCSV(tables []reportTable) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for i, table := range tables {
+		if i > 0 {
+			w.Write([]string{})
+		}
+		w.Write([]string{table.Title})
+		w.Write(table.Columns)
+		for _, row := range table.Rows {
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// renderXLSX builds a minimal Office Open XML workbook with one worksheet per table.
+// Numeric cells are written as numbers so they can be summed in a spreadsheet.
+func renderXLSX(tables []reportTable) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	names := make([]string, len(tables))
+	used := map[string]bool{}
+	for i, table := range tables {
+		names[i] = sheetName(table.Title, i+1, used)
+	}
+
+	var sheets, rels, overrides strings.Builder
+	for i := range tables {
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(names[i]), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(tables)+1)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		// Style 1 is the bold header font
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+			`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for i, table := range tables {
+		parts = append(parts, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), worksheetXML(table)})
+	}
+
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func worksheetXML(table reportTable) string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow := func(r int, cells []string, header bool) {
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		for c, value := range cells {
+			ref := columnName(c) + strconv.Itoa(r)
+			if header {
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr" s="1"><is><t>%s</t></is></c>`, ref, xmlEscape(value))
+			} else if _, err := strconv.ParseFloat(value, 64); err == nil && value != "" {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+			} else {
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xmlEscape(value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	writeRow(1, table.Columns, true)
+	for i, row := range table.Rows {
+		writeRow(i+2, row, false)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// sheetName makes a valid, unique worksheet name of at most 31 characters
+func sheetName(title string, index int, used map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, title)
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	if name == "" || used[strings.ToLower(name)] {
+		suffix := fmt.Sprintf(" %d", index)
+		if len(name)+len(suffix) > 31 {
+			name = name[:31-len(suffix)]
+		}
+		name += suffix
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// columnName converts a zero-based column index to A, B, ..., Z, AA, ...
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func xmlEscape(value string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// PDF page layout in points (A4 landscape)
+const (
+	pdfPageWidth  = 842.0
+	pdfPageHeight = 595.0
+	pdfMargin     = 36.0
+	pdfFontSize   = 7.0
+	pdfLineHeight = 10.0
+)
+
+// renderPDF lays the tables out as text on A4 landscape pages using the built-in
+// Helvetica fonts, so no font files need to be embedded
+func renderPDF(title string, tables []reportTable) ([]byte, error) {
+	var pages []string
+	var page strings.Builder
+	y := pdfPageHeight - pdfMargin
+
+	newPage := func() {
+		if page.Len() > 0 {
+			pages = append(pages, page.String())
+		}
+		page.Reset()
+		y = pdfPageHeight - pdfMargin
+	}
+	text := func(font string, size, x float64, value string) {
+		fmt.Fprintf(&page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(value))
+	}
+	ensure := func(lines int) {
+		if y-float64(lines)*pdfLineHeight < pdfMargin+pdfLineHeight {
+			newPage()
+		}
+	}
+
+	text("F2", 14, pdfMargin, title)
+	y -= 2 * pdfLineHeight
+
+	usable := pdfPageWidth - 2*pdfMargin
+	for _, table := range tables {
+		ensure(3)
+		text("F2", 10, pdfMargin, table.Title)
+		y -= 1.5 * pdfLineHeight
+
+		widths := pdfColumnWidths(table, usable)
+		row := func(font string, cells []string) {
+			x := pdfMargin
+			for i, cell := range cells {
+				if i >= len(widths) {
+					break
+				}
+				text(font, pdfFontSize, x, pdfFit(cell, widths[i]))
+				x += widths[i]
+			}
+			y -= pdfLineHeight
+		}
+
+		row("F2", table.Columns)
+		for _, cells := range table.Rows {
+			if y-pdfLineHeight < pdfMargin+pdfLineHeight {
+				newPage()
+				row("F2", table.Columns)
+			}
+			row("F1", cells)
+		}
+		if len(table.Rows) == 0 {
+			text("F1", pdfFontSize, pdfMargin, "No data")
+			y -= pdfLineHeight
+		}
+		y -= pdfLineHeight
+	}
+	newPage()
+
+	// Objects: 1 catalog, 2 page tree, 3-4 fonts, then a page and content stream per page
+	var out bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		footer := fmt.Sprintf("BT /F1 7.0 Tf %.2f %.2f Td (Page %d of %d) Tj ET\n", pdfPageWidth-pdfMargin-50, pdfMargin/2, i+1, len(pages))
+		content += footer
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
+
+// pdfColumnWidths shares the usable width between columns in proportion to their
+// longest value, capped so one wide column cannot squeeze out the rest
+func pdfColumnWidths(table reportTable, usable float64) []float64 {
+	lengths := make([]float64, len(table.Columns))
+	for i, column := range table.Columns {
+		lengths[i] = float64(len(column))
+	}
+	for _, row := range table.Rows {
+		for i, cell := range row {
+			if i < len(lengths) && float64(len(cell)) > lengths[i] {
+				lengths[i] = float64(len(cell))
+			}
+		}
+	}
+
+	total := 0.0
+	for i := range lengths {
+		if lengths[i] > 40 {
+			lengths[i] = 40
+		}
+		lengths[i] += 2
+		total += lengths[i]
+	}
+	widths := make([]float64, len(lengths))
+	for i := range lengths {
+		widths[i] = usable * lengths[i] / total
+	}
+	return widths
+}
+
+// pdfFit truncates a value to roughly fit a column, assuming an average Helvetica
+// glyph width of half the font size
+func pdfFit(value string, width float64) string {
+	max := int(width / (pdfFontSize * 0.5))
+	if max < 4 || len(value) <= max-1 {
+		return value
+	}
+	return value[:max-3] + ".."
+}
+
+func pdfEscape(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,681 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// Report job statuses
+const (
+	ReportStatusQueued    = "queued"
+	ReportStatusRunning   = "running"
+	ReportStatusCompleted = "completed"
+	ReportStatusFailed    = "failed"
+	ReportStatusExpired   = "expired"
+)
+
+// reportStaleAfter is how long a job may stay running before the worker assumes its
+// replica died and puts it back on the queue
+const reportStaleAfter = 30 * time.Minute
+
+var (
+	ErrReportNotFound       = errors.New("report not found")
+	ErrReportNotReady       = errors.New("report has not finished generating")
+	ErrReportExpired        = errors.New("report artifact has expired")
+	ErrReportFailed         = errors.New("report generation failed")
+	ErrInvalidReportRequest = errors.New("invalid report request")
+)
+
+// reportTypes lists the supported report types and what they contain
+var reportTypes = map[string]string{
+	"monthly_pack": "Overview, risk, exposures, concentrations, performance and holdings",
+	"holdings":     "Portfolio items originated by the end of the period",
+	"risk":         "Expected loss by rating and delinquency buckets",
+	"exposures":    "Exposure breakdowns and concentration metrics",
+	"performance":  "Yield, vintages and daily snapshots for the period",
+}
+
+var reportContentTypes = map[string]string{
+	"csv":  "text/csv",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pdf":  "application/pdf",
+}
+
+// ReportRequest is the input for enqueueing a portfolio report
+type ReportRequest struct {
+	ReportType       string     `json:"report_type" binding:"required"`
+	Format           string     `json:"format" binding:"required"` // csv, xlsx, pdf
+	Period           string     `json:"period"`                     // YYYY-MM; alternative to start/end dates
+	StartDate        string     `json:"start_date"`                 // YYYY-MM-DD
+	EndDate          string     `json:"end_date"`                   // YYYY-MM-DD
+	BankConnectionID *uuid.UUID `json:"bank_connection_id"`
+	Currency         string     `json:"currency"`
+	Industry         string     `json:"industry"`
+}
+
+// reportFilters is the JSON stored with a report job
+type reportFilters struct {
+	Currency string `json:"currency,omitempty"`
+	Industry string `json:"industry,omitempty"`
+}
+
+// reportTable is one titled table of report output; every renderer works from these
+type reportTable struct {
+	Title   string
+	Columns []string
+	Rows    [][]string
+}
+
+// ReportService queues portfolio reports and renders them in a background worker
+type ReportService struct {
+	db               *gorm.DB
+	config           *config.Config
+	portfolioService *PortfolioService
+}
+
+func NewReportService(db *gorm.DB, cfg *config.Config, portfolioService *PortfolioService) *ReportService {
+	return &ReportService{db: db, config: cfg, portfolioService: portfolioService}
+}
+
+// EnqueueReport validates the request and queues a report job for the worker
+func (s *ReportService) EnqueueReport(req ReportRequest, requestedBy string) (*models.PortfolioReport, error) {
+	reportType := strings.ToLower(strings.TrimSpace(req.ReportType))
+	if _, ok := reportTypes[reportType]; !ok {
+		return nil, fmt.Errorf("%w: unsupported report type %q", ErrInvalidReportRequest, req.ReportType)
+	}
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if _, ok := reportContentTypes[format]; !ok {
+		return nil, fmt.Errorf("%w: format must be csv, xlsx or pdf", ErrInvalidReportRequest)
+	}
+
+	start, end, err := reportPeriod(req)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PortfolioReport{
+		ReportType:       reportType,
+		Format:           format,
+		Status:           ReportStatusQueued,
+		PeriodStart:      start,
+		PeriodEnd:        end,
+		BankConnectionID: req.BankConnectionID,
+		Filters:          toJSON(reportFilters{Currency: strings.ToUpper(req.Currency), Industry: req.Industry}),
+		RequestedBy:      requestedBy,
+	}
+	if err := s.db.Create(report).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue report: %w", err)
+	}
+	return report, nil
+}
+
+// reportPeriod resolves the period of a request; the default is the previous calendar month
+func reportPeriod(req ReportRequest) (time.Time, time.Time, error) {
+	if req.Period != "" {
+		month, err := time.Parse("2006-01", req.Period)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: period must be in YYYY-MM format", ErrInvalidReportRequest)
+		}
+		return month, month.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+	}
+
+	if req.StartDate == "" && req.EndDate == "" {
+		now := time.Now().UTC()
+		thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return thisMonth.AddDate(0, -1, 0), thisMonth.Add(-time.Nanosecond), nil
+	}
+	if req.StartDate == "" || req.EndDate == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date and end_date must be given together", ErrInvalidReportRequest)
+	}
+
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+	}
+	end, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date must be in YYYY-MM-DD format", ErrInvalidReportRequest)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date is before start_date", ErrInvalidReportRequest)
+	}
+	return start, end.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// GetReport returns a report job. Non-admins can only see reports they requested.
+func (s *ReportService) GetReport(id uuid.UUID, actorID string, isAdmin bool) (*models.PortfolioReport, error) {
+	var report models.PortfolioReport
+	if err := s.db.First(&report, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to load report: %w", err)
+	}
+	if !isAdmin && report.RequestedBy != actorID {
+		return nil, ErrReportNotFound
+	}
+	return &report, nil
+}
+
+// ListReports returns the most recent reports, limited to the actor's own unless isAdmin
+func (s *ReportService) ListReports(actorID string, isAdmin bool, status string, limit int) ([]models.PortfolioReport, error) {
+	query := s.db.Model(&models.PortfolioReport{})
+	if !isAdmin {
+		query = query.Where("requested_by = ?", actorID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	reports := []models.PortfolioReport{}
+	if err := query.Order("created_at DESC").Limit(limit).Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	return reports, nil
+}
+
+// OpenArtifact returns a completed report and the path of its artifact
+func (s *ReportService) OpenArtifact(id uuid.UUID, actorID string, isAdmin bool) (*models.PortfolioReport, string, error) {
+	report, err := s.GetReport(id, actorID, isAdmin)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch report.Status {
+	case ReportStatusExpired:
+		return nil, "", ErrReportExpired
+	case ReportStatusFailed:
+		return nil, "", ErrReportFailed
+	case ReportStatusCompleted:
+	default:
+		return nil, "", ErrReportNotReady
+	}
+	if report.ExpiresAt != nil && report.ExpiresAt.Before(time.Now()) {
+		return nil, "", ErrReportExpired
+	}
+	if _, err := os.Stat(report.FilePath); err != nil {
+		return nil, "", fmt.Errorf("report artifact is unavailable: %w", err)
+	}
+	return report, report.FilePath, nil
+}
+
+// ProcessNext claims the oldest queued report and renders it. It returns false when
+// the queue is empty.
+func (s *ReportService) ProcessNext() (bool, error) {
+	report, err := s.claimNext()
+	if err != nil || report == nil {
+		return false, err
+	}
+
+	if err := s.generate(report); err != nil {
+		log.Printf("Report %s failed (attempt %d): %v", report.ID, report.Attempts, err)
+		s.fail(report, err)
+	}
+	return true, nil
+}
+
+// claimNext moves the oldest queued report to running. The guarded update means only
+// one replica wins a given job.
+func (s *ReportService) claimNext() (*models.PortfolioReport, error) {
+	for i := 0; i < 3; i++ {
+		var report models.PortfolioReport
+		err := s.db.Where("status = ?", ReportStatusQueued).Order("created_at").First(&report).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load queued reports: %w", err)
+		}
+
+		now := time.Now()
+		result := s.db.Model(&models.PortfolioReport{}).
+			Where("id = ? AND status = ?", report.ID, ReportStatusQueued).
+			Updates(map[string]interface{}{
+				"status":     ReportStatusRunning,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"started_at": now,
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to claim report: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			report.Status = ReportStatusRunning
+			report.Attempts++
+			report.StartedAt = &now
+			return &report, nil
+		}
+	}
+	return nil, nil
+}
+
+// generate renders a claimed report, stores the artifact and marks the job completed
+func (s *ReportService) generate(report *models.PortfolioReport) error {
+	tables, err := s.buildTables(report)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Portfolio %s report, %s to %s", strings.ReplaceAll(report.ReportType, "_", " "),
+		report.PeriodStart.Format("2006-01-02"), report.PeriodEnd.Format("2006-01-02"))
+
+	var content []byte
+	switch report.Format {
+	case "csv":
+		content, err = renderCSV(tables)
+	case "xlsx":
+		content, err = renderXLSX(tables)
+	case "pdf":
+		content, err = renderPDF(title, tables)
+	default:
+		err = fmt.Errorf("unsupported report format %q", report.Format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+
+	if err := os.MkdirAll(s.config.ReportStorageDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create report storage: %w", err)
+	}
+	fileName := fmt.Sprintf("portfolio_%s_%s_%s.%s", report.ReportType,
+		report.PeriodStart.Format("20060102"), report.PeriodEnd.Format("20060102"), report.Format)
+	path := filepath.Join(s.config.ReportStorageDir, report.ID.String()+"."+report.Format)
+
+	// Write to a temporary file first so a partially written artifact is never served
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o640); err != nil {
+		return fmt.Errorf("failed to write report artifact: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to store report artifact: %w", err)
+	}
+
+	rows := 0
+	for _, table := range tables {
+		rows += len(table.Rows)
+	}
+	sum := sha256.Sum256(content)
+	now := time.Now()
+	expiresAt := now.Add(s.config.ReportRetention)
+
+	result := s.db.Model(&models.PortfolioReport{}).
+		Where("id = ? AND status = ?", report.ID, ReportStatusRunning).
+		Updates(map[string]interface{}{
+			"status":        ReportStatusCompleted,
+			"file_name":     fileName,
+			"file_path":     path,
+			"content_type":  reportContentTypes[report.Format],
+			"file_size":     int64(len(content)),
+			"checksum":      hex.EncodeToString(sum[:]),
+			"row_count":     rows,
+			"error_message": "",
+			"completed_at":  now,
+			"expires_at":    expiresAt,
+		})
+	if result.Error != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to complete report: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// The job was requeued as stale while we worked; the other run owns it now
+		os.Remove(path)
+	}
+	return nil
+}
+
+// fail requeues a report for another attempt, or marks it failed once attempts run out
+func (s *ReportService) fail(report *models.PortfolioReport, cause error) {
+	status := ReportStatusQueued
+	if report.Attempts >= s.config.ReportMaxAttempts {
+		status = ReportStatusFailed
+	}
+	if err := s.db.Model(&models.PortfolioReport{}).
+		Where("id = ? AND status = ?", report.ID, ReportStatusRunning).
+		Updates(map[string]interface{}{"status": status, "error_message": cause.Error()}).Error; err != nil {
+		log.Printf("Failed to record report %s failure: %v", report.ID, err)
+	}
+}
+
+// RequeueStaleReports returns reports stuck in running, e.g. after a crash, to the queue
+func (s *ReportService) RequeueStaleReports() (int64, error) {
+	cutoff := time.Now().Add(-reportStaleAfter)
+	stale := s.db.Model(&models.PortfolioReport{}).Where("status = ? AND started_at < ?", ReportStatusRunning, cutoff)
+
+	failed := stale.Session(&gorm.Session{}).Where("attempts >= ?", s.config.ReportMaxAttempts).
+		Updates(map[string]interface{}{"status": ReportStatusFailed, "error_message": "report generation timed out"})
+	if failed.Error != nil {
+		return 0, fmt.Errorf("failed to fail stale reports: %w", failed.Error)
+	}
+	requeued := stale.Session(&gorm.Session{}).Where("attempts < ?", s.config.ReportMaxAttempts).
+		Update("status", ReportStatusQueued)
+	if requeued.Error != nil {
+		return 0, fmt.Errorf("failed to requeue stale reports: %w", requeued.Error)
+	}
+	return failed.RowsAffected + requeued.RowsAffected, nil
+}
+
+// PurgeExpiredReports deletes artifacts past their retention and marks the jobs expired
+func (s *ReportService) PurgeExpiredReports() (int, error) {
+	reports := []models.PortfolioReport{}
+	if err := s.db.Where("status = ? AND expires_at < ?", ReportStatusCompleted, time.Now()).
+		Find(&reports).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired reports: %w", err)
+	}
+
+	purged := 0
+	for _, report := range reports {
+		if report.FilePath != "" {
+			if err := os.Remove(report.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to delete report artifact %s: %v", report.FilePath, err)
+				continue
+			}
+		}
+		if err := s.db.Model(&models.PortfolioReport{}).
+			Where("id = ? AND status = ?", report.ID, ReportStatusCompleted).
+			Updates(map[string]interface{}{"status": ReportStatusExpired, "file_path": ""}).Error; err != nil {
+			return purged, fmt.Errorf("failed to expire report: %w", err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartWorker drains the report queue every ReportWorkerInterval and purges expired
+// artifacts hourly until ctx is cancelled
+func (s *ReportService) StartWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReportWorkerInterval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		if _, err := s.RequeueStaleReports(); err != nil {
+			log.Printf("Report requeue failed: %v", err)
+		}
+		for ctx.Err() == nil {
+			processed, err := s.ProcessNext()
+			if err != nil {
+				log.Printf("Report worker failed: %v", err)
+			}
+			if !processed {
+				break
+			}
+		}
+		if time.Since(lastPurge) >= time.Hour {
+			if purged, err := s.PurgeExpiredReports(); err != nil {
+				log.Printf("Report purge failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired report artifacts", purged)
+			}
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildTables gathers the report content for the job's type, period and filters
+func (s *ReportService) buildTables(report *models.PortfolioReport) ([]reportTable, error) {
+	var filters reportFilters
+	if report.Filters != "" {
+		if err := json.Unmarshal([]byte(report.Filters), &filters); err != nil {
+			return nil, fmt.Errorf("invalid report filters: %w", err)
+		}
+	}
+	filter := PortfolioFilter{BankConnectionID: report.BankConnectionID, Currency: filters.Currency, Industry: filters.Industry}
+
+	tables := []reportTable{s.parametersTable(report, filters)}
+	var sections []func() ([]reportTable, error)
+	switch report.ReportType {
+	case "monthly_pack":
+		sections = []func() ([]reportTable, error){
+			func() ([]reportTable, error) { return s.overviewTables(filter, report) },
+			func() ([]reportTable, error) { return s.riskTables(filter) },
+			func() ([]reportTable, error) { return s.exposureTables(filter) },
+			func() ([]reportTable, error) { return s.performanceTables(filter, report) },
+			func() ([]reportTable, error) { return s.holdingsTables(filter, report) },
+		}
+	case "holdings":
+		sections = []func() ([]reportTable, error){
+			func() ([]reportTable, error) { return s.holdingsTables(filter, report) },
+		}
+	case "risk":
+		sections = []func() ([]reportTable, error){
+			func() ([]reportTable, error) { return s.riskTables(filter) },
+		}
+	case "exposures":
+		sections = []func() ([]reportTable, error){
+			func() ([]reportTable, error) { return s.exposureTables(filter) },
+		}
+	case "performance":
+		sections = []func() ([]reportTable, error){
+			func() ([]reportTable, error) { return s.performanceTables(filter, report) },
+		}
+	default:
+		return nil, fmt.Errorf("unsupported report type %q", report.ReportType)
+	}
+
+	for _, section := range sections {
+		part, err := section()
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, part...)
+	}
+	return tables, nil
+}
+
+func (s *ReportService) parametersTable(report *models.PortfolioReport, filters reportFilters) reportTable {
+	bank := "all"
+	if report.BankConnectionID != nil {
+		bank = report.BankConnectionID.String()
+	}
+	currency, industry := "all", "all"
+	if filters.Currency != "" {
+		currency = filters.Currency
+	}
+	if filters.Industry != "" {
+		industry = filters.Industry
+	}
+
+	return reportTable{
+		Title:   "Report Parameters",
+		Columns: []string{"Parameter", "Value"},
+		Rows: [][]string{
+			{"Report ID", report.ID.String()},
+			{"Report Type", report.ReportType},
+			{"Description", reportTypes[report.ReportType]},
+			{"Period Start", report.PeriodStart.Format("2006-01-02")},
+			{"Period End", report.PeriodEnd.Format("2006-01-02")},
+			{"Bank Connection", bank},
+			{"Currency", currency},
+			{"Industry", industry},
+			{"Generated At", time.Now().UTC().Format(time.RFC3339)},
+		},
+	}
+}
+
+func (s *ReportService) overviewTables(filter PortfolioFilter, report *models.PortfolioReport) ([]reportTable, error) {
+	overview, err := s.portfolioService.Overview(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var originations struct {
+		Count     int64
+		Principal float64
+	}
+	if err := s.portfolioService.items(filter).
+		Where("created_at >= ? AND created_at <= ?", report.PeriodStart, report.PeriodEnd).
+		Select("COUNT(*) AS count, COALESCE(SUM(principal), 0) AS principal").
+		Scan(&originations).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute originations: %w", err)
+	}
+
+	summary := reportTable{
+		Title:   "Portfolio Summary",
+		Columns: []string{"Metric", "Value"},
+		Rows: [][]string{
+			{"Items", formatCount(overview.ItemCount)},
+			{"Total Principal", formatAmount(overview.TotalPrincipal)},
+			{"Total Outstanding", formatAmount(overview.TotalOutstanding)},
+			{"Weighted Rate (%)", formatRatio(overview.WeightedRate)},
+			{"Originations in Period", formatCount(originations.Count)},
+			{"Originated Principal in Period", formatAmount(originations.Principal)},
+		},
+	}
+
+	byStatus := reportTable{Title: "By Status", Columns: []string{"Status", "Count", "Principal", "Outstanding"}}
+	for _, status := range overview.ByStatus {
+		byStatus.Rows = append(byStatus.Rows, []string{status.Status, formatCount(status.Count),
+			formatAmount(status.Principal), formatAmount(status.Outstanding)})
+	}
+	return []reportTable{summary, byStatus}, nil
+}
+
+func (s *ReportService) riskTables(filter PortfolioFilter) ([]reportTable, error) {
+	risk, err := s.portfolioService.RiskAnalysis(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := reportTable{
+		Title:   "Risk Summary",
+		Columns: []string{"Metric", "Value"},
+		Rows: [][]string{
+			{"Total Exposure", formatAmount(risk.TotalExposure)},
+			{"Expected Loss", formatAmount(risk.ExpectedLoss)},
+			{"Expected Loss Ratio", formatRatio(risk.ExpectedLossRatio)},
+			{"Loss Given Default", formatRatio(risk.LossGivenDefault)},
+			{"Defaulted Amount", formatAmount(risk.DefaultedAmount)},
+			{"Written Off Amount", formatAmount(risk.WrittenOffAmount)},
+		},
+	}
+
+	byRating := reportTable{Title: "Expected Loss by Rating", Columns: []string{"Rating", "Count", "Outstanding", "PD", "Expected Loss"}}
+	for _, rating := range risk.ByRating {
+		byRating.Rows = append(byRating.Rows, []string{rating.RiskRating, formatCount(rating.Count),
+			formatAmount(rating.Outstanding), formatRatio(rating.PD), formatAmount(rating.ExpectedLoss)})
+	}
+
+	dpd := reportTable{Title: "Days Past Due", Columns: []string{"Bucket", "Count", "Outstanding"}}
+	for _, bucket := range risk.DPDBuckets {
+		dpd.Rows = append(dpd.Rows, []string{bucket.Bucket, formatCount(bucket.Count), formatAmount(bucket.Outstanding)})
+	}
+	return []reportTable{summary, byRating, dpd}, nil
+}
+
+func (s *ReportService) exposureTables(filter PortfolioFilter) ([]reportTable, error) {
+	tables := []reportTable{}
+	for _, dimension := range []string{"Customer", "Industry", "Currency"} {
+		entries, err := s.portfolioService.Exposures(filter, strings.ToLower(dimension), 20)
+		if err != nil {
+			return nil, err
+		}
+		table := reportTable{
+			Title:   "Top Exposures by " + dimension,
+			Columns: []string{dimension, "Count", "Outstanding", "Share"},
+		}
+		for _, entry := range entries {
+			table.Rows = append(table.Rows, []string{entry.Key, formatCount(entry.Count),
+				formatAmount(entry.Outstanding), formatRatio(entry.Share)})
+		}
+		tables = append(tables, table)
+	}
+
+	concentrations, err := s.portfolioService.Concentrations(filter)
+	if err != nil {
+		return nil, err
+	}
+	table := reportTable{Title: "Concentrations", Columns: []string{"Dimension", "HHI", "Groups", "Largest Share", "Top Ten Share", "Classification"}}
+	for _, m := range concentrations {
+		table.Rows = append(table.Rows, []string{m.Dimension, formatAmount(m.HHI), formatCount(m.Groups),
+			formatRatio(m.LargestShare), formatRatio(m.TopTenShare), m.Classification})
+	}
+	return append(tables, table), nil
+}
+
+func (s *ReportService) performanceTables(filter PortfolioFilter, report *models.PortfolioReport) ([]reportTable, error) {
+	performance, err := s.portfolioService.Performance(filter, report.PeriodStart, report.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	yield := reportTable{
+		Title:   "Yield",
+		Columns: []string{"Metric", "Value (%)"},
+		Rows: [][]string{
+			{"Contractual Yield", formatRatio(performance.Yield.ContractualYield)},
+			{"Expected Yield", formatRatio(performance.Yield.ExpectedYield)},
+			{"Realized Yield", formatRatio(performance.Yield.RealizedYield)},
+			{"Yield Gap", formatRatio(performance.Yield.YieldGap)},
+		},
+	}
+
+	vintages := reportTable{Title: "Vintages", Columns: []string{"Cohort", "Months on Book", "Count", "Principal",
+		"Outstanding", "Repaid %", "Default %", "DPD30 %"}}
+	for _, v := range performance.Vintages {
+		vintages.Rows = append(vintages.Rows, []string{v.Cohort, fmt.Sprint(v.MonthsOnBook), formatCount(v.Count),
+			formatAmount(v.Principal), formatAmount(v.Outstanding), formatRatio(v.RepaidPct),
+			formatRatio(v.DefaultPct), formatRatio(v.DPD30Pct)})
+	}
+
+	snapshots := reportTable{Title: "Daily Snapshots", Columns: []string{"Date", "Items", "Outstanding",
+		"Weighted Rate", "Expected Loss", "DPD30+", "DPD90+", "Customer HHI"}}
+	for _, snap := range performance.Snapshots {
+		snapshots.Rows = append(snapshots.Rows, []string{snap.SnapshotDate.Format("2006-01-02"), formatCount(snap.ItemCount),
+			formatAmount(snap.TotalOutstanding), formatRatio(snap.WeightedRate), formatAmount(snap.ExpectedLoss),
+			formatAmount(snap.DPD30Plus), formatAmount(snap.DPD90Plus), formatAmount(snap.CustomerHHI)})
+	}
+	return []reportTable{yield, vintages, snapshots}, nil
+}
+
+// holdingsTables lists every item originated by the end of the period
+func (s *ReportService) holdingsTables(filter PortfolioFilter, report *models.PortfolioReport) ([]reportTable, error) {
+	table := reportTable{Title: "Holdings", Columns: []string{"Item ID", "Customer ID", "Type", "Currency", "Industry",
+		"Principal", "Outstanding", "Rate (%)", "Rating", "Status", "Maturity Date", "Next Payment Due"}}
+
+	batch := []models.PortfolioItem{}
+	err := s.portfolioService.items(filter).
+		Where("created_at <= ?", report.PeriodEnd).
+		Order("created_at").
+		FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+			for _, item := range batch {
+				nextDue := ""
+				if item.NextPaymentDue != nil {
+					nextDue = item.NextPaymentDue.Format("2006-01-02")
+				}
+				table.Rows = append(table.Rows, []string{item.ID.String(), item.CustomerID.String(), item.Type,
+					item.Currency, item.Industry, formatAmount(item.Principal), formatAmount(item.Outstanding),
+					formatRatio(item.InterestRate), item.RiskRating, item.Status,
+					item.MaturityDate.Format("2006-01-02"), nextDue})
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holdings: %w", err)
+	}
+	return []reportTable{table}, nil
+}
+
+func formatAmount(v float64) string { return fmt.Sprintf("%.2f", v) }
+func formatRatio(v float64) string  { return fmt.Sprintf("%.4f", v) }
+func formatCount(v int64) string    { return fmt.Sprintf("%d", v) }
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+	"shared/retention"
+	"shared/retention/gormstore"
+)
+
+var (
+	ErrRetentionRuleNotFound = retention.ErrRuleNotFound
+	ErrInvalidRetentionRule  = retention.ErrInvalidRule
+	ErrLegalHoldNotFound     = retention.ErrHoldNotFound
+	ErrInvalidLegalHold      = retention.ErrInvalidHold
+	ErrPurgeRunNotFound      = retention.ErrRunNotFound
+	ErrPurgeRunInProgress    = retention.ErrRunInProgress
+	ErrPurgeReportUnsigned   = retention.ErrReportUnsigned
+)
+
+// retentionTables describes how retention reaches each table. The audit trail is
+// append-only and never subject to retention. Regulated tables must be kept for the
+// Epic 4 data retention period.
+var retentionTables = map[string]gormstore.Table{
+	"payment_transactions": {
+		Entity:        retention.Entity{Bases: []string{"processed_at"}, Regulated: true},
+		SubjectColumn: "customer_id",
+		Anonymize:     map[string]interface{}{"from_account_id": "", "to_account_id": "", "description": "", "reference": ""},
+	},
+	"bank_statement_lines": {
+		Entity:    retention.Entity{Bases: []string{"booking_date"}, Regulated: true},
+		Anonymize: map[string]interface{}{"counterparty_name": "", "counterparty_account": "", "description": "", "reference": ""},
+	},
+	"credit_decisions":    {Entity: retention.Entity{Bases: []string{"decision_date"}, Regulated: true}, SubjectColumn: "customer_id"},
+	"compliance_records":  {Entity: retention.Entity{Bases: []string{"checked_at"}, Regulated: true}},
+	"aml_alerts":          {Entity: retention.Entity{Bases: []string{"closed_at"}, Regulated: true}, SubjectColumn: "customer_id", Children: map[string]string{"aml_alert_notes": "alert_id"}},
+	"regulatory_filings":  {Entity: retention.Entity{Bases: []string{"acknowledged_at"}, Regulated: true}, SubjectColumn: "subject_id", Children: map[string]string{"regulatory_filing_submissions": "filing_id"}},
+	"risk_assessments":    {Entity: retention.Entity{Bases: []string{"assessed_at"}}},
+	"reconciliation_jobs": {Entity: retention.Entity{Bases: []string{"completed_at"}}},
+	"api_keys":            {Entity: retention.Entity{Bases: []string{"revoked_at", "expires_at"}}},
+	"dual_control_approvals": {
+		Entity:   retention.Entity{Bases: []string{"closed_at"}, Regulated: true},
+		Children: map[string]string{"dual_control_decisions": "approval_id"},
+	},
+}
+
+// defaultRetentionRules are created on startup when missing. Existing rules are never
+// overwritten, so changes made through the API survive restarts.
+func defaultRetentionRules(cfg *config.Config) []models.RetentionRule {
+	financialDays := int(cfg.Epic4Config.DataRetentionPeriod.Hours() / 24)
+	return []models.RetentionRule{
+		{Name: "settled_payments", Entity: "payment_transactions", Basis: "updated_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Statuses: []string{"completed", "failed", "cancelled"}, Description: "Delete settled payments after the data retention period"},
+		{Name: "statement_lines", Entity: "bank_statement_lines", Basis: "booking_date", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Description: "Delete imported statement lines after the data retention period"},
+		{Name: "credit_decisions", Entity: "credit_decisions", Basis: "created_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Description: "Delete credit decisions after the data retention period"},
+		{Name: "compliance_records", Entity: "compliance_records", Basis: "checked_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Description: "Delete compliance checks after the data retention period"},
+		{Name: "closed_aml_alerts", Entity: "aml_alerts", Basis: "closed_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Statuses: []string{AMLAlertClosed}, Description: "Delete closed AML cases and their notes after the data retention period"},
+		{Name: "acknowledged_filings", Entity: "regulatory_filings", Basis: "acknowledged_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Statuses: []string{FilingStatusAcknowledged}, Description: "Delete acknowledged regulatory filings after the data retention period"},
+		{Name: "risk_assessments", Entity: "risk_assessments", Basis: "assessed_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Description: "Delete risk assessments after the data retention period"},
+		{Name: "reconciliation_jobs", Entity: "reconciliation_jobs", Basis: "created_at", RetentionDays: 365, Action: retention.ActionPurge,
+			Statuses: []string{"completed", "failed"}, Description: "Delete finished reconciliation job summaries"},
+		{Name: "revoked_api_keys", Entity: "api_keys", Basis: "revoked_at", RetentionDays: 365, Action: retention.ActionPurge,
+			Description: "Delete revoked API keys a year after revocation"},
+		{Name: "closed_approvals", Entity: "dual_control_approvals", Basis: "closed_at", RetentionDays: financialDays, Action: retention.ActionPurge,
+			Statuses: []string{DualControlExecuted, DualControlFailed, DualControlRejected, DualControlCancelled, DualControlExpired}, Description: "Delete closed approvals and their decisions"},
+	}
+}
+
+// RetentionRuleRequest is the input for creating a rule
+type RetentionRuleRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Entity        string   `json:"entity" binding:"required"`
+	Basis         string   `json:"basis" binding:"required"`
+	Statuses      []string `json:"statuses"`
+	RetentionDays int      `json:"retention_days" binding:"required"`
+	Action        string   `json:"action" binding:"required"`
+	Description   string   `json:"description"`
+}
+
+// RetentionRuleUpdate changes the given fields of a rule
+type RetentionRuleUpdate struct {
+	RetentionDays *int      `json:"retention_days"`
+	Action        *string   `json:"action"`
+	Statuses      *[]string `json:"statuses"`
+	Enabled       *bool     `json:"enabled"`
+	Description   *string   `json:"description"`
+}
+
+// LegalHoldRequest is the input for placing a hold
+type LegalHoldRequest struct {
+	Entity        string     `json:"entity"`
+	RecordID      *uuid.UUID `json:"record_id"`
+	CustomerID    *uuid.UUID `json:"customer_id"`
+	Reason        string     `json:"reason" binding:"required"`
+	CaseReference string     `json:"case_reference"`
+}
+
+// RetentionService applies retention rules to the service's tables and keeps a report of
+// every run, signed with the Epic 4 certificate
+type RetentionService struct {
+	db     *gorm.DB
+	config *config.Config
+	engine *retention.Engine
+}
+
+func NewRetentionService(db *gorm.DB, cfg *config.Config) *RetentionService {
+	s := &RetentionService{db: db, config: cfg}
+	s.engine = retention.NewEngine(&retentionStore{
+		Tables:  gormstore.Tables{DB: db, BatchSize: cfg.RetentionBatchSize, Tables: retentionTables},
+		service: s,
+	}, retention.Config{
+		Service:         "bank-integration-service",
+		CertificatePath: cfg.Epic4Config.CertificatePath,
+		SigningKeyPath:  cfg.Epic4Config.SigningKeyPath,
+		MinimumDays:     int(cfg.Epic4Config.DataRetentionPeriod.Hours() / 24),
+	})
+	return s
+}
+
+// SeedRules creates the default rules that do not exist yet
+func (s *RetentionService) SeedRules() error {
+	for _, rule := range defaultRetentionRules(s.config) {
+		rule := rule
+		rule.Enabled = true
+		rule.UpdatedBy = "system"
+		if err := s.db.Where("name = ?", rule.Name).FirstOrCreate(&rule).Error; err != nil {
+			return fmt.Errorf("failed to seed retention rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *RetentionService) ListRules() ([]models.RetentionRule, error) {
+	rules := []models.RetentionRule{}
+	if err := s.db.Order("entity ASC, name ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list retention rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *RetentionService) CreateRule(req RetentionRuleRequest, actor string) (*models.RetentionRule, error) {
+	rule := &models.RetentionRule{
+		Name:          strings.TrimSpace(req.Name),
+		Entity:        req.Entity,
+		Basis:         req.Basis,
+		Statuses:      req.Statuses,
+		RetentionDays: req.RetentionDays,
+		Action:        req.Action,
+		Enabled:       true,
+		Description:   req.Description,
+		UpdatedBy:     actor,
+	}
+	if rule.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRetentionRule)
+	}
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.RetentionRule{}).Where("name = ?", rule.Name).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check retention rules: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: a rule named %q already exists", ErrInvalidRetentionRule, rule.Name)
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create retention rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *RetentionService) UpdateRule(name string, req RetentionRuleUpdate, actor string) (*models.RetentionRule, error) {
+	var rule models.RetentionRule
+	if err := s.db.Where("name = ?", name).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRetentionRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to load retention rule: %w", err)
+	}
+
+	if req.RetentionDays != nil {
+		rule.RetentionDays = *req.RetentionDays
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.Statuses != nil {
+		rule.Statuses = *req.Statuses
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.UpdatedBy = actor
+
+	if err := s.db.Save(&rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update retention rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *RetentionService) validateRule(rule *models.RetentionRule) error {
+	return s.engine.ValidateRule(engineRule(*rule))
+}
+
+func engineRule(rule models.RetentionRule) retention.Rule {
+	return retention.Rule{
+		Name:          rule.Name,
+		Entity:        rule.Entity,
+		Basis:         rule.Basis,
+		Statuses:      rule.Statuses,
+		RetentionDays: rule.RetentionDays,
+		Action:        rule.Action,
+		Enabled:       rule.Enabled,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PlaceHold places a legal hold on a row or on every row of a customer
+func (s *RetentionService) PlaceHold(req LegalHoldRequest, actor string) (*models.LegalHold, error) {
+	if err := s.engine.ValidateHold(req.Entity, req.RecordID, req.CustomerID, req.Reason, "customer_id"); err != nil {
+		return nil, err
+	}
+
+	hold := &models.LegalHold{
+		Entity:        req.Entity,
+		RecordID:      req.RecordID,
+		CustomerID:    req.CustomerID,
+		Reason:        strings.TrimSpace(req.Reason),
+		CaseReference: req.CaseReference,
+		PlacedBy:      actor,
+	}
+	if err := s.db.Create(hold).Error; err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+	return hold, nil
+}
+
+// ReleaseHold releases an active hold; releasing a released hold is a no-op
+func (s *RetentionService) ReleaseHold(id uuid.UUID, actor, note string) (*models.LegalHold, error) {
+	var hold models.LegalHold
+	if err := s.db.Where("id = ?", id).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLegalHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to load legal hold: %w", err)
+	}
+	if hold.ReleasedAt != nil {
+		return &hold, nil
+	}
+
+	now := time.Now()
+	hold.ReleasedAt = &now
+	hold.ReleasedBy = actor
+	hold.ReleaseNote = note
+	err := s.db.Model(&hold).Updates(map[string]interface{}{
+		"released_at":  hold.ReleasedAt,
+		"released_by":  hold.ReleasedBy,
+		"release_note": hold.ReleaseNote,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (s *RetentionService) ListHolds(activeOnly bool) ([]models.LegalHold, error) {
+	holds := []models.LegalHold{}
+	query := s.db.Order("created_at DESC")
+	if activeOnly {
+		query = query.Where("released_at IS NULL")
+	}
+	if err := query.Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	return holds, nil
+}
+
+// RunPurge applies every enabled rule and stores a signed report of the run. Dry runs
+// only count what would be purged.
+func (s *RetentionService) RunPurge(ctx context.Context, dryRun bool, triggeredBy string) (*models.PurgeRun, error) {
+	run, err := s.engine.RunPurge(ctx, dryRun, triggeredBy)
+	if run == nil {
+		return nil, err
+	}
+	return purgeRunModel(run), err
+}
+
+// VerifyRun checks a stored report against its digest and the certificate's signature
+func (s *RetentionService) VerifyRun(id uuid.UUID) (bool, string, error) {
+	run, err := s.GetRun(id)
+	if err != nil {
+		return false, "", err
+	}
+	return s.engine.Verify(&retention.Run{
+		Report:                 run.Report,
+		ReportSHA256:           run.ReportSHA256,
+		Signature:              run.Signature,
+		CertificateFingerprint: run.CertificateFingerprint,
+	})
+}
+
+func (s *RetentionService) GetRun(id uuid.UUID) (*models.PurgeRun, error) {
+	var run models.PurgeRun
+	if err := s.db.Where("id = ?", id).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurgeRunNotFound
+		}
+		return nil, fmt.Errorf("failed to load purge run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListRuns returns the most recent runs without their report bodies
+func (s *RetentionService) ListRuns(limit int) ([]models.PurgeRun, error) {
+	runs := []models.PurgeRun{}
+	err := s.db.Omit("report").Order("started_at DESC").Limit(limit).Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge runs: %w", err)
+	}
+	return runs, nil
+}
+
+// StartScheduler runs the retention rules every RetentionRunInterval until ctx is
+// cancelled
+func (s *RetentionService) StartScheduler(ctx context.Context) {
+	if !s.config.RetentionEnabled {
+		return
+	}
+	s.engine.Schedule(ctx, s.config.RetentionRunInterval)
+}
+
+// retentionStore keeps rules, holds and runs in the service's models
+type retentionStore struct {
+	gormstore.Tables
+	service *RetentionService
+}
+
+func (r *retentionStore) Rules(ctx context.Context) ([]retention.Rule, error) {
+	rules, err := r.service.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]retention.Rule, len(rules))
+	for i, rule := range rules {
+		out[i] = engineRule(rule)
+	}
+	return out, nil
+}
+
+func (r *retentionStore) ActiveHolds(ctx context.Context) ([]retention.Hold, error) {
+	holds, err := r.service.ListHolds(true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]retention.Hold, len(holds))
+	for i, hold := range holds {
+		out[i] = retention.Hold{Entity: hold.Entity, RecordID: hold.RecordID, SubjectID: hold.CustomerID}
+	}
+	return out, nil
+}
+
+func (r *retentionStore) StartRun(ctx context.Context, run *retention.Run, abandonBefore time.Time) error {
+	r.DB.Model(&models.PurgeRun{}).
+		Where("status = ? AND started_at < ?", retention.RunRunning, abandonBefore).
+		Update("status", retention.RunAbandoned)
+
+	// The partial unique index on running runs keeps replicas from purging concurrently
+	if err := r.DB.Create(purgeRunModel(run)).Error; err != nil {
+		var running int64
+		r.DB.Model(&models.PurgeRun{}).Where("status = ?", retention.RunRunning).Count(&running)
+		if running > 0 {
+			return ErrPurgeRunInProgress
+		}
+		return fmt.Errorf("failed to start purge run: %w", err)
+	}
+	return nil
+}
+
+func (r *retentionStore) FinishRun(ctx context.Context, run *retention.Run) error {
+	return r.DB.WithContext(ctx).Save(purgeRunModel(run)).Error
+}
+
+func purgeRunModel(run *retention.Run) *models.PurgeRun {
+	return &models.PurgeRun{
+		ID:                     run.ID,
+		Status:                 run.Status,
+		DryRun:                 run.DryRun,
+		TriggeredBy:            run.TriggeredBy,
+		Purged:                 run.Purged,
+		Anonymized:             run.Anonymized,
+		Held:                   run.Held,
+		Error:                  run.Error,
+		Report:                 run.Report,
+		ReportSHA256:           run.ReportSHA256,
+		Signature:              run.Signature,
+		SignatureAlgorithm:     run.SignatureAlgorithm,
+		CertificateFingerprint: run.CertificateFingerprint,
+		StartedAt:              run.StartedAt,
+		CompletedAt:            run.CompletedAt,
+	}
+}
@@ -1,8 +1,16 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
 )
 
 // BankAPIService handles bank API integrations
@@ -19,14 +27,20 @@ func (s *BankAPIService) TestConnection(bankCode string) (string, error) {
 	return "connected", nil
 }
 
-// CreditDecisionService handles credit decision processing
-type CreditDecisionService struct {
-	db     *gorm.DB
-	config *config.Config
+func (s *BankAPIService) SubmitFinancingRequest(bankCode string, request *models.FinancingRequest) (string, error) {
+	if _, ok := s.config.BankConfigs[bankCode]; !ok {
+		return "", fmt.Errorf("bank %s is not configured", bankCode)
+	}
+	// Implementation would post the request to the bank's financing API
+	return fmt.Sprintf("%s-%s", bankCode, request.ID.String()[:8]), nil
 }
 
-func NewCreditDecisionService(db *gorm.DB, cfg *config.Config) *CreditDecisionService {
-	return &CreditDecisionService{db: db, config: cfg}
+func (s *BankAPIService) RespondToOffer(bankCode, externalOfferID string, accepted bool) error {
+	if _, ok := s.config.BankConfigs[bankCode]; !ok {
+		return fmt.Errorf("bank %s is not configured", bankCode)
+	}
+	// Implementation would notify the bank that its offer was accepted or declined
+	return nil
 }
 
 // PaymentProcessingService handles payment processing
@@ -49,14 +63,34 @@ func NewFinancingService(db *gorm.DB, cfg *config.Config) *FinancingService {
 	return &FinancingService{db: db, config: cfg}
 }
 
-// PortfolioService handles portfolio management
-type PortfolioService struct {
-	db     *gorm.DB
-	config *config.Config
-}
-
-func NewPortfolioService(db *gorm.DB, cfg *config.Config) *PortfolioService {
-	return &PortfolioService{db: db, config: cfg}
+func (s *FinancingService) CreateRequest(request *models.FinancingRequest) error {
+	if request.RequestedAmount < s.config.MinCreditAmount || request.RequestedAmount > s.config.MaxCreditAmount {
+		return fmt.Errorf("requested amount must be between %.2f and %.2f", s.config.MinCreditAmount, s.config.MaxCreditAmount)
+	}
+	if request.Currency == "" {
+		request.Currency = "USD"
+	}
+	if request.Term <= 0 {
+		request.Term = s.config.DefaultCreditTerms
+	}
+	request.Status = "draft"
+	request.RequestedAt = time.Now()
+
+	if err := s.db.Create(request).Error; err != nil {
+		return fmt.Errorf("failed to create financing request: %w", err)
+	}
+	return nil
+}
+
+func (s *FinancingService) GetRequest(id uuid.UUID) (*models.FinancingRequest, error) {
+	var request models.FinancingRequest
+	if err := s.db.First(&request, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFinancingRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
 }
 
 // ComplianceService handles Epic 4 compliance
@@ -69,16 +103,6 @@ func NewComplianceService(db *gorm.DB, cfg *config.Config) *ComplianceService {
 	return &ComplianceService{db: db, config: cfg}
 }
 
-// FundingMatchingService handles funding matching
-type FundingMatchingService struct {
-	db     *gorm.DB
-	config *config.Config
-}
-
-func NewFundingMatchingService(db *gorm.DB, cfg *config.Config) *FundingMatchingService {
-	return &FundingMatchingService{db: db, config: cfg}
-}
-
 // RiskAssessmentService handles risk assessment
 type RiskAssessmentService struct {
 	db     *gorm.DB
@@ -93,8 +117,10 @@ func NewRiskAssessmentService(db *gorm.DB, cfg *config.Config) *RiskAssessmentSe
 type AuditService struct {
 	db     *gorm.DB
 	config *config.Config
+	client *http.Client
+	mu     sync.Mutex // serialises appends to the audit chain within this process
 }
 
 func NewAuditService(db *gorm.DB, cfg *config.Config) *AuditService {
-	return &AuditService{db: db, config: cfg}
+	return &AuditService{db: db, config: cfg, client: &http.Client{Timeout: 30 * time.Second}}
 }
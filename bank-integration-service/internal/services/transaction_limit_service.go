@@ -0,0 +1,393 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bank-integration-service/internal/config"
+	"bank-integration-service/internal/models"
+)
+
+// Rolling velocity windows
+const (
+	limitDailyWindow   = 24 * time.Hour
+	limitMonthlyWindow = 30 * 24 * time.Hour
+)
+
+// limitReservationTTL bounds how long a payment's reservation holds headroom when its
+// request never finishes
+const limitReservationTTL = 5 * time.Minute
+
+// structuringMinCount is how many near-threshold payments within a day flag possible
+// structuring; structuringBand is how close to the threshold a payment must be
+const (
+	structuringMinCount = 3
+	structuringBand     = 0.9
+)
+
+// countedPaymentStatuses are the statuses that use up limit headroom
+var countedPaymentStatuses = []string{"pending", "processing", "completed"}
+
+var (
+	ErrLimitOverrideNotFound = errors.New("transaction limit override not found")
+	ErrInvalidLimitOverride  = errors.New("invalid transaction limit override")
+)
+
+// TransactionCheck describes a payment about to be made
+type TransactionCheck struct {
+	CustomerID       *uuid.UUID
+	BankConnectionID *uuid.UUID
+	Amount           float64
+	Currency         string
+	Path             string
+	RequestID        string
+	RequestedBy      string
+}
+
+// LimitViolation is one exceeded limit
+type LimitViolation struct {
+	Scope     string  `json:"scope"`  // customer, bank_connection
+	Window    string  `json:"window"` // daily, monthly
+	Limit     float64 `json:"limit"`
+	Used      float64 `json:"used"`
+	Requested float64 `json:"requested"`
+}
+
+// LimitCheckResult is the outcome of a velocity check
+type LimitCheckResult struct {
+	Allowed            bool             `json:"allowed"`
+	Violations         []LimitViolation `json:"violations,omitempty"`
+	SuspiciousFlags    []string         `json:"suspicious_flags,omitempty"`
+	ComplianceRecordID *uuid.UUID       `json:"compliance_record_id,omitempty"`
+	ReservationID      *uuid.UUID       `json:"-"`
+}
+
+// CustomerLimits reports a customer's effective limits and rolling usage
+type CustomerLimits struct {
+	CustomerID   uuid.UUID                        `json:"customer_id"`
+	DailyLimit   float64                          `json:"daily_limit"`
+	MonthlyLimit float64                          `json:"monthly_limit"`
+	DailyUsed    float64                          `json:"daily_used"`
+	MonthlyUsed  float64                          `json:"monthly_used"`
+	Override     *models.TransactionLimitOverride `json:"override,omitempty"`
+}
+
+// LimitOverrideRequest is the input for setting a customer override
+type LimitOverrideRequest struct {
+	DailyLimit   *float64   `json:"daily_limit"`
+	MonthlyLimit *float64   `json:"monthly_limit"`
+	Reason       string     `json:"reason" binding:"required"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// TransactionLimitService enforces rolling daily and monthly velocity limits per customer
+// and per bank connection. Usage is aggregated from persisted payment transactions and
+// outstanding reservations, so every replica sees the same totals.
+type TransactionLimitService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+func NewTransactionLimitService(db *gorm.DB, cfg *config.Config) *TransactionLimitService {
+	return &TransactionLimitService{db: db, config: cfg}
+}
+
+// Check evaluates a payment against the velocity limits and flags suspicious activity.
+// Checks for the same customer or connection are serialized, and an allowed payment
+// reserves its amount until Release is called, so concurrent payments cannot together
+// exceed a limit. Suspicious payments are not blocked; a compliance record is opened for
+// manual review.
+func (s *TransactionLimitService) Check(check TransactionCheck) (*LimitCheckResult, error) {
+	result := &LimitCheckResult{Allowed: true}
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockLimitSubjects(tx, check); err != nil {
+			return fmt.Errorf("failed to lock transaction limits: %w", err)
+		}
+		if err := tx.Where("expires_at <= ?", now).Delete(&models.TransactionLimitReservation{}).Error; err != nil {
+			return fmt.Errorf("failed to expire limit reservations: %w", err)
+		}
+
+		if check.CustomerID != nil {
+			limits, err := s.customerLimits(tx, *check.CustomerID, now)
+			if err != nil {
+				return err
+			}
+			result.addViolation("customer", "daily", limits.DailyLimit, limits.DailyUsed, check.Amount)
+			result.addViolation("customer", "monthly", limits.MonthlyLimit, limits.MonthlyUsed, check.Amount)
+		}
+
+		if check.BankConnectionID != nil {
+			daily, err := s.usage(tx, "bank_connection_id", *check.BankConnectionID, now.Add(-limitDailyWindow), now)
+			if err != nil {
+				return err
+			}
+			monthly, err := s.usage(tx, "bank_connection_id", *check.BankConnectionID, now.Add(-limitMonthlyWindow), now)
+			if err != nil {
+				return err
+			}
+			result.addViolation("bank_connection", "daily", s.config.ConnectionDailyTransactionLimit, daily, check.Amount)
+			result.addViolation("bank_connection", "monthly", s.config.ConnectionMonthlyTransactionLimit, monthly, check.Amount)
+		}
+
+		if !result.Allowed {
+			return nil
+		}
+		reservation := &models.TransactionLimitReservation{
+			CustomerID:       check.CustomerID,
+			BankConnectionID: check.BankConnectionID,
+			Amount:           check.Amount,
+			RequestID:        check.RequestID,
+			ExpiresAt:        now.Add(limitReservationTTL),
+		}
+		if err := tx.Create(reservation).Error; err != nil {
+			return fmt.Errorf("failed to reserve transaction limit: %w", err)
+		}
+		result.ReservationID = &reservation.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := s.suspiciousFlags(check, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) > 0 {
+		result.SuspiciousFlags = flags
+		record, err := s.openReview(check, result)
+		if err != nil {
+			return nil, err
+		}
+		result.ComplianceRecordID = &record.ID
+	}
+	return result, nil
+}
+
+// Release frees a reservation once its request has finished. A payment it was made for
+// counts through its transaction from then on.
+func (s *TransactionLimitService) Release(reservationID uuid.UUID) error {
+	if err := s.db.Delete(&models.TransactionLimitReservation{}, "id = ?", reservationID).Error; err != nil {
+		return fmt.Errorf("failed to release limit reservation: %w", err)
+	}
+	return nil
+}
+
+// lockLimitSubjects takes transaction-scoped advisory locks on the customer and the
+// connection, always in that order
+func lockLimitSubjects(tx *gorm.DB, check TransactionCheck) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	keys := []string{}
+	if check.CustomerID != nil {
+		keys = append(keys, "transaction_limits:customer:"+check.CustomerID.String())
+	}
+	if check.BankConnectionID != nil {
+		keys = append(keys, "transaction_limits:bank_connection:"+check.BankConnectionID.String())
+	}
+	for _, key := range keys {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *LimitCheckResult) addViolation(scope, window string, limit, used, amount float64) {
+	if limit <= 0 || used+amount <= limit {
+		return
+	}
+	r.Allowed = false
+	r.Violations = append(r.Violations, LimitViolation{
+		Scope:     scope,
+		Window:    window,
+		Limit:     limit,
+		Used:      roundTo(used, 2),
+		Requested: amount,
+	})
+}
+
+// GetCustomerLimits returns the limits in force for a customer and their rolling usage
+func (s *TransactionLimitService) GetCustomerLimits(customerID uuid.UUID) (*CustomerLimits, error) {
+	return s.customerLimits(s.db, customerID, time.Now())
+}
+
+func (s *TransactionLimitService) customerLimits(db *gorm.DB, customerID uuid.UUID, now time.Time) (*CustomerLimits, error) {
+	limits := &CustomerLimits{
+		CustomerID:   customerID,
+		DailyLimit:   s.config.MaxDailyTransactionAmount,
+		MonthlyLimit: s.config.MaxMonthlyTransactionAmount,
+	}
+
+	var override models.TransactionLimitOverride
+	err := db.Where("customer_id = ? AND (expires_at IS NULL OR expires_at > ?)", customerID, now).First(&override).Error
+	switch {
+	case err == nil:
+		limits.Override = &override
+		if override.DailyLimit != nil {
+			limits.DailyLimit = *override.DailyLimit
+		}
+		if override.MonthlyLimit != nil {
+			limits.MonthlyLimit = *override.MonthlyLimit
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to load limit override: %w", err)
+	}
+
+	if limits.DailyUsed, err = s.usage(db, "customer_id", customerID, now.Add(-limitDailyWindow), now); err != nil {
+		return nil, err
+	}
+	if limits.MonthlyUsed, err = s.usage(db, "customer_id", customerID, now.Add(-limitMonthlyWindow), now); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// usage sums payments made since from and outstanding reservations, keyed by
+// customer_id or bank_connection_id
+func (s *TransactionLimitService) usage(db *gorm.DB, column string, id uuid.UUID, from, now time.Time) (float64, error) {
+	var paid, reserved float64
+	err := db.Model(&models.PaymentTransaction{}).
+		Where(column+" = ? AND created_at >= ? AND status IN ?", id, from, countedPaymentStatuses).
+		Select("COALESCE(SUM(amount), 0)").Scan(&paid).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate payment usage: %w", err)
+	}
+	err = db.Model(&models.TransactionLimitReservation{}).
+		Where(column+" = ? AND expires_at > ?", id, now).
+		Select("COALESCE(SUM(amount), 0)").Scan(&reserved).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate reserved usage: %w", err)
+	}
+	return paid + reserved, nil
+}
+
+func (s *TransactionLimitService) suspiciousFlags(check TransactionCheck, now time.Time) ([]string, error) {
+	threshold := s.config.SuspiciousActivityThreshold
+	if threshold <= 0 {
+		return nil, nil
+	}
+
+	flags := []string{}
+	if check.Amount > threshold {
+		flags = append(flags, "large_transaction")
+	}
+
+	// Several payments just under the threshold within a day suggest structuring
+	if check.CustomerID != nil && check.Amount >= threshold*structuringBand && check.Amount <= threshold {
+		var nearThreshold int64
+		err := s.db.Model(&models.PaymentTransaction{}).
+			Where("customer_id = ? AND created_at >= ? AND status IN ? AND amount >= ? AND amount <= ?",
+				*check.CustomerID, now.Add(-limitDailyWindow), countedPaymentStatuses, threshold*structuringBand, threshold).
+			Count(&nearThreshold).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for structuring: %w", err)
+		}
+		if nearThreshold+1 >= structuringMinCount {
+			flags = append(flags, "possible_structuring")
+		}
+	}
+	return flags, nil
+}
+
+// openReview records the suspicious activity as a compliance record awaiting review
+func (s *TransactionLimitService) openReview(check TransactionCheck, result *LimitCheckResult) (*models.ComplianceRecord, error) {
+	entityType, entityID := "customer", uuid.Nil
+	if check.CustomerID != nil {
+		entityID = *check.CustomerID
+	} else if check.BankConnectionID != nil {
+		entityType, entityID = "bank_connection", *check.BankConnectionID
+	}
+
+	record := &models.ComplianceRecord{
+		EntityType:     entityType,
+		EntityID:       entityID,
+		ComplianceType: "aml",
+		Status:         "under_review",
+		CheckedAt:      time.Now(),
+		ComplianceData: toJSON(map[string]interface{}{
+			"amount":             check.Amount,
+			"currency":           check.Currency,
+			"customer_id":        check.CustomerID,
+			"bank_connection_id": check.BankConnectionID,
+			"path":               check.Path,
+			"request_id":         check.RequestID,
+			"requested_by":       check.RequestedBy,
+			"threshold":          s.config.SuspiciousActivityThreshold,
+			"limit_violations":   result.Violations,
+		}),
+		Issues:      toJSON(result.SuspiciousFlags),
+		Remediation: "Manual review required before the payment is released",
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		return nil, fmt.Errorf("failed to create compliance record: %w", err)
+	}
+	return record, nil
+}
+
+// SetOverride creates or replaces a customer's limit override
+func (s *TransactionLimitService) SetOverride(customerID uuid.UUID, req LimitOverrideRequest, approvedBy string) (*models.TransactionLimitOverride, error) {
+	if req.DailyLimit == nil && req.MonthlyLimit == nil {
+		return nil, fmt.Errorf("%w: daily_limit or monthly_limit is required", ErrInvalidLimitOverride)
+	}
+	if (req.DailyLimit != nil && *req.DailyLimit < 0) || (req.MonthlyLimit != nil && *req.MonthlyLimit < 0) {
+		return nil, fmt.Errorf("%w: limits must not be negative", ErrInvalidLimitOverride)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidLimitOverride)
+	}
+
+	override := &models.TransactionLimitOverride{
+		CustomerID:   customerID,
+		DailyLimit:   req.DailyLimit,
+		MonthlyLimit: req.MonthlyLimit,
+		Reason:       req.Reason,
+		ApprovedBy:   approvedBy,
+		ExpiresAt:    req.ExpiresAt,
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "customer_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"daily_limit", "monthly_limit", "reason", "approved_by", "expires_at", "updated_at"}),
+	}).Create(override).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save limit override: %w", err)
+	}
+
+	// Reload so a replaced override reports its original ID and creation time
+	if err := s.db.Where("customer_id = ?", customerID).First(override).Error; err != nil {
+		return nil, fmt.Errorf("failed to load limit override: %w", err)
+	}
+	return override, nil
+}
+
+// RemoveOverride restores the default limits for a customer
+func (s *TransactionLimitService) RemoveOverride(customerID uuid.UUID) error {
+	result := s.db.Where("customer_id = ?", customerID).Delete(&models.TransactionLimitOverride{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove limit override: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLimitOverrideNotFound
+	}
+	return nil
+}
+
+// ResolveCustomer returns the customer that owns a bank connection
+func (s *TransactionLimitService) ResolveCustomer(bankConnectionID uuid.UUID) (*uuid.UUID, error) {
+	var connection models.BankConnection
+	if err := s.db.Select("user_id").Where("id = ?", bankConnectionID).First(&connection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load bank connection: %w", err)
+	}
+	return &connection.UserID, nil
+}
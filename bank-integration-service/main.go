@@ -2,9 +2,6 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,16 +9,14 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	"gorm.io/gorm"
 
 	"bank-integration-service/internal/config"
 	"bank-integration-service/internal/database"
 	"bank-integration-service/internal/handlers"
 	"bank-integration-service/internal/middleware"
-	"bank-integration-service/internal/models"
 	"bank-integration-service/internal/services"
+	"shared/webhooks"
 )
 
 func main() {
@@ -49,19 +44,62 @@ func main() {
 	creditDecisionService := services.NewCreditDecisionService(db, cfg)
 	paymentProcessingService := services.NewPaymentProcessingService(db, cfg)
 	financingService := services.NewFinancingService(db, cfg)
+	offerAuctionService := services.NewOfferAuctionService(db, cfg, bankAPIService)
 	portfolioService := services.NewPortfolioService(db, cfg)
 	complianceService := services.NewComplianceService(db, cfg)
 	fundingMatchingService := services.NewFundingMatchingService(db, cfg)
 	riskAssessmentService := services.NewRiskAssessmentService(db, cfg)
 	auditService := services.NewAuditService(db, cfg)
+	reconciliationService := services.NewReconciliationService(db, cfg)
+	reportService := services.NewReportService(db, cfg, portfolioService)
+	apiKeyService := services.NewAPIKeyService(db, cfg)
+	transactionLimitService := services.NewTransactionLimitService(db, cfg)
+	amlService := services.NewAMLService(db, cfg)
+	if err := amlService.SeedScenarios(); err != nil {
+		log.Printf("Failed to seed AML scenarios: %v", err)
+	}
+	regulatoryFilingService := services.NewRegulatoryFilingService(db, cfg)
+	retentionService := services.NewRetentionService(db, cfg)
+	if err := retentionService.SeedRules(); err != nil {
+		log.Printf("Failed to seed retention rules: %v", err)
+	}
+	dualControlService := services.NewDualControlService(db, cfg)
+
+	// Inbound webhook verification, seeded with the configured per-bank secrets
+	webhookSecrets := webhooks.NewGormSecretStore(db)
+	for code, bank := range cfg.BankConfigs {
+		if err := webhookSecrets.Seed(code, bank.WebhookSecret); err != nil {
+			log.Printf("Failed to seed webhook secret for %s: %v", code, err)
+		}
+	}
+	webhookEvents := webhooks.NewGormReplayStore(db)
+	webhookVerifier := webhooks.NewVerifier(webhookSecrets, webhookEvents, cfg.WebhookTimestampTolerance, cfg.WebhookDedupeRetention)
+
+	// Start scheduled background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reconciliationService.StartScheduler(ctx)
+	go creditDecisionService.StartExpiryScheduler(ctx)
+	go offerAuctionService.StartScheduler(ctx)
+	go fundingMatchingService.StartScheduler(ctx)
+	go portfolioService.StartSnapshotScheduler(ctx)
+	go reportService.StartWorker(ctx)
+	go webhookEvents.StartPurgeScheduler(ctx)
+	go amlService.StartMonitor(ctx)
+	go auditService.StartAnchorScheduler(ctx)
+	go regulatoryFilingService.StartScheduler(ctx)
+	go retentionService.StartScheduler(ctx)
 
 	// Initialize handlers
-	bankHandler := handlers.NewBankHandler(bankAPIService, complianceService, auditService)
+	bankHandler := handlers.NewBankHandler(bankAPIService, complianceService, auditService, paymentProcessingService)
 	creditHandler := handlers.NewCreditHandler(creditDecisionService, riskAssessmentService, complianceService)
-	paymentHandler := handlers.NewPaymentHandler(paymentProcessingService, bankAPIService, auditService)
-	financingHandler := handlers.NewFinancingHandler(financingService, fundingMatchingService, complianceService)
-	portfolioHandler := handlers.NewPortfolioHandler(portfolioService, riskAssessmentService)
-	complianceHandler := handlers.NewComplianceHandler(complianceService, auditService)
+	paymentHandler := handlers.NewPaymentHandler(paymentProcessingService, reconciliationService, bankAPIService, auditService)
+	financingHandler := handlers.NewFinancingHandler(financingService, offerAuctionService, fundingMatchingService, complianceService)
+	portfolioHandler := handlers.NewPortfolioHandler(portfolioService, reportService, riskAssessmentService)
+	complianceHandler := handlers.NewComplianceHandler(complianceService, auditService, transactionLimitService, amlService, regulatoryFilingService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	dualControlHandler := handlers.NewDualControlHandler(dualControlService)
 
 	// Initialize Gin router
 	if cfg.Environment == "production" {
@@ -79,7 +117,7 @@ func main() {
 	} else {
 		corsConfig.AllowOrigins = cfg.AllowedOrigins
 	}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-API-Key", "X-Bank-ID"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-API-Key", "X-Bank-ID", middleware.ApprovalHeader}
 	router.Use(cors.New(corsConfig))
 
 	// Middleware
@@ -111,9 +149,38 @@ func main() {
 		})
 	})
 
+	// Inbound webhooks authenticate with signatures rather than user tokens
+	webhookRoutes := router.Group("/api/v1/integrations/webhooks")
+	if cfg.WebhookRequireAPIKey {
+		webhookRoutes.Use(middleware.APIKeyAuth(apiKeyService), middleware.RequireScope("webhooks:receive"))
+	}
+	webhookRoutes.Use(webhooks.Middleware(webhookVerifier))
+	{
+		webhookRoutes.POST("/bank-notification", bankHandler.BankWebhookHandler)
+		webhookRoutes.POST("/payment-status", paymentHandler.PaymentStatusWebhook)
+	}
+
+	// Partner integrations authenticate with scoped API keys. Payments and transfers above
+	// the dual control thresholds wait for a checker like those of users; the partner
+	// repeats the request with the approval ID once it was confirmed.
+	partner := router.Group("/api/v1/partner")
+	partner.Use(middleware.APIKeyAuth(apiKeyService))
+	partner.Use(middleware.TransactionLimits(transactionLimitService))
+	partner.Use(middleware.AuditLogging(auditService))
+	{
+		partner.POST("/payments/process", middleware.RequireScope("payments:write"), middleware.DualControl(dualControlService, services.DualControlPayment), paymentHandler.ProcessPayment)
+		partner.GET("/payments/:paymentId/status", middleware.RequireScope("payments:read"), paymentHandler.GetPaymentStatus)
+		partner.POST("/transfers/initiate", middleware.RequireScope("transfers:write"), middleware.DualControl(dualControlService, services.DualControlTransfer), paymentHandler.InitiateTransfer)
+		partner.GET("/transfers/:transferId/status", middleware.RequireScope("transfers:read"), paymentHandler.GetTransferStatus)
+		partner.POST("/sync/account-balances", middleware.RequireScope("accounts:sync"), bankHandler.SyncAccountBalances)
+		partner.POST("/sync/transactions", middleware.RequireScope("accounts:sync"), bankHandler.SyncTransactions)
+	}
+
 	// API routes
 	v1 := router.Group("/api/v1")
 	v1.Use(middleware.JWTAuth(cfg.JWTSecret))
+	v1.Use(middleware.TransactionLimits(transactionLimitService))
+	v1.Use(middleware.AuditLogging(auditService))
 	{
 		// Bank connection and management
 		banks := v1.Group("/banks")
@@ -133,25 +200,31 @@ func main() {
 			credit.POST("/decisions/request", creditHandler.RequestCreditDecision)
 			credit.GET("/decisions/:decisionId", creditHandler.GetCreditDecision)
 			credit.PUT("/decisions/:decisionId/update", creditHandler.UpdateCreditDecision)
-			credit.POST("/decisions/:decisionId/approve", creditHandler.ApproveCreditDecision)
-			credit.POST("/decisions/:decisionId/reject", creditHandler.RejectCreditDecision)
+			credit.POST("/decisions/:decisionId/approve", middleware.RequireRole("bank", "admin", "bank_admin"), creditHandler.ApproveCreditDecision)
+			credit.POST("/decisions/:decisionId/reject", middleware.RequireRole("bank", "admin", "bank_admin"), creditHandler.RejectCreditDecision)
 			credit.GET("/decisions", creditHandler.GetCreditDecisions)
 			credit.POST("/assessment/risk", creditHandler.AssessRisk)
 			credit.GET("/limits/:customerId", creditHandler.GetCreditLimits)
-			credit.PUT("/limits/:customerId", creditHandler.UpdateCreditLimits)
+			credit.PUT("/limits/:customerId", middleware.DualControl(dualControlService, services.DualControlCreditLimit), creditHandler.UpdateCreditLimits)
 		}
 
 		// Payment processing
 		payments := v1.Group("/payments")
 		{
-			payments.POST("/process", paymentHandler.ProcessPayment)
+			payments.POST("/process", middleware.DualControl(dualControlService, services.DualControlPayment), paymentHandler.ProcessPayment)
 			payments.GET("/:paymentId", paymentHandler.GetPayment)
 			payments.GET("/:paymentId/status", paymentHandler.GetPaymentStatus)
 			payments.POST("/:paymentId/cancel", paymentHandler.CancelPayment)
-			payments.POST("/bulk-process", paymentHandler.BulkProcessPayments)
+			payments.POST("/bulk-process", middleware.DualControl(dualControlService, services.DualControlPayment), paymentHandler.BulkProcessPayments)
 			payments.GET("/transactions", paymentHandler.GetTransactions)
-			payments.POST("/reconcile", paymentHandler.ReconcilePayments)
-			payments.GET("/reconciliation/:jobId", paymentHandler.GetReconciliationStatus)
+			// Reconciliation is bank staff work. Jobs match every connection's statements and
+			// are left to administrators; bank users import and review their own connections'.
+			payments.POST("/reconcile", middleware.RequireRole("admin", "bank_admin"), paymentHandler.ReconcilePayments)
+			payments.GET("/reconciliation/:jobId", middleware.RequireRole("admin", "bank_admin"), paymentHandler.GetReconciliationStatus)
+			payments.POST("/statements/import", middleware.RequireRole("bank", "admin", "bank_admin"), paymentHandler.ImportStatementLines)
+			payments.GET("/reconciliation/queue", middleware.RequireRole("bank", "admin", "bank_admin"), paymentHandler.GetManualMatchQueue)
+			payments.POST("/reconciliation/queue/:lineId/match", middleware.RequireRole("bank", "admin", "bank_admin"), paymentHandler.ConfirmManualMatch)
+			payments.POST("/reconciliation/queue/:lineId/ignore", middleware.RequireRole("bank", "admin", "bank_admin"), paymentHandler.IgnoreStatementLine)
 		}
 
 		// Financing requests and management
@@ -163,7 +236,11 @@ func main() {
 			financing.POST("/requests/:requestId/review", financingHandler.ReviewFinancingRequest)
 			financing.POST("/requests/:requestId/approve", financingHandler.ApproveFinancing)
 			financing.POST("/requests/:requestId/reject", financingHandler.RejectFinancing)
-			financing.POST("/requests/:requestId/disburse", financingHandler.DisburseFinancing)
+			financing.POST("/requests/:requestId/disburse", middleware.RequireStepUp(cfg.StepUpMaxAge), middleware.DualControl(dualControlService, services.DualControlDisbursement), financingHandler.DisburseFinancing)
+			financing.POST("/requests/:requestId/auction", financingHandler.StartOfferAuction)
+			financing.GET("/requests/:requestId/offers", financingHandler.GetFinancingOffers)
+			financing.POST("/requests/:requestId/offers", middleware.RequireRole("bank", "bank_admin"), financingHandler.SubmitFinancingOffer)
+			financing.POST("/requests/:requestId/offers/:offerId/accept", financingHandler.AcceptFinancingOffer)
 			financing.GET("/opportunities", financingHandler.GetFinancingOpportunities)
 			financing.POST("/match-funding", financingHandler.MatchFunding)
 		}
@@ -177,7 +254,9 @@ func main() {
 			portfolio.GET("/exposures", portfolioHandler.GetExposures)
 			portfolio.GET("/concentrations", portfolioHandler.GetConcentrations)
 			portfolio.POST("/reports/generate", portfolioHandler.GenerateReport)
+			portfolio.GET("/reports", portfolioHandler.ListReports)
 			portfolio.GET("/reports/:reportId", portfolioHandler.GetReport)
+			portfolio.GET("/reports/:reportId/download", portfolioHandler.DownloadReport)
 			portfolio.GET("/analytics", portfolioHandler.GetAnalytics)
 		}
 
@@ -193,6 +272,9 @@ func main() {
 			funding.GET("/allocations", financingHandler.GetFundingAllocations)
 			funding.POST("/matching/run", financingHandler.RunFundingMatching)
 			funding.GET("/matching/results", financingHandler.GetMatchingResults)
+			// Matches are confirmed or rejected by the funder whose source they allocate
+			funding.POST("/matching/:matchId/confirm", middleware.RequireRole("bank", "admin", "bank_admin"), financingHandler.ConfirmFundingMatch)
+			funding.POST("/matching/:matchId/reject", middleware.RequireRole("bank", "admin", "bank_admin"), financingHandler.RejectFundingMatch)
 		}
 
 		// Compliance and audit
@@ -203,18 +285,61 @@ func main() {
 			compliance.GET("/epic4/reports", complianceHandler.GetEpic4Reports)
 			compliance.POST("/audit/trail", complianceHandler.CreateAuditTrail)
 			compliance.GET("/audit/trails", complianceHandler.GetAuditTrails)
-			compliance.POST("/regulatory/filing", complianceHandler.CreateRegulatoryFiling)
-			compliance.GET("/regulatory/filings", complianceHandler.GetRegulatoryFilings)
+			compliance.GET("/audit/verify", middleware.RequireRole("admin", "bank_admin"), complianceHandler.VerifyAuditChain)
+			compliance.GET("/audit/anchors", middleware.RequireRole("admin", "bank_admin"), complianceHandler.GetAuditAnchors)
+			compliance.POST("/audit/anchors", middleware.RequireRole("admin", "bank_admin"), complianceHandler.AnchorAuditChain)
+			compliance.POST("/regulatory/filing", middleware.RequireRole("admin", "bank_admin"), complianceHandler.CreateRegulatoryFiling)
+			compliance.GET("/regulatory/filings", middleware.RequireRole("admin", "bank_admin"), complianceHandler.GetRegulatoryFilings)
+			compliance.GET("/regulatory/filings/:filingId", middleware.RequireRole("admin", "bank_admin"), complianceHandler.GetRegulatoryFiling)
+			compliance.POST("/regulatory/filings/:filingId/submit", middleware.RequireRole("admin", "bank_admin"), complianceHandler.SubmitRegulatoryFiling)
+			compliance.POST("/regulatory/filings/:filingId/resubmit", middleware.RequireRole("admin", "bank_admin"), complianceHandler.ResubmitRegulatoryFiling)
+			compliance.POST("/regulatory/filings/:filingId/status", middleware.RequireRole("admin", "bank_admin"), complianceHandler.RefreshRegulatoryFilingStatus)
+			compliance.GET("/regulatory/filings/:filingId/submissions", middleware.RequireRole("admin", "bank_admin"), complianceHandler.GetRegulatoryFilingSubmissions)
+			compliance.GET("/limits/:customerId", complianceHandler.GetCustomerLimits)
+			compliance.PUT("/limits/:customerId/override", middleware.RequireRole("admin", "bank_admin"), complianceHandler.SetLimitOverride)
+			compliance.DELETE("/limits/:customerId/override", middleware.RequireRole("admin", "bank_admin"), complianceHandler.DeleteLimitOverride)
+		}
+
+		// Data retention: rules, legal holds and signed purge runs
+		retention := v1.Group("/compliance/retention")
+		retention.Use(middleware.RequireRole("admin", "bank_admin"))
+		{
+			retention.GET("/rules", retentionHandler.GetRules)
+			retention.POST("/rules", retentionHandler.CreateRule)
+			retention.PUT("/rules/:name", retentionHandler.UpdateRule)
+			retention.GET("/holds", retentionHandler.GetLegalHolds)
+			retention.POST("/holds", retentionHandler.PlaceLegalHold)
+			retention.POST("/holds/:holdId/release", retentionHandler.ReleaseLegalHold)
+			retention.GET("/runs", retentionHandler.GetPurgeRuns)
+			retention.POST("/runs", retentionHandler.RunPurge)
+			retention.GET("/runs/:runId", retentionHandler.GetPurgeRun)
+			retention.GET("/runs/:runId/verify", retentionHandler.VerifyPurgeRun)
+		}
+
+		// AML transaction monitoring and case management
+		aml := v1.Group("/compliance/aml")
+		aml.Use(middleware.RequireRole("admin", "bank_admin"))
+		{
+			aml.GET("/scenarios", complianceHandler.GetAMLScenarios)
+			aml.PUT("/scenarios/:code", complianceHandler.UpdateAMLScenario)
+			aml.POST("/screen/payments/:paymentId", complianceHandler.ScreenPayment)
+			aml.POST("/screen/financing/:requestId", complianceHandler.ScreenFinancing)
+			aml.GET("/alerts", complianceHandler.GetAMLAlerts)
+			aml.GET("/alerts/:alertId", complianceHandler.GetAMLAlert)
+			aml.POST("/alerts/:alertId/assign", complianceHandler.AssignAMLAlert)
+			aml.POST("/alerts/:alertId/notes", complianceHandler.AddAMLAlertNote)
+			aml.POST("/alerts/:alertId/escalate", complianceHandler.EscalateAMLAlert)
+			aml.POST("/alerts/:alertId/close", complianceHandler.CloseAMLAlert)
 		}
 
 		// Account management
 		accounts := v1.Group("/accounts")
 		{
 			accounts.GET("", bankHandler.GetBankAccounts)
-			accounts.POST("", bankHandler.CreateBankAccount)
+			accounts.POST("", middleware.RequireStepUp(cfg.StepUpMaxAge), bankHandler.CreateBankAccount)
 			accounts.GET("/:accountId", bankHandler.GetBankAccount)
-			accounts.PUT("/:accountId", bankHandler.UpdateBankAccount)
-			accounts.DELETE("/:accountId", bankHandler.DeleteBankAccount)
+			accounts.PUT("/:accountId", middleware.RequireStepUp(cfg.StepUpMaxAge), middleware.DualControl(dualControlService, services.DualControlBankAccount), bankHandler.UpdateBankAccount)
+			accounts.DELETE("/:accountId", middleware.RequireStepUp(cfg.StepUpMaxAge), bankHandler.DeleteBankAccount)
 			accounts.GET("/:accountId/balance", bankHandler.GetAccountBalance)
 			accounts.GET("/:accountId/transactions", bankHandler.GetAccountTransactions)
 			accounts.POST("/:accountId/verify", bankHandler.VerifyBankAccount)
@@ -223,12 +348,23 @@ func main() {
 		// Real-time transfers
 		transfers := v1.Group("/transfers")
 		{
-			transfers.POST("/initiate", paymentHandler.InitiateTransfer)
+			transfers.POST("/initiate", middleware.DualControl(dualControlService, services.DualControlTransfer), paymentHandler.InitiateTransfer)
 			transfers.GET("/:transferId", paymentHandler.GetTransfer)
 			transfers.GET("/:transferId/status", paymentHandler.GetTransferStatus)
 			transfers.POST("/:transferId/cancel", paymentHandler.CancelTransfer)
 			transfers.GET("/real-time/status", paymentHandler.GetRealTimeTransferStatus)
-			transfers.POST("/bulk-transfer", paymentHandler.BulkTransfer)
+			transfers.POST("/bulk-transfer", middleware.DualControl(dualControlService, services.DualControlTransfer), paymentHandler.BulkTransfer)
+		}
+
+		// Maker-checker approvals of the requests held above. Checkers confirm other users'
+		// requests; the initiator then repeats the request with the X-Approval-ID header.
+		approvals := v1.Group("/approvals")
+		{
+			approvals.GET("", dualControlHandler.GetApprovals)
+			approvals.GET("/:approvalId", dualControlHandler.GetApproval)
+			approvals.POST("/:approvalId/approve", middleware.RequireRole(cfg.DualControlCheckerRoles...), middleware.RequireStepUp(cfg.StepUpMaxAge), dualControlHandler.ApproveRequest)
+			approvals.POST("/:approvalId/reject", middleware.RequireRole(cfg.DualControlCheckerRoles...), dualControlHandler.RejectRequest)
+			approvals.POST("/:approvalId/cancel", dualControlHandler.CancelRequest)
 		}
 
 		// Administrative endpoints
@@ -242,13 +378,15 @@ func main() {
 			admin.GET("/metrics", getSystemMetrics)
 			admin.POST("/cache/clear", clearCache)
 			admin.GET("/audit/system", complianceHandler.GetSystemAuditLog)
+			admin.POST("/webhooks/:sender/rotate-secret", webhooks.RotateHandler(webhookSecrets))
+			admin.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+			admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+			admin.DELETE("/api-keys/:keyId", apiKeyHandler.RevokeAPIKey)
 		}
 
 		// Integration endpoints for external systems
 		integrations := v1.Group("/integrations")
 		{
-			integrations.POST("/webhooks/bank-notification", bankHandler.BankWebhookHandler)
-			integrations.POST("/webhooks/payment-status", paymentHandler.PaymentStatusWebhook)
 			integrations.GET("/external/bank-rates", bankHandler.GetExternalBankRates)
 			integrations.POST("/sync/account-balances", bankHandler.SyncAccountBalances)
 			integrations.POST("/sync/transactions", bankHandler.SyncTransactions)
@@ -260,7 +398,7 @@ func main() {
 			reports.GET("/financing-summary", financingHandler.GetFinancingSummary)
 			reports.GET("/payment-summary", paymentHandler.GetPaymentSummary)
 			reports.GET("/portfolio-summary", portfolioHandler.GetPortfolioSummary)
-			reports.POST("/custom-report", generateCustomReport)
+			reports.POST("/custom-report", portfolioHandler.GenerateReport) // Queued as a portfolio report job
 			reports.GET("/dashboard-data", getDashboardData)
 		}
 	}
@@ -403,31 +541,6 @@ func clearCache(c *gin.Context) {
 	})
 }
 
-func generateCustomReport(c *gin.Context) {
-	var request struct {
-		ReportType string                 `json:"report_type"`
-		StartDate  time.Time              `json:"start_date"`
-		EndDate    time.Time              `json:"end_date"`
-		Filters    map[string]interface{} `json:"filters"`
-		Format     string                 `json:"format"` // json, csv, pdf
-	}
-
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-		return
-	}
-
-	// Generate report based on request
-	reportData := generateReportData(request.ReportType, request.StartDate, request.EndDate, request.Filters)
-
-	c.JSON(http.StatusOK, gin.H{
-		"report_type": request.ReportType,
-		"period":      fmt.Sprintf("%s to %s", request.StartDate.Format("2006-01-02"), request.EndDate.Format("2006-01-02")),
-		"data":        reportData,
-		"generated_at": time.Now(),
-	})
-}
-
 func getDashboardData(c *gin.Context) {
 	dashboardData := gin.H{
 		"summary": gin.H{
@@ -511,27 +624,3 @@ func getActiveSessionCount() int {
 func getConcurrentConnectionCount() int {
 	return 18
 }
-
-func generateReportData(reportType string, startDate, endDate time.Time, filters map[string]interface{}) interface{} {
-	// Implementation would generate actual report data
-	return gin.H{
-		"summary": gin.H{
-			"total_transactions": 1250,
-			"total_volume":       "15,750,000",
-			"average_size":       "12,600",
-			"success_rate":       "99.1%",
-		},
-		"breakdown": []gin.H{
-			{
-				"category": "SME Financing",
-				"count":    800,
-				"volume":   "10,500,000",
-			},
-			{
-				"category": "Trade Finance",
-				"count":    450,
-				"volume":   "5,250,000",
-			},
-		},
-	}
-}
@@ -25,6 +25,8 @@ import (
 	"blockchain-ledger-service/internal/middleware"
 	"blockchain-ledger-service/internal/models"
 	"blockchain-ledger-service/internal/services"
+
+	"shared/webhooks"
 )
 
 func main() {
@@ -61,6 +63,19 @@ func main() {
 	complianceService := services.NewComplianceService(db, cfg)
 	duplicateCheckService := services.NewDuplicateCheckService(db, fabricGateway, cfg)
 
+	// Inbound webhook verification with replay protection shared across replicas. Secrets
+	// live in the database so they can be rotated; WEBHOOK_SECRETS seeds new senders.
+	if err := db.AutoMigrate(&webhooks.SenderSecret{}, &webhooks.ReceivedEvent{}); err != nil {
+		log.Fatal("Failed to migrate webhook tables:", err)
+	}
+	webhookSecrets := webhooks.NewGormSecretStore(db)
+	webhookSecrets.SeedStatic(webhooks.ParseStaticSecrets(os.Getenv("WEBHOOK_SECRETS")))
+	webhookEvents := webhooks.NewGormReplayStore(db)
+	webhookVerifier := webhooks.NewVerifier(webhookSecrets, webhookEvents, 5*time.Minute, 72*time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhookEvents.StartPurgeScheduler(ctx)
+
 	// Initialize handlers
 	ledgerHandler := handlers.NewLedgerHandler(ledgerService, auditService, complianceService)
 	tokenHandler := handlers.NewTokenHandler(tokenizationService, duplicateCheckService, complianceService)
@@ -116,6 +131,15 @@ func main() {
 		})
 	})
 
+	// Inbound webhooks authenticate with signatures rather than user tokens. Secrets are
+	// seeded from WEBHOOK_SECRETS=sender:secret,... and rotated through the admin API
+	webhookRoutes := router.Group("/api/v1/integrations/webhook")
+	webhookRoutes.Use(webhooks.Middleware(webhookVerifier))
+	{
+		webhookRoutes.POST("/settlement", tokenHandler.WebhookSettlement)
+		webhookRoutes.POST("/payment", tokenHandler.WebhookPayment)
+	}
+
 	// API routes
 	v1 := router.Group("/api/v1")
 	v1.Use(middleware.JWTAuth(cfg.JWTSecret))
@@ -208,6 +232,7 @@ func main() {
 			admin.POST("/restore-ledger", auditHandler.RestoreLedger)
 			admin.GET("/node-health", ledgerHandler.GetNodeHealth)
 			admin.POST("/consensus-check", ledgerHandler.CheckConsensus)
+			admin.POST("/webhooks/:sender/rotate-secret", webhooks.RotateHandler(webhookSecrets))
 		}
 
 		// Epic 4 compliance endpoints
@@ -233,8 +258,6 @@ func main() {
 		// Integration endpoints
 		integrations := v1.Group("/integrations")
 		{
-			integrations.POST("/webhook/settlement", tokenHandler.WebhookSettlement)
-			integrations.POST("/webhook/payment", tokenHandler.WebhookPayment)
 			integrations.GET("/external-verify/:transactionId", auditHandler.ExternalVerification)
 			integrations.POST("/sync/external-system", ledgerHandler.SyncExternalSystem)
 		}
@@ -63,6 +63,17 @@ type Investment struct {
 	ReturnDate         time.Time `json:"return_date"`
 }
 
+// AuditAnchor is the head of an off-chain audit chain, published by the service that keeps it
+type AuditAnchor struct {
+	Source    string    `json:"source"`
+	Sequence  int64     `json:"sequence"`
+	Hash      string    `json:"hash"`
+	PrevHash  string    `json:"prev_hash"`
+	Entries   int64     `json:"entries"`
+	CreatedAt time.Time `json:"created_at"`
+	TxID      string    `json:"tx_id"`
+}
+
 // TokenizeInvoice creates a new tokenized invoice on the ledger
 func (c *InvoiceFinancingContract) TokenizeInvoice(ctx contractapi.TransactionContextInterface, invoiceData string) (*Invoice, error) {
 	var invoice Invoice
@@ -568,6 +579,78 @@ func (c *InvoiceFinancingContract) QueryInvoicesByStatus(ctx contractapi.Transac
 	return invoices, nil
 }
 
+// AnchorAuditChain records an audit chain head. Anchors are write-once: publishing the
+// same head again is accepted, but a different hash for an anchored sequence is refused.
+func (c *InvoiceFinancingContract) AnchorAuditChain(ctx contractapi.TransactionContextInterface, anchorData string) (*AuditAnchor, error) {
+	var anchor AuditAnchor
+	err := json.Unmarshal([]byte(anchorData), &anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit anchor: %v", err)
+	}
+	if anchor.Source == "" || anchor.Sequence < 1 || anchor.Hash == "" {
+		return nil, fmt.Errorf("audit anchor requires source, sequence and hash")
+	}
+
+	key := auditAnchorKey(anchor.Source, anchor.Sequence)
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		var anchored AuditAnchor
+		if err := json.Unmarshal(existing, &anchored); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit anchor: %v", err)
+		}
+		if anchored.Hash != anchor.Hash {
+			return nil, fmt.Errorf("audit chain %s is already anchored at sequence %d with a different hash", anchor.Source, anchor.Sequence)
+		}
+		return &anchored, nil
+	}
+
+	anchor.TxID = ctx.GetStub().GetTxID()
+	anchorJSON, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit anchor: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, anchorJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put audit anchor to world state: %v", err)
+	}
+
+	eventData, _ := json.Marshal(anchor)
+	ctx.GetStub().SetEvent("AuditChainAnchored", eventData)
+
+	return &anchor, nil
+}
+
+// GetAuditAnchor retrieves the anchor a source published for a chain sequence
+func (c *InvoiceFinancingContract) GetAuditAnchor(ctx contractapi.TransactionContextInterface, source string, sequence string) (*AuditAnchor, error) {
+	seq, err := strconv.ParseInt(sequence, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit anchor sequence %q", sequence)
+	}
+
+	anchorJSON, err := ctx.GetStub().GetState(auditAnchorKey(source, seq))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit anchor: %v", err)
+	}
+	if anchorJSON == nil {
+		return nil, fmt.Errorf("audit anchor %s/%d does not exist", source, seq)
+	}
+
+	var anchor AuditAnchor
+	err = json.Unmarshal(anchorJSON, &anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit anchor: %v", err)
+	}
+
+	return &anchor, nil
+}
+
+func auditAnchorKey(source string, sequence int64) string {
+	return "audit_anchor_" + source + "_" + strconv.FormatInt(sequence, 10)
+}
+
 // HealthCheck returns the health status of the chaincode
 func (c *InvoiceFinancingContract) HealthCheck(ctx contractapi.TransactionContextInterface) string {
 	return "OK"
@@ -0,0 +1,150 @@
+// Package reports hands custom reports to the bank integration service, which queues them
+// as portfolio report jobs and renders CSV, XLSX and PDF output in a background worker.
+// The caller's token is forwarded, so report ownership is enforced there.
+package reports
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	bankReportsPath = "/api/v1/portfolio/reports"
+	localPath       = "/api/v1/reports/custom-report"
+)
+
+// Handler proxies report jobs to the bank integration service
+type Handler struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHandler(baseURL string) *Handler {
+	return &Handler{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// HandlerFromEnv reads BANK_INTEGRATION_SERVICE_URL, defaulting to the local service
+func HandlerFromEnv() *Handler {
+	baseURL := os.Getenv("BANK_INTEGRATION_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8087"
+	}
+	return NewHandler(baseURL)
+}
+
+// Generate queues a report job and answers 202 with its status URL on this service
+func (h *Handler) Generate(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	resp, err := h.forward(c, http.MethodPost, bankReportsPath+"/generate", body)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Report service is unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	h.relayJSON(c, resp, func(out map[string]interface{}) {
+		if report, ok := out["report"].(map[string]interface{}); ok {
+			out["status_url"] = fmt.Sprintf("%s/%v", localPath, report["id"])
+		}
+	})
+}
+
+// Get returns a report job's status
+func (h *Handler) Get(c *gin.Context) {
+	reportID, err := uuid.Parse(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	resp, err := h.forward(c, http.MethodGet, fmt.Sprintf("%s/%s", bankReportsPath, reportID), nil)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Report service is unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	h.relayJSON(c, resp, func(out map[string]interface{}) {
+		if _, ok := out["download_url"]; ok {
+			out["download_url"] = fmt.Sprintf("%s/%s/download", localPath, reportID)
+		}
+	})
+}
+
+// Download streams a completed report's artifact
+func (h *Handler) Download(c *gin.Context) {
+	reportID, err := uuid.Parse(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	resp, err := h.forward(c, http.MethodGet, fmt.Sprintf("%s/%s/download", bankReportsPath, reportID), nil)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Report service is unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		h.relayJSON(c, resp, nil)
+		return
+	}
+
+	headers := map[string]string{}
+	for _, name := range []string{"Content-Disposition", "X-Content-SHA256"} {
+		if value := resp.Header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	c.DataFromReader(http.StatusOK, resp.ContentLength, resp.Header.Get("Content-Type"), resp.Body, headers)
+}
+
+func (h *Handler) forward(c *gin.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, h.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", c.GetHeader("Authorization"))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if requestID := c.GetHeader("X-Request-ID"); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	return h.client.Do(req)
+}
+
+// relayJSON passes the bank service's JSON answer through, letting rewrite adjust its links
+func (h *Handler) relayJSON(c *gin.Context, resp *http.Response, rewrite func(map[string]interface{})) {
+	var out map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Invalid response from the report service"})
+		return
+	}
+	if rewrite != nil && resp.StatusCode < 300 {
+		rewrite(out)
+	}
+	c.JSON(resp.StatusCode, out)
+}
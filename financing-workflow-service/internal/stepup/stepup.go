@@ -0,0 +1,55 @@
+// Package stepup enforces fresh MFA for sensitive operations. The user management
+// service stamps an mfa_at claim into access tokens at MFA login and step-up.
+package stepup
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MaxAgeFromEnv reads STEP_UP_MAX_AGE, defaulting to five minutes
+func MaxAgeFromEnv() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("STEP_UP_MAX_AGE")); err == nil && value > 0 {
+		return value
+	}
+	return 5 * time.Minute
+}
+
+// Require rejects requests whose bearer token lacks an MFA assertion made within maxAge.
+// Clients answer the 403 by stepping up with the user management service and retrying.
+func Require(jwtSecret string, maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(jwtSecret), nil
+		})
+		if err != nil || !token.Valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		claims, _ := token.Claims.(jwt.MapClaims)
+		mfaAt, ok := claims["mfa_at"].(float64)
+		age := time.Since(time.Unix(int64(mfaAt), 0))
+		if !ok || age > maxAge || age < -time.Minute {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":            "Step-up authentication required",
+				"step_up_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
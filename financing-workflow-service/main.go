@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -21,7 +20,11 @@ import (
 	"financing-workflow-service/internal/handlers"
 	"financing-workflow-service/internal/middleware"
 	"financing-workflow-service/internal/models"
+	"financing-workflow-service/internal/reports"
 	"financing-workflow-service/internal/services"
+	"financing-workflow-service/internal/stepup"
+
+	"shared/webhooks"
 )
 
 func main() {
@@ -56,6 +59,19 @@ func main() {
 	notificationService := services.NewNotificationService(cfg)
 	auditService := services.NewAuditService(db, cfg)
 
+	// Inbound webhook verification with replay protection shared across replicas. Secrets
+	// live in the database so they can be rotated; WEBHOOK_SECRETS seeds new senders.
+	if err := db.AutoMigrate(&webhooks.SenderSecret{}, &webhooks.ReceivedEvent{}); err != nil {
+		log.Fatal("Failed to migrate webhook tables:", err)
+	}
+	webhookSecrets := webhooks.NewGormSecretStore(db)
+	webhookSecrets.SeedStatic(webhooks.ParseStaticSecrets(os.Getenv("WEBHOOK_SECRETS")))
+	webhookEvents := webhooks.NewGormReplayStore(db)
+	webhookVerifier := webhooks.NewVerifier(webhookSecrets, webhookEvents, 5*time.Minute, 72*time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhookEvents.StartPurgeScheduler(ctx)
+
 	// Initialize handlers
 	invoiceHandler := handlers.NewInvoiceHandler(invoiceService, documentService, complianceService)
 	financingHandler := handlers.NewFinancingHandler(financingService, creditService, workflowService)
@@ -65,6 +81,9 @@ func main() {
 	disbursementHandler := handlers.NewDisbursementHandler(financingService, blockchainService, auditService)
 	disputeHandler := handlers.NewDisputeHandler(financingService, workflowService, auditService)
 
+	// Custom reports run as portfolio report jobs in the bank integration service
+	reportHandler := reports.HandlerFromEnv()
+
 	// Initialize Gin router
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -107,6 +126,16 @@ func main() {
 		})
 	})
 
+	// Inbound webhooks authenticate with signatures rather than user tokens. Secrets are
+	// seeded from WEBHOOK_SECRETS=sender:secret,... and rotated through the admin API
+	webhookRoutes := router.Group("/api/v1/integrations/webhook")
+	webhookRoutes.Use(webhooks.Middleware(webhookVerifier))
+	{
+		webhookRoutes.POST("/buyer-confirmation", buyerHandler.WebhookBuyerConfirmation)
+		webhookRoutes.POST("/payment-received", disbursementHandler.WebhookPaymentReceived)
+		webhookRoutes.POST("/blockchain-event", agreementHandler.WebhookBlockchainEvent)
+	}
+
 	// API routes
 	v1 := router.Group("/api/v1")
 	v1.Use(middleware.JWTAuth(cfg.JWTSecret))
@@ -176,13 +205,14 @@ func main() {
 			agreements.GET("/:id/compliance-check", agreementHandler.CheckCompliance)
 		}
 
-		// Disbursement management
+		// Disbursement management; releasing funds needs a fresh MFA step-up
+		requireStepUp := stepup.Require(cfg.JWTSecret, stepup.MaxAgeFromEnv())
 		disbursements := v1.Group("/disbursements")
 		{
 			disbursements.POST("/create", disbursementHandler.CreateDisbursement)
 			disbursements.GET("/:id", disbursementHandler.GetDisbursement)
-			disbursements.POST("/:id/approve", disbursementHandler.ApproveDisbursement)
-			disbursements.POST("/:id/execute", disbursementHandler.ExecuteDisbursement)
+			disbursements.POST("/:id/approve", requireStepUp, disbursementHandler.ApproveDisbursement)
+			disbursements.POST("/:id/execute", requireStepUp, disbursementHandler.ExecuteDisbursement)
 			disbursements.GET("/:id/status", disbursementHandler.GetDisbursementStatus)
 			disbursements.POST("/:id/reconcile", disbursementHandler.ReconcileDisbursement)
 			disbursements.GET("/scheduled", disbursementHandler.GetScheduledDisbursements)
@@ -215,9 +245,6 @@ func main() {
 		// Integration endpoints
 		integrations := v1.Group("/integrations")
 		{
-			integrations.POST("/webhook/buyer-confirmation", buyerHandler.WebhookBuyerConfirmation)
-			integrations.POST("/webhook/payment-received", disbursementHandler.WebhookPaymentReceived)
-			integrations.POST("/webhook/blockchain-event", agreementHandler.WebhookBlockchainEvent)
 			integrations.GET("/external-verification/:invoiceId", invoiceHandler.ExternalVerification)
 		}
 
@@ -232,6 +259,7 @@ func main() {
 			admin.GET("/compliance/audit-logs", complianceService.GetAuditLogs)
 			admin.POST("/compliance/generate-report", complianceService.GenerateComplianceReport)
 			admin.GET("/system/health-check", systemHealthCheck)
+			admin.POST("/webhooks/:sender/rotate-secret", webhooks.RotateHandler(webhookSecrets))
 		}
 
 		// Reporting endpoints
@@ -240,7 +268,9 @@ func main() {
 			reports.GET("/financing-summary", financingHandler.GetFinancingSummary)
 			reports.GET("/workflow-status", workflowHandler.GetWorkflowStatusReport)
 			reports.GET("/dispute-summary", disputeHandler.GetDisputeSummary)
-			reports.POST("/custom-report", generateCustomReport)
+			reports.POST("/custom-report", reportHandler.Generate)
+			reports.GET("/custom-report/:reportId", reportHandler.Get)
+			reports.GET("/custom-report/:reportId/download", reportHandler.Download)
 		}
 	}
 
@@ -282,42 +312,6 @@ func systemHealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, healthStatus)
 }
 
-func generateCustomReport(c *gin.Context) {
-	var request struct {
-		ReportType string                 `json:"report_type"`
-		StartDate  time.Time              `json:"start_date"`
-		EndDate    time.Time              `json:"end_date"`
-		Filters    map[string]interface{} `json:"filters"`
-		Format     string                 `json:"format"` // json, csv, pdf
-	}
-
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-		return
-	}
-
-	// Generate custom report based on request
-	reportData := generateReportData(request.ReportType, request.StartDate, request.EndDate, request.Filters)
-
-	switch request.Format {
-	case "csv":
-		c.Header("Content-Type", "text/csv")
-		c.Header("Content-Disposition", "attachment; filename=report.csv")
-		// Convert to CSV and return
-	case "pdf":
-		c.Header("Content-Type", "application/pdf")
-		c.Header("Content-Disposition", "attachment; filename=report.pdf")
-		// Generate PDF and return
-	default:
-		c.JSON(http.StatusOK, gin.H{
-			"report_type": request.ReportType,
-			"period":      fmt.Sprintf("%s to %s", request.StartDate.Format("2006-01-02"), request.EndDate.Format("2006-01-02")),
-			"data":        reportData,
-			"generated_at": time.Now(),
-		})
-	}
-}
-
 // Helper functions for metrics (these would be implemented with actual database queries)
 func getActiveWorkflowCount() int {
 	// Implementation would query database for active workflows
@@ -338,28 +332,3 @@ func getScheduledDisbursementsCount() int {
 	// Implementation would query database for scheduled disbursements
 	return 28
 }
-
-func generateReportData(reportType string, startDate, endDate time.Time, filters map[string]interface{}) interface{} {
-	// This would implement the actual report generation logic
-	// based on the report type and parameters
-	return gin.H{
-		"summary": gin.H{
-			"total_transactions": 150,
-			"total_value":       "10,500,000",
-			"average_processing_time": "2.3 days",
-			"success_rate":      "97.3%",
-		},
-		"breakdown": []gin.H{
-			{
-				"category": "Invoice Financing",
-				"count":    120,
-				"value":    "8,500,000",
-			},
-			{
-				"category": "Trade Finance",
-				"count":    30,
-				"value":    "2,000,000",
-			},
-		},
-	}
-}
@@ -0,0 +1,68 @@
+package loginrisk
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is where an IP address was placed by the GeoIP database
+type Location struct {
+	Country        string  // ISO 3166-1 alpha-2
+	City           string  // English name, empty when the database only knows the country
+	Latitude       float64 // Zero with HasCoordinates unset when unknown
+	Longitude      float64
+	HasCoordinates bool
+	AnonymousProxy bool // VPN, Tor exit or hosting provider flagged by the database
+}
+
+// Locator looks addresses up in a local MaxMind GeoLite2/GeoIP2 City database, so login
+// data is not sent to a third party. A nil Locator finds nothing.
+type Locator struct {
+	reader *geoip2.Reader
+}
+
+func OpenLocator(path string) (*Locator, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database %s: %w", path, err)
+	}
+	return &Locator{reader: reader}, nil
+}
+
+// Lookup returns nil for private and unknown addresses
+func (l *Locator) Lookup(address string) *Location {
+	if l == nil {
+		return nil
+	}
+	ip := net.ParseIP(address)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return nil
+	}
+	record, err := l.reader.City(ip)
+	if err != nil || record.Country.IsoCode == "" {
+		return nil
+	}
+
+	location := &Location{
+		Country:        record.Country.IsoCode,
+		City:           record.City.Names["en"],
+		AnonymousProxy: record.Traits.IsAnonymousProxy,
+	}
+	// An accuracy radius of 1000 km or more means the coordinates are the centre of the
+	// country, which would make travel between neighbouring cities look impossible
+	if record.Location.AccuracyRadius > 0 && record.Location.AccuracyRadius < 1000 {
+		location.Latitude = record.Location.Latitude
+		location.Longitude = record.Location.Longitude
+		location.HasCoordinates = true
+	}
+	return location
+}
+
+func (l *Locator) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.reader.Close()
+}
@@ -0,0 +1,13 @@
+module shared/loginrisk
+
+go 1.21
+
+require (
+	github.com/google/uuid v1.5.0
+	github.com/oschwald/geoip2-golang v1.9.0
+)
+
+require (
+	github.com/oschwald/maxminddb-golang v1.11.0 // indirect
+	golang.org/x/sys v0.9.0 // indirect
+)
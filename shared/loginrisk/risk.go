@@ -0,0 +1,365 @@
+// Package loginrisk scores login attempts and decides lockouts for every service with a
+// login form, so the backend and the user management service judge logins alike.
+// Services keep attempts, devices and lockout state in their own stores and expose them
+// through Store.
+package loginrisk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons recorded on failed and blocked attempts
+const (
+	FailInvalidPassword      = "invalid_password"
+	FailUnknownAccount       = "unknown_account"
+	FailAccountLocked        = "account_locked"
+	FailSecondFactorRequired = "second_factor_required" // Password accepted, MFA challenge issued
+	FailConfirmationRequired = "confirmation_required"  // Password accepted, emailed code required
+	FailConfirmationFailed   = "confirmation_failed"
+)
+
+// CredentialFailures are the failures that say the password was guessed wrong
+var CredentialFailures = []string{FailInvalidPassword, FailUnknownAccount}
+
+// Risk factors and the score each adds. A score of Options.ChallengeThreshold or more
+// needs a second factor or an emailed code.
+const (
+	FactorNewDevice          = "new_device"
+	FactorNewCountry         = "new_country"
+	FactorImpossibleTravel   = "impossible_travel"
+	FactorAnonymousProxy     = "anonymous_proxy"
+	FactorCredentialStuffing = "credential_stuffing" // The IP is failing against many accounts
+	FactorDistributedAttack  = "distributed_attack"  // The account is failing from many IPs
+	FactorRecentFailures     = "recent_failures"
+)
+
+var factorWeights = map[string]int{
+	FactorNewDevice:          30,
+	FactorNewCountry:         25,
+	FactorImpossibleTravel:   60,
+	FactorAnonymousProxy:     25,
+	FactorCredentialStuffing: 50,
+	FactorDistributedAttack:  20,
+	FactorRecentFailures:     10,
+}
+
+// Travel shorter than this is ignored; GeoIP places nearby cities hundreds of kilometres
+// apart often enough
+const minTravelDistanceKm = 500
+
+// Options configures lockout and risk scoring
+type Options struct {
+	MaxAttempts        int           // Wrong passwords before the account is locked
+	LockoutBase        time.Duration // First lockout; each consecutive lockout doubles it
+	LockoutMax         time.Duration
+	ChallengeThreshold int     // Risk score that requires MFA or an emailed code
+	MaxTravelSpeed     float64 // km/h between logins above which travel is impossible
+	StuffingWindow     time.Duration
+	StuffingAccounts   int // Accounts failing from one IP, or IPs failing on one account, within the window
+}
+
+// Account is the part of a user that scoring reads
+type Account struct {
+	ID           uuid.UUID
+	FailedLogins int // Wrong passwords since the last successful login or lockout
+}
+
+// LocatedLogin is a successful login the GeoIP database placed
+type LocatedLogin struct {
+	Latitude    float64
+	Longitude   float64
+	AttemptedAt time.Time
+}
+
+// Store reads a service's login history. Errors leave the factor unscored.
+type Store interface {
+	// FailedAccounts counts the distinct emails with credential failures from ipAddress
+	// since the time given
+	FailedAccounts(ctx context.Context, ipAddress string, since time.Time) (int, error)
+	// FailedAddresses counts the distinct IPs with credential failures on the account
+	FailedAddresses(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error)
+	// KnownDevices returns the fingerprints of the account's known devices
+	KnownDevices(ctx context.Context, accountID uuid.UUID) ([]string, error)
+	// LoginCountries returns the countries of the account's successful logins
+	LoginCountries(ctx context.Context, accountID uuid.UUID) ([]string, error)
+	// LastLocatedLogin returns the account's latest successful login with coordinates,
+	// or nil
+	LastLocatedLogin(ctx context.Context, accountID uuid.UUID) (*LocatedLogin, error)
+}
+
+// Assessment is the risk of one login attempt
+type Assessment struct {
+	Score       int       `json:"score"`
+	Factors     []string  `json:"factors"`
+	Location    *Location `json:"-"`
+	Fingerprint string    `json:"-"`
+	Label       string    `json:"-"` // Such as "Chrome on Windows"
+}
+
+func (a *Assessment) add(factor string) {
+	a.Factors = append(a.Factors, factor)
+	a.Score += factorWeights[factor]
+	if a.Score > 100 {
+		a.Score = 100
+	}
+}
+
+// Has reports whether the assessment found factor
+func (a *Assessment) Has(factor string) bool {
+	for _, f := range a.Factors {
+		if f == factor {
+			return true
+		}
+	}
+	return false
+}
+
+// Engine scores logins against a service's Store
+type Engine struct {
+	store   Store
+	locator *Locator
+	opts    Options
+}
+
+// NewEngine scores logins with opts; a nil locator turns off the location factors
+func NewEngine(store Store, locator *Locator, opts Options) *Engine {
+	return &Engine{store: store, locator: locator, opts: opts}
+}
+
+func (e *Engine) Close() error {
+	return e.locator.Close()
+}
+
+// Options returns the options the engine was created with
+func (e *Engine) Options() Options {
+	return e.opts
+}
+
+// Assess scores a login attempt by account, which is nil for unknown emails, from
+// ipAddress
+func (e *Engine) Assess(ctx context.Context, account *Account, ipAddress, userAgent string) *Assessment {
+	now := time.Now()
+	a := &Assessment{
+		Factors:     []string{},
+		Location:    e.locator.Lookup(ipAddress),
+		Fingerprint: DeviceFingerprint(userAgent),
+		Label:       DescribeDevice(userAgent),
+	}
+	if a.Location != nil && a.Location.AnonymousProxy {
+		a.add(FactorAnonymousProxy)
+	}
+
+	since := now.Add(-e.opts.StuffingWindow)
+	if accounts, err := e.store.FailedAccounts(ctx, ipAddress, since); err == nil && accounts >= e.opts.StuffingAccounts {
+		a.add(FactorCredentialStuffing)
+	}
+
+	if account == nil {
+		return a
+	}
+	if addresses, err := e.store.FailedAddresses(ctx, account.ID, since); err == nil && addresses >= e.opts.StuffingAccounts {
+		a.add(FactorDistributedAttack)
+	}
+	if account.FailedLogins >= 3 {
+		a.add(FactorRecentFailures)
+	}
+
+	// Users signing in for the first time since devices were tracked have nothing to
+	// compare against
+	if devices, err := e.store.KnownDevices(ctx, account.ID); err == nil && len(devices) > 0 && !contains(devices, a.Fingerprint) {
+		a.add(FactorNewDevice)
+	}
+
+	if a.Location == nil {
+		return a
+	}
+	if countries, err := e.store.LoginCountries(ctx, account.ID); err == nil && len(countries) > 0 && !contains(countries, a.Location.Country) {
+		a.add(FactorNewCountry)
+	}
+
+	last, err := e.store.LastLocatedLogin(ctx, account.ID)
+	if err == nil && last != nil && a.Location.HasCoordinates {
+		distance := DistanceKm(last.Latitude, last.Longitude, a.Location.Latitude, a.Location.Longitude)
+		hours := now.Sub(last.AttemptedAt).Hours()
+		if distance > minTravelDistanceKm && (hours <= 0 || distance/hours > e.opts.MaxTravelSpeed) {
+			a.add(FactorImpossibleTravel)
+		}
+	}
+	return a
+}
+
+// RequiresConfirmation reports whether a login scored high enough to need a second
+// factor or an emailed code
+func (e *Engine) RequiresConfirmation(a *Assessment) bool {
+	return a.Score >= e.opts.ChallengeThreshold
+}
+
+// Locks reports whether failedLogins wrong passwords lock the account
+func (e *Engine) Locks(failedLogins int) bool {
+	return failedLogins >= e.opts.MaxAttempts
+}
+
+// LockoutDuration doubles with each of the account's earlier consecutive lockouts up to
+// LockoutMax
+func (e *Engine) LockoutDuration(lockouts int) time.Duration {
+	duration := e.opts.LockoutBase
+	for i := 0; i < lockouts && duration < e.opts.LockoutMax; i++ {
+		duration *= 2
+	}
+	if duration > e.opts.LockoutMax {
+		duration = e.opts.LockoutMax
+	}
+	return duration
+}
+
+// LockedUntil returns when a lockout ending at until is over, or nil when it already is
+func LockedUntil(until *time.Time, now time.Time) *time.Time {
+	if until != nil && now.Before(*until) {
+		return until
+	}
+	return nil
+}
+
+// Lockout is an account's password lockout state as a service stores it
+type Lockout struct {
+	FailedLogins int // Wrong passwords since the last successful login or lockout
+	LockedUntil  *time.Time
+	Lockouts     int // Consecutive lockouts, each doubling the next
+}
+
+// RegisterFailure returns the lockout state after one more wrong password, and when the
+// account became locked if this failure locked it. Failures while the account is locked
+// are not counted.
+func (e *Engine) RegisterFailure(state Lockout, now time.Time) (Lockout, *time.Time) {
+	if LockedUntil(state.LockedUntil, now) != nil {
+		return state, nil
+	}
+	state.FailedLogins++
+	if !e.Locks(state.FailedLogins) {
+		return state, nil
+	}
+	until := now.Add(e.LockoutDuration(state.Lockouts))
+	return Lockout{LockedUntil: &until, Lockouts: state.Lockouts + 1}, &until
+}
+
+// LockoutAlert is the data of the alert telling a user their account was locked, since
+// someone may be guessing their password
+func LockoutAlert(ipAddress string, until time.Time) map[string]interface{} {
+	return map[string]interface{}{"ip_address": ipAddress, "locked_until": until}
+}
+
+// Device is the device a login came from, as services remember it among an account's
+// known devices
+type Device struct {
+	Fingerprint string
+	Label       string
+	IPAddress   string
+	Country     string
+	City        string
+}
+
+// Device returns the device of the assessed login from ipAddress
+func (a *Assessment) Device(ipAddress string) Device {
+	device := Device{Fingerprint: a.Fingerprint, Label: a.Label, IPAddress: ipAddress}
+	if a.Location != nil {
+		device.Country = a.Location.Country
+		device.City = a.Location.City
+	}
+	return device
+}
+
+// NewDeviceAlert is the data of the alert telling a user of a login at time at from a
+// device the assessment found new
+func NewDeviceAlert(a *Assessment, ipAddress string, at time.Time) map[string]interface{} {
+	device := a.Device(ipAddress)
+	return map[string]interface{}{
+		"device":     device.Label,
+		"ip_address": ipAddress,
+		"country":    device.Country,
+		"city":       device.City,
+		"location":   strings.Trim(device.City+", "+device.Country, ", "),
+		"time":       at,
+	}
+}
+
+// DistanceKm is the great-circle distance between two coordinates
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+var versionPattern = regexp.MustCompile(`[0-9]+([._][0-9]+)*`)
+
+// DeviceFingerprint identifies a browser or app by its user agent with version numbers
+// removed, so updates do not make a known device look new
+func DeviceFingerprint(userAgent string) string {
+	normalized := versionPattern.ReplaceAllString(strings.ToLower(strings.TrimSpace(userAgent)), "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// DescribeDevice turns a user agent into a short label such as "Chrome on Windows"
+func DescribeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	for _, candidate := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+		{"okhttp", "Android app"},
+		{"cfnetwork", "iOS app"},
+	} {
+		if strings.Contains(ua, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os x", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+module shared/retention
+
+go 1.21
+
+require (
+	github.com/google/uuid v1.5.0
+	gorm.io/gorm v1.25.7
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+)
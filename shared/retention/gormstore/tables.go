@@ -0,0 +1,187 @@
+// Package gormstore applies retention rules to SQL tables through gorm. Services keep
+// their rules, holds and runs in their own models and embed Tables in their Store.
+package gormstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shared/retention"
+)
+
+// Table describes how retention reaches one table. Tables are read through db.Table, so
+// rows are not soft-delete scoped and rules see rows whose deleted_at is set.
+type Table struct {
+	retention.Entity
+	// SubjectColumn is matched against subject legal holds; empty when rows have no
+	// subject
+	SubjectColumn string
+	// Anonymize lists the columns scrubbed by the anonymize action; tables without it can
+	// only be purged. Anonymized rows are marked with anonymized_at.
+	Anonymize map[string]interface{}
+	// Children are deleted with the rows that own them, as table -> foreign key column
+	Children map[string]string
+}
+
+// Tables implements the Entity and Apply parts of retention.Store
+type Tables struct {
+	DB        *gorm.DB
+	BatchSize int
+	Tables    map[string]Table
+}
+
+func (t *Tables) Entity(name string) (retention.Entity, bool) {
+	table, ok := t.Tables[name]
+	if !ok {
+		return retention.Entity{}, false
+	}
+	entity := table.Entity
+	entity.Anonymizable = table.Anonymize != nil
+	return entity, true
+}
+
+// heldCondition returns the SQL condition matching rows of name under an active hold,
+// or an empty string when nothing in the table is held
+func (t *Tables) heldCondition(name string, holds []retention.Hold) (string, []interface{}) {
+	table := t.Tables[name]
+	var records, subjects []string
+	for _, hold := range holds {
+		if hold.Entity != "" && hold.Entity != name {
+			continue
+		}
+		if hold.RecordID != nil {
+			records = append(records, hold.RecordID.String())
+		}
+		if hold.SubjectID != nil && table.SubjectColumn != "" {
+			subjects = append(subjects, hold.SubjectID.String())
+		}
+	}
+
+	// Ids are compared as text because some tables keep their subject as a string
+	var conditions []string
+	var args []interface{}
+	if len(records) > 0 {
+		conditions = append(conditions, "CAST(id AS TEXT) IN ?")
+		args = append(args, records)
+	}
+	if len(subjects) > 0 {
+		// Rows without a subject must not make the condition NULL
+		conditions = append(conditions, fmt.Sprintf("(%[1]s IS NOT NULL AND CAST(%[1]s AS TEXT) IN ?)", table.SubjectColumn))
+		args = append(args, subjects)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+func (t *Tables) Apply(ctx context.Context, rule retention.Rule, holds []retention.Hold, result *retention.RuleResult, now time.Time, dryRun bool) error {
+	table := t.Tables[rule.Entity]
+
+	// Table and column names come from Tables and validated rules
+	expired := func(db *gorm.DB) *gorm.DB {
+		db = db.Table(rule.Entity).Where(rule.Basis+" < ?", result.Cutoff)
+		if len(rule.Statuses) > 0 {
+			db = db.Where("status IN ?", rule.Statuses)
+		}
+		if rule.Action == retention.ActionAnonymize {
+			db = db.Where("anonymized_at IS NULL")
+		}
+		return db
+	}
+	held, heldArgs := t.heldCondition(rule.Entity, holds)
+	eligible := func(db *gorm.DB) *gorm.DB {
+		db = expired(db)
+		if held != "" {
+			db = db.Where("NOT "+held, heldArgs...)
+		}
+		return db
+	}
+
+	db := t.DB.WithContext(ctx)
+	if held != "" {
+		if err := expired(db).Where(held, heldArgs...).Count(&result.Held).Error; err != nil {
+			return err
+		}
+	}
+	if err := eligible(db).Count(&result.Eligible).Error; err != nil {
+		return err
+	}
+	if dryRun || result.Eligible == 0 {
+		return nil
+	}
+
+	for ctx.Err() == nil {
+		var ids []uuid.UUID
+		if err := eligible(db).Limit(t.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Re-checking eligibility skips rows put on hold since the batch was read
+			var confirmed []uuid.UUID
+			if err := eligible(tx).Where("id IN ?", ids).Pluck("id", &confirmed).Error; err != nil {
+				return err
+			}
+			if len(confirmed) == 0 {
+				return nil
+			}
+
+			var processed int64
+			if rule.Action == retention.ActionAnonymize {
+				updates := map[string]interface{}{"anonymized_at": now, "updated_at": now}
+				for column, value := range table.Anonymize {
+					updates[column] = value
+				}
+				res := tx.Table(rule.Entity).Where("id IN ?", confirmed).Updates(updates)
+				if res.Error != nil {
+					return res.Error
+				}
+				processed = res.RowsAffected
+			} else {
+				children := make([]string, 0, len(table.Children))
+				for child := range table.Children {
+					children = append(children, child)
+				}
+				sort.Strings(children)
+				for _, child := range children {
+					res := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s IN ?", child, table.Children[child]), confirmed)
+					if res.Error != nil {
+						return res.Error
+					}
+					if result.Children == nil {
+						result.Children = map[string]int64{}
+					}
+					result.Children[child] += res.RowsAffected
+				}
+				res := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN ?", rule.Entity), confirmed)
+				if res.Error != nil {
+					return res.Error
+				}
+				processed = res.RowsAffected
+			}
+
+			result.Processed += processed
+			for _, id := range confirmed {
+				result.RecordIDs = append(result.RecordIDs, id.String())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(ids) < t.BatchSize {
+			break
+		}
+	}
+	return ctx.Err()
+}
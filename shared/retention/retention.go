@@ -0,0 +1,394 @@
+// Package retention is the data retention engine shared by the platform's services.
+//
+// A service describes the tables or collections rules may reach and implements Store
+// over its database. The engine validates rules and legal holds, applies the enabled
+// rules in a run and stores a report of every run signed with the service's X.509
+// certificate, so auditors can verify reports with the certificate alone.
+package retention
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Retention actions
+const (
+	ActionPurge     = "purge"     // Delete the record
+	ActionAnonymize = "anonymize" // Scrub personal data, keep the record
+)
+
+// Purge run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+	RunAbandoned = "abandoned" // The process died mid-run
+)
+
+// RunTimeout is how long a run may stay running before it is taken to belong to a
+// process that died
+const RunTimeout = 6 * time.Hour
+
+var (
+	ErrRuleNotFound   = errors.New("retention rule not found")
+	ErrInvalidRule    = errors.New("invalid retention rule")
+	ErrHoldNotFound   = errors.New("legal hold not found")
+	ErrInvalidHold    = errors.New("invalid legal hold")
+	ErrRunNotFound    = errors.New("purge run not found")
+	ErrRunInProgress  = errors.New("a purge run is already in progress")
+	ErrReportUnsigned = errors.New("purge reports cannot be signed")
+)
+
+// Entity is what rules and holds need to know about one table or collection
+type Entity struct {
+	// Bases are the timestamps a rule may expire on besides created_at and updated_at
+	Bases []string
+	// Anonymizable entities have personal data the anonymize action can scrub
+	Anonymizable bool
+	// AnonymizeOnly entities are referenced elsewhere and are never deleted
+	AnonymizeOnly bool
+	// Regulated records must be kept for the minimum retention period, unless a purge
+	// is limited to TransientStatuses, in which nothing reportable happened
+	Regulated         bool
+	TransientStatuses []string
+}
+
+// Rule is a retention rule as the engine sees it
+type Rule struct {
+	Name          string
+	Entity        string
+	Basis         string
+	Statuses      []string
+	RetentionDays int
+	Action        string
+	Enabled       bool
+}
+
+// Hold is an active legal hold on one record, or on every record of a subject (the
+// customer or user the records belong to). An empty Entity holds every entity.
+type Hold struct {
+	Entity    string
+	RecordID  *uuid.UUID
+	SubjectID *uuid.UUID
+}
+
+// Run is one retention run and its signed report
+type Run struct {
+	ID                     uuid.UUID
+	Status                 string
+	DryRun                 bool
+	TriggeredBy            string
+	StartedAt              time.Time
+	CompletedAt            *time.Time
+	Purged                 int64
+	Anonymized             int64
+	Held                   int64
+	Error                  string
+	Report                 string // Canonical JSON that was signed
+	ReportSHA256           string
+	Signature              string
+	SignatureAlgorithm     string
+	CertificateFingerprint string
+}
+
+// Report is the signed record of a run
+type Report struct {
+	RunID            uuid.UUID    `json:"run_id"`
+	Service          string       `json:"service"`
+	DryRun           bool         `json:"dry_run"`
+	TriggeredBy      string       `json:"triggered_by"`
+	StartedAt        time.Time    `json:"started_at"`
+	CompletedAt      time.Time    `json:"completed_at"`
+	ActiveLegalHolds int          `json:"active_legal_holds"`
+	Rules            []RuleResult `json:"rules"`
+	Purged           int64        `json:"purged"`
+	Anonymized       int64        `json:"anonymized"`
+	Held             int64        `json:"held"`
+	Error            string       `json:"error,omitempty"`
+}
+
+// RuleResult is what one rule did during a run
+type RuleResult struct {
+	Rule          string           `json:"rule"`
+	Entity        string           `json:"entity"`
+	Action        string           `json:"action"`
+	Basis         string           `json:"basis"`
+	RetentionDays int              `json:"retention_days"`
+	Cutoff        time.Time        `json:"cutoff"`
+	Eligible      int64            `json:"eligible"`  // Expired and not held when the rule started
+	Processed     int64            `json:"processed"` // Purged or anonymized; zero on dry runs
+	Held          int64            `json:"held"`      // Expired but blocked by a legal hold
+	Children      map[string]int64 `json:"children,omitempty"`
+	RecordIDs     []string         `json:"record_ids,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// Store is a service's database as seen by the engine
+type Store interface {
+	// Entity describes a table or collection rules may reach
+	Entity(name string) (Entity, bool)
+	// Rules returns every rule, enabled or not
+	Rules(ctx context.Context) ([]Rule, error)
+	// ActiveHolds returns the holds that have not been released
+	ActiveHolds(ctx context.Context) ([]Hold, error)
+	// StartRun marks runs still running since before abandonBefore as abandoned, then
+	// records run. It returns ErrRunInProgress while another run is running.
+	StartRun(ctx context.Context, run *Run, abandonBefore time.Time) error
+	// FinishRun stores the outcome and signed report of run
+	FinishRun(ctx context.Context, run *Run) error
+	// Apply counts the records rule expires and, unless dryRun, purges or anonymizes
+	// those no hold covers, filling in result. now is the run's start.
+	Apply(ctx context.Context, rule Rule, holds []Hold, result *RuleResult, now time.Time, dryRun bool) error
+}
+
+// Config names the service in reports and locates its signing certificate
+type Config struct {
+	Service         string
+	CertificatePath string
+	SigningKeyPath  string // Empty when the key follows the certificate in its file
+	// MinimumDays is how long regulated records must be kept
+	MinimumDays int
+}
+
+// Engine applies retention rules through a Store
+type Engine struct {
+	store Store
+	cfg   Config
+}
+
+func NewEngine(store Store, cfg Config) *Engine {
+	return &Engine{store: store, cfg: cfg}
+}
+
+// ValidateRule checks a rule against the entity it applies to
+func (e *Engine) ValidateRule(rule Rule) error {
+	entity, ok := e.store.Entity(rule.Entity)
+	if !ok {
+		return fmt.Errorf("%w: unknown entity %q", ErrInvalidRule, rule.Entity)
+	}
+	if rule.Basis != "created_at" && rule.Basis != "updated_at" && !contains(entity.Bases, rule.Basis) {
+		bases := append([]string{"created_at", "updated_at"}, entity.Bases...)
+		return fmt.Errorf("%w: %s cannot expire on %q; basis must be one of %s", ErrInvalidRule, rule.Entity, rule.Basis, strings.Join(bases, ", "))
+	}
+	if rule.RetentionDays < 1 {
+		return fmt.Errorf("%w: retention_days must be at least 1", ErrInvalidRule)
+	}
+	switch rule.Action {
+	case ActionPurge:
+		if entity.AnonymizeOnly {
+			return fmt.Errorf("%w: %s can only be anonymized", ErrInvalidRule, rule.Entity)
+		}
+		if entity.Regulated && !subsetOf(rule.Statuses, entity.TransientStatuses) && rule.RetentionDays < e.cfg.MinimumDays {
+			return fmt.Errorf("%w: %s must be kept for at least %d days", ErrInvalidRule, rule.Entity, e.cfg.MinimumDays)
+		}
+	case ActionAnonymize:
+		if !entity.Anonymizable {
+			return fmt.Errorf("%w: %s can only be purged", ErrInvalidRule, rule.Entity)
+		}
+	default:
+		return fmt.Errorf("%w: action must be purge or anonymize", ErrInvalidRule)
+	}
+	return nil
+}
+
+// ValidateHold checks a hold before it is placed. subjectField names the subject in
+// errors, as the service's API calls it.
+func (e *Engine) ValidateHold(entity string, recordID, subjectID *uuid.UUID, reason, subjectField string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidHold)
+	}
+	if recordID == nil && subjectID == nil {
+		return fmt.Errorf("%w: record_id or %s is required", ErrInvalidHold, subjectField)
+	}
+	if entity != "" {
+		if _, ok := e.store.Entity(entity); !ok {
+			return fmt.Errorf("%w: unknown entity %q", ErrInvalidHold, entity)
+		}
+	} else if recordID != nil {
+		return fmt.Errorf("%w: entity is required with record_id", ErrInvalidHold)
+	}
+	return nil
+}
+
+// RunPurge applies every enabled rule and stores a signed report of the run. Dry runs
+// only count what would be purged. Nothing is deleted unless the run can be evidenced,
+// so runs are refused while the certificate cannot be loaded.
+func (e *Engine) RunPurge(ctx context.Context, dryRun bool, triggeredBy string) (*Run, error) {
+	signer, err := LoadSigner(e.cfg.CertificatePath, e.cfg.SigningKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReportUnsigned, err)
+	}
+
+	now := time.Now()
+	run := &Run{ID: uuid.New(), Status: RunRunning, DryRun: dryRun, TriggeredBy: triggeredBy, StartedAt: now}
+	if err := e.store.StartRun(ctx, run, now.Add(-RunTimeout)); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		RunID:       run.ID,
+		Service:     e.cfg.Service,
+		DryRun:      dryRun,
+		TriggeredBy: triggeredBy,
+		StartedAt:   now,
+		Rules:       []RuleResult{},
+	}
+	runErr := e.applyRules(ctx, report)
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	report.CompletedAt = time.Now()
+
+	run.Status = RunCompleted
+	if runErr != nil {
+		run.Status = RunFailed
+		run.Error = runErr.Error()
+	}
+	run.Purged, run.Anonymized, run.Held = report.Purged, report.Anonymized, report.Held
+	run.CompletedAt = &report.CompletedAt
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode purge report: %w", err)
+	}
+	signature, err := signer.Sign(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign purge report: %w", err)
+	}
+	digest := sha256.Sum256(body)
+	run.Report = string(body)
+	run.ReportSHA256 = hex.EncodeToString(digest[:])
+	run.Signature = signature
+	run.SignatureAlgorithm = signer.Algorithm()
+	run.CertificateFingerprint = signer.Fingerprint()
+
+	// The report is written even when ctx was cancelled mid-run
+	if err := e.store.FinishRun(context.Background(), run); err != nil {
+		return nil, fmt.Errorf("failed to store purge report: %w", err)
+	}
+	return run, runErr
+}
+
+func (e *Engine) applyRules(ctx context.Context, report *Report) error {
+	rules, err := e.store.Rules(ctx)
+	if err != nil {
+		return err
+	}
+	holds, err := e.store.ActiveHolds(ctx)
+	if err != nil {
+		return err
+	}
+	report.ActiveLegalHolds = len(holds)
+
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		result := RuleResult{
+			Rule:          rule.Name,
+			Entity:        rule.Entity,
+			Action:        rule.Action,
+			Basis:         rule.Basis,
+			RetentionDays: rule.RetentionDays,
+			Cutoff:        report.StartedAt.AddDate(0, 0, -rule.RetentionDays),
+		}
+		// Rules are validated when saved; one for an entity the service no longer has
+		// is reported rather than applied
+		var err error
+		if _, ok := e.store.Entity(rule.Entity); !ok {
+			result.Error = "unknown entity"
+		} else if err = e.store.Apply(ctx, rule, holds, &result, report.StartedAt, report.DryRun); err != nil {
+			result.Error = err.Error()
+		}
+
+		report.Rules = append(report.Rules, result)
+		report.Held += result.Held
+		if rule.Action == ActionAnonymize {
+			report.Anonymized += result.Processed
+		} else {
+			report.Purged += result.Processed
+		}
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// Verify checks a stored report against its digest and the certificate's signature
+func (e *Engine) Verify(run *Run) (bool, string, error) {
+	if run.Report == "" {
+		return false, "report has not been written", nil
+	}
+
+	digest := sha256.Sum256([]byte(run.Report))
+	if hex.EncodeToString(digest[:]) != run.ReportSHA256 {
+		return false, "report digest mismatch", nil
+	}
+	signer, err := LoadSigner(e.cfg.CertificatePath, e.cfg.SigningKeyPath)
+	if err != nil {
+		return false, "", fmt.Errorf("%w: %v", ErrReportUnsigned, err)
+	}
+	if signer.Fingerprint() != run.CertificateFingerprint {
+		return false, "report was signed with a different certificate", nil
+	}
+	if !signer.Verify([]byte(run.Report), run.Signature) {
+		return false, "signature mismatch", nil
+	}
+	return true, "", nil
+}
+
+// Schedule runs the retention rules every interval until ctx is cancelled
+func (e *Engine) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		run, err := e.RunPurge(ctx, false, "scheduler")
+		if errors.Is(err, ErrRunInProgress) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Retention run failed: %v", err)
+			continue
+		}
+		log.Printf("Retention run %s purged %d and anonymized %d records; %d held", run.ID, run.Purged, run.Anonymized, run.Held)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// subsetOf reports whether values is non-empty and made only of allowed values
+func subsetOf(values, allowed []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, value := range values {
+		if !contains(allowed, value) {
+			return false
+		}
+	}
+	return true
+}
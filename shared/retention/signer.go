@@ -0,0 +1,176 @@
+package retention
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrSigningDisabled means no signing certificate is configured
+var ErrSigningDisabled = errors.New("no signing certificate is configured")
+
+// Signer signs payloads with the private key of an X.509 certificate. Signatures are
+// base64 and named with their JWA algorithm.
+type Signer struct {
+	key         crypto.Signer
+	algorithm   string
+	hash        crypto.Hash // Digest signed for RSA and ECDSA keys
+	fingerprint string      // Hex SHA-256 of the DER certificate
+}
+
+// LoadSigner reads the certificate and its private key. The key may be in its own file
+// or follow the certificate in the same PEM file.
+func LoadSigner(certPath, keyPath string) (*Signer, error) {
+	if certPath == "" {
+		return nil, ErrSigningDisabled
+	}
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+	}
+	keyPEM := certPEM
+	if keyPath != "" {
+		if keyPEM, err = os.ReadFile(keyPath); err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+	}
+
+	var cert *x509.Certificate
+	for rest := certPEM; cert == nil; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return nil, fmt.Errorf("no certificate found in %s", certPath)
+		}
+		if block.Type == "CERTIFICATE" {
+			if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, fmt.Errorf("failed to parse signing certificate: %w", err)
+			}
+		}
+	}
+
+	var key crypto.Signer
+	for rest := keyPEM; key == nil; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return nil, fmt.Errorf("no private key found for the signing certificate")
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse signing key: %w", err)
+			}
+			signer, ok := parsed.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported signing key type %T", parsed)
+			}
+			key = signer
+		case "RSA PRIVATE KEY":
+			if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				return nil, fmt.Errorf("failed to parse signing key: %w", err)
+			}
+		case "EC PRIVATE KEY":
+			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, fmt.Errorf("failed to parse signing key: %w", err)
+			}
+		}
+	}
+
+	certKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate public key: %w", err)
+	}
+	signerKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil || !bytes.Equal(certKey, signerKey) {
+		return nil, fmt.Errorf("signing key does not match the certificate")
+	}
+
+	signer := &Signer{key: key, hash: crypto.SHA256}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signer.algorithm = "RS256"
+	case *ecdsa.PrivateKey:
+		// The JWA name follows the curve, and each curve is paired with its own digest
+		switch k.Curve.Params().BitSize {
+		case 256:
+			signer.algorithm = "ES256"
+		case 384:
+			signer.algorithm, signer.hash = "ES384", crypto.SHA384
+		case 521:
+			signer.algorithm, signer.hash = "ES512", crypto.SHA512
+		default:
+			return nil, fmt.Errorf("unsupported signing key curve %s", k.Curve.Params().Name)
+		}
+	case ed25519.PrivateKey:
+		signer.algorithm = "EdDSA"
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	sum := sha256.Sum256(cert.Raw)
+	signer.fingerprint = hex.EncodeToString(sum[:])
+	return signer, nil
+}
+
+// Algorithm is the JWA name of the signatures, e.g. ES256
+func (s *Signer) Algorithm() string { return s.algorithm }
+
+// Fingerprint is the hex SHA-256 of the certificate
+func (s *Signer) Fingerprint() string { return s.fingerprint }
+
+func (s *Signer) Sign(payload []byte) (string, error) {
+	var signature []byte
+	var err error
+	if s.algorithm == "EdDSA" {
+		signature, err = s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		signature, err = s.key.Sign(rand.Reader, s.digest(payload), s.hash)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func (s *Signer) digest(payload []byte) []byte {
+	switch s.hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384(payload)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(payload)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(payload)
+		return sum[:]
+	}
+}
+
+// Verify checks a signature produced by Sign with the certificate's public key
+func (s *Signer) Verify(payload []byte, encoded string) bool {
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	switch key := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, s.hash, s.digest(payload), signature) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, s.digest(payload), signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	default:
+		return false
+	}
+}
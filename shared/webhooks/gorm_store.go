@@ -0,0 +1,170 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SenderSecret is a webhook signing secret stored in the database. Rotation adds a new
+// secret and gives the previous ones an expiry, so both verify during the overlap.
+type SenderSecret struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Sender    string     `gorm:"type:varchar(100);not null;index" json:"sender"`
+	Secret    string     `gorm:"type:varchar(255);not null" json:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (SenderSecret) TableName() string { return "webhook_secrets" }
+
+// ReceivedEvent records an accepted delivery for replay protection
+type ReceivedEvent struct {
+	Sender     string    `gorm:"type:varchar(100);primaryKey" json:"sender"`
+	EventID    string    `gorm:"type:varchar(255);primaryKey" json:"event_id"`
+	ReceivedAt time.Time `gorm:"not null" json:"received_at"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+func (ReceivedEvent) TableName() string { return "webhook_events" }
+
+// GormSecretStore keeps sender secrets in the database
+type GormSecretStore struct {
+	db *gorm.DB
+}
+
+func NewGormSecretStore(db *gorm.DB) *GormSecretStore {
+	return &GormSecretStore{db: db}
+}
+
+func (s *GormSecretStore) Secrets(sender string) ([]Secret, error) {
+	rows := []SenderSecret{}
+	if err := s.db.Where("sender = ? AND (expires_at IS NULL OR expires_at > ?)", sender, time.Now()).
+		Order("created_at DESC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load webhook secrets: %w", err)
+	}
+
+	secrets := make([]Secret, 0, len(rows))
+	for _, row := range rows {
+		secrets = append(secrets, Secret{Value: row.Secret, ExpiresAt: row.ExpiresAt})
+	}
+	return secrets, nil
+}
+
+// Seed stores secret for sender unless the sender already has one, so configured
+// secrets bootstrap the store without undoing later rotations
+func (s *GormSecretStore) Seed(sender, secret string) error {
+	if secret == "" {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&SenderSecret{}).Where("sender = ?", sender).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check webhook secrets: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := s.db.Create(&SenderSecret{Sender: sender, Secret: secret}).Error; err != nil {
+		return fmt.Errorf("failed to seed webhook secret: %w", err)
+	}
+	return nil
+}
+
+// Rotate generates a new secret for sender. Existing secrets stay valid for overlap and
+// then expire. The new secret is returned once and is not readable through the API again.
+func (s *GormSecretStore) Rotate(sender string, overlap time.Duration) (string, *time.Time, error) {
+	if sender == "" {
+		return "", nil, errors.New("sender is required")
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	secret := "whsec_" + hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(overlap)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Only shorten expiries; a secret already expiring sooner keeps its date
+		if err := tx.Model(&SenderSecret{}).
+			Where("sender = ? AND (expires_at IS NULL OR expires_at > ?)", sender, expiresAt).
+			Update("expires_at", expiresAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(&SenderSecret{Sender: sender, Secret: secret}).Error
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	return secret, &expiresAt, nil
+}
+
+// GormReplayStore records claimed event IDs in the database so that every replica
+// shares the same replay protection
+type GormReplayStore struct {
+	db *gorm.DB
+}
+
+func NewGormReplayStore(db *gorm.DB) *GormReplayStore {
+	return &GormReplayStore{db: db}
+}
+
+func (s *GormReplayStore) Claim(sender, eventID string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// An expired record no longer blocks the ID
+	if err := s.db.Where("sender = ? AND event_id = ? AND expires_at < ?", sender, eventID, now).
+		Delete(&ReceivedEvent{}).Error; err != nil {
+		return false, fmt.Errorf("failed to clear expired webhook event: %w", err)
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ReceivedEvent{
+		Sender:     sender,
+		EventID:    eventID,
+		ReceivedAt: now,
+		ExpiresAt:  now.Add(ttl),
+	})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record webhook event: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *GormReplayStore) Release(sender, eventID string) error {
+	if err := s.db.Where("sender = ? AND event_id = ?", sender, eventID).Delete(&ReceivedEvent{}).Error; err != nil {
+		return fmt.Errorf("failed to release webhook event: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes event records past their expiry
+func (s *GormReplayStore) PurgeExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&ReceivedEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge webhook events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartPurgeScheduler purges expired event records hourly until ctx is cancelled
+func (s *GormReplayStore) StartPurgeScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeExpired(); err != nil {
+			log.Printf("Webhook event purge failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
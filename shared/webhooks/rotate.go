@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeedStatic stores each configured sender's first secret unless the sender already has
+// secrets, so WEBHOOK_SECRETS bootstraps the store and later rotations are kept
+func (s *GormSecretStore) SeedStatic(secrets StaticSecrets) {
+	for sender, values := range secrets {
+		if len(values) == 0 {
+			continue
+		}
+		if err := s.Seed(sender, values[0]); err != nil {
+			log.Printf("Failed to seed webhook secret for %s: %v", sender, err)
+		}
+	}
+}
+
+// RotateHandler rotates the signing secret of the sender in the :sender path parameter.
+// The body may give an overlap, e.g. {"overlap": "24h"}, during which existing secrets
+// stay valid.
+func RotateHandler(store *GormSecretStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			Overlap string `json:"overlap"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		overlap := 24 * time.Hour
+		if request.Overlap != "" {
+			parsed, err := time.ParseDuration(request.Overlap)
+			if err != nil || parsed < 0 || parsed > 30*24*time.Hour {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "overlap must be a duration between 0s and 720h"})
+				return
+			}
+			overlap = parsed
+		}
+
+		sender := c.Param("sender")
+		secret, previousExpiresAt, err := store.Rotate(sender, overlap)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sender":              sender,
+			"secret":              secret,
+			"previous_expires_at": previousExpiresAt,
+			"message":             "Store this secret now; it cannot be retrieved again",
+		})
+	}
+}
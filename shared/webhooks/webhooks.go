@@ -0,0 +1,291 @@
+// Package webhooks verifies signed inbound webhooks.
+//
+// Senders sign each delivery with HMAC-SHA256 over "<timestamp>.<event id>.<raw body>"
+// using their shared secret and send it in these headers:
+//
+//	X-Webhook-Sender:    sender identifier, e.g. the bank code
+//	X-Webhook-Timestamp: unix seconds when the delivery was signed
+//	X-Webhook-Id:        unique event ID, used for replay protection
+//	X-Webhook-Signature: v1=<hex digest>[,v1=<hex digest>...]
+//
+// Several signatures may be sent so a sender can sign with both its old and new secret
+// while a rotation overlaps.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderSender    = "X-Webhook-Sender"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderSignature = "X-Webhook-Signature"
+
+	signatureVersion = "v1"
+	maxBodyBytes     = 1 << 20
+)
+
+// Verification failure reasons returned to the sender
+const (
+	ReasonMissingHeaders    = "missing_headers"
+	ReasonInvalidTimestamp  = "invalid_timestamp"
+	ReasonTimestampExpired  = "timestamp_outside_tolerance"
+	ReasonUnknownSender     = "unknown_sender"
+	ReasonSignatureMismatch = "signature_mismatch"
+	ReasonReplayed          = "replayed_event"
+	ReasonBodyTooLarge      = "body_too_large"
+	ReasonUnavailable       = "verification_unavailable"
+)
+
+// VerificationError explains why a delivery was rejected
+type VerificationError struct {
+	Reason string
+	Detail string
+}
+
+func (e *VerificationError) Error() string {
+	if e.Detail == "" {
+		return "webhook verification failed: " + e.Reason
+	}
+	return fmt.Sprintf("webhook verification failed: %s: %s", e.Reason, e.Detail)
+}
+
+// Secret is one signing secret of a sender. A nil ExpiresAt means it does not expire.
+type Secret struct {
+	Value     string
+	ExpiresAt *time.Time
+}
+
+// SecretStore returns the secrets currently accepted for a sender
+type SecretStore interface {
+	Secrets(sender string) ([]Secret, error)
+}
+
+// ReplayStore records event IDs that have been accepted
+type ReplayStore interface {
+	// Claim records the event and reports false if it was already claimed within ttl
+	Claim(sender, eventID string, ttl time.Duration) (bool, error)
+	// Release forgets an event so the sender can redeliver it
+	Release(sender, eventID string) error
+}
+
+// Verifier checks webhook signatures, timestamps and event IDs
+type Verifier struct {
+	secrets   SecretStore
+	replays   ReplayStore
+	tolerance time.Duration
+	dedupeTTL time.Duration
+	now       func() time.Time
+}
+
+// NewVerifier builds a verifier. Deliveries signed more than tolerance away from now are
+// rejected, and event IDs are remembered for dedupeTTL, which is never shorter than the
+// tolerance window.
+func NewVerifier(secrets SecretStore, replays ReplayStore, tolerance, dedupeTTL time.Duration) *Verifier {
+	if dedupeTTL < 2*tolerance {
+		dedupeTTL = 2 * tolerance
+	}
+	return &Verifier{secrets: secrets, replays: replays, tolerance: tolerance, dedupeTTL: dedupeTTL, now: time.Now}
+}
+
+// Sign returns the signature header value for a delivery
+func Sign(secret string, timestamp int64, eventID string, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(digest(secret, timestamp, eventID, body))
+}
+
+func digest(secret string, timestamp int64, eventID string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s.", timestamp, eventID)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// Verify checks the signature headers against the raw body and claims the event ID.
+// It returns the verified sender and event ID.
+func (v *Verifier) Verify(header http.Header, body []byte) (string, string, error) {
+	sender := strings.TrimSpace(header.Get(HeaderSender))
+	eventID := strings.TrimSpace(header.Get(HeaderEventID))
+	rawTimestamp := strings.TrimSpace(header.Get(HeaderTimestamp))
+	rawSignature := strings.TrimSpace(header.Get(HeaderSignature))
+	if sender == "" || eventID == "" || rawTimestamp == "" || rawSignature == "" {
+		return "", "", &VerificationError{Reason: ReasonMissingHeaders,
+			Detail: fmt.Sprintf("%s, %s, %s and %s are required", HeaderSender, HeaderEventID, HeaderTimestamp, HeaderSignature)}
+	}
+
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return "", "", &VerificationError{Reason: ReasonInvalidTimestamp, Detail: "timestamp must be unix seconds"}
+	}
+	skew := v.now().Sub(time.Unix(timestamp, 0))
+	if skew > v.tolerance || skew < -v.tolerance {
+		return "", "", &VerificationError{Reason: ReasonTimestampExpired,
+			Detail: fmt.Sprintf("timestamp must be within %s of server time", v.tolerance)}
+	}
+
+	secrets, err := v.secrets.Secrets(sender)
+	if err != nil {
+		return "", "", &VerificationError{Reason: ReasonUnavailable, Detail: err.Error()}
+	}
+	if len(secrets) == 0 {
+		return "", "", &VerificationError{Reason: ReasonUnknownSender}
+	}
+
+	if !v.signatureMatches(secrets, rawSignature, timestamp, eventID, body) {
+		return "", "", &VerificationError{Reason: ReasonSignatureMismatch}
+	}
+
+	// Claim the event only once the signature is valid, so forged deliveries cannot
+	// burn event IDs
+	fresh, err := v.replays.Claim(sender, eventID, v.dedupeTTL)
+	if err != nil {
+		return "", "", &VerificationError{Reason: ReasonUnavailable, Detail: err.Error()}
+	}
+	if !fresh {
+		return "", "", &VerificationError{Reason: ReasonReplayed, Detail: "event " + eventID + " was already delivered"}
+	}
+	return sender, eventID, nil
+}
+
+func (v *Verifier) signatureMatches(secrets []Secret, header string, timestamp int64, eventID string, body []byte) bool {
+	now := v.now()
+	for _, part := range strings.Split(header, ",") {
+		version, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || version != signatureVersion {
+			continue
+		}
+		provided, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, secret := range secrets {
+			if secret.ExpiresAt != nil && secret.ExpiresAt.Before(now) {
+				continue
+			}
+			if hmac.Equal(provided, digest(secret.Value, timestamp, eventID, body)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Middleware rejects unverified deliveries with 401 and the failure reason. Verified
+// requests get "webhookSender" and "webhookEventID" in the context and keep their body.
+// If the handler fails with a 5xx the event ID is released so the sender can retry.
+func Middleware(v *Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyBytes+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		if len(body) > maxBodyBytes {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":  "Webhook verification failed",
+				"reason": ReasonBodyTooLarge,
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sender, eventID, err := v.Verify(c.Request.Header, body)
+		if err != nil {
+			response := gin.H{"error": "Webhook verification failed", "reason": ReasonUnavailable}
+			if verr, ok := err.(*VerificationError); ok {
+				response["reason"] = verr.Reason
+				if verr.Detail != "" && verr.Reason != ReasonUnavailable {
+					response["detail"] = verr.Detail
+				}
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+			return
+		}
+
+		c.Set("webhookSender", sender)
+		c.Set("webhookEventID", eventID)
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			v.replays.Release(sender, eventID)
+		}
+	}
+}
+
+// StaticSecrets is a SecretStore backed by fixed configuration
+type StaticSecrets map[string][]string
+
+// ParseStaticSecrets parses "sender:secret[|previous],sender:secret". Listing a previous
+// secret after a pipe keeps it valid while the sender switches over.
+func ParseStaticSecrets(value string) StaticSecrets {
+	secrets := StaticSecrets{}
+	for _, entry := range strings.Split(value, ",") {
+		sender, list, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || sender == "" {
+			continue
+		}
+		for _, secret := range strings.Split(list, "|") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				secrets[sender] = append(secrets[sender], secret)
+			}
+		}
+	}
+	return secrets
+}
+
+func (s StaticSecrets) Secrets(sender string) ([]Secret, error) {
+	values := s[sender]
+	secrets := make([]Secret, 0, len(values))
+	for _, value := range values {
+		secrets = append(secrets, Secret{Value: value})
+	}
+	return secrets, nil
+}
+
+// MemoryReplayStore keeps claimed event IDs in process memory. It is only suitable for a
+// single replica; use GormReplayStore when running several.
+type MemoryReplayStore struct {
+	mu     sync.Mutex
+	events map[string]time.Time
+}
+
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{events: make(map[string]time.Time)}
+}
+
+func (s *MemoryReplayStore) Claim(sender, eventID string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, expires := range s.events {
+		if expires.Before(now) {
+			delete(s.events, key)
+		}
+	}
+	key := sender + "\x00" + eventID
+	if _, ok := s.events[key]; ok {
+		return false, nil
+	}
+	s.events[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *MemoryReplayStore) Release(sender, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.events, sender+"\x00"+eventID)
+	return nil
+}
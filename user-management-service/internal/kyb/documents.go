@@ -0,0 +1,122 @@
+package kyb
+
+import (
+	"time"
+
+	"user-management-service/internal/models"
+)
+
+// Document states in an assessment
+const (
+	DocumentOK         = "ok"
+	DocumentMissing    = "missing"
+	DocumentUnverified = "unverified" // Uploaded, not yet checked by compliance
+	DocumentOutdated   = "outdated"
+)
+
+// DocumentRequirement describes a company document by CompanyDocument.Type
+type DocumentRequirement struct {
+	Type        string `json:"type"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Condition   string `json:"condition,omitempty"`    // When the document is only required sometimes
+	MaxAgeDays  int    `json:"max_age_days,omitempty"` // Measured from the upload; 0 means no limit
+}
+
+// documentRequirements lists the company documents in the order they are shown to the
+// customer. Requirements that depend on the company are resolved by requirementsFor.
+var documentRequirements = []DocumentRequirement{
+	{
+		Type:        "certificate_of_incorporation",
+		Label:       "Certificate of incorporation",
+		Description: "Shows the registration number and legal name on the company register",
+		Required:    true,
+	},
+	{
+		Type:        "tax_certificate",
+		Label:       "Tax registration certificate",
+		Description: "Confirms the tax ID",
+		Required:    true,
+		MaxAgeDays:  365,
+	},
+	{
+		Type:        "bank_statement",
+		Label:       "Business bank statement",
+		Description: "Shows the company holds the account financing is paid out to",
+		Required:    true,
+		MaxAgeDays:  90,
+	},
+	{
+		Type:        "shareholder_register",
+		Label:       "Shareholder register",
+		Description: "Evidences the declared ownership of every beneficial owner",
+		Required:    true,
+		MaxAgeDays:  365,
+	},
+	{
+		Type:        "financial_report",
+		Label:       "Latest annual financial statements",
+		Description: "Filed accounts for the last financial year",
+		Condition:   "companies founded more than two years ago",
+		MaxAgeDays:  548,
+	},
+	{
+		Type:        "other",
+		Label:       "Other",
+		Description: "Anything else supporting the application",
+	},
+}
+
+// requirementsFor resolves the conditional requirements for a company
+func requirementsFor(company *models.Company, now time.Time) []DocumentRequirement {
+	requirements := make([]DocumentRequirement, len(documentRequirements))
+	copy(requirements, documentRequirements)
+	for i := range requirements {
+		if requirements[i].Type == "financial_report" {
+			requirements[i].Required = company.FoundedYear > 0 && company.FoundedYear <= now.Year()-2
+		}
+	}
+	return requirements
+}
+
+// DocumentStatus is how a company meets one document requirement
+type DocumentStatus struct {
+	DocumentRequirement
+	Status     string     `json:"status"`
+	DocumentID *string    `json:"document_id,omitempty"`
+	UploadedAt *time.Time `json:"uploaded_at,omitempty"`
+}
+
+// checkDocuments picks the best uploaded document for each required type: a verified
+// current one if there is any, otherwise the newest
+func checkDocuments(company *models.Company, now time.Time) []DocumentStatus {
+	statuses := []DocumentStatus{}
+	for _, requirement := range requirementsFor(company, now) {
+		if !requirement.Required {
+			continue
+		}
+		status := DocumentStatus{DocumentRequirement: requirement, Status: DocumentMissing}
+		rank := 0
+		for i := range company.Documents {
+			doc := &company.Documents[i]
+			if doc.Type != requirement.Type {
+				continue
+			}
+			state, docRank := DocumentUnverified, 2
+			switch {
+			case requirement.MaxAgeDays > 0 && doc.CreatedAt.Before(now.AddDate(0, 0, -requirement.MaxAgeDays)):
+				state, docRank = DocumentOutdated, 1
+			case doc.Verified:
+				state, docRank = DocumentOK, 3
+			}
+			if docRank > rank || (docRank == rank && status.UploadedAt != nil && doc.CreatedAt.After(*status.UploadedAt)) {
+				id := doc.ID.String()
+				uploaded := doc.CreatedAt
+				status.Status, status.DocumentID, status.UploadedAt, rank = state, &id, &uploaded, docRank
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
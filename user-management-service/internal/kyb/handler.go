@@ -0,0 +1,283 @@
+package kyb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler exposes company verification over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	var validation *ValidationError
+	var notReady *NotReadyError
+	switch {
+	case errors.As(err, &validation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid person", "problems": validation.Problems})
+	case errors.As(err, &notReady):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "assessment": notReady.Assessment})
+	case errors.Is(err, ErrInvalidDecision):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCompanyNotFound), errors.Is(err, ErrPersonNotFound), errors.Is(err, ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicatePerson), errors.Is(err, ErrAlreadyPending), errors.Is(err, ErrAlreadyVerified),
+		errors.Is(err, ErrNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "KYB operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *Handler) GetPeople(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	people, err := h.service.ListPeople(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"people": people})
+}
+
+func (h *Handler) AddPerson(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req PersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	person, err := h.service.AddPerson(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, person)
+}
+
+func (h *Handler) UpdatePerson(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	personID, err := uuid.Parse(c.Param("personId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid person ID"})
+		return
+	}
+	var req PersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	person, err := h.service.UpdatePerson(userID, personID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+func (h *Handler) RemovePerson(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	personID, err := uuid.Parse(c.Param("personId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid person ID"})
+		return
+	}
+
+	if err := h.service.RemovePerson(userID, personID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Person removed"})
+}
+
+func (h *Handler) GetRequirements(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	requirements, err := h.service.Requirements(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requirements)
+}
+
+// GetVerification shows the customer what their company still needs
+func (h *Handler) GetVerification(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	assessment, err := h.service.CompanyAssessment(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, assessment)
+}
+
+func (h *Handler) SubmitVerification(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	assessment, err := h.service.Submit(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Company submitted for verification", "assessment": assessment})
+}
+
+func (h *Handler) GetPending(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	companies, total, err := h.service.ListPending(page, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"companies": companies,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+func (h *Handler) GetCompany(c *gin.Context) {
+	companyID, err := uuid.Parse(c.Param("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	detail, err := h.service.GetCompany(companyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+func (h *Handler) VerifyDocument(c *gin.Context) {
+	companyID, err := uuid.Parse(c.Param("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	documentID, err := uuid.Parse(c.Param("documentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+	reviewer, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.VerifyDocument(companyID, documentID, reviewer); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document verified"})
+}
+
+// Decide verifies or rejects a company
+func (h *Handler) Decide(c *gin.Context) {
+	companyID, err := uuid.Parse(c.Param("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	var req struct {
+		Decision string `json:"decision" binding:"required"` // verify, reject
+		Reason   string `json:"reason"`                      // Required for a rejection, shown to the customer
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reviewer, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	decision, err := h.service.Decide(companyID, reviewer, req.Decision, req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// GetEligibility tells other services whether a company, identified by the user who
+// submitted its KYB case, may be financed
+func (h *Handler) GetEligibility(c *gin.Context) {
+	if c.Query("user_id") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	userID, err := uuid.Parse(c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	eligibility, err := h.service.Eligibility(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, eligibility)
+}
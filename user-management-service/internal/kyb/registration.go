@@ -0,0 +1,112 @@
+package kyb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Registration number checks
+const (
+	RegistrationValid       = "valid"
+	RegistrationInvalid     = "invalid"
+	RegistrationUnsupported = "unsupported_country" // Checked by the reviewer instead
+)
+
+type registrationFormat struct {
+	description string
+	pattern     *regexp.Regexp
+	checksum    func(number string) bool
+}
+
+// registrationFormats are the company register numbers accepted by country, after
+// spaces, dots, hyphens and slashes are removed
+var registrationFormats = map[string]registrationFormat{
+	"GB": {description: "Companies House number: 8 digits, or 2 letters and 6 digits", pattern: regexp.MustCompile(`^(\d{8}|[A-Z]{2}\d{6})$`)},
+	"IE": {description: "CRO number: up to 6 digits", pattern: regexp.MustCompile(`^\d{1,6}$`)},
+	"DE": {description: "Handelsregister number such as HRB 12345", pattern: regexp.MustCompile(`^(HRA|HRB|GNR|PR|VR|GSR)\d{1,6}[A-Z]{0,2}$`)},
+	"FR": {description: "SIREN: 9 digits", pattern: regexp.MustCompile(`^\d{9}$`), checksum: luhn},
+	"NL": {description: "KvK number: 8 digits", pattern: regexp.MustCompile(`^\d{8}$`)},
+	"ES": {description: "CIF: a letter, 7 digits and a check character", pattern: regexp.MustCompile(`^[ABCDEFGHJNPQRSUVW]\d{7}[0-9A-J]$`)},
+	"IT": {description: "Codice fiscale of the company: 11 digits", pattern: regexp.MustCompile(`^\d{11}$`), checksum: luhn},
+	"CH": {description: "UID such as CHE-123.456.789", pattern: regexp.MustCompile(`^CHE\d{9}$`)},
+	"US": {description: "EIN: 9 digits", pattern: regexp.MustCompile(`^\d{9}$`)},
+	"IN": {description: "CIN: 21 characters such as U72900KA2015PTC082988", pattern: regexp.MustCompile(`^[LU]\d{5}[A-Z]{2}\d{4}[A-Z]{3}\d{6}$`)},
+	"SG": {description: "UEN: 9 or 10 characters such as 201912345K", pattern: regexp.MustCompile(`^(\d{8}[A-Z]|\d{9}[A-Z]|[TSR]\d{2}[A-Z]{2}\d{4}[A-Z])$`)},
+	"AU": {description: "ACN: 9 digits", pattern: regexp.MustCompile(`^\d{9}$`), checksum: acnChecksum},
+}
+
+// RegistrationCheck is the outcome of checking a company registration number against
+// the format of the company's country
+type RegistrationCheck struct {
+	Country    string `json:"country"`
+	Number     string `json:"number"` // Normalised
+	Status     string `json:"status"`
+	Format     string `json:"format,omitempty"`
+	ChecksumOK *bool  `json:"checksum_ok,omitempty"`
+}
+
+func normalizeRegistration(number string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-', '/', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(number))
+}
+
+// CheckRegistration validates a registration number for an ISO 3166-1 alpha-2 country
+func CheckRegistration(country, number string) RegistrationCheck {
+	check := RegistrationCheck{Country: strings.ToUpper(country), Number: normalizeRegistration(number)}
+	format, ok := registrationFormats[check.Country]
+	if !ok {
+		check.Status = RegistrationUnsupported
+		if check.Number == "" {
+			check.Status = RegistrationInvalid
+		}
+		return check
+	}
+
+	check.Format = format.description
+	check.Status = RegistrationInvalid
+	if !format.pattern.MatchString(check.Number) {
+		return check
+	}
+	if format.checksum != nil {
+		valid := format.checksum(check.Number)
+		check.ChecksumOK = &valid
+		if !valid {
+			return check
+		}
+	}
+	check.Status = RegistrationValid
+	return check
+}
+
+// luhn validates SIREN numbers and Italian company fiscal codes
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// acnChecksum validates an Australian Company Number: the weighted sum of the first eight
+// digits determines the ninth
+func acnChecksum(number string) bool {
+	sum := 0
+	for i := 0; i < 8; i++ {
+		sum += int(number[i]-'0') * (8 - i)
+	}
+	return (10-sum%10)%10 == int(number[8]-'0')
+}
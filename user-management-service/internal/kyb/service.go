@@ -0,0 +1,759 @@
+package kyb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"user-management-service/internal/models"
+	"user-management-service/internal/screening"
+)
+
+var (
+	ErrCompanyNotFound  = errors.New("company not found")
+	ErrPersonNotFound   = errors.New("person not found")
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrDuplicatePerson  = errors.New("a person with this email is already declared for the company")
+	ErrNotReady         = errors.New("the company does not meet the KYB requirements yet")
+	ErrAlreadyPending   = errors.New("the company is already waiting for a KYB decision")
+	ErrAlreadyVerified  = errors.New("the company is already verified")
+	ErrNotPending       = errors.New("the company is not waiting for a KYB decision")
+	ErrInvalidDecision  = errors.New("decision must be verify or reject, and a rejection needs a reason")
+)
+
+// Decisions on a company
+const (
+	DecisionVerify = "verify"
+	DecisionReject = "reject"
+)
+
+// Ways of controlling a company other than holding shares
+var controlTypes = []string{"shares", "voting_rights", "other_control"}
+
+// ValidationError lists everything wrong with a declared person
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid person: " + strings.Join(e.Problems, "; ")
+}
+
+// NotReadyError carries the assessment that stopped a submission or verification
+type NotReadyError struct {
+	Assessment *Assessment
+}
+
+func (e *NotReadyError) Error() string {
+	return ErrNotReady.Error()
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return ErrNotReady
+}
+
+// Options configures KYB
+type Options struct {
+	UBOThreshold float64 // Ownership percentage from which a shareholder is a beneficial owner
+}
+
+// OptionsFromEnv reads KYB_UBO_THRESHOLD
+func OptionsFromEnv() Options {
+	opts := Options{UBOThreshold: 25}
+	if value, err := strconv.ParseFloat(os.Getenv("KYB_UBO_THRESHOLD"), 64); err == nil && value > 0 && value <= 100 {
+		opts.UBOThreshold = value
+	}
+	return opts
+}
+
+// Service verifies companies: their registration, documents, directors and beneficial
+// owners, whose KYC runs through their own user accounts
+type Service struct {
+	db       *gorm.DB
+	screener *screening.Service
+	opts     Options
+}
+
+func NewService(db *gorm.DB, screener *screening.Service, opts Options) *Service {
+	return &Service{db: db, screener: screener, opts: opts}
+}
+
+// PersonRequest declares a director or beneficial owner
+type PersonRequest struct {
+	FirstName         string  `json:"first_name" binding:"required,max=50"`
+	LastName          string  `json:"last_name" binding:"required,max=50"`
+	Email             string  `json:"email" binding:"required,email"`
+	DateOfBirth       string  `json:"date_of_birth"` // YYYY-MM-DD
+	Nationality       string  `json:"nationality"`
+	IsDirector        bool    `json:"is_director"`
+	IsBeneficialOwner bool    `json:"is_beneficial_owner"`
+	OwnershipPercent  float64 `json:"ownership_percent"`
+	ControlType       string  `json:"control_type"`
+	Position          string  `json:"position"`
+}
+
+func (s *Service) companyOf(db *gorm.DB, userID uuid.UUID) (*models.Company, error) {
+	var company models.Company
+	if err := db.Where("user_id = ?", userID).First(&company).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCompanyNotFound
+		}
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+	return &company, nil
+}
+
+// ListPeople returns the directors and beneficial owners of the user's company
+func (s *Service) ListPeople(userID uuid.UUID) ([]models.CompanyPerson, error) {
+	company, err := s.companyOf(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	var people []models.CompanyPerson
+	if err := s.db.Where("company_id = ?", company.ID).Order("created_at ASC").Find(&people).Error; err != nil {
+		return nil, fmt.Errorf("failed to list people: %w", err)
+	}
+	return people, nil
+}
+
+// AddPerson declares a director or beneficial owner of the user's company
+func (s *Service) AddPerson(userID uuid.UUID, req PersonRequest) (*models.CompanyPerson, error) {
+	var person models.CompanyPerson
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		company, err := s.companyOf(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.applyPerson(tx, company, &person, req); err != nil {
+			return err
+		}
+		if err := tx.Create(&person).Error; err != nil {
+			return fmt.Errorf("failed to add person: %w", err)
+		}
+		return s.reopen(tx, company)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// UpdatePerson replaces the details of a declared person
+func (s *Service) UpdatePerson(userID, personID uuid.UUID, req PersonRequest) (*models.CompanyPerson, error) {
+	var person models.CompanyPerson
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		company, err := s.companyOf(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND company_id = ?", personID, company.ID).First(&person).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPersonNotFound
+			}
+			return fmt.Errorf("failed to get person: %w", err)
+		}
+		if err := s.applyPerson(tx, company, &person, req); err != nil {
+			return err
+		}
+		if err := tx.Save(&person).Error; err != nil {
+			return fmt.Errorf("failed to update person: %w", err)
+		}
+		return s.reopen(tx, company)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// RemovePerson removes a declared person
+func (s *Service) RemovePerson(userID, personID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		company, err := s.companyOf(tx, userID)
+		if err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND company_id = ?", personID, company.ID).Delete(&models.CompanyPerson{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to remove person: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrPersonNotFound
+		}
+		return s.reopen(tx, company)
+	})
+}
+
+// applyPerson validates req and copies it onto person
+func (s *Service) applyPerson(tx *gorm.DB, company *models.Company, person *models.CompanyPerson, req PersonRequest) error {
+	var problems []string
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Nationality = strings.ToUpper(strings.TrimSpace(req.Nationality))
+	if req.ControlType == "" && req.OwnershipPercent > 0 {
+		req.ControlType = "shares"
+	}
+	// Anyone at or above the threshold is a beneficial owner whether declared so or not
+	if req.OwnershipPercent >= s.opts.UBOThreshold {
+		req.IsBeneficialOwner = true
+	}
+
+	if req.OwnershipPercent < 0 || req.OwnershipPercent > 100 {
+		problems = append(problems, "ownership_percent must be between 0 and 100")
+	}
+	if !req.IsDirector && !req.IsBeneficialOwner {
+		problems = append(problems, "a person must be a director, a beneficial owner or both")
+	}
+	if req.ControlType != "" && !containsString(controlTypes, req.ControlType) {
+		problems = append(problems, "control_type must be one of "+strings.Join(controlTypes, ", "))
+	}
+	if req.IsBeneficialOwner && req.OwnershipPercent < s.opts.UBOThreshold && req.ControlType == "shares" {
+		problems = append(problems, fmt.Sprintf("a beneficial owner holding less than %g%% must control the company through voting_rights or other_control", s.opts.UBOThreshold))
+	}
+	if req.Nationality != "" && len(req.Nationality) != 2 {
+		problems = append(problems, "nationality must be an ISO country code")
+	}
+	var dateOfBirth *time.Time
+	if req.DateOfBirth != "" {
+		parsed, err := time.Parse("2006-01-02", req.DateOfBirth)
+		switch {
+		case err != nil:
+			problems = append(problems, "date_of_birth must be YYYY-MM-DD")
+		case parsed.After(time.Now().AddDate(-18, 0, 0)):
+			problems = append(problems, "directors and beneficial owners must be adults")
+		default:
+			dateOfBirth = &parsed
+		}
+	}
+
+	var others []models.CompanyPerson
+	if err := tx.Where("company_id = ? AND id <> ?", company.ID, person.ID).Find(&others).Error; err != nil {
+		return fmt.Errorf("failed to get people: %w", err)
+	}
+	total := req.OwnershipPercent
+	for _, other := range others {
+		if strings.EqualFold(other.Email, req.Email) {
+			return ErrDuplicatePerson
+		}
+		total += other.OwnershipPercent
+	}
+	if total > 100.005 {
+		problems = append(problems, fmt.Sprintf("declared ownership would add up to %.2f%%", total))
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	if !strings.EqualFold(person.Email, req.Email) {
+		// A new email may belong to another account
+		person.UserID = nil
+		person.KYCDataID = nil
+	}
+	person.CompanyID = company.ID
+	person.FirstName = strings.TrimSpace(req.FirstName)
+	person.LastName = strings.TrimSpace(req.LastName)
+	person.Email = req.Email
+	person.DateOfBirth = dateOfBirth
+	person.Nationality = req.Nationality
+	person.IsDirector = req.IsDirector
+	person.IsBeneficialOwner = req.IsBeneficialOwner
+	person.OwnershipPercent = req.OwnershipPercent
+	person.ControlType = req.ControlType
+	person.Position = strings.TrimSpace(req.Position)
+	return nil
+}
+
+// reopen sends a company back to not started when its directors or owners change, since
+// any decision was based on the people declared at the time
+func (s *Service) reopen(tx *gorm.DB, company *models.Company) error {
+	if company.VerificationStatus != models.CompanyVerificationPending && company.VerificationStatus != models.CompanyVerified {
+		return nil
+	}
+	if err := tx.Model(&models.Company{}).Where("id = ?", company.ID).Updates(map[string]interface{}{
+		"verification_status": models.CompanyNotVerified,
+		"verified":            false,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to reopen company verification: %w", err)
+	}
+	log.Printf("KYB: company %s changed its directors or owners and needs to be verified again", company.ID)
+	return nil
+}
+
+// PersonStatus is where a director or beneficial owner stands in KYC
+type PersonStatus struct {
+	PersonID         uuid.UUID        `json:"person_id"`
+	Name             string           `json:"name"`
+	Roles            []string         `json:"roles"`
+	OwnershipPercent float64          `json:"ownership_percent"`
+	HasAccount       bool             `json:"has_account"` // An account with the person's email exists
+	KYCStatus        models.KYCStatus `json:"kyc_status"`
+	KYCExpiresAt     *time.Time       `json:"kyc_expires_at,omitempty"`
+	Compliant        bool             `json:"compliant"`
+}
+
+// Assessment is how far a company is through KYB. Missing items are for the customer to
+// complete before submitting; outstanding items are compliance steps left before the
+// company can be verified.
+type Assessment struct {
+	CompanyID      uuid.UUID                        `json:"company_id"`
+	Status         models.CompanyVerificationStatus `json:"status"`
+	Registration   RegistrationCheck                `json:"registration"`
+	Documents      []DocumentStatus                 `json:"documents"`
+	People         []PersonStatus                   `json:"people"`
+	OwnershipTotal float64                          `json:"ownership_total"`
+	Screening      models.ScreeningStatus           `json:"screening_status,omitempty"`
+	Missing        []string                         `json:"missing"`
+	Outstanding    []string                         `json:"outstanding"`
+	Warnings       []string                         `json:"warnings"`
+	CanSubmit      bool                             `json:"can_submit"`
+	CanVerify      bool                             `json:"can_verify"`
+	AssessedAt     time.Time                        `json:"assessed_at"`
+}
+
+// Assess checks a company against the KYB requirements
+func (s *Service) Assess(companyID uuid.UUID) (*Assessment, error) {
+	var company models.Company
+	if err := s.db.Preload("Documents").Preload("People").First(&company, "id = ?", companyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCompanyNotFound
+		}
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+	return s.assess(&company, time.Now())
+}
+
+func (s *Service) assess(company *models.Company, now time.Time) (*Assessment, error) {
+	a := &Assessment{
+		CompanyID:    company.ID,
+		Status:       company.VerificationStatus,
+		Registration: CheckRegistration(company.Country, company.RegistrationNum),
+		Documents:    checkDocuments(company, now),
+		People:       []PersonStatus{},
+		Missing:      []string{},
+		Outstanding:  []string{},
+		Warnings:     []string{},
+		AssessedAt:   now,
+	}
+
+	switch a.Registration.Status {
+	case RegistrationInvalid:
+		a.Missing = append(a.Missing, fmt.Sprintf("Registration number %q is not a valid %s company number (%s)",
+			company.RegistrationNum, a.Registration.Country, a.Registration.Format))
+	case RegistrationUnsupported:
+		a.Warnings = append(a.Warnings, fmt.Sprintf("Registration numbers from %s cannot be checked automatically; confirm it on the company register",
+			a.Registration.Country))
+	}
+
+	for _, doc := range a.Documents {
+		switch doc.Status {
+		case DocumentMissing:
+			a.Missing = append(a.Missing, doc.Label+" is missing")
+		case DocumentOutdated:
+			a.Missing = append(a.Missing, fmt.Sprintf("%s is older than %d days; upload a current one", doc.Label, doc.MaxAgeDays))
+		case DocumentUnverified:
+			a.Outstanding = append(a.Outstanding, doc.Label+" has not been verified")
+		}
+	}
+
+	directors, owners := 0, 0
+	for i := range company.People {
+		person := &company.People[i]
+		status, err := s.personStatus(person)
+		if err != nil {
+			return nil, err
+		}
+		a.People = append(a.People, *status)
+		a.OwnershipTotal += person.OwnershipPercent
+		if person.IsDirector {
+			directors++
+		}
+		if person.IsBeneficialOwner {
+			owners++
+		}
+
+		switch {
+		case status.Compliant:
+		case !status.HasAccount:
+			a.Missing = append(a.Missing, fmt.Sprintf("%s needs an account with %s to complete KYC", status.Name, person.Email))
+		case status.KYCStatus == models.KYCPending:
+			a.Outstanding = append(a.Outstanding, status.Name+"'s KYC is being reviewed")
+		default:
+			a.Missing = append(a.Missing, fmt.Sprintf("%s has not completed KYC (%s)", status.Name, status.KYCStatus))
+		}
+	}
+	a.OwnershipTotal = math.Round(a.OwnershipTotal*100) / 100
+	if directors == 0 {
+		a.Missing = append(a.Missing, "At least one director must be declared")
+	}
+	if a.OwnershipTotal > 100 {
+		a.Missing = append(a.Missing, fmt.Sprintf("Declared ownership adds up to %.2f%%", a.OwnershipTotal))
+	}
+	if owners == 0 {
+		a.Warnings = append(a.Warnings, fmt.Sprintf("No beneficial owner is declared; confirm against the shareholder register that nobody holds %g%% or more", s.opts.UBOThreshold))
+	}
+
+	var screened models.ScreeningResult
+	err := s.db.Where("subject_type = ? AND subject_id = ?", "company", company.ID.String()).
+		Order("screened_at DESC").First(&screened).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		a.Outstanding = append(a.Outstanding, "The company has not been screened")
+	case err != nil:
+		return nil, fmt.Errorf("failed to get company screening: %w", err)
+	default:
+		a.Screening = screened.Status
+		switch screened.Status {
+		case models.ScreeningPotentialMatch:
+			a.Outstanding = append(a.Outstanding, "Company screening has potential matches awaiting review")
+		case models.ScreeningConfirmedMatch:
+			a.Outstanding = append(a.Outstanding, "Company screening has a confirmed match")
+		}
+	}
+
+	a.CanSubmit = len(a.Missing) == 0
+	a.CanVerify = a.CanSubmit && len(a.Outstanding) == 0
+	return a, nil
+}
+
+// personStatus links a person to the user account with their email and reports its KYC
+func (s *Service) personStatus(person *models.CompanyPerson) (*PersonStatus, error) {
+	status := &PersonStatus{
+		PersonID:         person.ID,
+		Name:             person.FirstName + " " + person.LastName,
+		Roles:            []string{},
+		OwnershipPercent: person.OwnershipPercent,
+		KYCStatus:        models.KYCNotStarted,
+	}
+	if person.IsDirector {
+		status.Roles = append(status.Roles, "director")
+	}
+	if person.IsBeneficialOwner {
+		status.Roles = append(status.Roles, "beneficial_owner")
+	}
+
+	var user models.User
+	query := s.db.Preload("KYCData")
+	if person.UserID != nil {
+		query = query.Where("id = ?", *person.UserID)
+	} else {
+		query = query.Where("LOWER(email) = ?", strings.ToLower(person.Email))
+	}
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return status, nil
+		}
+		return nil, fmt.Errorf("failed to get person's account: %w", err)
+	}
+
+	status.HasAccount = true
+	status.KYCStatus = user.KYCStatus
+	status.KYCExpiresAt = user.KYCExpiresAt
+	status.Compliant = user.IsKYCCompliant()
+
+	var kycDataID *uuid.UUID
+	if user.KYCData != nil {
+		kycDataID = &user.KYCData.ID
+	}
+	if person.UserID == nil || (kycDataID != nil && (person.KYCDataID == nil || *person.KYCDataID != *kycDataID)) {
+		person.UserID = &user.ID
+		person.KYCDataID = kycDataID
+		if err := s.db.Model(&models.CompanyPerson{}).Where("id = ?", person.ID).Updates(map[string]interface{}{
+			"user_id":     person.UserID,
+			"kyc_data_id": person.KYCDataID,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to link person to account: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// Requirements is what a company needs for KYB. Every declared director and beneficial
+// owner has to pass KYC.
+type Requirements struct {
+	Documents          []DocumentRequirement `json:"documents"`
+	RegistrationFormat string                `json:"registration_format,omitempty"` // Empty when the country is not checked automatically
+	UBOThreshold       float64               `json:"ubo_threshold"`
+	ControlTypes       []string              `json:"control_types"`
+}
+
+func (s *Service) Requirements(userID uuid.UUID) (*Requirements, error) {
+	company, err := s.companyOf(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Requirements{
+		Documents:          requirementsFor(company, time.Now()),
+		RegistrationFormat: CheckRegistration(company.Country, company.RegistrationNum).Format,
+		UBOThreshold:       s.opts.UBOThreshold,
+		ControlTypes:       controlTypes,
+	}, nil
+}
+
+// CompanyAssessment assesses the user's own company
+func (s *Service) CompanyAssessment(userID uuid.UUID) (*Assessment, error) {
+	company, err := s.companyOf(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Assess(company.ID)
+}
+
+// Submit screens the user's company and, if nothing is missing, queues it for a
+// compliance decision
+func (s *Service) Submit(userID uuid.UUID) (*Assessment, error) {
+	company, err := s.companyOf(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch company.VerificationStatus {
+	case models.CompanyVerificationPending:
+		return nil, ErrAlreadyPending
+	case models.CompanyVerified:
+		return nil, ErrAlreadyVerified
+	}
+
+	if s.screener != nil {
+		subject := screening.Subject{
+			Type:       "company",
+			ID:         company.ID.String(),
+			Name:       company.Name,
+			EntityType: "entity",
+			Countries:  []string{company.Country},
+		}
+		if company.LegalName != "" && company.LegalName != company.Name {
+			subject.Aliases = []string{company.LegalName}
+		}
+		if _, err := s.screener.ScreenAndRecord(subject, "onboarding"); err != nil {
+			// The assessment reports the company as not screened
+			log.Printf("KYB: failed to screen company %s: %v", company.ID, err)
+		}
+	}
+
+	assessment, err := s.Assess(company.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !assessment.CanSubmit {
+		return assessment, &NotReadyError{Assessment: assessment}
+	}
+
+	result := s.db.Model(&models.Company{}).
+		Where("id = ? AND verification_status = ?", company.ID, company.VerificationStatus).
+		Updates(map[string]interface{}{
+			"verification_status": models.CompanyVerificationPending,
+			"rejection_reason":    "",
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to submit company for verification: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAlreadyPending
+	}
+	assessment.Status = models.CompanyVerificationPending
+	return assessment, nil
+}
+
+// ListPending returns companies waiting for a KYB decision, oldest first
+func (s *Service) ListPending(page, limit int) ([]models.Company, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := s.db.Model(&models.Company{}).Where("verification_status = ?", models.CompanyVerificationPending)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count companies: %w", err)
+	}
+	var companies []models.Company
+	if err := query.Preload("People").Order("updated_at ASC").
+		Offset((page - 1) * limit).Limit(limit).Find(&companies).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list companies: %w", err)
+	}
+	return companies, total, nil
+}
+
+// CompanyDetail is everything a compliance officer needs to decide on a company
+type CompanyDetail struct {
+	Company    *models.Company      `json:"company"`
+	Assessment *Assessment          `json:"assessment"`
+	Decisions  []models.KYBDecision `json:"decisions"`
+}
+
+func (s *Service) GetCompany(companyID uuid.UUID) (*CompanyDetail, error) {
+	var company models.Company
+	if err := s.db.Preload("Documents").Preload("People").First(&company, "id = ?", companyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCompanyNotFound
+		}
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+	assessment, err := s.assess(&company, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var decisions []models.KYBDecision
+	if err := s.db.Where("company_id = ?", companyID).Order("created_at DESC").Find(&decisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get KYB decisions: %w", err)
+	}
+	return &CompanyDetail{Company: &company, Assessment: assessment, Decisions: decisions}, nil
+}
+
+// VerifyDocument marks a company document as checked by compliance
+func (s *Service) VerifyDocument(companyID, documentID, reviewer uuid.UUID) error {
+	now := time.Now()
+	result := s.db.Model(&models.CompanyDocument{}).
+		Where("id = ? AND company_id = ?", documentID, companyID).
+		Updates(map[string]interface{}{
+			"verified":    true,
+			"verified_at": now,
+			"verified_by": reviewer,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to verify document: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrDocumentNotFound
+	}
+	return nil
+}
+
+// Decide verifies or rejects a pending company. Verification needs every requirement
+// met; the assessment it was based on is kept with the decision.
+func (s *Service) Decide(companyID, reviewer uuid.UUID, decision, reason string) (*models.KYBDecision, error) {
+	reason = strings.TrimSpace(reason)
+	if (decision != DecisionVerify && decision != DecisionReject) || (decision == DecisionReject && reason == "") {
+		return nil, ErrInvalidDecision
+	}
+
+	var company models.Company
+	if err := s.db.Preload("Documents").Preload("People").First(&company, "id = ?", companyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCompanyNotFound
+		}
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+	if company.VerificationStatus != models.CompanyVerificationPending {
+		return nil, ErrNotPending
+	}
+
+	now := time.Now()
+	assessment, err := s.assess(&company, now)
+	if err != nil {
+		return nil, err
+	}
+	record := &models.KYBDecision{
+		CompanyID:  companyID,
+		Status:     models.CompanyRejected,
+		Reason:     reason,
+		DecidedBy:  reviewer,
+		Assessment: assessmentSnapshot(assessment),
+	}
+	updates := map[string]interface{}{
+		"verification_status": models.CompanyRejected,
+		"verified":            false,
+		"verified_by":         reviewer,
+		"rejection_reason":    reason,
+	}
+	if decision == DecisionVerify {
+		if !assessment.CanVerify {
+			return nil, &NotReadyError{Assessment: assessment}
+		}
+		record.Status = models.CompanyVerified
+		updates["verification_status"] = models.CompanyVerified
+		updates["verified"] = true
+		updates["verified_at"] = now
+		updates["rejection_reason"] = ""
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Company{}).
+			Where("id = ? AND verification_status = ?", companyID, models.CompanyVerificationPending).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record KYB decision: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotPending
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to record KYB decision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func assessmentSnapshot(assessment *Assessment) map[string]interface{} {
+	snapshot := map[string]interface{}{}
+	if data, err := json.Marshal(assessment); err == nil {
+		_ = json.Unmarshal(data, &snapshot)
+	}
+	return snapshot
+}
+
+// Eligibility is whether a company may be financed
+type Eligibility struct {
+	CompanyID uuid.UUID                        `json:"company_id"`
+	Status    models.CompanyVerificationStatus `json:"kyb_status"`
+	Eligible  bool                             `json:"eligible"`
+	Reasons   []string                         `json:"reasons"`
+	// Annual revenue the company declared, once KYB review verified the company; zero
+	// until then
+	AnnualRevenue float64 `json:"annual_revenue"`
+}
+
+// Eligibility checks that the company whose KYB case userID submitted is verified and
+// that its directors and beneficial owners are still KYC compliant, since their approvals
+// expire on their own schedule. Companies are not looked up by tax ID, which anyone can
+// claim.
+func (s *Service) Eligibility(userID uuid.UUID) (*Eligibility, error) {
+	var company models.Company
+	if err := s.db.Preload("People").Where("user_id = ?", userID).First(&company).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCompanyNotFound
+		}
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+
+	eligibility := &Eligibility{CompanyID: company.ID, Status: company.VerificationStatus, Reasons: []string{}}
+	if company.VerificationStatus != models.CompanyVerified {
+		eligibility.Reasons = append(eligibility.Reasons, fmt.Sprintf("Company KYB status is %s", company.VerificationStatus))
+	} else {
+		eligibility.AnnualRevenue = company.AnnualRevenue
+	}
+	for i := range company.People {
+		status, err := s.personStatus(&company.People[i])
+		if err != nil {
+			return nil, err
+		}
+		if !status.Compliant {
+			eligibility.Reasons = append(eligibility.Reasons, fmt.Sprintf("KYC of %s (%s) is %s",
+				status.Name, strings.Join(status.Roles, ", "), status.KYCStatus))
+		}
+	}
+	eligibility.Eligible = len(eligibility.Reasons) == 0
+	return eligibility, nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
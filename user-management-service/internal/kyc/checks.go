@@ -0,0 +1,266 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"user-management-service/internal/models"
+	"user-management-service/internal/screening"
+)
+
+// Check is an automated check run on every KYC submission. Its score is added to the
+// submission's risk score; anything but a passed or skipped check sends the submission
+// to manual review.
+type Check interface {
+	Name() string
+	Run(ctx context.Context, user *models.User, data *models.KYCData) CheckResult
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Provider string
+	Status   models.KYCCheckStatus
+	Score    int // Risk points, 0-100
+	Details  map[string]interface{}
+}
+
+// Risk points for check outcomes
+const (
+	scoreCheckFailed      = 60
+	scoreCheckReview      = 25
+	scoreCheckUnavailable = 15
+)
+
+func unavailable(provider, reason string) CheckResult {
+	return CheckResult{
+		Provider: provider,
+		Status:   models.KYCCheckUnavailable,
+		Score:    scoreCheckUnavailable,
+		Details:  map[string]interface{}{"reason": reason},
+	}
+}
+
+// documentCheck sends the identity document to an OCR provider and compares what it
+// reads with the submitted data. The provider is called with
+// {"document_url", "back_url", "id_type"} and answers with the extracted fields.
+type documentCheck struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type ocrResult struct {
+	DocumentNumber    string  `json:"document_number"`
+	FullName          string  `json:"full_name"`
+	DateOfBirth       string  `json:"date_of_birth"` // YYYY-MM-DD
+	ExpiryDate        string  `json:"expiry_date"`   // YYYY-MM-DD
+	Nationality       string  `json:"nationality"`
+	Confidence        float64 `json:"confidence"` // 0-1
+	TamperingDetected bool    `json:"tampering_detected"`
+}
+
+func newDocumentCheck(opts Options) *documentCheck {
+	return &documentCheck{
+		url:    strings.TrimSuffix(opts.OCRURL, "/"),
+		token:  opts.OCRToken,
+		client: &http.Client{Timeout: opts.CheckTimeout},
+	}
+}
+
+func (c *documentCheck) Name() string { return "document_ocr" }
+
+func (c *documentCheck) Run(ctx context.Context, user *models.User, data *models.KYCData) CheckResult {
+	if c.url == "" {
+		return unavailable("", "KYC_OCR_URL is not set")
+	}
+
+	extracted, err := c.read(ctx, data)
+	if err != nil {
+		return unavailable(c.url, err.Error())
+	}
+
+	mismatches := []string{}
+	if strings.ToUpper(strings.Join(strings.Fields(extracted.DocumentNumber), "")) != data.IDNumber {
+		mismatches = append(mismatches, "document_number")
+	}
+	if !sameName(extracted.FullName, user.GetFullName()) {
+		mismatches = append(mismatches, "name")
+	}
+	if extracted.DateOfBirth != data.DateOfBirth.Format("2006-01-02") {
+		mismatches = append(mismatches, "date_of_birth")
+	}
+	if extracted.ExpiryDate != data.IDExpiryDate.Format("2006-01-02") {
+		mismatches = append(mismatches, "expiry_date")
+	}
+	if extracted.Nationality != "" && !strings.EqualFold(extracted.Nationality, data.Nationality) {
+		mismatches = append(mismatches, "nationality")
+	}
+
+	result := CheckResult{
+		Provider: c.url,
+		Status:   models.KYCCheckPassed,
+		Details: map[string]interface{}{
+			"confidence": extracted.Confidence,
+			"mismatches": mismatches,
+		},
+	}
+	switch {
+	case extracted.TamperingDetected:
+		result.Status = models.KYCCheckFailed
+		result.Score = scoreCheckFailed
+		result.Details["tampering_detected"] = true
+	case len(mismatches) > 0 || extracted.Confidence < 0.8:
+		result.Status = models.KYCCheckReview
+		result.Score = scoreCheckReview
+	}
+	return result
+}
+
+func (c *documentCheck) read(ctx context.Context, data *models.KYCData) (*ocrResult, error) {
+	body, err := json.Marshal(map[string]string{
+		"document_url": data.IDDocumentURL,
+		"back_url":     data.AdditionalDocsURL,
+		"id_type":      data.IDType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCR provider not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCR provider returned %s", resp.Status)
+	}
+	var extracted ocrResult
+	if err := json.NewDecoder(resp.Body).Decode(&extracted); err != nil {
+		return nil, fmt.Errorf("invalid OCR provider response: %w", err)
+	}
+	return &extracted, nil
+}
+
+// sameName compares names token by token after the normalisation used for screening, so
+// accents, case and name order do not matter
+func sameName(a, b string) bool {
+	tokensA, tokensB := screening.Normalize(a), screening.Normalize(b)
+	if len(tokensA) == 0 || len(tokensA) != len(tokensB) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, token := range tokensA {
+		counts[token]++
+	}
+	for _, token := range tokensB {
+		if counts[token] == 0 {
+			return false
+		}
+		counts[token]--
+	}
+	return true
+}
+
+// sanctionsCheck screens the customer and their company against the loaded sanctions
+// and PEP lists. Screening also records the AML outcome on the KYC data.
+type sanctionsCheck struct {
+	screener *screening.Service
+}
+
+func (c *sanctionsCheck) Name() string { return "sanctions_screening" }
+
+func (c *sanctionsCheck) Run(ctx context.Context, user *models.User, data *models.KYCData) CheckResult {
+	results, err := c.screener.ScreenUser(user.ID, "onboarding")
+	if errors.Is(err, screening.ErrNoActiveLists) {
+		return unavailable("screening", "no screening lists loaded")
+	}
+	if err != nil {
+		return unavailable("screening", err.Error())
+	}
+
+	result := CheckResult{Provider: "screening", Status: models.KYCCheckPassed}
+	screenings := make([]map[string]interface{}, 0, len(results))
+	for _, screened := range results {
+		screenings = append(screenings, map[string]interface{}{
+			"result_id":    screened.ID,
+			"subject_type": screened.SubjectType,
+			"status":       screened.Status,
+			"top_score":    screened.TopScore,
+		})
+
+		switch screened.Status {
+		case models.ScreeningConfirmedMatch:
+			for _, hit := range screened.Hits {
+				if hit.Source != screening.SourcePEP {
+					result.Status = models.KYCCheckFailed
+					result.Score = scoreCheckFailed
+				}
+			}
+		case models.ScreeningPotentialMatch:
+			if result.Status == models.KYCCheckPassed {
+				result.Status = models.KYCCheckReview
+				result.Score = scoreCheckReview
+			}
+		}
+	}
+	result.Details = map[string]interface{}{"results": screenings}
+	return result
+}
+
+// livenessCheck is a placeholder until a liveness provider is integrated. It does not
+// block approval; reviewers compare the selfie with the document photo.
+type livenessCheck struct{}
+
+func (livenessCheck) Name() string { return "liveness" }
+
+func (livenessCheck) Run(ctx context.Context, user *models.User, data *models.KYCData) CheckResult {
+	return CheckResult{
+		Status: models.KYCCheckSkipped,
+		Details: map[string]interface{}{
+			"reason":     "liveness detection is not integrated",
+			"selfie_url": data.SelfieURL,
+		},
+	}
+}
+
+// runChecks runs every check with a time limit and stores the results
+func (s *Service) runChecks(user *models.User, data *models.KYCData) ([]models.KYCCheck, error) {
+	checks := make([]models.KYCCheck, 0, len(s.checks))
+	for _, check := range s.checks {
+		ctx, cancel := context.WithTimeout(context.Background(), s.opts.CheckTimeout)
+		result := check.Run(ctx, user, data)
+		cancel()
+
+		checks = append(checks, models.KYCCheck{
+			KYCID:      data.ID,
+			UserID:     user.ID,
+			Submission: data.SubmissionCount,
+			Name:       check.Name(),
+			Provider:   result.Provider,
+			Status:     result.Status,
+			Score:      result.Score,
+			Details:    result.Details,
+			CheckedAt:  time.Now(),
+		})
+	}
+	if len(checks) > 0 {
+		if err := s.db.Create(&checks).Error; err != nil {
+			return nil, fmt.Errorf("failed to save KYC checks: %w", err)
+		}
+	}
+	return checks, nil
+}
@@ -0,0 +1,195 @@
+package kyc
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Requirement describes what a KYC submission with one kind of identity document needs
+type Requirement struct {
+	IDType          string   `json:"id_type"`
+	Label           string   `json:"label"`
+	NumberFormat    string   `json:"number_format"`
+	RequiresBack    bool     `json:"requires_back"` // The back side goes in additional_docs_url
+	MaxValidityYrs  int      `json:"max_validity_years"`
+	RequiredFields  []string `json:"required_fields"`
+	AddressProofs   []string `json:"address_proof_types"`
+	MinimumAge      int      `json:"minimum_age"`
+	MinValidityDays int      `json:"min_validity_days"` // Remaining validity of the document at submission
+	numberPattern   *regexp.Regexp
+}
+
+var addressProofTypes = []string{"utility_bill", "bank_statement", "lease_agreement"}
+
+const minimumAge = 18
+
+var commonFields = []string{
+	"date_of_birth", "nationality", "id_number", "id_expiry_date", "address_line_1", "city", "state",
+	"postal_code", "country", "address_proof_type", "id_document_url", "address_proof_url", "selfie_url",
+}
+
+var documentRequirements = map[string]Requirement{
+	"passport": {
+		Label:          "Passport",
+		NumberFormat:   "6 to 9 letters and digits",
+		MaxValidityYrs: 10,
+		numberPattern:  regexp.MustCompile(`^[A-Z0-9]{6,9}$`),
+	},
+	"driving_license": {
+		Label:          "Driving licence",
+		NumberFormat:   "5 to 20 letters, digits and hyphens",
+		RequiresBack:   true,
+		MaxValidityYrs: 15,
+		numberPattern:  regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{4,19}$`),
+	},
+	"national_id": {
+		Label:          "National identity card",
+		NumberFormat:   "5 to 20 letters, digits and hyphens",
+		RequiresBack:   true,
+		MaxValidityYrs: 15,
+		numberPattern:  regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{4,19}$`),
+	},
+}
+
+// Submission is a customer's KYC data and document uploads
+type Submission struct {
+	DateOfBirth  string `json:"date_of_birth" binding:"required"` // YYYY-MM-DD
+	Nationality  string `json:"nationality" binding:"required"`
+	IDType       string `json:"id_type" binding:"required"`
+	IDNumber     string `json:"id_number" binding:"required"`
+	IDExpiryDate string `json:"id_expiry_date" binding:"required"` // YYYY-MM-DD
+
+	AddressLine1     string `json:"address_line_1" binding:"required"`
+	AddressLine2     string `json:"address_line_2"`
+	City             string `json:"city" binding:"required"`
+	State            string `json:"state" binding:"required"`
+	PostalCode       string `json:"postal_code" binding:"required"`
+	Country          string `json:"country" binding:"required"`
+	AddressProofType string `json:"address_proof_type" binding:"required"`
+
+	PoliticallyExposed  bool   `json:"politically_exposed"`
+	SourceOfFunds       string `json:"source_of_funds"`
+	PurposeOfAccount    string `json:"purpose_of_account"`
+	ExpectedTransVolume string `json:"expected_transaction_volume"`
+
+	IDDocumentURL     string `json:"id_document_url" binding:"required"`
+	AddressProofURL   string `json:"address_proof_url" binding:"required"`
+	SelfieURL         string `json:"selfie_url" binding:"required"`
+	AdditionalDocsURL string `json:"additional_docs_url"`
+
+	dateOfBirth time.Time
+	idExpiry    time.Time
+}
+
+// ValidationError lists everything wrong with a submission
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid KYC submission: " + strings.Join(e.Problems, "; ")
+}
+
+// Requirements returns the document requirements by ID type
+func (s *Service) Requirements() []Requirement {
+	requirements := make([]Requirement, 0, len(documentRequirements))
+	for _, idType := range []string{"passport", "national_id", "driving_license"} {
+		requirement := documentRequirements[idType]
+		requirement.IDType = idType
+		requirement.RequiredFields = commonFields
+		if requirement.RequiresBack {
+			requirement.RequiredFields = append(append([]string{}, commonFields...), "additional_docs_url")
+		}
+		requirement.AddressProofs = addressProofTypes
+		requirement.MinimumAge = minimumAge
+		requirement.MinValidityDays = s.opts.MinDocumentValidityDays
+		requirements = append(requirements, requirement)
+	}
+	return requirements
+}
+
+// validate normalises the submission and checks it against the rules for its ID type
+func (s *Service) validate(sub *Submission, now time.Time) error {
+	var problems []string
+	sub.IDType = strings.ToLower(strings.TrimSpace(sub.IDType))
+	sub.IDNumber = strings.ToUpper(strings.Join(strings.Fields(sub.IDNumber), ""))
+	sub.Nationality = strings.ToUpper(strings.TrimSpace(sub.Nationality))
+	sub.Country = strings.ToUpper(strings.TrimSpace(sub.Country))
+
+	requirement, ok := documentRequirements[sub.IDType]
+	if !ok {
+		problems = append(problems, "id_type must be passport, driving_license or national_id")
+	} else if !requirement.numberPattern.MatchString(sub.IDNumber) {
+		problems = append(problems, fmt.Sprintf("id_number of a %s must be %s", strings.ToLower(requirement.Label), requirement.NumberFormat))
+	}
+
+	if len(sub.Nationality) != 2 {
+		problems = append(problems, "nationality must be an ISO 3166-1 alpha-2 country code")
+	}
+	if len(sub.Country) != 2 {
+		problems = append(problems, "country must be an ISO 3166-1 alpha-2 country code")
+	}
+
+	var err error
+	if sub.dateOfBirth, err = time.Parse("2006-01-02", sub.DateOfBirth); err != nil {
+		problems = append(problems, "date_of_birth must be a date as YYYY-MM-DD")
+	} else if sub.dateOfBirth.AddDate(minimumAge, 0, 0).After(now) {
+		problems = append(problems, fmt.Sprintf("customers must be at least %d years old", minimumAge))
+	} else if sub.dateOfBirth.AddDate(120, 0, 0).Before(now) {
+		problems = append(problems, "date_of_birth is not plausible")
+	}
+
+	if sub.idExpiry, err = time.Parse("2006-01-02", sub.IDExpiryDate); err != nil {
+		problems = append(problems, "id_expiry_date must be a date as YYYY-MM-DD")
+	} else if sub.idExpiry.Before(now.AddDate(0, 0, s.opts.MinDocumentValidityDays)) {
+		problems = append(problems, fmt.Sprintf("the identity document must be valid for at least %d more days", s.opts.MinDocumentValidityDays))
+	} else if ok && sub.idExpiry.After(now.AddDate(requirement.MaxValidityYrs, 0, 0)) {
+		problems = append(problems, fmt.Sprintf("id_expiry_date is more than %d years away, which is not possible for a %s",
+			requirement.MaxValidityYrs, strings.ToLower(requirement.Label)))
+	}
+
+	if !containsString(addressProofTypes, sub.AddressProofType) {
+		problems = append(problems, "address_proof_type must be utility_bill, bank_statement or lease_agreement")
+	}
+	if sub.PoliticallyExposed && (strings.TrimSpace(sub.SourceOfFunds) == "" || strings.TrimSpace(sub.PurposeOfAccount) == "") {
+		problems = append(problems, "politically exposed persons must give source_of_funds and purpose_of_account")
+	}
+
+	documents := map[string]string{
+		"id_document_url":   sub.IDDocumentURL,
+		"address_proof_url": sub.AddressProofURL,
+		"selfie_url":        sub.SelfieURL,
+	}
+	if ok && requirement.RequiresBack {
+		documents["additional_docs_url"] = sub.AdditionalDocsURL
+		if sub.AdditionalDocsURL == "" {
+			problems = append(problems, fmt.Sprintf("additional_docs_url must hold the back of the %s", strings.ToLower(requirement.Label)))
+		}
+	}
+	for _, field := range []string{"id_document_url", "address_proof_url", "selfie_url", "additional_docs_url"} {
+		value, required := documents[field]
+		if !required || value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			problems = append(problems, field+" must be the URL of an uploaded document")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
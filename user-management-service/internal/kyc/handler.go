@@ -0,0 +1,257 @@
+package kyc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler exposes KYC submission and the review queue over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	var validation *ValidationError
+	switch {
+	case errors.As(err, &validation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid KYC submission", "problems": validation.Problems})
+	case errors.Is(err, ErrInvalidDecision), errors.Is(err, ErrNoteRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSameReviewer):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, ErrKYCNotFound), errors.Is(err, ErrCaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadySubmitted), errors.Is(err, ErrReviewInProgress), errors.Is(err, ErrResubmitNotAllowed),
+		errors.Is(err, ErrNotEditable), errors.Is(err, ErrCaseChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "KYC operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *Handler) GetStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.service.Status(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) GetRequirements(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"requirements": h.service.Requirements()})
+}
+
+func (h *Handler) Submit(c *gin.Context) {
+	h.submit(c, false)
+}
+
+func (h *Handler) Resubmit(c *gin.Context) {
+	h.submit(c, true)
+}
+
+func (h *Handler) submit(c *gin.Context, resubmission bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req Submission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	submit := h.service.Submit
+	if resubmission {
+		submit = h.service.Resubmit
+	}
+	outcome, err := submit(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	message := "KYC approved"
+	if outcome.InReview {
+		message = "KYC submitted for review"
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":       message,
+		"kyc_id":        outcome.KYC.ID,
+		"kyc_status":    outcome.Status,
+		"review_status": outcome.KYC.ReviewStatus,
+		"submission":    outcome.KYC.SubmissionCount,
+	})
+}
+
+// GetEligibility tells other services whether a customer, identified by user ID or email,
+// may take new financing or invest
+func (h *Handler) GetEligibility(c *gin.Context) {
+	var eligibility *Eligibility
+	var err error
+	switch {
+	case c.Query("user_id") != "":
+		userID, parseErr := uuid.Parse(c.Query("user_id"))
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		eligibility, err = h.service.EligibilityByUser(userID)
+	case c.Query("email") != "":
+		eligibility, err = h.service.Eligibility(c.Query("email"))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id or email is required"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, eligibility)
+}
+
+// UpdateInfo changes due diligence answers while the submission waits for review
+func (h *Handler) UpdateInfo(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req InfoUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.service.UpdateInfo(userID, req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "KYC information updated"})
+}
+
+// GetQueue lists open review cases, highest risk first
+func (h *Handler) GetQueue(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	cases, total, err := h.service.ListQueue(QueueFilter{
+		Status:    c.Query("status"),
+		RiskLevel: c.Query("risk_level"),
+		Page:      page,
+		Limit:     limit,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cases": cases,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (h *Handler) GetCase(c *gin.Context) {
+	kycID, err := uuid.Parse(c.Param("kycId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid KYC ID"})
+		return
+	}
+
+	detail, err := h.service.GetCase(kycID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// Review records the first reviewer's decision on a case
+func (h *Handler) Review(c *gin.Context) {
+	kycID, err := uuid.Parse(c.Param("kycId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid KYC ID"})
+		return
+	}
+	var req struct {
+		Decision        string `json:"decision" binding:"required"` // approve, reject
+		Note            string `json:"note" binding:"required"`
+		RejectionReason string `json:"rejection_reason"` // Shown to the customer
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reviewer, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	reviewCase, err := h.service.Decide(kycID, reviewer, req.Decision, req.Note, req.RejectionReason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviewCase)
+}
+
+// Approve confirms another reviewer's decision, or sends it back to the queue
+func (h *Handler) Approve(c *gin.Context) {
+	kycID, err := uuid.Parse(c.Param("kycId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid KYC ID"})
+		return
+	}
+	var req struct {
+		Confirm *bool  `json:"confirm" binding:"required"`
+		Note    string `json:"note"` // Required when sending the decision back
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	checker, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	reviewCase, err := h.service.Approve(kycID, checker, *req.Confirm, req.Note)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviewCase)
+}
@@ -0,0 +1,114 @@
+package kyc
+
+import (
+	"time"
+
+	"user-management-service/internal/models"
+)
+
+// Risk levels
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Compliance levels are the due diligence a customer gets: simplified for low risk,
+// enhanced for medium risk and enhanced with closer ongoing monitoring for high risk
+const (
+	ComplianceBasic    = "basic"
+	ComplianceEnhanced = "enhanced"
+	CompliancePremium  = "premium"
+)
+
+// Score thresholds of the risk levels
+const (
+	mediumRiskScore = 30
+	highRiskScore   = 60
+)
+
+// Risk points of customer risk factors
+var factorScores = map[string]int{
+	"politically_exposed": 30,
+	"high_risk_country":   30,
+	"foreign_resident":    5,  // Lives outside the country of nationality
+	"document_expiring":   5,  // The ID expires within six months
+	"resubmission":        10, // A previous submission was rejected or lapsed
+}
+
+// assessment is the risk of one KYC submission
+type assessment struct {
+	score   int
+	level   string
+	factors []string
+	clean   bool // Every check passed or was skipped
+}
+
+func (s *Service) assess(data *models.KYCData, checks []models.KYCCheck, now time.Time) assessment {
+	a := assessment{clean: true}
+	for _, check := range checks {
+		if check.Score > 0 {
+			a.score += check.Score
+			a.factors = append(a.factors, check.Name+"_"+string(check.Status))
+		}
+		if check.Status != models.KYCCheckPassed && check.Status != models.KYCCheckSkipped {
+			a.clean = false
+		}
+	}
+
+	factor := func(name string, present bool) {
+		if present {
+			a.score += factorScores[name]
+			a.factors = append(a.factors, name)
+		}
+	}
+	factor("politically_exposed", data.PoliticallyExposed)
+	factor("high_risk_country", s.opts.HighRiskCountries[data.Nationality] || s.opts.HighRiskCountries[data.Country])
+	factor("foreign_resident", data.Nationality != data.Country)
+	factor("document_expiring", data.IDExpiryDate.Before(now.AddDate(0, 6, 0)))
+	factor("resubmission", data.SubmissionCount > 1)
+
+	if a.score > 100 {
+		a.score = 100
+	}
+	a.level = riskLevel(a.score)
+	// Politically exposed persons always get enhanced due diligence
+	if data.PoliticallyExposed && a.level == RiskLow {
+		a.level = RiskMedium
+	}
+	return a
+}
+
+func riskLevel(score int) string {
+	switch {
+	case score >= highRiskScore:
+		return RiskHigh
+	case score >= mediumRiskScore:
+		return RiskMedium
+	default:
+		return RiskLow
+	}
+}
+
+func complianceLevel(level string) string {
+	switch level {
+	case RiskHigh:
+		return CompliancePremium
+	case RiskMedium:
+		return ComplianceEnhanced
+	default:
+		return ComplianceBasic
+	}
+}
+
+// reviewPeriod is how long an approval holds before the customer is reviewed again
+func (s *Service) reviewPeriod(level string) time.Duration {
+	switch level {
+	case RiskHigh:
+		return s.opts.ReviewPeriodHigh
+	case RiskMedium:
+		return s.opts.ReviewPeriodMedium
+	default:
+		return s.opts.ReviewPeriodLow
+	}
+}
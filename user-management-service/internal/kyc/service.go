@@ -0,0 +1,738 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"user-management-service/internal/models"
+	"user-management-service/internal/screening"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrKYCNotFound        = errors.New("KYC data not found")
+	ErrAlreadySubmitted   = errors.New("KYC has already been submitted, use resubmit")
+	ErrReviewInProgress   = errors.New("the KYC submission is still being reviewed")
+	ErrResubmitNotAllowed = errors.New("KYC can only be resubmitted after rejection or expiry, or when renewal is due")
+	ErrNotEditable        = errors.New("KYC information can only be updated while the submission waits for review")
+	ErrCaseNotFound       = errors.New("no open review case for this KYC submission")
+	ErrCaseChanged        = errors.New("the review case is not waiting for this step")
+	ErrInvalidDecision    = errors.New("decision must be approve or reject, and a rejection needs a reason")
+	ErrSameReviewer       = errors.New("the decision must be approved by a different reviewer")
+	ErrNoteRequired       = errors.New("a note is required to send a decision back")
+)
+
+// Decisions of the first reviewer
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// Options configures the automated checks, approval periods and expiry
+type Options struct {
+	OCRURL                  string // Document OCR provider; documents go to manual review without one
+	OCRToken                string
+	CheckTimeout            time.Duration
+	AutoApprove             bool // Approve low-risk submissions whose checks all passed without review
+	HighRiskCountries       map[string]bool
+	ReviewPeriodLow         time.Duration // Time from approval to the next review, by risk level
+	ReviewPeriodMedium      time.Duration
+	ReviewPeriodHigh        time.Duration
+	ExpiryGrace             time.Duration // Approvals expire this long after the review date
+	MinDocumentValidityDays int
+	ExpiryCheckInterval     time.Duration
+}
+
+// OptionsFromEnv reads KYC_OCR_URL, KYC_OCR_TOKEN, KYC_CHECK_TIMEOUT, KYC_AUTO_APPROVE,
+// KYC_HIGH_RISK_COUNTRIES, KYC_REVIEW_DAYS_LOW, KYC_REVIEW_DAYS_MEDIUM,
+// KYC_REVIEW_DAYS_HIGH, KYC_EXPIRY_GRACE_DAYS, KYC_MIN_DOCUMENT_VALIDITY_DAYS and
+// KYC_EXPIRY_CHECK_INTERVAL
+func OptionsFromEnv() Options {
+	opts := Options{
+		OCRURL:                  os.Getenv("KYC_OCR_URL"),
+		OCRToken:                os.Getenv("KYC_OCR_TOKEN"),
+		CheckTimeout:            30 * time.Second,
+		AutoApprove:             os.Getenv("KYC_AUTO_APPROVE") != "false",
+		HighRiskCountries:       map[string]bool{},
+		MinDocumentValidityDays: 30,
+		ExpiryCheckInterval:     time.Hour,
+	}
+
+	countries := os.Getenv("KYC_HIGH_RISK_COUNTRIES")
+	if countries == "" {
+		countries = "KP,IR,MM" // FATF call for action
+	}
+	for _, country := range strings.Split(countries, ",") {
+		if country = strings.ToUpper(strings.TrimSpace(country)); country != "" {
+			opts.HighRiskCountries[country] = true
+		}
+	}
+
+	days := map[string]int{
+		"KYC_REVIEW_DAYS_LOW":    3 * 365,
+		"KYC_REVIEW_DAYS_MEDIUM": 2 * 365,
+		"KYC_REVIEW_DAYS_HIGH":   365,
+		"KYC_EXPIRY_GRACE_DAYS":  30,
+	}
+	for key := range days {
+		if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+			days[key] = value
+		}
+	}
+	opts.ReviewPeriodLow = time.Duration(days["KYC_REVIEW_DAYS_LOW"]) * 24 * time.Hour
+	opts.ReviewPeriodMedium = time.Duration(days["KYC_REVIEW_DAYS_MEDIUM"]) * 24 * time.Hour
+	opts.ReviewPeriodHigh = time.Duration(days["KYC_REVIEW_DAYS_HIGH"]) * 24 * time.Hour
+	opts.ExpiryGrace = time.Duration(days["KYC_EXPIRY_GRACE_DAYS"]) * 24 * time.Hour
+
+	if value, err := strconv.Atoi(os.Getenv("KYC_MIN_DOCUMENT_VALIDITY_DAYS")); err == nil && value >= 0 {
+		opts.MinDocumentValidityDays = value
+	}
+	for key, target := range map[string]*time.Duration{
+		"KYC_CHECK_TIMEOUT":         &opts.CheckTimeout,
+		"KYC_EXPIRY_CHECK_INTERVAL": &opts.ExpiryCheckInterval,
+	} {
+		if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+			*target = value
+		}
+	}
+	return opts
+}
+
+// Service runs KYC submissions through automated checks and risk scoring, approves low
+// risk ones and queues the rest for maker-checker review
+type Service struct {
+	db     *gorm.DB
+	opts   Options
+	checks []Check
+}
+
+// NewService sets up document OCR, sanctions screening and the liveness placeholder as
+// the automated checks
+func NewService(db *gorm.DB, screener *screening.Service, opts Options) *Service {
+	return &Service{
+		db:     db,
+		opts:   opts,
+		checks: []Check{newDocumentCheck(opts), &sanctionsCheck{screener: screener}, livenessCheck{}},
+	}
+}
+
+// SetChecks replaces the automated checks, e.g. to plug in other providers
+func (s *Service) SetChecks(checks ...Check) {
+	s.checks = checks
+}
+
+// Outcome is the state of a submission once its automated checks have run
+type Outcome struct {
+	KYC      *models.KYCData
+	Status   models.KYCStatus // The customer's KYC status
+	InReview bool
+}
+
+// Submit stores a customer's first KYC submission and processes it
+func (s *Service) Submit(userID uuid.UUID, sub Submission) (*Outcome, error) {
+	return s.submit(userID, sub, false)
+}
+
+// Resubmit replaces rejected or expired KYC data, or renews an approval that is due for
+// review. A renewing customer stays approved until the old approval expires.
+func (s *Service) Resubmit(userID uuid.UUID, sub Submission) (*Outcome, error) {
+	return s.submit(userID, sub, true)
+}
+
+func (s *Service) submit(userID uuid.UUID, sub Submission, resubmission bool) (*Outcome, error) {
+	now := time.Now()
+	if err := s.validate(&sub, now); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	var data models.KYCData
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		err := tx.Where("user_id = ?", userID).First(&data).Error
+		exists := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		switch {
+		case exists && data.ReviewStatus == models.KYCPending && data.SubmissionCount > 0:
+			return ErrReviewInProgress
+		case !resubmission && exists:
+			return ErrAlreadySubmitted
+		case resubmission && !exists:
+			return ErrKYCNotFound
+		case resubmission && user.KYCStatus != models.KYCRejected && user.KYCStatus != models.KYCExpired &&
+			!(user.KYCStatus == models.KYCApproved && user.RequiresKYCRenewal()):
+			return ErrResubmitNotAllowed
+		}
+
+		data.UserID = userID
+		data.DateOfBirth = sub.dateOfBirth
+		data.Nationality = sub.Nationality
+		data.IDType = sub.IDType
+		data.IDNumber = sub.IDNumber
+		data.IDExpiryDate = sub.idExpiry
+		data.AddressLine1 = sub.AddressLine1
+		data.AddressLine2 = sub.AddressLine2
+		data.City = sub.City
+		data.State = sub.State
+		data.PostalCode = sub.PostalCode
+		data.Country = sub.Country
+		data.AddressProofType = sub.AddressProofType
+		data.PoliticallyExposed = sub.PoliticallyExposed
+		data.SourceOfFunds = sub.SourceOfFunds
+		data.PurposeOfAccount = sub.PurposeOfAccount
+		data.ExpectedTransVolume = sub.ExpectedTransVolume
+		data.IDDocumentURL = sub.IDDocumentURL
+		data.AddressProofURL = sub.AddressProofURL
+		data.SelfieURL = sub.SelfieURL
+		data.AdditionalDocsURL = sub.AdditionalDocsURL
+
+		// Screening and review results belong to the previous submission
+		data.AMLCheckStatus = "pending"
+		data.AMLCheckDate = nil
+		data.SanctionsListCheck = false
+		data.WatchlistCheck = false
+		data.ReviewStatus = models.KYCPending
+		data.ReviewedAt = nil
+		data.ReviewedBy = nil
+		data.ReviewComments = ""
+		data.RejectionReason = ""
+		data.SubmissionCount++
+		data.SubmittedAt = &now
+
+		if err := tx.Omit(clause.Associations).Save(&data).Error; err != nil {
+			return err
+		}
+		if user.KYCStatus != models.KYCApproved {
+			user.KYCStatus = models.KYCPending
+			return tx.Model(&user).Update("kyc_status", models.KYCPending).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrKYCNotFound) || errors.Is(err, ErrAlreadySubmitted) ||
+			errors.Is(err, ErrReviewInProgress) || errors.Is(err, ErrResubmitNotAllowed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save KYC submission: %w", err)
+	}
+
+	return s.process(&user, &data, now)
+}
+
+// process runs the automated checks on a stored submission, scores it and either
+// approves it or opens a review case
+func (s *Service) process(user *models.User, data *models.KYCData, now time.Time) (*Outcome, error) {
+	checks, err := s.runChecks(user, data)
+	if err != nil {
+		return nil, err
+	}
+	// Screening records its outcome on the KYC data
+	if err := s.db.First(data, "id = ?", data.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload KYC data: %w", err)
+	}
+
+	a := s.assess(data, checks, now)
+	outcome := &Outcome{KYC: data, Status: user.KYCStatus}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		data.RiskScore = a.score
+		data.ComplianceLevel = complianceLevel(a.level)
+		if err := tx.Model(data).Updates(map[string]interface{}{
+			"risk_score":       data.RiskScore,
+			"compliance_level": data.ComplianceLevel,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"risk_rating":      a.level,
+			"compliance_score": 100 - a.score,
+		}).Error; err != nil {
+			return err
+		}
+
+		if s.opts.AutoApprove && a.level == RiskLow && a.clean {
+			outcome.Status = models.KYCApproved
+			return s.approve(tx, data, a.level, nil, "Approved automatically: low risk and every check passed", now)
+		}
+
+		outcome.InReview = true
+		return tx.Create(&models.KYCReviewCase{
+			KYCID:       data.ID,
+			UserID:      user.ID,
+			Submission:  data.SubmissionCount,
+			RiskScore:   a.score,
+			RiskLevel:   a.level,
+			RiskFactors: a.factors,
+			Status:      models.KYCReviewPending,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to process KYC submission: %w", err)
+	}
+	return outcome, nil
+}
+
+// approve records an approval. The next review is due after the risk level's review
+// period and the approval expires a grace period later, or when the ID document
+// expires if that is sooner.
+func (s *Service) approve(tx *gorm.DB, data *models.KYCData, level string, reviewer *uuid.UUID, note string, now time.Time) error {
+	nextReview := now.Add(s.reviewPeriod(level))
+	expiresAt := nextReview.Add(s.opts.ExpiryGrace)
+	if data.IDExpiryDate.Before(expiresAt) {
+		expiresAt = data.IDExpiryDate
+		nextReview = expiresAt.Add(-s.opts.ExpiryGrace)
+		if nextReview.Before(now) {
+			nextReview = now
+		}
+	}
+
+	data.ReviewStatus = models.KYCApproved
+	data.NextReviewDate = nextReview
+	if err := tx.Model(&models.KYCData{}).Where("id = ?", data.ID).Updates(map[string]interface{}{
+		"review_status":    models.KYCApproved,
+		"reviewed_at":      now,
+		"reviewed_by":      reviewer,
+		"review_comments":  note,
+		"rejection_reason": "",
+		"next_review_date": nextReview,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", data.UserID).Updates(map[string]interface{}{
+		"kyc_status":       models.KYCApproved,
+		"kyc_completed_at": now,
+		"kyc_expires_at":   expiresAt,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ? AND status = ?", data.UserID, models.StatusKYCPending).
+		Update("status", models.StatusActive).Error
+}
+
+func (s *Service) reject(tx *gorm.DB, data *models.KYCData, reviewer *uuid.UUID, reason, note string, now time.Time) error {
+	data.ReviewStatus = models.KYCRejected
+	if err := tx.Model(&models.KYCData{}).Where("id = ?", data.ID).Updates(map[string]interface{}{
+		"review_status":    models.KYCRejected,
+		"reviewed_at":      now,
+		"reviewed_by":      reviewer,
+		"review_comments":  note,
+		"rejection_reason": reason,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", data.UserID).Update("kyc_status", models.KYCRejected).Error
+}
+
+// StatusView is what a customer sees of their KYC
+type StatusView struct {
+	KYCStatus       models.KYCStatus `json:"kyc_status"`
+	KYCID           *uuid.UUID       `json:"kyc_id,omitempty"`
+	ReviewStatus    models.KYCStatus `json:"review_status,omitempty"` // Of the latest submission
+	Submission      int              `json:"submission"`
+	SubmittedAt     *time.Time       `json:"submitted_at,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+	NextReviewDate  *time.Time       `json:"next_review_date,omitempty"`
+	RefreshDueAt    *time.Time       `json:"refresh_due_at,omitempty"` // Verify again by this date to keep KYC
+	RejectionReason string           `json:"rejection_reason,omitempty"`
+	RequiresRenewal bool             `json:"requires_renewal"`
+	CanResubmit     bool             `json:"can_resubmit"`
+}
+
+func (s *Service) Status(userID uuid.UUID) (*StatusView, error) {
+	var user models.User
+	if err := s.db.Preload("KYCData").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	view := &StatusView{
+		KYCStatus:       user.KYCStatus,
+		CompletedAt:     user.KYCCompletedAt,
+		ExpiresAt:       user.KYCExpiresAt,
+		RefreshDueAt:    user.KYCRefreshDueAt,
+		RequiresRenewal: user.KYCStatus == models.KYCApproved && user.RequiresKYCRenewal(),
+	}
+	if data := user.KYCData; data != nil {
+		view.KYCID = &data.ID
+		view.ReviewStatus = data.ReviewStatus
+		view.Submission = data.SubmissionCount
+		view.SubmittedAt = data.SubmittedAt
+		view.RejectionReason = data.RejectionReason
+		if !data.NextReviewDate.IsZero() {
+			view.NextReviewDate = &data.NextReviewDate
+		}
+		view.CanResubmit = data.ReviewStatus != models.KYCPending &&
+			(user.KYCStatus == models.KYCRejected || user.KYCStatus == models.KYCExpired || view.RequiresRenewal)
+	}
+	return view, nil
+}
+
+// Eligibility is whether a customer's KYC allows new financing or investment
+type Eligibility struct {
+	UserID       uuid.UUID        `json:"user_id"`
+	KYCStatus    models.KYCStatus `json:"kyc_status"`
+	Eligible     bool             `json:"eligible"`
+	ExpiresAt    *time.Time       `json:"expires_at,omitempty"`
+	RefreshDueAt *time.Time       `json:"refresh_due_at,omitempty"`
+}
+
+// Eligibility looks a customer up by email for other services
+func (s *Service) Eligibility(email string) (*Eligibility, error) {
+	var user models.User
+	if err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return eligibilityOf(&user), nil
+}
+
+// EligibilityByUser looks a customer up by user ID for other services
+func (s *Service) EligibilityByUser(userID uuid.UUID) (*Eligibility, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return eligibilityOf(&user), nil
+}
+
+func eligibilityOf(user *models.User) *Eligibility {
+	return &Eligibility{
+		UserID:       user.ID,
+		KYCStatus:    user.KYCStatus,
+		Eligible:     user.IsKYCCompliant(),
+		ExpiresAt:    user.KYCExpiresAt,
+		RefreshDueAt: user.KYCRefreshDueAt,
+	}
+}
+
+// InfoUpdate changes the due diligence answers of a submission waiting for review
+type InfoUpdate struct {
+	SourceOfFunds       *string `json:"source_of_funds"`
+	PurposeOfAccount    *string `json:"purpose_of_account"`
+	ExpectedTransVolume *string `json:"expected_transaction_volume"`
+}
+
+// UpdateInfo lets a customer answer a reviewer's questions before the case is decided.
+// Identity data and documents can only change through a resubmission.
+func (s *Service) UpdateInfo(userID uuid.UUID, update InfoUpdate) (*models.KYCData, error) {
+	var data models.KYCData
+	if err := s.db.Where("user_id = ?", userID).First(&data).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKYCNotFound
+		}
+		return nil, fmt.Errorf("failed to get KYC data: %w", err)
+	}
+
+	var open int64
+	if err := s.db.Model(&models.KYCReviewCase{}).
+		Where("kyc_id = ? AND status = ?", data.ID, models.KYCReviewPending).Count(&open).Error; err != nil {
+		return nil, fmt.Errorf("failed to get review case: %w", err)
+	}
+	if data.ReviewStatus != models.KYCPending || open == 0 {
+		return nil, ErrNotEditable
+	}
+
+	updates := map[string]interface{}{}
+	if update.SourceOfFunds != nil {
+		updates["source_of_funds"] = *update.SourceOfFunds
+	}
+	if update.PurposeOfAccount != nil {
+		updates["purpose_of_account"] = *update.PurposeOfAccount
+	}
+	if update.ExpectedTransVolume != nil {
+		updates["expected_trans_volume"] = *update.ExpectedTransVolume
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(&data).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update KYC data: %w", err)
+		}
+	}
+	return &data, nil
+}
+
+// QueueFilter narrows ListQueue
+type QueueFilter struct {
+	Status    string // Open cases when empty
+	RiskLevel string
+	Page      int
+	Limit     int
+}
+
+// ListQueue returns review cases, highest risk first and then oldest first
+func (s *Service) ListQueue(filter QueueFilter) ([]models.KYCReviewCase, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	query := s.db.Model(&models.KYCReviewCase{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		query = query.Where("status IN ?", []models.KYCReviewStatus{models.KYCReviewPending, models.KYCReviewAwaitingApproval})
+	}
+	if filter.RiskLevel != "" {
+		query = query.Where("risk_level = ?", filter.RiskLevel)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count review cases: %w", err)
+	}
+	var cases []models.KYCReviewCase
+	if err := query.Order("risk_score DESC, created_at ASC").
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&cases).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list review cases: %w", err)
+	}
+	return cases, total, nil
+}
+
+// CaseDetail is everything a reviewer needs to decide on a submission
+type CaseDetail struct {
+	Case    *models.KYCReviewCase  `json:"case,omitempty"` // Latest case; unset for automatic approvals
+	KYC     *models.KYCData        `json:"kyc"`
+	Checks  []models.KYCCheck      `json:"checks"` // Of the latest submission
+	History []models.KYCReviewCase `json:"history"`
+}
+
+func (s *Service) GetCase(kycID uuid.UUID) (*CaseDetail, error) {
+	var data models.KYCData
+	if err := s.db.First(&data, "id = ?", kycID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKYCNotFound
+		}
+		return nil, fmt.Errorf("failed to get KYC data: %w", err)
+	}
+
+	detail := &CaseDetail{KYC: &data, Checks: []models.KYCCheck{}, History: []models.KYCReviewCase{}}
+	var cases []models.KYCReviewCase
+	if err := s.db.Where("kyc_id = ?", kycID).Order("created_at DESC").Find(&cases).Error; err != nil {
+		return nil, fmt.Errorf("failed to get review cases: %w", err)
+	}
+	if len(cases) > 0 && cases[0].Submission == data.SubmissionCount {
+		detail.Case = &cases[0]
+		cases = cases[1:]
+	}
+	detail.History = append(detail.History, cases...)
+	if err := s.db.Where("kyc_id = ? AND submission = ?", kycID, data.SubmissionCount).
+		Order("checked_at").Find(&detail.Checks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get KYC checks: %w", err)
+	}
+	return detail, nil
+}
+
+func (s *Service) openCase(kycID uuid.UUID) (*models.KYCReviewCase, error) {
+	var reviewCase models.KYCReviewCase
+	err := s.db.Where("kyc_id = ? AND status IN ?", kycID,
+		[]models.KYCReviewStatus{models.KYCReviewPending, models.KYCReviewAwaitingApproval}).
+		Order("created_at DESC").First(&reviewCase).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCaseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review case: %w", err)
+	}
+	return &reviewCase, nil
+}
+
+// Decide records the first reviewer's decision. It takes effect once a second reviewer
+// approves it.
+func (s *Service) Decide(kycID, reviewer uuid.UUID, decision, note, rejectionReason string) (*models.KYCReviewCase, error) {
+	if (decision != DecisionApprove && decision != DecisionReject) ||
+		(decision == DecisionReject && strings.TrimSpace(rejectionReason) == "") {
+		return nil, ErrInvalidDecision
+	}
+	reviewCase, err := s.openCase(kycID)
+	if err != nil {
+		return nil, err
+	}
+	if reviewCase.Status != models.KYCReviewPending {
+		return nil, ErrCaseChanged
+	}
+	if decision == DecisionApprove {
+		rejectionReason = ""
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.KYCReviewCase{}).
+		Where("id = ? AND status = ?", reviewCase.ID, models.KYCReviewPending).
+		Updates(map[string]interface{}{
+			"status":           models.KYCReviewAwaitingApproval,
+			"decision":         decision,
+			"decision_note":    note,
+			"rejection_reason": rejectionReason,
+			"decided_by":       reviewer,
+			"decided_at":       now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to record KYC decision: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCaseChanged
+	}
+
+	reviewCase.Status = models.KYCReviewAwaitingApproval
+	reviewCase.Decision = decision
+	reviewCase.DecisionNote = note
+	reviewCase.RejectionReason = rejectionReason
+	reviewCase.DecidedBy = &reviewer
+	reviewCase.DecidedAt = &now
+	return reviewCase, nil
+}
+
+// Approve lets a second reviewer confirm the first reviewer's decision, which then
+// applies to the customer, or send the case back to the queue
+func (s *Service) Approve(kycID, checker uuid.UUID, confirm bool, note string) (*models.KYCReviewCase, error) {
+	reviewCase, err := s.openCase(kycID)
+	if err != nil {
+		return nil, err
+	}
+	if reviewCase.Status != models.KYCReviewAwaitingApproval {
+		return nil, ErrCaseChanged
+	}
+	if reviewCase.DecidedBy != nil && *reviewCase.DecidedBy == checker {
+		return nil, ErrSameReviewer
+	}
+
+	now := time.Now()
+	if !confirm {
+		if strings.TrimSpace(note) == "" {
+			return nil, ErrNoteRequired
+		}
+		result := s.db.Model(&models.KYCReviewCase{}).
+			Where("id = ? AND status = ?", reviewCase.ID, models.KYCReviewAwaitingApproval).
+			Updates(map[string]interface{}{
+				"status":           models.KYCReviewPending,
+				"decision":         "",
+				"rejection_reason": "",
+				"decided_by":       nil,
+				"decided_at":       nil,
+				"approval_note":    note,
+				"returned_count":   gorm.Expr("returned_count + 1"),
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to return KYC decision: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrCaseChanged
+		}
+		return s.GetReviewCase(reviewCase.ID)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.KYCReviewCase{}).
+			Where("id = ? AND status = ?", reviewCase.ID, models.KYCReviewAwaitingApproval).
+			Updates(map[string]interface{}{
+				"status":        models.KYCReviewCompleted,
+				"approved_by":   checker,
+				"approved_at":   now,
+				"approval_note": note,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCaseChanged
+		}
+
+		var data models.KYCData
+		if err := tx.First(&data, "id = ?", reviewCase.KYCID).Error; err != nil {
+			return err
+		}
+		if reviewCase.Decision == DecisionApprove {
+			return s.approve(tx, &data, reviewCase.RiskLevel, reviewCase.DecidedBy, reviewCase.DecisionNote, now)
+		}
+		return s.reject(tx, &data, reviewCase.DecidedBy, reviewCase.RejectionReason, reviewCase.DecisionNote, now)
+	})
+	if errors.Is(err, ErrCaseChanged) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply KYC decision: %w", err)
+	}
+	return s.GetReviewCase(reviewCase.ID)
+}
+
+func (s *Service) GetReviewCase(id uuid.UUID) (*models.KYCReviewCase, error) {
+	var reviewCase models.KYCReviewCase
+	if err := s.db.First(&reviewCase, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCaseNotFound
+		}
+		return nil, fmt.Errorf("failed to get review case: %w", err)
+	}
+	return &reviewCase, nil
+}
+
+// ExpireLapsed moves approvals past their expiry to expired and returns how many
+// customers were affected. A renewal still in review keeps its pending review status.
+func (s *Service) ExpireLapsed() (int, error) {
+	var ids []uuid.UUID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("kyc_status = ? AND kyc_expires_at <= ?", models.KYCApproved, time.Now()).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.User{}).Where("id IN ? AND kyc_status = ?", ids, models.KYCApproved).
+			Update("kyc_status", models.KYCExpired).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.KYCData{}).Where("user_id IN ? AND review_status = ?", ids, models.KYCApproved).
+			Update("review_status", models.KYCExpired).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire KYC approvals: %w", err)
+	}
+	return len(ids), nil
+}
+
+// StartExpiryMonitor expires lapsed approvals until ctx is cancelled
+func (s *Service) StartExpiryMonitor(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.ExpiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if expired, err := s.ExpireLapsed(); err != nil {
+			log.Printf("KYC expiry check failed: %v", err)
+		} else if expired > 0 {
+			log.Printf("Expired KYC approval of %d customers", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package kycrefresh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler exposes the re-verification dashboard and campaigns to compliance
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCampaign):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCampaignNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCampaignClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "KYC refresh operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *Handler) GetDashboard(c *gin.Context) {
+	dashboard, err := h.service.Dashboard()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+func (h *Handler) GetRefreshes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	filter := RefreshFilter{
+		Status: c.Query("status"),
+		Reason: c.Query("reason"),
+		Page:   page,
+		Limit:  limit,
+	}
+	if value := c.Query("campaign_id"); value != "" {
+		campaignID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+			return
+		}
+		filter.CampaignID = &campaignID
+	}
+
+	refreshes, total, err := h.service.ListRefreshes(filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"refreshes": refreshes,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+// RunNow runs the refresh cycle without waiting for the scheduler
+func (h *Handler) RunNow(c *gin.Context) {
+	summary, err := h.service.Run()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// CreateCampaign starts a re-verification campaign, or previews it with dry_run
+func (h *Handler) CreateCampaign(c *gin.Context) {
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CreateCampaign(req, adminID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if req.DryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, result)
+}
+
+func (h *Handler) GetCampaigns(c *gin.Context) {
+	campaigns, err := h.service.ListCampaigns(c.Query("status"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+func (h *Handler) GetCampaign(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("campaignId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	progress, err := h.service.GetCampaign(campaignID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+func (h *Handler) CancelCampaign(c *gin.Context) {
+	campaignID, err := uuid.Parse(c.Param("campaignId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	progress, err := h.service.CancelCampaign(campaignID, adminID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
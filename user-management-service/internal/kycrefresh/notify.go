@@ -0,0 +1,83 @@
+package kycrefresh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notices sent to customers
+const (
+	NoticeReminder   = "kyc_refresh_reminder"
+	NoticeRestricted = "kyc_restricted"
+)
+
+// Notifier delivers re-verification notices to a customer
+type Notifier interface {
+	Notify(userID uuid.UUID, notice string, data map[string]interface{}) error
+}
+
+// httpNotifier sends notices by email through the notification service
+type httpNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (n *httpNotifier) Notify(userID uuid.UUID, notice string, data map[string]interface{}) error {
+	payload := map[string]interface{}{}
+	for key, value := range data {
+		payload[key] = value
+	}
+	payload["notice"] = notice
+
+	body, err := json.Marshal(map[string]interface{}{
+		"recipient_id":      userID.String(),
+		"notification_type": "kyc_refresh",
+		"channels":          []string{"email", "in_app"},
+		"priority":          "high",
+		"data":              payload,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(n.url, "/")+"/api/v1/notifications/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s notice: %w", notice, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send %s notice: notification service returned %s", notice, resp.Status)
+	}
+	return nil
+}
+
+// logNotifier is used when NOTIFICATION_SERVICE_URL is not set
+type logNotifier struct{}
+
+func (logNotifier) Notify(userID uuid.UUID, notice string, data map[string]interface{}) error {
+	log.Printf("KYC refresh notice %s for user %s: %v", notice, userID, data)
+	return nil
+}
+
+func newNotifier(opts Options) Notifier {
+	if opts.NotificationURL == "" {
+		return logNotifier{}
+	}
+	return &httpNotifier{url: opts.NotificationURL, token: opts.NotificationToken, client: &http.Client{Timeout: 10 * time.Second}}
+}
@@ -0,0 +1,711 @@
+package kycrefresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCampaignClosed   = errors.New("the campaign is no longer active")
+	ErrInvalidCampaign  = errors.New("invalid campaign")
+)
+
+// Options configures the refresh scheduler
+type Options struct {
+	RunInterval       time.Duration
+	ReminderDays      []int // Days before the deadline a reminder is sent, largest first
+	NotificationURL   string
+	NotificationToken string
+}
+
+// OptionsFromEnv reads KYC_REFRESH_INTERVAL, KYC_REFRESH_REMINDER_DAYS,
+// NOTIFICATION_SERVICE_URL and NOTIFICATION_SERVICE_TOKEN
+func OptionsFromEnv() Options {
+	opts := Options{
+		RunInterval:       6 * time.Hour,
+		ReminderDays:      []int{60, 30, 14, 7, 1},
+		NotificationURL:   os.Getenv("NOTIFICATION_SERVICE_URL"),
+		NotificationToken: os.Getenv("NOTIFICATION_SERVICE_TOKEN"),
+	}
+	if value, err := time.ParseDuration(os.Getenv("KYC_REFRESH_INTERVAL")); err == nil && value > 0 {
+		opts.RunInterval = value
+	}
+	if value := os.Getenv("KYC_REFRESH_REMINDER_DAYS"); value != "" {
+		var days []int
+		for _, field := range strings.Split(value, ",") {
+			if day, err := strconv.Atoi(strings.TrimSpace(field)); err == nil && day > 0 {
+				days = append(days, day)
+			}
+		}
+		if len(days) > 0 {
+			opts.ReminderDays = days
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(opts.ReminderDays)))
+	return opts
+}
+
+// Service asks customers to verify again before their KYC review date or ID document
+// expiry, or when a campaign selects them. Customers get staged reminders and their KYC
+// expires when the deadline passes, which stops new financing and investment.
+type Service struct {
+	db       *gorm.DB
+	opts     Options
+	notifier Notifier
+
+	runMu   sync.Mutex // One run at a time
+	mu      sync.Mutex
+	lastRun *RunSummary
+}
+
+func NewService(db *gorm.DB, opts Options) *Service {
+	return &Service{db: db, opts: opts, notifier: newNotifier(opts)}
+}
+
+// SetNotifier replaces how customers are notified
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// RunSummary is what one scheduler run did
+type RunSummary struct {
+	StartedAt         time.Time `json:"started_at"`
+	Completed         int       `json:"completed"`
+	Opened            int       `json:"opened"`
+	RemindersSent     int       `json:"reminders_sent"`
+	Restricted        int       `json:"restricted"`
+	CampaignsFinished int       `json:"campaigns_finished"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// Run closes refreshes of customers who were approved again, opens refreshes for
+// approvals and ID documents expiring within the first reminder stage, sends due
+// reminders and restricts customers whose deadline passed
+func (s *Service) Run() (*RunSummary, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	now := time.Now()
+	summary := &RunSummary{StartedAt: now}
+	err := s.run(summary, now)
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	s.mu.Lock()
+	s.lastRun = summary
+	s.mu.Unlock()
+	return summary, err
+}
+
+func (s *Service) run(summary *RunSummary, now time.Time) error {
+	var err error
+	if summary.Completed, err = s.completeRefreshed(now); err != nil {
+		return err
+	}
+	if summary.Opened, err = s.openDue(now); err != nil {
+		return err
+	}
+	if summary.RemindersSent, err = s.sendReminders(now); err != nil {
+		return err
+	}
+	if summary.Restricted, err = s.restrictOverdue(now); err != nil {
+		return err
+	}
+	summary.CampaignsFinished, err = s.finishCampaigns()
+	return err
+}
+
+// completeRefreshed closes open refreshes of customers approved again since the refresh
+// was opened
+func (s *Service) completeRefreshed(now time.Time) (int, error) {
+	var refreshes []models.KYCRefresh
+	if err := s.db.Joins("JOIN users ON users.id = kyc_refreshes.user_id").
+		Where("kyc_refreshes.status = ? AND users.kyc_status = ? AND users.kyc_completed_at > kyc_refreshes.created_at",
+			models.KYCRefreshOpen, models.KYCApproved).
+		Find(&refreshes).Error; err != nil {
+		return 0, fmt.Errorf("failed to find refreshed customers: %w", err)
+	}
+
+	completed := 0
+	for _, refresh := range refreshes {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.KYCRefresh{}).Where("id = ? AND status = ?", refresh.ID, models.KYCRefreshOpen).
+				Updates(map[string]interface{}{"status": models.KYCRefreshCompleted, "completed_at": now})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			completed++
+			return tx.Model(&models.User{}).Where("id = ?", refresh.UserID).Update("kyc_refresh_due_at", nil).Error
+		})
+		if err != nil {
+			return completed, fmt.Errorf("failed to complete KYC refresh: %w", err)
+		}
+	}
+	return completed, nil
+}
+
+// openDue opens a refresh for approved customers whose approval or ID document expires
+// within the first reminder stage. The deadline is whichever comes first.
+func (s *Service) openDue(now time.Time) (int, error) {
+	horizon := now.AddDate(0, 0, s.opts.ReminderDays[0])
+	var candidates []struct {
+		ID           uuid.UUID
+		KYCExpiresAt *time.Time
+		IDExpiryDate time.Time
+	}
+	if err := s.db.Table("users").
+		Select("users.id, users.kyc_expires_at, kyc_data.id_expiry_date").
+		Joins("JOIN kyc_data ON kyc_data.user_id = users.id AND kyc_data.deleted_at IS NULL").
+		Where("users.deleted_at IS NULL AND users.kyc_status = ?", models.KYCApproved).
+		Where("(users.kyc_expires_at <= ? OR kyc_data.id_expiry_date <= ?)", horizon, horizon).
+		Where("NOT EXISTS (SELECT 1 FROM kyc_refreshes WHERE kyc_refreshes.user_id = users.id AND kyc_refreshes.status = ?)",
+			models.KYCRefreshOpen).
+		Scan(&candidates).Error; err != nil {
+		return 0, fmt.Errorf("failed to find customers due for KYC refresh: %w", err)
+	}
+
+	opened := 0
+	for _, candidate := range candidates {
+		refresh := models.KYCRefresh{
+			UserID:   candidate.ID,
+			Reason:   models.KYCRefreshReviewDue,
+			Status:   models.KYCRefreshOpen,
+			Deadline: candidate.IDExpiryDate,
+		}
+		if candidate.KYCExpiresAt != nil && candidate.KYCExpiresAt.Before(candidate.IDExpiryDate) {
+			refresh.Deadline = *candidate.KYCExpiresAt
+		} else {
+			refresh.Reason = models.KYCRefreshDocumentExpiring
+		}
+		if err := s.open(s.db, &refresh); err != nil {
+			return opened, err
+		}
+		opened++
+	}
+	return opened, nil
+}
+
+// open stores a refresh and records its deadline on the customer, which lets them
+// resubmit KYC before their approval runs out
+func (s *Service) open(db *gorm.DB, refresh *models.KYCRefresh) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(refresh).Error; err != nil {
+			return fmt.Errorf("failed to open KYC refresh: %w", err)
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", refresh.UserID).
+			Update("kyc_refresh_due_at", refresh.Deadline).Error; err != nil {
+			return fmt.Errorf("failed to open KYC refresh: %w", err)
+		}
+		return nil
+	})
+}
+
+// stagesPassed counts the reminder stages that have started by now
+func (s *Service) stagesPassed(deadline, now time.Time) int {
+	passed := 0
+	for _, days := range s.opts.ReminderDays {
+		if !now.Before(deadline.AddDate(0, 0, -days)) {
+			passed++
+		}
+	}
+	return passed
+}
+
+// sendReminders notifies customers whose refresh entered a new reminder stage. Stages
+// missed while the scheduler was down, or already passed when a campaign opened the
+// refresh, are covered by one reminder.
+func (s *Service) sendReminders(now time.Time) (int, error) {
+	var refreshes []models.KYCRefresh
+	if err := s.db.Where("status = ? AND deadline > ?", models.KYCRefreshOpen, now).Find(&refreshes).Error; err != nil {
+		return 0, fmt.Errorf("failed to find open KYC refreshes: %w", err)
+	}
+
+	sent := 0
+	for _, refresh := range refreshes {
+		passed := s.stagesPassed(refresh.Deadline, now)
+		if passed <= refresh.RemindersSent {
+			continue
+		}
+		err := s.notifier.Notify(refresh.UserID, NoticeReminder, map[string]interface{}{
+			"reason":    refresh.Reason,
+			"deadline":  refresh.Deadline,
+			"days_left": int(refresh.Deadline.Sub(now).Hours()/24) + 1,
+			"final":     passed == len(s.opts.ReminderDays),
+		})
+		if err != nil {
+			// Retried on the next run
+			log.Printf("KYC refresh reminder for user %s failed: %v", refresh.UserID, err)
+			continue
+		}
+		if err := s.db.Model(&models.KYCRefresh{}).Where("id = ?", refresh.ID).Updates(map[string]interface{}{
+			"reminders_sent":   passed,
+			"last_reminder_at": now,
+		}).Error; err != nil {
+			return sent, fmt.Errorf("failed to record KYC refresh reminder: %w", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// restrictOverdue expires the KYC of customers who did not verify again by the deadline
+func (s *Service) restrictOverdue(now time.Time) (int, error) {
+	var refreshes []models.KYCRefresh
+	if err := s.db.Where("status = ? AND deadline <= ?", models.KYCRefreshOpen, now).Find(&refreshes).Error; err != nil {
+		return 0, fmt.Errorf("failed to find overdue KYC refreshes: %w", err)
+	}
+
+	restricted := 0
+	for _, refresh := range refreshes {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.KYCRefresh{}).Where("id = ? AND status = ?", refresh.ID, models.KYCRefreshOpen).
+				Updates(map[string]interface{}{"status": models.KYCRefreshRestricted, "restricted_at": now})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", refresh.UserID).
+				Update("kyc_refresh_due_at", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.User{}).Where("id = ? AND kyc_status = ?", refresh.UserID, models.KYCApproved).
+				Update("kyc_status", models.KYCExpired).Error; err != nil {
+				return err
+			}
+			// A resubmission waiting for review is left for the reviewers
+			if err := tx.Model(&models.KYCData{}).Where("user_id = ? AND review_status = ?", refresh.UserID, models.KYCApproved).
+				Update("review_status", models.KYCExpired).Error; err != nil {
+				return err
+			}
+			restricted++
+			return nil
+		})
+		if err != nil {
+			return restricted, fmt.Errorf("failed to restrict customer after KYC refresh deadline: %w", err)
+		}
+
+		if err := s.notifier.Notify(refresh.UserID, NoticeRestricted, map[string]interface{}{
+			"reason":   refresh.Reason,
+			"deadline": refresh.Deadline,
+		}); err != nil {
+			log.Printf("KYC restriction notice for user %s failed: %v", refresh.UserID, err)
+		}
+	}
+	return restricted, nil
+}
+
+// finishCampaigns marks active campaigns without open refreshes as finished
+func (s *Service) finishCampaigns() (int, error) {
+	result := s.db.Model(&models.KYCRefreshCampaign{}).
+		Where("status = ?", models.KYCCampaignActive).
+		Where("NOT EXISTS (SELECT 1 FROM kyc_refreshes WHERE kyc_refreshes.campaign_id = kyc_refresh_campaigns.id AND kyc_refreshes.status = ?)",
+			models.KYCRefreshOpen).
+		Update("status", models.KYCCampaignFinished)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to finish KYC refresh campaigns: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// StartScheduler runs the refresh cycle until ctx is cancelled
+func (s *Service) StartScheduler(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.RunInterval)
+	defer ticker.Stop()
+
+	for {
+		if summary, err := s.Run(); err != nil {
+			log.Printf("KYC refresh run failed: %v", err)
+		} else if summary.Opened+summary.RemindersSent+summary.Restricted > 0 {
+			log.Printf("KYC refresh: %d opened, %d reminders sent, %d restricted, %d completed",
+				summary.Opened, summary.RemindersSent, summary.Restricted, summary.Completed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CampaignRequest selects approved customers to verify again. Customers match when their
+// risk rating is one of RiskRatings and their nationality or country of residence is one
+// of Countries; an empty list matches everyone.
+type CampaignRequest struct {
+	Name         string   `json:"name" binding:"required,max=200"`
+	Description  string   `json:"description"`
+	RiskRatings  []string `json:"risk_ratings"`
+	Countries    []string `json:"countries"`
+	DeadlineDays int      `json:"deadline_days" binding:"required,min=7,max=365"`
+	DryRun       bool     `json:"dry_run"` // Only count the customers who would be selected
+}
+
+// CampaignResult is the outcome of creating, or previewing, a campaign
+type CampaignResult struct {
+	Campaign          *models.KYCRefreshCampaign `json:"campaign,omitempty"` // Unset for a dry run
+	Matched           int                        `json:"matched"`
+	AlreadyRefreshing int                        `json:"already_refreshing"` // Skipped: they already have a refresh open
+	Deadline          time.Time                  `json:"deadline"`
+}
+
+func (s *Service) campaignCandidates(req *CampaignRequest) ([]uuid.UUID, []uuid.UUID, error) {
+	for i, rating := range req.RiskRatings {
+		req.RiskRatings[i] = strings.ToLower(strings.TrimSpace(rating))
+		if rating = req.RiskRatings[i]; rating != "low" && rating != "medium" && rating != "high" {
+			return nil, nil, fmt.Errorf("%w: risk ratings are low, medium and high", ErrInvalidCampaign)
+		}
+	}
+	for i, country := range req.Countries {
+		req.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+		if len(req.Countries[i]) != 2 {
+			return nil, nil, fmt.Errorf("%w: countries must be ISO codes", ErrInvalidCampaign)
+		}
+	}
+
+	query := s.db.Table("users").
+		Joins("JOIN kyc_data ON kyc_data.user_id = users.id AND kyc_data.deleted_at IS NULL").
+		Where("users.deleted_at IS NULL AND users.kyc_status = ?", models.KYCApproved)
+	if len(req.RiskRatings) > 0 {
+		query = query.Where("users.risk_rating IN ?", req.RiskRatings)
+	}
+	if len(req.Countries) > 0 {
+		query = query.Where("(kyc_data.country IN ? OR kyc_data.nationality IN ?)", req.Countries, req.Countries)
+	}
+
+	var matched []uuid.UUID
+	if err := query.Pluck("users.id", &matched).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to select campaign customers: %w", err)
+	}
+	var refreshing []uuid.UUID
+	if len(matched) > 0 {
+		if err := s.db.Model(&models.KYCRefresh{}).Where("user_id IN ? AND status = ?", matched, models.KYCRefreshOpen).
+			Pluck("user_id", &refreshing).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to select campaign customers: %w", err)
+		}
+	}
+	busy := make(map[uuid.UUID]bool, len(refreshing))
+	for _, id := range refreshing {
+		busy[id] = true
+	}
+	selected := make([]uuid.UUID, 0, len(matched))
+	for _, id := range matched {
+		if !busy[id] {
+			selected = append(selected, id)
+		}
+	}
+	return selected, refreshing, nil
+}
+
+// CreateCampaign asks the selected customers to verify again by the deadline. The first
+// reminder goes out on the next scheduler run.
+func (s *Service) CreateCampaign(req CampaignRequest, adminID uuid.UUID) (*CampaignResult, error) {
+	selected, refreshing, err := s.campaignCandidates(&req)
+	if err != nil {
+		return nil, err
+	}
+	result := &CampaignResult{
+		Matched:           len(selected),
+		AlreadyRefreshing: len(refreshing),
+		Deadline:          time.Now().AddDate(0, 0, req.DeadlineDays),
+	}
+	if req.DryRun {
+		return result, nil
+	}
+
+	campaign := &models.KYCRefreshCampaign{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		RiskRatings: req.RiskRatings,
+		Countries:   req.Countries,
+		Deadline:    result.Deadline,
+		Status:      models.KYCCampaignActive,
+		Matched:     len(selected),
+		CreatedBy:   adminID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return fmt.Errorf("failed to create campaign: %w", err)
+		}
+		for _, userID := range selected {
+			campaignID := campaign.ID
+			if err := s.open(tx, &models.KYCRefresh{
+				UserID:     userID,
+				CampaignID: &campaignID,
+				Reason:     models.KYCRefreshByCampaign,
+				Status:     models.KYCRefreshOpen,
+				Deadline:   campaign.Deadline,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("KYC refresh campaign %q created by %s for %d customers", campaign.Name, adminID, len(selected))
+	result.Campaign = campaign
+	return result, nil
+}
+
+// CampaignProgress counts a campaign's refreshes by status
+type CampaignProgress struct {
+	Campaign   models.KYCRefreshCampaign `json:"campaign"`
+	Open       int64                     `json:"open"`
+	Completed  int64                     `json:"completed"`
+	Restricted int64                     `json:"restricted"`
+	Cancelled  int64                     `json:"cancelled"`
+}
+
+func (s *Service) progress(campaign models.KYCRefreshCampaign) (*CampaignProgress, error) {
+	var counts []struct {
+		Status models.KYCRefreshStatus
+		Count  int64
+	}
+	if err := s.db.Model(&models.KYCRefresh{}).Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaign.ID).Group("status").Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count campaign refreshes: %w", err)
+	}
+	progress := &CampaignProgress{Campaign: campaign}
+	for _, count := range counts {
+		switch count.Status {
+		case models.KYCRefreshOpen:
+			progress.Open = count.Count
+		case models.KYCRefreshCompleted:
+			progress.Completed = count.Count
+		case models.KYCRefreshRestricted:
+			progress.Restricted = count.Count
+		case models.KYCRefreshCancelled:
+			progress.Cancelled = count.Count
+		}
+	}
+	return progress, nil
+}
+
+// ListCampaigns returns campaigns with their progress, newest first
+func (s *Service) ListCampaigns(status string) ([]CampaignProgress, error) {
+	query := s.db.Order("created_at DESC").Limit(100)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var campaigns []models.KYCRefreshCampaign
+	if err := query.Find(&campaigns).Error; err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	list := make([]CampaignProgress, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		progress, err := s.progress(campaign)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *progress)
+	}
+	return list, nil
+}
+
+func (s *Service) GetCampaign(id uuid.UUID) (*CampaignProgress, error) {
+	var campaign models.KYCRefreshCampaign
+	if err := s.db.First(&campaign, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return s.progress(campaign)
+}
+
+// CancelCampaign withdraws the campaign's open refreshes. Customers already restricted
+// stay restricted until they verify again.
+func (s *Service) CancelCampaign(id, adminID uuid.UUID) (*CampaignProgress, error) {
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.KYCRefreshCampaign{}).Where("id = ? AND status = ?", id, models.KYCCampaignActive).
+			Updates(map[string]interface{}{
+				"status":       models.KYCCampaignCancelled,
+				"cancelled_by": adminID,
+				"cancelled_at": now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to cancel campaign: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&models.KYCRefreshCampaign{}).Where("id = ?", id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrCampaignNotFound
+			}
+			return ErrCampaignClosed
+		}
+
+		var userIDs []uuid.UUID
+		if err := tx.Model(&models.KYCRefresh{}).Where("campaign_id = ? AND status = ?", id, models.KYCRefreshOpen).
+			Pluck("user_id", &userIDs).Error; err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.KYCRefresh{}).Where("campaign_id = ? AND status = ?", id, models.KYCRefreshOpen).
+			Updates(map[string]interface{}{"status": models.KYCRefreshCancelled, "cancelled_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id IN ?", userIDs).Update("kyc_refresh_due_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCampaign(id)
+}
+
+// RefreshFilter selects refreshes
+type RefreshFilter struct {
+	Status     string
+	Reason     string
+	CampaignID *uuid.UUID
+	Page       int
+	Limit      int
+}
+
+// RefreshView is a refresh with the customer it belongs to
+type RefreshView struct {
+	models.KYCRefresh
+	Email      string `json:"email"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	RiskRating string `json:"risk_rating"`
+}
+
+// ListRefreshes returns refreshes by deadline, soonest first
+func (s *Service) ListRefreshes(filter RefreshFilter) ([]RefreshView, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	query := s.db.Model(&models.KYCRefresh{})
+	if filter.Status != "" {
+		query = query.Where("kyc_refreshes.status = ?", filter.Status)
+	}
+	if filter.Reason != "" {
+		query = query.Where("kyc_refreshes.reason = ?", filter.Reason)
+	}
+	if filter.CampaignID != nil {
+		query = query.Where("kyc_refreshes.campaign_id = ?", *filter.CampaignID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count KYC refreshes: %w", err)
+	}
+	var refreshes []RefreshView
+	if err := query.Select("kyc_refreshes.*, users.email, users.first_name, users.last_name, users.risk_rating").
+		Joins("JOIN users ON users.id = kyc_refreshes.user_id").
+		Order("kyc_refreshes.deadline ASC").
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).
+		Scan(&refreshes).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list KYC refreshes: %w", err)
+	}
+	return refreshes, total, nil
+}
+
+// Dashboard summarises re-verification for compliance
+type Dashboard struct {
+	Open             int64              `json:"open"`
+	DueWithin7Days   int64              `json:"due_within_7_days"`
+	DueWithin30Days  int64              `json:"due_within_30_days"`
+	Completed30Days  int64              `json:"completed_last_30_days"`
+	Restricted30Days int64              `json:"restricted_last_30_days"`
+	OpenByReason     map[string]int64   `json:"open_by_reason"`
+	OpenByRiskRating map[string]int64   `json:"open_by_risk_rating"`
+	ExpiredCustomers int64              `json:"expired_customers"` // KYC expired and not resubmitted
+	Campaigns        []CampaignProgress `json:"active_campaigns"`
+	Upcoming         []RefreshView      `json:"upcoming"`
+	LastRun          *RunSummary        `json:"last_run,omitempty"`
+	ReminderDays     []int              `json:"reminder_days"`
+}
+
+func (s *Service) Dashboard() (*Dashboard, error) {
+	now := time.Now()
+	monthAgo := now.AddDate(0, 0, -30)
+	d := &Dashboard{
+		OpenByReason:     map[string]int64{},
+		OpenByRiskRating: map[string]int64{},
+		ReminderDays:     s.opts.ReminderDays,
+	}
+
+	counts := []struct {
+		target *int64
+		query  *gorm.DB
+	}{
+		{&d.Open, s.db.Model(&models.KYCRefresh{}).Where("status = ?", models.KYCRefreshOpen)},
+		{&d.DueWithin7Days, s.db.Model(&models.KYCRefresh{}).Where("status = ? AND deadline <= ?", models.KYCRefreshOpen, now.AddDate(0, 0, 7))},
+		{&d.DueWithin30Days, s.db.Model(&models.KYCRefresh{}).Where("status = ? AND deadline <= ?", models.KYCRefreshOpen, now.AddDate(0, 0, 30))},
+		{&d.Completed30Days, s.db.Model(&models.KYCRefresh{}).Where("status = ? AND completed_at >= ?", models.KYCRefreshCompleted, monthAgo)},
+		{&d.Restricted30Days, s.db.Model(&models.KYCRefresh{}).Where("status = ? AND restricted_at >= ?", models.KYCRefreshRestricted, monthAgo)},
+		{&d.ExpiredCustomers, s.db.Model(&models.User{}).Where("kyc_status = ?", models.KYCExpired)},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.target).Error; err != nil {
+			return nil, fmt.Errorf("failed to build KYC refresh dashboard: %w", err)
+		}
+	}
+
+	var byReason []struct {
+		Reason string
+		Count  int64
+	}
+	if err := s.db.Model(&models.KYCRefresh{}).Select("reason, COUNT(*) AS count").
+		Where("status = ?", models.KYCRefreshOpen).Group("reason").Scan(&byReason).Error; err != nil {
+		return nil, fmt.Errorf("failed to build KYC refresh dashboard: %w", err)
+	}
+	for _, row := range byReason {
+		d.OpenByReason[row.Reason] = row.Count
+	}
+	var byRating []struct {
+		RiskRating string
+		Count      int64
+	}
+	if err := s.db.Model(&models.KYCRefresh{}).Select("users.risk_rating, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = kyc_refreshes.user_id").
+		Where("kyc_refreshes.status = ?", models.KYCRefreshOpen).Group("users.risk_rating").Scan(&byRating).Error; err != nil {
+		return nil, fmt.Errorf("failed to build KYC refresh dashboard: %w", err)
+	}
+	for _, row := range byRating {
+		rating := row.RiskRating
+		if rating == "" {
+			rating = "unrated"
+		}
+		d.OpenByRiskRating[rating] = row.Count
+	}
+
+	campaigns, err := s.ListCampaigns(string(models.KYCCampaignActive))
+	if err != nil {
+		return nil, err
+	}
+	d.Campaigns = campaigns
+	if d.Upcoming, _, err = s.ListRefreshes(RefreshFilter{Status: string(models.KYCRefreshOpen), Limit: 20}); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	d.LastRun = s.lastRun
+	s.mu.Unlock()
+	return d, nil
+}
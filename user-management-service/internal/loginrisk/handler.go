@@ -0,0 +1,264 @@
+package loginrisk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"user-management-service/internal/mfa"
+	"user-management-service/internal/models"
+)
+
+// Handler exposes the login gate, emailed login confirmation, login history and devices
+// over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid confirmation code"})
+	case errors.Is(err, ErrTooManyAttempts), errors.Is(err, mfa.ErrChallengeInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, mfa.ErrMethodNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, mfa.ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active"})
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+	case errors.Is(err, ErrDeliveryFailed):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login security operation failed"})
+	}
+}
+
+// respondInvalidCredentials answers unknown emails, wrong passwords and locked accounts
+// alike, so the response does not tell whether an email has an account. Users learn of a
+// lockout from the emailed alert.
+func respondInvalidCredentials(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	c.Abort()
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// Gate runs first on the password login. It records every attempt, rejects unknown
+// emails, wrong passwords and locked accounts with the same response, counts wrong
+// passwords towards the lockout and scores the login. Users with
+// MFA are passed on to the MFA gate, which challenges them anyway; users without MFA
+// whose login scores at or above the threshold get an emailed code instead of tokens.
+func (h *Handler) Gate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if json.Unmarshal(body, &req) != nil || req.Email == "" || req.Password == "" {
+			c.Next()
+			return
+		}
+		ipAddress, userAgent := c.ClientIP(), c.Request.UserAgent()
+
+		user, err := h.service.findUser(req.Email)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				a := h.service.Assess(nil, req.Email, ipAddress, userAgent)
+				h.service.record(nil, req.Email, ipAddress, userAgent, false, FailUnknownAccount, a)
+				respondInvalidCredentials(c)
+				return
+			}
+			c.Next()
+			return
+		}
+		a := h.service.Assess(user, user.Email, ipAddress, userAgent)
+
+		if until := LockedUntil(user, time.Now()); until != nil {
+			h.service.record(user, user.Email, ipAddress, userAgent, false, FailAccountLocked, a)
+			respondInvalidCredentials(c)
+			return
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+			h.service.record(user, user.Email, ipAddress, userAgent, false, FailInvalidPassword, a)
+			lockedUntil, err := h.service.registerFailure(user.ID)
+			if err != nil {
+				log.Println(err)
+			}
+			if lockedUntil != nil {
+				h.service.alertLocked(user, ipAddress, *lockedUntil)
+			}
+			respondInvalidCredentials(c)
+			return
+		}
+		if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+			c.Next()
+			return
+		}
+
+		if len(h.service.mfa.LoginMethods(user)) > 0 {
+			h.service.record(user, user.Email, ipAddress, userAgent, false, FailSecondFactorRequired, a)
+			c.Next()
+			return
+		}
+		if h.service.engine.RequiresConfirmation(a) {
+			challenge, err := h.service.StartConfirmation(user, ipAddress, userAgent)
+			if err != nil {
+				respondError(c, err)
+				c.Abort()
+				return
+			}
+			h.service.record(user, user.Email, ipAddress, userAgent, false, FailConfirmationRequired, a)
+			c.JSON(http.StatusOK, gin.H{
+				"confirmation_required": true,
+				"mfa_token":             challenge.Token,
+				"methods":               challenge.Methods,
+				"expires_at":            challenge.ExpiresAt,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+		if c.Writer.Status() == http.StatusOK {
+			h.service.recordSuccess(user, ipAddress, userAgent, a)
+		}
+	}
+}
+
+// ConfirmLogin completes a risky login with the code emailed to the user
+func (h *Handler) ConfirmLogin(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.ConfirmLogin(req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// GetLoginHistory returns the caller's recent login attempts
+func (h *Handler) GetLoginHistory(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	history, err := h.service.LoginHistory(userID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"login_history": history})
+}
+
+func (h *Handler) GetDevices(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	devices, err := h.service.Devices(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+func (h *Handler) ForgetDevice(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	deviceID, err := uuid.Parse(c.Param("deviceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	if err := h.service.ForgetDevice(userID, deviceID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device removed"})
+}
+
+// GetUserLoginHistory returns a user's recent login attempts for admins
+func (h *Handler) GetUserLoginHistory(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	history, err := h.service.LoginHistory(userID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"login_history": history})
+}
+
+// UnlockAccount lifts a login lockout before it runs out
+func (h *Handler) UnlockAccount(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.service.UnlockAccount(userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
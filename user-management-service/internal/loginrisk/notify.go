@@ -0,0 +1,85 @@
+package loginrisk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alerts sent to users
+const (
+	AlertNewDevice         = "new_device_login"
+	AlertLoginConfirmation = "login_confirmation"
+	AlertAccountLocked     = "account_locked"
+)
+
+// Notifier delivers security alerts to a user
+type Notifier interface {
+	Notify(userID uuid.UUID, alert string, data map[string]interface{}) error
+}
+
+// httpNotifier sends alerts by email through the notification service
+type httpNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (n *httpNotifier) Notify(userID uuid.UUID, alert string, data map[string]interface{}) error {
+	payload := map[string]interface{}{}
+	for key, value := range data {
+		payload[key] = value
+	}
+	payload["alert"] = alert
+
+	body, err := json.Marshal(map[string]interface{}{
+		"recipient_id":      userID.String(),
+		"notification_type": "security_alert",
+		"channels":          []string{"email"},
+		"priority":          "high",
+		"data":              payload,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(n.url, "/")+"/api/v1/notifications/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s alert: %w", alert, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send %s alert: notification service returned %s", alert, resp.Status)
+	}
+	return nil
+}
+
+// logNotifier is used when NOTIFICATION_SERVICE_URL is not set, so development setups can
+// read confirmation codes from the log
+type logNotifier struct{}
+
+func (logNotifier) Notify(userID uuid.UUID, alert string, data map[string]interface{}) error {
+	log.Printf("Security alert %s for user %s: %v", alert, userID, data)
+	return nil
+}
+
+func newNotifier(opts Options) Notifier {
+	if opts.NotificationURL == "" {
+		return logNotifier{}
+	}
+	return &httpNotifier{url: opts.NotificationURL, token: opts.NotificationToken, client: &http.Client{Timeout: 10 * time.Second}}
+}
@@ -0,0 +1,75 @@
+package loginrisk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	engine "shared/loginrisk"
+
+	"user-management-service/internal/models"
+)
+
+// Assessment is the risk of one login attempt, scored by the engine the backend uses as
+// well
+type Assessment = engine.Assessment
+
+// Assess scores a login attempt by user, which is nil for unknown accounts, from ipAddress
+func (s *Service) Assess(user *models.User, email, ipAddress, userAgent string) *Assessment {
+	var account *engine.Account
+	if user != nil {
+		account = &engine.Account{ID: user.ID, FailedLogins: user.LoginFailedAttempts}
+	}
+	return s.engine.Assess(context.Background(), account, ipAddress, userAgent)
+}
+
+// store reads the login history the engine scores against
+type store struct {
+	db *gorm.DB
+}
+
+func (st *store) FailedAccounts(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	var accounts int64
+	err := st.db.WithContext(ctx).Model(&models.LoginHistory{}).
+		Where("ip_address = ? AND success = ? AND fail_reason IN ? AND attempted_at > ?", ipAddress, false, engine.CredentialFailures, since).
+		Distinct("email").Count(&accounts).Error
+	return int(accounts), err
+}
+
+func (st *store) FailedAddresses(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error) {
+	var addresses int64
+	err := st.db.WithContext(ctx).Model(&models.LoginHistory{}).
+		Where("user_id = ? AND success = ? AND fail_reason IN ? AND attempted_at > ?", accountID, false, engine.CredentialFailures, since).
+		Distinct("ip_address").Count(&addresses).Error
+	return int(addresses), err
+}
+
+func (st *store) KnownDevices(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	var fingerprints []string
+	err := st.db.WithContext(ctx).Model(&models.KnownDevice{}).Where("user_id = ?", accountID).Pluck("fingerprint", &fingerprints).Error
+	return fingerprints, err
+}
+
+func (st *store) LoginCountries(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	var countries []string
+	err := st.db.WithContext(ctx).Model(&models.LoginHistory{}).
+		Where("user_id = ? AND success = ? AND country <> ''", accountID, true).
+		Distinct().Pluck("country", &countries).Error
+	return countries, err
+}
+
+func (st *store) LastLocatedLogin(ctx context.Context, accountID uuid.UUID) (*engine.LocatedLogin, error) {
+	var last models.LoginHistory
+	err := st.db.WithContext(ctx).Where("user_id = ? AND success = ? AND latitude IS NOT NULL AND longitude IS NOT NULL", accountID, true).
+		Order("attempted_at DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &engine.LocatedLogin{Latitude: *last.Latitude, Longitude: *last.Longitude, AttemptedAt: last.AttemptedAt}, nil
+}
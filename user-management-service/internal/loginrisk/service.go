@@ -0,0 +1,450 @@
+package loginrisk
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	engine "shared/loginrisk"
+
+	"user-management-service/internal/mfa"
+	"user-management-service/internal/models"
+)
+
+// Reasons recorded on failed and blocked attempts
+const (
+	FailInvalidPassword      = engine.FailInvalidPassword
+	FailUnknownAccount       = engine.FailUnknownAccount
+	FailAccountLocked        = engine.FailAccountLocked
+	FailSecondFactorRequired = engine.FailSecondFactorRequired // Password accepted, MFA challenge issued
+	FailConfirmationRequired = engine.FailConfirmationRequired // Password accepted, emailed code required
+	FailConfirmationFailed   = engine.FailConfirmationFailed
+)
+
+// Wrong confirmation codes allowed before the login has to be started again
+const confirmationAttempts = 5
+
+var (
+	ErrAccountLocked   = errors.New("account is temporarily locked after too many failed logins")
+	ErrDeviceNotFound  = errors.New("device not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrDeliveryFailed  = errors.New("the confirmation code could not be sent")
+	ErrInvalidCode     = errors.New("invalid confirmation code")
+	ErrTooManyAttempts = errors.New("too many wrong confirmation codes, sign in again")
+)
+
+// Options configures lockout, risk scoring and alerts
+type Options struct {
+	GeoIPDatabase      string        // Path of a GeoLite2/GeoIP2 City .mmdb file; geolocation is off without one
+	MaxAttempts        int           // Wrong passwords before the account is locked
+	LockoutBase        time.Duration // First lockout; each consecutive lockout doubles it
+	LockoutMax         time.Duration
+	ChallengeThreshold int     // Risk score that requires MFA or an emailed code
+	MaxTravelSpeed     float64 // km/h between logins above which travel is impossible
+	StuffingWindow     time.Duration
+	StuffingAccounts   int // Accounts failing from one IP, or IPs failing on one account, within the window
+	NotificationURL    string
+	NotificationToken  string
+}
+
+// OptionsFromEnv reads GEOIP_DATABASE_PATH, LOGIN_MAX_ATTEMPTS, LOGIN_LOCKOUT_BASE,
+// LOGIN_LOCKOUT_MAX, LOGIN_RISK_THRESHOLD, LOGIN_MAX_TRAVEL_SPEED, LOGIN_STUFFING_WINDOW,
+// LOGIN_STUFFING_ACCOUNTS, NOTIFICATION_SERVICE_URL and NOTIFICATION_SERVICE_TOKEN
+func OptionsFromEnv() Options {
+	opts := Options{
+		GeoIPDatabase:      os.Getenv("GEOIP_DATABASE_PATH"),
+		MaxAttempts:        5,
+		LockoutBase:        time.Minute,
+		LockoutMax:         24 * time.Hour,
+		ChallengeThreshold: 50,
+		MaxTravelSpeed:     900, // Faster than a commercial flight
+		StuffingWindow:     15 * time.Minute,
+		StuffingAccounts:   5,
+		NotificationURL:    os.Getenv("NOTIFICATION_SERVICE_URL"),
+		NotificationToken:  os.Getenv("NOTIFICATION_SERVICE_TOKEN"),
+	}
+	for key, target := range map[string]*int{
+		"LOGIN_MAX_ATTEMPTS":      &opts.MaxAttempts,
+		"LOGIN_RISK_THRESHOLD":    &opts.ChallengeThreshold,
+		"LOGIN_STUFFING_ACCOUNTS": &opts.StuffingAccounts,
+	} {
+		if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+			*target = value
+		}
+	}
+	for key, target := range map[string]*time.Duration{
+		"LOGIN_LOCKOUT_BASE":    &opts.LockoutBase,
+		"LOGIN_LOCKOUT_MAX":     &opts.LockoutMax,
+		"LOGIN_STUFFING_WINDOW": &opts.StuffingWindow,
+	} {
+		if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+			*target = value
+		}
+	}
+	if value, err := strconv.ParseFloat(os.Getenv("LOGIN_MAX_TRAVEL_SPEED"), 64); err == nil && value > 0 {
+		opts.MaxTravelSpeed = value
+	}
+	return opts
+}
+
+// Service records every login attempt, locks accounts after repeated wrong passwords,
+// scores logins for risk and tracks the devices users sign in from
+type Service struct {
+	db       *gorm.DB
+	mfa      *mfa.Service
+	opts     Options
+	engine   *engine.Engine
+	geoErr   error
+	notifier Notifier
+}
+
+// NewService opens the GeoIP database and registers the service as the recorder of logins
+// completed through MFA and passkeys
+func NewService(db *gorm.DB, mfaService *mfa.Service, opts Options) *Service {
+	s := &Service{db: db, mfa: mfaService, opts: opts, notifier: newNotifier(opts)}
+	var locator *engine.Locator
+	if opts.GeoIPDatabase != "" {
+		locator, s.geoErr = engine.OpenLocator(opts.GeoIPDatabase)
+	} else {
+		s.geoErr = errors.New("GEOIP_DATABASE_PATH is not set")
+	}
+	s.engine = engine.NewEngine(&store{db: db}, locator, engine.Options{
+		MaxAttempts:        opts.MaxAttempts,
+		LockoutBase:        opts.LockoutBase,
+		LockoutMax:         opts.LockoutMax,
+		ChallengeThreshold: opts.ChallengeThreshold,
+		MaxTravelSpeed:     opts.MaxTravelSpeed,
+		StuffingWindow:     opts.StuffingWindow,
+		StuffingAccounts:   opts.StuffingAccounts,
+	})
+	mfaService.SetLoginRecorder(s)
+	return s
+}
+
+// GeolocationReady reports why logins are not being geolocated, if they are not. New
+// country and impossible travel are only scored with a GeoIP database.
+func (s *Service) GeolocationReady() error {
+	return s.geoErr
+}
+
+func (s *Service) Close() error {
+	return s.engine.Close()
+}
+
+func (s *Service) findUser(email string) (*models.User, error) {
+	var user models.User
+	err := s.db.Where("LOWER(email) = ?", normalizeEmail(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LockedUntil returns when the user's lockout ends, or nil when they may sign in
+func LockedUntil(user *models.User, now time.Time) *time.Time {
+	return engine.LockedUntil(user.LoginLockedUntil, now)
+}
+
+// registerFailure counts a wrong password with the user row locked and returns when the
+// account became locked, if this attempt locked it
+func (s *Service) registerFailure(userID uuid.UUID) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		next, until := s.engine.RegisterFailure(engine.Lockout{
+			FailedLogins: user.LoginFailedAttempts,
+			LockedUntil:  user.LoginLockedUntil,
+			Lockouts:     user.LoginLockoutCount,
+		}, time.Now())
+		lockedUntil = until
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"login_failed_attempts": next.FailedLogins,
+			"login_locked_until":    next.LockedUntil,
+			"login_lockout_count":   next.Lockouts,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	return lockedUntil, nil
+}
+
+// alertLocked tells the user their account was locked, since someone may be guessing
+// their password
+func (s *Service) alertLocked(user *models.User, ipAddress string, until time.Time) {
+	data := engine.LockoutAlert(ipAddress, until)
+	go func() {
+		if err := s.notifier.Notify(user.ID, AlertAccountLocked, data); err != nil {
+			log.Printf("Failed to alert user %s of a lockout: %v", user.ID, err)
+		}
+	}()
+}
+
+// UnlockAccount clears a lockout and the failure count behind it
+func (s *Service) UnlockAccount(userID uuid.UUID) error {
+	res := s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"login_failed_attempts": 0,
+		"login_locked_until":    nil,
+		"login_lockout_count":   0,
+	})
+	if res.Error != nil {
+		return fmt.Errorf("failed to unlock account: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// record writes one login history row
+func (s *Service) record(user *models.User, email, ipAddress, userAgent string, success bool, failReason string, a *Assessment) *models.LoginHistory {
+	entry := &models.LoginHistory{
+		Email:              normalizeEmail(email),
+		IPAddress:          ipAddress,
+		UserAgent:          userAgent,
+		Success:            success,
+		FailReason:         failReason,
+		AttemptedAt:        time.Now(),
+		SuspiciousActivity: s.engine.RequiresConfirmation(a),
+		RiskScore:          a.Score,
+		RiskFactors:        a.Factors,
+	}
+	if user != nil {
+		entry.UserID = &user.ID
+		entry.Email = user.Email
+	}
+	if failReason == FailAccountLocked {
+		entry.BlockedReason = FailAccountLocked
+	}
+	if location := a.Location; location != nil {
+		entry.Country = location.Country
+		entry.City = location.City
+		entry.Location = strings.Trim(location.City+", "+location.Country, ", ")
+		if location.HasCoordinates {
+			entry.Latitude = &location.Latitude
+			entry.Longitude = &location.Longitude
+		}
+	}
+	if success {
+		entry.DeviceID = s.rememberDevice(user, ipAddress, a)
+	}
+	if err := s.db.Omit("User").Create(entry).Error; err != nil {
+		log.Printf("Failed to record login attempt for %s: %v", entry.Email, err)
+	}
+	return entry
+}
+
+// RecordLogin records a login attempt completed outside the password form, such as a
+// second factor or a passkey. It satisfies mfa.LoginRecorder.
+func (s *Service) RecordLogin(userID uuid.UUID, ipAddress, userAgent string, success bool, failReason string) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		log.Printf("Failed to record login attempt for user %s: %v", userID, err)
+		return
+	}
+	a := s.Assess(&user, user.Email, ipAddress, userAgent)
+	if success {
+		s.recordSuccess(&user, ipAddress, userAgent, a)
+		return
+	}
+	s.record(&user, user.Email, ipAddress, userAgent, false, failReason, a)
+}
+
+// recordSuccess clears the user's failure count, remembers the device and alerts the user
+// when it is new
+func (s *Service) recordSuccess(user *models.User, ipAddress, userAgent string, a *Assessment) {
+	s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"login_failed_attempts": 0,
+		"login_locked_until":    nil,
+		"login_lockout_count":   0,
+	})
+	entry := s.record(user, user.Email, ipAddress, userAgent, true, "", a)
+
+	if !a.Has(engine.FactorNewDevice) {
+		return
+	}
+	data := engine.NewDeviceAlert(a, ipAddress, entry.AttemptedAt)
+	go func() {
+		if err := s.notifier.Notify(user.ID, AlertNewDevice, data); err != nil {
+			log.Printf("Failed to alert user %s of a new device: %v", user.ID, err)
+		}
+	}()
+}
+
+// rememberDevice adds the device to the user's known devices or updates when it was last
+// used
+func (s *Service) rememberDevice(user *models.User, ipAddress string, a *Assessment) *uuid.UUID {
+	now := time.Now()
+	seen := a.Device(ipAddress)
+	device := models.KnownDevice{
+		UserID:      user.ID,
+		Fingerprint: seen.Fingerprint,
+		Label:       seen.Label,
+		LastIP:      seen.IPAddress,
+		LastCountry: seen.Country,
+		LastCity:    seen.City,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"label", "last_ip", "last_country", "last_city", "last_seen_at"}),
+	}).Create(&device).Error
+	if err != nil {
+		log.Printf("Failed to remember device for user %s: %v", user.ID, err)
+		return nil
+	}
+	// The insert may have been turned into an update, which leaves the ID unset
+	if err := s.db.Where("user_id = ? AND fingerprint = ?", user.ID, seen.Fingerprint).First(&device).Error; err != nil {
+		return nil
+	}
+	return &device.ID
+}
+
+// StartConfirmation opens a login challenge completed with a code emailed to the user
+func (s *Service) StartConfirmation(user *models.User, ipAddress, userAgent string) (*mfa.Challenge, error) {
+	challenge, err := s.mfa.StartLogin(user, []string{mfa.MethodEmail}, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.mfa.PendingChallenge(challenge.Token, mfa.MethodEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := confirmationCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate confirmation code: %w", err)
+	}
+	confirmation := &models.LoginConfirmation{
+		ChallengeID: pending.ID,
+		UserID:      user.ID,
+		CodeHash:    hashCode(pending.ID, code),
+		ExpiresAt:   pending.ExpiresAt,
+	}
+	if err := s.db.Create(confirmation).Error; err != nil {
+		return nil, fmt.Errorf("failed to store confirmation code: %w", err)
+	}
+
+	err = s.notifier.Notify(user.ID, AlertLoginConfirmation, map[string]interface{}{
+		"code":       code,
+		"device":     engine.DescribeDevice(userAgent),
+		"ip_address": ipAddress,
+		"expires_at": confirmation.ExpiresAt,
+	})
+	if err != nil {
+		log.Printf("Failed to send login confirmation to user %s: %v", user.ID, err)
+		return nil, ErrDeliveryFailed
+	}
+	return challenge, nil
+}
+
+// ConfirmLogin completes a confirmation challenge with the emailed code and opens the
+// session
+func (s *Service) ConfirmLogin(token, code, ipAddress, userAgent string) (*mfa.Session, error) {
+	challenge, err := s.mfa.PendingChallenge(token, mfa.MethodEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	// The attempt is counted before the code is compared so parallel guesses cannot
+	// exceed the limit
+	res := s.db.Model(&models.LoginConfirmation{}).
+		Where("challenge_id = ? AND confirmed_at IS NULL AND attempts < ?", challenge.ID, confirmationAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to check confirmation code: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrTooManyAttempts
+	}
+	var confirmation models.LoginConfirmation
+	if err := s.db.Where("challenge_id = ?", challenge.ID).First(&confirmation).Error; err != nil {
+		return nil, fmt.Errorf("failed to get confirmation code: %w", err)
+	}
+	if !hmac.Equal([]byte(hashCode(challenge.ID, strings.TrimSpace(code))), []byte(confirmation.CodeHash)) {
+		s.RecordLogin(challenge.UserID, ipAddress, userAgent, false, FailConfirmationFailed)
+		return nil, ErrInvalidCode
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ?", challenge.UserID).First(&user).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	now := time.Now()
+	s.db.Model(&confirmation).Update("confirmed_at", now)
+	return s.mfa.CompleteChallenge(challenge, &user, ipAddress, userAgent, []string{"pwd", "email"})
+}
+
+// confirmationCode returns six random digits
+func confirmationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashCode binds a code to its challenge so equal codes do not hash alike
+func hashCode(challengeID uuid.UUID, code string) string {
+	mac := hmac.New(sha256.New, []byte(challengeID.String()))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LoginHistory returns the user's most recent login attempts
+func (s *Service) LoginHistory(userID uuid.UUID, limit int) ([]models.LoginHistory, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	history := []models.LoginHistory{}
+	err := s.db.Where("user_id = ?", userID).Order("attempted_at DESC").Limit(limit).Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login history: %w", err)
+	}
+	return history, nil
+}
+
+// Devices returns the devices the user has signed in from, most recent first
+func (s *Service) Devices(userID uuid.UUID) ([]models.KnownDevice, error) {
+	devices := []models.KnownDevice{}
+	if err := s.db.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to get devices: %w", err)
+	}
+	return devices, nil
+}
+
+// ForgetDevice removes a known device, so the next login from it counts as new
+func (s *Service) ForgetDevice(userID, deviceID uuid.UUID) error {
+	res := s.db.Where("id = ? AND user_id = ?", deviceID, userID).Delete(&models.KnownDevice{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to forget device: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	secretPrefix    = "v1:" // Marks the encryption scheme of a stored secret
+	backupCodeCount = 10
+)
+
+// Backup codes avoid characters that are easily misread
+const backupCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// sealer encrypts TOTP secrets at rest with AES-256-GCM
+type sealer struct {
+	aead cipher.AEAD
+}
+
+// newSealer accepts a 32 byte key encoded as base64 or hex
+func newSealer(encoded string) (*sealer, error) {
+	if encoded == "" {
+		return nil, ErrEncryptionKeyMissing
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		key, err = hex.DecodeString(encoded)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%w: MFA_ENCRYPTION_KEY must be 32 bytes in base64 or hex", ErrEncryptionKeyMissing)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead: aead}, nil
+}
+
+func (s *sealer) seal(plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *sealer) open(stored string) (string, error) {
+	if !strings.HasPrefix(stored, secretPrefix) {
+		return "", errors.New("unsupported secret encoding")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, secretPrefix))
+	if err != nil {
+		return "", err
+	}
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return "", errors.New("secret is truncated")
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// generateBackupCodes returns new codes in plain text, shown to the user once, and their
+// bcrypt hashes for storage
+func generateBackupCodes() ([]string, []string, error) {
+	codes := make([]string, backupCodeCount)
+	hashes := make([]string, backupCodeCount)
+	for i := range codes {
+		var code strings.Builder
+		for length := 0; length < 10; {
+			var b [1]byte
+			if _, err := rand.Read(b[:]); err != nil {
+				return nil, nil, err
+			}
+			// Bytes past the last whole multiple of the alphabet would bias the code
+			if int(b[0]) >= 256-256%len(backupCodeAlphabet) {
+				continue
+			}
+			if length == 5 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(backupCodeAlphabet[int(b[0])%len(backupCodeAlphabet)])
+			length++
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(code.String()), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = code.String()
+		hashes[i] = string(hash)
+	}
+	return codes, hashes, nil
+}
+
+// matchBackupCode returns the index of the stored hash matching code, or -1
+func matchBackupCode(hashes []string, code string) int {
+	code = normalizeBackupCode(code)
+	if len(code) != 11 {
+		return -1
+	}
+	for i, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeBackupCode accepts codes typed in upper case, with spaces or without the dash
+func normalizeBackupCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,396 @@
+package mfa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"user-management-service/internal/models"
+)
+
+// Handler exposes MFA enrollment, the second login step and step-up over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	var ssoRequired *SSORequiredError
+	switch {
+	case errors.As(err, &ssoRequired):
+		RespondSSORequired(c, ssoRequired)
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+	case errors.Is(err, ErrChallengeInvalid), errors.Is(err, ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later"})
+	case errors.Is(err, ErrMethodNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active"})
+	case errors.Is(err, ErrAlreadyEnabled), errors.Is(err, ErrNotEnabled), errors.Is(err, ErrNoPendingEnrollment):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrEncryptionKeyMissing):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MFA is not available"})
+	case errors.Is(err, ErrSessionRevoked):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked or has expired", "code": "session_revoked"})
+	case errors.Is(err, ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	case errors.Is(err, ErrRegistryUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sessions cannot be checked right now"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "MFA operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+type codeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Setup starts enrollment and returns the secret and its provisioning URI
+func (h *Handler) Setup(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.service.BeginEnrollment(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Verify confirms enrollment with a code from the authenticator and returns the backup
+// codes
+func (h *Handler) Verify(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_enabled":  true,
+		"backup_codes": codes,
+		"message":      "Store the backup codes somewhere safe; they will not be shown again",
+	})
+}
+
+func (h *Handler) Disable(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Disable(userID, req.Code); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mfa_enabled": false})
+}
+
+// GetBackupCodes reports how many backup codes are left; the codes themselves are only
+// stored hashed
+func (h *Handler) GetBackupCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	remaining, err := h.service.BackupCodesRemaining(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"remaining": remaining})
+}
+
+// RegenerateBackupCodes replaces the backup codes after a valid TOTP code
+func (h *Handler) RegenerateBackupCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.RegenerateBackupCodes(userID, req.Code)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backup_codes": codes})
+}
+
+// StepUp exchanges a fresh TOTP code for an access token carrying a new MFA assertion,
+// which sensitive operations require
+func (h *Handler) StepUp(c *gin.Context) {
+	var req codeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, mfaAt, err := h.service.StepUp(c.GetHeader("Authorization"), req.Code)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":       token,
+		"mfa_at":      mfaAt,
+		"valid_until": mfaAt.Add(h.service.opts.StepUpMaxAge),
+		"step_up_ttl": int64(h.service.opts.StepUpMaxAge.Seconds()),
+	})
+}
+
+// LoginGate runs before the password login handler. Accounts with TOTP or a passkey whose
+// password is correct get an MFA challenge instead of tokens, and active accounts without
+// either get their session opened here so it is registered like every other login. Every
+// other request is passed on unchanged so the login handler keeps reporting failures.
+func (h *Handler) LoginGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if json.Unmarshal(body, &req) != nil || req.Email == "" || req.Password == "" {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		err = h.service.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error
+		if err != nil {
+			c.Next()
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+			c.Next()
+			return
+		}
+		methods := h.service.LoginMethods(&user)
+		if len(methods) == 0 {
+			if user.Status != models.StatusActive {
+				c.Next()
+				return
+			}
+			session, err := h.service.OpenSession(&user, c.ClientIP(), c.Request.UserAgent(), []string{"pwd"})
+			if err != nil {
+				respondError(c, err)
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusOK, session)
+			c.Abort()
+			return
+		}
+		if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+			respondError(c, ErrAccountInactive)
+			c.Abort()
+			return
+		}
+
+		challenge, err := h.service.StartLogin(&user, methods, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    challenge.Token,
+			"methods":      challenge.Methods,
+			"expires_at":   challenge.ExpiresAt,
+		})
+		c.Abort()
+	}
+}
+
+// CompleteLogin is the second login step with a TOTP or backup code. Passkeys complete the
+// challenge through the WebAuthn endpoints.
+func (h *Handler) CompleteLogin(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.CompleteLogin(req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// RequireStepUp rejects requests whose token lacks an MFA assertion made within
+// STEP_UP_MAX_AGE. Clients answer the 403 by calling /mfa/step-up, or the passkey
+// step-up under /users/webauthn, and retrying.
+func (h *Handler) RequireStepUp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := h.service.ParseToken(c.GetHeader("Authorization"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		if !h.service.RecentlyVerified(claims) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":            "Step-up authentication required",
+				"step_up_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSession rejects tokens whose session has been revoked or timed out, wherever
+// that happened. It runs after JWTAuth.
+func (h *Handler) RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := h.service.ParseToken(c.GetHeader("Authorization"))
+		if err == nil {
+			err = h.service.ValidateSession(claims)
+		}
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Set("sessionID", fmt.Sprint(claims["session_id"]))
+		c.Next()
+	}
+}
+
+// Refresh exchanges a refresh token for new tokens of the same session
+func (h *Handler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.Refresh(req.RefreshToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// Logout ends the session of the token presented
+func (h *Handler) Logout(c *gin.Context) {
+	claims, err := h.service.ParseToken(c.GetHeader("Authorization"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	userID, err := uuid.Parse(fmt.Sprint(claims["user_id"]))
+	if err != nil {
+		respondError(c, ErrInvalidToken)
+		return
+	}
+	sessionID, err := uuid.Parse(fmt.Sprint(claims["session_id"]))
+	if err != nil {
+		respondError(c, ErrInvalidToken)
+		return
+	}
+
+	if err := h.service.RevokeSession(userID, sessionID, RevokeLogout); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
+}
+
+// RevokeSession signs out one of the caller's sessions, including sessions of password
+// logins in the backend
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := h.service.RevokeSession(userID, sessionID, RevokeByUser); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
@@ -0,0 +1,732 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrEncryptionKeyMissing = errors.New("MFA encryption key is not configured")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrAlreadyEnabled       = errors.New("MFA is already enabled")
+	ErrNotEnabled           = errors.New("MFA is not enabled")
+	ErrNoPendingEnrollment  = errors.New("MFA setup has not been started")
+	ErrInvalidCode          = errors.New("invalid verification code")
+	ErrLocked               = errors.New("too many failed verification attempts")
+	ErrChallengeInvalid     = errors.New("MFA challenge is invalid or expired")
+	ErrAccountInactive      = errors.New("account is not active")
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrMethodNotAllowed     = errors.New("this verification method is not allowed for the account")
+)
+
+// Options configures enrollment, login challenges and step-up
+type Options struct {
+	Issuer          string        // Shown by authenticator apps
+	EncryptionKey   string        // 32 byte AES key for stored secrets, base64 or hex
+	ChallengeTTL    time.Duration // How long the second login step may take
+	StepUpMaxAge    time.Duration // How recent an MFA assertion must be for sensitive operations
+	MaxAttempts     int           // Failed codes before verification is locked
+	LockoutDuration time.Duration
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// SessionRegistryURL is the backend whose session store decides whether sessions are
+	// still valid; without it sessions are only checked in this service
+	SessionRegistryURL   string
+	SessionRegistryToken string
+	// PasskeyRequiredRoles must complete login and step-up with a passkey once they have one
+	PasskeyRequiredRoles []string
+}
+
+// OptionsFromEnv reads MFA_ISSUER, MFA_ENCRYPTION_KEY, MFA_CHALLENGE_TTL, STEP_UP_MAX_AGE,
+// MFA_MAX_ATTEMPTS, MFA_LOCKOUT_DURATION, JWT_EXPIRATION, JWT_REFRESH_EXPIRATION,
+// SESSION_REGISTRY_URL, SESSION_REGISTRY_TOKEN and PASSKEY_REQUIRED_ROLES
+func OptionsFromEnv() Options {
+	opts := Options{
+		Issuer:          "Invoice Financing Platform",
+		EncryptionKey:   os.Getenv("MFA_ENCRYPTION_KEY"),
+		ChallengeTTL:    5 * time.Minute,
+		StepUpMaxAge:    5 * time.Minute,
+		MaxAttempts:     5,
+		LockoutDuration: 15 * time.Minute,
+		AccessTokenTTL:  24 * time.Hour,
+		RefreshTokenTTL: 7 * 24 * time.Hour,
+		// Bank users are required to use phishing-resistant authenticators
+		PasskeyRequiredRoles: []string{string(models.RoleBank)},
+		SessionRegistryURL:   os.Getenv("SESSION_REGISTRY_URL"),
+		SessionRegistryToken: os.Getenv("SESSION_REGISTRY_TOKEN"),
+	}
+	if value := os.Getenv("PASSKEY_REQUIRED_ROLES"); value != "" {
+		opts.PasskeyRequiredRoles = strings.Split(value, ",")
+	}
+	if value := os.Getenv("MFA_ISSUER"); value != "" {
+		opts.Issuer = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("MFA_MAX_ATTEMPTS")); err == nil && value > 0 {
+		opts.MaxAttempts = value
+	}
+	for key, target := range map[string]*time.Duration{
+		"MFA_CHALLENGE_TTL":      &opts.ChallengeTTL,
+		"STEP_UP_MAX_AGE":        &opts.StepUpMaxAge,
+		"MFA_LOCKOUT_DURATION":   &opts.LockoutDuration,
+		"JWT_EXPIRATION":         &opts.AccessTokenTTL,
+		"JWT_REFRESH_EXPIRATION": &opts.RefreshTokenTTL,
+	} {
+		if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+			*target = value
+		}
+	}
+	return opts
+}
+
+// Enrollment is returned when setup starts. The secret is shown once so it can be typed
+// in when the QR code cannot be scanned.
+type Enrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // Encode as a QR code for authenticator apps
+	Issuer          string `json:"issuer"`
+	Account         string `json:"account"`
+}
+
+// Challenge is the pending second step of a login
+type Challenge struct {
+	Token     string    `json:"mfa_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Methods   []string  `json:"methods"`
+}
+
+// Session is a completed login
+type Session struct {
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in"`
+	User         *models.User `json:"user"`
+}
+
+// LoginRecorder stores login attempts in place of the plain login history row
+type LoginRecorder interface {
+	RecordLogin(userID uuid.UUID, ipAddress, userAgent string, success bool, failReason string)
+}
+
+// Service implements TOTP enrollment, two step login and step-up authentication
+type Service struct {
+	db        *gorm.DB
+	jwtSecret string
+	opts      Options
+	sealer    *sealer
+	sealerErr error
+	recorder  LoginRecorder
+	sessions  SessionRegistry
+	ssoPolicy SSOPolicy
+}
+
+func NewService(db *gorm.DB, jwtSecret string, opts Options) *Service {
+	s := &Service{db: db, jwtSecret: jwtSecret, opts: opts, sessions: newSessionRegistry(db, opts)}
+	s.sealer, s.sealerErr = newSealer(opts.EncryptionKey)
+	return s
+}
+
+// SetLoginRecorder routes every login recorded by the service, including passkey logins,
+// through recorder
+func (s *Service) SetLoginRecorder(recorder LoginRecorder) {
+	s.recorder = recorder
+}
+
+// Ready reports whether secrets can be encrypted; without a key MFA cannot be set up or
+// verified
+func (s *Service) Ready() error {
+	return s.sealerErr
+}
+
+// SealSecret encrypts another secret kept at rest, such as an SSO client secret, with the
+// MFA key
+func (s *Service) SealSecret(plaintext string) (string, error) {
+	if s.sealerErr != nil {
+		return "", s.sealerErr
+	}
+	return s.sealer.seal(plaintext)
+}
+
+// OpenSecret decrypts a secret sealed by SealSecret
+func (s *Service) OpenSecret(stored string) (string, error) {
+	if s.sealerErr != nil {
+		return "", s.sealerErr
+	}
+	return s.sealer.open(stored)
+}
+
+func (s *Service) getUser(userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// BeginEnrollment generates a new secret for the user. MFA stays off until a code from
+// the authenticator is confirmed; starting again replaces an unconfirmed secret.
+func (s *Service) BeginEnrollment(userID uuid.UUID) (*Enrollment, error) {
+	if s.sealerErr != nil {
+		return nil, s.sealerErr
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MFA secret: %w", err)
+	}
+	sealed, err := s.sealer.seal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt MFA secret: %w", err)
+	}
+	err = s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"mfa_secret":         sealed,
+		"mfa_last_used_step": 0,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store MFA secret: %w", err)
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningURI: provisioningURI(s.opts.Issuer, user.Email, secret),
+		Issuer:          s.opts.Issuer,
+		Account:         user.Email,
+	}, nil
+}
+
+// ConfirmEnrollment turns MFA on once the user proves the authenticator works and returns
+// the backup codes, which are not shown again
+func (s *Service) ConfirmEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, ErrNoPendingEnrollment
+	}
+	if _, err := s.verifyCode(userID, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateBackupCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate backup codes: %w", err)
+	}
+	now := time.Now()
+	err = s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"mfa_enabled":     true,
+		"mfa_enrolled_at": now,
+		"backup_codes":    hashes,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	return codes, nil
+}
+
+// Disable turns MFA off after a valid TOTP or backup code
+func (s *Service) Disable(userID uuid.UUID, code string) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrNotEnabled
+	}
+	if _, err := s.verifyCode(userID, code, true); err != nil {
+		return err
+	}
+
+	err = s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"mfa_enabled":        false,
+		"mfa_secret":         "",
+		"backup_codes":       nil,
+		"mfa_enrolled_at":    nil,
+		"mfa_last_used_step": 0,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	return nil
+}
+
+// RegenerateBackupCodes replaces every backup code after a valid TOTP code
+func (s *Service) RegenerateBackupCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ErrNotEnabled
+	}
+	if _, err := s.verifyCode(userID, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateBackupCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate backup codes: %w", err)
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Update("backup_codes", hashes).Error; err != nil {
+		return nil, fmt.Errorf("failed to store backup codes: %w", err)
+	}
+	return codes, nil
+}
+
+// BackupCodesRemaining returns how many unused backup codes the user has
+func (s *Service) BackupCodesRemaining(userID uuid.UUID) (int, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	if !user.MFAEnabled {
+		return 0, ErrNotEnabled
+	}
+	return len(user.BackupCodes), nil
+}
+
+// verifyCode checks a TOTP code, or a backup code when allowBackup is set, with the user
+// row locked so a code cannot be accepted twice by concurrent requests. Failures count
+// towards a temporary lockout.
+func (s *Service) verifyCode(userID uuid.UUID, code string, allowBackup bool) (*models.User, error) {
+	if s.sealerErr != nil {
+		return nil, s.sealerErr
+	}
+
+	var user models.User
+	var result error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result = ErrUserNotFound
+				return nil
+			}
+			return err
+		}
+		now := time.Now()
+		if user.MFALockedUntil != nil && now.Before(*user.MFALockedUntil) {
+			result = ErrLocked
+			return nil
+		}
+		if user.MFASecret == "" {
+			result = ErrNotEnabled
+			return nil
+		}
+		secret, err := s.sealer.open(user.MFASecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt MFA secret: %w", err)
+		}
+
+		updates := map[string]interface{}{"mfa_failed_attempts": 0, "mfa_locked_until": nil}
+		backup := -1
+		if allowBackup {
+			backup = matchBackupCode(user.BackupCodes, code)
+		}
+		if step, ok := validateTOTP(secret, code, now, user.MFALastUsedStep); ok {
+			updates["mfa_last_used_step"] = step
+			user.MFALastUsedStep = step
+		} else if backup >= 0 {
+			// Backup codes are single-use
+			remaining := append(append([]string{}, user.BackupCodes[:backup]...), user.BackupCodes[backup+1:]...)
+			updates["backup_codes"] = remaining
+			user.BackupCodes = remaining
+		} else {
+			result = ErrInvalidCode
+			updates = map[string]interface{}{"mfa_failed_attempts": user.MFAFailedAttempts + 1}
+			if user.MFAFailedAttempts+1 >= s.opts.MaxAttempts {
+				updates = map[string]interface{}{"mfa_failed_attempts": 0, "mfa_locked_until": now.Add(s.opts.LockoutDuration)}
+				result = ErrLocked
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify MFA code: %w", err)
+	}
+	if result != nil {
+		return nil, result
+	}
+	return &user, nil
+}
+
+// Second step methods
+const (
+	MethodTOTP       = "totp"
+	MethodBackupCode = "backup_code"
+	MethodWebAuthn   = "webauthn"
+	MethodEmail      = "email" // Emailed code for a risky login by a user without MFA
+)
+
+func (s *Service) hasPasskey(userID uuid.UUID) bool {
+	var count int64
+	s.db.Model(&models.WebAuthnCredential{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	return count > 0
+}
+
+// requiresPasskey reports whether the user's role must use a phishing-resistant factor.
+// Until their first passkey is registered these users sign in as before so they can
+// enroll one.
+func (s *Service) requiresPasskey(user *models.User) bool {
+	return s.PasskeyRequiredFor(user) && s.hasPasskey(user.ID)
+}
+
+// PasskeyRequiredFor reports whether the user's role is listed in PASSKEY_REQUIRED_ROLES
+func (s *Service) PasskeyRequiredFor(user *models.User) bool {
+	for _, role := range s.opts.PasskeyRequiredRoles {
+		if string(user.Role) == strings.TrimSpace(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginMethods returns the second step methods open to the user, or none when a password
+// alone signs them in
+func (s *Service) LoginMethods(user *models.User) []string {
+	passkey := s.hasPasskey(user.ID)
+	if passkey && s.PasskeyRequiredFor(user) {
+		return []string{MethodWebAuthn}
+	}
+	var methods []string
+	if user.MFAEnabled {
+		methods = append(methods, MethodTOTP)
+		if len(user.BackupCodes) > 0 {
+			methods = append(methods, MethodBackupCode)
+		}
+	}
+	if passkey {
+		methods = append(methods, MethodWebAuthn)
+	}
+	return methods
+}
+
+// StartLogin records the first login step of a user whose password has been checked and
+// returns the challenge to complete with one of methods
+func (s *Service) StartLogin(user *models.User, methods []string, ipAddress, userAgent string) (*Challenge, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate MFA challenge: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	challenge := &models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		Methods:   methods,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(s.opts.ChallengeTTL),
+	}
+	if err := s.db.Create(challenge).Error; err != nil {
+		return nil, fmt.Errorf("failed to store MFA challenge: %w", err)
+	}
+	return &Challenge{Token: token, ExpiresAt: challenge.ExpiresAt, Methods: methods}, nil
+}
+
+// PendingChallenge returns the open challenge for token if it can be completed with method
+func (s *Service) PendingChallenge(token, method string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+	err := s.db.Where("token_hash = ? AND consumed_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChallengeInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA challenge: %w", err)
+	}
+	for _, allowed := range challenge.Methods {
+		if allowed == method {
+			return &challenge, nil
+		}
+	}
+	return nil, ErrMethodNotAllowed
+}
+
+// CompleteLogin verifies a TOTP or backup code for a challenge and opens the session.
+// Wrong codes count towards the user's MFA lockout.
+func (s *Service) CompleteLogin(token, code, ipAddress, userAgent string) (*Session, error) {
+	challenge, err := s.PendingChallenge(token, MethodTOTP)
+	if err != nil {
+		return nil, err
+	}
+
+	allowBackup := false
+	for _, method := range challenge.Methods {
+		allowBackup = allowBackup || method == MethodBackupCode
+	}
+	user, err := s.verifyCode(challenge.UserID, code, allowBackup)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrLocked) {
+			s.RecordLogin(challenge.UserID, ipAddress, userAgent, false, "mfa_failed")
+		}
+		return nil, err
+	}
+	return s.CompleteChallenge(challenge, user, ipAddress, userAgent, []string{"pwd", "otp"})
+}
+
+// CompleteChallenge consumes a challenge whose second factor has been verified and opens
+// the session. A challenge can be completed once.
+func (s *Service) CompleteChallenge(challenge *models.MFAChallenge, user *models.User, ipAddress, userAgent string, amr []string) (*Session, error) {
+	if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+		return nil, ErrAccountInactive
+	}
+
+	// Consuming the challenge conditionally stops it being used by two requests at once
+	res := s.db.Model(&models.MFAChallenge{}).Where("id = ? AND consumed_at IS NULL", challenge.ID).Update("consumed_at", time.Now())
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to consume MFA challenge: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrChallengeInvalid
+	}
+
+	session, err := s.OpenSession(user, ipAddress, userAgent, amr)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordLogin(user.ID, ipAddress, userAgent, true, "")
+	return session, nil
+}
+
+// OpenSession issues tokens with amr naming the factors used, and stores the session. The
+// tokens carry an MFA assertion made now unless the only second step was an emailed code.
+// The session is registered with the session registry, and no tokens are issued when
+// that fails. Logins other than SSO are refused for users whose organization requires it.
+func (s *Service) OpenSession(user *models.User, ipAddress, userAgent string, amr []string) (*Session, error) {
+	if err := s.CheckLoginMethod(user, amr); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sessionID := uuid.New()
+	token, refreshToken, err := s.issueTokens(user, sessionID, amr, now, assertsMFA(amr))
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.UserSession{
+		ID:           sessionID,
+		UserID:       user.ID,
+		Token:        token,
+		RefreshToken: refreshToken,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		ExpiresAt:    now.Add(s.opts.RefreshTokenTTL),
+		LastUsedAt:   now,
+		IsActive:     true,
+	}
+	if err := s.db.Omit("User").Create(session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if err := s.sessions.Register(session, string(user.Role)); err != nil {
+		s.db.Model(&models.UserSession{}).Where("id = ?", sessionID).Update("is_active", false)
+		return nil, fmt.Errorf("failed to register session: %w", err)
+	}
+	s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"last_login_at": now,
+		"last_login_ip": ipAddress,
+	})
+
+	user.Password = ""
+	return &Session{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.opts.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+// issueTokens signs an access and a refresh token for the session. Both carry amr so a
+// refresh keeps the login's methods.
+func (s *Service) issueTokens(user *models.User, sessionID uuid.UUID, amr []string, now time.Time, mfa bool) (string, string, error) {
+	claims := jwt.MapClaims{
+		"user_id":    user.ID.String(),
+		"email":      user.Email,
+		"role":       string(user.Role),
+		"session_id": sessionID.String(),
+		"amr":        amr,
+		"sub":        user.ID.String(),
+		"iat":        now.Unix(),
+		"exp":        now.Add(s.opts.AccessTokenTTL).Unix(),
+	}
+	if mfa {
+		claims["mfa_at"] = now.Unix()
+	}
+	token, err := s.sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := s.sign(jwt.MapClaims{
+		"user_id":    user.ID.String(),
+		"email":      user.Email,
+		"role":       string(user.Role),
+		"session_id": sessionID.String(),
+		"amr":        amr,
+		"type":       "refresh",
+		"sub":        user.ID.String(),
+		"iat":        now.Unix(),
+		"exp":        now.Add(s.opts.RefreshTokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// assertsMFA reports whether amr includes a factor strong enough for step-up
+func assertsMFA(amr []string) bool {
+	for _, method := range amr {
+		if method == "otp" || method == "hwk" || method == "mfa" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) RecordLogin(userID uuid.UUID, ipAddress, userAgent string, success bool, failReason string) {
+	if s.recorder != nil {
+		s.recorder.RecordLogin(userID, ipAddress, userAgent, success, failReason)
+		return
+	}
+	var email string
+	s.db.Model(&models.User{}).Where("id = ?", userID).Pluck("email", &email)
+	s.db.Omit("User").Create(&models.LoginHistory{
+		UserID:      &userID,
+		Email:       email,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Success:     success,
+		FailReason:  failReason,
+		AttemptedAt: time.Now(),
+	})
+}
+
+// StepUp verifies a fresh TOTP code and reissues the caller's access token with a new MFA
+// assertion. Users who must use a passkey step up with it instead.
+func (s *Service) StepUp(tokenString, code string) (string, time.Time, error) {
+	claims, user, err := s.TokenUser(tokenString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !user.MFAEnabled {
+		return "", time.Time{}, ErrNotEnabled
+	}
+	if s.requiresPasskey(user) {
+		return "", time.Time{}, ErrMethodNotAllowed
+	}
+	if _, err := s.verifyCode(user.ID, code, false); err != nil {
+		return "", time.Time{}, err
+	}
+	return s.Reassert(claims, []string{"pwd", "otp"})
+}
+
+// TokenUser returns the claims of a valid access token and the user it belongs to
+func (s *Service) TokenUser(tokenString string) (jwt.MapClaims, *models.User, error) {
+	claims, err := s.ParseToken(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+	userID, err := uuid.Parse(fmt.Sprint(claims["user_id"]))
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claims, user, nil
+}
+
+// Reassert reissues a token with an MFA assertion made now. The token keeps its session
+// and expiry.
+func (s *Service) Reassert(claims jwt.MapClaims, amr []string) (string, time.Time, error) {
+	now := time.Now()
+	claims["mfa_at"] = now.Unix()
+	claims["amr"] = amr
+	token, err := s.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, now, nil
+}
+
+// ParseToken validates an access token signed with the service's JWT secret
+func (s *Service) ParseToken(tokenString string) (jwt.MapClaims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil || claims["type"] == "refresh" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// parse validates a token of either type signed with the service's JWT secret
+func (s *Service) parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(strings.TrimPrefix(tokenString, "Bearer "), func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.jwtSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// RecentlyVerified reports whether the token's MFA assertion was made within
+// STEP_UP_MAX_AGE
+func (s *Service) RecentlyVerified(claims jwt.MapClaims) bool {
+	age, ok := StepUpAge(claims, time.Now())
+	return ok && age <= s.opts.StepUpMaxAge && age >= -time.Minute
+}
+
+// StepUpMaxAge is how long a step-up assertion is accepted for
+func (s *Service) StepUpMaxAge() time.Duration {
+	return s.opts.StepUpMaxAge
+}
+
+// StepUpAge returns how long ago the token's MFA assertion was made, or false when it
+// carries none
+func StepUpAge(claims jwt.MapClaims, now time.Time) (time.Duration, bool) {
+	mfaAt, ok := claims["mfa_at"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return now.Sub(time.Unix(int64(mfaAt), 0)), true
+}
+
+func (s *Service) sign(claims jwt.MapClaims) (string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,286 @@
+package mfa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"user-management-service/internal/models"
+)
+
+// Reasons a session is revoked, as recorded by the backend's session store
+const (
+	RevokeLogout  = "logout"
+	RevokeByUser  = "revoked"
+	RevokeByAdmin = "admin"
+)
+
+var (
+	ErrSessionRevoked      = errors.New("session has been revoked or has expired")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrRegistryUnavailable = errors.New("session registry is unavailable")
+)
+
+// SessionRegistry decides whether a session is still valid. Sessions are kept with the
+// backend's, which hold password logins, so a revocation on either side ends access on
+// both.
+type SessionRegistry interface {
+	Register(session *models.UserSession, role string) error
+	Validate(sessionID, userID uuid.UUID) error
+	// Rotate swaps the session's refresh token; presenting one already rotated revokes
+	// the session
+	Rotate(sessionID uuid.UUID, presented, next string) error
+	Revoke(userID, sessionID uuid.UUID, reason string) error
+	RevokeAll(userID uuid.UUID, reason string) error
+}
+
+// httpRegistry keeps sessions in the backend's session store through its internal API
+type httpRegistry struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (r *httpRegistry) Register(session *models.UserSession, role string) error {
+	_, err := r.do(http.MethodPost, "/sessions", map[string]interface{}{
+		"session_id":         session.ID,
+		"user_id":            session.UserID,
+		"role":               role,
+		"ip_address":         session.IPAddress,
+		"user_agent":         session.UserAgent,
+		"location":           session.Location,
+		"refresh_token_hash": hashToken(session.RefreshToken), // Hex SHA-256, as the backend stores it
+	})
+	return err
+}
+
+func (r *httpRegistry) Validate(sessionID, userID uuid.UUID) error {
+	_, err := r.do(http.MethodGet, "/sessions/"+sessionID.String()+"?user_id="+url.QueryEscape(userID.String()), nil)
+	return err
+}
+
+func (r *httpRegistry) Rotate(sessionID uuid.UUID, presented, next string) error {
+	_, err := r.do(http.MethodPost, "/sessions/"+sessionID.String()+"/refresh", map[string]string{
+		"refresh_token":      presented,
+		"next_refresh_token": next,
+	})
+	return err
+}
+
+func (r *httpRegistry) Revoke(userID, sessionID uuid.UUID, reason string) error {
+	status, err := r.do(http.MethodPost, "/sessions/"+sessionID.String()+"/revoke", map[string]interface{}{
+		"user_id": userID,
+		"reason":  reason,
+	})
+	if status == http.StatusNotFound {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+func (r *httpRegistry) RevokeAll(userID uuid.UUID, reason string) error {
+	_, err := r.do(http.MethodPost, "/users/"+userID.String()+"/sessions/revoke", map[string]string{"reason": reason})
+	return err
+}
+
+// do calls the internal session API. A 401 from the store means the session is no longer
+// valid; anything else unexpected is reported as the registry being unavailable, so
+// callers fail closed.
+func (r *httpRegistry) do(method, path string, payload interface{}) (int, error) {
+	var body *bytes.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(encoded)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(r.url, "/")+"/api/v1/internal"+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRegistryUnavailable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return resp.StatusCode, nil
+	case resp.StatusCode == http.StatusUnauthorized && path != "/sessions":
+		return resp.StatusCode, ErrSessionRevoked
+	default:
+		return resp.StatusCode, fmt.Errorf("%w: %s %s returned %s", ErrRegistryUnavailable, method, path, resp.Status)
+	}
+}
+
+// localRegistry is used when SESSION_REGISTRY_URL is not set. Sessions are only checked
+// against the user_sessions table, so revocations in the backend do not reach them.
+type localRegistry struct {
+	db *gorm.DB
+}
+
+func (r *localRegistry) Register(session *models.UserSession, role string) error {
+	return nil
+}
+
+func (r *localRegistry) Validate(sessionID, userID uuid.UUID) error {
+	var count int64
+	err := r.db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND is_active = ? AND expires_at > ?", sessionID, userID, true, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	if count == 0 {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func (r *localRegistry) Rotate(sessionID uuid.UUID, presented, next string) error {
+	res := r.db.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token = ? AND is_active = ?", sessionID, presented, true).
+		Update("refresh_token", next)
+	if res.Error != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		// A rotated token presented again was copied
+		r.db.Model(&models.UserSession{}).Where("id = ?", sessionID).Update("is_active", false)
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func (r *localRegistry) Revoke(userID, sessionID uuid.UUID, reason string) error {
+	res := r.db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND is_active = ?", sessionID, userID, true).
+		Update("is_active", false)
+	if res.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *localRegistry) RevokeAll(userID uuid.UUID, reason string) error {
+	if err := r.db.Model(&models.UserSession{}).Where("user_id = ?", userID).Update("is_active", false).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+func newSessionRegistry(db *gorm.DB, opts Options) SessionRegistry {
+	if opts.SessionRegistryURL == "" {
+		return &localRegistry{db: db}
+	}
+	return &httpRegistry{url: opts.SessionRegistryURL, token: opts.SessionRegistryToken, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ValidateSession checks that the session an access token belongs to has not been
+// revoked or timed out
+func (s *Service) ValidateSession(claims jwt.MapClaims) error {
+	sessionID, err := uuid.Parse(fmt.Sprint(claims["session_id"]))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	userID, err := uuid.Parse(fmt.Sprint(claims["user_id"]))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	return s.sessions.Validate(sessionID, userID)
+}
+
+// Refresh exchanges a refresh token for new tokens of the same session. The refresh token
+// is single-use; the registry revokes the session when a rotated one comes back.
+func (s *Service) Refresh(refreshToken string) (*Session, error) {
+	claims, err := s.parse(refreshToken)
+	if err != nil || claims["type"] != "refresh" {
+		return nil, ErrInvalidToken
+	}
+	sessionID, err := uuid.Parse(fmt.Sprint(claims["session_id"]))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	userID, err := uuid.Parse(fmt.Sprint(claims["user_id"]))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+		return nil, ErrAccountInactive
+	}
+
+	// The refreshed token keeps the login's methods but not its MFA assertion, so step-up
+	// is asked for again
+	var amr []string
+	if methods, ok := claims["amr"].([]interface{}); ok {
+		for _, method := range methods {
+			amr = append(amr, fmt.Sprint(method))
+		}
+	}
+	// Sessions opened before the organization required SSO end at their next refresh
+	if err := s.CheckLoginMethod(user, amr); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token, next, err := s.issueTokens(user, sessionID, amr, now, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Rotate(sessionID, strings.TrimPrefix(refreshToken, "Bearer "), next); err != nil {
+		return nil, err
+	}
+	s.db.Model(&models.UserSession{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"token":         token,
+		"refresh_token": next,
+		"last_used_at":  now,
+	})
+
+	user.Password = ""
+	return &Session{
+		Token:        token,
+		RefreshToken: next,
+		ExpiresIn:    int64(s.opts.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+// RevokeSession signs out one of the user's sessions everywhere
+func (s *Service) RevokeSession(userID, sessionID uuid.UUID, reason string) error {
+	if err := s.sessions.Revoke(userID, sessionID, reason); err != nil {
+		return err
+	}
+	return s.db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Update("is_active", false).Error
+}
+
+// RevokeAllSessions signs the user out of every session, including those of password
+// logins in the backend
+func (s *Service) RevokeAllSessions(userID uuid.UUID, reason string) error {
+	if err := s.sessions.RevokeAll(userID, reason); err != nil {
+		return err
+	}
+	return s.db.Model(&models.UserSession{}).Where("user_id = ?", userID).Update("is_active", false).Error
+}
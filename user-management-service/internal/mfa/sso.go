@@ -0,0 +1,69 @@
+package mfa
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"user-management-service/internal/models"
+)
+
+// ErrSSORequired refuses a login that did not go through the identity provider of an
+// organization requiring single sign-on
+var ErrSSORequired = errors.New("this organization requires logging in with single sign-on")
+
+// AMRSSO names federated logins in the amr claim
+const AMRSSO = "sso"
+
+// SSORequiredError points a refused login to the SSO login of the user's organization
+type SSORequiredError struct {
+	ConnectionID uuid.UUID
+	LoginURL     string
+}
+
+func (e *SSORequiredError) Error() string {
+	return ErrSSORequired.Error()
+}
+
+func (e *SSORequiredError) Unwrap() error {
+	return ErrSSORequired
+}
+
+// SSOPolicy decides whether a user may only log in through their organization's identity
+// provider
+type SSOPolicy interface {
+	// RequireSSO returns an *SSORequiredError when the user must log in with SSO
+	RequireSSO(user *models.User) error
+}
+
+// SetSSOPolicy makes every session opened by a method other than SSO subject to policy
+func (s *Service) SetSSOPolicy(policy SSOPolicy) {
+	s.ssoPolicy = policy
+}
+
+// CheckLoginMethod refuses a login with the methods in amr when the user's organization
+// requires SSO. OpenSession checks it for every login; callers that resolve the user
+// before then check it too, so the refusal comes before anything else is spent.
+func (s *Service) CheckLoginMethod(user *models.User, amr []string) error {
+	if s.ssoPolicy == nil {
+		return nil
+	}
+	for _, method := range amr {
+		if method == AMRSSO {
+			return nil
+		}
+	}
+	return s.ssoPolicy.RequireSSO(user)
+}
+
+// RespondSSORequired answers a login refused by the SSO policy with where to log in instead
+func RespondSSORequired(c *gin.Context, err *SSORequiredError) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":         err.Error(),
+		"sso_required":  true,
+		"connection_id": err.ConnectionID,
+		"login_url":     err.LoginURL,
+	})
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CompanyVerificationStatus represents where a company is in KYB (know your business)
+type CompanyVerificationStatus string
+
+const (
+	CompanyNotVerified         CompanyVerificationStatus = "not_started"
+	CompanyVerificationPending CompanyVerificationStatus = "pending"  // Submitted, waiting for a compliance decision
+	CompanyVerified            CompanyVerificationStatus = "verified" // May be financed
+	CompanyRejected            CompanyVerificationStatus = "rejected"
+)
+
+// CompanyPerson is a director or ultimate beneficial owner of a company. Each has to pass
+// KYC through their own user account, found by email.
+type CompanyPerson struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	CompanyID         uuid.UUID  `json:"company_id" gorm:"type:uuid;not null;index"`
+	FirstName         string     `json:"first_name" gorm:"not null"`
+	LastName          string     `json:"last_name" gorm:"not null"`
+	Email             string     `json:"email" gorm:"not null"`
+	DateOfBirth       *time.Time `json:"date_of_birth,omitempty"`
+	Nationality       string     `json:"nationality,omitempty"` // ISO country code
+	IsDirector        bool       `json:"is_director"`
+	IsBeneficialOwner bool       `json:"is_beneficial_owner"`
+	OwnershipPercent  float64    `json:"ownership_percent"`                        // Direct and indirect shares, 0-100
+	ControlType       string     `json:"control_type,omitempty"`                   // shares, voting_rights, other_control
+	Position          string     `json:"position,omitempty"`                       // e.g. "Managing Director"
+	UserID            *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;index"` // Account the person verifies with
+	KYCDataID         *uuid.UUID `json:"kyc_data_id,omitempty" gorm:"type:uuid"`   // Their KYC submission
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// KYBDecision records a compliance decision on a company together with what it was
+// based on
+type KYBDecision struct {
+	ID        uuid.UUID                 `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	CompanyID uuid.UUID                 `json:"company_id" gorm:"type:uuid;not null;index"`
+	Status    CompanyVerificationStatus `json:"status" gorm:"not null"` // verified, rejected
+	Reason    string                    `json:"reason,omitempty"`
+	DecidedBy uuid.UUID                 `json:"decided_by" gorm:"type:uuid;not null"`
+	// Assessment is the KYB assessment at the time of the decision
+	Assessment map[string]interface{} `json:"assessment" gorm:"type:jsonb;serializer:json"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+func (p *CompanyPerson) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+func (d *KYBDecision) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KYCCheckStatus represents the outcome of an automated KYC check
+type KYCCheckStatus string
+
+const (
+	KYCCheckPassed      KYCCheckStatus = "passed"
+	KYCCheckFailed      KYCCheckStatus = "failed"      // e.g. a confirmed sanctions match or a tampered document
+	KYCCheckReview      KYCCheckStatus = "review"      // Inconclusive, a reviewer has to look
+	KYCCheckUnavailable KYCCheckStatus = "unavailable" // Provider not configured or not reachable
+	KYCCheckSkipped     KYCCheckStatus = "skipped"     // Not performed and not required for approval
+)
+
+// KYCReviewStatus represents where a case is in the manual review queue
+type KYCReviewStatus string
+
+const (
+	KYCReviewPending          KYCReviewStatus = "pending_review"   // Waiting for a first reviewer (maker)
+	KYCReviewAwaitingApproval KYCReviewStatus = "pending_approval" // Decided, waiting for a second reviewer (checker)
+	KYCReviewCompleted        KYCReviewStatus = "completed"
+)
+
+// KYCCheck records one automated check of a KYC submission
+type KYCCheck struct {
+	ID         uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	KYCID      uuid.UUID              `json:"kyc_id" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID              `json:"user_id" gorm:"type:uuid;not null"`
+	Submission int                    `json:"submission"`           // The KYC data's submission count when it was checked
+	Name       string                 `json:"name" gorm:"not null"` // document_ocr, sanctions_screening, liveness
+	Provider   string                 `json:"provider,omitempty"`
+	Status     KYCCheckStatus         `json:"status" gorm:"not null"`
+	Score      int                    `json:"score"` // Risk points added to the submission, 0-100
+	Details    map[string]interface{} `json:"details,omitempty" gorm:"type:jsonb;serializer:json"`
+	CheckedAt  time.Time              `json:"checked_at"`
+}
+
+// KYCReviewCase is a KYC submission in the manual review queue. A decision by one
+// reviewer only takes effect once a different reviewer approves it.
+type KYCReviewCase struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	KYCID       uuid.UUID       `json:"kyc_id" gorm:"type:uuid;not null;index"`
+	UserID      uuid.UUID       `json:"user_id" gorm:"type:uuid;not null;index"`
+	Submission  int             `json:"submission"`
+	RiskScore   int             `json:"risk_score"`                       // 0-100
+	RiskLevel   string          `json:"risk_level" gorm:"not null;index"` // low, medium, high
+	RiskFactors []string        `json:"risk_factors" gorm:"type:jsonb;serializer:json"`
+	Status      KYCReviewStatus `json:"status" gorm:"not null;index"`
+
+	// Maker decision
+	Decision        string     `json:"decision,omitempty"` // approve, reject
+	DecisionNote    string     `json:"decision_note,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"` // Shown to the customer
+	DecidedBy       *uuid.UUID `json:"decided_by,omitempty" gorm:"type:uuid"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty"`
+
+	// Checker approval
+	ApprovedBy    *uuid.UUID `json:"approved_by,omitempty" gorm:"type:uuid"`
+	ApprovedAt    *time.Time `json:"approved_at,omitempty"`
+	ApprovalNote  string     `json:"approval_note,omitempty"`
+	ReturnedCount int        `json:"returned_count"` // Decisions sent back to the queue by a checker
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c *KYCCheck) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (c *KYCReviewCase) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
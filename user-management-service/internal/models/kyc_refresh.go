@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KYCRefreshReason is why a customer has to verify again
+type KYCRefreshReason string
+
+const (
+	KYCRefreshReviewDue        KYCRefreshReason = "review_due"        // The approval reaches its review date
+	KYCRefreshDocumentExpiring KYCRefreshReason = "document_expiring" // The ID document expires first
+	KYCRefreshByCampaign       KYCRefreshReason = "campaign"          // Requested by a re-verification campaign
+)
+
+// KYCRefreshStatus tracks a re-verification request
+type KYCRefreshStatus string
+
+const (
+	KYCRefreshOpen       KYCRefreshStatus = "open"
+	KYCRefreshCompleted  KYCRefreshStatus = "completed"  // Approved again before the deadline
+	KYCRefreshRestricted KYCRefreshStatus = "restricted" // The deadline passed and KYC expired
+	KYCRefreshCancelled  KYCRefreshStatus = "cancelled"
+)
+
+// KYCRefresh asks a customer to verify again by a deadline. Reminders are sent in stages
+// before it; once it passes the customer's KYC expires.
+type KYCRefresh struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID         uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index"`
+	CampaignID     *uuid.UUID       `json:"campaign_id,omitempty" gorm:"type:uuid;index"`
+	Reason         KYCRefreshReason `json:"reason" gorm:"not null"`
+	Status         KYCRefreshStatus `json:"status" gorm:"not null;index"`
+	Deadline       time.Time        `json:"deadline" gorm:"not null;index"`
+	RemindersSent  int              `json:"reminders_sent"` // Reminder stages already notified
+	LastReminderAt *time.Time       `json:"last_reminder_at,omitempty"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
+	RestrictedAt   *time.Time       `json:"restricted_at,omitempty"`
+	CancelledAt    *time.Time       `json:"cancelled_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// KYCRefreshCampaignStatus tracks a re-verification campaign
+type KYCRefreshCampaignStatus string
+
+const (
+	KYCCampaignActive    KYCRefreshCampaignStatus = "active"
+	KYCCampaignFinished  KYCRefreshCampaignStatus = "finished" // Every refresh was completed or restricted
+	KYCCampaignCancelled KYCRefreshCampaignStatus = "cancelled"
+)
+
+// KYCRefreshCampaign is an ad-hoc re-verification of approved customers selected by risk
+// rating and country
+type KYCRefreshCampaign struct {
+	ID          uuid.UUID                `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string                   `json:"name" gorm:"not null"`
+	Description string                   `json:"description,omitempty"`
+	RiskRatings []string                 `json:"risk_ratings" gorm:"type:jsonb;serializer:json"` // Empty matches every rating
+	Countries   []string                 `json:"countries" gorm:"type:jsonb;serializer:json"`    // Nationality or residence; empty matches all
+	Deadline    time.Time                `json:"deadline" gorm:"not null"`
+	Status      KYCRefreshCampaignStatus `json:"status" gorm:"not null;index"`
+	Matched     int                      `json:"matched"` // Customers asked to verify again
+	CreatedBy   uuid.UUID                `json:"created_by" gorm:"type:uuid;not null"`
+	CancelledBy *uuid.UUID               `json:"cancelled_by,omitempty" gorm:"type:uuid"`
+	CancelledAt *time.Time               `json:"cancelled_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *KYCRefresh) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (c *KYCRefreshCampaign) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
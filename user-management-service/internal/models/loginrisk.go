@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KnownDevice is a browser or app a user has signed in from. Logins from a device that is
+// not on the list raise the login's risk score and notify the user.
+type KnownDevice struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_known_device"`
+	Fingerprint string    `json:"-" gorm:"not null;uniqueIndex:idx_known_device"` // SHA-256 of the user agent without version numbers
+	Label       string    `json:"label"`                                          // e.g. "Chrome on Windows"
+	LastIP      string    `json:"last_ip"`
+	LastCountry string    `json:"last_country,omitempty"`
+	LastCity    string    `json:"last_city,omitempty"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// LoginConfirmation holds the emailed code for a risky password login by a user without
+// MFA. It completes the MFA challenge it belongs to.
+type LoginConfirmation struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ChallengeID uuid.UUID  `json:"challenge_id" gorm:"type:uuid;not null;uniqueIndex"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash    string     `json:"-" gorm:"not null"` // SHA-256 of the code, keyed by the challenge
+	Attempts    int        `json:"attempts" gorm:"default:0"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAChallenge is the pending second step of a login by a user with MFA enabled
+type MFAChallenge struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex"`             // SHA-256 of the challenge token given to the client
+	Methods    []string   `json:"methods" gorm:"type:jsonb;serializer:json"` // totp, backup_code, webauthn
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
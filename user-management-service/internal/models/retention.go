@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionRule decides how long rows of one table are kept and what happens to them
+// afterwards
+type RetentionRule struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name          string    `json:"name" gorm:"not null;uniqueIndex"`
+	Entity        string    `json:"entity" gorm:"not null;index"`                         // Table the rule applies to
+	Basis         string    `json:"basis" gorm:"not null"`                                // Timestamp column the period runs from
+	Statuses      []string  `json:"statuses,omitempty" gorm:"type:jsonb;serializer:json"` // Only rows in these statuses expire
+	RetentionDays int       `json:"retention_days" gorm:"not null"`
+	Action        string    `json:"action" gorm:"not null"` // purge, anonymize
+	Enabled       bool      `json:"enabled" gorm:"default:true"`
+	Description   string    `json:"description,omitempty"`
+	UpdatedBy     string    `json:"updated_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// LegalHold blocks retention for one row, or for every row belonging to a user
+type LegalHold struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Entity        string     `json:"entity,omitempty"` // Empty holds the user's rows in every table
+	RecordID      *uuid.UUID `json:"record_id,omitempty" gorm:"type:uuid"`
+	UserID        *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	Reason        string     `json:"reason" gorm:"not null"`
+	CaseReference string     `json:"case_reference,omitempty"`
+	PlacedBy      string     `json:"placed_by"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty" gorm:"index"`
+	ReleasedBy    string     `json:"released_by,omitempty"`
+	ReleaseNote   string     `json:"release_note,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// PurgeRun records one retention run and its report, signed with the retention
+// certificate's key. The partial unique index on status allows one running run at a time
+// across replicas.
+type PurgeRun struct {
+	ID                     uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Status                 string     `json:"status" gorm:"not null;uniqueIndex:idx_purge_runs_running,where:status = 'running'"` // running, completed, failed, abandoned
+	DryRun                 bool       `json:"dry_run"`
+	TriggeredBy            string     `json:"triggered_by"`
+	Purged                 int64      `json:"purged"`
+	Anonymized             int64      `json:"anonymized"`
+	Held                   int64      `json:"held"`
+	Error                  string     `json:"error,omitempty"`
+	Report                 string     `json:"report,omitempty" gorm:"type:text"` // Canonical JSON that was signed
+	ReportSHA256           string     `json:"report_sha256,omitempty"`
+	Signature              string     `json:"signature,omitempty"`
+	SignatureAlgorithm     string     `json:"signature_algorithm,omitempty"`
+	CertificateFingerprint string     `json:"certificate_fingerprint,omitempty"` // SHA-256 of the signing certificate
+	StartedAt              time.Time  `json:"started_at" gorm:"index"`
+	CompletedAt            *time.Time `json:"completed_at,omitempty"`
+}
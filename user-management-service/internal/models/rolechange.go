@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RoleChangeRequest is a change of a user's role waiting for other administrators to
+// confirm it. A user has at most one pending request.
+type RoleChangeRequest struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_role_change_pending,where:status = 'pending'"`
+	FromRole          UserRole   `json:"from_role" gorm:"not null"`
+	ToRole            UserRole   `json:"to_role" gorm:"not null"`
+	Reason            string     `json:"reason"`
+	RequestedBy       uuid.UUID  `json:"requested_by" gorm:"type:uuid;not null"`
+	RequiredApprovals int        `json:"required_approvals" gorm:"not null"`
+	Approvals         int        `json:"approvals" gorm:"not null;default:0"`
+	Status            string     `json:"status" gorm:"not null;index;uniqueIndex:idx_role_change_pending"` // pending, applied, rejected, cancelled, expired, failed
+	FailureReason     string     `json:"failure_reason,omitempty"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"index"`
+	ClosedAt          *time.Time `json:"closed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Decisions []RoleChangeDecision `json:"decisions,omitempty" gorm:"foreignKey:RequestID"`
+}
+
+// RoleChangeDecision is one administrator confirming or rejecting a role change
+type RoleChangeDecision struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RequestID uuid.UUID `json:"request_id" gorm:"type:uuid;not null;uniqueIndex:idx_role_change_decision"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_role_change_decision"`
+	Decision  string    `json:"decision" gorm:"not null"` // approve, reject
+	Note      string    `json:"note,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *RoleChangeRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (d *RoleChangeDecision) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
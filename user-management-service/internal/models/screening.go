@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScreeningStatus represents the outcome of a sanctions/PEP screening
+type ScreeningStatus string
+
+const (
+	ScreeningClear          ScreeningStatus = "clear"           // No hit above the match threshold
+	ScreeningPotentialMatch ScreeningStatus = "potential_match" // Hits awaiting analyst review
+	ScreeningConfirmedMatch ScreeningStatus = "confirmed_match" // Analyst confirmed a true match
+	ScreeningDismissed      ScreeningStatus = "dismissed"       // Analyst dismissed the hits as false positives
+)
+
+// ScreeningList is one loaded version of a sanctions or PEP list file
+type ScreeningList struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Source     string    `json:"source" gorm:"not null;index"` // ofac_sdn, eu_consolidated, un_consolidated, pep
+	Version    string    `json:"version" gorm:"not null"`      // Publication date from the file, or its checksum
+	FileName   string    `json:"file_name"`
+	Checksum   string    `json:"checksum" gorm:"not null"` // SHA-256 of the file
+	EntryCount int       `json:"entry_count"`
+	Active     bool      `json:"active" gorm:"default:false;index"`
+	LoadedAt   time.Time `json:"loaded_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ScreeningEntry is a listed person or entity
+type ScreeningEntry struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ListID       uuid.UUID `json:"list_id" gorm:"type:uuid;not null;index"`
+	Source       string    `json:"source" gorm:"not null"`
+	ExternalID   string    `json:"external_id"`                // Identifier assigned by the list publisher
+	EntryType    string    `json:"entry_type" gorm:"not null"` // individual, entity
+	Name         string    `json:"name" gorm:"not null"`
+	Aliases      []string  `json:"aliases,omitempty" gorm:"type:jsonb;serializer:json"`
+	Countries    []string  `json:"countries,omitempty" gorm:"type:jsonb;serializer:json"` // ISO codes where known
+	DatesOfBirth []string  `json:"dates_of_birth,omitempty" gorm:"type:jsonb;serializer:json"`
+	Programs     []string  `json:"programs,omitempty" gorm:"type:jsonb;serializer:json"`
+	Remarks      string    `json:"remarks,omitempty"`
+}
+
+// ScreeningResult stores one screening of a customer, buyer or counterparty
+type ScreeningResult struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SubjectType string          `json:"subject_type" gorm:"not null;index:idx_screening_subject"` // user, company, buyer, counterparty
+	SubjectID   string          `json:"subject_id" gorm:"index:idx_screening_subject"`
+	SubjectName string          `json:"subject_name" gorm:"not null"`
+	Status      ScreeningStatus `json:"status" gorm:"not null;index"`
+	TopScore    float64         `json:"top_score"`
+	Hits        []ScreeningHit  `json:"hits" gorm:"type:jsonb;serializer:json"`
+	// ListVersions records the version of every list the subject was screened against
+	ListVersions map[string]string `json:"list_versions" gorm:"type:jsonb;serializer:json"`
+	Trigger      string            `json:"trigger"` // onboarding, list_update, manual, api
+
+	// Review information
+	ReviewedBy *uuid.UUID `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote string     `json:"review_note,omitempty"`
+
+	ScreenedAt time.Time `json:"screened_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ScreeningHit is a scored match against a list entry
+type ScreeningHit struct {
+	EntryID     uuid.UUID `json:"entry_id"`
+	Source      string    `json:"source"`
+	ListVersion string    `json:"list_version"`
+	ExternalID  string    `json:"external_id,omitempty"`
+	EntryType   string    `json:"entry_type"`
+	ListedName  string    `json:"listed_name"`
+	MatchedName string    `json:"matched_name"` // The name or alias that matched
+	Score       float64   `json:"score"`        // 0-1
+	Programs    []string  `json:"programs,omitempty"`
+	Reasons     []string  `json:"reasons,omitempty"` // e.g. date_of_birth_match, country_match
+}
+
+func (l *ScreeningList) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+func (e *ScreeningEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *ScreeningResult) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SSOConnection is an organization's corporate identity provider. Users whose email
+// domain is listed log in through it.
+type SSOConnection struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"` // Organization in the platform backend
+	Name           string    `json:"name" gorm:"not null"`
+	Protocol       string    `json:"protocol" gorm:"not null"`                           // oidc, saml
+	Domains        []string  `json:"domains" gorm:"type:jsonb;serializer:json;not null"` // Lower case email domains
+	Enabled        bool      `json:"enabled"`
+	EnforceSSO     bool      `json:"enforce_sso"` // Password login is refused for the domains
+	// Provisioning: users are created on first login when JIT is on, with the role mapped
+	// from their groups or the default role
+	JITProvisioning bool              `json:"jit_provisioning"`
+	DefaultRole     UserRole          `json:"default_role" gorm:"not null"`
+	GroupsAttribute string            `json:"groups_attribute"`                                // Claim or attribute holding the groups
+	RoleMappings    map[string]string `json:"role_mappings" gorm:"type:jsonb;serializer:json"` // Group to role; the first match in role order wins
+	MemberRole      string            `json:"member_role"`                                     // Role in the backend organization for new members; viewer when empty
+
+	// OIDC
+	Issuer       string   `json:"issuer,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"-"` // AES-GCM encrypted with the MFA key
+	Scopes       []string `json:"scopes,omitempty" gorm:"type:jsonb;serializer:json"`
+
+	// SAML
+	IdPEntityID    string `json:"idp_entity_id,omitempty"`
+	IdPSSOURL      string `json:"idp_sso_url,omitempty"`
+	IdPCertificate string `json:"idp_certificate,omitempty"` // PEM signing certificate
+	EmailAttribute string `json:"email_attribute,omitempty"` // Defaults to the NameID
+
+	CreatedBy uuid.UUID `json:"created_by" gorm:"type:uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SSOLoginState is a login started at the identity provider, used once when it returns
+type SSOLoginState struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ConnectionID uuid.UUID  `json:"connection_id" gorm:"type:uuid;not null;index"`
+	StateHash    string     `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the OIDC state or SAML request ID
+	Nonce        string     `json:"-"`
+	CodeVerifier string     `json:"-"` // PKCE verifier, sent with the code exchange
+	ReturnTo     string     `json:"return_to,omitempty"`
+	IPAddress    string     `json:"ip_address"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"index"`
+	ConsumedAt   *time.Time `json:"consumed_at,omitempty"`
+	// A completed login is handed to the client with a single-use code
+	UserID         *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`
+	ResultCodeHash *string    `json:"-" gorm:"uniqueIndex"`
+	ExchangedAt    *time.Time `json:"exchanged_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// SSOIdentity links an identity provider subject to a platform user
+type SSOIdentity struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ConnectionID uuid.UUID `json:"connection_id" gorm:"type:uuid;not null;uniqueIndex:idx_sso_identity_subject"`
+	Subject      string    `json:"subject" gorm:"not null;uniqueIndex:idx_sso_identity_subject"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Email        string    `json:"email"`
+	Groups       []string  `json:"groups,omitempty" gorm:"type:jsonb;serializer:json"` // As of the last login
+	Provisioned  bool      `json:"provisioned"`                                        // Created by JIT rather than linked
+	LastLoginAt  time.Time `json:"last_login_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (c *SSOConnection) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (s *SSOLoginState) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (i *SSOIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
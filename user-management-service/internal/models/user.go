@@ -61,8 +61,17 @@ type User struct {
 	
 	// Security
 	MFAEnabled        bool       `json:"mfa_enabled" gorm:"default:false"`
-	MFASecret         string     `json:"-"` // TOTP secret
-	BackupCodes       []string   `json:"-" gorm:"type:text[]"` // Recovery codes
+	MFASecret         string     `json:"-"` // TOTP secret, AES-GCM encrypted
+	BackupCodes       []string   `json:"-" gorm:"type:text[]"` // Recovery codes, bcrypt hashed and single-use
+	MFAEnrolledAt     *time.Time `json:"mfa_enrolled_at,omitempty"`
+	MFALastUsedStep   int64      `json:"-"` // Last accepted TOTP time step, so a code cannot be replayed
+	MFAFailedAttempts int        `json:"-" gorm:"default:0"`
+	MFALockedUntil    *time.Time `json:"mfa_locked_until,omitempty"`
+	
+	// Password login lockout, which grows with each consecutive lockout
+	LoginFailedAttempts int        `json:"-" gorm:"default:0"`
+	LoginLockedUntil    *time.Time `json:"login_locked_until,omitempty"`
+	LoginLockoutCount   int        `json:"-" gorm:"default:0"`
 	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
 	LastLoginAt       *time.Time `json:"last_login_at,omitempty"`
 	LastLoginIP       string     `json:"last_login_ip,omitempty"`
@@ -71,6 +80,7 @@ type User struct {
 	KYCStatus           KYCStatus  `json:"kyc_status" gorm:"default:not_started"`
 	KYCCompletedAt      *time.Time `json:"kyc_completed_at,omitempty"`
 	KYCExpiresAt        *time.Time `json:"kyc_expires_at,omitempty"`
+	KYCRefreshDueAt     *time.Time `json:"kyc_refresh_due_at,omitempty"`   // Re-verification requested by this date
 	ComplianceScore     int        `json:"compliance_score" gorm:"default:0"` // 0-100
 	RiskRating          string     `json:"risk_rating,omitempty"`             // low, medium, high
 	
@@ -86,6 +96,7 @@ type User struct {
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 	CreatedBy *uuid.UUID     `json:"created_by,omitempty"` // Admin who created the account
 	UpdatedBy *uuid.UUID     `json:"updated_by,omitempty"` // Last admin who updated the account
+	AnonymizedAt *time.Time  `json:"anonymized_at,omitempty"` // Personal data scrubbed by retention
 }
 
 // Company represents business entity information
@@ -123,12 +134,16 @@ type Company struct {
 	CreditUsed     float64 `json:"credit_used" gorm:"default:0"`
 	
 	// Verification status
-	Verified         bool       `json:"verified" gorm:"default:false"`
-	VerifiedAt       *time.Time `json:"verified_at,omitempty"`
+	Verified           bool                      `json:"verified" gorm:"default:false"`
+	VerifiedAt         *time.Time                `json:"verified_at,omitempty"`
+	VerificationStatus CompanyVerificationStatus `json:"verification_status" gorm:"default:not_started;index"`
+	VerifiedBy         *uuid.UUID                `json:"verified_by,omitempty"` // Compliance officer who decided
+	RejectionReason    string                    `json:"rejection_reason,omitempty"`
 	
 	// Relationships
 	User      User                `json:"user" gorm:"foreignKey:UserID"`
 	Documents []CompanyDocument   `json:"documents,omitempty" gorm:"foreignKey:CompanyID"`
+	People    []CompanyPerson     `json:"people,omitempty" gorm:"foreignKey:CompanyID"` // Directors and beneficial owners
 	
 	// Audit fields
 	CreatedAt time.Time      `json:"created_at"`
@@ -175,6 +190,10 @@ type KYCData struct {
 	SelfieURL           string `json:"selfie_url,omitempty"`
 	AdditionalDocsURL   string `json:"additional_docs_url,omitempty"`
 	
+	// Submission tracking; resubmissions replace the data above
+	SubmissionCount int        `json:"submission_count" gorm:"default:0"`
+	SubmittedAt     *time.Time `json:"submitted_at,omitempty"`
+	
 	// Review information
 	ReviewStatus   KYCStatus  `json:"review_status" gorm:"default:pending"`
 	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
@@ -203,7 +222,7 @@ type CompanyDocument struct {
 	
 	// Document information
 	Name         string `json:"name" validate:"required"`
-	Type         string `json:"type" validate:"required,oneof=certificate_of_incorporation tax_certificate bank_statement financial_report other"`
+	Type         string `json:"type" validate:"required,oneof=certificate_of_incorporation tax_certificate bank_statement financial_report shareholder_register other"`
 	Description  string `json:"description,omitempty"`
 	FileURL      string `json:"file_url" validate:"required"`
 	FileName     string `json:"file_name" validate:"required"`
@@ -261,9 +280,18 @@ type LoginHistory struct {
 	AttemptedAt time.Time `json:"attempted_at"`
 	Location    string    `json:"location,omitempty"`
 	
+	// Geolocation from the local GeoIP database
+	Country   string     `json:"country,omitempty"`
+	City      string     `json:"city,omitempty"`
+	Latitude  *float64   `json:"latitude,omitempty"`
+	Longitude *float64   `json:"longitude,omitempty"`
+	DeviceID  *uuid.UUID `json:"device_id,omitempty" gorm:"type:uuid"` // Known device, set on success
+	
 	// Security flags
-	SuspiciousActivity bool   `json:"suspicious_activity" gorm:"default:false"`
-	BlockedReason      string `json:"blocked_reason,omitempty"`
+	SuspiciousActivity bool     `json:"suspicious_activity" gorm:"default:false"`
+	BlockedReason      string   `json:"blocked_reason,omitempty"`
+	RiskScore          int      `json:"risk_score" gorm:"default:0"` // 0-100
+	RiskFactors        []string `json:"risk_factors,omitempty" gorm:"type:jsonb;serializer:json"`
 	
 	// Relationships
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -325,7 +353,8 @@ func (u *User) IsKYCCompliant() bool {
 }
 
 func (u *User) RequiresKYCRenewal() bool {
-	return u.KYCExpiresAt != nil && u.KYCExpiresAt.Before(time.Now().AddDate(0, 0, 30))
+	return u.KYCRefreshDueAt != nil ||
+		(u.KYCExpiresAt != nil && u.KYCExpiresAt.Before(time.Now().AddDate(0, 0, 30)))
 }
 
 func (c *Company) GetCreditAvailable() float64 {
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthnCredential is a passkey or security key registered by a user
+type WebAuthnCredential struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name         string    `json:"name"`
+	CredentialID string    `json:"credential_id" gorm:"not null;uniqueIndex"` // base64url, as sent by the authenticator
+	PublicKey    []byte    `json:"-" gorm:"not null"`                         // COSE_Key
+	Algorithm    int       `json:"algorithm"`                                 // COSE algorithm: -7 ES256, -8 EdDSA, -257 RS256
+	SignCount    int64     `json:"sign_count"`
+	Transports   []string  `json:"transports,omitempty" gorm:"type:jsonb;serializer:json"`
+
+	// Attestation metadata from registration
+	AAGUID              string `json:"aaguid"`             // Authenticator model, zero for most synced passkeys
+	AttestationFormat   string `json:"attestation_format"` // none, packed, fido-u2f, ...
+	AttestationVerified bool   `json:"attestation_verified"`
+	AttestationSubject  string `json:"attestation_subject,omitempty"` // Subject of the attestation certificate
+	AttestationIssuer   string `json:"attestation_issuer,omitempty"`
+
+	UserVerified   bool `json:"user_verified"`   // The authenticator verified the user at registration
+	BackupEligible bool `json:"backup_eligible"` // Synced passkey
+	BackedUp       bool `json:"backed_up"`
+	// CloneDetected is set when a sign counter went backwards; the credential is revoked
+	CloneDetected bool `json:"clone_detected" gorm:"default:false"`
+
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// WebAuthnChallenge is an open registration or assertion ceremony
+type WebAuthnChallenge struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Challenge  string     `json:"challenge" gorm:"not null;uniqueIndex"` // base64url
+	Ceremony   string     `json:"ceremony" gorm:"not null"`              // registration, login, mfa, step_up
+	UserID     *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`    // Empty for discoverable passkey login
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
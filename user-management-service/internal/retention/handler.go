@@ -0,0 +1,203 @@
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler exposes retention rules, legal holds and purge runs over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention rule not found"})
+	case errors.Is(err, ErrHoldNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Legal hold not found"})
+	case errors.Is(err, ErrRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purge run not found"})
+	case errors.Is(err, ErrInvalidRule), errors.Is(err, ErrInvalidHold):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRunInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrReportUnsigned):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention operation failed"})
+	}
+}
+
+func (h *Handler) GetRules(c *gin.Context) {
+	rules, err := h.service.ListRules()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *Handler) CreateRule(c *gin.Context) {
+	var req RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *Handler) UpdateRule(c *gin.Context) {
+	var req RuleUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Param("name"), req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *Handler) GetLegalHolds(c *gin.Context) {
+	holds, err := h.service.ListHolds(c.Query("include_released") != "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holds": holds})
+}
+
+func (h *Handler) PlaceLegalHold(c *gin.Context) {
+	var req HoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := h.service.PlaceHold(req, fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (h *Handler) ReleaseLegalHold(c *gin.Context) {
+	holdID, err := uuid.Parse(c.Param("holdId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid legal hold ID"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := h.service.ReleaseHold(holdID, fmt.Sprint(c.MustGet("userID")), req.Note)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// RunPurge applies the retention rules now; dry runs only count what would change
+func (h *Handler) RunPurge(c *gin.Context) {
+	var req struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := h.service.RunPurge(c.Request.Context(), req.DryRun, "admin:"+fmt.Sprint(c.MustGet("userID")))
+	if err != nil && run == nil {
+		respondError(c, err)
+		return
+	}
+	if err != nil {
+		// The run stopped part way; its signed report still records what was done
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retention run failed", "run": run})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) GetPurgeRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	runs, err := h.service.ListRuns(limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+func (h *Handler) GetPurgeRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge run ID"})
+		return
+	}
+
+	run, err := h.service.GetRun(runID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// VerifyPurgeRun checks the stored report against its signature
+func (h *Handler) VerifyPurgeRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge run ID"})
+		return
+	}
+
+	valid, reason, err := h.service.VerifyRun(runID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": valid, "reason": reason})
+}
@@ -0,0 +1,476 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	engine "shared/retention"
+	"shared/retention/gormstore"
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrRuleNotFound   = engine.ErrRuleNotFound
+	ErrInvalidRule    = engine.ErrInvalidRule
+	ErrHoldNotFound   = engine.ErrHoldNotFound
+	ErrInvalidHold    = engine.ErrInvalidHold
+	ErrRunNotFound    = engine.ErrRunNotFound
+	ErrRunInProgress  = engine.ErrRunInProgress
+	ErrReportUnsigned = engine.ErrReportUnsigned
+)
+
+// Options configures scheduled runs, the report signing certificate and the periods of
+// the seeded rules
+type Options struct {
+	Enabled                bool
+	RunInterval            time.Duration
+	BatchSize              int
+	CertificatePath        string        // Signs purge reports
+	SigningKeyPath         string        // Private key for CertificatePath; may be bundled in the certificate file
+	DataRetentionPeriod    time.Duration // KYC, company and screening records
+	DeletedRecordRetention time.Duration // Closed accounts before they are anonymized
+}
+
+// OptionsFromEnv reads RETENTION_ENABLED, RETENTION_RUN_INTERVAL, RETENTION_BATCH_SIZE,
+// RETENTION_CERT_PATH, RETENTION_SIGNING_KEY_PATH, DATA_RETENTION_PERIOD and
+// DELETED_RECORD_RETENTION
+func OptionsFromEnv() Options {
+	opts := Options{
+		Enabled:                true,
+		RunInterval:            24 * time.Hour,
+		BatchSize:              500,
+		DataRetentionPeriod:    7 * 365 * 24 * time.Hour, // 7 years
+		DeletedRecordRetention: 90 * 24 * time.Hour,
+	}
+	if value, err := strconv.ParseBool(os.Getenv("RETENTION_ENABLED")); err == nil {
+		opts.Enabled = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("RETENTION_RUN_INTERVAL")); err == nil && value > 0 {
+		opts.RunInterval = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("RETENTION_BATCH_SIZE")); err == nil && value > 0 {
+		opts.BatchSize = value
+	}
+	opts.CertificatePath = os.Getenv("RETENTION_CERT_PATH")
+	opts.SigningKeyPath = os.Getenv("RETENTION_SIGNING_KEY_PATH")
+	if value, err := time.ParseDuration(os.Getenv("DATA_RETENTION_PERIOD")); err == nil && value > 0 {
+		opts.DataRetentionPeriod = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("DELETED_RECORD_RETENTION")); err == nil && value > 0 {
+		opts.DeletedRecordRetention = value
+	}
+	return opts
+}
+
+// tables describes how retention reaches each table. Regulated tables must be kept for
+// the data retention period.
+var tables = map[string]gormstore.Table{
+	"users": {
+		Entity:        engine.Entity{Bases: []string{"deleted_at", "last_login_at"}, AnonymizeOnly: true},
+		SubjectColumn: "id",
+		Anonymize: map[string]interface{}{
+			// Email and phone are unique, so they are replaced per row rather than blanked
+			"email":         gorm.Expr("'anonymized-' || id || '@redacted.invalid'"),
+			"phone":         nil,
+			"password":      "",
+			"first_name":    "",
+			"last_name":     "",
+			"profile_image": "",
+			"mfa_enabled":   false,
+			"mfa_secret":    "",
+			"backup_codes":  nil,
+			"last_login_ip": "",
+		},
+	},
+	"kyc_data": {
+		Entity:        engine.Entity{Bases: []string{"deleted_at", "reviewed_at"}, Regulated: true},
+		SubjectColumn: "user_id",
+		Children:      map[string]string{"kyc_checks": "kyc_id", "kyc_review_cases": "kyc_id"},
+	},
+	"companies": {
+		Entity:        engine.Entity{Bases: []string{"deleted_at"}, Regulated: true},
+		SubjectColumn: "user_id",
+		Children:      map[string]string{"company_documents": "company_id", "company_people": "company_id", "kyb_decisions": "company_id"},
+	},
+	"company_documents":   {Entity: engine.Entity{Bases: []string{"deleted_at"}, Regulated: true}},
+	"screening_results":   {Entity: engine.Entity{Bases: []string{"reviewed_at"}, Regulated: true}, SubjectColumn: "subject_id"},
+	"kyc_refreshes":       {Entity: engine.Entity{Bases: []string{"completed_at", "restricted_at", "cancelled_at"}, Regulated: true}, SubjectColumn: "user_id"},
+	"user_sessions":       {Entity: engine.Entity{Bases: []string{"expires_at", "last_used_at"}}, SubjectColumn: "user_id"},
+	"login_histories":     {Entity: engine.Entity{Bases: []string{"attempted_at"}}, SubjectColumn: "user_id"},
+	"known_devices":       {Entity: engine.Entity{Bases: []string{"last_seen_at"}}, SubjectColumn: "user_id"},
+	"login_confirmations": {Entity: engine.Entity{Bases: []string{"expires_at"}}, SubjectColumn: "user_id"},
+}
+
+// defaultRules are created on startup when missing. Existing rules are never
+// overwritten, so changes made through the API survive restarts.
+func defaultRules(opts Options) []models.RetentionRule {
+	regulatedDays := int(opts.DataRetentionPeriod.Hours() / 24)
+	deletedDays := int(opts.DeletedRecordRetention.Hours() / 24)
+	return []models.RetentionRule{
+		{Name: "closed_accounts", Entity: "users", Basis: "deleted_at", RetentionDays: deletedDays, Action: engine.ActionAnonymize,
+			Description: "Anonymize deleted accounts once the deleted record period has passed"},
+		{Name: "deleted_kyc_data", Entity: "kyc_data", Basis: "deleted_at", RetentionDays: regulatedDays, Action: engine.ActionPurge,
+			Description: "Delete KYC data of closed accounts after the data retention period"},
+		{Name: "deleted_companies", Entity: "companies", Basis: "deleted_at", RetentionDays: regulatedDays, Action: engine.ActionPurge,
+			Description: "Delete closed companies with their documents, directors, owners and KYB decisions after the data retention period"},
+		{Name: "deleted_company_documents", Entity: "company_documents", Basis: "deleted_at", RetentionDays: regulatedDays, Action: engine.ActionPurge,
+			Description: "Delete removed company documents after the data retention period"},
+		{Name: "kyc_refreshes", Entity: "kyc_refreshes", Basis: "updated_at", RetentionDays: regulatedDays, Action: engine.ActionPurge,
+			Description: "Delete re-verification requests and reminders after the data retention period"},
+		{Name: "screening_results", Entity: "screening_results", Basis: "created_at", RetentionDays: regulatedDays, Action: engine.ActionPurge,
+			Description: "Delete sanctions and PEP screening results after the data retention period"},
+		{Name: "expired_sessions", Entity: "user_sessions", Basis: "expires_at", RetentionDays: 30, Action: engine.ActionPurge,
+			Description: "Delete sessions a month after they expire"},
+		{Name: "login_history", Entity: "login_histories", Basis: "attempted_at", RetentionDays: 365, Action: engine.ActionPurge,
+			Description: "Delete login attempts after a year"},
+		{Name: "inactive_devices", Entity: "known_devices", Basis: "last_seen_at", RetentionDays: 365, Action: engine.ActionPurge,
+			Description: "Forget devices not used to sign in for a year"},
+		{Name: "expired_login_confirmations", Entity: "login_confirmations", Basis: "expires_at", RetentionDays: 30, Action: engine.ActionPurge,
+			Description: "Delete emailed login confirmation codes a month after they expire"},
+	}
+}
+
+// RuleRequest is the input for creating a rule
+type RuleRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Entity        string   `json:"entity" binding:"required"`
+	Basis         string   `json:"basis" binding:"required"`
+	Statuses      []string `json:"statuses"`
+	RetentionDays int      `json:"retention_days" binding:"required"`
+	Action        string   `json:"action" binding:"required"`
+	Description   string   `json:"description"`
+}
+
+// RuleUpdate changes the given fields of a rule
+type RuleUpdate struct {
+	RetentionDays *int      `json:"retention_days"`
+	Action        *string   `json:"action"`
+	Statuses      *[]string `json:"statuses"`
+	Enabled       *bool     `json:"enabled"`
+	Description   *string   `json:"description"`
+}
+
+// HoldRequest is the input for placing a hold
+type HoldRequest struct {
+	Entity        string     `json:"entity"`
+	RecordID      *uuid.UUID `json:"record_id"`
+	UserID        *uuid.UUID `json:"user_id"`
+	Reason        string     `json:"reason" binding:"required"`
+	CaseReference string     `json:"case_reference"`
+}
+
+// Service applies retention rules to the user tables and keeps a report of every run,
+// signed with the retention certificate
+type Service struct {
+	db     *gorm.DB
+	opts   Options
+	engine *engine.Engine
+}
+
+func NewService(db *gorm.DB, opts Options) *Service {
+	s := &Service{db: db, opts: opts}
+	s.engine = engine.NewEngine(&store{
+		Tables:  gormstore.Tables{DB: db, BatchSize: opts.BatchSize, Tables: tables},
+		service: s,
+	}, engine.Config{
+		Service:         "user-management-service",
+		CertificatePath: opts.CertificatePath,
+		SigningKeyPath:  opts.SigningKeyPath,
+		MinimumDays:     int(opts.DataRetentionPeriod.Hours() / 24),
+	})
+	return s
+}
+
+// SeedRules creates the default rules that do not exist yet
+func (s *Service) SeedRules() error {
+	for _, rule := range defaultRules(s.opts) {
+		rule := rule
+		rule.Enabled = true
+		rule.UpdatedBy = "system"
+		if err := s.db.Where("name = ?", rule.Name).FirstOrCreate(&rule).Error; err != nil {
+			return fmt.Errorf("failed to seed retention rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) ListRules() ([]models.RetentionRule, error) {
+	rules := []models.RetentionRule{}
+	if err := s.db.Order("entity ASC, name ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list retention rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *Service) CreateRule(req RuleRequest, actor string) (*models.RetentionRule, error) {
+	rule := &models.RetentionRule{
+		Name:          strings.TrimSpace(req.Name),
+		Entity:        req.Entity,
+		Basis:         req.Basis,
+		Statuses:      req.Statuses,
+		RetentionDays: req.RetentionDays,
+		Action:        req.Action,
+		Enabled:       true,
+		Description:   req.Description,
+		UpdatedBy:     actor,
+	}
+	if rule.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.RetentionRule{}).Where("name = ?", rule.Name).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check retention rules: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: a rule named %q already exists", ErrInvalidRule, rule.Name)
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create retention rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *Service) UpdateRule(name string, req RuleUpdate, actor string) (*models.RetentionRule, error) {
+	var rule models.RetentionRule
+	if err := s.db.Where("name = ?", name).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to load retention rule: %w", err)
+	}
+
+	if req.RetentionDays != nil {
+		rule.RetentionDays = *req.RetentionDays
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.Statuses != nil {
+		rule.Statuses = *req.Statuses
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.UpdatedBy = actor
+
+	if err := s.db.Save(&rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update retention rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *Service) validateRule(rule *models.RetentionRule) error {
+	return s.engine.ValidateRule(engineRule(*rule))
+}
+
+func engineRule(rule models.RetentionRule) engine.Rule {
+	return engine.Rule{
+		Name:          rule.Name,
+		Entity:        rule.Entity,
+		Basis:         rule.Basis,
+		Statuses:      rule.Statuses,
+		RetentionDays: rule.RetentionDays,
+		Action:        rule.Action,
+		Enabled:       rule.Enabled,
+	}
+}
+
+// PlaceHold places a legal hold on a row or on every row of a user
+func (s *Service) PlaceHold(req HoldRequest, actor string) (*models.LegalHold, error) {
+	if err := s.engine.ValidateHold(req.Entity, req.RecordID, req.UserID, req.Reason, "user_id"); err != nil {
+		return nil, err
+	}
+
+	hold := &models.LegalHold{
+		Entity:        req.Entity,
+		RecordID:      req.RecordID,
+		UserID:        req.UserID,
+		Reason:        strings.TrimSpace(req.Reason),
+		CaseReference: req.CaseReference,
+		PlacedBy:      actor,
+	}
+	if err := s.db.Create(hold).Error; err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+	return hold, nil
+}
+
+// ReleaseHold releases an active hold; releasing a released hold is a no-op
+func (s *Service) ReleaseHold(id uuid.UUID, actor, note string) (*models.LegalHold, error) {
+	var hold models.LegalHold
+	if err := s.db.Where("id = ?", id).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to load legal hold: %w", err)
+	}
+	if hold.ReleasedAt != nil {
+		return &hold, nil
+	}
+
+	now := time.Now()
+	hold.ReleasedAt = &now
+	hold.ReleasedBy = actor
+	hold.ReleaseNote = note
+	err := s.db.Model(&hold).Updates(map[string]interface{}{
+		"released_at":  hold.ReleasedAt,
+		"released_by":  hold.ReleasedBy,
+		"release_note": hold.ReleaseNote,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (s *Service) ListHolds(activeOnly bool) ([]models.LegalHold, error) {
+	holds := []models.LegalHold{}
+	query := s.db.Order("created_at DESC")
+	if activeOnly {
+		query = query.Where("released_at IS NULL")
+	}
+	if err := query.Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	return holds, nil
+}
+
+// RunPurge applies every enabled rule and stores a signed report of the run. Dry runs
+// only count what would be purged.
+func (s *Service) RunPurge(ctx context.Context, dryRun bool, triggeredBy string) (*models.PurgeRun, error) {
+	run, err := s.engine.RunPurge(ctx, dryRun, triggeredBy)
+	if run == nil {
+		return nil, err
+	}
+	return purgeRunModel(run), err
+}
+
+// VerifyRun checks a stored report against its digest and the certificate's signature
+func (s *Service) VerifyRun(id uuid.UUID) (bool, string, error) {
+	run, err := s.GetRun(id)
+	if err != nil {
+		return false, "", err
+	}
+	return s.engine.Verify(&engine.Run{
+		Report:                 run.Report,
+		ReportSHA256:           run.ReportSHA256,
+		Signature:              run.Signature,
+		CertificateFingerprint: run.CertificateFingerprint,
+	})
+}
+
+func (s *Service) GetRun(id uuid.UUID) (*models.PurgeRun, error) {
+	var run models.PurgeRun
+	if err := s.db.Where("id = ?", id).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRunNotFound
+		}
+		return nil, fmt.Errorf("failed to load purge run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListRuns returns the most recent runs without their report bodies
+func (s *Service) ListRuns(limit int) ([]models.PurgeRun, error) {
+	runs := []models.PurgeRun{}
+	if err := s.db.Omit("report").Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list purge runs: %w", err)
+	}
+	return runs, nil
+}
+
+// StartScheduler runs the retention rules every RunInterval until ctx is cancelled
+func (s *Service) StartScheduler(ctx context.Context) {
+	if !s.opts.Enabled {
+		return
+	}
+	s.engine.Schedule(ctx, s.opts.RunInterval)
+}
+
+// store keeps rules, holds and runs in the service's models
+type store struct {
+	gormstore.Tables
+	service *Service
+}
+
+func (r *store) Rules(ctx context.Context) ([]engine.Rule, error) {
+	rules, err := r.service.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]engine.Rule, len(rules))
+	for i, rule := range rules {
+		out[i] = engineRule(rule)
+	}
+	return out, nil
+}
+
+func (r *store) ActiveHolds(ctx context.Context) ([]engine.Hold, error) {
+	holds, err := r.service.ListHolds(true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]engine.Hold, len(holds))
+	for i, hold := range holds {
+		out[i] = engine.Hold{Entity: hold.Entity, RecordID: hold.RecordID, SubjectID: hold.UserID}
+	}
+	return out, nil
+}
+
+func (r *store) StartRun(ctx context.Context, run *engine.Run, abandonBefore time.Time) error {
+	r.DB.Model(&models.PurgeRun{}).
+		Where("status = ? AND started_at < ?", engine.RunRunning, abandonBefore).
+		Update("status", engine.RunAbandoned)
+
+	if err := r.DB.Create(purgeRunModel(run)).Error; err != nil {
+		var running int64
+		r.DB.Model(&models.PurgeRun{}).Where("status = ?", engine.RunRunning).Count(&running)
+		if running > 0 {
+			return ErrRunInProgress
+		}
+		return fmt.Errorf("failed to start purge run: %w", err)
+	}
+	return nil
+}
+
+func (r *store) FinishRun(ctx context.Context, run *engine.Run) error {
+	return r.DB.WithContext(ctx).Save(purgeRunModel(run)).Error
+}
+
+func purgeRunModel(run *engine.Run) *models.PurgeRun {
+	return &models.PurgeRun{
+		ID:                     run.ID,
+		Status:                 run.Status,
+		DryRun:                 run.DryRun,
+		TriggeredBy:            run.TriggeredBy,
+		Purged:                 run.Purged,
+		Anonymized:             run.Anonymized,
+		Held:                   run.Held,
+		Error:                  run.Error,
+		Report:                 run.Report,
+		ReportSHA256:           run.ReportSHA256,
+		Signature:              run.Signature,
+		SignatureAlgorithm:     run.SignatureAlgorithm,
+		CertificateFingerprint: run.CertificateFingerprint,
+		StartedAt:              run.StartedAt,
+		CompletedAt:            run.CompletedAt,
+	}
+}
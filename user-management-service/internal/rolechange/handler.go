@@ -0,0 +1,182 @@
+package rolechange
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"user-management-service/internal/models"
+)
+
+// Handler exposes role change requests and their approval over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRole), errors.Is(err, ErrSameRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOwnRole), errors.Is(err, ErrSameReviewer), errors.Is(err, ErrNotRequester):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyPending), errors.Is(err, ErrNotPending), errors.Is(err, ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Role change operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func requestID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// RequestRoleChange replaces the direct role update: the change waits for other
+// administrators to confirm it
+func (h *Handler) RequestRoleChange(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var req struct {
+		Role   models.UserRole `json:"role" binding:"required"`
+		Reason string          `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	requester, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.service.Request(userID, requester, req.Role, req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Role change is waiting for approval by another administrator",
+		"request": request,
+	})
+}
+
+func (h *Handler) GetRequests(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filter := Filter{Status: c.Query("status"), Limit: limit, Offset: offset}
+	if userID, err := uuid.Parse(c.Query("user_id")); err == nil {
+		filter.UserID = &userID
+	}
+
+	requests, total, err := h.service.List(filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requests": requests, "total": total})
+}
+
+func (h *Handler) GetRequest(c *gin.Context) {
+	id, ok := requestID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.service.Get(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *Handler) Approve(c *gin.Context) {
+	h.decide(c, DecisionApprove)
+}
+
+func (h *Handler) Reject(c *gin.Context) {
+	h.decide(c, DecisionReject)
+}
+
+func (h *Handler) decide(c *gin.Context, decision string) {
+	id, ok := requestID(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	approver, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request *models.RoleChangeRequest
+	var err error
+	if decision == DecisionReject {
+		request, err = h.service.Reject(id, approver, req.Note, c.ClientIP())
+	} else {
+		request, err = h.service.Approve(id, approver, req.Note, c.ClientIP())
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if request.Status == StatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": request.FailureReason, "request": request})
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *Handler) Cancel(c *gin.Context) {
+	id, ok := requestID(c)
+	if !ok {
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.service.Cancel(id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
@@ -0,0 +1,359 @@
+package rolechange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrRequestNotFound = errors.New("role change request not found")
+	ErrInvalidRole     = errors.New("role must be sme, buyer, bank or admin")
+	ErrSameRole        = errors.New("the user already has this role")
+	ErrOwnRole         = errors.New("administrators cannot change or confirm a change of their own role")
+	ErrAlreadyPending  = errors.New("a role change for this user is already waiting for approval")
+	ErrNotPending      = errors.New("the role change is no longer waiting for approval")
+	ErrExpired         = errors.New("the role change was not confirmed in time")
+	ErrSameReviewer    = errors.New("the role change must be approved by a different administrator")
+	ErrAlreadyDecided  = errors.New("you have already decided on this role change")
+	ErrNotRequester    = errors.New("only the administrator who requested the role change can cancel it")
+	ErrRoleChanged     = errors.New("the user's role changed while the request was pending")
+)
+
+// Request statuses
+const (
+	StatusPending   = "pending"
+	StatusApplied   = "applied"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+	StatusFailed    = "failed"
+)
+
+// Decisions of an approver
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+var validRoles = map[models.UserRole]bool{
+	models.RoleSME:   true,
+	models.RoleBuyer: true,
+	models.RoleBank:  true,
+	models.RoleAdmin: true,
+}
+
+// Options configures how many confirmations a role change needs and how long it waits
+type Options struct {
+	Window              time.Duration
+	DefaultApprovals    int                     // Confirmations needed besides the requester
+	Approvals           map[models.UserRole]int // Per target role, e.g. more for granting admin
+	ExpiryCheckInterval time.Duration
+}
+
+// OptionsFromEnv reads ROLE_CHANGE_APPROVAL_WINDOW, ROLE_CHANGE_APPROVALS (a default
+// count, then role=count pairs such as "1,admin=2") and ROLE_CHANGE_EXPIRY_CHECK_INTERVAL
+func OptionsFromEnv() Options {
+	opts := Options{
+		Window:              24 * time.Hour,
+		DefaultApprovals:    1,
+		Approvals:           map[models.UserRole]int{models.RoleAdmin: 2},
+		ExpiryCheckInterval: 15 * time.Minute,
+	}
+
+	if approvals := os.Getenv("ROLE_CHANGE_APPROVALS"); approvals != "" {
+		opts.Approvals = map[models.UserRole]int{}
+		for _, part := range strings.Split(approvals, ",") {
+			role, count, found := strings.Cut(strings.TrimSpace(part), "=")
+			if !found {
+				count, role = role, ""
+			}
+			value, err := strconv.Atoi(strings.TrimSpace(count))
+			if err != nil || value < 1 {
+				continue // Every role change needs at least one other administrator
+			}
+			if role == "" {
+				opts.DefaultApprovals = value
+			} else {
+				opts.Approvals[models.UserRole(strings.ToLower(strings.TrimSpace(role)))] = value
+			}
+		}
+	}
+	for key, target := range map[string]*time.Duration{
+		"ROLE_CHANGE_APPROVAL_WINDOW":       &opts.Window,
+		"ROLE_CHANGE_EXPIRY_CHECK_INTERVAL": &opts.ExpiryCheckInterval,
+	} {
+		if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+			*target = value
+		}
+	}
+	return opts
+}
+
+// Service puts role changes under maker-checker control: an administrator requests the
+// change and other administrators must confirm it before the role is updated
+type Service struct {
+	db   *gorm.DB
+	opts Options
+}
+
+func NewService(db *gorm.DB, opts Options) *Service {
+	return &Service{db: db, opts: opts}
+}
+
+// RequiredApprovals is the number of confirmations needed to give a user the role
+func (s *Service) RequiredApprovals(role models.UserRole) int {
+	if count, ok := s.opts.Approvals[role]; ok {
+		return count
+	}
+	return s.opts.DefaultApprovals
+}
+
+// Request opens a role change for the user; the role is only updated once confirmed
+func (s *Service) Request(userID, requestedBy uuid.UUID, role models.UserRole, reason string) (*models.RoleChangeRequest, error) {
+	if !validRoles[role] {
+		return nil, ErrInvalidRole
+	}
+	if userID == requestedBy {
+		return nil, ErrOwnRole
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Role == role {
+		return nil, ErrSameRole
+	}
+
+	request := models.RoleChangeRequest{
+		UserID:            userID,
+		FromRole:          user.Role,
+		ToRole:            role,
+		Reason:            reason,
+		RequestedBy:       requestedBy,
+		RequiredApprovals: s.RequiredApprovals(role),
+		Status:            StatusPending,
+		ExpiresAt:         time.Now().Add(s.opts.Window),
+	}
+	if err := s.db.Create(&request).Error; err != nil {
+		var pending int64
+		s.db.Model(&models.RoleChangeRequest{}).Where("user_id = ? AND status = ?", userID, StatusPending).Count(&pending)
+		if pending > 0 {
+			return nil, ErrAlreadyPending
+		}
+		return nil, fmt.Errorf("failed to request role change: %w", err)
+	}
+	return &request, nil
+}
+
+// Approve records a confirmation and updates the user's role once enough administrators
+// confirmed. Neither the requester nor the user concerned can confirm.
+func (s *Service) Approve(requestID, approver uuid.UUID, note, ipAddress string) (*models.RoleChangeRequest, error) {
+	return s.decide(requestID, approver, DecisionApprove, note, ipAddress)
+}
+
+// Reject closes the request; a single rejection is enough
+func (s *Service) Reject(requestID, approver uuid.UUID, note, ipAddress string) (*models.RoleChangeRequest, error) {
+	return s.decide(requestID, approver, DecisionReject, note, ipAddress)
+}
+
+func (s *Service) decide(requestID, approver uuid.UUID, decision, note, ipAddress string) (*models.RoleChangeRequest, error) {
+	var request models.RoleChangeRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockRequest(tx, requestID, &request); err != nil {
+			return err
+		}
+		if request.Status != StatusPending {
+			return ErrNotPending
+		}
+		now := time.Now()
+		if now.After(request.ExpiresAt) {
+			return ErrExpired // Marked expired by the expiry monitor
+		}
+		if approver == request.RequestedBy {
+			return ErrSameReviewer
+		}
+		if approver == request.UserID {
+			return ErrOwnRole
+		}
+
+		var decided int64
+		if err := tx.Model(&models.RoleChangeDecision{}).
+			Where("request_id = ? AND user_id = ?", request.ID, approver).Count(&decided).Error; err != nil {
+			return err
+		}
+		if decided > 0 {
+			return ErrAlreadyDecided
+		}
+		if err := tx.Create(&models.RoleChangeDecision{
+			RequestID: request.ID,
+			UserID:    approver,
+			Decision:  decision,
+			Note:      note,
+			IPAddress: ipAddress,
+		}).Error; err != nil {
+			return err
+		}
+
+		if decision == DecisionReject {
+			request.Status = StatusRejected
+			request.ClosedAt = &now
+			return tx.Omit(clause.Associations).Save(&request).Error
+		}
+
+		request.Approvals++
+		if request.Approvals < request.RequiredApprovals {
+			return tx.Omit(clause.Associations).Save(&request).Error
+		}
+		return s.apply(tx, &request, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(request.ID)
+}
+
+// apply updates the user's role, provided nobody changed it while the request was pending
+func (s *Service) apply(tx *gorm.DB, request *models.RoleChangeRequest, now time.Time) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", request.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	request.ClosedAt = &now
+	if user.Role != request.FromRole {
+		request.Status = StatusFailed
+		request.FailureReason = ErrRoleChanged.Error()
+		return tx.Omit(clause.Associations).Save(request).Error
+	}
+	if err := tx.Model(&user).Update("role", request.ToRole).Error; err != nil {
+		return err
+	}
+	request.Status = StatusApplied
+	return tx.Omit(clause.Associations).Save(request).Error
+}
+
+// Cancel withdraws a pending request; only its requester can
+func (s *Service) Cancel(requestID, userID uuid.UUID) (*models.RoleChangeRequest, error) {
+	var request models.RoleChangeRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockRequest(tx, requestID, &request); err != nil {
+			return err
+		}
+		if request.RequestedBy != userID {
+			return ErrNotRequester
+		}
+		if request.Status != StatusPending {
+			return ErrNotPending
+		}
+		now := time.Now()
+		request.Status = StatusCancelled
+		request.ClosedAt = &now
+		return tx.Omit(clause.Associations).Save(&request).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func lockRequest(tx *gorm.DB, requestID uuid.UUID, request *models.RoleChangeRequest) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(request, "id = ?", requestID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRequestNotFound
+	}
+	return err
+}
+
+func (s *Service) Get(requestID uuid.UUID) (*models.RoleChangeRequest, error) {
+	var request models.RoleChangeRequest
+	err := s.db.Preload("Decisions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).First(&request, "id = ?", requestID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// Filter narrows the list of role change requests
+type Filter struct {
+	Status string
+	UserID *uuid.UUID
+	Limit  int
+	Offset int
+}
+
+func (s *Service) List(filter Filter) ([]models.RoleChangeRequest, int64, error) {
+	query := s.db.Model(&models.RoleChangeRequest{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+
+	var requests []models.RoleChangeRequest
+	err := query.Preload("Decisions").Order("created_at DESC").
+		Limit(filter.Limit).Offset(filter.Offset).Find(&requests).Error
+	return requests, total, err
+}
+
+// ExpireStale closes pending requests that were not confirmed within the window
+func (s *Service) ExpireStale() (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&models.RoleChangeRequest{}).
+		Where("status = ? AND expires_at < ?", StatusPending, now).
+		Updates(map[string]interface{}{"status": StatusExpired, "closed_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// StartExpiryMonitor expires stale requests until the context is cancelled
+func (s *Service) StartExpiryMonitor(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.ExpiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if expired, err := s.ExpireStale(); err != nil {
+			log.Printf("Role change expiry check failed: %v", err)
+		} else if expired > 0 {
+			log.Printf("Expired %d unconfirmed role changes", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package screening
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler exposes screening over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrResultNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Screening result not found"})
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, ErrAlreadyReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidDecision), errors.Is(err, ErrInvalidSubject):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNoActiveLists):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Screening lists are not loaded"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Screening failed"})
+	}
+}
+
+// ScreenSubject screens an invoice buyer, payment counterparty or other party and stores
+// the result
+func (h *Handler) ScreenSubject(c *gin.Context) {
+	var req Subject
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.Type {
+	case "buyer", "counterparty", "company", "user":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subject_type must be buyer, counterparty, company or user"})
+		return
+	}
+
+	result, err := h.service.ScreenAndRecord(req, "api")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ScreenUser screens a registered customer and their company on demand
+func (h *Handler) ScreenUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	results, err := h.service.ScreenUser(userID, "manual")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func (h *Handler) GetResults(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	results, total, err := h.service.ListResults(ResultFilter{
+		Status:      c.Query("status"),
+		SubjectType: c.Query("subject_type"),
+		SubjectID:   c.Query("subject_id"),
+		Page:        page,
+		Limit:       limit,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *Handler) GetResult(c *gin.Context) {
+	resultID, err := uuid.Parse(c.Param("resultId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return
+	}
+
+	result, err := h.service.GetResult(resultID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ReviewResult confirms or dismisses the hits of a potential match
+func (h *Handler) ReviewResult(c *gin.Context) {
+	resultID, err := uuid.Parse(c.Param("resultId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return
+	}
+
+	var req struct {
+		Decision string `json:"decision" binding:"required"` // confirm, dismiss
+		Note     string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviewer, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	result, err := h.service.ReviewResult(resultID, req.Decision, req.Note, reviewer)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) GetLists(c *gin.Context) {
+	lists, err := h.service.ListLists(c.Query("active") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lists": lists})
+}
+
+// ReloadLists loads any new list files immediately instead of waiting for the watcher
+func (h *Handler) ReloadLists(c *gin.Context) {
+	lists, err := h.service.SyncLists()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "loaded": lists})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loaded": lists})
+}
@@ -0,0 +1,400 @@
+package screening
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"user-management-service/internal/models"
+)
+
+// List sources
+const (
+	SourceOFAC = "ofac_sdn"
+	SourceEU   = "eu_consolidated"
+	SourceUN   = "un_consolidated"
+	SourcePEP  = "pep"
+)
+
+// listFile describes where a source is read from in the list directory and how
+type listFile struct {
+	source string
+	files  []string // first file is required, the rest are optional companions
+	parse  func(dir string) (*parsedList, error)
+}
+
+var listFiles = []listFile{
+	{source: SourceOFAC, files: []string{"sdn.csv", "alt.csv"}, parse: parseOFAC},
+	{source: SourceEU, files: []string{"eu_consolidated.csv"}, parse: parseEU},
+	{source: SourceUN, files: []string{"un_consolidated.xml"}, parse: parseUN},
+	{source: SourcePEP, files: []string{"pep.csv"}, parse: parsePEP},
+}
+
+// parsedList is the content of a list file ready to be stored
+type parsedList struct {
+	version string // publication date when the file carries one
+	entries []models.ScreeningEntry
+}
+
+var ofacDOB = regexp.MustCompile(`DOB (\d{1,2} [A-Za-z]{3} \d{4}|[A-Za-z]{3} \d{4}|\d{4})`)
+
+// parseOFAC reads the OFAC SDN list in its legacy CSV layout: sdn.csv holds the primary
+// records and alt.csv the aliases. Neither file has a header; "-0-" marks an empty field.
+func parseOFAC(dir string) (*parsedList, error) {
+	records, err := readCSV(filepath.Join(dir, "sdn.csv"), ',')
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.ScreeningEntry{}
+	byID := map[string]int{}
+	for _, record := range records {
+		if len(record) < 4 || ofacValue(record[0]) == "" || ofacValue(record[1]) == "" {
+			continue
+		}
+		entryType := "entity"
+		if strings.EqualFold(ofacValue(record[2]), "individual") {
+			entryType = "individual"
+		}
+		entry := models.ScreeningEntry{
+			Source:     SourceOFAC,
+			ExternalID: ofacValue(record[0]),
+			EntryType:  entryType,
+			Name:       ofacValue(record[1]),
+			Programs:   splitPrograms(ofacValue(record[3])),
+		}
+		if len(record) > 11 {
+			entry.Remarks = ofacValue(record[11])
+			for _, match := range ofacDOB.FindAllStringSubmatch(entry.Remarks, -1) {
+				entry.DatesOfBirth = append(entry.DatesOfBirth, ofacDate(match[1]))
+			}
+		}
+		byID[entry.ExternalID] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	aliases, err := readCSV(filepath.Join(dir, "alt.csv"), ',')
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, record := range aliases {
+		if len(record) < 4 {
+			continue
+		}
+		if i, ok := byID[ofacValue(record[0])]; ok && ofacValue(record[3]) != "" {
+			entries[i].Aliases = append(entries[i].Aliases, ofacValue(record[3]))
+		}
+	}
+	return &parsedList{entries: entries}, nil
+}
+
+func ofacValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "-0-" {
+		return ""
+	}
+	return value
+}
+
+// ofacDate converts "12 Jan 1960" to 1960-01-12; year-only and month-year dates keep
+// just the year
+func ofacDate(value string) string {
+	months := map[string]string{"jan": "01", "feb": "02", "mar": "03", "apr": "04", "may": "05", "jun": "06",
+		"jul": "07", "aug": "08", "sep": "09", "oct": "10", "nov": "11", "dec": "12"}
+	parts := strings.Fields(value)
+	switch len(parts) {
+	case 3:
+		if month, ok := months[strings.ToLower(parts[1])]; ok {
+			day := parts[0]
+			if len(day) == 1 {
+				day = "0" + day
+			}
+			return parts[2] + "-" + month + "-" + day
+		}
+		return parts[2]
+	case 2:
+		return parts[1]
+	default:
+		return value
+	}
+}
+
+func splitPrograms(value string) []string {
+	programs := []string{}
+	for _, program := range strings.Split(strings.Trim(value, "[] "), "] [") {
+		if program = strings.TrimSpace(program); program != "" {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// parseEU reads the EU consolidated financial sanctions list CSV. It has one row per
+// name, birth date, citizenship and address combination, grouped by Entity_LogicalId.
+func parseEU(dir string) (*parsedList, error) {
+	records, err := readCSV(filepath.Join(dir, "eu_consolidated.csv"), ';')
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 1 {
+		return &parsedList{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	if _, ok := columns["Entity_LogicalId"]; !ok {
+		return nil, fmt.Errorf("eu_consolidated.csv: missing Entity_LogicalId column")
+	}
+
+	list := &parsedList{}
+	byID := map[string]int{}
+	for _, record := range records[1:] {
+		id := field(record, "Entity_LogicalId")
+		if id == "" {
+			continue
+		}
+		if list.version == "" {
+			list.version = field(record, "fileGenerationDate")
+		}
+
+		i, ok := byID[id]
+		if !ok {
+			entryType := "entity"
+			if strings.EqualFold(field(record, "Entity_SubjectType_ClassificationCode"), "person") {
+				entryType = "individual"
+			}
+			list.entries = append(list.entries, models.ScreeningEntry{
+				Source:     SourceEU,
+				ExternalID: id,
+				EntryType:  entryType,
+				Remarks:    field(record, "Entity_Remark"),
+			})
+			i = len(list.entries) - 1
+			byID[id] = i
+		}
+		entry := &list.entries[i]
+
+		if name := field(record, "NameAlias_WholeName"); name != "" {
+			if entry.Name == "" {
+				entry.Name = name
+			} else if name != entry.Name && !containsFold(entry.Aliases, name) {
+				entry.Aliases = append(entry.Aliases, name)
+			}
+		}
+		appendUnique(&entry.Programs, field(record, "Entity_Regulation_Programme"))
+		appendUnique(&entry.DatesOfBirth, field(record, "BirthDate_BirthDate"))
+		appendUnique(&entry.Countries, strings.ToUpper(field(record, "Citizenship_CountryIso2Code")))
+		appendUnique(&entry.Countries, strings.ToUpper(field(record, "Address_CountryIso2Code")))
+	}
+
+	// Entries without any name cannot be matched
+	named := list.entries[:0]
+	for _, entry := range list.entries {
+		if entry.Name != "" {
+			named = append(named, entry)
+		}
+	}
+	list.entries = named
+	return list, nil
+}
+
+// UN Security Council consolidated list XML
+type unConsolidatedList struct {
+	XMLName       xml.Name       `xml:"CONSOLIDATED_LIST"`
+	DateGenerated string         `xml:"dateGenerated,attr"`
+	Individuals   []unIndividual `xml:"INDIVIDUALS>INDIVIDUAL"`
+	Entities      []unEntity     `xml:"ENTITIES>ENTITY"`
+}
+
+type unIndividual struct {
+	DataID        string    `xml:"DATAID"`
+	FirstName     string    `xml:"FIRST_NAME"`
+	SecondName    string    `xml:"SECOND_NAME"`
+	ThirdName     string    `xml:"THIRD_NAME"`
+	FourthName    string    `xml:"FOURTH_NAME"`
+	ListType      string    `xml:"UN_LIST_TYPE"`
+	Comments      string    `xml:"COMMENTS1"`
+	Aliases       []unAlias `xml:"INDIVIDUAL_ALIAS"`
+	DatesOfBirth  []unDate  `xml:"INDIVIDUAL_DATE_OF_BIRTH"`
+	Nationalities []string  `xml:"NATIONALITY>VALUE"`
+}
+
+type unEntity struct {
+	DataID   string    `xml:"DATAID"`
+	Name     string    `xml:"FIRST_NAME"`
+	ListType string    `xml:"UN_LIST_TYPE"`
+	Comments string    `xml:"COMMENTS1"`
+	Aliases  []unAlias `xml:"ENTITY_ALIAS"`
+}
+
+type unAlias struct {
+	Quality string `xml:"QUALITY"`
+	Name    string `xml:"ALIAS_NAME"`
+}
+
+type unDate struct {
+	Date string `xml:"DATE"`
+	Year string `xml:"YEAR"`
+}
+
+func parseUN(dir string) (*parsedList, error) {
+	file, err := os.Open(filepath.Join(dir, "un_consolidated.xml"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var doc unConsolidatedList
+	if err := xml.NewDecoder(file).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("un_consolidated.xml: %w", err)
+	}
+
+	list := &parsedList{version: doc.DateGenerated}
+	for _, person := range doc.Individuals {
+		name := strings.Join(strings.Fields(strings.Join([]string{person.FirstName, person.SecondName, person.ThirdName, person.FourthName}, " ")), " ")
+		if name == "" {
+			continue
+		}
+		entry := models.ScreeningEntry{
+			Source:     SourceUN,
+			ExternalID: person.DataID,
+			EntryType:  "individual",
+			Name:       name,
+			Aliases:    unAliasNames(person.Aliases),
+			Programs:   []string{person.ListType},
+			Remarks:    person.Comments,
+		}
+		// Nationalities are usually published as country names; only ISO codes are kept
+		for _, nationality := range person.Nationalities {
+			if nationality = strings.TrimSpace(nationality); len(nationality) == 2 {
+				appendUnique(&entry.Countries, strings.ToUpper(nationality))
+			}
+		}
+		for _, dob := range person.DatesOfBirth {
+			if dob.Date != "" {
+				appendUnique(&entry.DatesOfBirth, dob.Date)
+			} else {
+				appendUnique(&entry.DatesOfBirth, dob.Year)
+			}
+		}
+		list.entries = append(list.entries, entry)
+	}
+	for _, org := range doc.Entities {
+		if strings.TrimSpace(org.Name) == "" {
+			continue
+		}
+		list.entries = append(list.entries, models.ScreeningEntry{
+			Source:     SourceUN,
+			ExternalID: org.DataID,
+			EntryType:  "entity",
+			Name:       strings.TrimSpace(org.Name),
+			Aliases:    unAliasNames(org.Aliases),
+			Programs:   []string{org.ListType},
+			Remarks:    org.Comments,
+		})
+	}
+	return list, nil
+}
+
+// unAliasNames keeps good and low quality aliases; both are published as known names
+func unAliasNames(aliases []unAlias) []string {
+	names := []string{}
+	for _, alias := range aliases {
+		appendUnique(&names, alias.Name)
+	}
+	return names
+}
+
+// parsePEP reads a locally maintained politically exposed persons CSV with the header
+// id,name,aliases,country,position,date_of_birth. Aliases are separated by "|".
+func parsePEP(dir string) (*parsedList, error) {
+	records, err := readCSV(filepath.Join(dir, "pep.csv"), ',')
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 1 {
+		return &parsedList{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("pep.csv: missing name column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	list := &parsedList{}
+	for _, record := range records[1:] {
+		name := field(record, "name")
+		if name == "" {
+			continue
+		}
+		entry := models.ScreeningEntry{
+			Source:     SourcePEP,
+			ExternalID: field(record, "id"),
+			EntryType:  "individual",
+			Name:       name,
+			Remarks:    field(record, "position"),
+		}
+		for _, alias := range strings.Split(field(record, "aliases"), "|") {
+			appendUnique(&entry.Aliases, strings.TrimSpace(alias))
+		}
+		appendUnique(&entry.Countries, strings.ToUpper(field(record, "country")))
+		appendUnique(&entry.DatesOfBirth, field(record, "date_of_birth"))
+		list.entries = append(list.entries, entry)
+	}
+	return list, nil
+}
+
+func readCSV(path string, delimiter rune) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = delimiter
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	records := [][]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func appendUnique(values *[]string, value string) {
+	if value == "" || containsFold(*values, value) {
+		return
+	}
+	*values = append(*values, value)
+}
@@ -0,0 +1,333 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+
+	"user-management-service/internal/models"
+)
+
+// Subject is a person or organisation to screen
+type Subject struct {
+	Type        string   `json:"subject_type"` // user, company, buyer, counterparty
+	ID          string   `json:"subject_id"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	EntityType  string   `json:"entity_type,omitempty"`   // individual, entity; empty when unknown
+	DateOfBirth string   `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+	Countries   []string `json:"countries,omitempty"`     // ISO codes
+}
+
+// indexedName is one name or alias of a list entry, normalised for matching
+type indexedName struct {
+	entry  int
+	name   string
+	tokens []string
+}
+
+// index holds the active list entries in memory. Names are blocked on the phonetic key
+// of each token, so a query only scores names with a token that sounds like one of its
+// own.
+type index struct {
+	entries  []models.ScreeningEntry
+	versions map[string]string // source -> active version
+	names    []indexedName
+	blocks   map[string][]int // phonetic key -> positions in names
+}
+
+func newIndex(entries []models.ScreeningEntry, versions map[string]string) *index {
+	idx := &index{
+		entries:  entries,
+		versions: versions,
+		blocks:   make(map[string][]int),
+	}
+	for i, entry := range entries {
+		for _, name := range append([]string{entry.Name}, entry.Aliases...) {
+			tokens := Normalize(name)
+			if len(tokens) == 0 {
+				continue
+			}
+			pos := len(idx.names)
+			idx.names = append(idx.names, indexedName{entry: i, name: name, tokens: tokens})
+			seen := map[string]bool{}
+			for _, token := range tokens {
+				key := blockKey(token)
+				if !seen[key] {
+					seen[key] = true
+					idx.blocks[key] = append(idx.blocks[key], pos)
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// soundexCodes holds the Soundex digit of each letter a-z; 0 marks vowels and y
+const soundexCodes = "01230120022455012623010202"
+
+// blockKey returns the Soundex code of a normalised token, so spellings that sound alike
+// share a block even when they differ early on, such as "Mohammed" and "Muhammad" or
+// "Chaudhry" and "Choudhury". A leading vowel is kept as "a" so that "Osama" and "Usama"
+// share one too. Tokens that do not start with a letter block on themselves.
+func blockKey(token string) string {
+	if token == "" || token[0] < 'a' || token[0] > 'z' {
+		return token
+	}
+
+	key := []byte{token[0]}
+	switch token[0] {
+	case 'a', 'e', 'i', 'o', 'u':
+		key[0] = 'a'
+	}
+	last := soundexCodes[token[0]-'a']
+	for i := 1; i < len(token) && len(key) < 4; i++ {
+		ch := token[i]
+		if ch == 'h' || ch == 'w' {
+			// h and w do not separate letters with the same code
+			continue
+		}
+		if ch < 'a' || ch > 'z' || soundexCodes[ch-'a'] == '0' {
+			last = '0'
+			continue
+		}
+		if code := soundexCodes[ch-'a']; code != last {
+			key = append(key, code)
+			last = code
+		}
+	}
+	for len(key) < 4 {
+		key = append(key, '0')
+	}
+	return string(key)
+}
+
+// match scores subject against the index and returns hits at or above threshold, best
+// first, with at most one hit per list entry
+func (idx *index) match(subject Subject, threshold float64, maxHits int) []models.ScreeningHit {
+	best := map[int]models.ScreeningHit{}
+	for _, name := range append([]string{subject.Name}, subject.Aliases...) {
+		query := Normalize(name)
+		if len(query) == 0 {
+			continue
+		}
+
+		candidates := map[int]bool{}
+		for _, token := range query {
+			for _, pos := range idx.blocks[blockKey(token)] {
+				candidates[pos] = true
+			}
+		}
+
+		for pos := range candidates {
+			candidate := idx.names[pos]
+			entry := &idx.entries[candidate.entry]
+			score, reasons := adjustScore(nameScore(query, candidate.tokens), subject, entry)
+			if score < threshold {
+				continue
+			}
+			if current, ok := best[candidate.entry]; ok && current.Score >= score {
+				continue
+			}
+			best[candidate.entry] = models.ScreeningHit{
+				EntryID:     entry.ID,
+				Source:      entry.Source,
+				ListVersion: idx.versions[entry.Source],
+				ExternalID:  entry.ExternalID,
+				EntryType:   entry.EntryType,
+				ListedName:  entry.Name,
+				MatchedName: candidate.name,
+				Score:       round3(score),
+				Programs:    entry.Programs,
+				Reasons:     reasons,
+			}
+		}
+	}
+
+	hits := make([]models.ScreeningHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ListedName < hits[j].ListedName
+	})
+	if maxHits > 0 && len(hits) > maxHits {
+		hits = hits[:maxHits]
+	}
+	return hits
+}
+
+// adjustScore applies the secondary identifiers: a matching date of birth or country
+// raises the score, a conflicting date of birth or entity type lowers it
+func adjustScore(score float64, subject Subject, entry *models.ScreeningEntry) (float64, []string) {
+	reasons := []string{"name_match"}
+
+	if subject.DateOfBirth != "" && len(entry.DatesOfBirth) > 0 {
+		if dateOfBirthMatches(subject.DateOfBirth, entry.DatesOfBirth) {
+			score += 0.05
+			reasons = append(reasons, "date_of_birth_match")
+		} else {
+			score -= 0.10
+			reasons = append(reasons, "date_of_birth_mismatch")
+		}
+	}
+	for _, country := range subject.Countries {
+		if containsFold(entry.Countries, country) {
+			score += 0.03
+			reasons = append(reasons, "country_match")
+			break
+		}
+	}
+	if subject.EntityType != "" && entry.EntryType != "" && subject.EntityType != entry.EntryType {
+		score -= 0.15
+		reasons = append(reasons, "entity_type_mismatch")
+	}
+
+	if score > 1 {
+		score = 1
+	}
+	return score, reasons
+}
+
+// dateOfBirthMatches compares a YYYY-MM-DD date with listed dates, which are often only
+// a year
+func dateOfBirthMatches(dob string, listed []string) bool {
+	for _, value := range listed {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if value == dob || (len(value) == 4 && strings.HasPrefix(dob, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameScore compares two normalised names, 0-1. It takes the better of a whole-string
+// comparison and a token alignment, where each token of the shorter name is paired with
+// its closest unused token in the longer one. Unmatched extra tokens reduce the score,
+// most steeply for single-token names so a lone surname does not match every listed
+// person who shares it, while a missing middle name or patronymic costs little.
+func nameScore(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	whole := jaroWinkler(strings.Join(a, " "), strings.Join(b, " "))
+
+	short, long := a, b
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	used := make([]bool, len(long))
+	total := 0.0
+	for _, token := range short {
+		best, bestAt := 0.0, -1
+		for j, other := range long {
+			if used[j] {
+				continue
+			}
+			if score := jaroWinkler(token, other); score > best {
+				best, bestAt = score, j
+			}
+		}
+		if bestAt >= 0 {
+			used[bestAt] = true
+		}
+		total += best
+	}
+	coverage := float64(len(short)) / float64(len(long))
+	weight := 0.2
+	if len(short) == 1 {
+		weight = 0.3
+	}
+	aligned := total / float64(len(short)) * (1 - weight + weight*coverage)
+
+	if aligned > whole {
+		return aligned
+	}
+	return whole
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings, 0-1
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	la, lb := len(ra), len(rb)
+	if la == 0 || lb == 0 {
+		return 0
+	}
+
+	window := la
+	if lb > window {
+		window = lb
+	}
+	window = window/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, la)
+	matchedB := make([]bool, lb)
+	matches := 0
+	for i := 0; i < la; i++ {
+		start, end := i-window, i+window+1
+		if start < 0 {
+			start = 0
+		}
+		if end > lb {
+			end = lb
+		}
+		for j := start; j < end; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, k := 0, 0
+	for i := 0; i < la; i++ {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[k] {
+			k++
+		}
+		if ra[i] != rb[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(la) + m/float64(lb) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < 4 && prefix < la && prefix < lb && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), strings.TrimSpace(target)) {
+			return true
+		}
+	}
+	return false
+}
+
+func round3(value float64) float64 {
+	return float64(int(value*1000+0.5)) / 1000
+}
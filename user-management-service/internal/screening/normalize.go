@@ -0,0 +1,127 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// transliterations maps non-ASCII letters to their usual Latin spelling. Covers Latin
+// diacritics, Cyrillic and Greek, which account for nearly all names on the consolidated
+// lists that are not already romanised.
+var transliterations = map[rune]string{
+	// Latin with diacritics
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g",
+	'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j", 'ķ': "k",
+	'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o",
+	'ŕ': "r", 'ŗ': "r", 'ř': "r",
+	'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ș': "s",
+	'ţ': "t", 'ť': "t", 'ŧ': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ŵ': "w", 'ý': "y", 'ÿ': "y", 'ŷ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'þ': "th",
+
+	// Cyrillic (BGN/PCGN)
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+	'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ό': "o", 'ύ': "y", 'ώ': "o", 'ϊ': "i", 'ϋ': "y",
+}
+
+// spellingVariants folds common romanisation variants of the same sound so that, for
+// example, "Mohammed" and "Muhamad" normalise closer together
+var spellingVariants = strings.NewReplacer(
+	"ph", "f",
+	"kh", "h",
+	"ou", "u",
+	"oo", "u",
+	"ee", "i",
+	"dh", "d",
+	"th", "t",
+	"ck", "k",
+	"q", "k",
+	"w", "v",
+)
+
+// noiseTokens are honorifics and legal-form suffixes that carry no identifying weight
+var noiseTokens = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "sir": true, "sheikh": true, "haji": true,
+	"ltd": true, "limited": true, "llc": true, "inc": true, "incorporated": true, "corp": true,
+	"corporation": true, "co": true, "company": true, "plc": true, "gmbh": true, "ag": true,
+	"sa": true, "sarl": true, "srl": true, "spa": true, "bv": true, "nv": true, "oy": true,
+	"ab": true, "as": true, "ooo": true, "zao": true, "oao": true, "pjsc": true, "jsc": true,
+	"the": true, "and": true, "of": true,
+}
+
+// transliterate lower-cases name and rewrites it in plain ASCII letters, digits and spaces
+func transliterate(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		case unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// Normalize reduces a name to comparable tokens: transliterated, lower-case, without
+// punctuation, honorifics or legal-form suffixes, with spelling variants folded, and
+// sorted so that "SMITH, John" and "John Smith" compare equal
+func Normalize(name string) []string {
+	fields := joinInitials(strings.Fields(transliterate(name)))
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if noiseTokens[field] {
+			continue
+		}
+		tokens = append(tokens, spellingVariants.Replace(field))
+	}
+	// Keep suffix-only names such as "Company Ltd" matchable
+	if len(tokens) == 0 {
+		for _, field := range fields {
+			tokens = append(tokens, spellingVariants.Replace(field))
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// joinInitials merges runs of single letters, so that abbreviations written with dots
+// such as "S.A." or "L.L.C." become one token
+func joinInitials(fields []string) []string {
+	joined := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		if len(fields[i]) != 1 || i+1 == len(fields) || len(fields[i+1]) != 1 {
+			joined = append(joined, fields[i])
+			continue
+		}
+		run := fields[i]
+		for i+1 < len(fields) && len(fields[i+1]) == 1 {
+			i++
+			run += fields[i]
+		}
+		joined = append(joined, run)
+	}
+	return joined
+}
@@ -0,0 +1,577 @@
+package screening
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrNoActiveLists   = errors.New("no screening lists loaded")
+	ErrResultNotFound  = errors.New("screening result not found")
+	ErrAlreadyReviewed = errors.New("screening result already reviewed")
+	ErrInvalidDecision = errors.New("decision must be confirm or dismiss")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidSubject  = errors.New("subject name is required")
+)
+
+// Options configures list loading and matching
+type Options struct {
+	ListDir        string        // Directory holding the list files
+	MatchThreshold float64       // Minimum score, 0-1, for a hit to be reported
+	MaxHits        int           // Maximum hits kept per screening
+	ReloadInterval time.Duration // How often the list directory is checked for new files
+}
+
+// OptionsFromEnv reads SCREENING_LIST_DIR, SCREENING_MATCH_THRESHOLD, SCREENING_MAX_HITS
+// and SCREENING_RELOAD_INTERVAL
+func OptionsFromEnv() Options {
+	opts := Options{
+		ListDir:        "./data/screening",
+		MatchThreshold: 0.88,
+		MaxHits:        10,
+		ReloadInterval: time.Hour,
+	}
+	if value := os.Getenv("SCREENING_LIST_DIR"); value != "" {
+		opts.ListDir = value
+	}
+	if value, err := strconv.ParseFloat(os.Getenv("SCREENING_MATCH_THRESHOLD"), 64); err == nil && value > 0 && value <= 1 {
+		opts.MatchThreshold = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("SCREENING_MAX_HITS")); err == nil && value > 0 {
+		opts.MaxHits = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("SCREENING_RELOAD_INTERVAL")); err == nil && value > 0 {
+		opts.ReloadInterval = value
+	}
+	return opts
+}
+
+// Service screens people and organisations against sanctions and PEP lists held in memory
+type Service struct {
+	db    *gorm.DB
+	opts  Options
+	mu    sync.RWMutex
+	index *index
+	sync  sync.Mutex // serialises list loading
+}
+
+func NewService(db *gorm.DB, opts Options) *Service {
+	return &Service{db: db, opts: opts}
+}
+
+// LoadActive builds the in-memory index from the active lists stored in the database
+func (s *Service) LoadActive() error {
+	var lists []models.ScreeningList
+	if err := s.db.Where("active = ?", true).Find(&lists).Error; err != nil {
+		return fmt.Errorf("failed to load screening lists: %w", err)
+	}
+
+	versions := make(map[string]string, len(lists))
+	ids := make([]uuid.UUID, 0, len(lists))
+	for _, list := range lists {
+		versions[list.Source] = list.Version
+		ids = append(ids, list.ID)
+	}
+
+	var entries []models.ScreeningEntry
+	if len(ids) > 0 {
+		if err := s.db.Where("list_id IN ?", ids).Find(&entries).Error; err != nil {
+			return fmt.Errorf("failed to load screening entries: %w", err)
+		}
+	}
+
+	idx := newIndex(entries, versions)
+	s.mu.Lock()
+	s.index = idx
+	s.mu.Unlock()
+	return nil
+}
+
+// SyncLists loads every list file in the list directory whose content changed since it
+// was last loaded, makes it the active version of its source and, when anything changed,
+// re-screens the customer base against the new lists. It returns the lists loaded.
+func (s *Service) SyncLists() ([]models.ScreeningList, error) {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+
+	loaded := []models.ScreeningList{}
+	for _, lf := range listFiles {
+		checksum, err := checksumFiles(s.opts.ListDir, lf.files)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return loaded, fmt.Errorf("failed to read %s list: %w", lf.source, err)
+		}
+
+		var current models.ScreeningList
+		err = s.db.Where("source = ? AND active = ?", lf.source, true).First(&current).Error
+		if err == nil && current.Checksum == checksum {
+			continue
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return loaded, fmt.Errorf("failed to get active %s list: %w", lf.source, err)
+		}
+
+		parsed, err := lf.parse(s.opts.ListDir)
+		if err != nil {
+			return loaded, fmt.Errorf("failed to parse %s list: %w", lf.source, err)
+		}
+		list, err := s.storeList(lf, checksum, parsed)
+		if err != nil {
+			return loaded, err
+		}
+		log.Printf("Loaded %s screening list version %s with %d entries", list.Source, list.Version, list.EntryCount)
+		loaded = append(loaded, *list)
+	}
+
+	if len(loaded) == 0 {
+		return loaded, nil
+	}
+	if err := s.LoadActive(); err != nil {
+		return loaded, err
+	}
+	if _, err := s.RescreenAll("list_update"); err != nil {
+		return loaded, err
+	}
+	return loaded, nil
+}
+
+// storeList saves a parsed list and its entries and swaps it in as the active version
+func (s *Service) storeList(lf listFile, checksum string, parsed *parsedList) (*models.ScreeningList, error) {
+	version := parsed.version
+	if version == "" {
+		version = checksum[:12]
+	}
+	list := &models.ScreeningList{
+		Source:     lf.source,
+		Version:    version,
+		FileName:   lf.files[0],
+		Checksum:   checksum,
+		EntryCount: len(parsed.entries),
+		Active:     true,
+		LoadedAt:   time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ScreeningList{}).
+			Where("source = ? AND active = ?", lf.source, true).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(list).Error; err != nil {
+			return err
+		}
+		for i := range parsed.entries {
+			parsed.entries[i].ListID = list.ID
+		}
+		if len(parsed.entries) > 0 {
+			return tx.CreateInBatches(parsed.entries, 500).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store %s list: %w", lf.source, err)
+	}
+	return list, nil
+}
+
+// checksumFiles hashes the list files of a source; the first file must exist
+func checksumFiles(dir string, files []string) (string, error) {
+	hash := sha256.New()
+	for i, name := range files {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			if i > 0 && os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Screen matches subject against the active lists without recording the result
+func (s *Service) Screen(subject Subject) ([]models.ScreeningHit, map[string]string, error) {
+	if subject.Name == "" {
+		return nil, nil, ErrInvalidSubject
+	}
+	s.mu.RLock()
+	idx := s.index
+	s.mu.RUnlock()
+	if idx == nil || len(idx.versions) == 0 {
+		return nil, nil, ErrNoActiveLists
+	}
+	return idx.match(subject, s.opts.MatchThreshold, s.opts.MaxHits), idx.versions, nil
+}
+
+// ScreenAndRecord screens subject and stores the result. When every hit was already part
+// of a reviewed result for the same subject, that review decision carries over so
+// analysts are not asked to clear the same false positive after each list update.
+func (s *Service) ScreenAndRecord(subject Subject, trigger string) (*models.ScreeningResult, error) {
+	hits, versions, err := s.Screen(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := &models.ScreeningResult{
+		SubjectType:  subject.Type,
+		SubjectID:    subject.ID,
+		SubjectName:  subject.Name,
+		Status:       models.ScreeningClear,
+		Hits:         hits,
+		ListVersions: versions,
+		Trigger:      trigger,
+		ScreenedAt:   now,
+	}
+	if len(hits) > 0 {
+		result.Status = models.ScreeningPotentialMatch
+		result.TopScore = hits[0].Score
+		if subject.ID != "" {
+			s.carryOverReview(result)
+		}
+	}
+
+	if err := s.db.Create(result).Error; err != nil {
+		return nil, fmt.Errorf("failed to save screening result: %w", err)
+	}
+	return result, nil
+}
+
+func (s *Service) carryOverReview(result *models.ScreeningResult) {
+	var previous models.ScreeningResult
+	err := s.db.Where("subject_type = ? AND subject_id = ? AND status IN ?", result.SubjectType, result.SubjectID,
+		[]models.ScreeningStatus{models.ScreeningDismissed, models.ScreeningConfirmedMatch}).
+		Order("reviewed_at DESC").First(&previous).Error
+	if err != nil {
+		return
+	}
+
+	reviewed := map[string]bool{}
+	for _, hit := range previous.Hits {
+		reviewed[hit.Source+"/"+hit.ExternalID] = true
+	}
+	for _, hit := range result.Hits {
+		if !reviewed[hit.Source+"/"+hit.ExternalID] {
+			return
+		}
+	}
+	result.Status = previous.Status
+	result.ReviewedBy = previous.ReviewedBy
+	result.ReviewedAt = previous.ReviewedAt
+	result.ReviewNote = fmt.Sprintf("Carried over from review of result %s", previous.ID)
+}
+
+// ScreenUser screens a customer and their company and records the outcome on the
+// customer's KYC data
+func (s *Service) ScreenUser(userID uuid.UUID, trigger string) ([]models.ScreeningResult, error) {
+	var user models.User
+	if err := s.db.Preload("Company").Preload("KYCData").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	subjects := []Subject{{
+		Type:       "user",
+		ID:         user.ID.String(),
+		Name:       user.GetFullName(),
+		EntityType: "individual",
+	}}
+	if user.KYCData != nil {
+		if !user.KYCData.DateOfBirth.IsZero() {
+			subjects[0].DateOfBirth = user.KYCData.DateOfBirth.Format("2006-01-02")
+		}
+		subjects[0].Countries = []string{user.KYCData.Nationality, user.KYCData.Country}
+	}
+	if user.Company != nil && user.Company.Name != "" {
+		company := Subject{
+			Type:       "company",
+			ID:         user.Company.ID.String(),
+			Name:       user.Company.Name,
+			EntityType: "entity",
+			Countries:  []string{user.Company.Country},
+		}
+		if user.Company.LegalName != "" && user.Company.LegalName != user.Company.Name {
+			company.Aliases = []string{user.Company.LegalName}
+		}
+		subjects = append(subjects, company)
+	}
+
+	results := make([]models.ScreeningResult, 0, len(subjects))
+	for _, subject := range subjects {
+		result, err := s.ScreenAndRecord(subject, trigger)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *result)
+	}
+
+	if err := s.updateKYCChecks(&user); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// updateKYCChecks derives the customer's AML check status from the latest screening of
+// the customer and their company: a confirmed sanctions match fails it, an unreviewed
+// potential match leaves it pending, and a confirmed PEP match flags the customer as
+// politically exposed
+func (s *Service) updateKYCChecks(user *models.User) error {
+	if user.KYCData == nil {
+		return nil
+	}
+
+	subjects := [][2]string{{"user", user.ID.String()}}
+	if user.Company != nil {
+		subjects = append(subjects, [2]string{"company", user.Company.ID.String()})
+	}
+
+	status := "passed"
+	pep := user.KYCData.PoliticallyExposed
+	for _, subject := range subjects {
+		var latest models.ScreeningResult
+		err := s.db.Where("subject_type = ? AND subject_id = ?", subject[0], subject[1]).
+			Order("screened_at DESC").First(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get screening result: %w", err)
+		}
+
+		switch latest.Status {
+		case models.ScreeningConfirmedMatch:
+			for _, hit := range latest.Hits {
+				if hit.Source == SourcePEP {
+					pep = true
+				} else {
+					status = "failed"
+				}
+			}
+		case models.ScreeningPotentialMatch:
+			if status != "failed" {
+				status = "pending"
+			}
+		}
+	}
+
+	now := time.Now()
+	err := s.db.Model(user.KYCData).Updates(map[string]interface{}{
+		"sanctions_list_check": true,
+		"watchlist_check":      true,
+		"aml_check_status":     status,
+		"aml_check_date":       now,
+		"politically_exposed":  pep,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update KYC checks: %w", err)
+	}
+	return nil
+}
+
+// RescreenAll screens every user, whatever their role, with their company, and every
+// invoice buyer and counterparty screened before, returning how many subjects were
+// screened
+func (s *Service) RescreenAll(trigger string) (int, error) {
+	screened := 0
+	var failed error
+	var users []models.User
+	err := s.db.Select("id").
+		FindInBatches(&users, 200, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				if _, err := s.ScreenUser(user.ID, trigger); err != nil {
+					if errors.Is(err, ErrNoActiveLists) {
+						return err
+					}
+					log.Printf("Failed to screen user %s: %v", user.ID, err)
+					failed = err
+					continue
+				}
+				screened++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return screened, fmt.Errorf("failed to re-screen users: %w", err)
+	}
+
+	// Buyers and counterparties are not users; they are known from their latest screening
+	var parties []Subject
+	err = s.db.Raw(`SELECT DISTINCT ON (subject_type, subject_id) subject_type AS type, subject_id AS id, subject_name AS name
+		FROM screening_results WHERE subject_type IN ? AND subject_id <> ''
+		ORDER BY subject_type, subject_id, screened_at DESC`, []string{"buyer", "counterparty"}).
+		Scan(&parties).Error
+	if err != nil {
+		return screened, fmt.Errorf("failed to list screened parties: %w", err)
+	}
+	for _, party := range parties {
+		if _, err := s.ScreenAndRecord(party, trigger); err != nil {
+			if errors.Is(err, ErrNoActiveLists) {
+				return screened, err
+			}
+			log.Printf("Failed to screen %s %s: %v", party.Type, party.ID, err)
+			failed = err
+			continue
+		}
+		screened++
+	}
+
+	if failed != nil {
+		log.Printf("Re-screened %d subjects with errors, last error: %v", screened, failed)
+	}
+	return screened, nil
+}
+
+// ResultFilter narrows ListResults
+type ResultFilter struct {
+	Status      string
+	SubjectType string
+	SubjectID   string
+	Page        int
+	Limit       int
+}
+
+func (s *Service) ListResults(filter ResultFilter) ([]models.ScreeningResult, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	query := s.db.Model(&models.ScreeningResult{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.SubjectType != "" {
+		query = query.Where("subject_type = ?", filter.SubjectType)
+	}
+	if filter.SubjectID != "" {
+		query = query.Where("subject_id = ?", filter.SubjectID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count screening results: %w", err)
+	}
+	var results []models.ScreeningResult
+	if err := query.Order("screened_at DESC").Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&results).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list screening results: %w", err)
+	}
+	return results, total, nil
+}
+
+func (s *Service) GetResult(id uuid.UUID) (*models.ScreeningResult, error) {
+	var result models.ScreeningResult
+	if err := s.db.First(&result, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResultNotFound
+		}
+		return nil, fmt.Errorf("failed to get screening result: %w", err)
+	}
+	return &result, nil
+}
+
+// ReviewResult records an analyst's decision on a potential match. Decisions on customer
+// and company results are reflected on the customer's KYC data.
+func (s *Service) ReviewResult(id uuid.UUID, decision, note string, reviewer uuid.UUID) (*models.ScreeningResult, error) {
+	var status models.ScreeningStatus
+	switch decision {
+	case "confirm":
+		status = models.ScreeningConfirmedMatch
+	case "dismiss":
+		status = models.ScreeningDismissed
+	default:
+		return nil, ErrInvalidDecision
+	}
+
+	result, err := s.GetResult(id)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status != models.ScreeningPotentialMatch {
+		return nil, ErrAlreadyReviewed
+	}
+
+	now := time.Now()
+	result.Status = status
+	result.ReviewedBy = &reviewer
+	result.ReviewedAt = &now
+	result.ReviewNote = note
+	if err := s.db.Save(result).Error; err != nil {
+		return nil, fmt.Errorf("failed to save screening review: %w", err)
+	}
+
+	var userID string
+	switch result.SubjectType {
+	case "user":
+		userID = result.SubjectID
+	case "company":
+		var company models.Company
+		if err := s.db.Select("user_id").First(&company, "id = ?", result.SubjectID).Error; err == nil {
+			userID = company.UserID.String()
+		}
+	}
+	if userID != "" {
+		var user models.User
+		if err := s.db.Preload("Company").Preload("KYCData").First(&user, "id = ?", userID).Error; err == nil {
+			if err := s.updateKYCChecks(&user); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// ListLists returns the loaded list versions, newest first
+func (s *Service) ListLists(activeOnly bool) ([]models.ScreeningList, error) {
+	query := s.db.Order("loaded_at DESC")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	var lists []models.ScreeningList
+	if err := query.Find(&lists).Error; err != nil {
+		return nil, fmt.Errorf("failed to list screening lists: %w", err)
+	}
+	return lists, nil
+}
+
+// StartWatcher checks the list directory for new list files until ctx is cancelled
+func (s *Service) StartWatcher(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SyncLists(); err != nil {
+				log.Printf("Screening list sync failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package sso
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"user-management-service/internal/mfa"
+)
+
+// Handler exposes SSO logins and the administration of connections over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	var validation *ValidationError
+	switch {
+	case errors.As(err, &validation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid SSO connection", "problems": validation.Problems})
+	case errors.Is(err, ErrInvalidReturnURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrConnectionNotFound), errors.Is(err, ErrNoConnection):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDomainTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrStateInvalid), errors.Is(err, ErrInvalidAssertion), errors.Is(err, ErrIdentityProvider):
+		log.Printf("SSO login rejected: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "SSO login failed"})
+	case errors.Is(err, ErrConnectionDisabled), errors.Is(err, ErrDomainNotAllowed), errors.Is(err, ErrNotProvisioned),
+		errors.Is(err, ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMembership):
+		log.Printf("SSO login failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": ErrMembership.Error()})
+	default:
+		log.Printf("SSO operation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "SSO operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func connectionID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("connectionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondResult answers a completed login with the session, or redirects the browser to
+// the front end with a code to exchange for it
+func respondResult(c *gin.Context, result *Result) {
+	if result.Redirect != "" {
+		c.Redirect(http.StatusFound, result.Redirect)
+		return
+	}
+	c.JSON(http.StatusOK, result.Session)
+}
+
+// Discover tells the login page whether the email's domain uses SSO and where to start
+func (h *Handler) Discover(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.service.ConnectionForEmail(req.Email)
+	if errors.Is(err, ErrNoConnection) {
+		c.JSON(http.StatusOK, gin.H{"sso": false})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sso":           true,
+		"connection_id": conn.ID,
+		"protocol":      conn.Protocol,
+		"enforced":      conn.EnforceSSO,
+		"login_url":     h.service.LoginURL(conn),
+	})
+}
+
+func (h *Handler) Login(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+
+	target, err := h.service.BeginLogin(id, c.Query("return_to"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, target)
+}
+
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.FinishOIDC(id, c.Request.URL.Query(), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondResult(c, result)
+}
+
+func (h *Handler) SAMLAssertionConsumer(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.FinishSAML(id, c.PostForm("SAMLResponse"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondResult(c, result)
+}
+
+func (h *Handler) SAMLMetadata(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+
+	metadata, err := h.service.Metadata(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// ExchangeCode gives the front end the session for the code it was redirected with
+func (h *Handler) ExchangeCode(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.ExchangeCode(req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// EnforceGate refuses password logins, registration and password resets for email
+// domains whose organization requires SSO, pointing the client to the SSO login instead
+func (h *Handler) EnforceGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal(body, &req) != nil || req.Email == "" {
+			c.Next()
+			return
+		}
+
+		if conn := h.service.EnforcedConnection(req.Email); conn != nil {
+			mfa.RespondSSORequired(c, &mfa.SSORequiredError{ConnectionID: conn.ID, LoginURL: h.service.LoginURL(conn)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *Handler) GetConnections(c *gin.Context) {
+	var organizationID *uuid.UUID
+	if id, err := uuid.Parse(c.Query("organization_id")); err == nil {
+		organizationID = &id
+	}
+
+	conns, err := h.service.ListConnections(organizationID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"connections": conns})
+}
+
+func (h *Handler) GetConnection(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+
+	conn, err := h.service.GetConnection(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connection":   conn,
+		"login_url":    h.service.LoginURL(conn),
+		"sp_entity_id": h.service.SPEntityID(conn),
+	})
+}
+
+func (h *Handler) CreateConnection(c *gin.Context) {
+	var req ConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	conn, err := h.service.CreateConnection(req, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, conn)
+}
+
+func (h *Handler) UpdateConnection(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+	var req ConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.service.UpdateConnection(id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conn)
+}
+
+func (h *Handler) DeleteConnection(c *gin.Context) {
+	id, ok := connectionID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteConnection(id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SSO connection deleted"})
+}
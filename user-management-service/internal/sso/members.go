@@ -0,0 +1,72 @@
+package sso
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"user-management-service/internal/models"
+)
+
+// MemberDirectory makes users who log in through a connection members of its organization
+// in the platform backend, which only lets members act for an organization
+type MemberDirectory interface {
+	// Provision adds the user to the connection's organization unless they are a member
+	// already; existing members keep their role
+	Provision(conn *models.SSOConnection, user *models.User) error
+}
+
+// httpMembers provisions members through the backend's internal API
+type httpMembers struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (m *httpMembers) Provision(conn *models.SSOConnection, user *models.User) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"user_id":     user.ID,
+		"email":       user.Email,
+		"first_name":  user.FirstName,
+		"last_name":   user.LastName,
+		"role":        user.Role,
+		"member_role": conn.MemberRole,
+	})
+	if err != nil {
+		return err
+	}
+	target := fmt.Sprintf("%s/api/v1/internal/organizations/%s/members", strings.TrimSuffix(m.url, "/"), conn.OrganizationID)
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.token)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s returned %s", target, resp.Status)
+	}
+	return nil
+}
+
+// localMembers is used when SESSION_REGISTRY_URL is not set and there is no backend to
+// provision into
+type localMembers struct{}
+
+func (localMembers) Provision(conn *models.SSOConnection, user *models.User) error {
+	return nil
+}
+
+func newMemberDirectory(opts Options) MemberDirectory {
+	if opts.BackendURL == "" {
+		return localMembers{}
+	}
+	return &httpMembers{url: opts.BackendURL, token: opts.BackendToken, client: &http.Client{Timeout: opts.HTTPTimeout}}
+}
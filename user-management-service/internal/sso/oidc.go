@@ -0,0 +1,373 @@
+package sso
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+
+	"user-management-service/internal/models"
+)
+
+// ID token algorithms accepted from identity providers
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// provider is an OIDC issuer's discovery document and signing keys
+type provider struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	Issuer                string `json:"issuer"`
+
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// providerFor returns the issuer's configuration, fetched at most hourly
+func (s *Service) providerFor(issuer string) (*provider, error) {
+	s.mu.Lock()
+	cached := s.providers[issuer]
+	fresh := cached != nil && time.Since(cached.fetchedAt) < time.Hour
+	s.mu.Unlock()
+	if fresh {
+		return cached, nil
+	}
+
+	var p provider
+	if err := s.getJSON(issuer+"/.well-known/openid-configuration", &p); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimRight(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q", p.Issuer)
+	}
+	if !endpointURL(p.AuthorizationEndpoint) || !endpointURL(p.TokenEndpoint) || !endpointURL(p.JWKSURI) {
+		return nil, fmt.Errorf("OIDC discovery returned an endpoint that is not https")
+	}
+	if err := s.loadKeys(&p); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.providers[issuer] = &p
+	s.mu.Unlock()
+	return &p, nil
+}
+
+func (s *Service) getJSON(target string, v interface{}) error {
+	resp, err := s.client.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (s *Service) loadKeys(p *provider) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := s.getJSON(p.JWKSURI, &set); err != nil {
+		return fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if public, err := key.publicKey(); err == nil {
+			keys[key.Kid] = public
+		}
+	}
+
+	s.mu.Lock()
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Service) signingKey(p *provider, kid string) (interface{}, bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := p.keys[kid]
+	return key, ok, p.fetchedAt
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("key is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func (s *Service) oidcRedirectURI(conn *models.SSOConnection) string {
+	return s.connectionURL(conn.ID, "oidc/callback")
+}
+
+// beginOIDC sends the browser to the authorization endpoint using the authorization code
+// flow with PKCE
+func (s *Service) beginOIDC(conn *models.SSOConnection, returnTo, ipAddress string) (string, error) {
+	p, err := s.providerFor(conn.Issuer)
+	if err != nil {
+		return "", err
+	}
+	state, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomToken(48)
+	if err != nil {
+		return "", err
+	}
+	if err := s.startLogin(conn, state, nonce, verifier, returnTo, ipAddress); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	scopes := append([]string{"openid", "email", "profile"}, conn.Scopes...)
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {conn.ClientID},
+		"redirect_uri":          {s.oidcRedirectURI(conn)},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// FinishOIDC handles the identity provider's redirect back: it exchanges the code, checks
+// the ID token and logs the user in
+func (s *Service) FinishOIDC(connectionID uuid.UUID, params url.Values, ipAddress, userAgent string) (*Result, error) {
+	conn, err := s.GetConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Protocol != ProtocolOIDC {
+		return nil, ErrConnectionNotFound
+	}
+	state, err := s.consumeLogin(conn.ID, params.Get("state"))
+	if err != nil {
+		return nil, err
+	}
+	if !conn.Enabled {
+		return nil, ErrConnectionDisabled
+	}
+
+	identity, err := s.oidcIdentity(conn, state, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.finish(conn, state, identity, ipAddress, userAgent)
+}
+
+// oidcIdentity completes the login recorded in state with the identity provider's
+// redirect: the state must be the one sent, the code is exchanged with the login's PKCE
+// verifier and the ID token must carry the login's nonce
+func (s *Service) oidcIdentity(conn *models.SSOConnection, state *models.SSOLoginState, params url.Values) (*Identity, error) {
+	if subtle.ConstantTimeCompare([]byte(hashToken(params.Get("state"))), []byte(state.StateHash)) != 1 {
+		return nil, ErrStateInvalid
+	}
+	if params.Get("error") != "" {
+		return nil, fmt.Errorf("%w: %s", ErrIdentityProvider, params.Get("error"))
+	}
+
+	p, err := s.providerFor(conn.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := s.exchangeCode(conn, p, params.Get("code"), state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.verifyIDToken(conn, p, rawIDToken, state.Nonce)
+}
+
+func (s *Service) exchangeCode(conn *models.SSOConnection, p *provider, code, verifier string) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("%w: no authorization code", ErrIdentityProvider)
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.oidcRedirectURI(conn)},
+		"code_verifier": {verifier},
+		"client_id":     {conn.ClientID},
+	}
+	req, err := http.NewRequest(http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if conn.ClientSecret != "" {
+		secret, err := s.mfa.OpenSecret(conn.ClientSecret)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt client secret: %w", err)
+		}
+		req.SetBasicAuth(url.QueryEscape(conn.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("OIDC token response is invalid: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", fmt.Errorf("%w: token request returned %d %s", ErrIdentityProvider, resp.StatusCode, body.Error)
+	}
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the signature against the issuer's keys, refreshing them once for
+// an unknown key ID, and the issuer, audience, expiry and nonce
+func (s *Service) verifyIDToken(conn *models.SSOConnection, p *provider, raw, nonce string) (*Identity, error) {
+	// Time claims are checked below, allowing for clock skew
+	parser := jwt.NewParser(jwt.WithValidMethods(idTokenMethods), jwt.WithoutClaimsValidation())
+	token, err := parser.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok, fetchedAt := s.signingKey(p, kid)
+		if ok {
+			return key, nil
+		}
+		if time.Since(fetchedAt) > time.Minute {
+			if err := s.loadKeys(p); err != nil {
+				return nil, err
+			}
+			if key, ok, _ := s.signingKey(p, kid); ok {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidAssertion
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != conn.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidAssertion)
+	}
+	if !claims.VerifyAudience(conn.ClientID, true) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidAssertion)
+	}
+	if aud, multiple := claims["aud"].([]interface{}); multiple && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != conn.ClientID {
+			return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidAssertion)
+		}
+	}
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-s.opts.ClockSkew).Unix(), true) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidAssertion)
+	}
+	if !claims.VerifyIssuedAt(now.Add(s.opts.ClockSkew).Unix(), false) || !claims.VerifyNotBefore(now.Add(s.opts.ClockSkew).Unix(), false) {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidAssertion)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidAssertion)
+	}
+	if verified, present := claims["email_verified"].(bool); present && !verified {
+		return nil, fmt.Errorf("%w: email address is not verified", ErrInvalidAssertion)
+	}
+
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.FirstName, _ = claims["given_name"].(string)
+	identity.LastName, _ = claims["family_name"].(string)
+	switch groups := claims[conn.GroupsAttribute].(type) {
+	case string:
+		identity.Groups = []string{groups}
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,220 @@
+package sso
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"user-management-service/internal/models"
+)
+
+const testClientID = "platform"
+
+// oidcIdP is an identity provider serving discovery, its signing key and a token endpoint
+// that checks the PKCE verifier against the challenge the code was issued for
+type oidcIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+type issuedCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newOIDCIdP(t *testing.T) *oidcIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &oidcIdP{key: key, codes: map[string]issuedCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		issued, ok := idp.codes[r.PostFormValue("code")]
+		delete(idp.codes, r.PostFormValue("code"))
+		idp.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != issued.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, issued.claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize issues a code for a login that sent the challenge of verifier, as the
+// authorization endpoint would
+func (idp *oidcIdP) authorize(verifier string, claims jwt.MapClaims) string {
+	sum := sha256.Sum256([]byte(verifier))
+	code := "code-" + base64.RawURLEncoding.EncodeToString(sum[:8])
+	idp.mu.Lock()
+	idp.codes[code] = issuedCode{challenge: base64.RawURLEncoding.EncodeToString(sum[:]), claims: claims}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *oidcIdP) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            testClientID,
+		"sub":            "00u1",
+		"email":          "ada@corp.example.com",
+		"email_verified": true,
+		"given_name":     "Ada",
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	idp := newOIDCIdP(t)
+	service := testService()
+	service.client = idp.server.Client()
+	conn := &models.SSOConnection{
+		ID:              testConnectionID,
+		Protocol:        ProtocolOIDC,
+		Issuer:          idp.server.URL,
+		ClientID:        testClientID,
+		GroupsAttribute: "groups",
+	}
+
+	const (
+		state    = "state"
+		nonce    = "nonce"
+		verifier = "verifier-sent-with-the-login"
+	)
+	tests := []struct {
+		name     string
+		params   func(code string) url.Values
+		verifier string // Verifier stored with the login; the code is issued for verifier
+		claims   func(claims jwt.MapClaims)
+		wantErr  error
+	}{
+		{name: "valid login"},
+		{
+			name:    "state of another login",
+			params:  func(code string) url.Values { return url.Values{"state": {"other-state"}, "code": {code}} },
+			wantErr: ErrStateInvalid,
+		},
+		{
+			name:    "state missing",
+			params:  func(code string) url.Values { return url.Values{"code": {code}} },
+			wantErr: ErrStateInvalid,
+		},
+		{
+			name:     "PKCE verifier of another login",
+			verifier: "verifier-of-another-login",
+			wantErr:  ErrIdentityProvider,
+		},
+		{
+			name:    "code of another login",
+			params:  func(code string) url.Values { return url.Values{"state": {state}, "code": {"code-stolen"}} },
+			wantErr: ErrIdentityProvider,
+		},
+		{
+			name:    "nonce of another login",
+			claims:  func(claims jwt.MapClaims) { claims["nonce"] = "other-nonce" },
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name:    "nonce missing",
+			claims:  func(claims jwt.MapClaims) { delete(claims, "nonce") },
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name:    "audience of another client",
+			claims:  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name:    "expired ID token",
+			claims:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name:    "identity provider error",
+			params:  func(code string) url.Values { return url.Values{"state": {state}, "error": {"access_denied"}} },
+			wantErr: ErrIdentityProvider,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.claims(nonce)
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			code := idp.authorize(verifier, claims)
+			params := url.Values{"state": {state}, "code": {code}}
+			if tt.params != nil {
+				params = tt.params(code)
+			}
+			login := &models.SSOLoginState{StateHash: hashToken(state), Nonce: nonce, CodeVerifier: verifier}
+			if tt.verifier != "" {
+				login.CodeVerifier = tt.verifier
+			}
+
+			identity, err := service.oidcIdentity(conn, login, params)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Subject != "00u1" || identity.Email != "ada@corp.example.com" || identity.FirstName != "Ada" {
+				t.Fatalf("identity %+v does not match the ID token", identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"user-management-service/internal/models"
+)
+
+const (
+	nsSAMLP          = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsSAML           = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsMetadata       = "urn:oasis:names:tc:SAML:2.0:metadata"
+	samlSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlBearer       = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	samlPOSTBinding  = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlEmailNameID  = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	samlTimeFormat   = "2006-01-02T15:04:05Z"
+	maxSAMLResponse  = 256 << 10
+	samlRequestIDLen = 20
+)
+
+// SAML attributes commonly used for names
+var (
+	firstNameAttributes = []string{"firstName", "givenName", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname", "urn:oid:2.5.4.42"}
+	lastNameAttributes  = []string{"lastName", "surname", "sn", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname", "urn:oid:2.5.4.4"}
+)
+
+// SPEntityID identifies this service to the identity provider; it is also the metadata URL
+func (s *Service) SPEntityID(conn *models.SSOConnection) string {
+	return s.connectionURL(conn.ID, "saml/metadata")
+}
+
+func (s *Service) acsURL(conn *models.SSOConnection) string {
+	return s.connectionURL(conn.ID, "saml/acs")
+}
+
+// Metadata describes the service provider for the identity provider's configuration
+func (s *Service) Metadata(connectionID uuid.UUID) ([]byte, error) {
+	conn, err := s.GetConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Protocol != ProtocolSAML {
+		return nil, ErrConnectionNotFound
+	}
+
+	type acs struct {
+		Binding  string `xml:"Binding,attr"`
+		Location string `xml:"Location,attr"`
+		Index    int    `xml:"index,attr"`
+	}
+	metadata := struct {
+		XMLName    xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+		EntityID   string   `xml:"entityID,attr"`
+		Descriptor struct {
+			AuthnRequestsSigned        bool   `xml:"AuthnRequestsSigned,attr"`
+			WantAssertionsSigned       bool   `xml:"WantAssertionsSigned,attr"`
+			ProtocolSupportEnumeration string `xml:"protocolSupportEnumeration,attr"`
+			NameIDFormat               string `xml:"NameIDFormat"`
+			AssertionConsumerService   acs    `xml:"AssertionConsumerService"`
+		} `xml:"SPSSODescriptor"`
+	}{EntityID: s.SPEntityID(conn)}
+	metadata.Descriptor.WantAssertionsSigned = true
+	metadata.Descriptor.ProtocolSupportEnumeration = nsSAMLP
+	metadata.Descriptor.NameIDFormat = samlEmailNameID
+	metadata.Descriptor.AssertionConsumerService = acs{Binding: samlPOSTBinding, Location: s.acsURL(conn), Index: 0}
+
+	out, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// beginSAML sends the browser to the identity provider with an AuthnRequest using the
+// HTTP-Redirect binding. The request ID is remembered so only a response to it is taken.
+func (s *Service) beginSAML(conn *models.SSOConnection, returnTo, ipAddress string) (string, error) {
+	token, err := randomToken(samlRequestIDLen)
+	if err != nil {
+		return "", err
+	}
+	requestID := "_" + token // IDs must not start with a digit
+	if err := s.startLogin(conn, requestID, "", "", returnTo, ipAddress); err != nil {
+		return "", err
+	}
+
+	var request bytes.Buffer
+	request.WriteString(`<samlp:AuthnRequest xmlns:samlp="` + nsSAMLP + `" xmlns:saml="` + nsSAML + `"`)
+	for _, attr := range [][2]string{
+		{"ID", requestID},
+		{"Version", "2.0"},
+		{"IssueInstant", time.Now().UTC().Format(samlTimeFormat)},
+		{"Destination", conn.IdPSSOURL},
+		{"AssertionConsumerServiceURL", s.acsURL(conn)},
+		{"ProtocolBinding", samlPOSTBinding},
+	} {
+		request.WriteString(" " + attr[0] + `="`)
+		xml.EscapeText(&request, []byte(attr[1]))
+		request.WriteString(`"`)
+	}
+	request.WriteString(`><saml:Issuer>`)
+	xml.EscapeText(&request, []byte(s.SPEntityID(conn)))
+	request.WriteString(`</saml:Issuer><samlp:NameIDPolicy Format="` + samlEmailNameID + `" AllowCreate="true"/></samlp:AuthnRequest>`)
+
+	var deflated bytes.Buffer
+	writer, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	writer.Write(request.Bytes())
+	writer.Close()
+
+	separator := "?"
+	if strings.Contains(conn.IdPSSOURL, "?") {
+		separator = "&"
+	}
+	query := url.Values{"SAMLRequest": {base64.StdEncoding.EncodeToString(deflated.Bytes())}}
+	return conn.IdPSSOURL + separator + query.Encode(), nil
+}
+
+// FinishSAML takes the identity provider's response posted to the ACS. It must answer a
+// request this service sent, be signed with the connection's certificate and be meant for
+// this service right now.
+func (s *Service) FinishSAML(connectionID uuid.UUID, samlResponse, ipAddress, userAgent string) (*Result, error) {
+	conn, err := s.GetConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Protocol != ProtocolSAML {
+		return nil, ErrConnectionNotFound
+	}
+	if len(samlResponse) > maxSAMLResponse {
+		return nil, fmt.Errorf("%w: response too large", ErrInvalidAssertion)
+	}
+	raw, err := base64.StdEncoding.DecodeString(stripSpace(samlResponse))
+	if err != nil {
+		return nil, fmt.Errorf("%w: response is not base64", ErrInvalidAssertion)
+	}
+	response, err := parseXML(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+	if !response.is(nsSAMLP, "Response") {
+		return nil, fmt.Errorf("%w: not a SAML response", ErrInvalidAssertion)
+	}
+
+	// The request ID ties the response to a login started here, once
+	state, err := s.consumeLogin(conn.ID, response.attr("", "InResponseTo"))
+	if err != nil {
+		return nil, err
+	}
+	if !conn.Enabled {
+		return nil, ErrConnectionDisabled
+	}
+
+	identity, err := s.verifyResponse(conn, response, response.attr("", "InResponseTo"), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.finish(conn, state, identity, ipAddress, userAgent)
+}
+
+func (s *Service) verifyResponse(conn *models.SSOConnection, response *xmlNode, requestID string, now time.Time) (*Identity, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidAssertion, reason)
+	}
+
+	if status := response.child(nsSAMLP, "Status"); status == nil || status.child(nsSAMLP, "StatusCode") == nil {
+		return nil, invalid("status missing")
+	} else if code := status.child(nsSAMLP, "StatusCode").attr("", "Value"); code != samlSuccess {
+		return nil, fmt.Errorf("%w: %s", ErrIdentityProvider, code)
+	}
+	if destination := response.attr("", "Destination"); destination != "" && destination != s.acsURL(conn) {
+		return nil, invalid("destination mismatch")
+	}
+	if issuer := response.child(nsSAML, "Issuer"); issuer != nil && issuer.text() != conn.IdPEntityID {
+		return nil, invalid("issuer mismatch")
+	}
+	if len(response.childrenNamed(nsSAML, "EncryptedAssertion")) > 0 {
+		return nil, invalid("encrypted assertions are not supported")
+	}
+	assertions := response.childrenNamed(nsSAML, "Assertion")
+	if len(assertions) != 1 {
+		return nil, invalid("exactly one assertion is required")
+	}
+	assertion := assertions[0]
+
+	// Either the response or the assertion must be signed, and any signature present must
+	// verify. Only the nodes that were verified are read from here on.
+	cert, err := parseCertificate(conn.IdPCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("IdP certificate of connection %s is invalid: %w", conn.ID, err)
+	}
+	signed := false
+	for _, el := range []*xmlNode{response, assertion} {
+		signature, err := signatureOf(el)
+		if err != nil {
+			return nil, invalid(err.Error())
+		}
+		if signature == nil {
+			continue
+		}
+		if err := verifyEnveloped(el, signature, cert); err != nil {
+			return nil, invalid(err.Error())
+		}
+		signed = true
+	}
+	if !signed {
+		return nil, invalid("the assertion is not signed")
+	}
+
+	if issuer := assertion.child(nsSAML, "Issuer"); issuer == nil || issuer.text() != conn.IdPEntityID {
+		return nil, invalid("assertion issuer mismatch")
+	}
+
+	subject := assertion.child(nsSAML, "Subject")
+	if subject == nil || subject.child(nsSAML, "NameID") == nil {
+		return nil, invalid("subject missing")
+	}
+	confirmed := false
+	for _, confirmation := range subject.childrenNamed(nsSAML, "SubjectConfirmation") {
+		data := confirmation.child(nsSAML, "SubjectConfirmationData")
+		if confirmation.attr("", "Method") != samlBearer || data == nil {
+			continue
+		}
+		notOnOrAfter, err := time.Parse(time.RFC3339, data.attr("", "NotOnOrAfter"))
+		if err != nil || !now.Add(-s.opts.ClockSkew).Before(notOnOrAfter) {
+			continue
+		}
+		if data.attr("", "Recipient") != s.acsURL(conn) {
+			continue
+		}
+		if inResponseTo := data.attr("", "InResponseTo"); inResponseTo != "" && inResponseTo != requestID {
+			continue
+		}
+		confirmed = true
+	}
+	if !confirmed {
+		return nil, invalid("no valid bearer subject confirmation")
+	}
+
+	conditions := assertion.child(nsSAML, "Conditions")
+	if conditions == nil {
+		return nil, invalid("conditions missing")
+	}
+	if notBefore := conditions.attr("", "NotBefore"); notBefore != "" {
+		t, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil || now.Add(s.opts.ClockSkew).Before(t) {
+			return nil, invalid("assertion is not valid yet")
+		}
+	}
+	if notOnOrAfter := conditions.attr("", "NotOnOrAfter"); notOnOrAfter != "" {
+		t, err := time.Parse(time.RFC3339, notOnOrAfter)
+		if err != nil || !now.Add(-s.opts.ClockSkew).Before(t) {
+			return nil, invalid("assertion expired")
+		}
+	}
+	restrictions := conditions.childrenNamed(nsSAML, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, invalid("audience restriction missing")
+	}
+	for _, restriction := range restrictions {
+		matched := false
+		for _, audience := range restriction.childrenNamed(nsSAML, "Audience") {
+			matched = matched || audience.text() == s.SPEntityID(conn)
+		}
+		if !matched {
+			return nil, invalid("audience mismatch")
+		}
+	}
+
+	attributes := map[string][]string{}
+	for _, statement := range assertion.childrenNamed(nsSAML, "AttributeStatement") {
+		for _, attribute := range statement.childrenNamed(nsSAML, "Attribute") {
+			name := attribute.attr("", "Name")
+			for _, value := range attribute.childrenNamed(nsSAML, "AttributeValue") {
+				attributes[name] = append(attributes[name], value.text())
+			}
+		}
+	}
+	first := func(names ...string) string {
+		for _, name := range names {
+			if values := attributes[name]; len(values) > 0 {
+				return values[0]
+			}
+		}
+		return ""
+	}
+
+	identity := &Identity{
+		Subject:   subject.child(nsSAML, "NameID").text(),
+		Email:     subject.child(nsSAML, "NameID").text(),
+		FirstName: first(firstNameAttributes...),
+		LastName:  first(lastNameAttributes...),
+		Groups:    attributes[conn.GroupsAttribute],
+	}
+	if conn.EmailAttribute != "" {
+		identity.Email = first(conn.EmailAttribute)
+	}
+	return identity, nil
+}
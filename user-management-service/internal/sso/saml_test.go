@@ -0,0 +1,315 @@
+package sso
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"user-management-service/internal/models"
+)
+
+const (
+	testBaseURL     = "https://sso.example.com"
+	testIdPEntityID = "https://idp.example.com/metadata"
+	testRequestID   = "_request"
+)
+
+var testConnectionID = uuid.MustParse("6f1c1e0a-3a5e-4b8e-9d1c-2f7c9a0b1c2d")
+
+func testService() *Service {
+	return &Service{
+		opts:      Options{BaseURL: testBaseURL, ClockSkew: 2 * time.Minute},
+		providers: map[string]*provider{},
+	}
+}
+
+// samlIdP signs assertions with its own key and self-signed certificate
+type samlIdP struct {
+	key  *rsa.PrivateKey
+	cert string // PEM
+}
+
+func newSAMLIdP(t *testing.T) *samlIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &samlIdP{key: key, cert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+func (idp *samlIdP) connection() *models.SSOConnection {
+	return &models.SSOConnection{
+		ID:              testConnectionID,
+		Protocol:        ProtocolSAML,
+		IdPEntityID:     testIdPEntityID,
+		IdPCertificate:  idp.cert,
+		GroupsAttribute: "groups",
+	}
+}
+
+// samlAssertion is what the identity provider asserts; zero fields are filled in as a
+// valid assertion for the test connection
+type samlAssertion struct {
+	ID           string
+	Email        string
+	Recipient    string
+	Audience     string
+	InResponseTo string
+	NotOnOrAfter time.Time
+}
+
+func (a samlAssertion) withDefaults() samlAssertion {
+	service, conn := testService(), (&samlIdP{}).connection()
+	if a.ID == "" {
+		a.ID = "_assertion"
+	}
+	if a.Email == "" {
+		a.Email = "ada@corp.example.com"
+	}
+	if a.Recipient == "" {
+		a.Recipient = service.acsURL(conn)
+	}
+	if a.Audience == "" {
+		a.Audience = service.SPEntityID(conn)
+	}
+	if a.InResponseTo == "" {
+		a.InResponseTo = testRequestID
+	}
+	if a.NotOnOrAfter.IsZero() {
+		a.NotOnOrAfter = time.Now().Add(5 * time.Minute)
+	}
+	return a
+}
+
+// xml renders the assertion with signature, which may be empty, in the place the schema
+// puts it
+func (a samlAssertion) xml(signature string) string {
+	notBefore := time.Now().Add(-time.Minute).UTC().Format(samlTimeFormat)
+	notOnOrAfter := a.NotOnOrAfter.UTC().Format(samlTimeFormat)
+	return fmt.Sprintf(`<saml:Assertion xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s">`+
+		`<saml:Issuer>%s</saml:Issuer>%s`+
+		`<saml:Subject><saml:NameID Format="%s">%s</saml:NameID>`+
+		`<saml:SubjectConfirmation Method="%s"><saml:SubjectConfirmationData InResponseTo="%s" NotOnOrAfter="%s" Recipient="%s"/></saml:SubjectConfirmation></saml:Subject>`+
+		`<saml:Conditions NotBefore="%s" NotOnOrAfter="%s"><saml:AudienceRestriction><saml:Audience>%s</saml:Audience></saml:AudienceRestriction></saml:Conditions>`+
+		`<saml:AttributeStatement><saml:Attribute Name="firstName"><saml:AttributeValue>Ada</saml:AttributeValue></saml:Attribute></saml:AttributeStatement>`+
+		`</saml:Assertion>`,
+		nsSAML, a.ID, notBefore, testIdPEntityID, signature, samlEmailNameID, a.Email,
+		samlBearer, a.InResponseTo, notOnOrAfter, a.Recipient, notBefore, notOnOrAfter, a.Audience)
+}
+
+// digestOf is the SHA-256 digest of an unsigned assertion in exclusive canonical form
+func digestOf(t *testing.T, assertion string) string {
+	t.Helper()
+	node, err := parseXML([]byte(assertion))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var canonical bytes.Buffer
+	canonicalize(&canonical, node, nil, nil)
+	sum := sha256.Sum256(canonical.Bytes())
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func signatureXML(id, digest, value string) string {
+	return fmt.Sprintf(`<ds:Signature xmlns:ds="%s"><ds:SignedInfo>`+
+		`<ds:CanonicalizationMethod Algorithm="%s"/>`+
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>`+
+		`<ds:Reference URI="#%s"><ds:Transforms><ds:Transform Algorithm="%s"/><ds:Transform Algorithm="%s"/></ds:Transforms>`+
+		`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><ds:DigestValue>%s</ds:DigestValue></ds:Reference>`+
+		`</ds:SignedInfo><ds:SignatureValue>%s</ds:SignatureValue></ds:Signature>`,
+		nsDSig, algExcC, id, algEnv, algExcC, digest, value)
+}
+
+// sign returns the assertion with an enveloped signature, and the signature alone
+func (idp *samlIdP) sign(t *testing.T, a samlAssertion) (string, string) {
+	t.Helper()
+	unsigned := signatureXML(a.ID, digestOf(t, a.xml("")), "")
+	node, err := parseXML([]byte(unsigned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var canonical bytes.Buffer
+	canonicalize(&canonical, node.child(nsDSig, "SignedInfo"), nil, nil)
+	sum := sha256.Sum256(canonical.Bytes())
+	value, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := strings.Replace(unsigned, "<ds:SignatureValue></ds:SignatureValue>",
+		"<ds:SignatureValue>"+base64.StdEncoding.EncodeToString(value)+"</ds:SignatureValue>", 1)
+	return a.xml(signature), signature
+}
+
+func samlResponse(content string) string {
+	return fmt.Sprintf(`<samlp:Response xmlns:samlp="%s" xmlns:saml="%s" ID="_response" Version="2.0" InResponseTo="%s" Destination="%s">`+
+		`<saml:Issuer>%s</saml:Issuer><samlp:Status><samlp:StatusCode Value="%s"/></samlp:Status>%s</samlp:Response>`,
+		nsSAMLP, nsSAML, testRequestID, testService().acsURL((&samlIdP{}).connection()), testIdPEntityID, samlSuccess, content)
+}
+
+func TestVerifyResponse(t *testing.T) {
+	service := testService()
+	idp := newSAMLIdP(t)
+	other := newSAMLIdP(t)
+	genuine := samlAssertion{}.withDefaults()
+	forged := samlAssertion{Email: "mallory@corp.example.com"}.withDefaults()
+
+	tests := []struct {
+		name     string
+		response func() string
+		wantErr  error
+	}{
+		{
+			name: "valid response",
+			response: func() string {
+				signed, _ := idp.sign(t, genuine)
+				return samlResponse(signed)
+			},
+		},
+		{
+			name:     "unsigned assertion",
+			response: func() string { return samlResponse(genuine.xml("")) },
+			wantErr:  ErrInvalidAssertion,
+		},
+		{
+			name: "signed by another key",
+			response: func() string {
+				signed, _ := other.sign(t, genuine)
+				return samlResponse(signed)
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "assertion changed after signing",
+			response: func() string {
+				signed, _ := idp.sign(t, genuine)
+				return samlResponse(strings.Replace(signed, genuine.Email, forged.Email, 1))
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "digest value replaced with the forged assertion's",
+			response: func() string {
+				_, signature := idp.sign(t, genuine)
+				node, err := parseXML([]byte(signature))
+				if err != nil {
+					t.Fatal(err)
+				}
+				digest := node.child(nsDSig, "SignedInfo").child(nsDSig, "Reference").child(nsDSig, "DigestValue").text()
+				tampered := strings.Replace(signature, digest, digestOf(t, forged.xml("")), 1)
+				return samlResponse(forged.xml(tampered))
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "signature wrapping: signed assertion hidden in extensions",
+			response: func() string {
+				signed, _ := idp.sign(t, genuine)
+				return samlResponse(`<samlp:Extensions>` + signed + `</samlp:Extensions>` + samlAssertion{ID: "_forged", Email: forged.Email}.withDefaults().xml(""))
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "signature wrapping: signature moved to a forged assertion with the same ID",
+			response: func() string {
+				_, signature := idp.sign(t, genuine)
+				return samlResponse(forged.xml(signature))
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "signature wrapping: forged assertion referencing the signed one",
+			response: func() string {
+				signed, signature := idp.sign(t, genuine)
+				wrapper := samlAssertion{ID: "_forged", Email: forged.Email}.withDefaults().xml(signature)
+				return samlResponse(strings.Replace(wrapper, "</saml:Assertion>", "<saml:Advice>"+signed+"</saml:Advice></saml:Assertion>", 1))
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "signature wrapping: forged assertion next to the signed one",
+			response: func() string {
+				signed, _ := idp.sign(t, genuine)
+				return samlResponse(samlAssertion{ID: "_forged", Email: forged.Email}.withDefaults().xml("") + signed)
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "audience of another service provider",
+			response: func() string {
+				signed, _ := idp.sign(t, samlAssertion{Audience: "https://other-sp.example.com/metadata"}.withDefaults())
+				return samlResponse(signed)
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "recipient of another service provider",
+			response: func() string {
+				signed, _ := idp.sign(t, samlAssertion{Recipient: "https://other-sp.example.com/acs"}.withDefaults())
+				return samlResponse(signed)
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "answer to another request",
+			response: func() string {
+				signed, _ := idp.sign(t, samlAssertion{InResponseTo: "_other_request"}.withDefaults())
+				return samlResponse(signed)
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+		{
+			name: "expired assertion",
+			response: func() string {
+				signed, _ := idp.sign(t, samlAssertion{NotOnOrAfter: time.Now().Add(-time.Hour)}.withDefaults())
+				return samlResponse(signed)
+			},
+			wantErr: ErrInvalidAssertion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := parseXML([]byte(tt.response()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := service.verifyResponse(idp.connection(), response, testRequestID, time.Now())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Email != genuine.Email || identity.FirstName != "Ada" {
+				t.Fatalf("identity %+v, want %s named Ada", identity, genuine.Email)
+			}
+		})
+	}
+}
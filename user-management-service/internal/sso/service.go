@@ -0,0 +1,750 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"user-management-service/internal/mfa"
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrConnectionNotFound = errors.New("SSO connection not found")
+	ErrInvalidConnection  = errors.New("invalid SSO connection")
+	ErrDomainTaken        = errors.New("an email domain is already used by another SSO connection")
+	ErrConnectionDisabled = errors.New("SSO connection is disabled")
+	ErrNoConnection       = errors.New("no SSO connection for this email domain")
+	ErrInvalidReturnURL   = errors.New("return URL is not allowed")
+	ErrStateInvalid       = errors.New("SSO login is invalid or expired")
+	ErrIdentityProvider   = errors.New("the identity provider did not authenticate the user")
+	ErrInvalidAssertion   = errors.New("the identity provider's response could not be verified")
+	ErrDomainNotAllowed   = errors.New("the email address is not in a domain of this SSO connection")
+	ErrNotProvisioned     = errors.New("no account exists for this user and provisioning is off")
+	ErrAccountInactive    = errors.New("account is not active")
+	ErrSSORequired        = mfa.ErrSSORequired
+	ErrMembership         = errors.New("the organization membership could not be set up")
+)
+
+// Protocols of a connection
+const (
+	ProtocolOIDC = "oidc"
+	ProtocolSAML = "saml"
+)
+
+// amrSSO names federated logins in the amr claim. The identity provider's own factors are
+// not carried over, so SSO sessions still need step-up for sensitive operations.
+const amrSSO = mfa.AMRSSO
+
+// rolePrecedence decides between roles when a user's groups map to several. Platform
+// administrators are never provisioned through SSO.
+var rolePrecedence = []models.UserRole{models.RoleBank, models.RoleBuyer, models.RoleSME}
+
+// Options configures the service provider side of SSO
+type Options struct {
+	BaseURL     string        // Public URL of this service, for callback and ACS URLs
+	ReturnURLs  []string      // Prefixes of front-end URLs a login may return to
+	LoginTTL    time.Duration // How long the identity provider step may take
+	CodeTTL     time.Duration // How long the client has to exchange the code for a session
+	ClockSkew   time.Duration
+	HTTPTimeout time.Duration
+	// The backend's internal API, where users are made members of the connection's
+	// organization; the same as the session registry's
+	BackendURL   string
+	BackendToken string
+}
+
+// OptionsFromEnv reads SSO_BASE_URL, SSO_RETURN_URLS, SSO_LOGIN_TTL, SSO_CODE_TTL,
+// SSO_CLOCK_SKEW, SSO_HTTP_TIMEOUT, SESSION_REGISTRY_URL and SESSION_REGISTRY_TOKEN
+func OptionsFromEnv() Options {
+	opts := Options{
+		BaseURL:      strings.TrimRight(os.Getenv("SSO_BASE_URL"), "/"),
+		BackendURL:   os.Getenv("SESSION_REGISTRY_URL"),
+		BackendToken: os.Getenv("SESSION_REGISTRY_TOKEN"),
+		LoginTTL:     10 * time.Minute,
+		CodeTTL:      time.Minute,
+		ClockSkew:    2 * time.Minute,
+		HTTPTimeout:  10 * time.Second,
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = "http://localhost:8081"
+	}
+	for _, prefix := range strings.Split(os.Getenv("SSO_RETURN_URLS"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			opts.ReturnURLs = append(opts.ReturnURLs, prefix)
+		}
+	}
+	for key, target := range map[string]*time.Duration{
+		"SSO_LOGIN_TTL":    &opts.LoginTTL,
+		"SSO_CODE_TTL":     &opts.CodeTTL,
+		"SSO_CLOCK_SKEW":   &opts.ClockSkew,
+		"SSO_HTTP_TIMEOUT": &opts.HTTPTimeout,
+	} {
+		if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+			*target = value
+		}
+	}
+	return opts
+}
+
+// Service logs users in through their organization's identity provider over OIDC or
+// SAML, provisions them on first login and opens the same sessions as a password login
+type Service struct {
+	db      *gorm.DB
+	mfa     *mfa.Service
+	opts    Options
+	client  *http.Client
+	members MemberDirectory
+
+	mu        sync.Mutex
+	providers map[string]*provider // OIDC discovery and keys by issuer
+}
+
+func NewService(db *gorm.DB, mfaService *mfa.Service, opts Options) *Service {
+	return &Service{
+		db:        db,
+		mfa:       mfaService,
+		opts:      opts,
+		client:    &http.Client{Timeout: opts.HTTPTimeout},
+		members:   newMemberDirectory(opts),
+		providers: map[string]*provider{},
+	}
+}
+
+// ConnectionRequest creates or replaces a connection. The client secret is kept when an
+// update leaves it empty.
+type ConnectionRequest struct {
+	OrganizationID  uuid.UUID         `json:"organization_id" binding:"required"`
+	Name            string            `json:"name" binding:"required"`
+	Protocol        string            `json:"protocol" binding:"required"`
+	Domains         []string          `json:"domains" binding:"required"`
+	Enabled         *bool             `json:"enabled"`
+	EnforceSSO      bool              `json:"enforce_sso"`
+	JITProvisioning *bool             `json:"jit_provisioning"`
+	DefaultRole     models.UserRole   `json:"default_role"`
+	GroupsAttribute string            `json:"groups_attribute"`
+	RoleMappings    map[string]string `json:"role_mappings"`
+	MemberRole      string            `json:"member_role"`
+	Issuer          string            `json:"issuer"`
+	ClientID        string            `json:"client_id"`
+	ClientSecret    string            `json:"client_secret"`
+	Scopes          []string          `json:"scopes"`
+	IdPEntityID     string            `json:"idp_entity_id"`
+	IdPSSOURL       string            `json:"idp_sso_url"`
+	IdPCertificate  string            `json:"idp_certificate"`
+	EmailAttribute  string            `json:"email_attribute"`
+}
+
+// ValidationError lists what is wrong with a connection
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInvalidConnection.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidConnection
+}
+
+func provisionableRole(role models.UserRole) bool {
+	for _, allowed := range rolePrecedence {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointURL accepts https URLs, and http ones on the local machine for a mock IdP
+func endpointURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := u.Hostname()
+	return u.Scheme == "https" || (u.Scheme == "http" && (host == "localhost" || host == "127.0.0.1" || host == "::1"))
+}
+
+// apply validates the request into the connection
+func (s *Service) apply(conn *models.SSOConnection, req ConnectionRequest) error {
+	var problems []string
+
+	conn.OrganizationID = req.OrganizationID
+	conn.Name = strings.TrimSpace(req.Name)
+	conn.Protocol = strings.ToLower(req.Protocol)
+	conn.EnforceSSO = req.EnforceSSO
+	if req.Enabled != nil {
+		conn.Enabled = *req.Enabled
+	}
+	if req.JITProvisioning != nil {
+		conn.JITProvisioning = *req.JITProvisioning
+	}
+
+	conn.Domains = nil
+	for _, domain := range req.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || strings.ContainsAny(domain, "@/ ") || !strings.Contains(domain, ".") {
+			problems = append(problems, fmt.Sprintf("%q is not an email domain", domain))
+			continue
+		}
+		conn.Domains = append(conn.Domains, domain)
+	}
+	if len(conn.Domains) == 0 {
+		problems = append(problems, "at least one email domain is required")
+	}
+
+	conn.DefaultRole = req.DefaultRole
+	if conn.DefaultRole == "" {
+		conn.DefaultRole = models.RoleBank
+	}
+	if !provisionableRole(conn.DefaultRole) {
+		problems = append(problems, "default_role must be sme, buyer or bank")
+	}
+	conn.RoleMappings = map[string]string{}
+	for group, role := range req.RoleMappings {
+		if !provisionableRole(models.UserRole(role)) {
+			problems = append(problems, fmt.Sprintf("group %q maps to %q; roles must be sme, buyer or bank", group, role))
+			continue
+		}
+		conn.RoleMappings[group] = role
+	}
+	conn.MemberRole = strings.TrimSpace(req.MemberRole)
+	conn.GroupsAttribute = req.GroupsAttribute
+	if conn.GroupsAttribute == "" {
+		conn.GroupsAttribute = "groups"
+	}
+
+	switch conn.Protocol {
+	case ProtocolOIDC:
+		conn.Issuer = strings.TrimRight(req.Issuer, "/")
+		conn.ClientID = req.ClientID
+		conn.Scopes = req.Scopes
+		if !endpointURL(conn.Issuer) {
+			problems = append(problems, "issuer must be an https URL")
+		}
+		if conn.ClientID == "" {
+			problems = append(problems, "client_id is required")
+		}
+		if req.ClientSecret != "" {
+			sealed, err := s.mfa.SealSecret(req.ClientSecret)
+			if err != nil {
+				return err
+			}
+			conn.ClientSecret = sealed
+		}
+	case ProtocolSAML:
+		conn.IdPEntityID = req.IdPEntityID
+		conn.IdPSSOURL = req.IdPSSOURL
+		conn.IdPCertificate = strings.TrimSpace(req.IdPCertificate)
+		conn.EmailAttribute = req.EmailAttribute
+		if conn.IdPEntityID == "" {
+			problems = append(problems, "idp_entity_id is required")
+		}
+		if !endpointURL(conn.IdPSSOURL) {
+			problems = append(problems, "idp_sso_url must be an https URL")
+		}
+		if _, err := parseCertificate(conn.IdPCertificate); err != nil {
+			problems = append(problems, "idp_certificate must be a PEM or base64 X.509 certificate")
+		}
+	default:
+		problems = append(problems, "protocol must be oidc or saml")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// domainsFree checks that no other connection claims one of the domains
+func (s *Service) domainsFree(conn *models.SSOConnection) error {
+	for _, domain := range conn.Domains {
+		other, err := s.connectionForDomain(domain, false)
+		if err != nil && !errors.Is(err, ErrNoConnection) {
+			return err
+		}
+		if other != nil && other.ID != conn.ID {
+			return fmt.Errorf("%w: %s", ErrDomainTaken, domain)
+		}
+	}
+	return nil
+}
+
+// connectionForDomain finds the connection claiming an email domain. There are few
+// connections, so they are matched here rather than through a JSON query.
+func (s *Service) connectionForDomain(domain string, enabledOnly bool) (*models.SSOConnection, error) {
+	query := s.db.Model(&models.SSOConnection{})
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	var conns []models.SSOConnection
+	if err := query.Find(&conns).Error; err != nil {
+		return nil, err
+	}
+	for i := range conns {
+		for _, claimed := range conns[i].Domains {
+			if claimed == domain {
+				return &conns[i], nil
+			}
+		}
+	}
+	return nil, ErrNoConnection
+}
+
+func (s *Service) CreateConnection(req ConnectionRequest, createdBy uuid.UUID) (*models.SSOConnection, error) {
+	conn := &models.SSOConnection{ID: uuid.New(), Enabled: true, JITProvisioning: true, CreatedBy: createdBy}
+	if err := s.apply(conn, req); err != nil {
+		return nil, err
+	}
+	if err := s.domainsFree(conn); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(conn).Error; err != nil {
+		return nil, fmt.Errorf("failed to create SSO connection: %w", err)
+	}
+	return conn, nil
+}
+
+func (s *Service) UpdateConnection(id uuid.UUID, req ConnectionRequest) (*models.SSOConnection, error) {
+	conn, err := s.GetConnection(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(conn, req); err != nil {
+		return nil, err
+	}
+	if err := s.domainsFree(conn); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(conn).Error; err != nil {
+		return nil, fmt.Errorf("failed to update SSO connection: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.providers, conn.Issuer)
+	s.mu.Unlock()
+	return conn, nil
+}
+
+// DeleteConnection removes the connection. Provisioned users keep their accounts and can
+// reset a password once SSO is no longer enforced for their domain.
+func (s *Service) DeleteConnection(id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.SSOConnection{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConnectionNotFound
+		}
+		if err := tx.Delete(&models.SSOLoginState{}, "connection_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.SSOIdentity{}, "connection_id = ?", id).Error
+	})
+}
+
+func (s *Service) GetConnection(id uuid.UUID) (*models.SSOConnection, error) {
+	var conn models.SSOConnection
+	if err := s.db.First(&conn, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConnectionNotFound
+		}
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (s *Service) ListConnections(organizationID *uuid.UUID) ([]models.SSOConnection, error) {
+	query := s.db.Order("created_at")
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	}
+	var conns []models.SSOConnection
+	err := query.Find(&conns).Error
+	return conns, err
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+// ConnectionForEmail finds the enabled connection for the email's domain
+func (s *Service) ConnectionForEmail(email string) (*models.SSOConnection, error) {
+	domain := emailDomain(email)
+	if domain == "" {
+		return nil, ErrNoConnection
+	}
+	return s.connectionForDomain(domain, true)
+}
+
+// BeginLogin returns the identity provider URL to send the browser to. The login returns
+// to returnTo with a code when given, or answers with the session.
+func (s *Service) BeginLogin(connectionID uuid.UUID, returnTo, ipAddress string) (string, error) {
+	conn, err := s.GetConnection(connectionID)
+	if err != nil {
+		return "", err
+	}
+	if conn.Protocol == ProtocolSAML {
+		return s.beginSAML(conn, returnTo, ipAddress)
+	}
+	return s.beginOIDC(conn, returnTo, ipAddress)
+}
+
+// LoginURL is where a browser starts logging in through the connection
+func (s *Service) LoginURL(conn *models.SSOConnection) string {
+	return s.connectionURL(conn.ID, "login")
+}
+
+func (s *Service) connectionURL(id uuid.UUID, path string) string {
+	return fmt.Sprintf("%s/api/v1/auth/sso/%s/%s", s.opts.BaseURL, id, path)
+}
+
+func (s *Service) returnAllowed(returnTo string) bool {
+	if returnTo == "" {
+		return true
+	}
+	for _, prefix := range s.opts.ReturnURLs {
+		if strings.HasPrefix(returnTo, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// startLogin records a login about to be sent to the identity provider under key, the
+// OIDC state or SAML request ID
+func (s *Service) startLogin(conn *models.SSOConnection, key, nonce, verifier, returnTo, ipAddress string) error {
+	if !conn.Enabled {
+		return ErrConnectionDisabled
+	}
+	if !s.returnAllowed(returnTo) {
+		return ErrInvalidReturnURL
+	}
+	state := &models.SSOLoginState{
+		ConnectionID: conn.ID,
+		StateHash:    hashToken(key),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ReturnTo:     returnTo,
+		IPAddress:    ipAddress,
+		ExpiresAt:    time.Now().Add(s.opts.LoginTTL),
+	}
+	if err := s.db.Create(state).Error; err != nil {
+		return fmt.Errorf("failed to start SSO login: %w", err)
+	}
+	return nil
+}
+
+// consumeLogin takes the pending login for key. It can be used once, which stops a
+// response from the identity provider being replayed.
+func (s *Service) consumeLogin(connectionID uuid.UUID, key string) (*models.SSOLoginState, error) {
+	var state models.SSOLoginState
+	err := s.db.First(&state, "state_hash = ? AND connection_id = ?", hashToken(key), connectionID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStateInvalid
+		}
+		return nil, err
+	}
+	if time.Now().After(state.ExpiresAt) {
+		return nil, ErrStateInvalid
+	}
+
+	now := time.Now()
+	res := s.db.Model(&models.SSOLoginState{}).Where("id = ? AND consumed_at IS NULL", state.ID).Update("consumed_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrStateInvalid
+	}
+	state.ConsumedAt = &now
+	return &state, nil
+}
+
+// Identity is what the identity provider asserted about the user
+type Identity struct {
+	Subject   string
+	Email     string
+	FirstName string
+	LastName  string
+	Groups    []string
+}
+
+// Result of a completed SSO login: a session for API clients, or a URL on the front end
+// carrying a code to exchange for one
+type Result struct {
+	Session  *mfa.Session
+	Redirect string
+}
+
+// mappedRole picks the role for the user's groups, or the connection's default
+func mappedRole(conn *models.SSOConnection, groups []string) models.UserRole {
+	mapped := map[models.UserRole]bool{}
+	for _, group := range groups {
+		if role, ok := conn.RoleMappings[group]; ok {
+			mapped[models.UserRole(role)] = true
+		}
+	}
+	for _, role := range rolePrecedence {
+		if mapped[role] {
+			return role
+		}
+	}
+	return conn.DefaultRole
+}
+
+// resolveUser finds the user the identity belongs to, linking an existing account with the
+// same email or provisioning one. Provisioned users' roles follow their groups on every
+// login; linked accounts keep the role they have.
+func (s *Service) resolveUser(conn *models.SSOConnection, identity *Identity) (*models.User, error) {
+	if identity.Subject == "" || identity.Email == "" {
+		return nil, fmt.Errorf("%w: subject or email missing", ErrInvalidAssertion)
+	}
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	allowed := false
+	for _, domain := range conn.Domains {
+		allowed = allowed || emailDomain(email) == domain
+	}
+	if !allowed {
+		return nil, ErrDomainNotAllowed
+	}
+	role := mappedRole(conn, identity.Groups)
+	now := time.Now()
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.SSOIdentity
+		err := tx.First(&link, "connection_id = ? AND subject = ?", conn.ID, identity.Subject).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil {
+			if err := tx.First(&user, "id = ?", link.UserID).Error; err != nil {
+				return err
+			}
+		} else {
+			link = models.SSOIdentity{ConnectionID: conn.ID, Subject: identity.Subject}
+			err := tx.Where("LOWER(email) = ?", email).First(&user).Error
+			switch {
+			case err == nil:
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return err
+			case !conn.JITProvisioning:
+				return ErrNotProvisioned
+			default:
+				user = models.User{
+					Email:           email,
+					Password:        "!sso", // Never matches a bcrypt hash
+					Role:            role,
+					Status:          models.StatusActive,
+					FirstName:       identity.FirstName,
+					LastName:        identity.LastName,
+					EmailVerified:   true,
+					EmailVerifiedAt: &now,
+				}
+				// Phone is unique and SSO users have none
+				if err := tx.Omit("Phone").Create(&user).Error; err != nil {
+					return fmt.Errorf("failed to provision user: %w", err)
+				}
+				link.Provisioned = true
+			}
+		}
+
+		if link.Provisioned && user.Role != role && user.Role != models.RoleAdmin {
+			if err := tx.Model(&user).Update("role", role).Error; err != nil {
+				return err
+			}
+		}
+		link.UserID = user.ID
+		link.Email = email
+		link.Groups = identity.Groups
+		link.LastLoginAt = now
+		return tx.Save(&link).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+		return nil, ErrAccountInactive
+	}
+	return &user, nil
+}
+
+// finish resolves the user and completes the login as the client asked when it started
+func (s *Service) finish(conn *models.SSOConnection, state *models.SSOLoginState, identity *Identity, ipAddress, userAgent string) (*Result, error) {
+	user, err := s.resolveUser(conn, identity)
+	if err != nil {
+		return nil, err
+	}
+	// The backend only lets members act for an organization, so the user joins the
+	// connection's before the login completes
+	if err := s.members.Provision(conn, user); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMembership, err)
+	}
+
+	if state.ReturnTo == "" {
+		session, err := s.mfa.OpenSession(user, ipAddress, userAgent, []string{amrSSO})
+		if err != nil {
+			return nil, err
+		}
+		s.mfa.RecordLogin(user.ID, ipAddress, userAgent, true, "")
+		return &Result{Session: session}, nil
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	codeHash := hashToken(code)
+	if err := s.db.Model(state).Updates(map[string]interface{}{
+		"user_id":          user.ID,
+		"result_code_hash": codeHash,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(state.ReturnTo)
+	if err != nil {
+		return nil, ErrInvalidReturnURL
+	}
+	query := target.Query()
+	query.Set("sso_code", code)
+	target.RawQuery = query.Encode()
+	return &Result{Redirect: target.String()}, nil
+}
+
+// ExchangeCode opens the session for a login handed to the front end. A code works once
+// and only shortly after the login.
+func (s *Service) ExchangeCode(code, ipAddress, userAgent string) (*mfa.Session, error) {
+	var state models.SSOLoginState
+	if err := s.db.First(&state, "result_code_hash = ?", hashToken(code)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStateInvalid
+		}
+		return nil, err
+	}
+	if state.UserID == nil || state.ConsumedAt == nil || time.Since(*state.ConsumedAt) > s.opts.CodeTTL {
+		return nil, ErrStateInvalid
+	}
+
+	res := s.db.Model(&models.SSOLoginState{}).Where("id = ? AND exchanged_at IS NULL", state.ID).Update("exchanged_at", time.Now())
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrStateInvalid
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", *state.UserID).Error; err != nil {
+		return nil, ErrStateInvalid
+	}
+	if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+		return nil, ErrAccountInactive
+	}
+	session, err := s.mfa.OpenSession(&user, ipAddress, userAgent, []string{amrSSO})
+	if err != nil {
+		return nil, err
+	}
+	s.mfa.RecordLogin(user.ID, ipAddress, userAgent, true, "")
+	return session, nil
+}
+
+// RequireSSO refuses logins by any other method for users whose organization requires
+// SSO. The MFA service asks it before opening a session, once the user is known, so
+// passkey and second-factor logins are held to it as well as passwords. Platform
+// administrators are exempt so that they can always get in; a failed lookup refuses.
+func (s *Service) RequireSSO(user *models.User) error {
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+	conn, err := s.ConnectionForEmail(user.Email)
+	if errors.Is(err, ErrNoConnection) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up the SSO connection: %w", err)
+	}
+	if !conn.EnforceSSO {
+		return nil
+	}
+	return &mfa.SSORequiredError{ConnectionID: conn.ID, LoginURL: s.LoginURL(conn)}
+}
+
+// EnforcedConnection returns the connection when the email's domain may only log in with
+// SSO. Platform administrators are exempt so that they can always get in.
+func (s *Service) EnforcedConnection(email string) *models.SSOConnection {
+	conn, err := s.ConnectionForEmail(email)
+	if err != nil || !conn.EnforceSSO {
+		return nil
+	}
+	var admins int64
+	s.db.Model(&models.User{}).
+		Where("LOWER(email) = ? AND role = ?", strings.ToLower(strings.TrimSpace(email)), models.RoleAdmin).
+		Count(&admins)
+	if admins > 0 {
+		return nil
+	}
+	return conn
+}
+
+// PurgeStates deletes login states that can no longer be used
+func (s *Service) PurgeStates(olderThan time.Duration) (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now().Add(-olderThan)).Delete(&models.SSOLoginState{})
+	return result.RowsAffected, result.Error
+}
+
+// StartCleanup deletes spent login states every hour until the context is cancelled
+func (s *Service) StartCleanup(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeStates(24 * time.Hour); err != nil {
+			log.Printf("SSO login state cleanup failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
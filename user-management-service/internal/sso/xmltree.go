@@ -0,0 +1,149 @@
+package sso
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var errMalformedXML = errors.New("malformed XML")
+
+// xmlNode is an element as written: names keep their prefix so the signed form can be
+// reproduced exactly, and namespaces are resolved on demand
+type xmlNode struct {
+	name     xml.Name   // Space holds the prefix
+	attrs    []xml.Attr // Including namespace declarations
+	children []interface{}
+	parent   *xmlNode
+}
+
+// parseXML reads a document into a tree. Documents with a DTD are refused.
+func parseXML(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *xmlNode
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errMalformedXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attrs: append([]xml.Attr(nil), t.Attr...), parent: current}
+			if current != nil {
+				current.children = append(current.children, node)
+			} else if root != nil {
+				return nil, fmt.Errorf("%w: more than one root element", errMalformedXML)
+			} else {
+				root = node
+			}
+			current = node
+		case xml.EndElement:
+			if current == nil || current.name != t.Name {
+				return nil, fmt.Errorf("%w: unexpected end element %s", errMalformedXML, t.Name.Local)
+			}
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(t))
+			}
+		case xml.Directive:
+			return nil, fmt.Errorf("%w: DTDs are not accepted", errMalformedXML)
+		case xml.ProcInst:
+			if current != nil {
+				return nil, fmt.Errorf("%w: processing instructions are not accepted", errMalformedXML)
+			}
+		case xml.Comment:
+		}
+	}
+	if root == nil || current != nil {
+		return nil, fmt.Errorf("%w: incomplete document", errMalformedXML)
+	}
+	return root, nil
+}
+
+// lookup resolves a prefix, "" being the default namespace, from the declarations in force
+// at the element
+func (n *xmlNode) lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for node := n; node != nil; node = node.parent {
+		for _, attr := range node.attrs {
+			if (prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns") ||
+				(prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix) {
+				return attr.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (n *xmlNode) namespace() string {
+	uri, _ := n.lookup(n.name.Space)
+	return uri
+}
+
+func (n *xmlNode) qname() string {
+	if n.name.Space == "" {
+		return n.name.Local
+	}
+	return n.name.Space + ":" + n.name.Local
+}
+
+func (n *xmlNode) is(namespace, local string) bool {
+	return n.name.Local == local && n.namespace() == namespace
+}
+
+func (n *xmlNode) childrenNamed(namespace, local string) []*xmlNode {
+	var matches []*xmlNode
+	for _, child := range n.children {
+		if node, ok := child.(*xmlNode); ok && node.is(namespace, local) {
+			matches = append(matches, node)
+		}
+	}
+	return matches
+}
+
+// child returns the first child element with the name, or nil
+func (n *xmlNode) child(namespace, local string) *xmlNode {
+	if matches := n.childrenNamed(namespace, local); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// attr returns an attribute value; namespace is empty for unqualified attributes
+func (n *xmlNode) attr(namespace, local string) string {
+	for _, attr := range n.attrs {
+		if attr.Name.Local != local || attr.Name.Space == "xmlns" {
+			continue
+		}
+		if attr.Name.Space == "" && namespace == "" {
+			return attr.Value
+		}
+		if attr.Name.Space != "" {
+			if uri, _ := n.lookup(attr.Name.Space); uri == namespace {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// text is the element's character data, without that of child elements
+func (n *xmlNode) text() string {
+	var text strings.Builder
+	for _, child := range n.children {
+		if s, ok := child.(string); ok {
+			text.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
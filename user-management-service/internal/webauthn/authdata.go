@@ -0,0 +1,215 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackedUp       = 0x10
+	flagAttestedData   = 0x40
+	flagExtensions     = 0x80
+)
+
+// authenticatorData is the parsed authData of a registration or assertion
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// Attested credential data, present on registration
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE_Key as sent
+	Key          *coseKey
+}
+
+func (a *authenticatorData) has(flag byte) bool {
+	return a.Flags&flag != 0
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+	auth := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if auth.has(flagAttestedData) {
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		auth.AAGUID = rest[:16]
+		length := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if length == 0 || length > 1023 || len(rest) < length {
+			return nil, errors.New("credential ID has an invalid length")
+		}
+		auth.CredentialID = rest[:length]
+		rest = rest[length:]
+
+		key, n, err := parseCOSEKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		auth.Key = key
+		auth.PublicKey = rest[:n]
+		rest = rest[n:]
+	}
+	if auth.has(flagExtensions) {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extensions: %w", err)
+		}
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("authenticator data has trailing bytes")
+	}
+	return auth, nil
+}
+
+// formatAAGUID renders an AAGUID in the usual UUID form
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// attestation is what registration learned about the authenticator. Verified means the
+// attestation statement's signature checks out; whether its certificate issuer is trusted
+// is left to policy on the stored issuer and AAGUID, as no metadata service is consulted.
+type attestation struct {
+	Format   string
+	Verified bool
+	Subject  string
+	Issuer   string
+}
+
+// Extension carrying the AAGUID in packed attestation certificates
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// verifyAttestation checks the attestation statement of a registration. Formats other
+// than none, packed and fido-u2f are accepted but recorded as unverified.
+func verifyAttestation(format string, stmt map[interface{}]interface{}, rawAuthData []byte, auth *authenticatorData, clientDataHash []byte) (*attestation, error) {
+	result := &attestation{Format: format}
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash...)
+
+	switch format {
+	case "none":
+		return result, nil
+
+	case "packed":
+		alg, _ := stmt["alg"].(int64)
+		sig, _ := stmt["sig"].([]byte)
+		if len(sig) == 0 {
+			return nil, errors.New("packed attestation has no signature")
+		}
+		certs, err := attestationCerts(stmt)
+		if err != nil {
+			return nil, err
+		}
+		if len(certs) == 0 {
+			// Self attestation is signed with the credential key itself
+			if int(alg) != auth.Key.Algorithm {
+				return nil, errors.New("self attestation algorithm does not match the credential")
+			}
+			if err := auth.Key.verify(signed, sig); err != nil {
+				return nil, fmt.Errorf("self attestation: %w", err)
+			}
+			result.Verified = true
+			return result, nil
+		}
+		cert := certs[0]
+		if err := verifySignature(cert.PublicKey, int(alg), signed, sig); err != nil {
+			return nil, fmt.Errorf("packed attestation: %w", err)
+		}
+		for _, ext := range cert.Extensions {
+			if !ext.Id.Equal(oidFIDOAAGUID) {
+				continue
+			}
+			var aaguid []byte
+			if _, err := asn1.Unmarshal(ext.Value, &aaguid); err != nil || !bytes.Equal(aaguid, auth.AAGUID) {
+				return nil, errors.New("attestation certificate AAGUID does not match")
+			}
+		}
+		result.Verified = true
+		result.Subject = cert.Subject.String()
+		result.Issuer = cert.Issuer.String()
+		return result, nil
+
+	case "fido-u2f":
+		sig, _ := stmt["sig"].([]byte)
+		certs, err := attestationCerts(stmt)
+		if err != nil {
+			return nil, err
+		}
+		if len(certs) != 1 || len(sig) == 0 {
+			return nil, errors.New("fido-u2f attestation needs one certificate and a signature")
+		}
+		pub, ok := auth.Key.Key.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.New("fido-u2f credentials must be P-256 keys")
+		}
+		data := []byte{0x00}
+		data = append(data, auth.RPIDHash...)
+		data = append(data, clientDataHash...)
+		data = append(data, auth.CredentialID...)
+		data = append(data, 0x04)
+		data = append(data, pub.X.FillBytes(make([]byte, 32))...)
+		data = append(data, pub.Y.FillBytes(make([]byte, 32))...)
+		certKey, ok := certs[0].PublicKey.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(data)
+		if !ok || !ecdsa.VerifyASN1(certKey, digest[:], sig) {
+			return nil, errors.New("fido-u2f attestation signature is invalid")
+		}
+		result.Verified = true
+		result.Subject = certs[0].Subject.String()
+		result.Issuer = certs[0].Issuer.String()
+		return result, nil
+	}
+
+	// tpm, android-key, android-safetynet, apple and future formats
+	if certs, err := attestationCerts(stmt); err == nil && len(certs) > 0 {
+		result.Subject = certs[0].Subject.String()
+		result.Issuer = certs[0].Issuer.String()
+	}
+	return result, nil
+}
+
+func attestationCerts(stmt map[interface{}]interface{}) ([]*x509.Certificate, error) {
+	raw, ok := stmt["x5c"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	certs := make([]*x509.Certificate, 0, len(raw))
+	for _, item := range raw {
+		der, ok := item.([]byte)
+		if !ok {
+			return nil, errors.New("attestation certificate is not a byte string")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attestation certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
@@ -0,0 +1,142 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The CBOR decoder covers what authenticators send: attestation objects and COSE keys in
+// the definite-length encoding CTAP2 requires. Maps decode to map[interface{}]interface{}
+// with int64 or string keys.
+
+var errCBORTruncated = errors.New("cbor: truncated input")
+
+const cborMaxDepth = 16
+
+// decodeCBOR decodes one item and returns it with the number of bytes it used
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, int, error) {
+	if depth > cborMaxDepth {
+		return nil, 0, errors.New("cbor: nesting too deep")
+	}
+	if len(data) == 0 {
+		return nil, 0, errCBORTruncated
+	}
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+
+	// Simple values and floats
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, 1, nil
+		case 21:
+			return true, 1, nil
+		case 22, 23:
+			return nil, 1, nil
+		default:
+			return nil, 0, fmt.Errorf("cbor: unsupported simple value %d", info)
+		}
+	}
+
+	arg, n, err := cborArgument(data, info)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case 0: // Unsigned integer
+		if arg > 1<<63-1 {
+			return nil, 0, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), n, nil
+	case 1: // Negative integer
+		if arg > 1<<63-1 {
+			return nil, 0, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), n, nil
+	case 2, 3: // Byte and text strings
+		if uint64(len(data)-n) < arg {
+			return nil, 0, errCBORTruncated
+		}
+		value := data[n : n+int(arg)]
+		if major == 3 {
+			return string(value), n + int(arg), nil
+		}
+		return append([]byte{}, value...), n + int(arg), nil
+	case 4: // Array
+		if arg > uint64(len(data)) {
+			return nil, 0, errCBORTruncated
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			n += used
+		}
+		return items, n, nil
+	case 5: // Map
+		if arg > uint64(len(data)) {
+			return nil, 0, errCBORTruncated
+		}
+		items := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += used
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, errors.New("cbor: unsupported map key type")
+			}
+			value, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += used
+			items[key] = value
+		}
+		return items, n, nil
+	case 6: // Tag; the tagged value is returned as is
+		value, used, err := decodeCBORItem(data[n:], depth+1)
+		return value, n + used, err
+	}
+	return nil, 0, fmt.Errorf("cbor: unsupported major type %d", major)
+}
+
+func cborArgument(data []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info == 24:
+		if len(data) < 2 {
+			return 0, 0, errCBORTruncated
+		}
+		return uint64(data[1]), 2, nil
+	case info == 25:
+		if len(data) < 3 {
+			return 0, 0, errCBORTruncated
+		}
+		return uint64(binary.BigEndian.Uint16(data[1:])), 3, nil
+	case info == 26:
+		if len(data) < 5 {
+			return 0, 0, errCBORTruncated
+		}
+		return uint64(binary.BigEndian.Uint32(data[1:])), 5, nil
+	case info == 27:
+		if len(data) < 9 {
+			return 0, 0, errCBORTruncated
+		}
+		return binary.BigEndian.Uint64(data[1:]), 9, nil
+	}
+	return 0, 0, errors.New("cbor: indefinite lengths are not supported")
+}
@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms accepted for credentials, in order of preference
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+var supportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2 // Also the RSA modulus
+	coseY         = -3 // Also the RSA exponent
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// coseKey is a credential public key together with its COSE algorithm
+type coseKey struct {
+	Algorithm int
+	Key       crypto.PublicKey
+}
+
+// parseCOSEKey decodes a COSE_Key and returns it with the number of bytes it used, which
+// attested credential data needs to find the extensions that follow the key
+func parseCOSEKey(data []byte) (*coseKey, int, error) {
+	item, n, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	params, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("COSE key is not a map")
+	}
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseAlgorithm)].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgES256:
+		crv, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		y, _ := params[int64(coseY)].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("unsupported EC2 key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, 0, errors.New("EC2 key is not on the curve")
+		}
+		return &coseKey{Algorithm: AlgES256, Key: key}, n, nil
+	case kty == coseKeyTypeOKP && alg == AlgEdDSA:
+		crv, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("unsupported OKP key")
+		}
+		return &coseKey{Algorithm: AlgEdDSA, Key: ed25519.PublicKey(x)}, n, nil
+	case kty == coseKeyTypeRSA && alg == AlgRS256:
+		modulus, _ := params[int64(coseX)].([]byte)
+		exponent, _ := params[int64(coseY)].([]byte)
+		if len(modulus) < 256 || len(exponent) == 0 || len(exponent) > 4 {
+			return nil, 0, errors.New("unsupported RSA key")
+		}
+		e := 0
+		for _, b := range exponent {
+			e = e<<8 | int(b)
+		}
+		return &coseKey{Algorithm: AlgRS256, Key: &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: e}}, n, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported COSE key type %d with algorithm %d", kty, alg)
+}
+
+// verify checks a signature over data made with the key's algorithm
+func (k *coseKey) verify(data, signature []byte) error {
+	return verifySignature(k.Key, k.Algorithm, data, signature)
+}
+
+func verifySignature(key crypto.PublicKey, alg int, data, signature []byte) error {
+	ok := false
+	switch alg {
+	case AlgES256:
+		pub, isECDSA := key.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(data)
+		ok = isECDSA && ecdsa.VerifyASN1(pub, digest[:], signature)
+	case AlgEdDSA:
+		pub, isEd25519 := key.(ed25519.PublicKey)
+		ok = isEd25519 && ed25519.Verify(pub, data, signature)
+	case AlgRS256:
+		pub, isRSA := key.(*rsa.PublicKey)
+		digest := sha256.Sum256(data)
+		ok = isRSA && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return fmt.Errorf("unsupported algorithm %d", alg)
+	}
+	if !ok {
+		return errors.New("signature is invalid")
+	}
+	return nil
+}
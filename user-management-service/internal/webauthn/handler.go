@@ -0,0 +1,298 @@
+package webauthn
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"user-management-service/internal/mfa"
+)
+
+// Handler exposes passkey registration, login, second factor, step-up and credential
+// management over HTTP
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func respondError(c *gin.Context, err error) {
+	var ssoRequired *mfa.SSORequiredError
+	switch {
+	case errors.As(err, &ssoRequired):
+		mfa.RespondSSORequired(c, ssoRequired)
+	case errors.Is(err, ErrVerificationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChallengeInvalid), errors.Is(err, mfa.ErrChallengeInvalid),
+		errors.Is(err, mfa.ErrInvalidToken), errors.Is(err, ErrUserVerification), errors.Is(err, ErrCloneDetected):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credential not found"})
+	case errors.Is(err, mfa.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, ErrCredentialExists), errors.Is(err, ErrLastRequiredPasskey):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrStepUpRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Step-up authentication required", "step_up_required": true})
+	case errors.Is(err, mfa.ErrMethodNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, mfa.ErrAccountInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebAuthn operation failed"})
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(fmt.Sprint(c.MustGet("userID")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// requireRecentAuth stops a stolen access token from adding or removing credentials on an
+// account that already has a second factor
+func (h *Handler) requireRecentAuth(c *gin.Context, userID uuid.UUID) bool {
+	claims, user, err := h.service.mfa.TokenUser(c.GetHeader("Authorization"))
+	if err == nil && user.ID != userID {
+		err = mfa.ErrInvalidToken
+	}
+	if err != nil {
+		respondError(c, err)
+		return false
+	}
+	needed, err := h.service.NeedsStepUp(claims, user)
+	if err != nil {
+		respondError(c, err)
+		return false
+	}
+	if needed {
+		respondError(c, ErrStepUpRequired)
+		return false
+	}
+	return true
+}
+
+// BeginRegistration returns the options for navigator.credentials.create
+func (h *Handler) BeginRegistration(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !h.requireRecentAuth(c, userID) {
+		return
+	}
+
+	options, err := h.service.BeginRegistration(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishRegistration stores the credential created by the authenticator
+func (h *Handler) FinishRegistration(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !h.requireRecentAuth(c, userID) {
+		return
+	}
+	var req struct {
+		Name       string                 `json:"name"`
+		Credential RegistrationCredential `json:"credential" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.service.FinishRegistration(userID, req.Name, &req.Credential)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+func (h *Handler) ListCredentials(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := h.service.ListCredentials(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"credentials": credentials})
+}
+
+func (h *Handler) RenameCredential(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	credentialID, err := uuid.Parse(c.Param("credentialId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential ID"})
+		return
+	}
+	var req struct {
+		Name string `json:"name" binding:"required,max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.service.RenameCredential(userID, credentialID, req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+func (h *Handler) RevokeCredential(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	credentialID, err := uuid.Parse(c.Param("credentialId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential ID"})
+		return
+	}
+	if !h.requireRecentAuth(c, userID) {
+		return
+	}
+
+	credential, err := h.service.RevokeCredential(userID, credentialID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// BeginLogin returns the options for a passkey login. The email is optional.
+func (h *Handler) BeginLogin(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	options, err := h.service.BeginLogin(req.Email)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishLogin signs the user in with a passkey assertion
+func (h *Handler) FinishLogin(c *gin.Context) {
+	var req AssertionCredential
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.FinishLogin(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// BeginMFA returns the options for completing a password login with a passkey
+func (h *Handler) BeginMFA(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options, err := h.service.BeginMFA(req.MFAToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishMFA completes a password login with a passkey assertion
+func (h *Handler) FinishMFA(c *gin.Context) {
+	var req struct {
+		MFAToken   string              `json:"mfa_token" binding:"required"`
+		Credential AssertionCredential `json:"credential" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.service.FinishMFA(req.MFAToken, &req.Credential, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// BeginStepUp returns the options for re-verifying the signed in user with a passkey
+func (h *Handler) BeginStepUp(c *gin.Context) {
+	options, err := h.service.BeginStepUp(c.GetHeader("Authorization"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishStepUp exchanges a passkey assertion for an access token carrying a new MFA
+// assertion
+func (h *Handler) FinishStepUp(c *gin.Context) {
+	var req AssertionCredential
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, mfaAt, err := h.service.FinishStepUp(c.GetHeader("Authorization"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	maxAge := h.service.mfa.StepUpMaxAge()
+	c.JSON(http.StatusOK, gin.H{
+		"token":       token,
+		"mfa_at":      mfaAt,
+		"valid_until": mfaAt.Add(maxAge),
+		"step_up_ttl": int64(maxAge.Seconds()),
+	})
+}
@@ -0,0 +1,768 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"user-management-service/internal/mfa"
+	"user-management-service/internal/models"
+)
+
+var (
+	ErrChallengeInvalid    = errors.New("WebAuthn challenge is invalid or expired")
+	ErrVerificationFailed  = errors.New("WebAuthn verification failed")
+	ErrCredentialNotFound  = errors.New("credential not found")
+	ErrCredentialExists    = errors.New("credential is already registered")
+	ErrCloneDetected       = errors.New("credential sign counter went backwards; the credential has been revoked")
+	ErrUserVerification    = errors.New("the authenticator must verify the user")
+	ErrStepUpRequired      = errors.New("step-up authentication required")
+	ErrLastRequiredPasskey = errors.New("the last passkey cannot be revoked while passkeys are required for the account")
+	errNoCredentials       = errors.New("no credentials")
+)
+
+// Ceremonies a challenge is issued for
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+	CeremonyMFA          = "mfa"
+	CeremonyStepUp       = "step_up"
+)
+
+// Options configures the relying party
+type Options struct {
+	RPID    string   // Domain the credentials are scoped to
+	RPName  string   // Shown by the authenticator
+	Origins []string // Origins allowed to run ceremonies, such as https://app.example.com
+	Timeout time.Duration
+}
+
+// OptionsFromEnv reads WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME, WEBAUTHN_ORIGINS (comma separated)
+// and WEBAUTHN_TIMEOUT
+func OptionsFromEnv() Options {
+	opts := Options{
+		RPID:    "localhost",
+		RPName:  "Invoice Financing Platform",
+		Timeout: 5 * time.Minute,
+	}
+	if value := os.Getenv("WEBAUTHN_RP_ID"); value != "" {
+		opts.RPID = value
+	}
+	if value := os.Getenv("WEBAUTHN_RP_NAME"); value != "" {
+		opts.RPName = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("WEBAUTHN_TIMEOUT")); err == nil && value > 0 {
+		opts.Timeout = value
+	}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			opts.Origins = append(opts.Origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	if len(opts.Origins) == 0 {
+		opts.Origins = []string{"https://" + opts.RPID}
+		if opts.RPID == "localhost" {
+			opts.Origins = append(opts.Origins, "http://localhost:3000")
+		}
+	}
+	return opts
+}
+
+// Public key credential options sent to navigator.credentials, with binary values
+// base64url encoded as in the WebAuthn JSON serialization
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationCredential is the PublicKeyCredential returned by navigator.credentials.create
+type RegistrationCredential struct {
+	ID       string `json:"id" binding:"required"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type" binding:"required"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject" binding:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response" binding:"required"`
+}
+
+// AssertionCredential is the PublicKeyCredential returned by navigator.credentials.get
+type AssertionCredential struct {
+	ID       string `json:"id" binding:"required"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type" binding:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response" binding:"required"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// Service runs WebAuthn registration and assertion ceremonies. Sessions and step-up tokens
+// are issued through the MFA service so passkey logins look like any other.
+type Service struct {
+	db   *gorm.DB
+	mfa  *mfa.Service
+	opts Options
+}
+
+func NewService(db *gorm.DB, mfaService *mfa.Service, opts Options) *Service {
+	return &Service{db: db, mfa: mfaService, opts: opts}
+}
+
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func encodeBase64URL(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func verificationError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrVerificationFailed, fmt.Sprintf(format, args...))
+}
+
+func (s *Service) getUser(userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, mfa.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *Service) activeCredentials(userID uuid.UUID) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at").Find(&credentials).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+func descriptors(credentials []models.WebAuthnCredential) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		list = append(list, CredentialDescriptor{Type: "public-key", ID: credential.CredentialID, Transports: credential.Transports})
+	}
+	return list
+}
+
+// newChallenge stores a random challenge for one ceremony. Old challenges are cleared out
+// on the way.
+func (s *Service) newChallenge(ceremony string, userID *uuid.UUID) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	now := time.Now()
+	s.db.Where("expires_at < ?", now.Add(-time.Hour)).Delete(&models.WebAuthnChallenge{})
+
+	challenge := &models.WebAuthnChallenge{
+		Challenge: encodeBase64URL(raw),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: now.Add(s.opts.Timeout),
+	}
+	if err := s.db.Create(challenge).Error; err != nil {
+		return "", fmt.Errorf("failed to store challenge: %w", err)
+	}
+	return challenge.Challenge, nil
+}
+
+// consumeChallenge checks the client data of a ceremony and uses up the challenge it
+// answers. A challenge can be answered once, even by concurrent requests.
+func (s *Service) consumeChallenge(rawClientData []byte, clientType, ceremony string) (*models.WebAuthnChallenge, error) {
+	data, err := s.checkClientData(rawClientData, clientType)
+	if err != nil {
+		return nil, err
+	}
+
+	var challenge models.WebAuthnChallenge
+	err = s.db.Where("challenge = ? AND ceremony = ? AND consumed_at IS NULL AND expires_at > ?", data.Challenge, ceremony, time.Now()).
+		First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChallengeInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	res := s.db.Model(&models.WebAuthnChallenge{}).Where("id = ? AND consumed_at IS NULL", challenge.ID).Update("consumed_at", time.Now())
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to consume challenge: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrChallengeInvalid
+	}
+	return &challenge, nil
+}
+
+// checkClientData checks the type and origin of a ceremony's client data
+func (s *Service) checkClientData(rawClientData []byte, clientType string) (*clientData, error) {
+	var data clientData
+	if err := json.Unmarshal(rawClientData, &data); err != nil {
+		return nil, verificationError("client data is not valid JSON")
+	}
+	if data.Type != clientType {
+		return nil, verificationError("client data type is %q", data.Type)
+	}
+	if data.CrossOrigin {
+		return nil, verificationError("cross-origin ceremonies are not allowed")
+	}
+	allowed := false
+	for _, origin := range s.opts.Origins {
+		allowed = allowed || data.Origin == origin
+	}
+	if !allowed {
+		return nil, verificationError("origin %q is not allowed", data.Origin)
+	}
+	return &data, nil
+}
+
+func (s *Service) rpIDHash() []byte {
+	sum := sha256.Sum256([]byte(s.opts.RPID))
+	return sum[:]
+}
+
+// BeginRegistration returns the options for creating a passkey. Credentials the user
+// already has are excluded so one authenticator is not registered twice.
+func (s *Service) BeginRegistration(userID uuid.UUID) (*CreationOptions, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.activeCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.newChallenge(CeremonyRegistration, &user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]CredentialParameter, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	displayName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if displayName == "" {
+		displayName = user.Email
+	}
+	return &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingParty{ID: s.opts.RPID, Name: s.opts.RPName},
+		// The user handle is the user's ID so discoverable logins can find the account
+		User:                   UserEntity{ID: encodeBase64URL(user.ID[:]), Name: user.Email, DisplayName: displayName},
+		PubKeyCredParams:       params,
+		Timeout:                s.opts.Timeout.Milliseconds(),
+		ExcludeCredentials:     descriptors(credentials),
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "preferred", UserVerification: "preferred"},
+		Attestation:            "direct",
+	}, nil
+}
+
+// FinishRegistration verifies a new credential and its attestation and stores it for the
+// user
+func (s *Service) FinishRegistration(userID uuid.UUID, name string, response *RegistrationCredential) (*models.WebAuthnCredential, error) {
+	if response.Type != "public-key" {
+		return nil, verificationError("credential type is %q", response.Type)
+	}
+	rawClientData, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, verificationError("client data is not base64url")
+	}
+	rawAttestation, err := decodeBase64URL(response.Response.AttestationObject)
+	if err != nil {
+		return nil, verificationError("attestation object is not base64url")
+	}
+	challenge, err := s.consumeChallenge(rawClientData, "webauthn.create", CeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != userID {
+		return nil, ErrChallengeInvalid
+	}
+
+	item, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, verificationError("attestation object: %v", err)
+	}
+	object, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, verificationError("attestation object is not a map")
+	}
+	format, _ := object["fmt"].(string)
+	stmt, _ := object["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := object["authData"].([]byte)
+	if format == "" || stmt == nil || rawAuthData == nil {
+		return nil, verificationError("attestation object is incomplete")
+	}
+
+	auth, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+	if !bytes.Equal(auth.RPIDHash, s.rpIDHash()) {
+		return nil, verificationError("credential was created for another relying party")
+	}
+	if !auth.has(flagUserPresent) {
+		return nil, verificationError("user was not present")
+	}
+	if auth.Key == nil {
+		return nil, verificationError("no attested credential data")
+	}
+	credentialID := encodeBase64URL(auth.CredentialID)
+	if strings.TrimRight(response.ID, "=") != credentialID {
+		return nil, verificationError("credential ID does not match the authenticator data")
+	}
+
+	clientDataHash := sha256.Sum256(rawClientData)
+	att, err := verifyAttestation(format, stmt, rawAuthData, auth, clientDataHash[:])
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+
+	var existing int64
+	s.db.Model(&models.WebAuthnCredential{}).Where("credential_id = ?", credentialID).Count(&existing)
+	if existing > 0 {
+		return nil, ErrCredentialExists
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	credential := &models.WebAuthnCredential{
+		UserID:              userID,
+		Name:                name,
+		CredentialID:        credentialID,
+		PublicKey:           auth.PublicKey,
+		Algorithm:           auth.Key.Algorithm,
+		SignCount:           int64(auth.SignCount),
+		Transports:          response.Response.Transports,
+		AAGUID:              formatAAGUID(auth.AAGUID),
+		AttestationFormat:   att.Format,
+		AttestationVerified: att.Verified,
+		AttestationSubject:  att.Subject,
+		AttestationIssuer:   att.Issuer,
+		UserVerified:        auth.has(flagUserVerified),
+		BackupEligible:      auth.has(flagBackupEligible),
+		BackedUp:            auth.has(flagBackedUp),
+	}
+	if err := s.db.Create(credential).Error; err != nil {
+		return nil, fmt.Errorf("failed to store credential: %w", err)
+	}
+	return credential, nil
+}
+
+// requestOptions starts an assertion ceremony, limited to the user's credentials when the
+// user is known
+func (s *Service) requestOptions(ceremony string, user *models.User, userVerification string) (*RequestOptions, error) {
+	options := &RequestOptions{
+		RPID:             s.opts.RPID,
+		Timeout:          s.opts.Timeout.Milliseconds(),
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: userVerification,
+	}
+	var userID *uuid.UUID
+	if user != nil {
+		credentials, err := s.activeCredentials(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(credentials) == 0 && ceremony != CeremonyLogin {
+			return nil, errNoCredentials
+		}
+		options.AllowCredentials = descriptors(credentials)
+		userID = &user.ID
+	}
+	challenge, err := s.newChallenge(ceremony, userID)
+	if err != nil {
+		return nil, err
+	}
+	options.Challenge = challenge
+	return options, nil
+}
+
+// verifyAssertion checks an assertion against the challenge it answers and the stored
+// credential, then advances the credential's sign counter. A counter that does not move
+// forward means the authenticator may have been cloned, so the credential is revoked.
+func (s *Service) verifyAssertion(response *AssertionCredential, ceremony string, requireUV bool) (*models.WebAuthnChallenge, *models.WebAuthnCredential, error) {
+	if response.Type != "public-key" {
+		return nil, nil, verificationError("credential type is %q", response.Type)
+	}
+	rawClientData, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, nil, verificationError("client data is not base64url")
+	}
+	rawAuthData, err := decodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, nil, verificationError("authenticator data is not base64url")
+	}
+	signature, err := decodeBase64URL(response.Response.Signature)
+	if err != nil {
+		return nil, nil, verificationError("signature is not base64url")
+	}
+	challenge, err := s.consumeChallenge(rawClientData, "webauthn.get", ceremony)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var credential models.WebAuthnCredential
+	err = s.db.Where("credential_id = ? AND revoked_at IS NULL", strings.TrimRight(response.ID, "=")).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return challenge, nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return challenge, nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	if challenge.UserID != nil && *challenge.UserID != credential.UserID {
+		return challenge, &credential, ErrCredentialNotFound
+	}
+	// Discoverable credentials return the user handle, which must name the owner
+	if response.Response.UserHandle != "" {
+		handle, err := decodeBase64URL(response.Response.UserHandle)
+		if err != nil || !bytes.Equal(handle, credential.UserID[:]) {
+			return challenge, &credential, verificationError("user handle does not match the credential")
+		}
+	} else if challenge.UserID == nil {
+		return challenge, &credential, verificationError("user handle is required")
+	}
+
+	auth, err := s.checkAssertion(&credential, rawClientData, rawAuthData, signature, requireUV)
+	if err != nil {
+		return challenge, &credential, err
+	}
+
+	var result error
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var stored models.WebAuthnCredential
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", credential.ID).First(&stored).Error; err != nil {
+			return err
+		}
+		if stored.RevokedAt != nil {
+			result = ErrCredentialNotFound
+			return nil
+		}
+		now := time.Now()
+		counter := int64(auth.SignCount)
+		if counterRegressed(stored.SignCount, auth.SignCount) {
+			result = ErrCloneDetected
+			return tx.Model(&models.WebAuthnCredential{}).Where("id = ?", stored.ID).Updates(map[string]interface{}{
+				"clone_detected": true,
+				"revoked_at":     now,
+			}).Error
+		}
+		credential.SignCount = counter
+		credential.LastUsedAt = &now
+		credential.BackedUp = auth.has(flagBackedUp)
+		return tx.Model(&models.WebAuthnCredential{}).Where("id = ?", stored.ID).Updates(map[string]interface{}{
+			"sign_count":   counter,
+			"last_used_at": now,
+			"backed_up":    credential.BackedUp,
+		}).Error
+	})
+	if err != nil {
+		return challenge, &credential, fmt.Errorf("failed to update credential: %w", err)
+	}
+	if result != nil {
+		return challenge, &credential, result
+	}
+	return challenge, &credential, nil
+}
+
+// checkAssertion verifies the authenticator data and signature of an assertion against
+// the stored credential
+func (s *Service) checkAssertion(credential *models.WebAuthnCredential, rawClientData, rawAuthData, signature []byte, requireUV bool) (*authenticatorData, error) {
+	auth, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+	if !bytes.Equal(auth.RPIDHash, s.rpIDHash()) {
+		return nil, verificationError("assertion was made for another relying party")
+	}
+	if !auth.has(flagUserPresent) {
+		return nil, verificationError("user was not present")
+	}
+	if requireUV && !auth.has(flagUserVerified) {
+		return nil, ErrUserVerification
+	}
+
+	key, _, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("stored credential key is invalid: %w", err)
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if err := key.verify(signed, signature); err != nil {
+		return nil, verificationError("%v", err)
+	}
+	return auth, nil
+}
+
+// counterRegressed reports whether an assertion's sign counter failed to move past the
+// stored one. Authenticators that do not count always report zero.
+func counterRegressed(stored int64, counter uint32) bool {
+	return (counter != 0 || stored != 0) && int64(counter) <= stored
+}
+
+// BeginLogin starts a passkey login. With an email the user's credentials are listed;
+// without one, or for an unknown email, the browser offers its discoverable passkeys so
+// the response does not reveal which accounts exist.
+func (s *Service) BeginLogin(email string) (*RequestOptions, error) {
+	var user *models.User
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		var found models.User
+		if s.db.Where("LOWER(email) = ?", email).First(&found).Error == nil {
+			user = &found
+		}
+	}
+	return s.requestOptions(CeremonyLogin, user, "required")
+}
+
+// FinishLogin signs a user in with a passkey alone. The authenticator must have verified
+// the user, which makes the passkey a multi-factor credential on its own.
+func (s *Service) FinishLogin(response *AssertionCredential, ipAddress, userAgent string) (*mfa.Session, error) {
+	_, credential, err := s.verifyAssertion(response, CeremonyLogin, true)
+	if err != nil {
+		if credential != nil {
+			s.mfa.RecordLogin(credential.UserID, ipAddress, userAgent, false, "webauthn_failed")
+		}
+		return nil, err
+	}
+	user, err := s.getUser(credential.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == models.StatusSuspended || user.Status == models.StatusDeactivated {
+		return nil, mfa.ErrAccountInactive
+	}
+	// Passkey logins can start without an email, so whether the user's organization
+	// requires SSO is only known now
+	amr := []string{"hwk", "user", "mfa"}
+	if err := s.mfa.CheckLoginMethod(user, amr); err != nil {
+		s.mfa.RecordLogin(user.ID, ipAddress, userAgent, false, "sso_required")
+		return nil, err
+	}
+
+	session, err := s.mfa.OpenSession(user, ipAddress, userAgent, amr)
+	if err != nil {
+		return nil, err
+	}
+	s.mfa.RecordLogin(user.ID, ipAddress, userAgent, true, "")
+	return session, nil
+}
+
+// BeginMFA starts the second login step with a passkey for a password login challenge
+func (s *Service) BeginMFA(mfaToken string) (*RequestOptions, error) {
+	challenge, err := s.mfa.PendingChallenge(mfaToken, mfa.MethodWebAuthn)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.getUser(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	options, err := s.requestOptions(CeremonyMFA, user, "preferred")
+	if errors.Is(err, errNoCredentials) {
+		return nil, mfa.ErrMethodNotAllowed
+	}
+	return options, err
+}
+
+// FinishMFA completes a password login challenge with a passkey assertion
+func (s *Service) FinishMFA(mfaToken string, response *AssertionCredential, ipAddress, userAgent string) (*mfa.Session, error) {
+	pending, err := s.mfa.PendingChallenge(mfaToken, mfa.MethodWebAuthn)
+	if err != nil {
+		return nil, err
+	}
+	challenge, credential, err := s.verifyAssertion(response, CeremonyMFA, false)
+	if err == nil && (challenge.UserID == nil || *challenge.UserID != pending.UserID) {
+		err = ErrChallengeInvalid
+	}
+	if err != nil {
+		s.mfa.RecordLogin(pending.UserID, ipAddress, userAgent, false, "webauthn_failed")
+		return nil, err
+	}
+	user, err := s.getUser(credential.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfa.CompleteChallenge(pending, user, ipAddress, userAgent, []string{"pwd", "hwk"})
+}
+
+// BeginStepUp starts a passkey assertion for the holder of an access token
+func (s *Service) BeginStepUp(tokenString string) (*RequestOptions, error) {
+	_, user, err := s.mfa.TokenUser(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	options, err := s.requestOptions(CeremonyStepUp, user, "required")
+	if errors.Is(err, errNoCredentials) {
+		return nil, ErrCredentialNotFound
+	}
+	return options, err
+}
+
+// FinishStepUp verifies the assertion and reissues the access token with a new MFA
+// assertion
+func (s *Service) FinishStepUp(tokenString string, response *AssertionCredential) (string, time.Time, error) {
+	claims, user, err := s.mfa.TokenUser(tokenString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	challenge, _, err := s.verifyAssertion(response, CeremonyStepUp, true)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != user.ID {
+		return "", time.Time{}, ErrChallengeInvalid
+	}
+	return s.mfa.Reassert(claims, []string{"hwk", "user", "mfa"})
+}
+
+// ListCredentials returns the user's credentials, including revoked ones
+func (s *Service) ListCredentials(userID uuid.UUID) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+func (s *Service) getCredential(userID, credentialID uuid.UUID) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := s.db.Where("id = ? AND user_id = ?", credentialID, userID).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+	return &credential, nil
+}
+
+func (s *Service) RenameCredential(userID, credentialID uuid.UUID, name string) (*models.WebAuthnCredential, error) {
+	credential, err := s.getCredential(userID, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	credential.Name = strings.TrimSpace(name)
+	if err := s.db.Model(credential).Update("name", credential.Name).Error; err != nil {
+		return nil, fmt.Errorf("failed to rename credential: %w", err)
+	}
+	return credential, nil
+}
+
+// RevokeCredential stops a credential being used. Users whose role requires passkeys keep
+// at least one, since without it they would fall back to weaker factors.
+func (s *Service) RevokeCredential(userID, credentialID uuid.UUID) (*models.WebAuthnCredential, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := s.getCredential(userID, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if credential.RevokedAt != nil {
+		return credential, nil
+	}
+	if s.mfa.PasskeyRequiredFor(user) {
+		active, err := s.activeCredentials(userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(active) <= 1 {
+			return nil, ErrLastRequiredPasskey
+		}
+	}
+	now := time.Now()
+	credential.RevokedAt = &now
+	if err := s.db.Model(credential).Update("revoked_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke credential: %w", err)
+	}
+	return credential, nil
+}
+
+// NeedsStepUp reports whether adding or removing credentials needs a recent MFA assertion.
+// Accounts with no second factor yet can register their first passkey with the token
+// from their password login.
+func (s *Service) NeedsStepUp(claims jwt.MapClaims, user *models.User) (bool, error) {
+	if s.mfa.RecentlyVerified(claims) {
+		return false, nil
+	}
+	if user.MFAEnabled {
+		return true, nil
+	}
+	credentials, err := s.activeCredentials(user.ID)
+	if err != nil {
+		return false, err
+	}
+	return len(credentials) > 0, nil
+}
@@ -0,0 +1,254 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"user-management-service/internal/models"
+)
+
+const (
+	testRPID   = "app.example.com"
+	testOrigin = "https://app.example.com"
+)
+
+func testService() *Service {
+	return &Service{opts: Options{RPID: testRPID, Origins: []string{testOrigin}}}
+}
+
+// softAuthenticator is a P-256 authenticator that counts its signatures
+type softAuthenticator struct {
+	key     *ecdsa.PrivateKey
+	rpID    string
+	counter uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, rpID: testRPID}
+}
+
+// credential is the stored credential a registration would have produced
+func (a *softAuthenticator) credential(signCount int64) *models.WebAuthnCredential {
+	return &models.WebAuthnCredential{
+		PublicKey: coseEC2Key(&a.key.PublicKey),
+		Algorithm: AlgES256,
+		SignCount: signCount,
+	}
+}
+
+// assertion is what navigator.credentials.get returns, decoded
+type assertion struct {
+	clientData []byte
+	authData   []byte
+	signature  []byte
+}
+
+func (a *softAuthenticator) assert(t *testing.T, clientType, origin string, flags byte) assertion {
+	t.Helper()
+	a.counter++
+	data, err := json.Marshal(clientData{Type: clientType, Challenge: "challenge", Origin: origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	authData := append(append([]byte{}, rpIDHash[:]...), flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(authData[33:], a.counter)
+	return assertion{clientData: data, authData: authData, signature: a.sign(t, authData, data)}
+}
+
+func (a *softAuthenticator) sign(t *testing.T, authData, clientData []byte) []byte {
+	t.Helper()
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+// coseEC2Key encodes a P-256 key as the COSE_Key map {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+func coseEC2Key(key *ecdsa.PublicKey) []byte {
+	out := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	out = append(out, key.X.FillBytes(make([]byte, 32))...)
+	out = append(out, 0x22, 0x58, 0x20)
+	return append(out, key.Y.FillBytes(make([]byte, 32))...)
+}
+
+func TestCheckAssertion(t *testing.T) {
+	service := testService()
+	authenticator := newSoftAuthenticator(t)
+	other := newSoftAuthenticator(t)
+
+	tests := []struct {
+		name      string
+		flags     byte
+		requireUV bool
+		tamper    func(a *assertion)
+		wantErr   error
+	}{
+		{name: "valid assertion", flags: flagUserPresent | flagUserVerified, requireUV: true},
+		{name: "user verification not required", flags: flagUserPresent},
+		{
+			name:  "signature by another key",
+			flags: flagUserPresent | flagUserVerified,
+			tamper: func(a *assertion) {
+				a.signature = other.sign(t, a.authData, a.clientData)
+			},
+			wantErr: ErrVerificationFailed,
+		},
+		{
+			name:  "corrupted signature",
+			flags: flagUserPresent | flagUserVerified,
+			tamper: func(a *assertion) {
+				a.signature[len(a.signature)-1] ^= 0xff
+			},
+			wantErr: ErrVerificationFailed,
+		},
+		{
+			name:  "client data changed after signing",
+			flags: flagUserPresent | flagUserVerified,
+			tamper: func(a *assertion) {
+				a.clientData = append(a.clientData[:len(a.clientData)-1], ' ', '}')
+			},
+			wantErr: ErrVerificationFailed,
+		},
+		{
+			name:  "sign counter changed after signing",
+			flags: flagUserPresent | flagUserVerified,
+			tamper: func(a *assertion) {
+				binary.BigEndian.PutUint32(a.authData[33:], 1000)
+			},
+			wantErr: ErrVerificationFailed,
+		},
+		{
+			name:  "another relying party",
+			flags: flagUserPresent | flagUserVerified,
+			tamper: func(a *assertion) {
+				rpIDHash := sha256.Sum256([]byte("evil.example.com"))
+				copy(a.authData, rpIDHash[:])
+				a.signature = authenticator.sign(t, a.authData, a.clientData)
+			},
+			wantErr: ErrVerificationFailed,
+		},
+		{name: "user not present", flags: flagUserVerified, wantErr: ErrVerificationFailed},
+		{name: "user not verified", flags: flagUserPresent, requireUV: true, wantErr: ErrUserVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := authenticator.assert(t, "webauthn.get", testOrigin, tt.flags)
+			if tt.tamper != nil {
+				tt.tamper(&a)
+			}
+			auth, err := service.checkAssertion(authenticator.credential(0), a.clientData, a.authData, a.signature, tt.requireUV)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if auth.SignCount != authenticator.counter {
+				t.Fatalf("sign count %d, want %d", auth.SignCount, authenticator.counter)
+			}
+		})
+	}
+}
+
+func TestSignCountRegression(t *testing.T) {
+	service := testService()
+	authenticator := newSoftAuthenticator(t)
+	credential := authenticator.credential(0)
+
+	// The genuine authenticator signs twice and the stored counter follows it
+	for i := 0; i < 2; i++ {
+		a := authenticator.assert(t, "webauthn.get", testOrigin, flagUserPresent|flagUserVerified)
+		auth, err := service.checkAssertion(credential, a.clientData, a.authData, a.signature, true)
+		if err != nil {
+			t.Fatalf("assertion %d: %v", i+1, err)
+		}
+		if counterRegressed(credential.SignCount, auth.SignCount) {
+			t.Fatalf("assertion %d: counter %d reported as regressed from %d", i+1, auth.SignCount, credential.SignCount)
+		}
+		credential.SignCount = int64(auth.SignCount)
+	}
+
+	// A clone made before the second signature replays the first counter with a valid
+	// signature
+	clone := &softAuthenticator{key: authenticator.key, rpID: testRPID}
+	a := clone.assert(t, "webauthn.get", testOrigin, flagUserPresent|flagUserVerified)
+	auth, err := service.checkAssertion(credential, a.clientData, a.authData, a.signature, true)
+	if err != nil {
+		t.Fatalf("clone assertion: %v", err)
+	}
+	if !counterRegressed(credential.SignCount, auth.SignCount) {
+		t.Fatalf("counter %d after %d was not reported as regressed", auth.SignCount, credential.SignCount)
+	}
+}
+
+func TestCounterRegressed(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  int64
+		counter uint32
+		want    bool
+	}{
+		{name: "authenticator without a counter", stored: 0, counter: 0, want: false},
+		{name: "first use", stored: 0, counter: 1, want: false},
+		{name: "counter advanced", stored: 5, counter: 6, want: false},
+		{name: "counter advanced by more than one", stored: 5, counter: 40, want: false},
+		{name: "counter repeated", stored: 5, counter: 5, want: true},
+		{name: "counter went backwards", stored: 5, counter: 3, want: true},
+		{name: "counter reset to zero", stored: 5, counter: 0, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := counterRegressed(tt.stored, tt.counter); got != tt.want {
+				t.Fatalf("counterRegressed(%d, %d) = %v, want %v", tt.stored, tt.counter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClientData(t *testing.T) {
+	service := testService()
+
+	tests := []struct {
+		name    string
+		data    clientData
+		wantErr bool
+	}{
+		{name: "valid", data: clientData{Type: "webauthn.get", Challenge: "c", Origin: testOrigin}},
+		{name: "registration client data", data: clientData{Type: "webauthn.create", Challenge: "c", Origin: testOrigin}, wantErr: true},
+		{name: "other origin", data: clientData{Type: "webauthn.get", Challenge: "c", Origin: "https://evil.example.com"}, wantErr: true},
+		{name: "cross origin", data: clientData{Type: "webauthn.get", Challenge: "c", Origin: testOrigin, CrossOrigin: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = service.checkClientData(raw, "webauthn.get")
+			if tt.wantErr && !errors.Is(err, ErrVerificationFailed) {
+				t.Fatalf("got error %v, want %v", err, ErrVerificationFailed)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -12,8 +13,19 @@ import (
 	"user-management-service/internal/config"
 	"user-management-service/internal/database"
 	"user-management-service/internal/handlers"
+	"user-management-service/internal/kyb"
+	"user-management-service/internal/kyc"
+	"user-management-service/internal/kycrefresh"
+	"user-management-service/internal/loginrisk"
+	"user-management-service/internal/mfa"
 	"user-management-service/internal/middleware"
+	"user-management-service/internal/models"
+	"user-management-service/internal/retention"
+	"user-management-service/internal/rolechange"
+	"user-management-service/internal/screening"
 	"user-management-service/internal/services"
+	"user-management-service/internal/sso"
+	"user-management-service/internal/webauthn"
 )
 
 func main() {
@@ -44,11 +56,110 @@ func main() {
 	complianceService := services.NewComplianceService(db, cfg)
 	notificationService := services.NewNotificationService(cfg)
 
+	// TOTP enrollment, the second login step and step-up for sensitive operations. Every
+	// login opens its session through this service, which registers it with the backend's
+	// session store when SESSION_REGISTRY_URL is set.
+	if err := db.AutoMigrate(&models.User{}, &models.MFAChallenge{}); err != nil {
+		log.Fatal("Failed to migrate MFA tables:", err)
+	}
+	totpService := mfa.NewService(db, cfg.JWTSecret, mfa.OptionsFromEnv())
+	if err := totpService.Ready(); err != nil {
+		log.Println("MFA setup and verification are unavailable:", err)
+	}
+
+	// Passkeys as a primary login, a second factor and for step-up
+	if err := db.AutoMigrate(&models.WebAuthnCredential{}, &models.WebAuthnChallenge{}); err != nil {
+		log.Fatal("Failed to migrate WebAuthn tables:", err)
+	}
+	webauthnService := webauthn.NewService(db, totpService, webauthn.OptionsFromEnv())
+
+	// Login attempt history, per-account lockout, risk scoring and known devices
+	if err := db.AutoMigrate(&models.User{}, &models.LoginHistory{}, &models.KnownDevice{}, &models.LoginConfirmation{}); err != nil {
+		log.Fatal("Failed to migrate login security tables:", err)
+	}
+	loginRiskService := loginrisk.NewService(db, totpService, loginrisk.OptionsFromEnv())
+	if err := loginRiskService.GeolocationReady(); err != nil {
+		log.Println("Login geolocation is unavailable:", err)
+	}
+	defer loginRiskService.Close()
+
+	// Sanctions and PEP screening against list files loaded from SCREENING_LIST_DIR
+	if err := db.AutoMigrate(&models.ScreeningList{}, &models.ScreeningEntry{}, &models.ScreeningResult{}); err != nil {
+		log.Fatal("Failed to migrate screening tables:", err)
+	}
+	screeningService := screening.NewService(db, screening.OptionsFromEnv())
+	if err := screeningService.LoadActive(); err != nil {
+		log.Fatal("Failed to load screening lists:", err)
+	}
+	if _, err := screeningService.SyncLists(); err != nil {
+		log.Println("Screening list sync failed:", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go screeningService.StartWatcher(ctx)
+
+	// KYC submissions: document validation, automated checks, risk scoring, the review
+	// queue and expiry of lapsed approvals
+	if err := db.AutoMigrate(&models.KYCData{}, &models.KYCCheck{}, &models.KYCReviewCase{}); err != nil {
+		log.Fatal("Failed to migrate KYC tables:", err)
+	}
+	kycWorkflowService := kyc.NewService(db, screeningService, kyc.OptionsFromEnv())
+	go kycWorkflowService.StartExpiryMonitor(ctx)
+
+	// Periodic re-verification: staged reminders before KYC or ID expiry, restriction once
+	// the deadline passes, and ad-hoc campaigns
+	if err := db.AutoMigrate(&models.User{}, &models.KYCRefresh{}, &models.KYCRefreshCampaign{}); err != nil {
+		log.Fatal("Failed to migrate KYC refresh tables:", err)
+	}
+	kycRefreshService := kycrefresh.NewService(db, kycrefresh.OptionsFromEnv())
+	go kycRefreshService.StartScheduler(ctx)
+
+	// KYB: directors, beneficial owners and the company verification decision
+	if err := db.AutoMigrate(&models.Company{}, &models.CompanyPerson{}, &models.KYBDecision{}); err != nil {
+		log.Fatal("Failed to migrate KYB tables:", err)
+	}
+	kybService := kyb.NewService(db, screeningService, kyb.OptionsFromEnv())
+
+	// Retention rules, legal holds and signed purge runs
+	if err := db.AutoMigrate(&models.RetentionRule{}, &models.LegalHold{}, &models.PurgeRun{}); err != nil {
+		log.Fatal("Failed to migrate retention tables:", err)
+	}
+	retentionService := retention.NewService(db, retention.OptionsFromEnv())
+	if err := retentionService.SeedRules(); err != nil {
+		log.Fatal("Failed to seed retention rules:", err)
+	}
+	go retentionService.StartScheduler(ctx)
+
+	// Maker-checker for role changes: another administrator confirms before the role changes
+	if err := db.AutoMigrate(&models.RoleChangeRequest{}, &models.RoleChangeDecision{}); err != nil {
+		log.Fatal("Failed to migrate role change tables:", err)
+	}
+	roleChangeService := rolechange.NewService(db, rolechange.OptionsFromEnv())
+	go roleChangeService.StartExpiryMonitor(ctx)
+
+	// Single sign-on through organizations' identity providers over OIDC and SAML
+	if err := db.AutoMigrate(&models.SSOConnection{}, &models.SSOLoginState{}, &models.SSOIdentity{}); err != nil {
+		log.Fatal("Failed to migrate SSO tables:", err)
+	}
+	ssoService := sso.NewService(db, totpService, sso.OptionsFromEnv())
+	// Sessions of every other login method are refused for organizations requiring SSO
+	totpService.SetSSOPolicy(ssoService)
+	go ssoService.StartCleanup(ctx)
+
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService, kycService, authService, mfaService)
-	kycHandler := handlers.NewKYCHandler(kycService, complianceService)
+	kycHandler := kyc.NewHandler(kycWorkflowService)
+	kycRefreshHandler := kycrefresh.NewHandler(kycRefreshService)
+	kybHandler := kyb.NewHandler(kybService)
+	roleChangeHandler := rolechange.NewHandler(roleChangeService)
+	ssoHandler := sso.NewHandler(ssoService)
 	authHandler := handlers.NewAuthHandler(authService, mfaService)
 	adminHandler := handlers.NewAdminHandler(userService, kycService, complianceService)
+	totpHandler := mfa.NewHandler(totpService)
+	webauthnHandler := webauthn.NewHandler(webauthnService)
+	loginRiskHandler := loginrisk.NewHandler(loginRiskService)
+	screeningHandler := screening.NewHandler(screeningService)
+	retentionHandler := retention.NewHandler(retentionService)
 
 	// Initialize Gin router
 	if cfg.Environment == "production" {
@@ -89,81 +200,153 @@ func main() {
 		// Authentication routes (public)
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/refresh", authHandler.RefreshToken)
-			auth.POST("/logout", authHandler.Logout)
-			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/register", ssoHandler.EnforceGate(), authHandler.Register)
+			auth.POST("/login", ssoHandler.EnforceGate(), loginRiskHandler.Gate(), totpHandler.LoginGate(), authHandler.Login)
+			auth.POST("/login/confirm", loginRiskHandler.ConfirmLogin)
+			auth.POST("/mfa/verify", totpHandler.CompleteLogin)
+			auth.POST("/webauthn/login/begin", ssoHandler.EnforceGate(), webauthnHandler.BeginLogin)
+			auth.POST("/webauthn/login/finish", webauthnHandler.FinishLogin)
+			auth.POST("/webauthn/mfa/begin", webauthnHandler.BeginMFA)
+			auth.POST("/webauthn/mfa/finish", webauthnHandler.FinishMFA)
+			auth.POST("/refresh", totpHandler.Refresh)
+			auth.POST("/logout", totpHandler.Logout)
+			auth.POST("/forgot-password", ssoHandler.EnforceGate(), authHandler.ForgotPassword)
 			auth.POST("/reset-password", authHandler.ResetPassword)
 			auth.POST("/verify-email", authHandler.VerifyEmail)
 			auth.POST("/resend-verification", authHandler.ResendVerification)
+			auth.POST("/sso/discover", ssoHandler.Discover)
+			auth.POST("/sso/token", ssoHandler.ExchangeCode)
+			auth.GET("/sso/:connectionId/login", ssoHandler.Login)
+			auth.GET("/sso/:connectionId/oidc/callback", ssoHandler.OIDCCallback)
+			auth.POST("/sso/:connectionId/saml/acs", ssoHandler.SAMLAssertionConsumer)
+			auth.GET("/sso/:connectionId/saml/metadata", ssoHandler.SAMLMetadata)
 		}
 
 		// MFA routes
-		mfa := v1.Group("/mfa")
-		mfa.Use(middleware.JWTAuth(cfg.JWTSecret))
+		mfaRoutes := v1.Group("/mfa")
+		mfaRoutes.Use(middleware.JWTAuth(cfg.JWTSecret), totpHandler.RequireSession())
 		{
-			mfa.POST("/setup", authHandler.SetupMFA)
-			mfa.POST("/verify", authHandler.VerifyMFA)
-			mfa.POST("/disable", authHandler.DisableMFA)
-			mfa.GET("/backup-codes", authHandler.GenerateBackupCodes)
+			mfaRoutes.POST("/setup", totpHandler.Setup)
+			mfaRoutes.POST("/verify", totpHandler.Verify)
+			mfaRoutes.POST("/disable", totpHandler.Disable)
+			mfaRoutes.GET("/backup-codes", totpHandler.GetBackupCodes)
+			mfaRoutes.POST("/backup-codes", totpHandler.RegenerateBackupCodes)
+			mfaRoutes.POST("/step-up", totpHandler.StepUp)
 		}
 
 		// User management routes (authenticated)
 		users := v1.Group("/users")
-		users.Use(middleware.JWTAuth(cfg.JWTSecret))
+		users.Use(middleware.JWTAuth(cfg.JWTSecret), totpHandler.RequireSession())
 		{
 			users.GET("/profile", userHandler.GetProfile)
 			users.PUT("/profile", userHandler.UpdateProfile)
 			users.POST("/change-password", userHandler.ChangePassword)
 			users.DELETE("/account", userHandler.DeleteAccount)
 			users.GET("/sessions", userHandler.GetActiveSessions)
-			users.DELETE("/sessions/:sessionId", userHandler.RevokeSession)
+			users.DELETE("/sessions/:sessionId", totpHandler.RevokeSession)
+			users.GET("/login-history", loginRiskHandler.GetLoginHistory)
+			users.GET("/devices", loginRiskHandler.GetDevices)
+			users.DELETE("/devices/:deviceId", loginRiskHandler.ForgetDevice)
+			users.POST("/webauthn/register/begin", webauthnHandler.BeginRegistration)
+			users.POST("/webauthn/register/finish", webauthnHandler.FinishRegistration)
+			users.GET("/webauthn/credentials", webauthnHandler.ListCredentials)
+			users.PUT("/webauthn/credentials/:credentialId", webauthnHandler.RenameCredential)
+			users.DELETE("/webauthn/credentials/:credentialId", webauthnHandler.RevokeCredential)
+			users.POST("/webauthn/step-up/begin", webauthnHandler.BeginStepUp)
+			users.POST("/webauthn/step-up/finish", webauthnHandler.FinishStepUp)
 		}
 
 		// KYC routes (authenticated)
-		kyc := v1.Group("/kyc")
-		kyc.Use(middleware.JWTAuth(cfg.JWTSecret))
+		kycRoutes := v1.Group("/kyc")
+		kycRoutes.Use(middleware.JWTAuth(cfg.JWTSecret), totpHandler.RequireSession())
 		{
-			kyc.GET("/status", kycHandler.GetKYCStatus)
-			kyc.POST("/submit", kycHandler.SubmitKYCDocuments)
-			kyc.GET("/requirements", kycHandler.GetKYCRequirements)
-			kyc.PUT("/update", kycHandler.UpdateKYCInfo)
-			kyc.POST("/resubmit", kycHandler.ResubmitKYC)
+			kycRoutes.GET("/status", kycHandler.GetStatus)
+			kycRoutes.POST("/submit", kycHandler.Submit)
+			kycRoutes.GET("/requirements", kycHandler.GetRequirements)
+			kycRoutes.PUT("/update", kycHandler.UpdateInfo)
+			kycRoutes.POST("/resubmit", kycHandler.Resubmit)
 		}
 
 		// Company management routes (authenticated SME/Buyer users)
 		companies := v1.Group("/companies")
-		companies.Use(middleware.JWTAuth(cfg.JWTSecret))
+		companies.Use(middleware.JWTAuth(cfg.JWTSecret), totpHandler.RequireSession())
 		companies.Use(middleware.RequireRole("sme", "buyer", "admin"))
 		{
 			companies.GET("/profile", userHandler.GetCompanyProfile)
-			companies.PUT("/profile", userHandler.UpdateCompanyProfile)
+			companies.PUT("/profile", totpHandler.RequireStepUp(), userHandler.UpdateCompanyProfile) // Holds the payout bank account
 			companies.POST("/documents", userHandler.UploadCompanyDocument)
 			companies.GET("/documents", userHandler.GetCompanyDocuments)
 			companies.DELETE("/documents/:documentId", userHandler.DeleteCompanyDocument)
+			companies.GET("/people", kybHandler.GetPeople)
+			companies.POST("/people", kybHandler.AddPerson)
+			companies.PUT("/people/:personId", kybHandler.UpdatePerson)
+			companies.DELETE("/people/:personId", kybHandler.RemovePerson)
+			companies.GET("/verification", kybHandler.GetVerification)
+			companies.POST("/verification", kybHandler.SubmitVerification)
+			companies.GET("/verification/requirements", kybHandler.GetRequirements)
 		}
 
 		// Admin routes (admin only)
 		admin := v1.Group("/admin")
-		admin.Use(middleware.JWTAuth(cfg.JWTSecret))
+		admin.Use(middleware.JWTAuth(cfg.JWTSecret), totpHandler.RequireSession())
 		admin.Use(middleware.RequireRole("admin"))
 		{
 			admin.GET("/users", adminHandler.GetUsers)
 			admin.GET("/users/:userId", adminHandler.GetUser)
 			admin.PUT("/users/:userId/status", adminHandler.UpdateUserStatus)
-			admin.PUT("/users/:userId/role", adminHandler.UpdateUserRole)
-			admin.GET("/kyc/pending", adminHandler.GetPendingKYC)
-			admin.PUT("/kyc/:kycId/review", adminHandler.ReviewKYC)
+			admin.PUT("/users/:userId/role", totpHandler.RequireStepUp(), roleChangeHandler.RequestRoleChange) // Applied once confirmed
+			admin.GET("/role-changes", roleChangeHandler.GetRequests)
+			admin.GET("/role-changes/:requestId", roleChangeHandler.GetRequest)
+			admin.POST("/role-changes/:requestId/approve", totpHandler.RequireStepUp(), roleChangeHandler.Approve)
+			admin.POST("/role-changes/:requestId/reject", roleChangeHandler.Reject)
+			admin.POST("/role-changes/:requestId/cancel", roleChangeHandler.Cancel)
+			admin.GET("/sso/connections", ssoHandler.GetConnections)
+			admin.POST("/sso/connections", totpHandler.RequireStepUp(), ssoHandler.CreateConnection) // Decides who can log in
+			admin.GET("/sso/connections/:connectionId", ssoHandler.GetConnection)
+			admin.PUT("/sso/connections/:connectionId", totpHandler.RequireStepUp(), ssoHandler.UpdateConnection)
+			admin.DELETE("/sso/connections/:connectionId", totpHandler.RequireStepUp(), ssoHandler.DeleteConnection)
+			admin.GET("/users/:userId/login-history", loginRiskHandler.GetUserLoginHistory)
+			admin.POST("/users/:userId/unlock", loginRiskHandler.UnlockAccount)
+			admin.GET("/kyc/pending", kycHandler.GetQueue)
+			admin.GET("/kyc/:kycId", kycHandler.GetCase)
+			admin.PUT("/kyc/:kycId/review", kycHandler.Review)
+			admin.POST("/kyc/:kycId/approve", totpHandler.RequireStepUp(), kycHandler.Approve) // Second reviewer of maker-checker
+			admin.GET("/kyc/refresh/dashboard", kycRefreshHandler.GetDashboard)
+			admin.GET("/kyc/refresh", kycRefreshHandler.GetRefreshes)
+			admin.POST("/kyc/refresh/run", kycRefreshHandler.RunNow)
+			admin.GET("/kyc/campaigns", kycRefreshHandler.GetCampaigns)
+			admin.POST("/kyc/campaigns", totpHandler.RequireStepUp(), kycRefreshHandler.CreateCampaign) // Can restrict many customers
+			admin.GET("/kyc/campaigns/:campaignId", kycRefreshHandler.GetCampaign)
+			admin.POST("/kyc/campaigns/:campaignId/cancel", kycRefreshHandler.CancelCampaign)
+			admin.GET("/companies/pending", kybHandler.GetPending)
+			admin.GET("/companies/:companyId/verification", kybHandler.GetCompany)
+			admin.PUT("/companies/:companyId/verification", totpHandler.RequireStepUp(), kybHandler.Decide) // Gates financing
+			admin.PUT("/companies/:companyId/documents/:documentId/verify", kybHandler.VerifyDocument)
 			admin.GET("/compliance/reports", adminHandler.GetComplianceReports)
 			admin.POST("/compliance/audit", adminHandler.TriggerComplianceAudit)
 			admin.GET("/analytics/users", adminHandler.GetUserAnalytics)
 			admin.GET("/analytics/kyc", adminHandler.GetKYCAnalytics)
+			admin.POST("/screening/users/:userId", screeningHandler.ScreenUser)
+			admin.GET("/screening/results", screeningHandler.GetResults)
+			admin.GET("/screening/results/:resultId", screeningHandler.GetResult)
+			admin.PUT("/screening/results/:resultId/review", screeningHandler.ReviewResult)
+			admin.GET("/screening/lists", screeningHandler.GetLists)
+			admin.POST("/screening/lists/reload", screeningHandler.ReloadLists)
+			admin.GET("/retention/rules", retentionHandler.GetRules)
+			admin.POST("/retention/rules", retentionHandler.CreateRule)
+			admin.PUT("/retention/rules/:name", retentionHandler.UpdateRule)
+			admin.GET("/retention/holds", retentionHandler.GetLegalHolds)
+			admin.POST("/retention/holds", retentionHandler.PlaceLegalHold)
+			admin.POST("/retention/holds/:holdId/release", retentionHandler.ReleaseLegalHold)
+			admin.POST("/retention/runs", retentionHandler.RunPurge)
+			admin.GET("/retention/runs", retentionHandler.GetPurgeRuns)
+			admin.GET("/retention/runs/:runId", retentionHandler.GetPurgeRun)
+			admin.GET("/retention/runs/:runId/verify", retentionHandler.VerifyPurgeRun)
 		}
 
 		// Bank routes (bank users only)
 		bank := v1.Group("/bank")
-		bank.Use(middleware.JWTAuth(cfg.JWTSecret))
+		bank.Use(middleware.JWTAuth(cfg.JWTSecret), totpHandler.RequireSession())
 		bank.Use(middleware.RequireRole("bank", "admin"))
 		{
 			bank.GET("/customers", userHandler.GetBankCustomers)
@@ -171,6 +354,30 @@ func main() {
 			bank.PUT("/customers/:customerId/credit-limit", userHandler.UpdateCustomerCreditLimit)
 			bank.GET("/risk-assessment/:customerId", userHandler.GetCustomerRiskAssessment)
 		}
+
+		// Screening of invoice buyers and payment counterparties by other services
+		screeningRoutes := v1.Group("/screening")
+		screeningRoutes.Use(middleware.JWTAuth(cfg.JWTSecret))
+		screeningRoutes.Use(middleware.RequireRole("bank", "admin"))
+		{
+			screeningRoutes.POST("/screen", screeningHandler.ScreenSubject)
+		}
+
+		// Financing eligibility of a company, checked by the invoice financing backend
+		kybRoutes := v1.Group("/kyb")
+		kybRoutes.Use(middleware.JWTAuth(cfg.JWTSecret))
+		kybRoutes.Use(middleware.RequireRole("bank", "admin"))
+		{
+			kybRoutes.GET("/eligibility", kybHandler.GetEligibility)
+		}
+
+		// KYC standing of a customer, checked by the backend before financing or investment
+		kycChecks := v1.Group("/kyc/customers")
+		kycChecks.Use(middleware.JWTAuth(cfg.JWTSecret))
+		kycChecks.Use(middleware.RequireRole("bank", "admin"))
+		{
+			kycChecks.GET("/eligibility", kycHandler.GetEligibility)
+		}
 	}
 
 	// Start server
@@ -184,6 +391,7 @@ func main() {
 	log.Printf("Database: Connected")
 	log.Printf("KYC/AML: Enabled")
 	log.Printf("MFA: Enabled")
+	log.Printf("WebAuthn: Enabled")
 
 	if err := router.Run(":" + port); err != nil {
 		log.Fatal("Failed to start server:", err)